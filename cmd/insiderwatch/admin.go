@@ -0,0 +1,238 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// pollResult is the outcome of a poll cycle run on request
+type pollResult struct {
+	summary processor.PollSummary
+	err     error
+}
+
+// recalcResult is the outcome of a win rate recalculation run on request
+type recalcResult struct {
+	resolved int
+	err      error
+}
+
+// adminTriggers carries on-demand jobs from the admin endpoints to the main
+// loop, which runs them alongside its scheduled ones. Each request is the
+// channel the loop sends the job's result on; it must have room for one
+// result so the loop never waits on a client that went away.
+type adminTriggers struct {
+	process     chan chan pollResult
+	recalculate chan chan recalcResult
+	summary     chan chan summaryResult
+	stopping    <-chan struct{} // Closed at shutdown, when the loop stops taking requests
+}
+
+func newAdminTriggers(stopping <-chan struct{}) *adminTriggers {
+	return &adminTriggers{
+		process:     make(chan chan pollResult),
+		recalculate: make(chan chan recalcResult),
+		summary:     make(chan chan summaryResult),
+		stopping:    stopping,
+	}
+}
+
+// pollSummaryJSON is the POST /admin/process response
+type pollSummaryJSON struct {
+	Fetched    int            `json:"fetched"`
+	New        int            `json:"new"`
+	Processed  int            `json:"processed"`
+	Alerted    int            `json:"alerted"`
+	Trades     map[string]int `json:"trades"` // By status
+	Alerts     map[string]int `json:"alerts"` // By severity
+	CutShort   string         `json:"cut_short,omitempty"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// adminAuthorized reports whether r carries the admin token as a bearer
+// token. An empty token authorizes nothing.
+func adminAuthorized(r *http.Request, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+// handleProcessNow serves POST /admin/process: it has the main loop run a
+// poll cycle now and returns what the cycle did, or 409 if one is already
+// running
+func handleProcessNow(triggers *adminTriggers, token string, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !adminAuthorized(r, token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		// A cycle can run up to POLL_CYCLE_TIMEOUT, past the server's write timeout
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		reply := make(chan pollResult, 1)
+		if !sendTrigger(w, r, triggers, triggers.process, reply) {
+			return
+		}
+		var res pollResult
+		select {
+		case res = <-reply:
+		case <-r.Context().Done():
+			return
+		}
+
+		if errors.Is(res.err, processor.ErrPollRunning) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "poll cycle already running"})
+			return
+		}
+		if res.err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "poll cycle failed: " + res.err.Error()})
+			return
+		}
+
+		s := res.summary
+		log.WithField("processed", s.Processed).Info("Manual poll cycle finished")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(pollSummaryJSON{
+			Fetched:    s.Fetched,
+			New:        s.New,
+			Processed:  s.Processed,
+			Alerted:    s.Alerted,
+			Trades:     s.Trades,
+			Alerts:     s.Alerts,
+			CutShort:   s.CutShort,
+			DurationMS: s.Duration.Milliseconds(),
+		})
+	}
+}
+
+// handleRecalculateNow serves POST /admin/recalculate-winrates: it has the
+// main loop run a win rate recalculation now and returns the markets it
+// resolved, or 409 if one is already running
+func handleRecalculateNow(triggers *adminTriggers, token string, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !adminAuthorized(r, token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		// A full recalculation can outlast the server's write timeout
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		reply := make(chan recalcResult, 1)
+		if !sendTrigger(w, r, triggers, triggers.recalculate, reply) {
+			return
+		}
+		var res recalcResult
+		select {
+		case res = <-reply:
+		case <-r.Context().Done():
+			return
+		}
+
+		if errors.Is(res.err, processor.ErrRecalculationRunning) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(map[string]string{"error": "recalculation already running"})
+			return
+		}
+		if res.err != nil {
+			log.WithError(res.err).Error("Manual win rate recalculation failed")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "recalculation failed"})
+			return
+		}
+
+		log.WithField("resolved_markets", res.resolved).Info("Manual win rate recalculation finished")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]int{"resolved_markets": res.resolved})
+	}
+}
+
+// summaryJSON is the POST /admin/summary response
+type summaryJSON struct {
+	Date             string           `json:"date"`
+	Timezone         string           `json:"timezone"`
+	QualifyingTrades int64            `json:"qualifying_trades"`
+	VolumeUSD        float64          `json:"volume_usd"`
+	Alerts           map[string]int64 `json:"alerts"`        // By severity
+	TopAlertIDs      []int64          `json:"top_alert_ids"` // Best first
+	FlaggedClusters  int              `json:"flagged_clusters"`
+	FlaggedWins      int              `json:"flagged_wins"`
+}
+
+// handleSummaryNow serves POST /admin/summary: it has the main loop send
+// the daily summary for the day up to now to the summary channels, whether
+// or not DAILY_SUMMARY_TIME is set, and returns what it reported
+func handleSummaryNow(triggers *adminTriggers, token string, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if !adminAuthorized(r, token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		reply := make(chan summaryResult, 1)
+		if !sendTrigger(w, r, triggers, triggers.summary, reply) {
+			return
+		}
+		var res summaryResult
+		select {
+		case res = <-reply:
+		case <-r.Context().Done():
+			return
+		}
+
+		if res.err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "daily summary failed: " + res.err.Error()})
+			return
+		}
+
+		s := res.summary
+		out := summaryJSON{
+			Date:             s.Date,
+			Timezone:         s.Timezone,
+			QualifyingTrades: s.QualifyingTrades,
+			VolumeUSD:        s.QualifyingVolumeUSD,
+			Alerts:           make(map[string]int64, len(s.Alerts)),
+			TopAlertIDs:      []int64{},
+			FlaggedClusters:  len(s.FlaggedClusters),
+			FlaggedWins:      len(s.FlaggedWins),
+		}
+		for severity, n := range s.Alerts {
+			out.Alerts[string(severity)] = n
+		}
+		for _, a := range s.TopAlerts {
+			out.TopAlertIDs = append(out.TopAlertIDs, a.AlertID)
+		}
+		log.WithField("date", s.Date).Info("Manual daily summary sent")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(out)
+	}
+}
+
+// sendTrigger hands reply to the main loop on c. It writes a 503 and returns
+// false if the service is shutting down.
+func sendTrigger[T any](w http.ResponseWriter, r *http.Request, triggers *adminTriggers, c chan chan T, reply chan T) bool {
+	select {
+	case c <- reply:
+		return true
+	case <-triggers.stopping:
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "shutting down"})
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// serveTriggers stands in for the main loop, answering each process request
+// with res
+func serveTriggers(triggers *adminTriggers, res pollResult) {
+	go func() {
+		for reply := range triggers.process {
+			reply <- res
+		}
+	}()
+}
+
+func TestProcessNow(t *testing.T) {
+	tests := []struct {
+		name           string
+		result         pollResult
+		expectedStatus int
+		description    string
+	}{
+		{"ran", pollResult{summary: processor.PollSummary{Fetched: 12, New: 3, Processed: 2, Alerted: 1}}, http.StatusOK, "A finished cycle returns its summary"},
+		{"running", pollResult{err: processor.ErrPollRunning}, http.StatusConflict, "A cycle already in progress is a 409"},
+		{"failed", pollResult{err: errors.New("fetch trades: timeout")}, http.StatusInternalServerError, "A failed cycle is a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			triggers := newAdminTriggers(make(chan struct{}))
+			defer close(triggers.process)
+			serveTriggers(triggers, tt.result)
+
+			req := httptest.NewRequest("POST", "/admin/process", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			rec := httptest.NewRecorder()
+			handleProcessNow(triggers, "secret", log)(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var got pollSummaryJSON
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Fetched != 12 || got.Processed != 2 || got.Alerted != 1 {
+				t.Errorf("got %+v, want 12 fetched, 2 processed, 1 alerted\nDescription: %s", got, tt.description)
+			}
+		})
+	}
+}
+
+func TestAdminTriggersShutdown(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	stopping := make(chan struct{})
+	close(stopping)
+	triggers := newAdminTriggers(stopping) // Nothing serves the loop side
+
+	req := httptest.NewRequest("POST", "/admin/recalculate-winrates", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handleRecalculateNow(triggers, "secret", log)(rec, req)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want %d once the loop stops taking requests", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	triggers := newAdminTriggers(make(chan struct{}))
+
+	tests := []struct {
+		name        string
+		token       string
+		auth        string
+		description string
+	}{
+		{"missing", "secret", "", "Requests without the token are rejected"},
+		{"wrong", "secret", "Bearer nope", "A wrong token is rejected"},
+		{"unset", "", "Bearer ", "An empty admin token authorizes nothing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/admin/process", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handleProcessNow(triggers, tt.token, log)(rec, req)
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, http.StatusUnauthorized, tt.description)
+			}
+		})
+	}
+}
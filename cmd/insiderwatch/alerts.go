@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Page sizes for the paginated read endpoints
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// alertLister reads stored alerts; *storage.DB implements it
+type alertLister interface {
+	ListAlerts(ctx context.Context, filter storage.AlertFilter) ([]storage.Alert, int64, error)
+}
+
+// alertItem is one alert in a GET /alerts response
+type alertItem struct {
+	ID              int64           `json:"id"`
+	Severity        string          `json:"severity"`
+	Wallet          string          `json:"wallet"`
+	ConditionID     string          `json:"condition_id"`
+	MarketTitle     string          `json:"market_title"`
+	MarketURL       string          `json:"market_url"`
+	Side            string          `json:"side"`
+	Outcome         string          `json:"outcome"`
+	NotionalUSD     float64         `json:"notional_usd"`
+	Price           float64         `json:"price"`
+	WalletAgeDays   int             `json:"wallet_age_days"`
+	SuspicionScore  float64         `json:"suspicion_score"`
+	RecordOnly      bool            `json:"record_only"`
+	Profile         string          `json:"profile,omitempty"` // Watch profile that raised it, with PROFILES
+	TransactionHash string          `json:"transaction_hash"`
+	TradeTimestamp  int64           `json:"trade_timestamp"`
+	CreatedTS       int64           `json:"created_ts"`
+	Breakdown       json.RawMessage `json:"breakdown,omitempty"` // With ?include=breakdown
+}
+
+// newAlertItem converts a stored alert, leaving out its breakdown
+func newAlertItem(a storage.Alert) alertItem {
+	return alertItem{
+		ID:              a.ID,
+		Severity:        a.AlertType,
+		Wallet:          a.WalletAddress,
+		ConditionID:     a.ConditionID,
+		MarketTitle:     a.MarketTitle,
+		MarketURL:       a.MarketURL,
+		Side:            a.Side,
+		Outcome:         a.Outcome,
+		NotionalUSD:     a.NotionalUSD,
+		Price:           a.Price,
+		WalletAgeDays:   a.WalletAgeDays,
+		SuspicionScore:  a.SuspicionScore,
+		RecordOnly:      a.RecordOnly,
+		Profile:         a.Profile,
+		TransactionHash: a.TransactionHash,
+		TradeTimestamp:  a.TradeTimestampSec,
+		CreatedTS:       a.CreatedTS,
+	}
+}
+
+// alertsPage is the GET /alerts response envelope
+type alertsPage struct {
+	Items      []alertItem `json:"items"`
+	Total      int64       `json:"total"`       // Alerts matching the filters across all pages
+	NextCursor *string     `json:"next_cursor"` // Pass as ?cursor= for the next page; null on the last one
+}
+
+// handleListAlerts serves GET /alerts: stored alerts, newest first,
+// filtered by the query parameters.
+func handleListAlerts(db alertLister, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		filter, breakdown, err := parseAlertsQuery(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// One extra row tells whether there is another page
+		limit := filter.Limit
+		filter.Limit++
+		rows, total, err := db.ListAlerts(r.Context(), filter)
+		if err != nil {
+			log.WithError(err).Error("Failed to list alerts")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to list alerts"})
+			return
+		}
+
+		page := alertsPage{Items: make([]alertItem, 0, min(len(rows), limit)), Total: total}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			cursor := strconv.FormatInt(rows[limit-1].ID, 10)
+			page.NextCursor = &cursor
+		}
+		for _, a := range rows {
+			item := newAlertItem(a)
+			if breakdown && json.Valid([]byte(a.ScoreBreakdown)) {
+				item.Breakdown = json.RawMessage(a.ScoreBreakdown)
+			}
+			page.Items = append(page.Items, item)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// parseAlertsQuery turns GET /alerts query parameters into a filter, and
+// reports whether score breakdowns were requested
+func parseAlertsQuery(q url.Values) (storage.AlertFilter, bool, error) {
+	filter := storage.AlertFilter{
+		Wallet:      strings.ToLower(q.Get("wallet")),
+		ConditionID: q.Get("condition_id"),
+		Limit:       defaultPageLimit,
+	}
+
+	if s := q.Get("severity"); s != "" {
+		switch severity := alerts.Severity(strings.ToUpper(s)); severity {
+		case alerts.SeverityInfo, alerts.SeverityWarn, alerts.SeverityAlert:
+			filter.AlertType = string(severity)
+		default:
+			return filter, false, fmt.Errorf("severity must be %s, %s or %s", alerts.SeverityInfo, alerts.SeverityWarn, alerts.SeverityAlert)
+		}
+	}
+
+	var err error
+	if filter.SinceTS, err = parseTimestampParam(q, "since"); err != nil {
+		return filter, false, err
+	}
+	if filter.UntilTS, err = parseTimestampParam(q, "until"); err != nil {
+		return filter, false, err
+	}
+	if filter.SinceTS > 0 && filter.UntilTS > 0 && filter.UntilTS <= filter.SinceTS {
+		return filter, false, fmt.Errorf("until must be after since")
+	}
+
+	if s := q.Get("min_notional"); s != "" {
+		if filter.MinNotional, err = strconv.ParseFloat(s, 64); err != nil || filter.MinNotional < 0 {
+			return filter, false, fmt.Errorf("min_notional must be a non-negative number")
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 1 || filter.Limit > maxPageLimit {
+			return filter, false, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	if s := q.Get("cursor"); s != "" {
+		if filter.BeforeID, err = strconv.ParseInt(s, 10, 64); err != nil || filter.BeforeID < 1 {
+			return filter, false, fmt.Errorf("cursor must be a next_cursor from a previous page")
+		}
+	}
+
+	breakdown := false
+	if s := q.Get("include"); s != "" {
+		if s != "breakdown" {
+			return filter, false, fmt.Errorf("include must be breakdown")
+		}
+		breakdown = true
+	}
+	return filter, breakdown, nil
+}
+
+// parseTimestampParam reads a Unix timestamp in seconds or an RFC 3339 time
+func parseTimestampParam(q url.Values, name string) (int64, error) {
+	s := q.Get(name)
+	if s == "" {
+		return 0, nil
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil && ts > 0 {
+		return ts, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+	return 0, fmt.Errorf("%s must be a Unix timestamp or an RFC 3339 time", name)
+}
+
+// alertDetailCoordinated caps the coordinated trade events in an alert detail
+const alertDetailCoordinated = 20
+
+// alertDetailReader reads an alert and its context; *storage.DB implements it
+type alertDetailReader interface {
+	GetAlert(ctx context.Context, id int64) (*storage.Alert, error)
+	GetWallet(ctx context.Context, address string) (*storage.Wallet, error)
+	GetWalletTotalsAt(ctx context.Context, walletAddress string, atTS int64) (*storage.WalletTotals, error)
+	GetWalletFundingSource(ctx context.Context, walletAddress string) (*storage.WalletFundingSource, error)
+	GetMarketMap(ctx context.Context, conditionID string) (*storage.MarketMap, error)
+	GetMarketResolution(ctx context.Context, conditionID string) (*storage.MarketResolution, error)
+	GetClusterMember(ctx context.Context, walletAddress string) (*storage.WalletClusterMember, error)
+	GetWalletClusterByID(ctx context.Context, clusterID string) (*storage.WalletCluster, error)
+	GetCoordinatedTradesInMarket(ctx context.Context, clusterID, conditionID string, limit int) ([]storage.CoordinatedTrade, error)
+}
+
+// alertDetail is the GET /alerts/{id} response: the alert with its full
+// score breakdown and what storage knows around it
+type alertDetail struct {
+	alertItem
+	MarketSlug        string             `json:"market_slug"`
+	Wallet            alertWallet        `json:"wallet"`
+	Market            *alertMarket       `json:"market"`             // null when the market isn't cached
+	Cluster           *walletCluster     `json:"cluster"`            // The wallet's cluster now; null when it isn't in one
+	CoordinatedTrades []coordinatedEvent `json:"coordinated_trades"` // The cluster's coordinated trades in this market
+}
+
+// alertWallet is the alerted wallet as of the alerted trade
+type alertWallet struct {
+	FirstSeenTS       int64          `json:"first_seen_ts"`
+	AgeDays           int            `json:"age_days"` // When the alert fired
+	FundingReceivedTS int64          `json:"funding_received_ts"`
+	TradesToDate      int64          `json:"trades_to_date"` // Stored trades up to and including the alerted one
+	VolumeToDateUSD   float64        `json:"volume_to_date_usd"`
+	Funding           *walletFunding `json:"funding"` // null when no funding source was found
+}
+
+type alertMarket struct {
+	Title        string            `json:"title"`
+	Slug         string            `json:"slug"`
+	URL          string            `json:"url"`
+	Category     string            `json:"category"`
+	EndDate      int64             `json:"end_date"`
+	VolumeUSD    float64           `json:"volume_usd"`
+	LiquidityUSD float64           `json:"liquidity_usd"`
+	Resolution   *marketResolution `json:"resolution"` // null until the market resolves
+}
+
+type marketResolution struct {
+	WinningOutcome string `json:"winning_outcome"`
+	ResolvedTS     int64  `json:"resolved_ts"`
+	Method         string `json:"method"`
+}
+
+// handleAlertDetail serves GET /alerts/{id}: one alert with its full score
+// breakdown, the wallet as of the alert, the market, and the wallet's
+// cluster with its coordinated trades in that market.
+func handleAlertDetail(db alertDetailReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil || id < 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "id must be a positive integer"})
+			return
+		}
+
+		detail, err := loadAlertDetail(r.Context(), db, id)
+		if err != nil {
+			log.WithError(err).WithField("alert_id", id).Error("Failed to load alert")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to load alert"})
+			return
+		}
+		if detail == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "alert not found"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// loadAlertDetail composes an alert's storage records. It returns nil for
+// an unknown ID.
+func loadAlertDetail(ctx context.Context, db alertDetailReader, id int64) (*alertDetail, error) {
+	a, err := db.GetAlert(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("get alert: %w", err)
+	}
+	if a == nil {
+		return nil, nil
+	}
+
+	detail := &alertDetail{
+		alertItem:         newAlertItem(*a),
+		MarketSlug:        a.MarketSlug,
+		Wallet:            alertWallet{AgeDays: a.WalletAgeDays},
+		CoordinatedTrades: []coordinatedEvent{},
+	}
+	if json.Valid([]byte(a.ScoreBreakdown)) {
+		detail.Breakdown = json.RawMessage(a.ScoreBreakdown)
+	}
+
+	wallet, err := db.GetWallet(ctx, a.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("get wallet: %w", err)
+	}
+	if wallet != nil {
+		detail.Wallet.FirstSeenTS = wallet.FirstSeenTS
+		detail.Wallet.FundingReceivedTS = wallet.FundingReceivedTS
+	}
+	totals, err := db.GetWalletTotalsAt(ctx, a.WalletAddress, a.TradeTimestampSec)
+	if err != nil {
+		return nil, fmt.Errorf("get wallet totals: %w", err)
+	}
+	detail.Wallet.TradesToDate = totals.Trades
+	detail.Wallet.VolumeToDateUSD = totals.VolumeUSD
+	funding, err := db.GetWalletFundingSource(ctx, a.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("get funding source: %w", err)
+	}
+	if funding != nil {
+		detail.Wallet.Funding = newWalletFunding(funding)
+	}
+
+	market, err := db.GetMarketMap(ctx, a.ConditionID)
+	if err != nil {
+		return nil, fmt.Errorf("get market: %w", err)
+	}
+	if market != nil {
+		detail.Market = &alertMarket{
+			Title:        market.MarketTitle,
+			Slug:         market.MarketSlug,
+			URL:          market.MarketURL,
+			Category:     market.Category,
+			EndDate:      market.EndDate,
+			VolumeUSD:    market.VolumeNum,
+			LiquidityUSD: market.LiquidityNum,
+		}
+		resolution, err := db.GetMarketResolution(ctx, a.ConditionID)
+		if err != nil {
+			return nil, fmt.Errorf("get resolution: %w", err)
+		}
+		if resolution != nil {
+			detail.Market.Resolution = &marketResolution{
+				WinningOutcome: resolution.WinningOutcome,
+				ResolvedTS:     resolution.ResolvedTS,
+				Method:         resolution.Method,
+			}
+		}
+	}
+
+	member, err := db.GetClusterMember(ctx, a.WalletAddress)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster member: %w", err)
+	}
+	if member == nil {
+		return detail, nil
+	}
+	cluster, err := db.GetWalletClusterByID(ctx, member.ClusterID)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster: %w", err)
+	}
+	if cluster == nil {
+		return detail, nil
+	}
+	detail.Cluster = newWalletCluster(cluster, member)
+	events, err := db.GetCoordinatedTradesInMarket(ctx, cluster.ClusterID, a.ConditionID, alertDetailCoordinated)
+	if err != nil {
+		return nil, fmt.Errorf("get coordinated trades: %w", err)
+	}
+	for _, e := range events {
+		detail.CoordinatedTrades = append(detail.CoordinatedTrades, newCoordinatedEvent(e))
+	}
+
+	return detail, nil
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeAlerts serves alerts 1..n, newest first, like storage.ListAlerts
+type fakeAlerts struct {
+	n      int64
+	err    error
+	filter storage.AlertFilter
+}
+
+func (f *fakeAlerts) ListAlerts(ctx context.Context, filter storage.AlertFilter) ([]storage.Alert, int64, error) {
+	f.filter = filter
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	var out []storage.Alert
+	for id := f.n; id > 0 && len(out) < filter.Limit; id-- {
+		if filter.BeforeID > 0 && id >= filter.BeforeID {
+			continue
+		}
+		out = append(out, storage.Alert{ID: id, AlertType: "ALERT", ScoreBreakdown: `{"FinalScore":12.5}`})
+	}
+	return out, f.n, nil
+}
+
+func TestListAlerts(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		listErr        error
+		expectedStatus int
+		expectedIDs    []int64
+		expectedCursor string // "" for none
+		description    string
+	}{
+		{"first page", "?limit=2", nil, http.StatusOK, []int64{5, 4}, "4", "A full page links to the next one"},
+		{"next page", "?limit=2&cursor=4", nil, http.StatusOK, []int64{3, 2}, "2", "The cursor continues after the previous page"},
+		{"last page", "?limit=2&cursor=2", nil, http.StatusOK, []int64{1}, "", "The last page has no next cursor"},
+		{"exact last page", "?limit=5", nil, http.StatusOK, []int64{5, 4, 3, 2, 1}, "", "A page that ends exactly at the last alert has no next cursor"},
+		{"bad severity", "?severity=CRITICAL", nil, http.StatusBadRequest, nil, "", "Unknown severities are rejected"},
+		{"bad since", "?since=yesterday", nil, http.StatusBadRequest, nil, "", "Timestamps must be Unix seconds or RFC 3339"},
+		{"inverted range", "?since=2000&until=1000", nil, http.StatusBadRequest, nil, "", "until must be after since"},
+		{"bad limit", "?limit=0", nil, http.StatusBadRequest, nil, "", "limit must be positive"},
+		{"large limit", "?limit=501", nil, http.StatusBadRequest, nil, "", "limit is capped"},
+		{"bad notional", "?min_notional=-1", nil, http.StatusBadRequest, nil, "", "min_notional must not be negative"},
+		{"bad cursor", "?cursor=abc", nil, http.StatusBadRequest, nil, "", "Cursors are alert IDs"},
+		{"bad include", "?include=trades", nil, http.StatusBadRequest, nil, "", "Only breakdowns can be included"},
+		{"storage error", "", errors.New("db down"), http.StatusInternalServerError, nil, "", "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			handler := handleListAlerts(&fakeAlerts{n: 5, err: tt.listErr}, log)
+
+			rec := httptest.NewRecorder()
+			handler(rec, httptest.NewRequest("GET", "/alerts"+tt.query, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("got status %d (%s), want %d\nDescription: %s", rec.Code, rec.Body, tt.expectedStatus, tt.description)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var page alertsPage
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("got %v decoding %s", err, rec.Body)
+			}
+			var ids []int64
+			for _, item := range page.Items {
+				ids = append(ids, item.ID)
+			}
+			cursor := ""
+			if page.NextCursor != nil {
+				cursor = *page.NextCursor
+			}
+			if len(ids) != len(tt.expectedIDs) || cursor != tt.expectedCursor || page.Total != 5 {
+				t.Errorf("got IDs %v, cursor %q, total %d, want %v, %q, 5\nDescription: %s", ids, cursor, page.Total, tt.expectedIDs, tt.expectedCursor, tt.description)
+				return
+			}
+			for i := range ids {
+				if ids[i] != tt.expectedIDs[i] {
+					t.Errorf("got IDs %v, want %v\nDescription: %s", ids, tt.expectedIDs, tt.description)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestListAlertsFilters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	lister := &fakeAlerts{n: 1}
+	handler := handleListAlerts(lister, log)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/alerts?severity=warn&wallet=0xabc&condition_id=0xcond&since=1700000000&until=2024-01-01T00:00:00Z&min_notional=25000&include=breakdown", nil))
+
+	want := storage.AlertFilter{
+		AlertType:   "WARN",
+		Wallet:      "0xabc",
+		ConditionID: "0xcond",
+		SinceTS:     1700000000,
+		UntilTS:     1704067200,
+		MinNotional: 25000,
+		Limit:       defaultPageLimit + 1,
+	}
+	if lister.filter != want {
+		t.Errorf("got filter %+v, want %+v\nDescription: Query parameters map onto the storage filter", lister.filter, want)
+	}
+
+	var page alertsPage
+	json.Unmarshal(rec.Body.Bytes(), &page)
+	if len(page.Items) != 1 || string(page.Items[0].Breakdown) != `{"FinalScore":12.5}` {
+		t.Errorf("got %s, want the stored breakdown\nDescription: include=breakdown adds the score breakdown", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/alerts", nil))
+	var plain alertsPage
+	json.Unmarshal(rec.Body.Bytes(), &plain)
+	if len(plain.Items) != 1 || plain.Items[0].Breakdown != nil {
+		t.Errorf("got %s, want no breakdown\nDescription: Breakdowns are left out by default", rec.Body)
+	}
+}
+
+func TestListAlertsAuth(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	handler := requireToken(handleListAlerts(&fakeAlerts{n: 1}, log), "secret")
+
+	tests := []struct {
+		name           string
+		auth           string
+		expectedStatus int
+		description    string
+	}{
+		{"missing", "", http.StatusUnauthorized, "Requests without the token are rejected"},
+		{"wrong", "Bearer nope", http.StatusUnauthorized, "A wrong token is rejected"},
+		{"valid", "Bearer secret", http.StatusOK, "The configured token is accepted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/alerts", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
+
+// fakeAlertDetailStore holds alert 7 on testWallet, in an unresolved market
+// its cluster traded in together
+type fakeAlertDetailStore struct {
+	fakeWalletStore
+}
+
+func (f *fakeAlertDetailStore) GetAlert(ctx context.Context, id int64) (*storage.Alert, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if id != 7 {
+		return nil, nil
+	}
+	return &storage.Alert{
+		ID: 7, AlertType: "ALERT", WalletAddress: testWallet, ConditionID: "0xcond", MarketSlug: "some-market",
+		WalletAgeDays: 3, TradeTimestampSec: 1700000000, ScoreBreakdown: `{"FinalScore":12.5}`,
+	}, nil
+}
+
+func (f *fakeAlertDetailStore) GetWalletTotalsAt(ctx context.Context, walletAddress string, atTS int64) (*storage.WalletTotals, error) {
+	return &storage.WalletTotals{Trades: 5, VolumeUSD: 42000}, nil
+}
+
+func (f *fakeAlertDetailStore) GetMarketMap(ctx context.Context, conditionID string) (*storage.MarketMap, error) {
+	return &storage.MarketMap{ConditionID: conditionID, MarketTitle: "Some market", MarketSlug: "some-market"}, nil
+}
+
+func (f *fakeAlertDetailStore) GetMarketResolution(ctx context.Context, conditionID string) (*storage.MarketResolution, error) {
+	return nil, nil
+}
+
+func (f *fakeAlertDetailStore) GetCoordinatedTradesInMarket(ctx context.Context, clusterID, conditionID string, limit int) ([]storage.CoordinatedTrade, error) {
+	return []storage.CoordinatedTrade{{ClusterID: clusterID, ConditionID: conditionID, WalletCount: 3}}, nil
+}
+
+func TestAlertDetail(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		storeErr       error
+		expectedStatus int
+		description    string
+	}{
+		{"found", "7", nil, http.StatusOK, "A stored alert returns its detail"},
+		{"unknown", "8", nil, http.StatusNotFound, "An alert storage doesn't know is a 404"},
+		{"not a number", "abc", nil, http.StatusBadRequest, "IDs must be integers"},
+		{"zero", "0", nil, http.StatusBadRequest, "IDs must be positive"},
+		{"storage error", "7", errors.New("db down"), http.StatusInternalServerError, "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			store := &fakeAlertDetailStore{fakeWalletStore{err: tt.storeErr}}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /alerts/{id}", handleAlertDetail(store, log))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/alerts/"+tt.id, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d (%s), want %d\nDescription: %s", rec.Code, rec.Body, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
+
+func TestAlertDetailContext(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /alerts/{id}", handleAlertDetail(&fakeAlertDetailStore{}, log))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/alerts/7", nil))
+
+	var detail alertDetail
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("got %v decoding %s", err, rec.Body)
+	}
+	if string(detail.Breakdown) != `{"FinalScore":12.5}` {
+		t.Errorf("got breakdown %s, want the stored JSON\nDescription: The full score breakdown is always included", detail.Breakdown)
+	}
+	if detail.Wallet.AgeDays != 3 || detail.Wallet.TradesToDate != 5 || detail.Wallet.VolumeToDateUSD != 42000 {
+		t.Errorf("got wallet %+v, want age 3 with 5 trades for $42000\nDescription: The wallet is snapshotted as of the alert", detail.Wallet)
+	}
+	if detail.Market == nil || detail.Market.Title != "Some market" || detail.Market.Resolution != nil {
+		t.Errorf("got market %+v, want the unresolved market\nDescription: Market info is included", detail.Market)
+	}
+	if detail.Cluster == nil || detail.Cluster.ClusterID != "cluster-1" || len(detail.CoordinatedTrades) != 1 {
+		t.Errorf("got cluster %+v with %d coordinated trades, want cluster-1 with 1\nDescription: The cluster's coordinated trades in the market are included", detail.Cluster, len(detail.CoordinatedTrades))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/broadcast"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// alertStreamHeartbeat is how often an idle stream sends a comment, so
+	// proxies don't time the connection out
+	alertStreamHeartbeat = 15 * time.Second
+
+	// alertStreamWriteTimeout bounds each write, so a client that stopped
+	// reading is disconnected instead of holding its handler forever
+	alertStreamWriteTimeout = 10 * time.Second
+)
+
+// alertBackfiller reads the alerts a reconnecting client missed;
+// *storage.DB implements it
+type alertBackfiller interface {
+	GetAlertsAfter(ctx context.Context, afterID int64, limit int) ([]storage.Alert, error)
+}
+
+// handleAlertStream serves GET /alerts/stream: server-sent events carrying
+// each alert as it is stored, in the GET /alerts item format with the alert
+// ID as the event ID. A client reconnecting with Last-Event-ID first gets
+// the alerts it missed from the database. Clients that fall behind the hub's
+// buffer are disconnected and can reconnect to catch up the same way.
+func handleAlertStream(db alertBackfiller, hub *broadcast.Hub[storage.Alert], log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var lastID int64
+		if s := r.Header.Get("Last-Event-ID"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil || id < 0 {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "Last-Event-ID must be an alert ID"})
+				return
+			}
+			lastID = id
+		}
+
+		// Subscribe before the backfill so alerts stored meanwhile aren't missed
+		sub, err := hub.Subscribe()
+		if err != nil {
+			message := "alert stream is shutting down"
+			if errors.Is(err, broadcast.ErrFull) {
+				message = "too many alert stream clients"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]string{"error": message})
+			return
+		}
+		defer sub.Close()
+		metrics.AlertStreamClients.Inc()
+		defer metrics.AlertStreamClients.Dec()
+
+		rc := http.NewResponseController(w)
+		write := func(s string) error {
+			rc.SetWriteDeadline(time.Now().Add(alertStreamWriteTimeout))
+			if _, err := fmt.Fprint(w, s); err != nil {
+				return err
+			}
+			return rc.Flush()
+		}
+		send := func(a storage.Alert) error {
+			data, err := json.Marshal(newAlertItem(a))
+			if err != nil {
+				return err
+			}
+			lastID = a.ID
+			return write(fmt.Sprintf("id: %d\nevent: alert\ndata: %s\n\n", a.ID, data))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering events
+		w.WriteHeader(http.StatusOK)
+		if err := write(": connected\n\n"); err != nil {
+			return
+		}
+
+		if lastID > 0 {
+			for {
+				rows, err := db.GetAlertsAfter(r.Context(), lastID, maxPageLimit)
+				if err != nil {
+					if r.Context().Err() == nil {
+						log.WithError(err).Error("Failed to backfill alert stream")
+					}
+					return
+				}
+				for _, a := range rows {
+					if err := send(a); err != nil {
+						return
+					}
+				}
+				if len(rows) < maxPageLimit {
+					break
+				}
+			}
+		}
+
+		heartbeat := time.NewTicker(alertStreamHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case a, ok := <-sub.C:
+				if !ok {
+					if sub.Dropped() {
+						metrics.AlertStreamDropped.Inc()
+						log.WithField("remote_addr", r.RemoteAddr).Warn("Dropped alert stream client that fell behind")
+					}
+					return
+				}
+				if a.ID <= lastID {
+					continue // Already sent by the backfill
+				}
+				if err := send(a); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := write(": keepalive\n\n"); err != nil {
+					return
+				}
+			case <-r.Context().Done():
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/broadcast"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeBackfill struct {
+	alerts []storage.Alert
+}
+
+func (f *fakeBackfill) GetAlertsAfter(_ context.Context, afterID int64, limit int) ([]storage.Alert, error) {
+	var out []storage.Alert
+	for _, a := range f.alerts {
+		if a.ID > afterID && len(out) < limit {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+// readEventIDs reads events from an SSE body until it has n IDs
+func readEventIDs(t *testing.T, sc *bufio.Scanner, n int) []string {
+	t.Helper()
+	var ids []string
+	for len(ids) < n && sc.Scan() {
+		if id, ok := strings.CutPrefix(sc.Text(), "id: "); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func TestHandleAlertStream(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	db := &fakeBackfill{alerts: []storage.Alert{{ID: 1}, {ID: 2}, {ID: 3}}}
+	hub := broadcast.New[storage.Alert](1, 8)
+	srv := httptest.NewServer(handleAlertStream(db, hub, log))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got %d %q, want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	sc := bufio.NewScanner(resp.Body)
+
+	// Alerts after Last-Event-ID come from the database first
+	if got := readEventIDs(t, sc, 2); strings.Join(got, ",") != "2,3" {
+		t.Errorf("got backfill %v, want 2,3", got)
+	}
+
+	// Then live alerts, skipping any the backfill already sent
+	hub.Publish(storage.Alert{ID: 3})
+	hub.Publish(storage.Alert{ID: 4, AlertType: "ALERT"})
+	if got := readEventIDs(t, sc, 1); strings.Join(got, ",") != "4" {
+		t.Errorf("got live %v, want 4", got)
+	}
+	if sc.Scan(); sc.Text() != "event: alert" {
+		t.Errorf("got %q, want the alert event type", sc.Text())
+	}
+	if sc.Scan(); !strings.Contains(sc.Text(), `"severity":"ALERT"`) {
+		t.Errorf("got %q, want the alert as JSON", sc.Text())
+	}
+
+	// The hub allows one client, which is connected
+	rec := httptest.NewRecorder()
+	full := httptest.NewRequest("GET", "/alerts/stream", nil)
+	handleAlertStream(db, hub, log).ServeHTTP(rec, full)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d for a client over the limit, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+
+	// Closing the hub ends the stream
+	hub.Close()
+	done := make(chan struct{})
+	go func() {
+		for sc.Scan() {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("stream still open after the hub closed")
+	}
+}
+
+func TestHandleAlertStreamRejects(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name           string
+		auth           string
+		lastEventID    string
+		expectedStatus int
+		description    string
+	}{
+		{"no token", "", "", http.StatusUnauthorized, "The stream needs the API token"},
+		{"bad id", "Bearer secret", "abc", http.StatusBadRequest, "Last-Event-ID must be numeric"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/alerts/stream", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			if tt.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tt.lastEventID)
+			}
+			rec := httptest.NewRecorder()
+			requireToken(handleAlertStream(&fakeBackfill{}, broadcast.New[storage.Alert](1, 1), log), "secret").ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// backfillOptions are the flags of `insiderwatch backfill`
+type backfillOptions struct {
+	since      timeFlag
+	until      timeFlag
+	sendAlerts bool
+}
+
+// parseBackfillFlags parses the backfill flags, writing errors and usage
+// to out
+func parseBackfillFlags(args []string, out io.Writer) (*backfillOptions, error) {
+	opts := &backfillOptions{}
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Var(&opts.since, "since", "Start of the window: a duration before now (24h), Unix timestamp, RFC 3339 time or date (required)")
+	fs.Var(&opts.until, "until", "End of the window, in the same forms (default now)")
+	fs.BoolVar(&opts.sendAlerts, "send-alerts", false, "Send alerts through ALERT_MODE; by default they are stored and only logged")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if opts.since.t.IsZero() {
+		return nil, errors.New("--since is required")
+	}
+	if !opts.until.t.IsZero() && !opts.until.t.After(opts.since.t) {
+		return nil, errors.New("--until must be after --since")
+	}
+	return opts, nil
+}
+
+// runBackfillCommand implements `insiderwatch backfill`, which processes
+// the trades of a past window through the normal pipeline without moving
+// the poll checkpoint, to fill a gap left by downtime. Alerts for old trades
+// are stored but not sent unless --send-alerts is given. SIGINT stops it
+// early.
+func runBackfillCommand(args []string) int {
+	opts, err := parseBackfillFlags(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backfill: %v\n", err)
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stdout, logrus.InfoLevel)
+	cfg, err := bootstrap.Config(log, false)
+	if err != nil {
+		log.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+	flush, err := bootstrap.Observability(cfg, log)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up observability")
+		return 1
+	}
+	defer flush()
+	db, err := bootstrap.Database(cfg, log, false)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up database")
+		return 1
+	}
+	defer db.Close()
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up API clients")
+		return 1
+	}
+
+	var sender alerts.Sender = alerts.NewLogSender(log, cfg.DisplayLocation())
+	if opts.sendAlerts {
+		sender = createAlertSender(cfg, cfg.AlertMode, log)
+	}
+	proc := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, sender, log)
+	var profileSenders map[string]alerts.Sender
+	if opts.sendAlerts {
+		profileSenders = createProfileSenders(cfg, log)
+		proc.SetProfileSenders(profileSenders)
+	}
+	if cfg.CustomRulesFile != "" {
+		if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+			log.WithError(err).Error("Failed to load custom rules")
+			return 1
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	summary, err := proc.Backfill(ctx, opts.since.t, opts.until.t)
+	flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+	if err := flushAlerts(flushCtx, sender, profileSenders); err != nil {
+		log.WithError(err).Warn("Failed to send alert overflow summaries")
+	}
+	if err != nil {
+		log.WithError(err).Error("Backfill failed")
+		return 1
+	}
+	if summary.CutShort != "" {
+		log.WithField("status", summary.CutShort).Error("Backfill cut short; run it again to process the remaining trades")
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// backtestOptions are the flags of `insiderwatch backtest`
+type backtestOptions struct {
+	fixtures string
+	results  string
+	json     bool
+}
+
+// parseBacktestFlags parses the backtest flags, writing errors and usage
+// to out
+func parseBacktestFlags(args []string, out io.Writer) (*backtestOptions, error) {
+	opts := &backtestOptions{}
+	fs := flag.NewFlagSet("backtest", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.StringVar(&opts.fixtures, "fixtures", "backtest", "Directory of scenario files (*.json)")
+	fs.StringVar(&opts.results, "results", "backtest-results.json", "File the previous run's results are read from and this run's written to, or empty to neither compare nor save")
+	fs.BoolVar(&opts.json, "json", false, "Print the report and changes as JSON")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return opts, nil
+}
+
+// runBacktestCommand implements `insiderwatch backtest`, which scores the
+// labeled scenario trades with the current configuration and custom rules,
+// reports how many alerted as labeled and what changed since the previous
+// run. It returns the process exit code.
+func runBacktestCommand(args []string) int {
+	opts, err := parseBacktestFlags(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "backtest: %v\n", err)
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stderr, logrus.WarnLevel)
+	cfg, err := bootstrap.Config(log, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load configuration: %v\n", err)
+		return 1
+	}
+	scenarios, err := processor.LoadScenarios(opts.fixtures)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load scenarios: %v\n", err)
+		return 1
+	}
+
+	// Like score, the backtest needs neither storage nor API clients
+	proc := processor.New(cfg, nil, nil, nil, nil, nil, nil, log)
+	if cfg.CustomRulesFile != "" {
+		if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+			fmt.Fprintf(os.Stderr, "load custom rules: %v\n", err)
+			return 1
+		}
+	}
+	report := proc.Backtest(context.Background(), scenarios)
+
+	var previous *processor.BacktestReport
+	if opts.results != "" {
+		if previous, err = readBacktestResults(opts.results); err != nil {
+			fmt.Fprintf(os.Stderr, "read previous results: %v\n", err)
+			return 1
+		}
+	}
+	var changes []processor.BacktestChange
+	if previous != nil {
+		changes = processor.DiffBacktest(previous.Results, report.Results)
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(struct {
+			*processor.BacktestReport
+			Precision float64                    `json:"precision"`
+			Recall    float64                    `json:"recall"`
+			Changes   []processor.BacktestChange `json:"changes"`
+		}{report, report.Precision(), report.Recall(), changes})
+	} else {
+		err = printBacktest(os.Stdout, report, previous, changes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "write report: %v\n", err)
+		return 1
+	}
+
+	if opts.results != "" {
+		if err := writeBacktestResults(opts.results, report); err != nil {
+			fmt.Fprintf(os.Stderr, "save results: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
+
+// readBacktestResults reads a previous run's report, or returns nil when
+// there is none
+func readBacktestResults(path string) (*processor.BacktestReport, error) {
+	body, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var report processor.BacktestReport
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &report, nil
+}
+
+func writeBacktestResults(path string, report *processor.BacktestReport) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(body, '\n'), 0o644)
+}
+
+// printBacktest writes the labeled trades' results, the totals, and the
+// changes since the previous run
+func printBacktest(w io.Writer, report *processor.BacktestReport, previous *processor.BacktestReport, changes []processor.BacktestChange) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCENARIO\tTRADE\tEXPECTED\tGOT\tSCORE\tRULES")
+	for i := range report.Results {
+		r := &report.Results[i]
+		if r.ShouldAlert == nil {
+			continue
+		}
+		mark := ""
+		if !r.Correct() {
+			mark = "  <- mismatch"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%.1f\t%s%s\n", r.Scenario, r.Trade, expectedLabel(*r.ShouldAlert), backtestOutcome(r), r.Score, strings.Join(r.FiredRules, ","), mark)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "\nLabeled trades: %d alerted as expected, %d missed, %d false alarms, %d quiet as expected\n",
+		report.TruePositives, report.FalseNegatives, report.FalsePositives, report.TrueNegatives)
+	fmt.Fprintf(w, "Precision: %.2f  Recall: %.2f\n", report.Precision(), report.Recall())
+
+	if previous == nil {
+		fmt.Fprintln(w, "\nNo previous run to compare with")
+		return nil
+	}
+	fmt.Fprintf(w, "Previous run: precision %.2f, recall %.2f\n", previous.Precision(), previous.Recall())
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "\nNo changes since the previous run")
+		return nil
+	}
+	fmt.Fprintf(w, "\nChanges since the previous run (%d):\n", len(changes))
+	for _, c := range changes {
+		switch {
+		case c.Before == nil:
+			fmt.Fprintf(w, "  %s: new, %s %.1f\n", c.Key, backtestOutcome(c.After), c.After.Score)
+		case c.After == nil:
+			fmt.Fprintf(w, "  %s: removed, was %s %.1f\n", c.Key, backtestOutcome(c.Before), c.Before.Score)
+		default:
+			fmt.Fprintf(w, "  %s: %s %.1f -> %s %.1f\n", c.Key, backtestOutcome(c.Before), c.Before.Score, backtestOutcome(c.After), c.After.Score)
+		}
+	}
+	return nil
+}
+
+func expectedLabel(shouldAlert bool) string {
+	if shouldAlert {
+		return "alert"
+	}
+	return "quiet"
+}
+
+// backtestOutcome describes what a trade produced: its severity, marked
+// record-only, or why it was filtered out
+func backtestOutcome(r *processor.BacktestResult) string {
+	switch {
+	case r.Filtered != "":
+		return "filtered:" + r.Filtered
+	case r.RecordOnly:
+		return string(r.Severity) + "(record-only)"
+	}
+	return string(r.Severity)
+}
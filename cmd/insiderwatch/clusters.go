@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// clusterDetailEvents caps the coordinated trade events in a cluster detail
+const clusterDetailEvents = 50
+
+// clusterReader reads wallet clusters; *storage.DB implements it
+type clusterReader interface {
+	ListClusters(ctx context.Context, filter storage.ClusterFilter) ([]storage.WalletCluster, int64, error)
+	GetWalletClusterByID(ctx context.Context, clusterID string) (*storage.WalletCluster, error)
+	GetClusterMemberDetails(ctx context.Context, clusterID string) ([]storage.ClusterMemberDetail, error)
+	GetCoordinatedTrades(ctx context.Context, clusterID string, limit int) ([]storage.CoordinatedTrade, error)
+}
+
+// clusterSummary is one cluster in a GET /clusters response
+type clusterSummary struct {
+	ClusterID             string  `json:"cluster_id"`
+	LinkType              string  `json:"link_type"` // funding, withdrawal or both
+	FundingSource         string  `json:"funding_source"`
+	WithdrawalDestination string  `json:"withdrawal_destination"`
+	WalletCount           int     `json:"wallet_count"`
+	TotalVolumeUSD        float64 `json:"total_volume_usd"`
+	SuspicionScore        float64 `json:"suspicion_score"`
+	IsFlagged             bool    `json:"is_flagged"`
+	FirstSeenTS           int64   `json:"first_seen_ts"`
+	LastActivityTS        int64   `json:"last_activity_ts"`
+}
+
+func newClusterSummary(c storage.WalletCluster) clusterSummary {
+	return clusterSummary{
+		ClusterID:             c.ClusterID,
+		LinkType:              c.LinkType,
+		FundingSource:         c.FundingSource,
+		WithdrawalDestination: c.WithdrawalDestination,
+		WalletCount:           c.WalletCount,
+		TotalVolumeUSD:        c.TotalVolumeUSD,
+		SuspicionScore:        c.SuspicionScore,
+		IsFlagged:             c.IsFlagged,
+		FirstSeenTS:           c.FirstSeenTS,
+		LastActivityTS:        c.LastActivityTS,
+	}
+}
+
+// clustersPage is the GET /clusters response envelope
+type clustersPage struct {
+	Items      []clusterSummary `json:"items"`
+	Total      int64            `json:"total"`
+	NextCursor *string          `json:"next_cursor"`
+}
+
+// clusterDetail is the GET /clusters/{id} response
+type clusterDetail struct {
+	clusterSummary
+	MergedInto        string             `json:"merged_into,omitempty"` // Set when another cluster absorbed this one; its members moved there
+	MemberVolumeUSD   float64            `json:"member_volume_usd"`     // Sum of the members' tracked volume
+	MemberTrades      int                `json:"member_trades"`
+	Members           []clusterMember    `json:"members"`
+	CoordinatedTrades []coordinatedEvent `json:"coordinated_trades"`
+}
+
+type clusterMember struct {
+	Wallet         string  `json:"wallet"`
+	LinkType       string  `json:"link_type"`
+	JoinedTS       int64   `json:"joined_ts"`
+	FirstSeenTS    int64   `json:"first_seen_ts"`
+	TotalTrades    int     `json:"total_trades"`
+	TotalVolumeUSD float64 `json:"total_volume_usd"`
+	LastActivityTS int64   `json:"last_activity_ts"`
+	ResolvedTrades int     `json:"resolved_trades"`
+	WinRate        float64 `json:"win_rate"`
+	RealizedPnLUSD float64 `json:"realized_pnl_usd"`
+}
+
+type coordinatedEvent struct {
+	ConditionID      string  `json:"condition_id"`
+	MarketTitle      string  `json:"market_title"`
+	Pattern          string  `json:"pattern"` // same_side or opposing
+	WalletCount      int     `json:"wallet_count"`
+	TotalNotionalUSD float64 `json:"total_notional_usd"`
+	TimeWindowSec    int     `json:"time_window_sec"`
+	FirstTradeTS     int64   `json:"first_trade_ts"`
+	LastTradeTS      int64   `json:"last_trade_ts"`
+}
+
+// newCoordinatedEvent converts a stored coordinated trade event
+func newCoordinatedEvent(e storage.CoordinatedTrade) coordinatedEvent {
+	return coordinatedEvent{
+		ConditionID:      e.ConditionID,
+		MarketTitle:      e.MarketTitle,
+		Pattern:          e.PatternType,
+		WalletCount:      e.WalletCount,
+		TotalNotionalUSD: e.TotalNotionalUSD,
+		TimeWindowSec:    e.TimeWindowSec,
+		FirstTradeTS:     e.FirstTradeTS,
+		LastTradeTS:      e.LastTradeTS,
+	}
+}
+
+// handleListClusters serves GET /clusters: clusters still in use, most
+// suspicious first.
+func handleListClusters(db clusterReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		filter, err := parseClustersQuery(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		// One extra row tells whether there is another page
+		limit := filter.Limit
+		filter.Limit++
+		rows, total, err := db.ListClusters(r.Context(), filter)
+		if err != nil {
+			log.WithError(err).Error("Failed to list clusters")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to list clusters"})
+			return
+		}
+
+		page := clustersPage{Items: make([]clusterSummary, 0, min(len(rows), limit)), Total: total}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			cursor := strconv.Itoa(filter.Offset + limit)
+			page.NextCursor = &cursor
+		}
+		for _, c := range rows {
+			page.Items = append(page.Items, newClusterSummary(c))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// parseClustersQuery turns GET /clusters query parameters into a filter
+func parseClustersQuery(q url.Values) (storage.ClusterFilter, error) {
+	filter := storage.ClusterFilter{Limit: defaultPageLimit}
+
+	var err error
+	if s := q.Get("min_wallets"); s != "" {
+		if filter.MinWallets, err = strconv.Atoi(s); err != nil || filter.MinWallets < 1 {
+			return filter, fmt.Errorf("min_wallets must be a positive integer")
+		}
+	}
+	if s := q.Get("flagged"); s != "" {
+		if filter.FlaggedOnly, err = strconv.ParseBool(s); err != nil {
+			return filter, fmt.Errorf("flagged must be true or false")
+		}
+	}
+	if s := q.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 1 || filter.Limit > maxPageLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	// Clusters are ranked by score, which changes, so the cursor is an
+	// offset into the ranking
+	if s := q.Get("cursor"); s != "" {
+		if filter.Offset, err = strconv.Atoi(s); err != nil || filter.Offset < 0 {
+			return filter, fmt.Errorf("cursor must be a next_cursor from a previous page")
+		}
+	}
+	return filter, nil
+}
+
+// handleClusterDetail serves GET /clusters/{id}: the cluster with its
+// members' stats and coordinated trade events.
+func handleClusterDetail(db clusterReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		clusterID := r.PathValue("id")
+		detail, err := loadClusterDetail(r.Context(), db, clusterID)
+		if err != nil {
+			log.WithError(err).WithField("cluster_id", clusterID).Error("Failed to load cluster")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to load cluster"})
+			return
+		}
+		if detail == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "cluster not found"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// loadClusterDetail composes a cluster's storage records. It returns nil
+// for an unknown cluster.
+func loadClusterDetail(ctx context.Context, db clusterReader, clusterID string) (*clusterDetail, error) {
+	cluster, err := db.GetWalletClusterByID(ctx, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster: %w", err)
+	}
+	if cluster == nil {
+		return nil, nil
+	}
+
+	detail := &clusterDetail{
+		clusterSummary:    newClusterSummary(*cluster),
+		MergedInto:        cluster.MergedInto,
+		Members:           []clusterMember{},
+		CoordinatedTrades: []coordinatedEvent{},
+	}
+
+	members, err := db.GetClusterMemberDetails(ctx, clusterID)
+	if err != nil {
+		return nil, fmt.Errorf("get members: %w", err)
+	}
+	for _, m := range members {
+		detail.MemberVolumeUSD += m.TotalVolumeUSD
+		detail.MemberTrades += m.TotalTrades
+		detail.Members = append(detail.Members, clusterMember{
+			Wallet:         m.WalletAddress,
+			LinkType:       m.LinkType,
+			JoinedTS:       m.JoinedTS,
+			FirstSeenTS:    m.FirstSeenTS,
+			TotalTrades:    m.TotalTrades,
+			TotalVolumeUSD: m.TotalVolumeUSD,
+			LastActivityTS: m.LastActivityTS,
+			ResolvedTrades: m.TotalResolvedTrades,
+			WinRate:        m.WinRate,
+			RealizedPnLUSD: m.TotalProfitUSD,
+		})
+	}
+
+	events, err := db.GetCoordinatedTrades(ctx, clusterID, clusterDetailEvents)
+	if err != nil {
+		return nil, fmt.Errorf("get coordinated trades: %w", err)
+	}
+	for _, e := range events {
+		detail.CoordinatedTrades = append(detail.CoordinatedTrades, newCoordinatedEvent(e))
+	}
+
+	return detail, nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeClusters serves n clusters, most suspicious first, like
+// storage.ListClusters, and one known cluster "cluster-1" in detail
+type fakeClusters struct {
+	n      int
+	err    error
+	filter storage.ClusterFilter
+}
+
+func (f *fakeClusters) ListClusters(ctx context.Context, filter storage.ClusterFilter) ([]storage.WalletCluster, int64, error) {
+	f.filter = filter
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	var out []storage.WalletCluster
+	for i := filter.Offset; i < f.n && len(out) < filter.Limit; i++ {
+		out = append(out, storage.WalletCluster{ClusterID: fmt.Sprintf("cluster-%d", i+1), SuspicionScore: float64(f.n - i)})
+	}
+	return out, int64(f.n), nil
+}
+
+func (f *fakeClusters) GetWalletClusterByID(ctx context.Context, clusterID string) (*storage.WalletCluster, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if clusterID != "cluster-1" {
+		return nil, nil
+	}
+	return &storage.WalletCluster{ClusterID: clusterID, FundingSource: "0xfunder", LinkType: storage.LinkFunding, WalletCount: 2, IsFlagged: true}, nil
+}
+
+func (f *fakeClusters) GetClusterMemberDetails(ctx context.Context, clusterID string) ([]storage.ClusterMemberDetail, error) {
+	return []storage.ClusterMemberDetail{
+		{WalletAddress: "0xa", LinkType: storage.LinkFunding, TotalTrades: 3, TotalVolumeUSD: 60000, WinRate: 0.75},
+		{WalletAddress: "0xb", LinkType: storage.LinkFunding, TotalTrades: 1, TotalVolumeUSD: 15000},
+	}, nil
+}
+
+func (f *fakeClusters) GetCoordinatedTrades(ctx context.Context, clusterID string, limit int) ([]storage.CoordinatedTrade, error) {
+	return []storage.CoordinatedTrade{{ClusterID: clusterID, ConditionID: "0xcond", PatternType: "same_side", WalletCount: 2}}, nil
+}
+
+func TestListClusters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name           string
+		query          string
+		storeErr       error
+		expectedStatus int
+		expectedItems  int
+		expectedNext   string // Empty for the last page
+		description    string
+	}{
+		{"default page", "", nil, http.StatusOK, 50, "50", "Without a limit a page holds 50 clusters"},
+		{"limit", "?limit=10", nil, http.StatusOK, 10, "10", "The next cursor is the offset of the following page"},
+		{"cursor", "?limit=10&cursor=60", nil, http.StatusOK, 10, "70", "A cursor continues from its offset"},
+		{"last page", "?limit=10&cursor=70", nil, http.StatusOK, 5, "", "The last page has no next cursor"},
+		{"past the end", "?cursor=500", nil, http.StatusOK, 0, "", "A cursor past the end is an empty page"},
+		{"bad min wallets", "?min_wallets=0", nil, http.StatusBadRequest, 0, "", "min_wallets must be positive"},
+		{"bad flagged", "?flagged=maybe", nil, http.StatusBadRequest, 0, "", "flagged must be a boolean"},
+		{"bad limit", "?limit=501", nil, http.StatusBadRequest, 0, "", "limit is capped at 500"},
+		{"bad cursor", "?cursor=-1", nil, http.StatusBadRequest, 0, "", "cursor must be a non-negative offset"},
+		{"storage error", "", errors.New("db down"), http.StatusInternalServerError, 0, "", "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeClusters{n: 75, err: tt.storeErr}
+			rec := httptest.NewRecorder()
+			handleListClusters(store, log)(rec, httptest.NewRequest("GET", "/clusters"+tt.query, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var page clustersPage
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			next := ""
+			if page.NextCursor != nil {
+				next = *page.NextCursor
+			}
+			if len(page.Items) != tt.expectedItems || next != tt.expectedNext || page.Total != 75 {
+				t.Errorf("got %d items, next %q, total %d, want %d items, next %q, total 75\nDescription: %s",
+					len(page.Items), next, page.Total, tt.expectedItems, tt.expectedNext, tt.description)
+			}
+		})
+	}
+}
+
+func TestListClustersFilters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	store := &fakeClusters{n: 1}
+
+	rec := httptest.NewRecorder()
+	handleListClusters(store, log)(rec, httptest.NewRequest("GET", "/clusters?min_wallets=3&flagged=true&limit=5&cursor=20", nil))
+
+	want := storage.ClusterFilter{MinWallets: 3, FlaggedOnly: true, Offset: 20, Limit: 6}
+	if store.filter != want {
+		t.Errorf("got filter %+v, want %+v", store.filter, want)
+	}
+}
+
+func TestClusterDetail(t *testing.T) {
+	tests := []struct {
+		name           string
+		id             string
+		storeErr       error
+		expectedStatus int
+		description    string
+	}{
+		{"known", "cluster-1", nil, http.StatusOK, "A known cluster returns its detail"},
+		{"unknown", "cluster-9", nil, http.StatusNotFound, "A cluster storage doesn't know is a 404"},
+		{"storage error", "cluster-1", errors.New("db down"), http.StatusInternalServerError, "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /clusters/{id}", handleClusterDetail(&fakeClusters{err: tt.storeErr}, log))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/"+tt.id, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
+
+func TestClusterDetailComposition(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /clusters/{id}", handleClusterDetail(&fakeClusters{}, log))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/clusters/cluster-1", nil))
+
+	var got clusterDetail
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.FundingSource != "0xfunder" || len(got.Members) != 2 || len(got.CoordinatedTrades) != 1 {
+		t.Errorf("got funding %q, %d members, %d coordinated trades, want 0xfunder, 2 and 1",
+			got.FundingSource, len(got.Members), len(got.CoordinatedTrades))
+	}
+	if got.MemberVolumeUSD != 75000 || got.MemberTrades != 4 {
+		t.Errorf("got member volume %.0f over %d trades, want 75000 over 4", got.MemberVolumeUSD, got.MemberTrades)
+	}
+	if got.Members[0].WinRate != 0.75 {
+		t.Errorf("got member win rate %v, want 0.75", got.Members[0].WinRate)
+	}
+}
+
+func TestClustersAuth(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		path        string
+		description string
+	}{
+		{"list", handleListClusters(&fakeClusters{n: 1}, log), "/clusters", "The list needs the read token"},
+		{"detail", handleClusterDetail(&fakeClusters{}, log), "/clusters/cluster-1", "The detail needs the read token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			requireToken(tt.handler, "secret").ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, http.StatusUnauthorized, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/version"
+)
+
+// command is an insiderwatch subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) int // Returns the process exit code
+}
+
+// commands lists the subcommands in the order usage prints them; serve
+// runs when none is named
+var commands = []command{
+	{"serve", "Run the service (the default)", runServeCommand},
+	{"migrate", "Create or update the database schema and exit", runMigrateCommand},
+	{"backfill", "Process the trades of a past window, for gaps after downtime", runBackfillCommand},
+	{"score", "Print the score breakdown of a hypothetical trade", runScoreCommand},
+	{"backtest", "Score labeled scenario trades and compare with the previous run", runBacktestCommand},
+	{"export-alerts", "Write stored alerts as CSV or JSON", runExportAlertsCommand},
+	{"seed", "Fill an empty database with synthetic data for demos and development", runSeedCommand},
+	{"healthcheck", "Probe a running service's /ready endpoint", runHealthcheckCommand},
+}
+
+// run dispatches args to a subcommand and returns the process exit code.
+// Arguments starting with a flag go to serve, so `insiderwatch` and
+// `insiderwatch --skip-migrate` keep starting the service.
+func run(args []string) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	switch {
+	case len(args) > 0 && (args[0] == "--version" || args[0] == "-version") && name == "serve":
+		fmt.Println(version.String())
+		return 0
+	case name == "help":
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args)
+		}
+	}
+	fmt.Fprintf(os.Stderr, "insiderwatch: unknown command %q\n\n", name)
+	printUsage(os.Stderr)
+	return 2
+}
+
+// printUsage lists the subcommands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: insiderwatch [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-14s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run `insiderwatch <command> -h` for a command's flags.")
+}
+
+// timeFlag is a flag holding a point in time, given as a duration before
+// now (24h), a Unix timestamp in seconds, an RFC 3339 time or a date
+type timeFlag struct {
+	t   time.Time
+	now func() time.Time
+}
+
+func (f *timeFlag) String() string {
+	if f.t.IsZero() {
+		return ""
+	}
+	return f.t.UTC().Format(time.RFC3339)
+}
+
+func (f *timeFlag) Set(s string) error {
+	now := time.Now
+	if f.now != nil {
+		now = f.now
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		f.t = now().Add(-d)
+		return nil
+	}
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil && ts > 0 {
+		f.t = time.Unix(ts, 0)
+		return nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		f.t = t
+		return nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		f.t = t
+		return nil
+	}
+	return fmt.Errorf("want a duration like 24h, a Unix timestamp, an RFC 3339 time or a date")
+}
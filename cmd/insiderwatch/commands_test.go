@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+)
+
+func TestRunUsageErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		description  string
+	}{
+		{"unknown command", []string{"replay"}, 2, "Unknown commands print usage and fail"},
+		{"help", []string{"help"}, 0, "help prints usage"},
+		{"serve argument", []string{"serve", "extra"}, 2, "serve takes no arguments"},
+		{"serve flag", []string{"--no-such-flag"}, 2, "Flags without a command go to serve"},
+		{"migrate argument", []string{"migrate", "up"}, 2, "migrate takes no arguments"},
+		{"backfill without since", []string{"backfill"}, 2, "backfill needs --since"},
+		{"score bad flag", []string{"score", "--notional=abc"}, 2, "score rejects malformed flags"},
+		{"export bad format", []string{"export-alerts", "--format=xml"}, 2, "export-alerts rejects unknown formats"},
+		{"healthcheck bad flag", []string{"healthcheck", "--port"}, 2, "healthcheck rejects unknown flags"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := run(tt.args); code != tt.expectedCode {
+				t.Errorf("got exit code %d, want %d\nDescription: %s", code, tt.expectedCode, tt.description)
+			}
+		})
+	}
+}
+
+func TestTimeFlag(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		value         string
+		expected      time.Time
+		expectedError bool
+		description   string
+	}{
+		{"duration", "24h", now.Add(-24 * time.Hour), false, "A duration counts back from now"},
+		{"unix", "1748736000", time.Unix(1748736000, 0), false, "Unix seconds are accepted"},
+		{"rfc3339", "2025-05-30T08:00:00Z", time.Date(2025, 5, 30, 8, 0, 0, 0, time.UTC), false, "RFC 3339 times are accepted"},
+		{"date", "2025-05-30", time.Date(2025, 5, 30, 0, 0, 0, 0, time.UTC), false, "A date means its midnight UTC"},
+		{"negative duration", "-1h", time.Time{}, true, "Durations must be positive"},
+		{"garbage", "yesterday", time.Time{}, true, "Other values are rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := timeFlag{now: func() time.Time { return now }}
+			err := f.Set(tt.value)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if !f.t.Equal(tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", f.t, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseBackfillFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		args          []string
+		expectedError bool
+		expectedSend  bool
+		description   string
+	}{
+		{"since", []string{"--since", "6h"}, false, false, "Alerts are only logged by default"},
+		{"send alerts", []string{"--since", "6h", "--send-alerts"}, false, true, "--send-alerts uses ALERT_MODE"},
+		{"window", []string{"--since", "2025-05-01", "--until", "2025-05-02"}, false, false, "A closed window is accepted"},
+		{"inverted window", []string{"--since", "2025-05-02", "--until", "2025-05-01"}, true, false, "until must be after since"},
+		{"missing since", []string{"--until", "2025-05-01"}, true, false, "since is required"},
+		{"argument", []string{"--since", "6h", "extra"}, true, false, "Positional arguments are rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseBackfillFlags(tt.args, io.Discard)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if err == nil && opts.sendAlerts != tt.expectedSend {
+				t.Errorf("got send alerts %v, want %v\nDescription: %s", opts.sendAlerts, tt.expectedSend, tt.description)
+			}
+		})
+	}
+
+	if _, err := parseBackfillFlags([]string{"-h"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("got %v for -h, want flag.ErrHelp", err)
+	}
+}
+
+func TestParseExportFlags(t *testing.T) {
+	tests := []struct {
+		name             string
+		args             []string
+		expectedError    bool
+		expectedSeverity string
+		expectedFormat   string
+		description      string
+	}{
+		{"defaults", nil, false, "", "csv", "CSV of every alert by default"},
+		{"json severity", []string{"--format", "json", "--severity", "warn"}, false, "WARN", "json", "Severities are case-insensitive"},
+		{"bad severity", []string{"--severity", "CRITICAL"}, true, "", "", "Unknown severities are rejected"},
+		{"bad format", []string{"--format", "xml"}, true, "", "", "Only csv and json are supported"},
+		{"inverted window", []string{"--since", "1h", "--until", "2h"}, true, "", "", "until must be after since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseExportFlags(tt.args, io.Discard)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if err == nil && (opts.severity != tt.expectedSeverity || opts.format != tt.expectedFormat) {
+				t.Errorf("got %q as %s, want %q as %s\nDescription: %s", opts.severity, opts.format, tt.expectedSeverity, tt.expectedFormat, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseSeedFlags(t *testing.T) {
+	tests := []struct {
+		name            string
+		args            []string
+		expectedError   bool
+		expectedSeed    int64
+		expectedWallets int
+		description     string
+	}{
+		{"defaults", nil, false, 1, 300, "Seed 1 and 300 wallets by default"},
+		{"custom", []string{"--seed", "42", "--wallets", "50"}, false, 42, 50, "Seed and wallet count are configurable"},
+		{"too few wallets", []string{"--wallets", "5"}, true, 0, 0, "Clusters alone need more than a handful of wallets"},
+		{"extra argument", []string{"now"}, true, 0, 0, "Positional arguments are rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := parseSeedFlags(tt.args, io.Discard)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if err == nil && (opts.seed != tt.expectedSeed || opts.wallets != tt.expectedWallets) {
+				t.Errorf("got seed %d with %d wallets, want %d with %d\nDescription: %s", opts.seed, opts.wallets, tt.expectedSeed, tt.expectedWallets, tt.description)
+			}
+		})
+	}
+}
+
+func TestExportAlerts(t *testing.T) {
+	tests := []struct {
+		name        string
+		alerts      int64
+		format      string
+		description string
+	}{
+		{"csv", 3, "csv", "Every alert is written after the header"},
+		{"json", 3, "json", "Every alert is written to the array"},
+		{"paged", exportPageSize + 20, "csv", "Exports continue past the first page"},
+		{"empty json", 0, "json", "No alerts is an empty array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			n, err := exportAlerts(context.Background(), &fakeAlerts{n: tt.alerts}, storage.AlertFilter{}, tt.format, &buf)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if int64(n) != tt.alerts {
+				t.Errorf("got %d alerts exported, want %d\nDescription: %s", n, tt.alerts, tt.description)
+			}
+
+			var rows int
+			if tt.format == "csv" {
+				records, err := csv.NewReader(&buf).ReadAll()
+				if err != nil {
+					t.Fatalf("got %v reading CSV, want valid CSV", err)
+				}
+				rows = len(records) - 1
+				if records[0][0] != "id" || len(records[0]) != len(exportColumns) {
+					t.Errorf("got header %v, want exportColumns", records[0])
+				}
+			} else {
+				var items []alertItem
+				if err := json.Unmarshal(buf.Bytes(), &items); err != nil {
+					t.Fatalf("got %v decoding %q, want a JSON array", err, buf.String())
+				}
+				rows = len(items)
+			}
+			if int64(rows) != tt.alerts {
+				t.Errorf("got %d rows, want %d\nDescription: %s", rows, tt.alerts, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	dashboardRefresh  = 30 * time.Second
+	dashboardAlerts   = 50
+	dashboardWallets  = 15
+	dashboardClusters = 15
+
+	// dashboardCookie holds the API token after a ?token= sign-in, since a
+	// browser can't attach a bearer header to a page load or meta refresh
+	dashboardCookie = "insiderwatch_token"
+)
+
+//go:embed dashboard
+var dashboardAssets embed.FS
+
+var dashboardTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"addressURL": func(address string) string { return "https://polygonscan.com/address/" + address },
+	"txURL":      func(hash string) string { return "https://polygonscan.com/tx/" + hash },
+	"unixTime": func(ts int64, loc *time.Location) string {
+		return time.Unix(ts, 0).In(loc).Format("2006-01-02 15:04 MST")
+	},
+	"usd":   formatUSD,
+	"lower": strings.ToLower,
+	"short": shortHex,
+	"deref": func(s *string) string { return *s },
+	"duration": func(sec int64) string {
+		return (time.Duration(sec) * time.Second).String()
+	},
+}).ParseFS(dashboardAssets, "dashboard/index.html"))
+
+// dashboardReader runs the storage queries behind the dashboard; *storage.DB
+// implements it
+type dashboardReader interface {
+	alertLister
+	statsReader
+	ListClusters(ctx context.Context, filter storage.ClusterFilter) ([]storage.WalletCluster, int64, error)
+	GetTopAlertedWallets(ctx context.Context, sinceTS int64, limit int) ([]storage.WalletAlertSummary, error)
+}
+
+// dashboardData is what the dashboard template renders
+type dashboardData struct {
+	RefreshSec  int
+	GeneratedAt string
+	Location    *time.Location // DISPLAY_TIMEZONE, which the page's times are in
+	Stats       serviceStats
+	Alerts      []storage.Alert
+	Wallets     []storage.WalletAlertSummary
+	Clusters    []storage.WalletCluster
+}
+
+// registerDashboard mounts the read-only HTML dashboard at /dashboard and
+// its static assets under /dashboard/static/
+func registerDashboard(mux *http.ServeMux, db dashboardReader, proc pollStatusReader, token string, loc *time.Location, log *logrus.Logger) {
+	static, _ := fs.Sub(dashboardAssets, "dashboard/static")
+	mux.Handle("GET /dashboard/static/", http.StripPrefix("/dashboard/static/", http.FileServerFS(static)))
+	mux.HandleFunc("GET /dashboard", handleDashboard(db, proc, token, loc, log))
+}
+
+// handleDashboard serves GET /dashboard: recent alerts, the most suspicious
+// wallets and flagged clusters, and the service status, refreshed every 30
+// seconds. It takes the API token as a bearer header or, for browsers,
+// once as ?token= which is then kept in a cookie. Times are shown in loc.
+func handleDashboard(db dashboardReader, proc pollStatusReader, token string, loc *time.Location, log *logrus.Logger) http.HandlerFunc {
+	cache := &statsCache{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("token"); q != "" && tokenMatches(q, token) {
+			http.SetCookie(w, &http.Cookie{
+				Name:     dashboardCookie,
+				Value:    q,
+				Path:     "/dashboard",
+				HttpOnly: true,
+				Secure:   r.TLS != nil,
+				SameSite: http.SameSiteStrictMode,
+			})
+			// Drop the token from the address bar and history
+			http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+			return
+		}
+		if !dashboardAuthorized(r, token) {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintln(w, "unauthorized: open /dashboard?token=<API_AUTH_TOKEN> to sign in")
+			return
+		}
+
+		data, err := loadDashboard(r.Context(), db, proc, cache, loc)
+		if err != nil {
+			log.WithError(err).Error("Failed to load dashboard")
+			http.Error(w, "failed to load dashboard", http.StatusInternalServerError)
+			return
+		}
+
+		// Render fully before writing so a template error is still a clean 500
+		var buf bytes.Buffer
+		if err := dashboardTemplate.Execute(&buf, data); err != nil {
+			log.WithError(err).Error("Failed to render dashboard")
+			http.Error(w, "failed to render dashboard", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+// loadDashboard runs the dashboard's queries. The service counts share the
+// GET /stats cache policy.
+func loadDashboard(ctx context.Context, db dashboardReader, proc pollStatusReader, cache *statsCache, loc *time.Location) (*dashboardData, error) {
+	now := time.Now()
+	counts, asOf, err := cache.get(ctx, db, now)
+	if err != nil {
+		return nil, fmt.Errorf("get stats: %w", err)
+	}
+	data := &dashboardData{
+		RefreshSec:  int(dashboardRefresh.Seconds()),
+		GeneratedAt: now.In(loc).Format("15:04:05 MST"),
+		Location:    loc,
+		Stats:       newServiceStats(counts, proc.PollStatus(), now.Sub(startTime), asOf),
+	}
+
+	if data.Alerts, _, err = db.ListAlerts(ctx, storage.AlertFilter{Limit: dashboardAlerts}); err != nil {
+		return nil, fmt.Errorf("list alerts: %w", err)
+	}
+	if data.Wallets, err = db.GetTopAlertedWallets(ctx, now.Add(-7*24*time.Hour).Unix(), dashboardWallets); err != nil {
+		return nil, fmt.Errorf("top wallets: %w", err)
+	}
+	if data.Clusters, _, err = db.ListClusters(ctx, storage.ClusterFilter{FlaggedOnly: true, Limit: dashboardClusters}); err != nil {
+		return nil, fmt.Errorf("list clusters: %w", err)
+	}
+	return data, nil
+}
+
+// dashboardAuthorized reports whether r carries the API token as a bearer
+// header or in the dashboard cookie
+func dashboardAuthorized(r *http.Request, token string) bool {
+	if adminAuthorized(r, token) {
+		return true
+	}
+	c, err := r.Cookie(dashboardCookie)
+	return err == nil && tokenMatches(c.Value, token)
+}
+
+// tokenMatches compares a presented token with the configured one in
+// constant time. An empty configured token matches nothing.
+func tokenMatches(got, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// formatUSD formats an amount as whole dollars with thousands separators
+func formatUSD(v float64) string {
+	s := fmt.Sprintf("%.0f", v)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	if neg {
+		return "-$" + b.String()
+	}
+	return "$" + b.String()
+}
+
+// shortHex abbreviates an address or hash to its first and last characters
+func shortHex(s string) string {
+	if len(s) <= 14 {
+		return s
+	}
+	return s[:8] + "…" + s[len(s)-4:]
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeDashboard serves one alert, wallet and cluster
+type fakeDashboard struct {
+	fakeStats
+}
+
+func (f *fakeDashboard) ListAlerts(ctx context.Context, filter storage.AlertFilter) ([]storage.Alert, int64, error) {
+	return []storage.Alert{{
+		ID: 1, AlertType: "ALERT", WalletAddress: testWallet, MarketTitle: "Will it <rain>?",
+		MarketURL: "https://polymarket.com/market/rain", NotionalUSD: 25000, TransactionHash: "0xfeedfacefeedface",
+		CreatedTS: 1717243200, // 2024-06-01 12:00 UTC
+	}}, 1, nil
+}
+
+func (f *fakeDashboard) ListClusters(ctx context.Context, filter storage.ClusterFilter) ([]storage.WalletCluster, int64, error) {
+	return []storage.WalletCluster{{ClusterID: "cluster-1", FundingSource: "0xfunder", IsFlagged: true}}, 1, nil
+}
+
+func (f *fakeDashboard) GetTopAlertedWallets(ctx context.Context, sinceTS int64, limit int) ([]storage.WalletAlertSummary, error) {
+	return []storage.WalletAlertSummary{{WalletAddress: testWallet, Alerts: 3, MaxScore: 14.5}}, nil
+}
+
+func TestDashboard(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("got %v loading time zone, want no error", err)
+	}
+	mux := http.NewServeMux()
+	registerDashboard(mux, &fakeDashboard{}, fakePollStatus{LastSuccess: time.Now()}, "secret", newYork, log)
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		`class="sev-alert"`,
+		`https://polygonscan.com/address/` + testWallet,
+		`https://polygonscan.com/tx/0xfeedfacefeedface`,
+		`https://polymarket.com/market/rain`,
+		`Will it &lt;rain&gt;?`,
+		`$25,000`,
+		`cluster-1`,
+		`2024-06-01 08:00 EDT`, // Times are shown in DISPLAY_TIMEZONE
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("got a page without %q", want)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/dashboard/static/dashboard.css", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("got %d for the stylesheet, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestDashboardAuth(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	handler := handleDashboard(&fakeDashboard{}, fakePollStatus{}, "secret", time.UTC, log)
+
+	tests := []struct {
+		name           string
+		path           string
+		header         string
+		cookie         string
+		expectedStatus int
+		description    string
+	}{
+		{"missing", "/dashboard", "", "", http.StatusUnauthorized, "Requests without the token are rejected"},
+		{"bearer", "/dashboard", "Bearer secret", "", http.StatusOK, "The admin token is accepted as a bearer header"},
+		{"cookie", "/dashboard", "", "secret", http.StatusOK, "The sign-in cookie is accepted"},
+		{"wrong cookie", "/dashboard", "", "nope", http.StatusUnauthorized, "A wrong cookie is rejected"},
+		{"sign in", "/dashboard?token=secret", "", "", http.StatusSeeOther, "A valid ?token= sets the cookie and redirects"},
+		{"wrong sign in", "/dashboard?token=nope", "", "", http.StatusUnauthorized, "A wrong ?token= is rejected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: dashboardCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if rec.Code == http.StatusSeeOther && (rec.Header().Get("Location") != "/dashboard" || len(rec.Result().Cookies()) != 1) {
+				t.Errorf("got redirect to %q with %d cookies, want /dashboard with the cookie\nDescription: %s",
+					rec.Header().Get("Location"), len(rec.Result().Cookies()), tt.description)
+			}
+		})
+	}
+}
+
+func TestFormatUSD(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{0, "$0"},
+		{999.6, "$1,000"},
+		{25000, "$25,000"},
+		{1234567.2, "$1,234,567"},
+		{-4500, "-$4,500"},
+	}
+
+	for _, tt := range tests {
+		if got := formatUSD(tt.value); got != tt.expected {
+			t.Errorf("formatUSD(%v) got %q, want %q", tt.value, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// runtimeStatsReader reports the processor's concurrency; *processor.Processor
+// implements it
+type runtimeStatsReader interface {
+	RuntimeStats() processor.RuntimeStats
+}
+
+// debugVars is the GET /debug/vars response
+type debugVars struct {
+	Goroutines    int                    `json:"goroutines"`
+	HeapAllocMB   float64                `json:"heap_alloc_mb"` // Live heap
+	HeapInuseMB   float64                `json:"heap_inuse_mb"`
+	SysMB         float64                `json:"sys_mb"` // Memory obtained from the OS; compare with RSS
+	NumGC         uint32                 `json:"num_gc"`
+	LastGC        string                 `json:"last_gc,omitempty"`
+	Processor     processor.RuntimeStats `json:"processor"`
+	UptimeSeconds int64                  `json:"uptime_seconds"`
+}
+
+// registerDebug mounts the pprof handlers under /debug/pprof/ and the
+// runtime counters at /debug/vars on mux. Importing net/http/pprof also
+// registers its handlers on http.DefaultServeMux, which nothing here serves,
+// so they are only reachable through mux.
+func registerDebug(mux *http.ServeMux, proc runtimeStatsReader) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/vars", handleDebugVars(proc))
+}
+
+// handleDebugVars serves GET /debug/vars: goroutine, memory and worker pool
+// counters for a first look before reaching for a profile
+func handleDebugVars(proc runtimeStatsReader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+
+		vars := debugVars{
+			Goroutines:    runtime.NumGoroutine(),
+			HeapAllocMB:   float64(mem.HeapAlloc) / (1 << 20),
+			HeapInuseMB:   float64(mem.HeapInuse) / (1 << 20),
+			SysMB:         float64(mem.Sys) / (1 << 20),
+			NumGC:         mem.NumGC,
+			Processor:     proc.RuntimeStats(),
+			UptimeSeconds: int64(time.Since(startTime).Seconds()),
+		}
+		if mem.LastGC > 0 {
+			vars.LastGC = time.Unix(0, int64(mem.LastGC)).UTC().Format(time.RFC3339)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(vars)
+	}
+}
+
+// debugHandler serves the debug endpoints on their own port. Profiles
+// expose memory contents and the command line, so they need API_AUTH_TOKEN
+// there as on the health port.
+func debugHandler(token string, proc runtimeStatsReader) http.Handler {
+	mux := http.NewServeMux()
+	registerDebug(mux, proc)
+	return requireToken(mux, token)
+}
+
+// startDebugServer serves the debug endpoints on PPROF_PORT and returns the
+// server, or nil when they share the health server's port
+func startDebugServer(cfg *config.Config, proc runtimeStatsReader, log *logrus.Logger) *http.Server {
+	if !cfg.EnablePprof || cfg.PprofPort == 0 {
+		return nil
+	}
+	// No write timeout: CPU profiles and traces stream for as long as requested
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", cfg.PprofPort),
+		Handler:           debugHandler(cfg.APIAuthToken, proc),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.WithField("port", cfg.PprofPort).Info("Starting debug server (pprof)")
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("Debug server failed")
+		}
+	}()
+	return server
+}
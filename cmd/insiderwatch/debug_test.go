@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/processor"
+)
+
+type fakeRuntimeStats processor.RuntimeStats
+
+func (f fakeRuntimeStats) RuntimeStats() processor.RuntimeStats {
+	return processor.RuntimeStats(f)
+}
+
+func TestRegisterDebug(t *testing.T) {
+	mux := http.NewServeMux()
+	registerDebug(mux, fakeRuntimeStats{Workers: 5, WorkersBusy: 2, WaitingTrades: 7})
+
+	tests := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		description    string
+	}{
+		{"index", "/debug/pprof/", http.StatusOK, "The pprof index is served"},
+		{"heap", "/debug/pprof/heap", http.StatusOK, "Named profiles are served through the index"},
+		{"vars", "/debug/vars", http.StatusOK, "Runtime counters are served"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", tt.path, nil))
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/debug/vars", nil))
+	var vars debugVars
+	if err := json.NewDecoder(rec.Body).Decode(&vars); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if vars.Goroutines < 1 || vars.Processor.WorkersBusy != 2 || vars.Processor.WaitingTrades != 7 {
+		t.Errorf("got %+v, want goroutines counted and the processor's stats", vars)
+	}
+}
+
+func TestDebugHandlerRequiresToken(t *testing.T) {
+	handler := debugHandler("secret", fakeRuntimeStats{})
+
+	tests := []struct {
+		name           string
+		path           string
+		auth           string
+		expectedStatus int
+		description    string
+	}{
+		{"profile without token", "/debug/pprof/heap", "", http.StatusUnauthorized, "Profiles on PPROF_PORT need the token"},
+		{"cmdline without token", "/debug/pprof/cmdline", "", http.StatusUnauthorized, "The command line is not served anonymously"},
+		{"vars wrong token", "/debug/vars", "Bearer wrong", http.StatusUnauthorized, "A wrong token is rejected"},
+		{"profile with token", "/debug/pprof/heap", "Bearer secret", http.StatusOK, "The token unlocks profiles"},
+		{"vars with token", "/debug/vars", "Bearer secret", http.StatusOK, "The token unlocks runtime counters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// entityCountsInterval is how often the tracked entity gauges are refreshed.
+// The counts move slowly, so there is no point paying for the queries on
+// every scrape.
+const entityCountsInterval = 5 * time.Minute
+
+// entityCounter reads the tracked entity counts; *storage.DB implements it
+type entityCounter interface {
+	GetEntityCounts(ctx context.Context, activeSinceTS int64) (*storage.EntityCounts, error)
+}
+
+// collectEntityCounts refreshes the tracked entity gauges. On error the
+// gauges keep their previous values.
+func collectEntityCounts(ctx context.Context, db entityCounter, now time.Time, log *logrus.Logger) {
+	counts, err := db.GetEntityCounts(ctx, now.Add(-24*time.Hour).Unix())
+	if err != nil {
+		if ctx.Err() == nil {
+			log.WithError(err).Warn("Failed to count tracked entities")
+		}
+		return
+	}
+	metrics.RecordEntityCounts(counts.Wallets, counts.ActiveWallets, counts.Clusters, counts.FlaggedClusters, counts.UnresolvedMarkets)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeEntityCounter struct {
+	counts      *storage.EntityCounts
+	err         error
+	activeSince int64
+}
+
+func (f *fakeEntityCounter) GetEntityCounts(_ context.Context, activeSinceTS int64) (*storage.EntityCounts, error) {
+	f.activeSince = activeSinceTS
+	return f.counts, f.err
+}
+
+func TestCollectEntityCounts(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	now := time.Unix(1_700_000_000, 0)
+
+	db := &fakeEntityCounter{counts: &storage.EntityCounts{
+		Wallets:           1200,
+		ActiveWallets:     85,
+		Clusters:          14,
+		FlaggedClusters:   3,
+		UnresolvedMarkets: 410,
+	}}
+	collectEntityCounts(context.Background(), db, now, log)
+
+	if want := now.Add(-24 * time.Hour).Unix(); db.activeSince != want {
+		t.Errorf("got active since %d, want %d", db.activeSince, want)
+	}
+	tests := []struct {
+		name        string
+		got         float64
+		expected    float64
+		description string
+	}{
+		{"wallets", testutil.ToFloat64(metrics.TrackedWallets), 1200, "All stored wallets"},
+		{"active", testutil.ToFloat64(metrics.ActiveWallets), 85, "Wallets active in the last 24 hours"},
+		{"clusters", testutil.ToFloat64(metrics.TrackedClusters), 14, "Clusters of 2 or more wallets"},
+		{"flagged", testutil.ToFloat64(metrics.FlaggedClusters), 3, "Flagged clusters"},
+		{"unresolved", testutil.ToFloat64(metrics.UnresolvedMarkets), 410, "Markets awaiting resolution"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.expected {
+			t.Errorf("%s: got %v, want %v\nDescription: %s", tt.name, tt.got, tt.expected, tt.description)
+		}
+	}
+
+	// A failed refresh leaves the last good values in place
+	collectEntityCounts(context.Background(), &fakeEntityCounter{err: errors.New("db down")}, now, log)
+	if got := testutil.ToFloat64(metrics.TrackedWallets); got != 1200 {
+		t.Errorf("got %v after a failed refresh, want the previous 1200", got)
+	}
+}
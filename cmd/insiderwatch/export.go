@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// exportPageSize is how many alerts export-alerts reads a query
+const exportPageSize = 500
+
+// exportColumns are the CSV columns of export-alerts, matching the fields
+// of a GET /alerts item
+var exportColumns = []string{
+	"id", "severity", "wallet", "condition_id", "market_title", "market_url",
+	"side", "outcome", "notional_usd", "price", "wallet_age_days",
+	"suspicion_score", "record_only", "transaction_hash", "trade_timestamp", "created_ts",
+}
+
+// exportOptions are the flags of `insiderwatch export-alerts`
+type exportOptions struct {
+	since    timeFlag
+	until    timeFlag
+	severity string
+	format   string
+	output   string
+}
+
+// parseExportFlags parses the export-alerts flags, writing errors and
+// usage to out
+func parseExportFlags(args []string, out io.Writer) (*exportOptions, error) {
+	opts := &exportOptions{}
+	fs := flag.NewFlagSet("export-alerts", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Var(&opts.since, "since", "Only alerts created since: a duration before now (24h), Unix timestamp, RFC 3339 time or date")
+	fs.Var(&opts.until, "until", "Only alerts created before, in the same forms")
+	fs.StringVar(&opts.severity, "severity", "", "Only alerts of this severity: INFO, WARN or ALERT")
+	fs.StringVar(&opts.format, "format", "csv", "Output format: csv or json")
+	fs.StringVar(&opts.output, "output", "-", "File to write, or - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if opts.format != "csv" && opts.format != "json" {
+		return nil, fmt.Errorf("--format must be csv or json, got %q", opts.format)
+	}
+	opts.severity = strings.ToUpper(opts.severity)
+	switch opts.severity {
+	case "", "INFO", "WARN", "ALERT":
+	default:
+		return nil, fmt.Errorf("--severity must be INFO, WARN or ALERT, got %q", opts.severity)
+	}
+	if !opts.since.t.IsZero() && !opts.until.t.IsZero() && !opts.until.t.After(opts.since.t) {
+		return nil, errors.New("--until must be after --since")
+	}
+	return opts, nil
+}
+
+// filter returns the storage filter for the options, without a limit
+func (o *exportOptions) filter() storage.AlertFilter {
+	filter := storage.AlertFilter{AlertType: o.severity}
+	if !o.since.t.IsZero() {
+		filter.SinceTS = o.since.t.Unix()
+	}
+	if !o.until.t.IsZero() {
+		filter.UntilTS = o.until.t.Unix()
+	}
+	return filter
+}
+
+// runExportAlertsCommand implements `insiderwatch export-alerts`, which
+// writes the stored alerts matching its flags, newest first, as CSV or a
+// JSON array. It needs only the database.
+func runExportAlertsCommand(args []string) int {
+	opts, err := parseExportFlags(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export-alerts: %v\n", err)
+		return 2
+	}
+
+	// Logs go to stderr so they don't mix with an export on stdout
+	log := bootstrap.Logger(os.Stderr, logrus.WarnLevel)
+	cfg, err := bootstrap.Config(log, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load configuration: %v\n", err)
+		return 1
+	}
+	db, err := bootstrap.Database(cfg, log, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export-alerts: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	out := io.Writer(os.Stdout)
+	if opts.output != "-" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export-alerts: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	n, err := exportAlerts(context.Background(), db, opts.filter(), opts.format, out)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "export-alerts: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d alerts\n", n)
+	return 0
+}
+
+// exportAlerts writes the alerts matching filter to w in format, a page at
+// a time, and returns how many it wrote
+func exportAlerts(ctx context.Context, db alertLister, filter storage.AlertFilter, format string, w io.Writer) (int, error) {
+	var cw *csv.Writer
+	if format == "csv" {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return 0, err
+		}
+	} else if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+
+	written := 0
+	filter.Limit = exportPageSize
+	for {
+		rows, _, err := db.ListAlerts(ctx, filter)
+		if err != nil {
+			return written, fmt.Errorf("list alerts: %w", err)
+		}
+		for _, a := range rows {
+			item := newAlertItem(a)
+			if cw != nil {
+				err = cw.Write(alertRecord(item))
+			} else {
+				err = writeJSONItem(w, item, written == 0)
+			}
+			if err != nil {
+				return written, err
+			}
+			written++
+		}
+		if len(rows) < filter.Limit {
+			break
+		}
+		filter.BeforeID = rows[len(rows)-1].ID
+	}
+
+	if cw != nil {
+		cw.Flush()
+		return written, cw.Error()
+	}
+	_, err := io.WriteString(w, "]\n")
+	return written, err
+}
+
+// writeJSONItem writes one element of the JSON array export
+func writeJSONItem(w io.Writer, item alertItem, first bool) error {
+	if !first {
+		if _, err := io.WriteString(w, ","); err != nil {
+			return err
+		}
+	}
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append([]byte("\n"), raw...))
+	return err
+}
+
+// alertRecord returns item's CSV row, in exportColumns order
+func alertRecord(item alertItem) []string {
+	return []string{
+		strconv.FormatInt(item.ID, 10),
+		item.Severity,
+		item.Wallet,
+		item.ConditionID,
+		item.MarketTitle,
+		item.MarketURL,
+		item.Side,
+		item.Outcome,
+		strconv.FormatFloat(item.NotionalUSD, 'f', 2, 64),
+		strconv.FormatFloat(item.Price, 'f', -1, 64),
+		strconv.Itoa(item.WalletAgeDays),
+		strconv.FormatFloat(item.SuspicionScore, 'f', -1, 64),
+		strconv.FormatBool(item.RecordOnly),
+		item.TransactionHash,
+		strconv.FormatInt(item.TradeTimestamp, 10),
+		strconv.FormatInt(item.CreatedTS, 10),
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// healthcheckTimeout bounds `insiderwatch healthcheck`, well inside a
+// container runtime's own health check timeout
+const healthcheckTimeout = 3 * time.Second
+
+// runHealthcheckCommand implements `insiderwatch healthcheck`, which probes
+// the running service's /ready endpoint (/health with --liveness) on
+// HEALTH_PORT, for images without curl or wget. It exits 0 when the probe
+// succeeds and 1 with the reason on stderr when it doesn't.
+func runHealthcheckCommand(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	liveness := fs.Bool("liveness", false, "Check /health (the process is up) instead of /ready")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	// HEALTH_PORT is looked up on its own rather than through config.Load,
+	// so the probe doesn't need the database or API secrets
+	port := 8080
+	v, err := config.Lookup("HEALTH_PORT")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "healthcheck: %v\n", err)
+		return 1
+	}
+	if v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "healthcheck: invalid HEALTH_PORT %q\n", v)
+			return 1
+		}
+		port = p
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthcheckTimeout)
+	defer cancel()
+	if err := probe(ctx, http.DefaultClient, fmt.Sprintf("http://127.0.0.1:%d", port), *liveness); err != nil {
+		fmt.Fprintf(os.Stderr, "healthcheck: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// probe requests /ready, or /health when liveness is set, from baseURL and
+// returns why it failed. A non-200 response's body is included, since
+// /ready explains itself there.
+func probe(ctx context.Context, client *http.Client, baseURL string, liveness bool) error {
+	path := "/ready"
+	if liveness {
+		path = "/health"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("GET %s: status %d: %s", path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
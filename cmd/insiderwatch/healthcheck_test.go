@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	ready := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/health":
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/ready" && ready:
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"not_ready","last_error":"poll failed"}`))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name        string
+		ready       bool
+		liveness    bool
+		wantErr     string
+		description string
+	}{
+		{"ready", true, false, "", "A 200 from /ready passes"},
+		{"not ready", false, false, "poll failed", "A 503 from /ready fails with the response body"},
+		{"liveness", false, true, "", "--liveness only checks /health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready = tt.ready
+			err := probe(context.Background(), srv.Client(), srv.URL, tt.liveness)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got %v, want error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+		})
+	}
+
+	srv.Close()
+	if err := probe(context.Background(), http.DefaultClient, srv.URL, false); err == nil {
+		t.Errorf("got no error, want one\nDescription: An unreachable service fails")
+	}
+}
@@ -2,138 +2,118 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"os/signal"
+	"runtime/debug"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/circuit"
 	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/errreport"
 	"github.com/liamashdown/insiderwatch/internal/metrics"
-	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
-	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
 	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/rules"
 	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/liamashdown/insiderwatch/internal/version"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
+// startTime is when the process started, for uptime in GET /stats
+var startTime = time.Now()
+
 func main() {
-	// Initialize logger
-	log := logrus.New()
-	log.SetFormatter(&logrus.JSONFormatter{})
-	log.SetOutput(os.Stdout)
-	log.SetLevel(logrus.InfoLevel)
+	os.Exit(run(os.Args[1:]))
+}
 
-	log.Info("Starting insiderwatch service...")
+// errJobPanicked is reported to an admin request whose job panicked
+var errJobPanicked = errors.New("job panicked; see the service logs")
 
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.WithError(err).Fatal("Failed to load configuration")
+// recoverJob keeps a panic in a background job from taking down the
+// service: it logs the panic with its stack and counts it. Defer it directly
+// in the job's goroutine.
+func recoverJob(log *logrus.Logger, job string) {
+	v := recover()
+	if v == nil {
+		return
 	}
-
+	stack := debug.Stack()
+	metrics.Panics.WithLabelValues(job).Inc()
 	log.WithFields(logrus.Fields{
-		"environment":       cfg.Environment,
-		"big_trade_usd":     cfg.BigTradeUSD,
-		"new_wallet_days":   cfg.NewWalletDaysMax,
-		"poll_interval_sec": cfg.PollIntervalSec,
-		"alert_mode":        cfg.AlertMode,
-	}).Info("Configuration loaded")
-
-	// Initialize database
-	db, err := storage.New(cfg, log)
+		"job":   job,
+		"panic": v,
+		"stack": string(stack),
+	}).Error("Background job panicked")
+	errreport.Capture(errreport.Event{Err: fmt.Errorf("panic: %v", v), Source: job, Stack: stack})
+}
+
+// runWinRateRecalculation runs a win rate recalculation, skipping it if one
+// is already in progress
+func runWinRateRecalculation(ctx context.Context, proc *processor.Processor, log *logrus.Logger) {
+	defer recoverJob(log, "win_rate")
+	resolved, err := proc.RecalculateWinRates(ctx)
+	if errors.Is(err, processor.ErrRecalculationRunning) {
+		log.Info("Win rate recalculation already running, skipping")
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Info("Win rate recalculation stopped for shutdown")
+		return
+	}
 	if err != nil {
-		log.WithError(err).Fatal("Failed to connect to database")
+		log.WithError(err).Error("Error recalculating win rates")
+		return
 	}
-	defer db.Close()
-
-	log.Info("Database connected")
+	log.WithField("resolved_markets", resolved).Debug("Scheduled win rate recalculation finished")
+}
 
-	// Run auto-migration
-	if err := db.AutoMigrate(); err != nil {
-		log.WithError(err).Fatal("Failed to run database migrations")
+// logPollError logs a failed poll cycle. An open API circuit is expected
+// during outages and only skips the cycle, as does a tick that arrives
+// while the previous cycle is still running.
+func logPollError(log *logrus.Logger, err error) {
+	if errors.Is(err, processor.ErrPollRunning) {
+		log.Warn("Previous poll cycle still running, skipping tick")
+		return
 	}
-
-	log.Info("Database migrations complete")
-
-	// Initialize API clients
-	dataClient := dataapi.NewClient(cfg)
-	gammaClient := gammaapi.NewClient(cfg)
-
-	log.Info("API clients initialized")
-
-	// Initialize alert sender
-	alertSender := createAlertSender(cfg, log)
-
-	log.WithField("alert_mode", cfg.AlertMode).Info("Alert sender initialized")
-
-	// Initialize processor
-	proc := processor.New(cfg, db, dataClient, gammaClient, alertSender, log)
-
-	// Start HTTP server (health + metrics)
-	go startHTTPServer(cfg.HealthPort, log)
-
-	// Setup graceful shutdown
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	// Start polling loop
-	ticker := time.NewTicker(time.Duration(cfg.PollIntervalSec) * time.Second)
-	defer ticker.Stop()
-
-	// Start daily win rate recalculation ticker
-	winRateTicker := time.NewTicker(24 * time.Hour)
-	defer winRateTicker.Stop()
-
-	log.Info("Starting trade processing loop")
-
-	// Process immediately on startup
-	if err := proc.ProcessTrades(ctx); err != nil {
+	if errors.Is(err, circuit.ErrOpen) {
+		log.WithError(err).Warn("Skipping poll cycle, API circuit open")
+		return
+	}
+	if err != nil {
 		log.WithError(err).Error("Error processing trades")
 	}
+}
 
-	// Run win rate calculation on startup (async)
-	go func() {
-		if err := proc.RecalculateWinRates(ctx); err != nil {
-			log.WithError(err).Error("Error calculating win rates on startup")
-		}
-	}()
+// loadCustomRules compiles the rules file and installs it on the processor
+func loadCustomRules(path string, proc *processor.Processor, log *logrus.Logger) error {
+	compiled, err := rules.LoadFile(path)
+	if err != nil {
+		return err
+	}
+	proc.SetCustomRules(compiled)
 
-	for {
-		select {
-		case <-ticker.C:
-			if err := proc.ProcessTrades(ctx); err != nil {
-				log.WithError(err).Error("Error processing trades")
-			}
-		case <-winRateTicker.C:
-			// Run win rate recalculation daily
-			go func() {
-				if err := proc.RecalculateWinRates(ctx); err != nil {
-					log.WithError(err).Error("Error recalculating win rates")
-				}
-			}()
-		case sig := <-sigChan:
-			log.WithField("signal", sig).Info("Received shutdown signal")
-			cancel()
-			log.Info("Graceful shutdown complete")
-			return
-		case <-ctx.Done():
-			log.Info("Context cancelled, shutting down")
-			return
-		}
+	labels := make([]string, 0, len(compiled))
+	for _, r := range compiled {
+		labels = append(labels, r.Label)
 	}
+	log.WithFields(logrus.Fields{
+		"file":  path,
+		"count": len(compiled),
+		"rules": strings.Join(labels, ", "),
+	}).Info("Custom rules loaded")
+	return nil
 }
 
-func createAlertSender(cfg *config.Config, log *logrus.Logger) alerts.Sender {
+// createAlertSender builds the sender for a comma-separated list of alert
+// modes: ALERT_MODE, or DAILY_SUMMARY_CHANNELS for the daily summary
+func createAlertSender(cfg *config.Config, alertMode string, log *logrus.Logger) alerts.Sender {
 	// Parse comma-separated alert modes
-	modes := strings.Split(cfg.AlertMode, ",")
+	modes := strings.Split(alertMode, ",")
 	
 	// Trim whitespace from each mode
 	for i, mode := range modes {
@@ -144,37 +124,34 @@ func createAlertSender(cfg *config.Config, log *logrus.Logger) alerts.Sender {
 	if len(modes) == 1 {
 		switch modes[0] {
 		case "log":
-			return alerts.NewLogSender(log)
+			return capSender(cfg, "log", alerts.NewLogSender(log, cfg.DisplayLocation()), log)
 
 		case "discord":
-			// Create senders for all webhook URLs
-			if len(cfg.DiscordWebhookURLs) == 0 {
+			discordSenders := newDiscordSenders(cfg, log)
+			if len(discordSenders) == 0 {
 				log.Warn("Discord mode specified but no webhook URLs configured")
-				return alerts.NewLogSender(log)
+				return alerts.NewLogSender(log, cfg.DisplayLocation())
 			}
-			if len(cfg.DiscordWebhookURLs) == 1 {
-				return alerts.NewDiscordSender(cfg.DiscordWebhookURLs[0])
+			if len(discordSenders) == 1 {
+				return discordSenders[0]
 			}
 			// Multiple webhooks - use multi sender
-			discordSenders := []alerts.Sender{}
-			for _, url := range cfg.DiscordWebhookURLs {
-				discordSenders = append(discordSenders, alerts.NewDiscordSender(url))
-			}
 			return alerts.NewMultiSender(discordSenders...)
 
 		case "smtp":
-			return alerts.NewSMTPSender(
+			return capSender(cfg, "smtp", alerts.NewSMTPSender(
 				cfg.SMTPHost,
 				cfg.SMTPPort,
 				cfg.SMTPUser,
 				cfg.SMTPPassword,
 				cfg.SMTPFrom,
 				cfg.SMTPTo,
-			)
+				cfg.DisplayLocation(),
+			), log)
 
 		default:
 			log.WithField("alert_mode", modes[0]).Warn("Unknown alert mode, using log")
-			return alerts.NewLogSender(log)
+			return alerts.NewLogSender(log, cfg.DisplayLocation())
 		}
 	}
 	
@@ -184,26 +161,24 @@ func createAlertSender(cfg *config.Config, log *logrus.Logger) alerts.Sender {
 	for _, mode := range modes {
 		switch mode {
 		case "log":
-			senders = append(senders, alerts.NewLogSender(log))
+			senders = append(senders, capSender(cfg, "log", alerts.NewLogSender(log, cfg.DisplayLocation()), log))
 		case "discord":
-			if len(cfg.DiscordWebhookURLs) > 0 {
-				// Add a sender for each webhook URL
-				for _, url := range cfg.DiscordWebhookURLs {
-					senders = append(senders, alerts.NewDiscordSender(url))
-				}
-			} else {
+			discordSenders := newDiscordSenders(cfg, log)
+			if len(discordSenders) == 0 {
 				log.Warn("Discord mode specified but DISCORD_WEBHOOK_URLS not set")
 			}
+			senders = append(senders, discordSenders...)
 		case "smtp":
 			if cfg.SMTPHost != "" {
-				senders = append(senders, alerts.NewSMTPSender(
+				senders = append(senders, capSender(cfg, "smtp", alerts.NewSMTPSender(
 					cfg.SMTPHost,
 					cfg.SMTPPort,
 					cfg.SMTPUser,
 					cfg.SMTPPassword,
 					cfg.SMTPFrom,
 					cfg.SMTPTo,
-				))
+					cfg.DisplayLocation(),
+				), log))
 			} else {
 				log.Warn("SMTP mode specified but SMTP_HOST not set")
 			}
@@ -214,42 +189,210 @@ func createAlertSender(cfg *config.Config, log *logrus.Logger) alerts.Sender {
 	
 	if len(senders) == 0 {
 		log.Warn("No valid alert senders configured, using log")
-		return alerts.NewLogSender(log)
+		return alerts.NewLogSender(log, cfg.DisplayLocation())
 	}
 	
 	return alerts.NewMultiSender(senders...)
 }
 
-func startHTTPServer(port int, log *logrus.Logger) {
+// createProfileSenders builds the alert route of each watch profile, by
+// profile name
+func createProfileSenders(cfg *config.Config, log *logrus.Logger) map[string]alerts.Sender {
+	senders := make(map[string]alerts.Sender, len(cfg.Profiles))
+	for _, pr := range cfg.Profiles {
+		route := cfg.ForProfile(pr)
+		senders[pr.Name] = createAlertSender(route, route.AlertMode, log)
+		log.WithFields(logrus.Fields{
+			"profile":    pr.Name,
+			"alert_mode": route.AlertMode,
+		}).Info("Profile alert sender initialized")
+	}
+	return senders
+}
+
+// flushAlerts sends the overflow summaries that the alert rate caps of
+// sender and the profile senders still hold
+func flushAlerts(ctx context.Context, sender alerts.Sender, profileSenders map[string]alerts.Sender) error {
+	senders := []alerts.Sender{sender}
+	for _, s := range profileSenders {
+		senders = append(senders, s)
+	}
+	return alerts.Flush(ctx, senders...)
+}
+
+// usesSMTP reports whether a comma-separated list of alert modes includes
+// smtp
+func usesSMTP(alertMode string) bool {
+	for _, mode := range strings.Split(alertMode, ",") {
+		if strings.TrimSpace(mode) == "smtp" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSMTP connects and authenticates to the SMTP server without sending,
+// when SMTP_CHECK_ON_STARTUP is set and alerts or the daily summary go by
+// email
+func checkSMTP(cfg *config.Config, log *logrus.Logger) error {
+	if !cfg.SMTPCheckOnStartup || cfg.SMTPHost == "" {
+		return nil
+	}
+	needed := usesSMTP(cfg.AlertMode) || (cfg.DailySummaryTime != "" && usesSMTP(cfg.DailySummaryChannels))
+	for _, pr := range cfg.Profiles {
+		needed = needed || usesSMTP(cfg.ForProfile(pr).AlertMode)
+	}
+	if !needed {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	sender := alerts.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPFrom, cfg.SMTPTo, cfg.DisplayLocation())
+	if err := sender.Check(ctx); err != nil {
+		return err
+	}
+	log.WithFields(logrus.Fields{
+		"host":       cfg.SMTPHost,
+		"port":       cfg.SMTPPort,
+		"recipients": len(cfg.SMTPTo),
+	}).Info("SMTP server check passed")
+	return nil
+}
+
+// handleReady serves /ready. While polling is enabled the instance is only
+// ready if a poll cycle succeeded within READY_MAX_POLL_AGE.
+func handleReady(cfg *config.Config, proc *processor.Processor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if cfg.IngestMode == "websocket" {
+			metrics.RecordHealthCheck(true)
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, `{"status":"ready"}`)
+			return
+		}
+
+		ready, status := proc.PollReady(time.Now(), cfg.ReadyMaxPollAge)
+		metrics.RecordHealthCheck(ready)
+
+		body := map[string]any{"status": "ready"}
+		if !status.LastSuccess.IsZero() {
+			body["last_successful_poll"] = status.LastSuccess.UTC().Format(time.RFC3339)
+		}
+		if status.LastError != "" {
+			body["last_error"] = status.LastError
+		}
+		if ready {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			body["status"] = "not_ready"
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(body)
+	}
+}
+
+// newDiscordSenders creates one Discord sender per configured webhook URL,
+// each with its own rate cap
+func newDiscordSenders(cfg *config.Config, log *logrus.Logger) []alerts.Sender {
+	senders := make([]alerts.Sender, 0, len(cfg.DiscordWebhookURLs))
+	for _, url := range cfg.DiscordWebhookURLs {
+		senders = append(senders, capSender(cfg, "discord", alerts.NewDiscordSender(url, cfg.DisplayLocation()), log))
+	}
+	return senders
+}
+
+// capSender applies the channel's ALERT_RATE_LIMITS cap to a sender, when
+// it has one
+func capSender(cfg *config.Config, channel string, sender alerts.Sender, log *logrus.Logger) alerts.Sender {
+	perMinute, ok := cfg.AlertRateLimits[channel]
+	if !ok {
+		return sender
+	}
+	exempt := make([]alerts.Severity, len(cfg.AlertRateLimitExempt))
+	for i, severity := range cfg.AlertRateLimitExempt {
+		exempt[i] = alerts.Severity(severity)
+	}
+	return alerts.NewCappedSender(sender, channel, perMinute, cfg.AlertOverflowSummaryInterval, exempt, log)
+}
+
+// startHTTPServer starts the health, metrics and admin server and returns it
+// for shutdown. The admin endpoints hand their jobs to the main loop through
+// triggers.
+func startHTTPServer(cfg *config.Config, proc *processor.Processor, db *storage.DB, triggers *adminTriggers, log *logrus.Logger) *http.Server {
+	port := cfg.HealthPort
 	mux := http.NewServeMux()
 
 	// Health check endpoints
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		metrics.RecordHealthCheck(true)
 		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"healthy"}`)
+		fmt.Fprintf(w, `{"status":"healthy","version":%q,"commit":%q,"build_date":%q}`, version.Version, version.Commit, version.BuildDate)
 	})
 
-	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
-		metrics.RecordHealthCheck(true)
-		w.WriteHeader(http.StatusOK)
-		fmt.Fprintf(w, `{"status":"ready"}`)
-	})
+	mux.HandleFunc("/ready", handleReady(cfg, proc))
 
 	// Prometheus metrics endpoint
 	mux.Handle("/metrics", promhttp.Handler())
 
+	// Score simulation for tuning thresholds; read-only
+	mux.HandleFunc("/score", handleScore(proc))
+
+	// Wallet holdings from the Data API; read-only
+	mux.HandleFunc("GET /wallets/{address}/positions", handleWalletPositions(proc, log))
+
+	// Stored alerts, wallets and clusters
+	mux.HandleFunc("GET /alerts", handleListAlerts(db, log))
+	mux.HandleFunc("GET /alerts/stream", handleAlertStream(db, proc.AlertStream(), log))
+	mux.HandleFunc("GET /alerts/{id}", handleAlertDetail(db, log))
+	mux.HandleFunc("GET /wallets/{address}", handleWalletDetail(db, log))
+	mux.HandleFunc("GET /clusters", handleListClusters(db, log))
+	mux.HandleFunc("GET /clusters/{id}", handleClusterDetail(db, log))
+	mux.HandleFunc("GET /markets", handleListMarkets(db, log))
+	mux.HandleFunc("GET /markets/{conditionID}", handleMarketDetail(db, log))
+	mux.HandleFunc("GET /stats", handleStats(db, proc, startTime, log))
+
+	// Manual poll cycles and win rate recalculations. /admin/recalculate is
+	// the older name.
+	if cfg.IngestMode != "websocket" {
+		mux.HandleFunc("POST /admin/process", handleProcessNow(triggers, cfg.APIAuthToken, log))
+	}
+	recalculate := handleRecalculateNow(triggers, cfg.APIAuthToken, log)
+	mux.HandleFunc("POST /admin/recalculate-winrates", recalculate)
+	mux.HandleFunc("POST /admin/recalculate", recalculate)
+
+	// The daily summary, sent now for testing the channels and layout
+	mux.HandleFunc("POST /admin/summary", handleSummaryNow(triggers, cfg.APIAuthToken, log))
+
+	// Read-only HTML view of the same data for browsers
+	registerDashboard(mux, db, proc, cfg.APIAuthToken, cfg.DisplayLocation(), log)
+
+	// Profiling and runtime counters, here or on their own port
+	if cfg.EnablePprof && cfg.PprofPort == 0 {
+		registerDebug(mux, proc)
+	}
+
 	addr := fmt.Sprintf(":%d", port)
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      withMiddleware(mux, cfg.APIAuthToken, log),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  15 * time.Second,
 	}
 
-	log.WithField("port", port).Info("Starting HTTP server (health + metrics)")
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.WithError(err).Error("HTTP server failed")
+	// Streams never finish on their own; end them so shutdown isn't held up
+	server.RegisterOnShutdown(proc.AlertStream().Close)
+
+	// The debug server has nothing worth draining, so it closes with this one
+	if debug := startDebugServer(cfg, proc, log); debug != nil {
+		server.RegisterOnShutdown(func() { debug.Close() })
 	}
+
+	log.WithField("port", port).Info("Starting HTTP server (health + metrics)")
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("HTTP server failed")
+		}
+	}()
+	return server
 }
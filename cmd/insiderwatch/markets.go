@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	marketDetailTrades = 50
+	marketDetailAlerts = 50
+)
+
+// marketReader reads cached markets and what was recorded against them;
+// *storage.DB implements it
+type marketReader interface {
+	alertLister
+	ListMarkets(ctx context.Context, filter storage.MarketFilter) ([]storage.MarketActivity, int64, error)
+	GetMarketMap(ctx context.Context, conditionID string) (*storage.MarketMap, error)
+	GetMarketResolution(ctx context.Context, conditionID string) (*storage.MarketResolution, error)
+	GetMarketTrades(ctx context.Context, conditionID string, limit int) ([]storage.TradeSeen, error)
+}
+
+// marketSummary is one market in a GET /markets response
+type marketSummary struct {
+	ConditionID        string            `json:"condition_id"`
+	Title              string            `json:"title"`
+	Slug               string            `json:"slug"`
+	URL                string            `json:"url"`
+	Category           string            `json:"category"`
+	EndDate            int64             `json:"end_date"`
+	VolumeUSD          float64           `json:"volume_usd"`
+	LiquidityUSD       float64           `json:"liquidity_usd"`
+	AlertCount         int64             `json:"alert_count"`          // Notified alerts; record-only ones aren't counted
+	FlaggedNotionalUSD float64           `json:"flagged_notional_usd"` // Notional of those alerts
+	LastAlertTS        int64             `json:"last_alert_ts"`
+	Resolution         *marketResolution `json:"resolution"` // null until the market resolves
+}
+
+func newMarketSummary(m storage.MarketActivity) marketSummary {
+	summary := marketSummary{
+		ConditionID:        m.ConditionID,
+		Title:              m.MarketTitle,
+		Slug:               m.MarketSlug,
+		URL:                m.MarketURL,
+		Category:           m.Category,
+		EndDate:            m.EndDate,
+		VolumeUSD:          m.VolumeNum,
+		LiquidityUSD:       m.LiquidityNum,
+		AlertCount:         m.AlertCount,
+		FlaggedNotionalUSD: m.FlaggedNotionalUSD,
+		LastAlertTS:        m.LastAlertTS,
+	}
+	if m.ResolvedTS > 0 {
+		summary.Resolution = &marketResolution{WinningOutcome: m.WinningOutcome, ResolvedTS: m.ResolvedTS}
+	}
+	return summary
+}
+
+// marketsPage is the GET /markets response envelope
+type marketsPage struct {
+	Items      []marketSummary `json:"items"`
+	Total      int64           `json:"total"`
+	NextCursor *string         `json:"next_cursor"`
+}
+
+// marketDetail is the GET /markets/{conditionID} response
+type marketDetail struct {
+	ConditionID  string        `json:"condition_id"`
+	Market       alertMarket   `json:"market"`      // With its resolution once it resolves
+	AlertCount   int64         `json:"alert_count"` // All alerts, including record-only ones
+	RecentAlerts []alertItem   `json:"recent_alerts"`
+	RecentTrades []marketTrade `json:"recent_trades"`
+}
+
+type marketTrade struct {
+	Wallet          string  `json:"wallet"`
+	TransactionHash string  `json:"transaction_hash"`
+	Side            string  `json:"side"`
+	Outcome         string  `json:"outcome"`
+	Price           float64 `json:"price"`
+	NotionalUSD     float64 `json:"notional_usd"`
+	Role            string  `json:"role"`
+	Timestamp       int64   `json:"timestamp"`
+}
+
+// handleListMarkets serves GET /markets: the cached markets, most flagged
+// notional first.
+func handleListMarkets(db marketReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		filter, err := parseMarketsQuery(r.URL.Query())
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		filter.NowTS = time.Now().Unix()
+
+		// One extra row tells whether there is another page
+		limit := filter.Limit
+		filter.Limit++
+		rows, total, err := db.ListMarkets(r.Context(), filter)
+		if err != nil {
+			log.WithError(err).Error("Failed to list markets")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to list markets"})
+			return
+		}
+
+		page := marketsPage{Items: make([]marketSummary, 0, min(len(rows), limit)), Total: total}
+		if len(rows) > limit {
+			rows = rows[:limit]
+			cursor := strconv.Itoa(filter.Offset + limit)
+			page.NextCursor = &cursor
+		}
+		for _, m := range rows {
+			page.Items = append(page.Items, newMarketSummary(m))
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(page)
+	}
+}
+
+// parseMarketsQuery turns GET /markets query parameters into a filter
+func parseMarketsQuery(q url.Values) (storage.MarketFilter, error) {
+	filter := storage.MarketFilter{Category: q.Get("category"), Limit: defaultPageLimit}
+
+	var err error
+	switch s := q.Get("status"); s {
+	case "", storage.MarketActive, storage.MarketClosed:
+		filter.Status = s
+	default:
+		return filter, fmt.Errorf("status must be %s or %s", storage.MarketActive, storage.MarketClosed)
+	}
+	if s := q.Get("has_alerts"); s != "" {
+		hasAlerts, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, fmt.Errorf("has_alerts must be true or false")
+		}
+		filter.HasAlerts = &hasAlerts
+	}
+	if s := q.Get("limit"); s != "" {
+		if filter.Limit, err = strconv.Atoi(s); err != nil || filter.Limit < 1 || filter.Limit > maxPageLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+	}
+	// Markets are ranked by flagged notional, which changes, so the cursor
+	// is an offset into the ranking
+	if s := q.Get("cursor"); s != "" {
+		if filter.Offset, err = strconv.Atoi(s); err != nil || filter.Offset < 0 {
+			return filter, fmt.Errorf("cursor must be a next_cursor from a previous page")
+		}
+	}
+	return filter, nil
+}
+
+// handleMarketDetail serves GET /markets/{conditionID}: the cached market,
+// its resolution, and the alerts and trades recorded against it.
+func handleMarketDetail(db marketReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		conditionID := r.PathValue("conditionID")
+		detail, err := loadMarketDetail(r.Context(), db, conditionID)
+		if err != nil {
+			log.WithError(err).WithField("condition_id", conditionID).Error("Failed to load market")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to load market"})
+			return
+		}
+		if detail == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "market not tracked"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// loadMarketDetail composes a market's storage records. It returns nil for
+// a market that isn't cached.
+func loadMarketDetail(ctx context.Context, db marketReader, conditionID string) (*marketDetail, error) {
+	market, err := db.GetMarketMap(ctx, conditionID)
+	if err != nil {
+		return nil, fmt.Errorf("get market: %w", err)
+	}
+	if market == nil {
+		return nil, nil
+	}
+
+	detail := &marketDetail{
+		ConditionID: conditionID,
+		Market: alertMarket{
+			Title:        market.MarketTitle,
+			Slug:         market.MarketSlug,
+			URL:          market.MarketURL,
+			Category:     market.Category,
+			EndDate:      market.EndDate,
+			VolumeUSD:    market.VolumeNum,
+			LiquidityUSD: market.LiquidityNum,
+		},
+		RecentAlerts: []alertItem{},
+		RecentTrades: []marketTrade{},
+	}
+
+	resolution, err := db.GetMarketResolution(ctx, conditionID)
+	if err != nil {
+		return nil, fmt.Errorf("get resolution: %w", err)
+	}
+	if resolution != nil {
+		detail.Market.Resolution = &marketResolution{
+			WinningOutcome: resolution.WinningOutcome,
+			ResolvedTS:     resolution.ResolvedTS,
+			Method:         resolution.Method,
+		}
+	}
+
+	alertRows, total, err := db.ListAlerts(ctx, storage.AlertFilter{ConditionID: conditionID, Limit: marketDetailAlerts})
+	if err != nil {
+		return nil, fmt.Errorf("list alerts: %w", err)
+	}
+	detail.AlertCount = total
+	for _, a := range alertRows {
+		detail.RecentAlerts = append(detail.RecentAlerts, newAlertItem(a))
+	}
+
+	trades, err := db.GetMarketTrades(ctx, conditionID, marketDetailTrades)
+	if err != nil {
+		return nil, fmt.Errorf("get trades: %w", err)
+	}
+	for _, t := range trades {
+		detail.RecentTrades = append(detail.RecentTrades, marketTrade{
+			Wallet:          t.ProxyWallet,
+			TransactionHash: t.TransactionHash,
+			Side:            t.Side,
+			Outcome:         t.Outcome,
+			Price:           t.Price,
+			NotionalUSD:     t.NotionalUSD,
+			Role:            t.Role,
+			Timestamp:       t.TimestampSec,
+		})
+	}
+
+	return detail, nil
+}
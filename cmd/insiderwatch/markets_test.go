@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeMarkets serves n markets, most flagged first, like storage.ListMarkets,
+// and one known market "0xcond" in detail, resolved with two alerts
+type fakeMarkets struct {
+	fakeAlerts
+	markets      int
+	marketFilter storage.MarketFilter
+}
+
+func (f *fakeMarkets) ListMarkets(ctx context.Context, filter storage.MarketFilter) ([]storage.MarketActivity, int64, error) {
+	f.marketFilter = filter
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	var out []storage.MarketActivity
+	for i := filter.Offset; i < f.markets && len(out) < filter.Limit; i++ {
+		out = append(out, storage.MarketActivity{ConditionID: fmt.Sprintf("0x%d", i+1), FlaggedNotionalUSD: float64(f.markets - i)})
+	}
+	return out, int64(f.markets), nil
+}
+
+func (f *fakeMarkets) GetMarketMap(ctx context.Context, conditionID string) (*storage.MarketMap, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if conditionID != "0xcond" {
+		return nil, nil
+	}
+	return &storage.MarketMap{ConditionID: conditionID, MarketTitle: "Some market", Category: "Politics"}, nil
+}
+
+func (f *fakeMarkets) GetMarketResolution(ctx context.Context, conditionID string) (*storage.MarketResolution, error) {
+	return &storage.MarketResolution{ConditionID: conditionID, WinningOutcome: "Yes", ResolvedTS: 1700000000}, nil
+}
+
+func (f *fakeMarkets) GetMarketTrades(ctx context.Context, conditionID string, limit int) ([]storage.TradeSeen, error) {
+	return []storage.TradeSeen{{ProxyWallet: testWallet, ConditionID: conditionID, Side: "BUY", NotionalUSD: 25000}}, nil
+}
+
+func TestListMarkets(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name           string
+		query          string
+		storeErr       error
+		expectedStatus int
+		expectedItems  int
+		expectedNext   string // Empty for the last page
+		description    string
+	}{
+		{"default page", "", nil, http.StatusOK, 50, "50", "Without a limit a page holds 50 markets"},
+		{"cursor", "?limit=10&cursor=60", nil, http.StatusOK, 10, "70", "A cursor continues from its offset"},
+		{"last page", "?limit=10&cursor=70", nil, http.StatusOK, 5, "", "The last page has no next cursor"},
+		{"bad status", "?status=open", nil, http.StatusBadRequest, 0, "", "status must be active or closed"},
+		{"bad has_alerts", "?has_alerts=maybe", nil, http.StatusBadRequest, 0, "", "has_alerts must be a boolean"},
+		{"bad limit", "?limit=0", nil, http.StatusBadRequest, 0, "", "limit must be positive"},
+		{"storage error", "", errors.New("db down"), http.StatusInternalServerError, 0, "", "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeMarkets{fakeAlerts: fakeAlerts{err: tt.storeErr}, markets: 75}
+			rec := httptest.NewRecorder()
+			handleListMarkets(store, log)(rec, httptest.NewRequest("GET", "/markets"+tt.query, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var page marketsPage
+			if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			next := ""
+			if page.NextCursor != nil {
+				next = *page.NextCursor
+			}
+			if len(page.Items) != tt.expectedItems || next != tt.expectedNext || page.Total != 75 {
+				t.Errorf("got %d items, next %q, total %d, want %d items, next %q, total 75\nDescription: %s",
+					len(page.Items), next, page.Total, tt.expectedItems, tt.expectedNext, tt.description)
+			}
+		})
+	}
+}
+
+func TestListMarketsFilters(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	store := &fakeMarkets{markets: 1}
+
+	rec := httptest.NewRecorder()
+	handleListMarkets(store, log)(rec, httptest.NewRequest("GET", "/markets?category=Politics&status=closed&has_alerts=false&limit=5&cursor=20", nil))
+
+	f := store.marketFilter
+	if f.Category != "Politics" || f.Status != storage.MarketClosed || f.HasAlerts == nil || *f.HasAlerts || f.Offset != 20 || f.Limit != 6 || f.NowTS == 0 {
+		t.Errorf("got filter %+v, want Politics, closed, without alerts, offset 20, limit 6 and the current time", f)
+	}
+}
+
+func TestMarketDetail(t *testing.T) {
+	tests := []struct {
+		name           string
+		conditionID    string
+		storeErr       error
+		expectedStatus int
+		description    string
+	}{
+		{"tracked", "0xcond", nil, http.StatusOK, "A cached market returns its detail"},
+		{"unknown", "0xother", nil, http.StatusNotFound, "A market storage doesn't know is a 404"},
+		{"storage error", "0xcond", errors.New("db down"), http.StatusInternalServerError, "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			store := &fakeMarkets{fakeAlerts: fakeAlerts{n: 2, err: tt.storeErr}}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /markets/{conditionID}", handleMarketDetail(store, log))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/markets/"+tt.conditionID, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("got %d (%s), want %d\nDescription: %s", rec.Code, rec.Body, tt.expectedStatus, tt.description)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+
+			var detail marketDetail
+			if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+				t.Fatalf("got %v decoding %s", err, rec.Body)
+			}
+			if detail.Market.Title != "Some market" || detail.Market.Resolution == nil || detail.Market.Resolution.WinningOutcome != "Yes" {
+				t.Errorf("got market %+v, want the resolved market\nDescription: The market and its resolution are included", detail.Market)
+			}
+			if detail.AlertCount != 2 || len(detail.RecentAlerts) != 2 || store.fakeAlerts.filter.ConditionID != "0xcond" || len(detail.RecentTrades) != 1 {
+				t.Errorf("got %d alerts (%d listed, filter %+v) and %d trades, want 2 alerts for the market and 1 trade\nDescription: Alerts and trades on the market are included",
+					detail.AlertCount, len(detail.RecentAlerts), store.fakeAlerts.filter, len(detail.RecentTrades))
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/errreport"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// httpRequestTimeout bounds a request's context, matching the server's
+// write timeout. Admin jobs and profiles run longer and are exempt.
+const httpRequestTimeout = 10 * time.Second
+
+// withMiddleware wraps the HTTP server's handler with request logging,
+// panic recovery, token authentication and the request timeout, outermost
+// first
+func withMiddleware(h http.Handler, token string, log *logrus.Logger) http.Handler {
+	return logRequests(recoverPanics(requireToken(withTimeout(h, httpRequestTimeout), token), log), log)
+}
+
+// statusRecorder remembers the status a handler wrote
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, for the
+// handlers that lift the write deadline or flush
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// logRequests logs each request once it completes. Probes, scrapes and
+// static assets log at Debug so they don't drown out the rest at Info.
+func logRequests(next http.Handler, log *logrus.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := logrus.InfoLevel
+		if routineRequest(r.URL.Path) {
+			level = logrus.DebugLevel
+		}
+		log.WithFields(logrus.Fields{
+			"method":      r.Method,
+			"path":        r.URL.Path,
+			"status":      status,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"remote_addr": r.RemoteAddr,
+		}).Log(level, "HTTP request")
+	})
+}
+
+// routineRequest reports whether path is polled by machines: health probes,
+// metric scrapes and dashboard assets
+func routineRequest(path string) bool {
+	switch path {
+	case "/health", "/ready", "/metrics":
+		return true
+	}
+	return strings.HasPrefix(path, "/dashboard/static/")
+}
+
+// recoverPanics turns a handler panic into a 500 and a logged stack trace
+// instead of a dropped connection
+func recoverPanics(next http.Handler, log *logrus.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec, ok := w.(*statusRecorder)
+		if !ok {
+			rec = &statusRecorder{ResponseWriter: w}
+		}
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v) // Deliberate abort; let the server drop the connection
+			}
+			stack := debug.Stack()
+			metrics.HTTPPanics.Inc()
+			log.WithFields(logrus.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"panic":  v,
+				"stack":  string(stack),
+			}).Error("HTTP handler panicked")
+			errreport.Capture(errreport.Event{
+				Err:    fmt.Errorf("panic: %v", v),
+				Source: "http",
+				Tags:   map[string]string{"method": r.Method, "path": r.URL.Path},
+				Stack:  stack,
+			})
+
+			// Too late for a status once the handler started writing
+			if rec.status == 0 {
+				w.Header().Set("Content-Type", "application/json")
+				rec.WriteHeader(http.StatusInternalServerError)
+				json.NewEncoder(rec).Encode(map[string]string{"error": "internal error"})
+			}
+		}()
+		next.ServeHTTP(rec, r)
+	})
+}
+
+// requireToken rejects requests that don't carry token as a bearer token
+// with a 401, except health probes and metric scrapes, which come from
+// orchestrators and Prometheus. The dashboard page signs browsers in with
+// ?token= and checks its cookie itself, and its assets accept the cookie too.
+// An empty token rejects everything else.
+func requireToken(next http.Handler, token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch path := r.URL.Path; {
+		case path == "/health", path == "/ready", path == "/metrics", path == "/dashboard":
+		case strings.HasPrefix(path, "/dashboard/") && dashboardAuthorized(r, token):
+		case !adminAuthorized(r, token):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withTimeout cancels a request's context after timeout, so storage queries
+// behind a slow endpoint give up with the client. The admin endpoints wait on
+// jobs bounded by their own deadlines, profiles run as long as asked, and the
+// alert stream stays open until the client leaves.
+func withTimeout(next http.Handler, timeout time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/admin/") || strings.HasPrefix(r.URL.Path, "/debug/pprof/") || r.URL.Path == "/alerts/stream" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
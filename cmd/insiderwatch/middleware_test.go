@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLogRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		status      int
+		wantLevel   string
+		description string
+	}{
+		{"health", "/health", http.StatusOK, "debug", "Health probes log at Debug"},
+		{"metrics", "/metrics", http.StatusOK, "debug", "Metric scrapes log at Debug"},
+		{"static", "/dashboard/static/dashboard.css", http.StatusOK, "debug", "Dashboard assets log at Debug"},
+		{"admin", "/admin/process", http.StatusAccepted, "info", "Admin requests log at Info"},
+		{"alerts", "/alerts", http.StatusBadRequest, "info", "API requests log at Info"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log := logrus.New()
+			log.SetOutput(&buf)
+			log.SetLevel(logrus.DebugLevel)
+			log.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+
+			h := logRequests(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}), log)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			line := buf.String()
+			for _, want := range []string{
+				"level=" + tt.wantLevel,
+				"method=GET",
+				"path=" + tt.path,
+				fmt.Sprintf("status=%d", tt.status),
+				"duration_ms=",
+				"remote_addr=",
+			} {
+				if !strings.Contains(line, want) {
+					t.Errorf("got %q, want it to contain %q\nDescription: %s", line, want, tt.description)
+				}
+			}
+		})
+	}
+}
+
+func TestRecoverPanics(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		expectedStatus int
+		description    string
+	}{
+		{
+			"before write",
+			func(w http.ResponseWriter, r *http.Request) { panic("boom") },
+			http.StatusInternalServerError,
+			"A panic before any output is answered with a 500",
+		},
+		{
+			"after write",
+			func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				panic("boom")
+			},
+			http.StatusOK,
+			"A panic after the status was sent can't change it",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testutil.ToFloat64(metrics.HTTPPanics)
+			rec := httptest.NewRecorder()
+			recoverPanics(tt.handler, log).ServeHTTP(rec, httptest.NewRequest("GET", "/alerts", nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if got := testutil.ToFloat64(metrics.HTTPPanics) - before; got != 1 {
+				t.Errorf("got %v panics counted, want 1\nDescription: %s", got, tt.description)
+			}
+		})
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		defer func() {
+			if v := recover(); v != http.ErrAbortHandler {
+				t.Errorf("got %v, want http.ErrAbortHandler re-panicked", v)
+			}
+		}()
+		recoverPanics(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}), log).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/alerts", nil))
+	})
+}
+
+func TestWithTimeout(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		wantDeadline bool
+		description  string
+	}{
+		{"api", "/alerts", true, "API requests get a deadline"},
+		{"admin", "/admin/recalculate", false, "Admin jobs aren't bounded by the request timeout"},
+		{"pprof", "/debug/pprof/profile", false, "Profiles run as long as asked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deadline time.Time
+			var ok bool
+			h := withTimeout(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, ok = r.Context().Deadline()
+			}), time.Minute)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+
+			if ok != tt.wantDeadline {
+				t.Errorf("got deadline %v (%v), want deadline %v\nDescription: %s", deadline, ok, tt.wantDeadline, tt.description)
+			}
+		})
+	}
+}
+
+func TestWithMiddlewareKeepsResponseController(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	// Handlers that stream or wait lift the write deadline through the
+	// wrapped writer
+	h := withMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("got %v, want the flush to reach the underlying writer", err)
+		}
+	}), "secret", log)
+	req := httptest.NewRequest("GET", "/alerts", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestRequireToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		token          string
+		path           string
+		header         string
+		cookie         string
+		expectedStatus int
+		description    string
+	}{
+		{"health", "secret", "/health", "", "", http.StatusOK, "Health probes need no token"},
+		{"ready", "secret", "/ready", "", "", http.StatusOK, "Readiness probes need no token"},
+		{"metrics", "secret", "/metrics", "", "", http.StatusOK, "Metric scrapes need no token"},
+		{"missing", "secret", "/alerts", "", "", http.StatusUnauthorized, "Data endpoints need the token"},
+		{"wrong", "secret", "/admin/process", "Bearer nope", "", http.StatusUnauthorized, "A wrong token is rejected"},
+		{"valid", "secret", "/wallets/0xabc", "Bearer secret", "", http.StatusOK, "The right bearer token is accepted"},
+		{"score", "secret", "/score", "", "", http.StatusUnauthorized, "Everything but the probes is protected"},
+		{"dashboard", "secret", "/dashboard", "", "", http.StatusOK, "The dashboard page signs browsers in itself"},
+		{"asset cookie", "secret", "/dashboard/static/dashboard.css", "", "secret", http.StatusOK, "Dashboard assets accept the sign-in cookie"},
+		{"asset", "secret", "/dashboard/static/dashboard.css", "", "", http.StatusUnauthorized, "Dashboard assets need the token or cookie"},
+		{"unset", "", "/alerts", "Bearer ", "", http.StatusUnauthorized, "An empty token fails closed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := requireToken(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), tt.token)
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: dashboardCookie, Value: tt.cookie})
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("got content type %q, want a JSON error\nDescription: %s", rec.Header().Get("Content-Type"), tt.description)
+			}
+		})
+	}
+}
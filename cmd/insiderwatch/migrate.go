@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/sirupsen/logrus"
+)
+
+// runMigrateCommand implements `insiderwatch migrate`, which creates or
+// updates the database schema and exits, for deployments that run
+// migrations as a separate step and start serve with --skip-migrate. With
+// NORMALIZE_OUTCOMES it also normalizes outcome labels stored before
+// migration 026, which other commands leave alone.
+func runMigrateCommand(args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "migrate: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stdout, logrus.InfoLevel)
+	cfg, err := bootstrap.Config(log, false)
+	if err != nil {
+		log.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+	db, err := bootstrap.Database(cfg, log, true)
+	if err != nil {
+		log.WithError(err).Error("Failed to migrate database")
+		return 1
+	}
+	defer db.Close()
+
+	if cfg.NormalizeOutcomes {
+		rewritten, err := db.NormalizeOutcomeLabels(context.Background())
+		if err != nil {
+			log.WithError(err).Error("Failed to normalize outcome labels")
+			return 1
+		}
+		if rewritten > 0 {
+			log.WithField("rows", rewritten).Info("Normalized stored outcome labels")
+		}
+	}
+	return 0
+}
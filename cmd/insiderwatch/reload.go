@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// configReloader takes a reloaded configuration; *processor.Processor
+// implements it
+type configReloader interface {
+	Reload(cfg *config.Config)
+}
+
+// reloadConfig re-reads the configuration on SIGHUP and applies the
+// settings that can change at runtime, logging what changed and what
+// needs a restart. It returns the configuration now in effect; on a load
+// error nothing changes.
+func reloadConfig(current *config.Config, proc configReloader, log *logrus.Logger) *config.Config {
+	next, err := config.Load()
+	if err != nil {
+		log.WithError(err).Error("Failed to reload configuration, keeping the running one")
+		return current
+	}
+
+	for _, warning := range next.Warnings() {
+		log.Warn("Configuration: " + warning)
+	}
+
+	reloaded, restart := current.Diff(next)
+	if len(restart) > 0 {
+		log.WithField("fields", strings.Join(restart, ", ")).Warn("Configuration changes need a restart to take effect")
+	}
+	if len(reloaded) == 0 {
+		log.Info("Configuration reloaded, no runtime settings changed")
+		return current
+	}
+
+	merged := current.Reload(next)
+	proc.Reload(merged)
+	level, _ := logrus.ParseLevel(merged.LogLevel) // Checked by Validate
+	log.SetLevel(level)
+	log.WithField("fields", strings.Join(reloaded, ", ")).Info("Configuration reloaded")
+	return merged
+}
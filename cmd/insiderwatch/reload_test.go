@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeReloader records the configuration it was switched to
+type fakeReloader struct {
+	cfg *config.Config
+}
+
+func (f *fakeReloader) Reload(cfg *config.Config) { f.cfg = cfg }
+
+func TestReloadConfig(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	current, err := config.Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	tests := []struct {
+		name           string
+		env            map[string]string
+		expectedReload bool
+		expectedAlert  float64
+		expectedPort   int
+		description    string
+	}{
+		{"unchanged", nil, false, 85, 8080, "Nothing is applied when nothing changed"},
+		{"threshold", map[string]string{"SUSPICION_SCORE_ALERT": "90", "HEALTH_PORT": "9999"}, true, 90, 8080, "Thresholds apply at once; the port keeps its running value"},
+		{"restart only", map[string]string{"HEALTH_PORT": "9999"}, false, 85, 8080, "Settings needing a restart are not applied"},
+		{"invalid", map[string]string{"SUSPICION_SCORE_ALERT": "90", "LOG_LEVEL": "loud"}, false, 85, 8080, "An invalid configuration changes nothing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			proc := &fakeReloader{}
+
+			got := reloadConfig(current, proc, log)
+			if (proc.cfg != nil) != tt.expectedReload {
+				t.Errorf("got processor reloaded %v, want %v\nDescription: %s", proc.cfg != nil, tt.expectedReload, tt.description)
+			}
+			if got.SuspicionScoreAlert != tt.expectedAlert || got.HealthPort != tt.expectedPort {
+				t.Errorf("got alert threshold %v and port %d, want %v and %d\nDescription: %s",
+					got.SuspicionScoreAlert, got.HealthPort, tt.expectedAlert, tt.expectedPort, tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// backgroundJitter is how far the win rate, withdrawal scan and entity count
+// intervals vary, as a fraction of the interval
+const backgroundJitter = 0.1
+
+// jitteredInterval returns interval varied by up to ±fraction of itself,
+// given a random number r in [0, 1)
+func jitteredInterval(interval time.Duration, fraction, r float64) time.Duration {
+	return interval + time.Duration(float64(interval)*fraction*(2*r-1))
+}
+
+// jitterTicker delivers ticks like time.Ticker, but every interval varies
+// randomly and the first tick can wait a random splay instead, so instances
+// started together drift apart rather than hitting the APIs in the same
+// second. Like time.Ticker it drops ticks for a slow receiver.
+type jitterTicker struct {
+	C    <-chan time.Time
+	stop chan struct{}
+	once sync.Once
+}
+
+// newJitterTicker starts a ticker whose intervals vary by up to ±fraction.
+// With a positive splay the first tick comes after a random delay of up to
+// splay; otherwise it comes after the first interval.
+func newJitterTicker(interval time.Duration, fraction float64, splay time.Duration) *jitterTicker {
+	return startJitterTicker(time.After, rand.Float64, interval, fraction, splay)
+}
+
+// startJitterTicker is newJitterTicker with the clock and random source
+// injected, for tests
+func startJitterTicker(after func(time.Duration) <-chan time.Time, random func() float64, interval time.Duration, fraction float64, splay time.Duration) *jitterTicker {
+	c := make(chan time.Time, 1)
+	t := &jitterTicker{C: c, stop: make(chan struct{})}
+
+	wait := jitteredInterval(interval, fraction, random())
+	if splay > 0 {
+		wait = time.Duration(random() * float64(splay))
+	}
+	go func() {
+		for {
+			select {
+			case now := <-after(wait):
+				select {
+				case <-t.stop:
+					return
+				default:
+				}
+				select {
+				case c <- now:
+				default:
+				}
+			case <-t.stop:
+				return
+			}
+			wait = jitteredInterval(interval, fraction, random())
+		}
+	}()
+	return t
+}
+
+// Stop turns off the ticker. Like time.Ticker.Stop it doesn't close C.
+func (t *jitterTicker) Stop() {
+	t.once.Do(func() { close(t.stop) })
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock hands the ticker a channel per wait and records how long each
+// wait was, so a test fires ticks by hand
+type fakeClock struct {
+	waits chan time.Duration
+	fire  chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{waits: make(chan time.Duration, 10), fire: make(chan time.Time)}
+}
+
+func (c *fakeClock) after(d time.Duration) <-chan time.Time {
+	c.waits <- d
+	return c.fire
+}
+
+func (c *fakeClock) nextWait(t *testing.T) time.Duration {
+	t.Helper()
+	select {
+	case d := <-c.waits:
+		return d
+	case <-time.After(time.Second):
+		t.Fatal("got no wait, want the ticker waiting for its next tick")
+		return 0
+	}
+}
+
+func TestJitteredInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		fraction    float64
+		r           float64
+		expected    time.Duration
+		description string
+	}{
+		{"no jitter", 0, 0.9, 30 * time.Second, "A zero fraction keeps the interval"},
+		{"lowest", 0.1, 0, 27 * time.Second, "The smallest random number shortens the interval by the whole fraction"},
+		{"middle", 0.1, 0.5, 30 * time.Second, "The middle of the range keeps the interval"},
+		{"highest", 0.1, 0.75, 31500 * time.Millisecond, "Larger random numbers lengthen it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jitteredInterval(30*time.Second, tt.fraction, tt.r); got != tt.expected {
+				t.Errorf("got %s, want %s\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestJitterTicker(t *testing.T) {
+	tests := []struct {
+		name          string
+		splay         time.Duration
+		expectedFirst time.Duration
+		description   string
+	}{
+		{"no splay", 0, 34500 * time.Millisecond, "Without a splay the first tick comes after a jittered interval"},
+		{"splay", 20 * time.Second, 16 * time.Second, "A splay delays the first tick by a random part of it instead"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := newFakeClock()
+			random := func() float64 { return 0.8 }
+			ticker := startJitterTicker(clock.after, random, 30*time.Second, 0.25, tt.splay)
+			defer ticker.Stop()
+
+			if got := clock.nextWait(t); got != tt.expectedFirst {
+				t.Errorf("got first wait %s, want %s\nDescription: %s", got, tt.expectedFirst, tt.description)
+			}
+			tick := time.Unix(1700000000, 0)
+			clock.fire <- tick
+			if got := <-ticker.C; !got.Equal(tick) {
+				t.Errorf("got tick %v, want %v\nDescription: %s", got, tick, tt.description)
+			}
+			if got := clock.nextWait(t); got != 34500*time.Millisecond {
+				t.Errorf("got second wait %s, want the interval jittered by 15%%\nDescription: %s", got, tt.description)
+			}
+		})
+	}
+}
+
+func TestJitterTickerDropsTicksAndStops(t *testing.T) {
+	clock := newFakeClock()
+	ticker := startJitterTicker(clock.after, func() float64 { return 0.5 }, time.Minute, 0.1, 0)
+
+	// Nobody reads the first two ticks; like time.Ticker the second is dropped
+	first := time.Unix(1700000000, 0)
+	clock.nextWait(t)
+	clock.fire <- first
+	clock.nextWait(t)
+	clock.fire <- first.Add(time.Minute)
+	clock.nextWait(t)
+	if got := <-ticker.C; !got.Equal(first) {
+		t.Errorf("got tick %v, want the first tick kept and the later one dropped", got)
+	}
+
+	ticker.Stop()
+	ticker.Stop() // Stopping twice is harmless
+	select {
+	case clock.fire <- first.Add(2 * time.Minute):
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case got := <-ticker.C:
+		t.Errorf("got tick %v after Stop, want none", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
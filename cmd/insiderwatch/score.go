@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// runScoreCommand implements `insiderwatch score`, which prints the score
+// breakdown and severity for a hypothetical trade using the current
+// configuration and custom rules. It returns the process exit code.
+func runScoreCommand(args []string) int {
+	var in processor.ScoreInput
+	fs := flag.NewFlagSet("score", flag.ContinueOnError)
+	fs.Float64Var(&in.Notional, "notional", 0, "Trade size in USD (required)")
+	fs.Float64Var(&in.Price, "price", 0, "Trade price between 0 and 1 (required)")
+	fs.StringVar(&in.Side, "side", "BUY", "BUY or SELL")
+	fs.IntVar(&in.WalletAgeDays, "wallet-age-days", 0, "Days since the wallet was first seen")
+	fs.Float64Var(&in.HoursToClose, "hours-to-close", 0, "Hours until the market closes (0 = unknown)")
+	fs.Float64Var(&in.WinRate, "win-rate", 0, "Wallet win rate between 0 and 1")
+	fs.IntVar(&in.ResolvedTrades, "resolved-trades", 0, "Resolved trades behind the win rate")
+	fs.Float64Var(&in.LiquidityRatio, "liquidity-ratio", 0, "Trade size / market liquidity (0 = unknown)")
+	fs.IntVar(&in.VelocityCount, "velocity-count", 0, "Trades by the wallet in the velocity window")
+	fs.Float64Var(&in.Concentration, "concentration", 0, "Share of the wallet's market volume on this side")
+	fs.Float64Var(&in.FundingAgeHours, "funding-age-hours", 0, "Hours between funding and the first trade")
+	fs.Float64Var(&in.FundingAmountUSD, "funding-amount", 0, "Wallet's initial funding in USD")
+	fs.IntVar(&in.PriorTrades, "prior-trades", 0, "Tracked trades before this one")
+	fs.Float64Var(&in.PriorVolumeUSD, "prior-volume", 0, "Tracked volume before this one in USD")
+	fs.IntVar(&in.ClusterSize, "cluster-size", 0, "Wallets in the wallet's cluster")
+	fs.StringVar(&in.Coordinated, "coordinated", "", "Cluster coordination: same_side or opposing")
+	fs.BoolVar(&in.FirstTrade, "first-trade", false, "Trade is the wallet's first")
+	fs.BoolVar(&in.Maker, "maker", false, "Trade is a maker fill")
+	fs.BoolVar(&in.CopyTrade, "copy-trade", false, "Trade mirrors a flagged wallet")
+	fs.IntVar(&in.HolderRank, "holder-rank", 0, "Wallet's rank among the outcome's top holders (0 = not listed)")
+	fs.Float64Var(&in.HolderShare, "holder-share", 0, "Wallet's share of the top holders' combined balance")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stderr, logrus.WarnLevel)
+	cfg, err := bootstrap.Config(log, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load configuration: %v\n", err)
+		return 1
+	}
+
+	// Scoring needs neither storage nor API clients
+	proc := processor.New(cfg, nil, nil, nil, nil, nil, nil, log)
+	if cfg.CustomRulesFile != "" {
+		if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+			fmt.Fprintf(os.Stderr, "load custom rules: %v\n", err)
+			return 1
+		}
+	}
+
+	result, err := proc.SimulateScore(context.Background(), in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "score: %v\n", err)
+		return 2
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		fmt.Fprintf(os.Stderr, "encode result: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// handleScore serves POST /score, the HTTP equivalent of `insiderwatch score`
+func handleScore(proc *processor.Processor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		var in processor.ScoreInput
+		dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&in); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("invalid request body: %v", err)})
+			return
+		}
+
+		result, err := proc.SimulateScore(r.Context(), in)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(result)
+	}
+}
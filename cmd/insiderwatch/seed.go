@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/sirupsen/logrus"
+)
+
+// seedOptions are the flags of `insiderwatch seed`
+type seedOptions struct {
+	seed    int64
+	wallets int
+}
+
+// parseSeedFlags parses the seed flags, writing errors and usage to out
+func parseSeedFlags(args []string, out io.Writer) (*seedOptions, error) {
+	opts := &seedOptions{}
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.Int64Var(&opts.seed, "seed", 1, "Random seed; the same seed gives the same data")
+	fs.IntVar(&opts.wallets, "wallets", 300, "Wallets to create (at least 20)")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	if opts.wallets < 20 {
+		return nil, fmt.Errorf("--wallets must be at least 20, got %d", opts.wallets)
+	}
+	return opts, nil
+}
+
+// runSeedCommand implements `insiderwatch seed`, which fills an empty
+// database with a week of synthetic wallets, trades, clusters, resolved
+// markets and alerts for demos and development. It refuses a database that
+// already tracks wallets.
+func runSeedCommand(args []string) int {
+	opts, err := parseSeedFlags(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stderr, logrus.WarnLevel)
+	cfg, err := bootstrap.Config(log, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load configuration: %v\n", err)
+		return 1
+	}
+	db, err := bootstrap.Database(cfg, log, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	counts, err := db.GetEntityCounts(ctx, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+	if counts.Wallets > 0 {
+		fmt.Fprintf(os.Stderr, "seed: the database already tracks %d wallets; seed an empty database\n", counts.Wallets)
+		return 1
+	}
+
+	// Alerts are scored with the current configuration and custom rules
+	proc := processor.New(cfg, db, nil, nil, nil, nil, nil, log)
+	if cfg.CustomRulesFile != "" {
+		if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+			fmt.Fprintf(os.Stderr, "load custom rules: %v\n", err)
+			return 1
+		}
+	}
+	data, err := proc.GenerateSeed(ctx, processor.SeedOptions{Seed: opts.seed, Wallets: opts.wallets, Now: time.Now()})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+	if err := proc.WriteSeed(ctx, data); err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Seeded %d markets (%d resolved), %d wallets, %d clusters, %d trades and %d alerts\n",
+		len(data.Markets), len(data.Resolutions), len(data.Wallets), len(data.Clusters), len(data.Trades), len(data.Alerts))
+	return 0
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/livefeed"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+// runServeCommand implements `insiderwatch serve`, the default command: it
+// polls and streams trades, sends alerts and serves the HTTP API until it
+// receives SIGINT or SIGTERM. It returns the process exit code.
+func runServeCommand(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	skipMigrate := fs.Bool("skip-migrate", false, "Don't run the schema migrations at startup (run `insiderwatch migrate` instead)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "serve: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+
+	log := bootstrap.Logger(os.Stdout, logrus.InfoLevel)
+
+	log.WithFields(logrus.Fields{
+		"version":    version.Version,
+		"commit":     version.Commit,
+		"build_date": version.BuildDate,
+		"go_version": runtime.Version(),
+	}).Info("Starting insiderwatch service...")
+	metrics.BuildInfo.WithLabelValues(version.Version, version.Commit, version.BuildDate, runtime.Version()).Set(1)
+
+	// Load configuration
+	cfg, err := bootstrap.Config(log, false)
+	if err != nil {
+		log.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+	if err := cfg.ValidateServe(); err != nil {
+		log.WithError(err).Error("Failed to load configuration")
+		return 1
+	}
+
+	log.WithFields(logrus.Fields{
+		"environment":             cfg.Environment,
+		"big_trade_usd":           cfg.BigTradeUSD,
+		"new_wallet_days":         cfg.NewWalletDaysMax,
+		"poll_interval_sec":       cfg.PollIntervalSec,
+		"alert_mode":              cfg.AlertMode,
+		"velocity_enabled":        cfg.EnableVelocityDetection,
+		"velocity_threshold":      cfg.VelocityThreshold,
+		"velocity_window_minutes": cfg.VelocityWindowMinutes,
+		"cluster_enabled":         cfg.EnableClusterDetection,
+		"cluster_lookback_hours":  cfg.ClusterLookbackHours,
+		"withdrawal_clustering":   cfg.EnableWithdrawalClustering,
+		"market_prewarm":          cfg.EnableMarketPrewarm,
+		"win_rate_interval":       cfg.WinRateRecalcInterval.String(),
+		"trades_timeout":          cfg.DataAPITradesTimeout.String(),
+		"activity_timeout":        cfg.DataAPIActivityTimeout.String(),
+		"gamma_timeout":           cfg.GammaAPITimeout.String(),
+	}).Info("Configuration loaded")
+	if disabled := cfg.DisabledDetectors(); len(disabled) > 0 {
+		log.WithField("detectors", disabled).Info("Detectors disabled; their multipliers are left out of scores")
+	}
+
+	// Optional tracing and error reporting
+	flush, err := bootstrap.Observability(cfg, log)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up observability")
+		return 1
+	}
+	defer flush()
+
+	db, err := bootstrap.Database(cfg, log, !*skipMigrate)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up database")
+		return 1
+	}
+
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		log.WithError(err).Error("Failed to set up API clients")
+		return 1
+	}
+
+	if err := checkSMTP(cfg, log); err != nil {
+		log.WithError(err).Error("SMTP server check failed")
+		return 1
+	}
+
+	// Initialize alert sender
+	alertSender := createAlertSender(cfg, cfg.AlertMode, log)
+	summarySender := createAlertSender(cfg, cfg.DailySummaryChannels, log)
+
+	log.WithField("alert_mode", cfg.AlertMode).Info("Alert sender initialized")
+
+	// Initialize processor
+	proc := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, alertSender, log)
+	profileSenders := createProfileSenders(cfg, log)
+	proc.SetProfileSenders(profileSenders)
+
+	// Load custom scoring rules; an invalid file is fatal at startup
+	if cfg.CustomRulesFile != "" {
+		if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+			log.WithError(err).Error("Failed to load custom rules")
+			return 1
+		}
+	}
+
+	// Setup graceful shutdown. Cancelling intakeCtx stops new work (the
+	// live feed, ticks, admin triggers and background jobs); ctx is only
+	// cancelled if in-flight trades outlast the drain.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	intakeCtx, stopIntake := context.WithCancel(ctx)
+	defer stopIntake()
+
+	// Start HTTP server (health + metrics)
+	triggers := newAdminTriggers(intakeCtx.Done())
+	server := startHTTPServer(cfg, proc, db, triggers, log)
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+
+	// Start live feed ingestion
+	if cfg.IngestMode == "websocket" || cfg.IngestMode == "both" {
+		feed := livefeed.NewClient(cfg, log)
+		go feed.Run(intakeCtx, func(_ context.Context, trade dataapi.Trade) {
+			proc.ProcessLiveTrade(ctx, trade)
+		})
+		log.WithField("url", cfg.LiveFeedURL).Info("Live feed ingestion started")
+	}
+
+	// Start polling loop (disabled in websocket-only mode; a nil channel
+	// never fires). Jitter and the startup splay keep instances started
+	// together from polling the Data API in the same second.
+	var pollC <-chan time.Time
+	var pollTicker *jitterTicker
+	if cfg.IngestMode != "websocket" {
+		pollTicker = newJitterTicker(time.Duration(cfg.PollIntervalSec)*time.Second, cfg.PollJitterPct/100, cfg.PollStartSplay)
+		defer pollTicker.Stop()
+		pollC = pollTicker.C
+	}
+
+	// Start win rate recalculation ticker; jitter keeps replicas from
+	// hitting the Gamma API at the same moment
+	winRateTicker := newJitterTicker(cfg.WinRateRecalcInterval, backgroundJitter, 0)
+	defer winRateTicker.Stop()
+
+	// Start withdrawal destination scans (disabled unless withdrawal clustering is on)
+	var withdrawalC <-chan time.Time
+	if cfg.EnableWithdrawalClustering {
+		ticker := newJitterTicker(cfg.WithdrawalScanInterval, backgroundJitter, 0)
+		defer ticker.Stop()
+		withdrawalC = ticker.C
+	}
+
+	// Start fetching alerted wallets' smaller trades for exit alerts
+	var exitScanC <-chan time.Time
+	if cfg.EnableExitAlerts {
+		ticker := newJitterTicker(cfg.ExitScanInterval, backgroundJitter, 0)
+		defer ticker.Stop()
+		exitScanC = ticker.C
+	}
+
+	// Start caching new markets ahead of their first trade (disabled unless
+	// ENABLE_MARKET_PREWARM is on)
+	var prewarmC <-chan time.Time
+	if cfg.EnableMarketPrewarm {
+		ticker := newJitterTicker(cfg.MarketPrewarmInterval, backgroundJitter, 0)
+		defer ticker.Stop()
+		prewarmC = ticker.C
+	}
+
+	// Jobs run here rather than in the processor, which Drain doesn't cover;
+	// shutdown waits for them before closing the database
+	var background sync.WaitGroup
+	goBackground := func(job func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			job()
+		}()
+	}
+
+	// Refresh the tracked entity gauges
+	entityCountsTicker := newJitterTicker(entityCountsInterval, backgroundJitter, 0)
+	defer entityCountsTicker.Stop()
+	goBackground(func() { collectEntityCounts(intakeCtx, db, time.Now(), log) })
+
+	// Send the daily summary at DAILY_SUMMARY_TIME (disabled when unset)
+	var summaryC <-chan time.Time
+	var summaryTimer *time.Timer
+	if cfg.DailySummaryTime != "" {
+		next := nextDailySummary(time.Now(), cfg.DailySummaryTime, cfg.DailySummaryLocation())
+		summaryTimer = time.NewTimer(time.Until(next))
+		defer summaryTimer.Stop()
+		summaryC = summaryTimer.C
+		log.WithField("next", next.Format(time.RFC3339)).Info("Daily summary scheduled")
+	}
+
+	log.WithField("ingest_mode", cfg.IngestMode).Info("Starting trade processing loop")
+
+	// Process immediately on startup, unless the first poll is splayed
+	if pollC != nil && cfg.PollStartSplay == 0 {
+		_, err := proc.ProcessTrades(ctx)
+		logPollError(log, err)
+	}
+
+	// Run win rate calculation on startup (async), after the initial trade
+	// processing, if any, so it doesn't race it on a cold database
+	go runWinRateRecalculation(intakeCtx, proc, log)
+
+	// The configuration as reloaded by SIGHUP. Everything outside the
+	// processor keeps using cfg, whose fields a reload doesn't change.
+	running := cfg
+
+	for {
+		select {
+		case <-pollC:
+			// Run off the loop so a slow cycle doesn't hold up signals;
+			// ticks that arrive while it runs are skipped
+			go func() {
+				defer recoverJob(log, "poll")
+				_, err := proc.ProcessTrades(ctx)
+				logPollError(log, err)
+			}()
+		case reply := <-triggers.process:
+			go func() {
+				result := pollResult{err: errJobPanicked}
+				defer func() { reply <- result }()
+				defer recoverJob(log, "poll")
+				summary, err := proc.ProcessTrades(ctx)
+				logPollError(log, err)
+				result = pollResult{summary, err}
+			}()
+		case <-winRateTicker.C:
+			go runWinRateRecalculation(intakeCtx, proc, log)
+		case reply := <-triggers.recalculate:
+			go func() {
+				result := recalcResult{err: errJobPanicked}
+				defer func() { reply <- result }()
+				defer recoverJob(log, "win_rate")
+				resolved, err := proc.RecalculateWinRates(intakeCtx)
+				result = recalcResult{resolved, err}
+			}()
+		case <-withdrawalC:
+			go func() {
+				defer recoverJob(log, "withdrawals")
+				if _, err := proc.TrackWithdrawals(intakeCtx); err != nil {
+					log.WithError(err).Error("Error tracking withdrawal destinations")
+				}
+			}()
+		case <-exitScanC:
+			go func() {
+				defer recoverJob(log, "exit_scan")
+				if _, err := proc.ScanPositionExits(intakeCtx); err != nil {
+					log.WithError(err).Warn("Error scanning alerted positions for exits")
+				}
+			}()
+		case <-prewarmC:
+			go func() {
+				defer recoverJob(log, "market_prewarm")
+				if _, err := proc.PrewarmMarkets(intakeCtx); err != nil {
+					log.WithError(err).Warn("Error pre-warming the market cache")
+				}
+			}()
+		case <-summaryC:
+			goBackground(func() {
+				defer recoverJob(log, "daily_summary")
+				if _, err := sendDailySummary(intakeCtx, proc, summarySender, cfg.Environment, time.Now(), log); err != nil {
+					log.WithError(err).Error("Failed to send daily summary")
+				}
+			})
+			summaryTimer.Reset(time.Until(nextDailySummary(time.Now(), cfg.DailySummaryTime, cfg.DailySummaryLocation())))
+		case reply := <-triggers.summary:
+			goBackground(func() {
+				result := summaryResult{err: errJobPanicked}
+				defer func() { reply <- result }()
+				defer recoverJob(log, "daily_summary")
+				summary, err := sendDailySummary(intakeCtx, proc, summarySender, cfg.Environment, time.Now(), log)
+				result = summaryResult{summary, err}
+			})
+		case now := <-entityCountsTicker.C:
+			goBackground(func() { collectEntityCounts(intakeCtx, db, now, log) })
+		case <-reloadChan:
+			running = reloadConfig(running, proc, log)
+			if cfg.CustomRulesFile == "" {
+				continue
+			}
+			// Keep the previous rules if the new file is invalid
+			if err := loadCustomRules(cfg.CustomRulesFile, proc, log); err != nil {
+				log.WithError(err).Error("Failed to reload custom rules, keeping previous rules")
+			}
+		case sig := <-sigChan:
+			log.WithField("signal", sig).Info("Received shutdown signal")
+			runShutdown(shutdownSteps(func() {
+				if pollTicker != nil {
+					pollTicker.Stop()
+				}
+				stopIntake()
+			}, cancel, proc, func(ctx context.Context) error {
+				return flushAlerts(ctx, alertSender, profileSenders)
+			}, &background, server, db), log)
+			log.Info("Graceful shutdown complete")
+			return 0
+		case <-ctx.Done():
+			log.Info("Context cancelled, shutting down")
+			return 0
+		}
+	}
+}
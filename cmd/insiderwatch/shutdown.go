@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	drainTimeout        = 15 * time.Second // In-flight poll cycles and live trades
+	backgroundTimeout   = 5 * time.Second  // Storage reads outside the processor, cancelled with intake
+	httpShutdownTimeout = 5 * time.Second  // In-flight scrapes and admin requests
+	flushTimeout        = 10 * time.Second // Overflow summaries the alert rate caps still hold
+)
+
+// drainer waits for in-flight processing; *processor.Processor implements it
+type drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// shutdownStep is one stage of graceful shutdown
+type shutdownStep struct {
+	name    string
+	timeout time.Duration // 0 for steps that can't block
+	run     func(ctx context.Context) error
+}
+
+// shutdownSteps returns the shutdown sequence. Intake stops first so the
+// drain has an end. Workers send their alerts inline, so the drain also
+// flushes alerts; if it times out, cancelWork aborts what is left and those
+// trades are picked up again by the next poll. Alerts the rate caps held
+// back are then reported in their overflow summaries. Jobs main runs outside the
+// processor, such as the entity count refresh, are tracked by background and
+// waited for too. The HTTP server stays up until processing is done so
+// /metrics can still be scraped, and the database closes last.
+func shutdownSteps(stopIntake, cancelWork func(), proc drainer, flushAlerts func(ctx context.Context) error, background *sync.WaitGroup, server *http.Server, db io.Closer) []shutdownStep {
+	return []shutdownStep{
+		{name: "stop intake", run: func(ctx context.Context) error {
+			stopIntake()
+			return nil
+		}},
+		{name: "drain workers", timeout: drainTimeout, run: func(ctx context.Context) error {
+			err := proc.Drain(ctx)
+			if err != nil {
+				cancelWork()
+			}
+			return err
+		}},
+		{name: "flush held alerts", timeout: flushTimeout, run: flushAlerts},
+		{name: "wait for background jobs", timeout: backgroundTimeout, run: func(ctx context.Context) error {
+			return waitGroup(ctx, background)
+		}},
+		{name: "shutdown HTTP server", timeout: httpShutdownTimeout, run: server.Shutdown},
+		{name: "close database", run: func(ctx context.Context) error {
+			return db.Close()
+		}},
+	}
+}
+
+// runShutdown runs the steps in order. A step that fails or times out is
+// logged and the rest still run.
+func runShutdown(steps []shutdownStep, log *logrus.Logger) {
+	for _, step := range steps {
+		ctx, cancel := context.Background(), func() {}
+		if step.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, step.timeout)
+		}
+		start := time.Now()
+		err := step.run(ctx)
+		cancel()
+
+		entry := log.WithFields(logrus.Fields{"step": step.name, "duration": time.Since(start).String()})
+		if err != nil {
+			entry.WithError(err).Warn("Shutdown step failed")
+			continue
+		}
+		entry.Info("Shutdown step complete")
+	}
+}
+
+// waitGroup waits for wg or until ctx is done
+func waitGroup(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
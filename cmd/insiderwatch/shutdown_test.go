@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+)
+
+// recorder logs the order shutdown reaches each component
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	r.events = append(r.events, event)
+	r.mu.Unlock()
+}
+
+func (r *recorder) list() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+type fakeDrainer struct {
+	rec   *recorder
+	delay time.Duration
+}
+
+func (d *fakeDrainer) Drain(ctx context.Context) error {
+	select {
+	case <-time.After(d.delay):
+		d.rec.add("drain")
+		return nil
+	case <-ctx.Done():
+		d.rec.add("drain timeout")
+		return ctx.Err()
+	}
+}
+
+type fakeCloser struct{ rec *recorder }
+
+func (c fakeCloser) Close() error {
+	c.rec.add("close database")
+	return nil
+}
+
+func TestShutdownOrder(t *testing.T) {
+	rec := &recorder{}
+
+	// A slow scrape is in flight when the shutdown starts
+	scrapeStarted := make(chan struct{})
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(scrapeStarted)
+		time.Sleep(50 * time.Millisecond)
+		io.WriteString(w, "insiderwatch_up 1")
+	})}
+	server.RegisterOnShutdown(func() { rec.add("shutdown HTTP server") })
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+
+	scrape := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/metrics")
+		if err != nil {
+			scrape <- err.Error()
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		scrape <- string(body)
+	}()
+	<-scrapeStarted
+
+	// So is a background storage read, which outlasts the drain
+	var background sync.WaitGroup
+	background.Add(1)
+	go func() {
+		defer background.Done()
+		time.Sleep(30 * time.Millisecond)
+		rec.add("background job")
+	}()
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	cancelled := false
+	runShutdown(shutdownSteps(
+		func() { rec.add("stop intake") },
+		func() { cancelled = true },
+		&fakeDrainer{rec: rec, delay: 10 * time.Millisecond},
+		func(ctx context.Context) error {
+			rec.add("flush alerts")
+			return nil
+		},
+		&background,
+		server,
+		fakeCloser{rec: rec},
+	), log)
+
+	want := []string{"stop intake", "drain", "flush alerts", "background job", "shutdown HTTP server", "close database"}
+	if got := rec.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v\nDescription: Intake stops, workers drain, held alerts are flushed and background jobs finish, then HTTP shuts down before the database closes", got, want)
+	}
+	if cancelled {
+		t.Errorf("got in-flight work cancelled, want it left to finish\nDescription: Work that drains in time is not cancelled")
+	}
+	if got := <-scrape; got != "insiderwatch_up 1" {
+		t.Errorf("got %q, want the full response\nDescription: In-flight scrapes complete during shutdown", got)
+	}
+}
+
+func TestShutdownDrainTimeout(t *testing.T) {
+	rec := &recorder{}
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+
+	steps := shutdownSteps(
+		func() { rec.add("stop intake") },
+		func() { rec.add("cancel work") },
+		&fakeDrainer{rec: rec, delay: time.Hour},
+		func(ctx context.Context) error {
+			rec.add("flush alerts")
+			return nil
+		},
+		&sync.WaitGroup{},
+		&http.Server{},
+		fakeCloser{rec: rec},
+	)
+	steps[1].timeout = 10 * time.Millisecond
+	runShutdown(steps, log)
+
+	want := []string{"stop intake", "drain timeout", "cancel work", "flush alerts", "close database"}
+	if got := rec.list(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v\nDescription: Work still running at the drain timeout is cancelled and shutdown carries on", got, want)
+	}
+}
+
+func TestRecoverJob(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	before := testutil.ToFloat64(metrics.Panics.WithLabelValues("win_rate"))
+
+	// An admin job that panics still answers its request
+	reply := make(chan recalcResult, 1)
+	go func() {
+		result := recalcResult{err: errJobPanicked}
+		defer func() { reply <- result }()
+		defer recoverJob(log, "win_rate")
+		var stats map[string]int
+		stats["resolved"]++ // Nil map
+		result = recalcResult{}
+	}()
+
+	if res := <-reply; !errors.Is(res.err, errJobPanicked) {
+		t.Errorf("got %v, want errJobPanicked", res.err)
+	}
+	if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("win_rate")) - before; got != 1 {
+		t.Errorf("got %v panics counted, want 1", got)
+	}
+}
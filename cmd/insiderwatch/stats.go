@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+// statsCacheTTL is how long GET /stats reuses its storage counts, so
+// frequent scrapes cost a handful of queries every half minute at most
+const statsCacheTTL = 30 * time.Second
+
+// statsReader runs the aggregate queries behind GET /stats; *storage.DB
+// implements it
+type statsReader interface {
+	GetServiceCounts(ctx context.Context, dayStartTS int64, now time.Time) (*storage.ServiceCounts, error)
+}
+
+// pollStatusReader reports the last poll cycles; *processor.Processor
+// implements it
+type pollStatusReader interface {
+	PollStatus() processor.PollStatus
+}
+
+// serviceStats is the GET /stats response
+type serviceStats struct {
+	Version         string           `json:"version"`
+	UptimeSeconds   int64            `json:"uptime_seconds"`
+	TradesToday     int64            `json:"trades_today"` // Since midnight UTC
+	Alerts24h       map[string]int64 `json:"alerts_24h"`   // By severity
+	Alerts7d        map[string]int64 `json:"alerts_7d"`
+	Wallets         int64            `json:"wallets"`
+	Clusters        int64            `json:"clusters"`
+	FlaggedClusters int64            `json:"flagged_clusters"`
+	ResolvedMarkets int64            `json:"resolved_markets"`
+	Checkpoint      *string          `json:"checkpoint"` // Poll checkpoint as RFC 3339; null before the first poll
+	LastPoll        pollSummary      `json:"last_poll"`
+	CountsAsOf      string           `json:"counts_as_of"` // When the storage counts were read
+}
+
+type pollSummary struct {
+	LastAttempt *string `json:"last_attempt"`
+	LastSuccess *string `json:"last_success"`
+	LastError   string  `json:"last_error,omitempty"`
+}
+
+// statsCache holds the latest storage counts for statsCacheTTL
+type statsCache struct {
+	mu      sync.Mutex
+	counts  *storage.ServiceCounts
+	fetched time.Time
+}
+
+// get returns cached counts, reading them again once they are older than
+// statsCacheTTL. Concurrent requests wait for one read rather than each
+// running the queries.
+func (c *statsCache) get(ctx context.Context, db statsReader, now time.Time) (*storage.ServiceCounts, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts != nil && now.Sub(c.fetched) < statsCacheTTL {
+		return c.counts, c.fetched, nil
+	}
+	dayStart := now.UTC().Truncate(24 * time.Hour)
+	counts, err := db.GetServiceCounts(ctx, dayStart.Unix(), now)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	c.counts, c.fetched = counts, now
+	return counts, now, nil
+}
+
+// handleStats serves GET /stats: a summary of the service for dashboards
+// and eyeballing.
+func handleStats(db statsReader, proc pollStatusReader, started time.Time, log *logrus.Logger) http.HandlerFunc {
+	cache := &statsCache{}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		now := time.Now()
+		counts, asOf, err := cache.get(r.Context(), db, now)
+		if err != nil {
+			log.WithError(err).Error("Failed to read service stats")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to read stats"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(newServiceStats(counts, proc.PollStatus(), now.Sub(started), asOf))
+	}
+}
+
+func newServiceStats(counts *storage.ServiceCounts, poll processor.PollStatus, uptime time.Duration, asOf time.Time) serviceStats {
+	stats := serviceStats{
+		Version:         version.Version,
+		UptimeSeconds:   int64(uptime.Seconds()),
+		TradesToday:     counts.TradesToday,
+		Alerts24h:       map[string]int64{},
+		Alerts7d:        map[string]int64{},
+		Wallets:         counts.Wallets,
+		Clusters:        counts.Clusters,
+		FlaggedClusters: counts.FlaggedClusters,
+		ResolvedMarkets: counts.ResolvedMarkets,
+		LastPoll: pollSummary{
+			LastAttempt: formatTime(poll.LastAttempt),
+			LastSuccess: formatTime(poll.LastSuccess),
+			LastError:   poll.LastError,
+		},
+		CountsAsOf: asOf.UTC().Format(time.RFC3339),
+	}
+
+	// Every severity is listed so dashboards see zeros rather than gaps
+	for _, severity := range []alerts.Severity{alerts.SeverityInfo, alerts.SeverityWarn, alerts.SeverityAlert} {
+		stats.Alerts24h[string(severity)] = 0
+		stats.Alerts7d[string(severity)] = 0
+	}
+	for _, a := range counts.Alerts {
+		stats.Alerts24h[a.AlertType] = a.Last24h
+		stats.Alerts7d[a.AlertType] = a.Last7d
+	}
+
+	if counts.LastProcessedTS > 0 {
+		stats.Checkpoint = formatTime(time.Unix(counts.LastProcessedTS, 0))
+	}
+	return stats
+}
+
+// formatTime formats t as RFC 3339 in UTC, or returns nil for the zero time
+func formatTime(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	s := t.UTC().Format(time.RFC3339)
+	return &s
+}
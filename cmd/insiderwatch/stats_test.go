@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeStats counts how often the aggregate queries run
+type fakeStats struct {
+	calls    int
+	dayStart int64
+	err      error
+	counts   storage.ServiceCounts
+}
+
+func (f *fakeStats) GetServiceCounts(ctx context.Context, dayStartTS int64, now time.Time) (*storage.ServiceCounts, error) {
+	f.calls++
+	f.dayStart = dayStartTS
+	if f.err != nil {
+		return nil, f.err
+	}
+	counts := f.counts
+	return &counts, nil
+}
+
+type fakePollStatus processor.PollStatus
+
+func (f fakePollStatus) PollStatus() processor.PollStatus {
+	return processor.PollStatus(f)
+}
+
+func TestStats(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	store := &fakeStats{counts: storage.ServiceCounts{
+		TradesToday:     120,
+		Alerts:          []storage.AlertCounts{{AlertType: "ALERT", Last24h: 2, Last7d: 9}},
+		Wallets:         40,
+		LastProcessedTS: 1700000000,
+	}}
+	poll := fakePollStatus{LastAttempt: time.Unix(1700000100, 0), LastError: "poll timed out"}
+	handler := handleStats(store, poll, time.Now().Add(-time.Hour), log)
+
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest("GET", "/stats", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var got serviceStats
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.TradesToday != 120 || got.Wallets != 40 || got.UptimeSeconds < 3600 {
+		t.Errorf("got %d trades today, %d wallets, %ds uptime, want 120, 40 and at least 3600s", got.TradesToday, got.Wallets, got.UptimeSeconds)
+	}
+	if got.Alerts24h["ALERT"] != 2 || got.Alerts7d["ALERT"] != 9 {
+		t.Errorf("got ALERT counts %d/%d, want 2/9", got.Alerts24h["ALERT"], got.Alerts7d["ALERT"])
+	}
+	if n, ok := got.Alerts7d["INFO"]; !ok || n != 0 {
+		t.Errorf("got INFO count %d (listed %v), want a listed 0", n, ok)
+	}
+	if got.Checkpoint == nil || *got.Checkpoint != "2023-11-14T22:13:20Z" {
+		t.Errorf("got checkpoint %v, want 2023-11-14T22:13:20Z", got.Checkpoint)
+	}
+	if got.LastPoll.LastSuccess != nil || got.LastPoll.LastError != "poll timed out" {
+		t.Errorf("got last poll %+v, want no success and the error", got.LastPoll)
+	}
+	if store.dayStart%86400 != 0 {
+		t.Errorf("got day start %d, want midnight UTC", store.dayStart)
+	}
+}
+
+func TestStatsCache(t *testing.T) {
+	store := &fakeStats{}
+	cache := &statsCache{}
+	now := time.Now()
+
+	tests := []struct {
+		name          string
+		at            time.Time
+		expectedCalls int
+		description   string
+	}{
+		{"first", now, 1, "The first request reads storage"},
+		{"cached", now.Add(10 * time.Second), 1, "Requests within the TTL reuse the counts"},
+		{"expired", now.Add(statsCacheTTL), 2, "Counts are read again once they reach the TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := cache.get(context.Background(), store, tt.at); err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if store.calls != tt.expectedCalls {
+				t.Errorf("got %d reads, want %d\nDescription: %s", store.calls, tt.expectedCalls, tt.description)
+			}
+		})
+	}
+
+	// A failed read isn't cached
+	store.err = errors.New("db down")
+	later := now.Add(2 * statsCacheTTL)
+	if _, _, err := cache.get(context.Background(), store, later); err == nil {
+		t.Fatalf("got no error, want the storage error")
+	}
+	store.err = nil
+	if _, _, err := cache.get(context.Background(), store, later); err != nil || store.calls != 4 {
+		t.Errorf("got %v after %d reads, want a fresh read after the failure", err, store.calls)
+	}
+}
+
+func TestStatsAuth(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	handler := requireToken(handleStats(&fakeStats{}, fakePollStatus{}, time.Now(), log), "secret")
+
+	tests := []struct {
+		name           string
+		auth           string
+		expectedStatus int
+		description    string
+	}{
+		{"missing", "", http.StatusUnauthorized, "Requests without the token are rejected"},
+		{"valid", "Bearer secret", http.StatusOK, "The configured token is accepted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/stats", nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d, want %d\nDescription: %s", rec.Code, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
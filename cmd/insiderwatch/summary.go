@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/errreport"
+	"github.com/sirupsen/logrus"
+)
+
+// summaryGenerator builds the daily summary; *processor.Processor implements it
+type summaryGenerator interface {
+	GenerateDailySummary(ctx context.Context, until time.Time) (*alerts.DailySummary, error)
+}
+
+// summaryResult is the outcome of a daily summary sent on request
+type summaryResult struct {
+	summary *alerts.DailySummary
+	err     error
+}
+
+// nextDailySummary returns the first time after now that the wall clock in
+// loc reads clock (HH:MM, already validated)
+func nextDailySummary(now time.Time, clock string, loc *time.Location) time.Time {
+	at, _ := time.Parse("15:04", clock)
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), at.Hour(), at.Minute(), 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, at.Hour(), at.Minute(), 0, 0, loc)
+	}
+	return next
+}
+
+// sendDailySummary generates the summary of the day up to now and sends it
+// to the summary channels
+func sendDailySummary(ctx context.Context, gen summaryGenerator, sender alerts.Sender, environment string, now time.Time, log *logrus.Logger) (*alerts.DailySummary, error) {
+	summary, err := gen.GenerateDailySummary(ctx, now)
+	if err != nil {
+		return nil, fmt.Errorf("generate summary: %w", err)
+	}
+	payload := &alerts.AlertPayload{
+		Summary:     summary,
+		Timestamp:   now,
+		Environment: environment,
+	}
+	if err := sender.Send(ctx, payload); err != nil {
+		errreport.Capture(errreport.Event{Err: err, Source: "alert_send", Tags: map[string]string{"alert_kind": "daily_summary"}})
+		return summary, fmt.Errorf("send summary: %w", err)
+	}
+	log.WithFields(logrus.Fields{
+		"date":              summary.Date,
+		"qualifying_trades": summary.QualifyingTrades,
+		"top_alerts":        len(summary.TopAlerts),
+	}).Info("Daily summary sent")
+	return summary, nil
+}
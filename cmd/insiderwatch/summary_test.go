@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/sirupsen/logrus"
+)
+
+func TestNextDailySummary(t *testing.T) {
+	loc := time.FixedZone("UTC-5", -5*3600)
+
+	tests := []struct {
+		name        string
+		now         time.Time
+		clock       string
+		expected    time.Time
+		description string
+	}{
+		{"later today", time.Date(2024, 6, 3, 9, 0, 0, 0, loc), "18:00", time.Date(2024, 6, 3, 18, 0, 0, 0, loc), "A time still ahead today is today"},
+		{"passed", time.Date(2024, 6, 3, 19, 0, 0, 0, loc), "18:00", time.Date(2024, 6, 4, 18, 0, 0, 0, loc), "A time already passed is tomorrow"},
+		{"exactly now", time.Date(2024, 6, 3, 18, 0, 0, 0, loc), "18:00", time.Date(2024, 6, 4, 18, 0, 0, 0, loc), "The summary that just fired isn't scheduled again"},
+		{"other zone", time.Date(2024, 6, 3, 22, 30, 0, 0, time.UTC), "18:00", time.Date(2024, 6, 3, 18, 0, 0, 0, loc), "The clock is read in the configured zone, where it is still 17:30"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := nextDailySummary(tt.now, tt.clock, loc)
+			if !got.Equal(tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+// fakeSummaries generates a fixed summary, or fails with err
+type fakeSummaries struct {
+	err   error
+	until time.Time
+}
+
+func (f *fakeSummaries) GenerateDailySummary(ctx context.Context, until time.Time) (*alerts.DailySummary, error) {
+	f.until = until
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &alerts.DailySummary{
+		Date:             "2024-06-03",
+		QualifyingTrades: 12,
+		Alerts:           map[alerts.Severity]int64{alerts.SeverityAlert: 2},
+		TopAlerts:        []alerts.SummaryAlert{{AlertID: 9}, {AlertID: 4}},
+	}, nil
+}
+
+// recordingSender keeps the payloads it is sent
+type recordingSender struct {
+	err  error
+	sent []*alerts.AlertPayload
+}
+
+func (s *recordingSender) Send(ctx context.Context, payload *alerts.AlertPayload) error {
+	s.sent = append(s.sent, payload)
+	return s.err
+}
+
+func TestSendDailySummary(t *testing.T) {
+	now := time.Date(2024, 6, 3, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		genErr      error
+		sendErr     error
+		wantSent    int
+		wantErr     bool
+		description string
+	}{
+		{"sent", nil, nil, 1, false, "The summary is sent once as a summary payload"},
+		{"generate fails", errors.New("db down"), nil, 0, true, "Nothing is sent when the summary can't be built"},
+		{"send fails", nil, errors.New("webhook down"), 1, true, "Delivery failures are reported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			gen := &fakeSummaries{err: tt.genErr}
+			sender := &recordingSender{err: tt.sendErr}
+
+			_, err := sendDailySummary(context.Background(), gen, sender, "test", now, log)
+			if (err != nil) != tt.wantErr || len(sender.sent) != tt.wantSent {
+				t.Fatalf("got %v with %d sent, want error %v with %d sent\nDescription: %s", err, len(sender.sent), tt.wantErr, tt.wantSent, tt.description)
+			}
+			if tt.wantSent > 0 && (sender.sent[0].Summary == nil || sender.sent[0].Environment != "test" || !gen.until.Equal(now)) {
+				t.Errorf("got %+v for %v, want the summary up to %v\nDescription: %s", sender.sent[0], gen.until, now, tt.description)
+			}
+		})
+	}
+}
+
+func TestSummaryNow(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	triggers := newAdminTriggers(make(chan struct{}))
+	go func() {
+		for reply := range triggers.summary {
+			summary, err := (&fakeSummaries{}).GenerateDailySummary(context.Background(), time.Now())
+			reply <- summaryResult{summary, err}
+		}
+	}()
+
+	req := httptest.NewRequest("POST", "/admin/summary", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	handleSummaryNow(triggers, "secret", log)(rec, req)
+
+	var got summaryJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("got %v decoding %s", err, rec.Body)
+	}
+	if rec.Code != http.StatusOK || got.QualifyingTrades != 12 || got.Alerts["ALERT"] != 2 || len(got.TopAlertIDs) != 2 || got.TopAlertIDs[0] != 9 {
+		t.Errorf("got %d %+v, want 200 with the summary's totals and top alert IDs", rec.Code, got)
+	}
+}
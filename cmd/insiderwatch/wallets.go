@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// handleWalletPositions serves GET /wallets/{address}/positions: the
+// wallet's current positions and total open exposure from the Data API
+func handleWalletPositions(proc *processor.Processor, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		address := r.PathValue("address")
+		exposure, err := proc.WalletExposure(r.Context(), address)
+		if err != nil {
+			log.WithError(err).WithField("wallet", address).Warn("Failed to get wallet positions")
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to fetch positions"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(exposure)
+	}
+}
+
+const (
+	walletDetailTrades = 50
+	walletDetailAlerts = 20
+)
+
+// walletAddressPattern matches a wallet address in any hex case
+var walletAddressPattern = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// walletReader reads what storage knows about a wallet; *storage.DB
+// implements it
+type walletReader interface {
+	alertLister
+	GetWallet(ctx context.Context, address string) (*storage.Wallet, error)
+	GetWalletStats(ctx context.Context, walletAddress string) (*storage.WalletStats, error)
+	GetWalletFundingSource(ctx context.Context, walletAddress string) (*storage.WalletFundingSource, error)
+	GetClusterMember(ctx context.Context, walletAddress string) (*storage.WalletClusterMember, error)
+	GetWalletClusterByID(ctx context.Context, clusterID string) (*storage.WalletCluster, error)
+	GetWalletTrades(ctx context.Context, walletAddress string, limit int) ([]storage.TradeSeen, error)
+}
+
+// walletDetail is the GET /wallets/{address} response
+type walletDetail struct {
+	Address      string         `json:"address"`
+	Wallet       walletSummary  `json:"wallet"`
+	Funding      *walletFunding `json:"funding"` // null when no funding source was found
+	Stats        *walletStats   `json:"stats"`   // null until a market the wallet traded resolves
+	Cluster      *walletCluster `json:"cluster"` // null when the wallet isn't in a cluster
+	RecentTrades []walletTrade  `json:"recent_trades"`
+	RecentAlerts []alertItem    `json:"recent_alerts"`
+}
+
+type walletSummary struct {
+	FirstSeenTS       int64   `json:"first_seen_ts"`
+	AgeDays           int     `json:"age_days"`
+	FundingReceivedTS int64   `json:"funding_received_ts"`
+	TotalTrades       int     `json:"total_trades"`
+	TotalVolumeUSD    float64 `json:"total_volume_usd"`
+	LastActivityTS    int64   `json:"last_activity_ts"`
+}
+
+type walletFunding struct {
+	Source    string  `json:"source"`
+	FundingTS int64   `json:"funding_ts"`
+	AmountUSD float64 `json:"amount_usd"`
+	TxHash    string  `json:"tx_hash"`
+}
+
+type walletStats struct {
+	ResolvedTrades   int     `json:"resolved_trades"`
+	WinningTrades    int     `json:"winning_trades"`
+	LosingTrades     int     `json:"losing_trades"`
+	WinRate          float64 `json:"win_rate"`
+	RealizedPnLUSD   float64 `json:"realized_pnl_usd"`
+	LastCalculatedTS int64   `json:"last_calculated_ts"`
+}
+
+type walletCluster struct {
+	ClusterID             string  `json:"cluster_id"`
+	LinkType              string  `json:"link_type"` // How this wallet was linked: funding, withdrawal or both
+	FundingSource         string  `json:"funding_source"`
+	WithdrawalDestination string  `json:"withdrawal_destination"`
+	WalletCount           int     `json:"wallet_count"`
+	TotalVolumeUSD        float64 `json:"total_volume_usd"`
+	SuspicionScore        float64 `json:"suspicion_score"`
+	IsFlagged             bool    `json:"is_flagged"`
+}
+
+type walletTrade struct {
+	ConditionID     string  `json:"condition_id"`
+	TransactionHash string  `json:"transaction_hash"`
+	Side            string  `json:"side"`
+	Outcome         string  `json:"outcome"`
+	Price           float64 `json:"price"`
+	NotionalUSD     float64 `json:"notional_usd"`
+	Role            string  `json:"role"`
+	Timestamp       int64   `json:"timestamp"`
+}
+
+// handleWalletDetail serves GET /wallets/{address}: everything storage
+// knows about a tracked wallet.
+func handleWalletDetail(db walletReader, log *logrus.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		address := r.PathValue("address")
+		if !walletAddressPattern.MatchString(address) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "address must be a 0x-prefixed 40 character hex address"})
+			return
+		}
+		address = strings.ToLower(address)
+
+		detail, err := loadWalletDetail(r.Context(), db, address, time.Now())
+		if err != nil {
+			log.WithError(err).WithField("wallet", address).Error("Failed to load wallet")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to load wallet"})
+			return
+		}
+		if detail == nil {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "wallet not tracked"})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(detail)
+	}
+}
+
+// loadWalletDetail composes the wallet's storage records. It returns nil
+// for a wallet that has never been tracked.
+func loadWalletDetail(ctx context.Context, db walletReader, address string, now time.Time) (*walletDetail, error) {
+	wallet, err := db.GetWallet(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get wallet: %w", err)
+	}
+	if wallet == nil {
+		return nil, nil
+	}
+
+	detail := &walletDetail{
+		Address: address,
+		Wallet: walletSummary{
+			FirstSeenTS:       wallet.FirstSeenTS,
+			AgeDays:           int((now.Unix() - wallet.FirstSeenTS) / 86400),
+			FundingReceivedTS: wallet.FundingReceivedTS,
+			TotalTrades:       wallet.TotalTrades,
+			TotalVolumeUSD:    wallet.TotalVolumeUSD,
+			LastActivityTS:    wallet.LastActivityTS,
+		},
+		RecentTrades: []walletTrade{},
+		RecentAlerts: []alertItem{},
+	}
+
+	funding, err := db.GetWalletFundingSource(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get funding source: %w", err)
+	}
+	if funding != nil {
+		detail.Funding = newWalletFunding(funding)
+	}
+
+	stats, err := db.GetWalletStats(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get wallet stats: %w", err)
+	}
+	if stats != nil {
+		detail.Stats = &walletStats{
+			ResolvedTrades:   stats.TotalResolvedTrades,
+			WinningTrades:    stats.WinningTrades,
+			LosingTrades:     stats.LosingTrades,
+			WinRate:          stats.WinRate,
+			RealizedPnLUSD:   stats.TotalProfitUSD,
+			LastCalculatedTS: stats.LastCalculatedTS,
+		}
+	}
+
+	member, err := db.GetClusterMember(ctx, address)
+	if err != nil {
+		return nil, fmt.Errorf("get cluster member: %w", err)
+	}
+	if member != nil {
+		cluster, err := db.GetWalletClusterByID(ctx, member.ClusterID)
+		if err != nil {
+			return nil, fmt.Errorf("get cluster: %w", err)
+		}
+		if cluster != nil {
+			detail.Cluster = newWalletCluster(cluster, member)
+		}
+	}
+
+	trades, err := db.GetWalletTrades(ctx, address, walletDetailTrades)
+	if err != nil {
+		return nil, fmt.Errorf("get trades: %w", err)
+	}
+	for _, t := range trades {
+		detail.RecentTrades = append(detail.RecentTrades, walletTrade{
+			ConditionID:     t.ConditionID,
+			TransactionHash: t.TransactionHash,
+			Side:            t.Side,
+			Outcome:         t.Outcome,
+			Price:           t.Price,
+			NotionalUSD:     t.NotionalUSD,
+			Role:            t.Role,
+			Timestamp:       t.TimestampSec,
+		})
+	}
+
+	alertRows, _, err := db.ListAlerts(ctx, storage.AlertFilter{Wallet: address, Limit: walletDetailAlerts})
+	if err != nil {
+		return nil, fmt.Errorf("list alerts: %w", err)
+	}
+	for _, a := range alertRows {
+		detail.RecentAlerts = append(detail.RecentAlerts, newAlertItem(a))
+	}
+
+	return detail, nil
+}
+
+// newWalletFunding converts a stored funding source
+func newWalletFunding(funding *storage.WalletFundingSource) *walletFunding {
+	return &walletFunding{
+		Source:    funding.FundingSource,
+		FundingTS: funding.FundingTS,
+		AmountUSD: funding.AmountUSD,
+		TxHash:    funding.TxHash,
+	}
+}
+
+// newWalletCluster converts a stored cluster and the wallet's membership
+func newWalletCluster(cluster *storage.WalletCluster, member *storage.WalletClusterMember) *walletCluster {
+	return &walletCluster{
+		ClusterID:             cluster.ClusterID,
+		LinkType:              member.LinkType,
+		FundingSource:         cluster.FundingSource,
+		WithdrawalDestination: cluster.WithdrawalDestination,
+		WalletCount:           cluster.WalletCount,
+		TotalVolumeUSD:        cluster.TotalVolumeUSD,
+		SuspicionScore:        cluster.SuspicionScore,
+		IsFlagged:             cluster.IsFlagged,
+	}
+}
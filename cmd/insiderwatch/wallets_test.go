@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+const testWallet = "0xabcdef1111111111111111111111111111111111"
+
+// fakeWalletStore holds one tracked wallet in a cluster
+type fakeWalletStore struct {
+	fakeAlerts
+	err    error
+	lookup string // Address the wallet was looked up by
+}
+
+func (f *fakeWalletStore) GetWallet(ctx context.Context, address string) (*storage.Wallet, error) {
+	f.lookup = address
+	if f.err != nil {
+		return nil, f.err
+	}
+	if address != testWallet {
+		return nil, nil
+	}
+	return &storage.Wallet{WalletAddress: testWallet, FirstSeenTS: 1, TotalTrades: 3, TotalVolumeUSD: 75000}, nil
+}
+
+func (f *fakeWalletStore) GetWalletStats(ctx context.Context, walletAddress string) (*storage.WalletStats, error) {
+	return &storage.WalletStats{WalletAddress: walletAddress, TotalResolvedTrades: 4, WinningTrades: 3, WinRate: 0.75, TotalProfitUSD: 1200}, nil
+}
+
+func (f *fakeWalletStore) GetWalletFundingSource(ctx context.Context, walletAddress string) (*storage.WalletFundingSource, error) {
+	return nil, nil
+}
+
+func (f *fakeWalletStore) GetClusterMember(ctx context.Context, walletAddress string) (*storage.WalletClusterMember, error) {
+	return &storage.WalletClusterMember{WalletAddress: walletAddress, ClusterID: "cluster-1", LinkType: storage.LinkFunding}, nil
+}
+
+func (f *fakeWalletStore) GetWalletClusterByID(ctx context.Context, clusterID string) (*storage.WalletCluster, error) {
+	return &storage.WalletCluster{ClusterID: clusterID, WalletCount: 4, IsFlagged: true}, nil
+}
+
+func (f *fakeWalletStore) GetWalletTrades(ctx context.Context, walletAddress string, limit int) ([]storage.TradeSeen, error) {
+	return []storage.TradeSeen{{ProxyWallet: walletAddress, ConditionID: "0xcond", Side: "BUY", NotionalUSD: 25000}}, nil
+}
+
+func TestWalletDetail(t *testing.T) {
+	tests := []struct {
+		name           string
+		address        string
+		storeErr       error
+		expectedStatus int
+		description    string
+	}{
+		{"tracked", testWallet, nil, http.StatusOK, "A tracked wallet returns its detail"},
+		{"mixed case", "0xAbCdEf1111111111111111111111111111111111", nil, http.StatusOK, "Addresses match in any hex case"},
+		{"unknown", "0x2222222222222222222222222222222222222222", nil, http.StatusNotFound, "A wallet storage doesn't know is a 404"},
+		{"malformed", "0x1234", nil, http.StatusBadRequest, "Addresses must be 40 hex characters"},
+		{"not hex", "0x111111111111111111111111111111111111111z", nil, http.StatusBadRequest, "Addresses must be hex"},
+		{"storage error", testWallet, errors.New("db down"), http.StatusInternalServerError, "Storage failures are a 500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetLevel(logrus.PanicLevel)
+			store := &fakeWalletStore{fakeAlerts: fakeAlerts{n: 2}, err: tt.storeErr}
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /wallets/{address}", handleWalletDetail(store, log))
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest("GET", "/wallets/"+tt.address, nil))
+
+			if rec.Code != tt.expectedStatus {
+				t.Errorf("got %d (%s), want %d\nDescription: %s", rec.Code, rec.Body, tt.expectedStatus, tt.description)
+			}
+		})
+	}
+}
+
+func TestWalletDetailNormalizesAddress(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	store := &fakeWalletStore{fakeAlerts: fakeAlerts{n: 2}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /wallets/{address}", handleWalletDetail(store, log))
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/wallets/0xABCDEF1111111111111111111111111111111111", nil))
+
+	var detail walletDetail
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("got %v decoding %s", err, rec.Body)
+	}
+	if store.lookup != testWallet || detail.Address != testWallet {
+		t.Errorf("got lookup %q and address %q, want %q\nDescription: Addresses are normalized to lowercase", store.lookup, detail.Address, testWallet)
+	}
+	if detail.Stats == nil || detail.Stats.RealizedPnLUSD != 1200 || detail.Cluster == nil || !detail.Cluster.IsFlagged || detail.Funding != nil {
+		t.Errorf("got %+v, want stats and cluster but no funding\nDescription: The detail composes each storage read", detail)
+	}
+	if len(detail.RecentTrades) != 1 || len(detail.RecentAlerts) != 2 || store.filter.Wallet != testWallet {
+		t.Errorf("got %d trades and %d alerts (filter %+v), want 1 and 2 for the wallet\nDescription: Recent trades and alerts are included", len(detail.RecentTrades), len(detail.RecentAlerts), store.filter)
+	}
+}
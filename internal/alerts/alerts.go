@@ -2,6 +2,7 @@ package alerts
 
 import (
 	"context"
+	"fmt"
 	"time"
 )
 
@@ -14,6 +15,12 @@ const (
 	SeverityAlert Severity = "ALERT"
 )
 
+// Coordination patterns for cluster trades on the same market
+const (
+	CoordinationSameSide = "same_side" // Cluster wallets piled into the same position
+	CoordinationOpposing = "opposing"  // Cluster wallets took opposite sides (hedging across wallets)
+)
+
 // ScoreBreakdown contains the calculation details for the suspicion score
 type ScoreBreakdown struct {
 	BaseScore                  float64
@@ -38,13 +45,65 @@ type ScoreBreakdown struct {
 	HoursToClose               float64
 	LiquidityRatio             float64
 	NetConcentration           float64
+	ConcentrationWindowHrs     int // Lookback NetConcentration was measured over
 	VelocityCount              int
 	ClusterID                  string
 	IsCoordinated              bool
+	CoordinationPattern        string // same_side or opposing, when IsCoordinated
+	MakerMultiplier            float64 // Discount for maker fills
+	FundingUtilizationMultiplier float64
+	FundingUtilization         float64 // First trade notional / initial funding amount
+	SizeAnomalyMultiplier      float64
+	SizeRatio                  float64 // Trade notional / wallet's typical trade size
+	CopyTradeMultiplier        float64
+	CopyLeader                 string // Flagged wallet whose trade this one mirrored
+	CopyLagMinutes             float64
+	LosingRecordMultiplier     float64 // Below 1.0: discount for a proven losing record
+	HolderDominanceMultiplier  float64
+	HolderRank                 int     // Wallet's rank among the outcome's top holders; 0 when not checked or not listed
+	HolderShare                float64 // Wallet's share of the top holders' combined balance
+	IsMaker                    bool
+	Evidence                   []string // Reasons reported by the scoring rules that fired
+	FiredRules                 []string // Names of the scoring rules that fired, in the order of Evidence
+	DisabledRules              []string // Built-in rules switched off in the config, which never ran
+	CustomRules                []CustomRuleResult // User-defined rules that fired
+}
+
+// CustomRuleResult is a user-defined rule that contributed to the score
+type CustomRuleResult struct {
+	Label      string
+	Multiplier float64
+}
+
+// CombinedMultiplier returns the product of every multiplier in the breakdown.
+// FinalScore is always BaseScore * CombinedMultiplier().
+func (b *ScoreBreakdown) CombinedMultiplier() float64 {
+	combined := b.TimeToCloseMultiplier *
+		b.WinRateMultiplier *
+		b.FirstTradeLargeMultiplier *
+		b.FlashFundingMultiplier *
+		b.LiquidityMultiplier *
+		b.PriceConfidenceMultiplier *
+		b.ConcentrationMultiplier *
+		b.VelocityMultiplier *
+		b.ClusterMultiplier *
+		b.CoordinatedMultiplier *
+		b.FundingAgeMultiplier *
+		b.MakerMultiplier *
+		b.FundingUtilizationMultiplier *
+		b.SizeAnomalyMultiplier *
+		b.CopyTradeMultiplier *
+		b.LosingRecordMultiplier *
+		b.HolderDominanceMultiplier
+	for _, r := range b.CustomRules {
+		combined *= r.Multiplier
+	}
+	return combined
 }
 
 // AlertPayload contains all information for an alert
 type AlertPayload struct {
+	AlertID         int64 // Stored alert, for GET /alerts/{id}; 0 when not stored
 	Severity        Severity
 	WalletAddress   string
 	WalletShort     string // Shortened for display
@@ -54,18 +113,134 @@ type AlertPayload struct {
 	Outcome         string
 	NotionalUSD     float64
 	Price           float64
+	CurrentPrice    float64 // Outcome midpoint when the alert was sent; 0 when unavailable
 	WalletAgeDays      int
 	FirstSeenDate      string
 	SuspicionScore     float64 // Raw score (kept for backwards compatibility)
 	NormalizedScore    float64 // 0-100 normalized score (primary display)
 	ScoreBreakdown     *ScoreBreakdown // Calculation details
+	BookDepthUSD       float64 // Order book depth near the trade price before the trade; 0 when not checked
+	BookConsumed       float64 // Fraction of that depth the trade took
+	ExposureUSD        float64 // Current value of the wallet's open positions; 0 when not fetched
+	ExposureMarkets    int     // Markets those positions span
 	TransactionHash    string
 	TxHashShort     string // Shortened for display
 	Timestamp       time.Time
 	Environment     string
+	Exit            *ExitDetails // Set when this is a follow-up about an alerted wallet exiting its position
+	Aggregate       *AggregateExposure // Set when this is an aggregate exposure alert rather than a trade alert
+	Summary         *DailySummary // Set when this is the daily summary rather than a trade alert
+	Overflow        *OverflowSummary // Set when this reports alerts a channel's rate cap held back
+}
+
+// ExitDetails describes a previously alerted wallet reversing its position.
+// The payload's trade fields describe the exit trade.
+type ExitDetails struct {
+	OriginalAlertID  int64
+	AlertedAt        time.Time
+	EntrySide        string
+	EntryPrice       float64
+	EntryNotionalUSD float64
+	ExitPrice        float64 // Average price of the reversing trades
+	ExitedFraction   float64 // Share of the alerted position reversed so far
+	PnLUSD           float64 // Implied profit on the reversed shares
+}
+
+// AggregateExposure describes a new wallet whose buys across markets added
+// up within a window. The payload's trade fields describe the trade that
+// crossed the threshold.
+type AggregateExposure struct {
+	TotalUSD    float64
+	WindowHours int
+	WindowStart time.Time
+	Markets     []ExposureMarket // Largest first
+}
+
+// ExposureMarket is one market's part of an aggregate exposure
+type ExposureMarket struct {
+	MarketTitle string
+	MarketURL   string
+	NotionalUSD float64
+	Trades      int64
+}
+
+// DailySummary is the end-of-day report. A payload carrying one has no trade
+// fields; only Timestamp and Environment are set alongside it.
+type DailySummary struct {
+	Date                string // Day the report covers, in its time zone
+	Timezone            string
+	From                time.Time
+	To                  time.Time
+	QualifyingTrades    int64 // Trades that passed the filters and were scored
+	QualifyingVolumeUSD float64
+	Alerts              map[Severity]int64
+	TopAlerts           []SummaryAlert   // Highest scoring notified alerts, best first
+	FlaggedClusters     []SummaryCluster // Flagged clusters first seen in the period
+	FlaggedWins         []SummaryWin     // Resolved markets where alerted wallets bought the winner
+}
+
+// SummaryAlert is one of the day's top alerts
+type SummaryAlert struct {
+	AlertID         int64
+	Severity        Severity
+	WalletAddress   string
+	WalletShort     string // Shortened for display
+	MarketTitle     string
+	MarketURL       string
+	Side            string
+	Outcome         string
+	NotionalUSD     float64
+	NormalizedScore float64
+}
+
+// SummaryCluster is a newly flagged wallet cluster
+type SummaryCluster struct {
+	ClusterID      string
+	WalletCount    int
+	TotalVolumeUSD float64
+}
+
+// SummaryWin is a resolved market where alerted wallets held the winner
+type SummaryWin struct {
+	MarketTitle    string
+	MarketURL      string
+	WinningOutcome string
+	Wallets        int64
+	AlertedUSD     float64
+}
+
+// OverflowSummary reports the alerts a channel's rate cap held back over a
+// period. A payload carrying one has no trade fields; only Timestamp and
+// Environment are set alongside it.
+type OverflowSummary struct {
+	Suppressed int
+	BySeverity map[Severity]int
+	From       time.Time     // When the first alert was held back
+	To         time.Time     // When the last one was
+	Top        *AlertPayload // Highest scoring alert held back
 }
 
 // Sender defines the interface for alert senders
 type Sender interface {
 	Send(ctx context.Context, payload *AlertPayload) error
 }
+
+// formatPrice shows the entry price, alongside the current price when known
+func formatPrice(entry, current float64) string {
+	if current <= 0 {
+		return fmt.Sprintf("%.2f", entry)
+	}
+	return fmt.Sprintf("current %.2f (entry %.2f)", current, entry)
+}
+
+// displayLayout is how senders show a time; the zone abbreviation says
+// which DISPLAY_TIMEZONE it is in
+const displayLayout = "2006-01-02 15:04:05 MST"
+
+// displayTime formats t in loc, or in UTC when loc is nil
+func displayTime(t time.Time, loc *time.Location) string {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format(displayLayout)
+}
@@ -7,19 +7,23 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/version"
 )
 
 // DiscordSender sends alerts to Discord via webhook
 type DiscordSender struct {
 	webhookURL string
 	httpClient *http.Client
+	loc        *time.Location // DISPLAY_TIMEZONE
 }
 
-// NewDiscordSender creates a new Discord sender
-func NewDiscordSender(webhookURL string) *DiscordSender {
+// NewDiscordSender creates a new Discord sender showing times in loc
+func NewDiscordSender(webhookURL string, loc *time.Location) *DiscordSender {
 	return &DiscordSender{
 		webhookURL: webhookURL,
 		httpClient: &http.Client{Timeout: 10 * time.Second},
+		loc:        loc,
 	}
 }
 
@@ -57,6 +61,19 @@ func (s *DiscordSender) Send(ctx context.Context, payload *AlertPayload) error {
 }
 
 func (s *DiscordSender) buildEmbed(payload *AlertPayload) map[string]interface{} {
+	if payload.Summary != nil {
+		return s.buildSummaryEmbed(payload)
+	}
+	if payload.Exit != nil {
+		return s.buildExitEmbed(payload)
+	}
+	if payload.Aggregate != nil {
+		return s.buildExposureEmbed(payload)
+	}
+	if payload.Overflow != nil {
+		return s.buildOverflowEmbed(payload)
+	}
+
 	// Determine title and color
 	var title string
 	var color int
@@ -105,7 +122,7 @@ func (s *DiscordSender) buildEmbed(payload *AlertPayload) map[string]interface{}
 		},
 		{
 			"name":   "Bet Price",
-			"value":  fmt.Sprintf("%.2f", payload.Price),
+			"value":  formatPrice(payload.Price, payload.CurrentPrice),
 			"inline": true,
 		},
 		{
@@ -123,6 +140,27 @@ func (s *DiscordSender) buildEmbed(payload *AlertPayload) map[string]interface{}
 			"value":  fmt.Sprintf("`%s`", payload.TxHashShort),
 			"inline": true,
 		},
+		{
+			"name":   "Traded",
+			"value":  discordTime(payload.Timestamp, s.loc),
+			"inline": true,
+		},
+	}
+
+	if payload.BookDepthUSD > 0 {
+		fields = append(fields, map[string]interface{}{
+			"name":   "Book Depth",
+			"value":  fmt.Sprintf("Consumed **%.0f%%** of visible depth ($%.0f)", payload.BookConsumed*100, payload.BookDepthUSD),
+			"inline": true,
+		})
+	}
+
+	if payload.ExposureMarkets > 0 {
+		fields = append(fields, map[string]interface{}{
+			"name":   "Open Exposure",
+			"value":  fmt.Sprintf("$%.0f across %d markets", payload.ExposureUSD, payload.ExposureMarkets),
+			"inline": true,
+		})
 	}
 
 	// Add score breakdown if available
@@ -135,9 +173,13 @@ func (s *DiscordSender) buildEmbed(payload *AlertPayload) map[string]interface{}
 		})
 	}
 
-	// Footer
+	// Footer, led by the alert ID so the full record is one lookup away
+	footerText := fmt.Sprintf("Whale Activity • %s • %s • %s", payload.Environment, version.Short(), displayTime(payload.Timestamp, s.loc))
+	if payload.AlertID > 0 {
+		footerText = fmt.Sprintf("Alert #%d • %s", payload.AlertID, footerText)
+	}
 	footer := map[string]interface{}{
-		"text": fmt.Sprintf("Whale Activity • %s • %s", payload.Environment, payload.Timestamp.UTC().Format("2006-01-02 15:04:05 UTC")),
+		"text": footerText,
 	}
 
 	embed := map[string]interface{}{
@@ -153,6 +195,249 @@ func (s *DiscordSender) buildEmbed(payload *AlertPayload) map[string]interface{}
 	return embed
 }
 
+// buildSummaryEmbed lays out the daily summary: totals up top, then one
+// field per section, each linking to the markets involved
+func (s *DiscordSender) buildSummaryEmbed(payload *AlertPayload) map[string]interface{} {
+	summary := payload.Summary
+
+	description := fmt.Sprintf("**%d** qualifying trades ($%.0f)\n🚨 **%d** ALERT • ⚠️ **%d** WARN • ℹ️ **%d** INFO",
+		summary.QualifyingTrades,
+		summary.QualifyingVolumeUSD,
+		summary.Alerts[SeverityAlert],
+		summary.Alerts[SeverityWarn],
+		summary.Alerts[SeverityInfo],
+	)
+
+	top := make([]string, 0, len(summary.TopAlerts))
+	for i, a := range summary.TopAlerts {
+		top = append(top, fmt.Sprintf("%d. **%.0f/100** [%s](%s) — `%s` %s %s $%.0f (#%d)",
+			i+1, a.NormalizedScore, truncate(a.MarketTitle, 60), a.MarketURL, a.WalletShort, a.Side, a.Outcome, a.NotionalUSD, a.AlertID))
+	}
+	clusters := make([]string, 0, len(summary.FlaggedClusters))
+	for _, c := range summary.FlaggedClusters {
+		clusters = append(clusters, fmt.Sprintf("`%s` — %d wallets, $%.0f volume", c.ClusterID, c.WalletCount, c.TotalVolumeUSD))
+	}
+	wins := make([]string, 0, len(summary.FlaggedWins))
+	for _, w := range summary.FlaggedWins {
+		wins = append(wins, fmt.Sprintf("[%s](%s) — **%s** won; %d alerted wallets, $%.0f", truncate(w.MarketTitle, 60), w.MarketURL, w.WinningOutcome, w.Wallets, w.AlertedUSD))
+	}
+
+	fields := []map[string]interface{}{
+		{
+			"name":   "🏆 Top Alerts",
+			"value":  summarySection(top),
+			"inline": false,
+		},
+		{
+			"name":   "🕸️ Newly Flagged Clusters",
+			"value":  summarySection(clusters),
+			"inline": false,
+		},
+		{
+			"name":   "✅ Resolved Markets Won by Alerted Wallets",
+			"value":  summarySection(wins),
+			"inline": false,
+		},
+	}
+
+	footer := map[string]interface{}{
+		"text": fmt.Sprintf("Whale Activity • %s • %s • %s to %s %s", payload.Environment, version.Short(),
+			summary.From.Format("2006-01-02 15:04"), summary.To.Format("2006-01-02 15:04"), summary.Timezone),
+	}
+
+	return map[string]interface{}{
+		"title":       fmt.Sprintf("📋 Daily summary for %s", summary.Date),
+		"description": description,
+		"color":       0x2ECC71, // Green
+		"fields":      fields,
+		"footer":      footer,
+		"timestamp":   payload.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// discordTime shows t in loc followed by Discord's relative timestamp
+// ("14 minutes ago"), which each reader's client renders and keeps current
+func discordTime(t time.Time, loc *time.Location) string {
+	return fmt.Sprintf("%s (<t:%d:R>)", displayTime(t, loc), t.Unix())
+}
+
+// summarySection joins a summary section's lines within Discord's field
+// limit, or says there was nothing
+func summarySection(lines []string) string {
+	if len(lines) == 0 {
+		return "None"
+	}
+	return truncate(joinParts(lines), 1000)
+}
+
+// buildExposureEmbed reports a new wallet's buys across markets adding up,
+// listing the markets that contributed
+func (s *DiscordSender) buildExposureEmbed(payload *AlertPayload) map[string]interface{} {
+	agg := payload.Aggregate
+
+	description := fmt.Sprintf("A **%d-day-old** wallet bought **$%.0f** across **%d markets** in the last %dh",
+		payload.WalletAgeDays,
+		agg.TotalUSD,
+		len(agg.Markets),
+		agg.WindowHours,
+	)
+
+	markets := make([]string, 0, len(agg.Markets))
+	for _, m := range agg.Markets {
+		title := truncate(m.MarketTitle, 60)
+		if m.MarketURL != "" {
+			title = fmt.Sprintf("[%s](%s)", title, m.MarketURL)
+		}
+		markets = append(markets, fmt.Sprintf("%s — $%.0f (%d trades)", title, m.NotionalUSD, m.Trades))
+	}
+
+	fields := []map[string]interface{}{
+		{
+			"name":   "Wallet",
+			"value":  fmt.Sprintf("`%s`", payload.WalletShort),
+			"inline": true,
+		},
+		{
+			"name":   "First Seen",
+			"value":  payload.FirstSeenDate,
+			"inline": true,
+		},
+		{
+			"name":   "Latest Trade",
+			"value":  fmt.Sprintf("%s %s $%.2f @ %.2f", payload.Side, payload.Outcome, payload.NotionalUSD, payload.Price),
+			"inline": true,
+		},
+		{
+			"name":   "Markets",
+			"value":  summarySection(markets),
+			"inline": false,
+		},
+		{
+			"name":   "Tx",
+			"value":  fmt.Sprintf("`%s`", payload.TxHashShort),
+			"inline": true,
+		},
+		{
+			"name":   "Traded",
+			"value":  discordTime(payload.Timestamp, s.loc),
+			"inline": true,
+		},
+	}
+
+	footer := map[string]interface{}{
+		"text": fmt.Sprintf("Whale Activity • %s • %s • %s", payload.Environment, version.Short(), displayTime(payload.Timestamp, s.loc)),
+	}
+
+	return map[string]interface{}{
+		"title":       fmt.Sprintf("🧮 Aggregate exposure from a new wallet (%s)", payload.Severity),
+		"description": description,
+		"color":       0xE67E22, // Orange
+		"fields":      fields,
+		"footer":      footer,
+		"timestamp":   payload.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// buildOverflowEmbed reports the alerts the webhook's rate cap held back,
+// linking the highest scoring one
+func (s *DiscordSender) buildOverflowEmbed(payload *AlertPayload) map[string]interface{} {
+	o := payload.Overflow
+
+	description := fmt.Sprintf("🚨 **%d** ALERT • ⚠️ **%d** WARN • ℹ️ **%d** INFO held back between %s and %s",
+		o.BySeverity[SeverityAlert],
+		o.BySeverity[SeverityWarn],
+		o.BySeverity[SeverityInfo],
+		displayTime(o.From, s.loc),
+		displayTime(o.To, s.loc),
+	)
+
+	var fields []map[string]interface{}
+	if top := o.Top; top != nil {
+		fields = append(fields, map[string]interface{}{
+			"name":   fmt.Sprintf("Highest score: %.0f/100", top.NormalizedScore),
+			"value":  fmt.Sprintf("[%s](%s) — `%s` %s %s $%.0f", truncate(top.MarketTitle, 60), top.MarketURL, top.WalletShort, top.Side, top.Outcome, top.NotionalUSD),
+			"inline": false,
+		})
+	}
+
+	footer := map[string]interface{}{
+		"text": fmt.Sprintf("Whale Activity • %s • %s • %s", payload.Environment, version.Short(), displayTime(payload.Timestamp, s.loc)),
+	}
+
+	return map[string]interface{}{
+		"title":       fmt.Sprintf("⏸️ %d additional alerts held back by the rate cap", o.Suppressed),
+		"description": description,
+		"color":       0x95A5A6, // Grey
+		"fields":      fields,
+		"footer":      footer,
+		"timestamp":   payload.Timestamp.Format(time.RFC3339),
+	}
+}
+
+// buildExitEmbed reports an alerted wallet reversing its position
+func (s *DiscordSender) buildExitEmbed(payload *AlertPayload) map[string]interface{} {
+	exit := payload.Exit
+
+	color := 0x9B59B6 // Purple
+	description := fmt.Sprintf("Wallet reversed **%.0f%%** of the position from alert #%d (%s)\nEntry **%s %s** @ **%.2f** ($%.2f) → exit @ **%.2f**",
+		exit.ExitedFraction*100,
+		exit.OriginalAlertID,
+		displayTime(exit.AlertedAt, s.loc),
+		exit.EntrySide,
+		payload.Outcome,
+		exit.EntryPrice,
+		exit.EntryNotionalUSD,
+		exit.ExitPrice,
+	)
+
+	fields := []map[string]interface{}{
+		{
+			"name":   "Wallet",
+			"value":  fmt.Sprintf("`%s`", payload.WalletShort),
+			"inline": true,
+		},
+		{
+			"name":   "Market",
+			"value":  truncate(payload.MarketTitle, 100),
+			"inline": true,
+		},
+		{
+			"name":   "Exit Trade",
+			"value":  fmt.Sprintf("%s %s $%.2f @ %.2f", payload.Side, payload.Outcome, payload.NotionalUSD, payload.Price),
+			"inline": true,
+		},
+		{
+			"name":   "Implied PnL",
+			"value":  fmt.Sprintf("**%+.2f USD**", exit.PnLUSD),
+			"inline": true,
+		},
+		{
+			"name":   "Tx",
+			"value":  fmt.Sprintf("`%s`", payload.TxHashShort),
+			"inline": true,
+		},
+		{
+			"name":   "Traded",
+			"value":  discordTime(payload.Timestamp, s.loc),
+			"inline": true,
+		},
+	}
+
+	footer := map[string]interface{}{
+		"text": fmt.Sprintf("Whale Activity • %s • %s • %s", payload.Environment, version.Short(), displayTime(payload.Timestamp, s.loc)),
+	}
+
+	return map[string]interface{}{
+		"title":       fmt.Sprintf("↩️ Alerted wallet exiting position (%s)", payload.Severity),
+		"url":         payload.MarketURL,
+		"description": description,
+		"color":       color,
+		"fields":      fields,
+		"footer":      footer,
+		"timestamp":   payload.Timestamp.Format(time.RFC3339),
+	}
+}
+
 func (s *DiscordSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 	var parts []string
 	
@@ -176,7 +461,7 @@ func (s *DiscordSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		parts = append(parts, fmt.Sprintf("💪 Betting on extreme odds - high conviction: **%.1fx**", b.PriceConfidenceMultiplier))
 	}
 	if b.ConcentrationMultiplier > 1.0 {
-		parts = append(parts, fmt.Sprintf("📈 Heavily one-sided betting (%.0f%% concentration): **%.1fx**", b.NetConcentration*100, b.ConcentrationMultiplier))
+		parts = append(parts, fmt.Sprintf("📈 Heavily one-sided betting (%.0f%% concentration over %dh): **%.1fx**", b.NetConcentration*100, b.ConcentrationWindowHrs, b.ConcentrationMultiplier))
 	}
 	if b.VelocityMultiplier > 1.0 {
 		parts = append(parts, fmt.Sprintf("🚀 Rapid-fire trading (%d trades in short time): **%.1fx**", b.VelocityCount, b.VelocityMultiplier))
@@ -185,11 +470,36 @@ func (s *DiscordSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		parts = append(parts, fmt.Sprintf("👥 Part of connected wallet group: **%.1fx**", b.ClusterMultiplier))
 	}
 	if b.CoordinatedMultiplier > 1.0 {
-		parts = append(parts, fmt.Sprintf("🤝 Coordinated activity with other wallets: **%.1fx**", b.CoordinatedMultiplier))
+		if b.CoordinationPattern == CoordinationOpposing {
+			parts = append(parts, fmt.Sprintf("🔀 Connected wallets took opposite sides of this market: **%.1fx**", b.CoordinatedMultiplier))
+		} else {
+			parts = append(parts, fmt.Sprintf("🤝 Coordinated activity with other wallets: **%.1fx**", b.CoordinatedMultiplier))
+		}
 	}
 	if b.FundingAgeMultiplier > 1.0 {
 		parts = append(parts, fmt.Sprintf("⏱️ Very new wallet (funded %.1fh ago): **%.2fx**", b.FundingAgeHours, b.FundingAgeMultiplier))
 	}
+	if b.FundingUtilizationMultiplier > 1.0 {
+		parts = append(parts, fmt.Sprintf("💸 Bet %.0f%% of its initial funding: **%.1fx**", b.FundingUtilization*100, b.FundingUtilizationMultiplier))
+	}
+	if b.SizeAnomalyMultiplier > 1.0 {
+		parts = append(parts, fmt.Sprintf("🐋 Trade is %.0fx its usual size: **%.1fx**", b.SizeRatio, b.SizeAnomalyMultiplier))
+	}
+	if b.CopyTradeMultiplier > 1.0 {
+		parts = append(parts, fmt.Sprintf("👥 Copied flagged wallet `%s` %.0fm later: **%.1fx**", b.CopyLeader, b.CopyLagMinutes, b.CopyTradeMultiplier))
+	}
+	if b.HolderDominanceMultiplier > 1.0 {
+		parts = append(parts, fmt.Sprintf("👑 #%d holder of the outcome (%.0f%% of top holders): **%.1fx**", b.HolderRank, b.HolderShare*100, b.HolderDominanceMultiplier))
+	}
+	if b.LosingRecordMultiplier < 1.0 {
+		parts = append(parts, fmt.Sprintf("📉 Losing track record (%.0f%% wins, %d trades): **%.2fx**", b.WinRate*100, b.ResolvedTrades, b.LosingRecordMultiplier))
+	}
+	if b.IsMaker && b.MakerMultiplier != 1.0 {
+		parts = append(parts, fmt.Sprintf("🧾 Maker fill (resting limit order): **%.2fx**", b.MakerMultiplier))
+	}
+	for _, r := range b.CustomRules {
+		parts = append(parts, fmt.Sprintf("📏 %s: **%.2fx**", r.Label, r.Multiplier))
+	}
 	
 	if len(parts) > 1 {
 		parts = append(parts, fmt.Sprintf("\n🎯 Final Suspicion Score: **%.0f/100** (raw: %.0f)", b.NormalizedScore, b.FinalScore))
@@ -3,6 +3,8 @@ package alerts
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -10,16 +12,92 @@ import (
 // LogSender sends alerts to the logger
 type LogSender struct {
 	log *logrus.Logger
+	loc *time.Location // DISPLAY_TIMEZONE
 }
 
-// NewLogSender creates a new log sender
-func NewLogSender(log *logrus.Logger) *LogSender {
-	return &LogSender{log: log}
+// NewLogSender creates a new log sender showing trade times in loc
+func NewLogSender(log *logrus.Logger, loc *time.Location) *LogSender {
+	return &LogSender{log: log, loc: loc}
 }
 
 // Send logs the alert
 func (s *LogSender) Send(ctx context.Context, payload *AlertPayload) error {
+	if summary := payload.Summary; summary != nil {
+		fields := logrus.Fields{
+			"date":              summary.Date,
+			"timezone":          summary.Timezone,
+			"qualifying_trades": summary.QualifyingTrades,
+			"volume_usd":        summary.QualifyingVolumeUSD,
+			"flagged_clusters":  len(summary.FlaggedClusters),
+			"flagged_wins":      len(summary.FlaggedWins),
+		}
+		for _, severity := range []Severity{SeverityAlert, SeverityWarn, SeverityInfo} {
+			fields["alerts_"+strings.ToLower(string(severity))] = summary.Alerts[severity]
+		}
+		if len(summary.TopAlerts) > 0 {
+			fields["top_alert_id"] = summary.TopAlerts[0].AlertID
+			fields["top_score"] = summary.TopAlerts[0].NormalizedScore
+		}
+		s.log.WithFields(fields).Info("Daily summary generated")
+		return nil
+	}
+
+	if o := payload.Overflow; o != nil {
+		fields := logrus.Fields{
+			"suppressed": o.Suppressed,
+			"from":       displayTime(o.From, s.loc),
+			"to":         displayTime(o.To, s.loc),
+		}
+		for _, severity := range []Severity{SeverityAlert, SeverityWarn, SeverityInfo} {
+			fields["suppressed_"+strings.ToLower(string(severity))] = o.BySeverity[severity]
+		}
+		if top := o.Top; top != nil {
+			fields["top_alert_id"] = top.AlertID
+			fields["top_score"] = top.NormalizedScore
+			fields["top_wallet"] = top.WalletShort
+			fields["top_market"] = top.MarketTitle
+		}
+		s.log.WithFields(fields).Info("Alert overflow summary generated")
+		return nil
+	}
+
+	if payload.Exit != nil {
+		s.log.WithFields(logrus.Fields{
+			"severity":          payload.Severity,
+			"wallet":            payload.WalletShort,
+			"market":            payload.MarketTitle,
+			"original_alert_id": payload.Exit.OriginalAlertID,
+			"entry_side":        payload.Exit.EntrySide,
+			"entry_price":       payload.Exit.EntryPrice,
+			"exit_price":        payload.Exit.ExitPrice,
+			"exited_pct":        payload.Exit.ExitedFraction * 100,
+			"pnl_usd":           payload.Exit.PnLUSD,
+			"tx_hash":           payload.TxHashShort,
+			"trade_time":        displayTime(payload.Timestamp, s.loc),
+		}).Info("Exit alert generated")
+		return nil
+	}
+
+	if agg := payload.Aggregate; agg != nil {
+		markets := make([]string, 0, len(agg.Markets))
+		for _, m := range agg.Markets {
+			markets = append(markets, fmt.Sprintf("%s ($%.0f)", m.MarketTitle, m.NotionalUSD))
+		}
+		s.log.WithFields(logrus.Fields{
+			"severity":        payload.Severity,
+			"wallet":          payload.WalletShort,
+			"wallet_age_days": payload.WalletAgeDays,
+			"total_usd":       agg.TotalUSD,
+			"window_hours":    agg.WindowHours,
+			"markets":         strings.Join(markets, "; "),
+			"tx_hash":         payload.TxHashShort,
+			"trade_time":      displayTime(payload.Timestamp, s.loc),
+		}).Info("Aggregate exposure alert generated")
+		return nil
+	}
+
 	fields := logrus.Fields{
+		"alert_id":         payload.AlertID,
 		"severity":         payload.Severity,
 		"wallet":           payload.WalletShort,
 		"market":           payload.MarketTitle,
@@ -28,8 +106,21 @@ func (s *LogSender) Send(ctx context.Context, payload *AlertPayload) error {
 		"normalized_score": payload.NormalizedScore,
 		"raw_score":        payload.SuspicionScore,
 		"tx_hash":          payload.TxHashShort,
+		"trade_time":       displayTime(payload.Timestamp, s.loc),
 	}
 	
+	if payload.BookDepthUSD > 0 {
+		fields["book_depth_usd"] = payload.BookDepthUSD
+		fields["book_consumed_pct"] = payload.BookConsumed * 100
+	}
+	if payload.CurrentPrice > 0 {
+		fields["current_price"] = payload.CurrentPrice
+	}
+	if payload.ExposureMarkets > 0 {
+		fields["exposure_usd"] = payload.ExposureUSD
+		fields["exposure_markets"] = payload.ExposureMarkets
+	}
+
 	if payload.ScoreBreakdown != nil {
 		fields["score_breakdown"] = s.formatScoreBreakdown(payload.ScoreBreakdown)
 	}
@@ -60,7 +151,7 @@ func (s *LogSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		breakdown += fmt.Sprintf(", extreme_price=%.1fx", b.PriceConfidenceMultiplier)
 	}
 	if b.ConcentrationMultiplier > 1.0 {
-		breakdown += fmt.Sprintf(", concentration=%.1fx(%.0f%%)", b.ConcentrationMultiplier, b.NetConcentration*100)
+		breakdown += fmt.Sprintf(", concentration=%.1fx(%.0f%%/%dh)", b.ConcentrationMultiplier, b.NetConcentration*100, b.ConcentrationWindowHrs)
 	}
 	if b.VelocityMultiplier > 1.0 {
 		breakdown += fmt.Sprintf(", velocity=%.1fx(%dt)", b.VelocityMultiplier, b.VelocityCount)
@@ -69,11 +160,32 @@ func (s *LogSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		breakdown += fmt.Sprintf(", cluster=%.1fx", b.ClusterMultiplier)
 	}
 	if b.CoordinatedMultiplier > 1.0 {
-		breakdown += fmt.Sprintf(", coordinated=%.1fx", b.CoordinatedMultiplier)
+		breakdown += fmt.Sprintf(", coordinated=%.1fx(%s)", b.CoordinatedMultiplier, b.CoordinationPattern)
 	}
 	if b.FundingAgeMultiplier > 1.0 {
 		breakdown += fmt.Sprintf(", fast_fund=%.2fx(%.1fh)", b.FundingAgeMultiplier, b.FundingAgeHours)
 	}
+	if b.FundingUtilizationMultiplier > 1.0 {
+		breakdown += fmt.Sprintf(", funding_used=%.1fx(%.0f%%)", b.FundingUtilizationMultiplier, b.FundingUtilization*100)
+	}
+	if b.SizeAnomalyMultiplier > 1.0 {
+		breakdown += fmt.Sprintf(", size_anomaly=%.1fx(%.0fx usual)", b.SizeAnomalyMultiplier, b.SizeRatio)
+	}
+	if b.CopyTradeMultiplier > 1.0 {
+		breakdown += fmt.Sprintf(", copy_trade=%.1fx(leader=%s, lag=%.0fm)", b.CopyTradeMultiplier, b.CopyLeader, b.CopyLagMinutes)
+	}
+	if b.HolderDominanceMultiplier > 1.0 {
+		breakdown += fmt.Sprintf(", holder=%.1fx(#%d, %.0f%%)", b.HolderDominanceMultiplier, b.HolderRank, b.HolderShare*100)
+	}
+	if b.LosingRecordMultiplier < 1.0 {
+		breakdown += fmt.Sprintf(", losing_record=%.2fx(%.0f%%, %dt)", b.LosingRecordMultiplier, b.WinRate*100, b.ResolvedTrades)
+	}
+	if b.IsMaker {
+		breakdown += fmt.Sprintf(", maker=%.2fx", b.MakerMultiplier)
+	}
+	for _, r := range b.CustomRules {
+		breakdown += fmt.Sprintf(", rule[%s]=%.2fx", r.Label, r.Multiplier)
+	}
 	
 	breakdown += fmt.Sprintf(" => final=%.0f", b.FinalScore)
 	
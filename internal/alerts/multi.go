@@ -32,3 +32,8 @@ func (s *MultiSender) Send(ctx context.Context, payload *AlertPayload) error {
 
 	return nil
 }
+
+// Flush flushes the senders that hold alerts back
+func (s *MultiSender) Flush(ctx context.Context) error {
+	return Flush(ctx, s.senders...)
+}
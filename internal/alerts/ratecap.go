@@ -0,0 +1,144 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/sirupsen/logrus"
+)
+
+// overflowSendTimeout bounds sending an overflow summary from its timer
+const overflowSendTimeout = 30 * time.Second
+
+// Flusher is a Sender that holds alerts back and can send what it holds
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Flush sends whatever the senders hold back, for shutdown
+func Flush(ctx context.Context, senders ...Sender) error {
+	var errs []error
+	for _, s := range senders {
+		if f, ok := s.(Flusher); ok {
+			if err := f.Flush(ctx); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CappedSender passes alerts on to one channel at no more than a set number
+// per minute, so a burst of alerts doesn't get a webhook throttled or a
+// mail account flagged. Alerts over the cap aren't dropped: they are rolled
+// into an overflow summary sent once the summary interval has passed since
+// the first of them. Alerts of an exempt severity and daily summaries
+// always go through.
+type CappedSender struct {
+	next     Sender
+	channel  string // log, discord or smtp, for the metric
+	limiter  *ratelimit.Limiter
+	interval time.Duration
+	exempt   map[Severity]bool
+	log      *logrus.Logger
+
+	mu          sync.Mutex
+	pending     *OverflowSummary // nil while nothing is held back
+	environment string
+	timer       *time.Timer
+}
+
+// NewCappedSender caps next at perMinute alerts a minute, with room for a
+// burst of perMinute after a quiet spell, and sends an overflow summary
+// every interval while alerts are being held back
+func NewCappedSender(next Sender, channel string, perMinute int, interval time.Duration, exempt []Severity, log *logrus.Logger) *CappedSender {
+	exemptSet := make(map[Severity]bool, len(exempt))
+	for _, severity := range exempt {
+		exemptSet[severity] = true
+	}
+	return &CappedSender{
+		next:     next,
+		channel:  channel,
+		limiter:  ratelimit.New(float64(perMinute)/60, perMinute),
+		interval: interval,
+		exempt:   exemptSet,
+		log:      log,
+	}
+}
+
+// Send passes the alert on while the channel is under its cap, and holds
+// it back for the next overflow summary otherwise
+func (s *CappedSender) Send(ctx context.Context, payload *AlertPayload) error {
+	if payload.Summary != nil || payload.Overflow != nil || s.exempt[payload.Severity] || s.limiter.Allow() {
+		return s.next.Send(ctx, payload)
+	}
+
+	metrics.AlertsRateCapped.WithLabelValues(s.channel).Inc()
+	s.hold(payload)
+	return nil
+}
+
+// hold adds an alert to the pending overflow summary, starting the summary
+// timer for the first one
+func (s *CappedSender) hold(payload *AlertPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.pending == nil {
+		s.pending = &OverflowSummary{BySeverity: make(map[Severity]int), From: now}
+		s.timer = time.AfterFunc(s.interval, func() {
+			ctx, cancel := context.WithTimeout(context.Background(), overflowSendTimeout)
+			defer cancel()
+			if err := s.Flush(ctx); err != nil {
+				s.log.WithError(err).WithField("channel", s.channel).Warn("Failed to send alert overflow summary")
+			}
+		})
+	}
+
+	o := s.pending
+	o.Suppressed++
+	o.BySeverity[payload.Severity]++
+	o.To = now
+	if o.Top == nil || payload.NormalizedScore > o.Top.NormalizedScore {
+		o.Top = payload
+	}
+	s.environment = payload.Environment
+}
+
+// Flush sends the pending overflow summary, if any, now
+func (s *CappedSender) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	o := s.pending
+	s.pending = nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	environment := s.environment
+	s.mu.Unlock()
+
+	if o == nil {
+		return nil
+	}
+	s.log.WithFields(logrus.Fields{
+		"channel":    s.channel,
+		"suppressed": o.Suppressed,
+		"top_score":  o.Top.NormalizedScore,
+	}).Info("Sending alert overflow summary")
+
+	payload := &AlertPayload{
+		Timestamp:   time.Now(),
+		Environment: environment,
+		Overflow:    o,
+	}
+	if err := s.next.Send(ctx, payload); err != nil {
+		return fmt.Errorf("send %s overflow summary: %w", s.channel, err)
+	}
+	return nil
+}
@@ -2,8 +2,13 @@ package alerts
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"net/mail"
 	"net/smtp"
+	"strings"
 	"time"
 )
 
@@ -14,11 +19,19 @@ type SMTPSender struct {
 	user     string
 	password string
 	from     string
-	to       []string
+	to       []string       // Header forms, possibly with display names
+	rcpt     []string       // Bare addresses for the envelope
+	loc      *time.Location // DISPLAY_TIMEZONE
 }
 
-// NewSMTPSender creates a new SMTP sender
-func NewSMTPSender(host string, port int, user, password, from string, to []string) *SMTPSender {
+// NewSMTPSender creates a new SMTP sender showing times in loc. from and to
+// may carry display names ("Alerts <alerts@example.com>"); the config has
+// already checked that they parse.
+func NewSMTPSender(host string, port int, user, password, from string, to []string, loc *time.Location) *SMTPSender {
+	rcpt := make([]string, 0, len(to))
+	for _, r := range to {
+		rcpt = append(rcpt, envelopeAddress(r))
+	}
 	return &SMTPSender{
 		host:     host,
 		port:     port,
@@ -26,16 +39,87 @@ func NewSMTPSender(host string, port int, user, password, from string, to []stri
 		password: password,
 		from:     from,
 		to:       to,
+		rcpt:     rcpt,
+		loc:      loc,
+	}
+}
+
+// envelopeAddress returns the bare address of a header address, or the
+// value unchanged if it doesn't parse
+func envelopeAddress(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
+
+// Check connects to the server, says EHLO, upgrades to TLS when offered and
+// authenticates, the way a send would, then quits without sending anything.
+// It catches a wrong host, port or password at startup rather than at the
+// first alert.
+func (s *SMTPSender) Check(ctx context.Context) error {
+	addr := net.JoinHostPort(s.host, fmt.Sprint(s.port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting from %s: %w", addr, err)
 	}
+	defer client.Close()
+
+	if err := client.Hello("localhost"); err != nil {
+		return fmt.Errorf("EHLO: %w", err)
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("STARTTLS: %w", err)
+		}
+	}
+	if ok, _ := client.Extension("AUTH"); ok {
+		if err := client.Auth(smtp.PlainAuth("", s.user, s.password, s.host)); err != nil {
+			return fmt.Errorf("authenticate as %q: %w", s.user, err)
+		}
+	}
+	return client.Quit()
 }
 
 // Send sends the alert via email
 func (s *SMTPSender) Send(ctx context.Context, payload *AlertPayload) error {
 	subject := fmt.Sprintf("[%s] Suspicious trade: $%.2f on %s", payload.Severity, payload.NotionalUSD, payload.MarketTitle)
 	body := s.buildEmailBody(payload)
+	if payload.Exit != nil {
+		subject = fmt.Sprintf("[%s] Alerted wallet exiting: %s", payload.Severity, payload.MarketTitle)
+		body = s.buildExitEmailBody(payload)
+	}
+	if payload.Aggregate != nil {
+		subject = fmt.Sprintf("[%s] Aggregate exposure: $%.2f across %d markets", payload.Severity, payload.Aggregate.TotalUSD, len(payload.Aggregate.Markets))
+		body = s.buildExposureEmailBody(payload)
+	}
+	if o := payload.Overflow; o != nil {
+		subject = fmt.Sprintf("%d additional alerts suppressed", o.Suppressed)
+		if o.Top != nil {
+			subject += fmt.Sprintf(", highest score %.0f", o.Top.NormalizedScore)
+		}
+		body = s.buildOverflowEmailBody(payload)
+	}
+	if payload.Summary != nil {
+		subject = fmt.Sprintf("Daily summary for %s: %d ALERT, %d WARN", payload.Summary.Date, payload.Summary.Alerts[SeverityAlert], payload.Summary.Alerts[SeverityWarn])
+		body = s.buildSummaryEmailBody(payload)
+	}
+
+	if len(s.rcpt) == 0 {
+		return errors.New("send email: no recipients configured (SMTP_TO)")
+	}
 
 	message := fmt.Sprintf("From: %s\r\n", s.from)
-	message += fmt.Sprintf("To: %s\r\n", s.to[0])
+	message += fmt.Sprintf("To: %s\r\n", strings.Join(s.to, ", "))
 	message += fmt.Sprintf("Subject: %s\r\n", subject)
 	message += "Content-Type: text/plain; charset=UTF-8\r\n"
 	message += "\r\n"
@@ -44,7 +128,7 @@ func (s *SMTPSender) Send(ctx context.Context, payload *AlertPayload) error {
 	auth := smtp.PlainAuth("", s.user, s.password, s.host)
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
 
-	err := smtp.SendMail(addr, auth, s.from, s.to, []byte(message))
+	err := smtp.SendMail(addr, auth, envelopeAddress(s.from), s.rcpt, []byte(message))
 	if err != nil {
 		return fmt.Errorf("send email: %w", err)
 	}
@@ -60,13 +144,19 @@ func (s *SMTPSender) buildEmailBody(payload *AlertPayload) string {
 	body += fmt.Sprintf("─────────────────────────────────────\n")
 	body += fmt.Sprintf("Notional:       $%.2f\n", payload.NotionalUSD)
 	body += fmt.Sprintf("Side:           %s %s\n", payload.Side, payload.Outcome)
-	body += fmt.Sprintf("Price:          %.2f\n", payload.Price)
+	body += fmt.Sprintf("Price:          %s\n", formatPrice(payload.Price, payload.CurrentPrice))
 	body += fmt.Sprintf("Market:         %s\n", payload.MarketTitle)
+	if payload.BookDepthUSD > 0 {
+		body += fmt.Sprintf("Book Depth:     consumed %.0f%% of visible book depth ($%.0f)\n", payload.BookConsumed*100, payload.BookDepthUSD)
+	}
 	body += fmt.Sprintf("Market URL:     %s\n\n", payload.MarketURL)
 	body += fmt.Sprintf("WALLET DETAILS\n")
 	body += fmt.Sprintf("─────────────────────────────────────\n")
 	body += fmt.Sprintf("Address:        %s\n", payload.WalletAddress)
 	body += fmt.Sprintf("Age:            %d days (first seen %s)\n", payload.WalletAgeDays, payload.FirstSeenDate)
+	if payload.ExposureMarkets > 0 {
+		body += fmt.Sprintf("Open Exposure:  $%.0f across %d markets\n", payload.ExposureUSD, payload.ExposureMarkets)
+	}
 	body += fmt.Sprintf("Suspicion Score: %.0f/100 (raw: %.0f)\n\n", payload.NormalizedScore, payload.SuspicionScore)
 	
 	// Add score breakdown if available
@@ -77,16 +167,149 @@ func (s *SMTPSender) buildEmailBody(payload *AlertPayload) string {
 	body += fmt.Sprintf("TRANSACTION\n")
 	body += fmt.Sprintf("─────────────────────────────────────\n")
 	body += fmt.Sprintf("Hash:           %s\n", payload.TransactionHash)
-	body += fmt.Sprintf("Time:           %s\n\n", payload.Timestamp.Format(time.RFC3339))
+	body += fmt.Sprintf("Time:           %s\n\n", displayTime(payload.Timestamp, s.loc))
 	body += fmt.Sprintf("═══════════════════════════════════════\n")
 	body += fmt.Sprintf("Environment: %s\n", payload.Environment)
-	body += fmt.Sprintf("Generated: %s\n", time.Now().UTC().Format("2006-01-02 15:04:05 UTC"))
+	body += fmt.Sprintf("Generated: %s\n", displayTime(time.Now(), s.loc))
 	body += fmt.Sprintf("\nNote: This system detects suspicious behavior;\n")
 	body += fmt.Sprintf("it does NOT prove insider trading.\n")
 
 	return body
 }
 
+func (s *SMTPSender) buildExitEmailBody(payload *AlertPayload) string {
+	exit := payload.Exit
+
+	body := fmt.Sprintf("INSIDERWATCH EXIT ALERT - %s\n", payload.Severity)
+	body += fmt.Sprintf("═══════════════════════════════════════\n\n")
+	body += fmt.Sprintf("A wallet we alerted on is exiting its position:\n\n")
+	body += fmt.Sprintf("ORIGINAL ALERT\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	body += fmt.Sprintf("Alert ID:       %d\n", exit.OriginalAlertID)
+	body += fmt.Sprintf("Alerted At:     %s\n", displayTime(exit.AlertedAt, s.loc))
+	body += fmt.Sprintf("Entry:          %s %s @ %.2f ($%.2f)\n\n", exit.EntrySide, payload.Outcome, exit.EntryPrice, exit.EntryNotionalUSD)
+	body += fmt.Sprintf("EXIT\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	body += fmt.Sprintf("Trade:          %s %s $%.2f @ %.2f\n", payload.Side, payload.Outcome, payload.NotionalUSD, payload.Price)
+	body += fmt.Sprintf("Reversed:       %.0f%% of the position\n", exit.ExitedFraction*100)
+	body += fmt.Sprintf("Avg Exit Price: %.2f\n", exit.ExitPrice)
+	body += fmt.Sprintf("Implied PnL:    %+.2f USD\n", exit.PnLUSD)
+	body += fmt.Sprintf("Market:         %s\n", payload.MarketTitle)
+	body += fmt.Sprintf("Market URL:     %s\n", payload.MarketURL)
+	body += fmt.Sprintf("Wallet:         %s\n", payload.WalletAddress)
+	body += fmt.Sprintf("Hash:           %s\n", payload.TransactionHash)
+	body += fmt.Sprintf("Time:           %s\n\n", displayTime(payload.Timestamp, s.loc))
+	body += fmt.Sprintf("═══════════════════════════════════════\n")
+	body += fmt.Sprintf("Environment: %s\n", payload.Environment)
+	body += fmt.Sprintf("Generated: %s\n", displayTime(time.Now(), s.loc))
+
+	return body
+}
+
+func (s *SMTPSender) buildExposureEmailBody(payload *AlertPayload) string {
+	agg := payload.Aggregate
+
+	body := fmt.Sprintf("INSIDERWATCH AGGREGATE EXPOSURE ALERT - %s\n", payload.Severity)
+	body += fmt.Sprintf("═══════════════════════════════════════\n\n")
+	body += fmt.Sprintf("A new wallet's buys across markets have added up:\n\n")
+	body += fmt.Sprintf("WALLET DETAILS\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	body += fmt.Sprintf("Address:        %s\n", payload.WalletAddress)
+	body += fmt.Sprintf("Age:            %d days (first seen %s)\n", payload.WalletAgeDays, payload.FirstSeenDate)
+	body += fmt.Sprintf("Exposure:       $%.2f across %d markets\n", agg.TotalUSD, len(agg.Markets))
+	body += fmt.Sprintf("Window:         %dh since %s\n\n", agg.WindowHours, displayTime(agg.WindowStart, s.loc))
+	body += fmt.Sprintf("MARKETS\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	for _, m := range agg.Markets {
+		body += fmt.Sprintf("$%.2f (%d trades) - %s\n", m.NotionalUSD, m.Trades, m.MarketTitle)
+		if m.MarketURL != "" {
+			body += fmt.Sprintf("   URL: %s\n", m.MarketURL)
+		}
+	}
+	body += fmt.Sprintf("\nLATEST TRADE\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	body += fmt.Sprintf("Trade:          %s %s $%.2f @ %.2f\n", payload.Side, payload.Outcome, payload.NotionalUSD, payload.Price)
+	body += fmt.Sprintf("Market:         %s\n", payload.MarketTitle)
+	body += fmt.Sprintf("Hash:           %s\n", payload.TransactionHash)
+	body += fmt.Sprintf("Time:           %s\n\n", displayTime(payload.Timestamp, s.loc))
+	body += fmt.Sprintf("═══════════════════════════════════════\n")
+	body += fmt.Sprintf("Environment: %s\n", payload.Environment)
+	body += fmt.Sprintf("Generated: %s\n", displayTime(time.Now(), s.loc))
+
+	return body
+}
+
+func (s *SMTPSender) buildOverflowEmailBody(payload *AlertPayload) string {
+	o := payload.Overflow
+
+	body := fmt.Sprintf("INSIDERWATCH ALERT OVERFLOW\n")
+	body += fmt.Sprintf("═══════════════════════════════════════\n\n")
+	body += fmt.Sprintf("%d additional alerts were held back by the email rate cap:\n\n", o.Suppressed)
+	body += fmt.Sprintf("Period:         %s to %s\n", displayTime(o.From, s.loc), displayTime(o.To, s.loc))
+	body += fmt.Sprintf("Alerts:         %d ALERT, %d WARN, %d INFO\n\n", o.BySeverity[SeverityAlert], o.BySeverity[SeverityWarn], o.BySeverity[SeverityInfo])
+	if top := o.Top; top != nil {
+		body += fmt.Sprintf("HIGHEST SCORE\n")
+		body += fmt.Sprintf("─────────────────────────────────────\n")
+		body += fmt.Sprintf("Score:          %.0f/100 (%s)\n", top.NormalizedScore, top.Severity)
+		body += fmt.Sprintf("Trade:          %s %s $%.2f\n", top.Side, top.Outcome, top.NotionalUSD)
+		body += fmt.Sprintf("Market:         %s\n", top.MarketTitle)
+		body += fmt.Sprintf("Market URL:     %s\n", top.MarketURL)
+		body += fmt.Sprintf("Wallet:         %s\n\n", top.WalletAddress)
+	}
+	body += fmt.Sprintf("═══════════════════════════════════════\n")
+	body += fmt.Sprintf("Environment: %s\n", payload.Environment)
+	body += fmt.Sprintf("Generated: %s\n", displayTime(payload.Timestamp, s.loc))
+
+	return body
+}
+
+func (s *SMTPSender) buildSummaryEmailBody(payload *AlertPayload) string {
+	summary := payload.Summary
+
+	body := fmt.Sprintf("INSIDERWATCH DAILY SUMMARY - %s\n", summary.Date)
+	body += fmt.Sprintf("═══════════════════════════════════════\n\n")
+	body += fmt.Sprintf("Period:            %s to %s %s\n", summary.From.Format("2006-01-02 15:04"), summary.To.Format("2006-01-02 15:04"), summary.Timezone)
+	body += fmt.Sprintf("Qualifying Trades: %d ($%.2f)\n", summary.QualifyingTrades, summary.QualifyingVolumeUSD)
+	body += fmt.Sprintf("Alerts:            %d ALERT, %d WARN, %d INFO\n\n", summary.Alerts[SeverityAlert], summary.Alerts[SeverityWarn], summary.Alerts[SeverityInfo])
+
+	body += fmt.Sprintf("TOP ALERTS\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	if len(summary.TopAlerts) == 0 {
+		body += "None\n"
+	}
+	for i, a := range summary.TopAlerts {
+		body += fmt.Sprintf("%d. Score %.0f/100 - %s %s $%.2f (alert #%d)\n", i+1, a.NormalizedScore, a.Side, a.Outcome, a.NotionalUSD, a.AlertID)
+		body += fmt.Sprintf("   Market: %s\n", a.MarketTitle)
+		body += fmt.Sprintf("   URL:    %s\n", a.MarketURL)
+		body += fmt.Sprintf("   Wallet: %s\n", a.WalletAddress)
+	}
+
+	body += fmt.Sprintf("\nNEWLY FLAGGED CLUSTERS\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	if len(summary.FlaggedClusters) == 0 {
+		body += "None\n"
+	}
+	for _, c := range summary.FlaggedClusters {
+		body += fmt.Sprintf("%s - %d wallets, $%.2f volume\n", c.ClusterID, c.WalletCount, c.TotalVolumeUSD)
+	}
+
+	body += fmt.Sprintf("\nRESOLVED MARKETS WON BY ALERTED WALLETS\n")
+	body += fmt.Sprintf("─────────────────────────────────────\n")
+	if len(summary.FlaggedWins) == 0 {
+		body += "None\n"
+	}
+	for _, w := range summary.FlaggedWins {
+		body += fmt.Sprintf("%s - %s won; %d alerted wallets, $%.2f\n", w.MarketTitle, w.WinningOutcome, w.Wallets, w.AlertedUSD)
+		body += fmt.Sprintf("   URL: %s\n", w.MarketURL)
+	}
+
+	body += fmt.Sprintf("\n═══════════════════════════════════════\n")
+	body += fmt.Sprintf("Environment: %s\n", payload.Environment)
+	body += fmt.Sprintf("Generated: %s\n", displayTime(payload.Timestamp, s.loc))
+
+	return body
+}
+
 func (s *SMTPSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 	breakdown := fmt.Sprintf("SCORE CALCULATION\n")
 	breakdown += fmt.Sprintf("─────────────────────────────────────\n")
@@ -111,7 +334,7 @@ func (s *SMTPSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		breakdown += fmt.Sprintf("Extreme Price:  %.1fx\n", b.PriceConfidenceMultiplier)
 	}
 	if b.ConcentrationMultiplier > 1.0 {
-		breakdown += fmt.Sprintf("Concentration:  %.1fx (%.0f%% one-sided)\n", b.ConcentrationMultiplier, b.NetConcentration*100)
+		breakdown += fmt.Sprintf("Concentration:  %.1fx (%.0f%% one-sided over %dh)\n", b.ConcentrationMultiplier, b.NetConcentration*100, b.ConcentrationWindowHrs)
 	}
 	if b.VelocityMultiplier > 1.0 {
 		breakdown += fmt.Sprintf("Velocity:       %.1fx (%d trades)\n", b.VelocityMultiplier, b.VelocityCount)
@@ -120,11 +343,36 @@ func (s *SMTPSender) formatScoreBreakdown(b *ScoreBreakdown) string {
 		breakdown += fmt.Sprintf("Cluster:        %.1fx\n", b.ClusterMultiplier)
 	}
 	if b.CoordinatedMultiplier > 1.0 {
-		breakdown += fmt.Sprintf("Coordinated:    %.1fx\n", b.CoordinatedMultiplier)
+		if b.CoordinationPattern == CoordinationOpposing {
+			breakdown += fmt.Sprintf("Coordinated:    %.1fx (opposite sides across cluster)\n", b.CoordinatedMultiplier)
+		} else {
+			breakdown += fmt.Sprintf("Coordinated:    %.1fx (same side across cluster)\n", b.CoordinatedMultiplier)
+		}
 	}
 	if b.FundingAgeMultiplier > 1.0 {
 		breakdown += fmt.Sprintf("Fast Funding:   %.2fx (%.1f hours)\n", b.FundingAgeMultiplier, b.FundingAgeHours)
 	}
+	if b.FundingUtilizationMultiplier > 1.0 {
+		breakdown += fmt.Sprintf("Funding Used:   %.1fx (bet %.0f%% of its initial funding)\n", b.FundingUtilizationMultiplier, b.FundingUtilization*100)
+	}
+	if b.SizeAnomalyMultiplier > 1.0 {
+		breakdown += fmt.Sprintf("Size Anomaly:   %.1fx (%.0fx usual trade size)\n", b.SizeAnomalyMultiplier, b.SizeRatio)
+	}
+	if b.CopyTradeMultiplier > 1.0 {
+		breakdown += fmt.Sprintf("Copy Trade:     %.1fx (mirrored %s %.0fm later)\n", b.CopyTradeMultiplier, b.CopyLeader, b.CopyLagMinutes)
+	}
+	if b.HolderDominanceMultiplier > 1.0 {
+		breakdown += fmt.Sprintf("Top Holder:     %.1fx (#%d, %.0f%% of top holders)\n", b.HolderDominanceMultiplier, b.HolderRank, b.HolderShare*100)
+	}
+	if b.LosingRecordMultiplier < 1.0 {
+		breakdown += fmt.Sprintf("Losing Record:  %.2fx (%.0f%%, %d trades)\n", b.LosingRecordMultiplier, b.WinRate*100, b.ResolvedTrades)
+	}
+	if b.IsMaker {
+		breakdown += fmt.Sprintf("Maker Fill:     %.2fx\n", b.MakerMultiplier)
+	}
+	for _, r := range b.CustomRules {
+		breakdown += fmt.Sprintf("Rule:           %.2fx (%s)\n", r.Multiplier, r.Label)
+	}
 	
 	breakdown += fmt.Sprintf("\nNormalized:     %.0f/100\n", b.NormalizedScore)
 	breakdown += fmt.Sprintf("Raw Score:      %.0f\n\n", b.FinalScore)
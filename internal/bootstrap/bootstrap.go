@@ -0,0 +1,155 @@
+// Package bootstrap wires up what insiderwatch's commands share: the
+// logger, configuration, tracing and error reporting, the database and the
+// Polymarket API clients. Each command calls only the steps it needs, so
+// `score` runs without a database and `migrate` without API clients.
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/errreport"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/clobapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/subgraph"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/liamashdown/insiderwatch/internal/tracing"
+	"github.com/sirupsen/logrus"
+)
+
+// flushTimeout bounds flushing traces and error reports on exit
+const flushTimeout = 5 * time.Second
+
+// Logger returns a JSON logger writing to out at level. Config raises or
+// lowers the level to LOG_LEVEL once the configuration is loaded.
+func Logger(out io.Writer, level logrus.Level) *logrus.Logger {
+	log := logrus.New()
+	log.SetFormatter(&logrus.JSONFormatter{})
+	log.SetOutput(out)
+	log.SetLevel(level)
+	return log
+}
+
+// Config loads and validates the configuration, applies its log level and
+// metric buckets, and logs its warnings and where each setting came from.
+// Commands that log only warnings pass keepLevel to leave log's level alone.
+func Config(log *logrus.Logger, keepLevel bool) (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	metrics.SetHistogramBuckets(cfg.MetricScoreBuckets, cfg.MetricTradeDurationBuckets)
+	if !keepLevel {
+		level, _ := logrus.ParseLevel(cfg.LogLevel) // Checked by Validate
+		log.SetLevel(level)
+	}
+	for _, warning := range cfg.Warnings() {
+		log.Warn("Configuration: " + warning)
+	}
+	sources := make(logrus.Fields, len(cfg.Sources))
+	for key, source := range cfg.Sources {
+		sources[strings.ToLower(key)] = source
+	}
+	log.WithFields(sources).Debug("Configuration sources")
+	return cfg, nil
+}
+
+// Observability sets up tracing (when ENABLE_TRACING is set) and error
+// reporting (when SENTRY_DSN is set). The returned func flushes both and
+// should be deferred.
+func Observability(cfg *config.Config, log *logrus.Logger) (func(), error) {
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.EnableTracing)
+	if err != nil {
+		return nil, fmt.Errorf("set up tracing: %w", err)
+	}
+	if cfg.EnableTracing {
+		log.Info("OpenTelemetry tracing enabled")
+	}
+
+	if cfg.SentryDSN != "" {
+		sentry, err := errreport.NewSentry(cfg.SentryDSN, cfg.Environment, log)
+		if err != nil {
+			return nil, fmt.Errorf("set up error reporting: %w", err)
+		}
+		errreport.Set(errreport.NewSampler(sentry, cfg.ErrorReportSampleRate, cfg.ErrorReportMaxPerMinute))
+		log.Info("Sentry error reporting enabled")
+	}
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		// Deliver errors from shutdown, and spans from the last poll
+		if err := errreport.Flush(ctx); err != nil {
+			log.WithError(err).Warn("Failed to flush error reports")
+		}
+		if err := shutdownTracing(ctx); err != nil {
+			log.WithError(err).Warn("Failed to flush traces")
+		}
+	}, nil
+}
+
+// Database connects to the database, running the schema migrations first
+// when migrate is set
+func Database(cfg *config.Config, log *logrus.Logger, migrate bool) (*storage.DB, error) {
+	db, err := storage.New(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("connect to database: %w", err)
+	}
+	log.Info("Database connected")
+
+	if !migrate {
+		return db, nil
+	}
+	if err := db.AutoMigrate(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("run database migrations: %w", err)
+	}
+	log.Info("Database migrations complete")
+	return db, nil
+}
+
+// Clients are the Polymarket API clients. Subgraph is nil unless
+// SUBGRAPH_URL is set.
+type Clients struct {
+	Data     *dataapi.Client
+	Gamma    *gammaapi.Client
+	Clob     *clobapi.Client
+	Subgraph *subgraph.Client
+}
+
+// APIClients creates the Polymarket API clients, sharing the optional
+// SHARED_RATE_LIMIT_RPS budget between them
+func APIClients(cfg *config.Config, log *logrus.Logger) (*Clients, error) {
+	// Optional budget shared by the Polymarket APIs, which limit per IP
+	budget := ratelimit.NewBudget(cfg.SharedRateLimitRPS, cfg.SharedRateLimitBurst)
+	if budget != nil {
+		log.WithField("rps", cfg.SharedRateLimitRPS).Info("Shared API rate limit enabled")
+	}
+	dataClient, err := dataapi.NewClient(cfg, budget, log)
+	if err != nil {
+		return nil, fmt.Errorf("create Data API client: %w", err)
+	}
+	gammaClient, err := gammaapi.NewClient(cfg, budget, log)
+	if err != nil {
+		return nil, fmt.Errorf("create Gamma API client: %w", err)
+	}
+	clients := &Clients{
+		Data:     dataClient,
+		Gamma:    gammaClient,
+		Clob:     clobapi.NewClient(cfg, budget, log),
+		Subgraph: subgraph.NewClient(cfg, log),
+	}
+	if clients.Subgraph != nil {
+		log.WithField("url", cfg.SubgraphURL).Info("Subgraph wallet history enabled")
+	}
+
+	log.Info("API clients initialized")
+	return clients, nil
+}
@@ -0,0 +1,130 @@
+package broadcast
+
+import (
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrFull is returned by Subscribe when the hub has its maximum number
+	// of subscribers
+	ErrFull = errors.New("too many subscribers")
+
+	// ErrClosed is returned by Subscribe once the hub is closed
+	ErrClosed = errors.New("hub closed")
+)
+
+// Hub fans published values out to subscribers. Publish never blocks: a
+// subscriber whose buffer is full is dropped and its channel closed, so one
+// slow reader can't hold up the publisher or the other subscribers. A nil
+// *Hub drops everything published to it.
+type Hub[T any] struct {
+	max    int // Subscribers allowed at once; 0 for no limit
+	buffer int // Values held for each subscriber
+
+	mu     sync.Mutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription receives the values published after it was made on C, which
+// is closed when the subscriber is dropped, unsubscribes or the hub closes
+type Subscription[T any] struct {
+	C <-chan T
+
+	c       chan T
+	hub     *Hub[T]
+	dropped bool // Guarded by hub.mu
+}
+
+// New creates a hub for at most maxSubscribers (0 for no limit), each
+// holding up to buffer values it hasn't read yet
+func New[T any](maxSubscribers, buffer int) *Hub[T] {
+	return &Hub[T]{
+		max:    max(maxSubscribers, 0),
+		buffer: max(buffer, 1),
+		subs:   make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Subscribe adds a subscriber, or returns ErrFull or ErrClosed
+func (h *Hub[T]) Subscribe() (*Subscription[T], error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.max > 0 && len(h.subs) >= h.max {
+		return nil, ErrFull
+	}
+	c := make(chan T, h.buffer)
+	s := &Subscription[T]{C: c, c: c, hub: h}
+	h.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Publish hands v to every subscriber, dropping those that are full
+func (h *Hub[T]) Publish(v T) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs {
+		select {
+		case s.c <- v:
+		default:
+			s.dropped = true
+			h.remove(s)
+		}
+	}
+}
+
+// Len returns the number of subscribers
+func (h *Hub[T]) Len() int {
+	if h == nil {
+		return 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// Close ends every subscription and refuses new ones
+func (h *Hub[T]) Close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for s := range h.subs {
+		h.remove(s)
+	}
+}
+
+// remove drops a subscriber and closes its channel. Callers hold mu.
+func (h *Hub[T]) remove(s *Subscription[T]) {
+	if _, ok := h.subs[s]; ok {
+		delete(h.subs, s)
+		close(s.c)
+	}
+}
+
+// Close unsubscribes; it is safe to call more than once
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Dropped reports whether the subscription ended because the subscriber
+// fell behind
+func (s *Subscription[T]) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
@@ -0,0 +1,98 @@
+package broadcast
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestHubPublish(t *testing.T) {
+	h := New[int](0, 4)
+	a, _ := h.Subscribe()
+	b, _ := h.Subscribe()
+
+	h.Publish(1)
+	h.Publish(2)
+
+	for name, s := range map[string]*Subscription[int]{"a": a, "b": b} {
+		for _, want := range []int{1, 2} {
+			if got := <-s.C; got != want {
+				t.Errorf("%s: got %d, want %d", name, got, want)
+			}
+		}
+	}
+}
+
+func TestHubDropsSlowSubscriber(t *testing.T) {
+	h := New[int](0, 2)
+	slow, _ := h.Subscribe()
+	fast, _ := h.Subscribe()
+
+	for i := 1; i <= 3; i++ {
+		h.Publish(i)
+		<-fast.C
+	}
+
+	// The slow subscriber keeps what it had buffered, then its channel closes
+	var got []int
+	for v := range slow.C {
+		got = append(got, v)
+	}
+	if len(got) != 2 || !slow.Dropped() {
+		t.Errorf("got %v dropped %v, want the 2 buffered values and the subscriber dropped", got, slow.Dropped())
+	}
+	if fast.Dropped() || h.Len() != 1 {
+		t.Errorf("got fast dropped %v with %d subscribers, want the fast subscriber kept", fast.Dropped(), h.Len())
+	}
+}
+
+func TestHubSubscribe(t *testing.T) {
+	tests := []struct {
+		name        string
+		max         int
+		existing    int
+		closed      bool
+		expectedErr error
+		description string
+	}{
+		{"room", 2, 1, false, nil, "Subscribers are added below the limit"},
+		{"full", 2, 2, false, ErrFull, "The limit bounds subscribers"},
+		{"unlimited", 0, 10, false, nil, "A limit of 0 allows any number"},
+		{"closed", 2, 0, true, ErrClosed, "A closed hub refuses subscribers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New[int](tt.max, 1)
+			for i := 0; i < tt.existing; i++ {
+				h.Subscribe()
+			}
+			if tt.closed {
+				h.Close()
+			}
+			if _, err := h.Subscribe(); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("got %v, want %v\nDescription: %s", err, tt.expectedErr, tt.description)
+			}
+		})
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	h := New[int](0, 1)
+	s, _ := h.Subscribe()
+	s2, _ := h.Subscribe()
+
+	s2.Close()
+	s2.Close()
+	h.Close()
+	h.Publish(1)
+
+	if _, ok := <-s.C; ok {
+		t.Error("got a value after Close, want the channel closed")
+	}
+	if s.Dropped() || s2.Dropped() {
+		t.Error("got subscribers marked dropped, want only slow ones marked")
+	}
+
+	var nilHub *Hub[int]
+	nilHub.Publish(1) // Must not panic
+}
@@ -0,0 +1,154 @@
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrOpen is returned without calling the API while a circuit is open
+var ErrOpen = errors.New("circuit open")
+
+// State is a breaker state, exported as the insiderwatch_circuit_state gauge
+type State int
+
+const (
+	Closed   State = 0
+	HalfOpen State = 1
+	Open     State = 2
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case HalfOpen:
+		return "half_open"
+	default:
+		return "open"
+	}
+}
+
+// Breaker fails calls to an API fast after it has failed repeatedly. After
+// threshold consecutive failures the circuit opens for cooldown; then one
+// probe call is let through and its outcome closes or reopens the circuit.
+type Breaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	log       *logrus.Logger
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	probeAt  time.Time // Start of the half-open probe in flight, zero if none
+	now      func() time.Time
+}
+
+// New creates a breaker for an endpoint group. A threshold of 0 disables it:
+// the returned nil *Breaker allows every call.
+func New(name string, threshold int, cooldown time.Duration, log *logrus.Logger) *Breaker {
+	if threshold <= 0 {
+		return nil
+	}
+	metrics.CircuitState.WithLabelValues(name).Set(float64(Closed))
+	return &Breaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		log:       log,
+		now:       time.Now,
+	}
+}
+
+// Allow returns ErrOpen if the call should fail fast, or nil if it may go
+// ahead. Every allowed call must be followed by Record.
+func (b *Breaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case Open:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.setState(HalfOpen)
+		b.probeAt = now
+		return nil
+	case HalfOpen:
+		// One probe at a time; a probe that never reported back is replaced
+		// after another cooldown
+		if !b.probeAt.IsZero() && now.Sub(b.probeAt) < b.cooldown {
+			return fmt.Errorf("%s: %w", b.name, ErrOpen)
+		}
+		b.probeAt = now
+		return nil
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call. Only failures that say the
+// API is unhealthy (outages, rate limiting) should be passed as failed.
+func (b *Breaker) Record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.probeAt = time.Time{}
+		if b.state != Closed {
+			b.setState(Closed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == HalfOpen || (b.state == Closed && b.failures >= b.threshold) {
+		b.openedAt = b.now()
+		b.probeAt = time.Time{}
+		b.setState(Open)
+	}
+}
+
+// State returns the current state
+func (b *Breaker) State() State {
+	if b == nil {
+		return Closed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *Breaker) setState(s State) {
+	from := b.state
+	b.state = s
+	metrics.CircuitState.WithLabelValues(b.name).Set(float64(s))
+	if b.log == nil {
+		return
+	}
+
+	entry := b.log.WithFields(logrus.Fields{
+		"api":      b.name,
+		"from":     from.String(),
+		"to":       s.String(),
+		"failures": b.failures,
+	})
+	if s == Open {
+		entry.WithField("cooldown_sec", b.cooldown.Seconds()).Warn("Circuit opened, failing API calls fast")
+	} else {
+		entry.Info("Circuit state changed")
+	}
+}
@@ -0,0 +1,72 @@
+package circuit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	b := New("test", 3, time.Minute, nil)
+	b.now = func() time.Time { return now }
+
+	// Failures below the threshold keep the circuit closed
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("failure %d: got %v, want call allowed", i, err)
+		}
+		b.Record(true)
+	}
+	if b.State() != Closed {
+		t.Fatalf("got %s after 2 failures, want closed", b.State())
+	}
+
+	// A success resets the count
+	b.Allow()
+	b.Record(false)
+	for i := 0; i < 3; i++ {
+		b.Allow()
+		b.Record(true)
+	}
+	if b.State() != Open {
+		t.Fatalf("got %s after 3 consecutive failures, want open", b.State())
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("got %v during cooldown, want ErrOpen", err)
+	}
+
+	// After the cooldown one probe goes through; a failed probe reopens
+	now = now.Add(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("got %v after cooldown, want probe allowed", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("got %v with a probe in flight, want ErrOpen", err)
+	}
+	b.Record(true)
+	if b.State() != Open {
+		t.Fatalf("got %s after failed probe, want open", b.State())
+	}
+
+	// A successful probe closes the circuit
+	now = now.Add(time.Minute)
+	b.Allow()
+	b.Record(false)
+	if b.State() != Closed {
+		t.Errorf("got %s after successful probe, want closed", b.State())
+	}
+	if err := b.Allow(); err != nil {
+		t.Errorf("got %v once closed, want call allowed", err)
+	}
+}
+
+func TestDisabledBreaker(t *testing.T) {
+	b := New("disabled", 0, time.Minute, nil)
+	for i := 0; i < 10; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("got %v, want a disabled breaker to allow every call", err)
+		}
+		b.Record(true)
+	}
+}
@@ -0,0 +1,36 @@
+// Package clock abstracts the current time, so code that depends on it
+// (wallet ages, cooldowns, windows, cache TTLs) can be tested against a
+// frozen clock instead of replicating its formulas.
+package clock
+
+import "time"
+
+// Clock tells the time and makes tickers
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	NewTicker(d time.Duration) Ticker
+}
+
+// Ticker delivers ticks like time.Ticker
+type Ticker interface {
+	C() <-chan time.Time
+	Stop()
+}
+
+// Real is the system clock
+type Real struct{}
+
+func (Real) Now() time.Time { return time.Now() }
+
+func (Real) Since(t time.Time) time.Duration { return time.Since(t) }
+
+func (Real) NewTicker(d time.Duration) Ticker { return realTicker{time.NewTicker(d)} }
+
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.t.C }
+
+func (t realTicker) Stop() { t.t.Stop() }
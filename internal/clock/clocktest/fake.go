@@ -0,0 +1,106 @@
+// Package clocktest provides a controllable clock for tests
+package clocktest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/clock"
+)
+
+// Fake is a clock that stands still until the test moves it. Tickers fire
+// as Advance or Set carries the time past their next tick; like
+// time.Ticker they drop ticks a slow receiver hasn't taken.
+type Fake struct {
+	mu      sync.Mutex
+	now     time.Time
+	tickers []*fakeTicker
+}
+
+// NewFake returns a clock frozen at now
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// NewTicker returns a ticker that ticks every d of fake time
+func (f *Fake) NewTicker(d time.Duration) clock.Ticker {
+	if d <= 0 {
+		panic("clocktest: non-positive interval for NewTicker")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTicker{c: make(chan time.Time, 1), interval: d, next: f.now.Add(d)}
+	f.tickers = append(f.tickers, t)
+	return t
+}
+
+// Advance moves the clock forward by d, firing any tickers that come due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	f.now = f.now.Add(d)
+	f.fire()
+	f.mu.Unlock()
+}
+
+// Set moves the clock to now, firing any tickers that come due
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	f.now = now
+	f.fire()
+	f.mu.Unlock()
+}
+
+// fire sends the tickers that are due their tick. Callers hold mu.
+func (f *Fake) fire() {
+	live := f.tickers[:0]
+	for _, t := range f.tickers {
+		if t.stopped() {
+			continue
+		}
+		live = append(live, t)
+		if t.next.After(f.now) {
+			continue
+		}
+		select {
+		case t.c <- f.now:
+		default:
+		}
+		for !t.next.After(f.now) {
+			t.next = t.next.Add(t.interval)
+		}
+	}
+	f.tickers = live
+}
+
+type fakeTicker struct {
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time // Guarded by the clock's mu
+
+	mu   sync.Mutex
+	stop bool
+}
+
+func (t *fakeTicker) C() <-chan time.Time { return t.c }
+
+func (t *fakeTicker) Stop() {
+	t.mu.Lock()
+	t.stop = true
+	t.mu.Unlock()
+}
+
+func (t *fakeTicker) stopped() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stop
+}
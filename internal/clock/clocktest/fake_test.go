@@ -0,0 +1,75 @@
+package clocktest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeNow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	f := NewFake(start)
+	if got := f.Now(); !got.Equal(start) {
+		t.Errorf("got %v, want the clock frozen at %v", got, start)
+	}
+
+	f.Advance(90 * time.Second)
+	if got := f.Since(start); got != 90*time.Second {
+		t.Errorf("got %s since the start, want 1m30s", got)
+	}
+
+	f.Set(start.Add(-time.Hour))
+	if got := f.Now(); !got.Equal(start.Add(-time.Hour)) {
+		t.Errorf("got %v, want Set to move the clock backwards too", got)
+	}
+}
+
+func TestFakeTicker(t *testing.T) {
+	tests := []struct {
+		name        string
+		advance     []time.Duration
+		expected    int
+		description string
+	}{
+		{"not due", []time.Duration{59 * time.Second}, 0, "Nothing fires before the interval passes"},
+		{"due", []time.Duration{time.Minute}, 1, "A tick fires once the interval passes"},
+		{"in steps", []time.Duration{30 * time.Second, 30 * time.Second}, 1, "Small steps add up"},
+		{"dropped", []time.Duration{5 * time.Minute}, 1, "A jump past several ticks delivers one, like time.Ticker"},
+		{"unread", []time.Duration{time.Minute, time.Minute}, 1, "Ticks the receiver hasn't taken are dropped"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFake(time.Unix(1700000000, 0))
+			ticker := f.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for _, d := range tt.advance {
+				f.Advance(d)
+			}
+
+			got := 0
+			for done := false; !done; {
+				select {
+				case <-ticker.C():
+					got++
+				default:
+					done = true
+				}
+			}
+			if got != tt.expected {
+				t.Errorf("got %d ticks, want %d\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestFakeTickerStop(t *testing.T) {
+	f := NewFake(time.Unix(1700000000, 0))
+	ticker := f.NewTicker(time.Minute)
+	ticker.Stop()
+	f.Advance(time.Hour)
+	select {
+	case <-ticker.C():
+		t.Error("got a tick after Stop, want none")
+	default:
+	}
+}
@@ -3,12 +3,15 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/liamashdown/insiderwatch/internal/secrets"
+	"github.com/sirupsen/logrus"
 )
 
 // AuthMode represents the authentication mode for Data API
@@ -40,36 +43,167 @@ type Config struct {
 	// Gamma API
 	GammaAPIBaseURL string
 
+	// Market cache pre-warming
+	EnableMarketPrewarm   bool          // Cache newly listed markets from Gamma ahead of their first trade
+	MarketPrewarmInterval time.Duration // Time between pre-warm runs (jittered by up to ±10%)
+	MarketPrewarmPageSize int           // Markets requested per Gamma page
+	MarketPrewarmPages    int           // Most pages a run reads, newest first
+
+	// CLOB API (order books)
+	ClobAPIBaseURL       string
+	EnableOrderbookCheck bool    // Fetch the order book for WARN/ALERT trades to measure depth consumed
+	OrderbookPriceBand   float64 // Depth is counted within this distance of the trade price (e.g. 0.03 = 3 cents)
+
+	// Subgraph (optional on-chain wallet history, e.g. Goldsky)
+	SubgraphURL    string
+	SubgraphAPIKey string
+	SubgraphRPS    float64
+	SubgraphBurst  int
+
 	// Detection thresholds
 	BigTradeUSD          float64 // Minimum to fetch from API
 	MinTradeUSD          float64 // Minimum to process and alert
 	NewWalletDaysMax     int
 	SuspicionScoreWarn   float64 // 0-100 scale (e.g., 70)
 	SuspicionScoreAlert  float64 // 0-100 scale (e.g., 85)
+	OldWalletScoreAlert  float64 // 0-100 scale; wallets older than NewWalletDaysMax only notify at or above this
 	NetPositionWindowHrs int
+	ConcentrationWindowHrs int // Lookback for the one-sided concentration check
 	AlertCooldownMins    int
 	TimeToCloseHoursMax  int     // Hours before market close to flag trades
 	MinWinRateThreshold  float64 // Win rate threshold (0.0-1.0) to flag wallets
+	MaxMarketHorizonDays int     // Skip markets ending more than this many days after the trade
+	MarketFallbackTTL    time.Duration // How long a market Gamma couldn't find stays cached from trade data
+	MinMarketLiquidityUSD float64            // Skip trades on markets with less liquidity; 0 disables
+	MinMarketLiquidityByCategory map[string]float64 // Per-category overrides of MinMarketLiquidityUSD
 
 	// Cluster detection
 	EnableClusterDetection bool // Enable wallet clustering and coordinated trade detection
 	ClusterLookbackHours   int  // Hours to look back for coordinated trades
+	OpposingCoordinatedMultiplier float64 // Multiplier when cluster wallets take opposite sides of a market
+	ClusterExcludedAddresses []string // Shared addresses (e.g. exchange hot wallets) that never link wallets
+	EnableWithdrawalClustering bool          // Also cluster flagged wallets by withdrawal destination (requires SUBGRAPH_URL)
+	WithdrawalScanInterval     time.Duration // Time between withdrawal destination scans
+	WithdrawalLookbackDays     int           // Scan wallets alerted on within this many days
 
 	// Velocity detection
 	EnableVelocityDetection bool // Enable rapid trade detection
 	VelocityWindowMinutes   int  // Time window for velocity check (e.g., 5 minutes)
 	VelocityThreshold       int  // Number of trades in window to flag (e.g., 3)
 
+	// Detector toggles for the built-in rules without one of their own above
+	EnableFirstTradeDetection      bool
+	EnableFlashFundingDetection    bool
+	EnableFundingAgeDetection      bool
+	EnableLiquidityDetection       bool
+	EnablePriceConfidenceDetection bool // Extreme prices
+	EnableConcentrationDetection   bool
+	EnableCoordinatedDetection     bool // Also needs EnableClusterDetection
+	EnableWinRateDetection         bool
+
+	// Funding utilization
+	FundingUtilizationThreshold  float64 // Fraction of initial funding a first trade must use to be flagged
+	FundingUtilizationMultiplier float64
+
+	// Size anomaly
+	SizeAnomalyMultiplier float64 // Multiplier at 10x the wallet's usual trade size; 50x and 100x add the same step again
+	SizeAnomalyMinTrades  int     // Prior trades required before a trade can look anomalous
+
+	// Losing record discount
+	LosingRecordMinTrades  int     // Resolved trades required before a losing record counts
+	LosingRecordMaxWinRate float64 // Win rate below which a wallet is discounted; 0 disables
+	LosingRecordFloor      float64 // Multiplier at a 0% win rate
+
+	// Holder dominance
+	EnableHolderDominance     bool    // Check top holders for trades already at the WARN threshold
+	HolderDominanceMinShare   float64 // Share of the listed holders' supply a top-3 holder needs
+	HolderDominanceMultiplier float64
+
+	// Copy-trade detection
+	EnableCopyTradeDetection bool    // Score trades that mirror a recently flagged wallet
+	CopyTradeFlaggedDays     int     // Wallets with an ALERT-severity alert within this many days are leaders
+	CopyTradeWindowMins      int     // How far a leader's trade may precede the follower's
+	CopyTradeMultiplier      float64
+
+	// Exit alerts
+	EnableExitAlerts  bool    // Follow up when a wallet reverses the position behind a WARN or ALERT alert
+	ExitAlertFraction float64 // Fraction of the alerted position that must be reversed
+	ExitScanInterval  time.Duration // Time between fetches of alerted wallets' trades below BIG_TRADE_USD
+
+	// Aggregate exposure alerts
+	EnableExposureAlerts  bool    // Alert when a new wallet's buys across markets add up within a window
+	ExposureAlertUSD      float64 // Combined buy notional that triggers the alert
+	ExposureWindowHours   int     // Rolling window the buys are summed over
+	ExposureMinMarkets    int     // Markets the buys must span
+	ExposureCooldownHours int     // Hours before the same wallet can raise another exposure alert
+
+	// Built-in rule thresholds and multipliers without a setting of their own
+	Detection Detection
+
+	// Watch profiles, each with its own filters, thresholds and alert route.
+	// With none, trades alert through ALERT_MODE with the global thresholds.
+	Profiles []Profile
+
+	// Custom rules
+	CustomRulesFile string // YAML file of threshold rules, reloaded on SIGHUP; disabled when empty
+
 	// Rate limits (requests per second)
 	DataAPITradesRPS   float64
 	DataAPIActivityRPS float64
+	DataAPIPositionsRPS float64
+	DataAPIHoldersRPS   float64
 	GammaAPIMarketsRPS float64
+	ClobAPIBookRPS     float64
+
+	// Rate limit bursts: requests allowed at once after a quiet period; 0 uses the RPS
+	DataAPITradesBurst    int
+	DataAPIActivityBurst  int
+	DataAPIPositionsBurst int
+	DataAPIHoldersBurst   int
+	GammaAPIMarketsBurst  int
+	ClobAPIBookBurst      int
+
+	// Shared budget for the Data, Gamma, and CLOB APIs on top of the
+	// per-endpoint limits, granted by priority; 0 RPS disables
+	SharedRateLimitRPS   float64
+	SharedRateLimitBurst int
+
+	// Circuit breaker
+	CircuitBreakerFailures    int // Consecutive failed calls that open an API's circuit; 0 disables
+	CircuitBreakerCooldownSec int // Seconds an open circuit fails fast before probing
+
+	// Response limits
+	APIMaxResponseMB int // Largest decompressed Data or Gamma API response read, in MB
+
+	// Request timeouts, per attempt
+	DataAPITradesTimeout   time.Duration
+	DataAPIActivityTimeout time.Duration // Also covers positions and holders
+	GammaAPITimeout        time.Duration
+
+	// Responses slower than this are logged with their request ID; 0 disables
+	APISlowRequestThreshold time.Duration
+
+	// Outbound proxies; empty falls back to HTTP_PROXY / HTTPS_PROXY
+	DataAPIProxyURL  string
+	GammaAPIProxyURL string
+	APICABundle      string // Extra PEM roots for Data and Gamma API TLS, e.g. a proxy's CA
 
 	// Worker pool
 	WalletLookupWorkers int
 
+	// Trade ingestion
+	AggregateSameTxFills bool   // Merge fills sharing a transaction hash before processing
+	IncludeMakerTrades   bool    // Also fetch maker-side fills (resting limit orders)
+	MakerScoreMultiplier float64 // Score multiplier for maker fills, which are noisier than taker trades
+	IngestMode           string // poll, websocket, both
+	LiveFeedURL          string // Real-time data socket for websocket ingestion
+
 	// Polling
-	PollIntervalSec int
+	PollIntervalSec  int
+	PollJitterPct    float64       // Each poll interval varies randomly by up to ± this percentage, so instances drift apart
+	PollStartSplay   time.Duration // The first poll waits a random delay of up to this long instead of running at startup; 0 polls immediately
+	PollCycleTimeout time.Duration // Deadline for one poll cycle; defaults to 90% of the poll interval
+	ReadyMaxPollAge  time.Duration // /ready fails once the last successful poll is older; defaults to 3 poll intervals
 
 	// Alerts
 	AlertMode        string   // log, discord, smtp, multi
@@ -79,72 +213,309 @@ type Config struct {
 	SMTPUser      string
 	SMTPPassword  string
 	SMTPFrom      string
-	SMTPTo        []string
+	SMTPTo        []string // Recipients, bare or with a display name ("Alerts <alerts@example.com>"), deduplicated
+	SMTPCheckOnStartup bool // Connect, EHLO and authenticate at startup, without sending, so a bad SMTP setup fails the boot
+	DisplayTimezone string // IANA time zone alert timestamps and the dashboard are shown in; storage stays in UTC
+
+	// Alert rate caps
+	AlertRateLimits              map[string]int // Alerts per minute each sender of a channel (log, discord, smtp) passes on; channels without one are uncapped
+	AlertOverflowSummaryInterval time.Duration  // How often alerts held back by a cap are rolled into a summary
+	AlertRateLimitExempt         []string       // Severities that always go through, whatever the cap
+
+	// Daily summary
+	DailySummaryTime     string // Local time of day (HH:MM) the summary is sent; empty disables it
+	DailySummaryTimezone string // IANA time zone DailySummaryTime is in
+	DailySummaryChannels string // Where the summary goes, in ALERT_MODE's format; defaults to ALERT_MODE
+
+	// Win rate
+	WinRateRecalcInterval time.Duration // Time between scheduled win rate recalculations (jittered by up to ±10%)
+	WinRateWorkers        int           // Market batches resolved in parallel during a recalculation
+	NormalizeOutcomes     bool          // Trim and collapse whitespace in stored outcome labels, and ignore case when matching them to winners
 
 	// Metrics/Health
 	MetricsPort int
 	HealthPort  int
+	APIAuthToken string // Bearer token required on every HTTP endpoint except /health, /ready and /metrics
+	AlertStreamMaxClients int // Clients connected to GET /alerts/stream at once
+	MetricScoreBuckets        []float64 // Raw suspicion score histogram buckets; nil keeps the defaults
+	MetricTradeDurationBuckets []float64 // Trade processing duration histogram buckets in seconds; nil keeps the defaults
+	LogLevel     string // Minimum level logged: debug, info, warn or error
+	EnablePprof  bool   // Serve /debug/pprof and /debug/vars
+	EnableTracing bool  // Export OpenTelemetry traces over OTLP, configured by the standard OTEL_* variables
+	SentryDSN               string  // Report errors and panics to Sentry (or a compatible service); empty disables reporting
+	ErrorReportSampleRate   float64 // Fraction of errors reported, 0 to 1
+	ErrorReportMaxPerMinute int     // Most errors reported a minute; 0 for no cap
+	PprofPort    int    // Port for the debug endpoints; 0 serves them on the health port
+
+	// Sources maps each setting Load read to where its value came from:
+	// SourceEnv, SourceFile, SourceSecret or SourceDefault
+	Sources map[string]string
 }
 
-// Load reads configuration from environment variables
+// Load reads configuration from environment variables and, when
+// CONFIG_FILE names one, a YAML file. Environment variables override the
+// file.
 func Load() (*Config, error) {
+	src, err := readConfigFile(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return nil, err
+	}
+	loadMu.Lock()
+	loading = src
+	defer func() {
+		loading = nil
+		loadMu.Unlock()
+	}()
+
 	cfg := &Config{
 		Environment:          getEnv("ENVIRONMENT", "production"),
-		DatabaseDSN:          getEnv("DATABASE_DSN", "insiderwatch:insiderwatch@tcp(mysql:3306)/insiderwatch?parseTime=true"),
+		DatabaseDSN:          getSecret("DATABASE_DSN", ""),
 		DatabaseMaxConns:     getEnvInt("DATABASE_MAX_CONNS", 25),
 		DatabaseMaxIdleTime:  time.Duration(getEnvInt("DATABASE_MAX_IDLE_TIME_MINS", 5)) * time.Minute,
 		DataAPIBaseURL:       getEnv("DATA_API_BASE_URL", "https://data-api.polymarket.com"),
 		DataAPIAuthMode:      AuthMode(getEnv("DATA_API_AUTH_MODE", "none")),
-		DataAPIBearerToken:   secrets.GetOptionalSecret("DATA_API_BEARER_TOKEN", ""),
-		DataAPIAPIKey:        secrets.GetOptionalSecret("DATA_API_API_KEY", ""),
+		DataAPIBearerToken:   getSecret("DATA_API_BEARER_TOKEN", ""),
+		DataAPIAPIKey:        getSecret("DATA_API_API_KEY", ""),
 		GammaAPIBaseURL:      getEnv("GAMMA_API_BASE_URL", "https://gamma-api.polymarket.com"),
+		EnableMarketPrewarm:   getEnvBool("ENABLE_MARKET_PREWARM", false),
+		MarketPrewarmInterval: getEnvDuration("MARKET_PREWARM_INTERVAL", 5*time.Minute),
+		MarketPrewarmPageSize: getEnvInt("MARKET_PREWARM_PAGE_SIZE", 100),
+		MarketPrewarmPages:    getEnvInt("MARKET_PREWARM_PAGES", 3),
+		ClobAPIBaseURL:       getEnv("CLOB_API_BASE_URL", "https://clob.polymarket.com"),
+		EnableOrderbookCheck: getEnvBool("ENABLE_ORDERBOOK_CHECK", true),
+		OrderbookPriceBand:   getEnvFloat("ORDERBOOK_PRICE_BAND", 0.03),
+		SubgraphURL:          getEnv("SUBGRAPH_URL", ""),
+		SubgraphAPIKey:       getSecret("SUBGRAPH_API_KEY", ""),
+		SubgraphRPS:          getEnvFloat("SUBGRAPH_RPS", 2.0),
+		SubgraphBurst:        getEnvInt("SUBGRAPH_BURST", 0),
 		BigTradeUSD:          getEnvFloat("BIG_TRADE_USD", 10000.0),
 		MinTradeUSD:          getEnvFloat("MIN_TRADE_USD", 5000.0),
 		NewWalletDaysMax:     getEnvInt("NEW_WALLET_DAYS_MAX", 1800),
 		SuspicionScoreWarn:   getEnvFloat("SUSPICION_SCORE_WARN", 70.0),
 		SuspicionScoreAlert:  getEnvFloat("SUSPICION_SCORE_ALERT", 85.0),
+		OldWalletScoreAlert:  getEnvFloat("OLD_WALLET_SCORE_ALERT", 95.0),
 		NetPositionWindowHrs: getEnvInt("NET_POSITION_WINDOW_HRS", 24),
+		ConcentrationWindowHrs: getEnvInt("CONCENTRATION_WINDOW_HRS", 6),
 		AlertCooldownMins:    getEnvInt("ALERT_COOLDOWN_MINS", 60),
 		TimeToCloseHoursMax:  getEnvInt("TIME_TO_CLOSE_HOURS_MAX", 48),
 		MinWinRateThreshold:  getEnvFloat("MIN_WIN_RATE_THRESHOLD", 0.75),
+		MaxMarketHorizonDays: getEnvInt("MAX_MARKET_HORIZON_DAYS", 60),
+		MarketFallbackTTL:    getEnvDuration("MARKET_FALLBACK_TTL", 10*time.Minute),
+		MinMarketLiquidityUSD: getEnvFloat("MIN_MARKET_LIQUIDITY_USD", 0),
 		EnableClusterDetection: getEnvBool("ENABLE_CLUSTER_DETECTION", true),
 		ClusterLookbackHours:   getEnvInt("CLUSTER_LOOKBACK_HOURS", 24),
+		OpposingCoordinatedMultiplier: getEnvFloat("OPPOSING_COORDINATED_MULTIPLIER", 1.5),
+		EnableWithdrawalClustering: getEnvBool("ENABLE_WITHDRAWAL_CLUSTERING", false),
+		WithdrawalScanInterval:     getEnvDuration("WITHDRAWAL_SCAN_INTERVAL", time.Hour),
+		WithdrawalLookbackDays:     getEnvInt("WITHDRAWAL_LOOKBACK_DAYS", 30),
 		EnableVelocityDetection: getEnvBool("ENABLE_VELOCITY_DETECTION", true),
 		VelocityWindowMinutes:   getEnvInt("VELOCITY_WINDOW_MINUTES", 10),
 		VelocityThreshold:       getEnvInt("VELOCITY_THRESHOLD", 3),
+		EnableFirstTradeDetection:      getEnvBool("ENABLE_FIRST_TRADE_DETECTION", true),
+		EnableFlashFundingDetection:    getEnvBool("ENABLE_FLASH_FUNDING_DETECTION", true),
+		EnableFundingAgeDetection:      getEnvBool("ENABLE_FUNDING_AGE_DETECTION", true),
+		EnableLiquidityDetection:       getEnvBool("ENABLE_LIQUIDITY_DETECTION", true),
+		EnablePriceConfidenceDetection: getEnvBool("ENABLE_PRICE_CONFIDENCE_DETECTION", true),
+		EnableConcentrationDetection:   getEnvBool("ENABLE_CONCENTRATION_DETECTION", true),
+		EnableCoordinatedDetection:     getEnvBool("ENABLE_COORDINATED_DETECTION", true),
+		EnableWinRateDetection:         getEnvBool("ENABLE_WIN_RATE_DETECTION", true),
+		CustomRulesFile:         getEnv("CUSTOM_RULES_FILE", ""),
+		FundingUtilizationThreshold:  getEnvFloat("FUNDING_UTILIZATION_THRESHOLD", 0.9),
+		FundingUtilizationMultiplier: getEnvFloat("FUNDING_UTILIZATION_MULTIPLIER", 2.0),
+		SizeAnomalyMultiplier:   getEnvFloat("SIZE_ANOMALY_MULTIPLIER", 1.5),
+		SizeAnomalyMinTrades:    getEnvInt("SIZE_ANOMALY_MIN_TRADES", 5),
+		LosingRecordMinTrades:    getEnvInt("LOSING_RECORD_MIN_TRADES", 20),
+		LosingRecordMaxWinRate:   getEnvFloat("LOSING_RECORD_MAX_WIN_RATE", 0.3),
+		LosingRecordFloor:        getEnvFloat("LOSING_RECORD_FLOOR", 0.5),
+		EnableHolderDominance:     getEnvBool("ENABLE_HOLDER_DOMINANCE", true),
+		HolderDominanceMinShare:   getEnvFloat("HOLDER_DOMINANCE_MIN_SHARE", 0.2),
+		HolderDominanceMultiplier: getEnvFloat("HOLDER_DOMINANCE_MULTIPLIER", 1.5),
+		EnableExitAlerts:         getEnvBool("ENABLE_EXIT_ALERTS", true),
+		ExitAlertFraction:        getEnvFloat("EXIT_ALERT_FRACTION", 0.8),
+		ExitScanInterval:         getEnvDuration("EXIT_SCAN_INTERVAL", 5*time.Minute),
+		EnableExposureAlerts:     getEnvBool("ENABLE_EXPOSURE_ALERTS", false),
+		ExposureAlertUSD:         getEnvFloat("EXPOSURE_ALERT_USD", 50000),
+		ExposureWindowHours:      getEnvInt("EXPOSURE_WINDOW_HOURS", 24),
+		ExposureMinMarkets:       getEnvInt("EXPOSURE_MIN_MARKETS", 2),
+		ExposureCooldownHours:    getEnvInt("EXPOSURE_COOLDOWN_HOURS", 24),
+		EnableCopyTradeDetection: getEnvBool("ENABLE_COPY_TRADE_DETECTION", true),
+		CopyTradeFlaggedDays:     getEnvInt("COPY_TRADE_FLAGGED_DAYS", 7),
+		CopyTradeWindowMins:      getEnvInt("COPY_TRADE_WINDOW_MINS", 30),
+		CopyTradeMultiplier:      getEnvFloat("COPY_TRADE_MULTIPLIER", 1.8),
 		DataAPITradesRPS:     getEnvFloat("DATA_API_TRADES_RPS", 2.0),
 		DataAPIActivityRPS:   getEnvFloat("DATA_API_ACTIVITY_RPS", 1.0),
+		DataAPIPositionsRPS:  getEnvFloat("DATA_API_POSITIONS_RPS", 1.0),
+		DataAPIHoldersRPS:    getEnvFloat("DATA_API_HOLDERS_RPS", 1.0),
 		GammaAPIMarketsRPS:   getEnvFloat("GAMMA_API_MARKETS_RPS", 5.0),
+		ClobAPIBookRPS:       getEnvFloat("CLOB_API_BOOK_RPS", 2.0),
+		DataAPITradesBurst:    getEnvInt("DATA_API_TRADES_BURST", 0),
+		DataAPIActivityBurst:  getEnvInt("DATA_API_ACTIVITY_BURST", 0),
+		DataAPIPositionsBurst: getEnvInt("DATA_API_POSITIONS_BURST", 0),
+		DataAPIHoldersBurst:   getEnvInt("DATA_API_HOLDERS_BURST", 0),
+		GammaAPIMarketsBurst:  getEnvInt("GAMMA_API_MARKETS_BURST", 0),
+		ClobAPIBookBurst:      getEnvInt("CLOB_API_BOOK_BURST", 0),
+		SharedRateLimitRPS:    getEnvFloat("SHARED_RATE_LIMIT_RPS", 0),
+		SharedRateLimitBurst:  getEnvInt("SHARED_RATE_LIMIT_BURST", 0),
+		CircuitBreakerFailures:    getEnvInt("CIRCUIT_BREAKER_FAILURES", 5),
+		CircuitBreakerCooldownSec: getEnvInt("CIRCUIT_BREAKER_COOLDOWN_SECS", 60),
+		APIMaxResponseMB:          getEnvInt("API_MAX_RESPONSE_MB", 64),
+		DataAPITradesTimeout:      getEnvDuration("DATA_API_TRADES_TIMEOUT", 30*time.Second),
+		DataAPIActivityTimeout:    getEnvDuration("DATA_API_ACTIVITY_TIMEOUT", 30*time.Second),
+		GammaAPITimeout:           getEnvDuration("GAMMA_API_TIMEOUT", 30*time.Second),
+		APISlowRequestThreshold:   getEnvDuration("API_SLOW_REQUEST_THRESHOLD", 5*time.Second),
+		DataAPIProxyURL:           getSecret("DATA_API_PROXY_URL", ""), // May carry proxy credentials
+		GammaAPIProxyURL:          getSecret("GAMMA_API_PROXY_URL", ""),
+		APICABundle:               getEnv("API_CA_BUNDLE", ""),
 		WalletLookupWorkers:  getEnvInt("WALLET_LOOKUP_WORKERS", 1),
+		AggregateSameTxFills: getEnvBool("AGGREGATE_SAME_TX_FILLS", true),
+		IncludeMakerTrades:   getEnvBool("INCLUDE_MAKER_TRADES", false),
+		MakerScoreMultiplier: getEnvFloat("MAKER_SCORE_MULTIPLIER", 0.8),
+		IngestMode:           getEnv("INGEST_MODE", "poll"),
+		LiveFeedURL:          getEnv("LIVE_FEED_URL", "wss://ws-live-data.polymarket.com"),
 		PollIntervalSec:      getEnvInt("POLL_INTERVAL_SEC", 30),
+		PollJitterPct:        getEnvFloat("POLL_JITTER_PCT", 0),
+		PollStartSplay:       getEnvDuration("POLL_START_SPLAY", 0),
 		AlertMode:            getEnv("ALERT_MODE", "log"),
-		SMTPHost:             getEnv("SMTP_HOST", ""),
+		SMTPHost:             getSecret("SMTP_HOST", ""),
 		SMTPPort:             getEnvInt("SMTP_PORT", 587),
-		SMTPUser:             getEnv("SMTP_USER", ""),
-		SMTPPassword:         secrets.GetOptionalSecret("SMTP_PASSWORD", ""),
-		SMTPFrom:             getEnv("SMTP_FROM", "insiderwatch@example.com"),
+		SMTPUser:             getSecret("SMTP_USER", ""),
+		SMTPPassword:         getSecret("SMTP_PASSWORD", ""),
+		SMTPFrom:             getSecret("SMTP_FROM", "insiderwatch@example.com"),
+		SMTPCheckOnStartup:   getEnvBool("SMTP_CHECK_ON_STARTUP", false),
+		DisplayTimezone:      getEnv("DISPLAY_TIMEZONE", "UTC"),
+		AlertOverflowSummaryInterval: getEnvDuration("ALERT_OVERFLOW_SUMMARY_INTERVAL", 5*time.Minute),
+		DailySummaryTime:     getEnv("DAILY_SUMMARY_TIME", ""),
+		DailySummaryTimezone: getEnv("DAILY_SUMMARY_TIMEZONE", "UTC"),
 		MetricsPort:          getEnvInt("METRICS_PORT", 9090),
 		HealthPort:           getEnvInt("HEALTH_PORT", 8080),
+		APIAuthToken:         getSecret("API_AUTH_TOKEN", ""),
+		LogLevel:             getEnv("LOG_LEVEL", "info"),
+		EnablePprof:          getEnvBool("ENABLE_PPROF", false),
+		PprofPort:            getEnvInt("PPROF_PORT", 0),
+		AlertStreamMaxClients: getEnvInt("ALERT_STREAM_MAX_CLIENTS", 10),
+		EnableTracing:        getEnvBool("ENABLE_TRACING", false),
+		SentryDSN:            getSecret("SENTRY_DSN", ""),
+		ErrorReportSampleRate:   getEnvFloat("ERROR_REPORT_SAMPLE_RATE", 1.0),
+		ErrorReportMaxPerMinute: getEnvInt("ERROR_REPORT_MAX_PER_MINUTE", 20),
+		WinRateRecalcInterval: getEnvDuration("WIN_RATE_RECALC_INTERVAL", 24*time.Hour),
+		WinRateWorkers:        getEnvInt("WIN_RATE_WORKERS", 4),
+		NormalizeOutcomes:     getEnvBool("NORMALIZE_OUTCOMES", true),
+	}
+
+	// Finish each poll cycle before the next tick is due
+	cfg.PollCycleTimeout = getEnvDuration("POLL_CYCLE_TIMEOUT", time.Duration(cfg.PollIntervalSec)*time.Second*9/10)
+	cfg.ReadyMaxPollAge = getEnvDuration("READY_MAX_POLL_AGE", 3*time.Duration(cfg.PollIntervalSec)*time.Second)
+
+	cfg.DailySummaryChannels = getEnv("DAILY_SUMMARY_CHANNELS", cfg.AlertMode)
+
+	// Without a DSN, assemble one from its parts so only the password
+	// needs to be a secret
+	dsnParts := fmt.Sprintf("%s:%s@tcp(%s)/%s?%s",
+		getEnv("DATABASE_USER", "insiderwatch"),
+		getSecret("DATABASE_PASSWORD", "insiderwatch"),
+		getEnv("DATABASE_HOST", "mysql:3306"),
+		getEnv("DATABASE_NAME", "insiderwatch"),
+		getEnv("DATABASE_PARAMS", "parseTime=true"))
+	if cfg.DatabaseDSN == "" {
+		cfg.DatabaseDSN = dsnParts
+	} else {
+		for _, part := range []string{"DATABASE_USER", "DATABASE_PASSWORD", "DATABASE_HOST", "DATABASE_NAME", "DATABASE_PARAMS"} {
+			if src.sources[part] != SourceDefault {
+				return nil, fmt.Errorf("set DATABASE_DSN or %s and the other parts, not both", part)
+			}
+		}
 	}
 
-	// Parse SMTP_TO (comma-separated)
-	smtpTo := getEnv("SMTP_TO", "")
+	// Parse SMTP_TO (comma-separated; commas inside quoted display names don't split)
+	smtpTo := getSecret("SMTP_TO", "")
 	if smtpTo != "" {
-		cfg.SMTPTo = parseCSV(smtpTo)
+		cfg.SMTPTo = parseRecipients(smtpTo)
 	}
 
-	// Parse Discord webhook URLs (comma-separated)
-	discordWebhooks := secrets.GetOptionalSecret("DISCORD_WEBHOOK_URLS", "")
+	// Parse CLUSTER_EXCLUDED_ADDRESSES (comma-separated)
+	if excluded := getEnv("CLUSTER_EXCLUDED_ADDRESSES", ""); excluded != "" {
+		cfg.ClusterExcludedAddresses = parseCSV(excluded)
+	}
+
+	// Parse Discord webhook URLs (comma-separated), falling back to the
+	// singular DISCORD_WEBHOOK_URL used by older deployments
+	discordWebhooks := getSecret("DISCORD_WEBHOOK_URLS", "")
+	legacyWebhook := getSecret("DISCORD_WEBHOOK_URL", "")
+	if discordWebhooks == "" {
+		discordWebhooks = legacyWebhook
+	}
 	if discordWebhooks != "" {
 		cfg.DiscordWebhookURLs = parseCSV(discordWebhooks)
 	}
 
 	// Parse extra headers JSON
-	extraHeadersJSON := getEnv("DATA_API_EXTRA_HEADERS", "{}")
+	extraHeadersJSON := getSecret("DATA_API_EXTRA_HEADERS", "{}")
 	if err := json.Unmarshal([]byte(extraHeadersJSON), &cfg.DataAPIExtraHeaders); err != nil {
 		return nil, fmt.Errorf("invalid DATA_API_EXTRA_HEADERS JSON: %w", err)
 	}
 
+	// Parse per-category liquidity minimums JSON, keyed by lowercase category
+	liquidityJSON := getEnv("MIN_MARKET_LIQUIDITY_BY_CATEGORY", "{}")
+	var liquidityByCategory map[string]float64
+	if err := json.Unmarshal([]byte(liquidityJSON), &liquidityByCategory); err != nil {
+		return nil, fmt.Errorf("invalid MIN_MARKET_LIQUIDITY_BY_CATEGORY JSON: %w", err)
+	}
+	cfg.MinMarketLiquidityByCategory = make(map[string]float64, len(liquidityByCategory))
+	for category, minimum := range liquidityByCategory {
+		cfg.MinMarketLiquidityByCategory[strings.ToLower(strings.TrimSpace(category))] = minimum
+	}
+
+	// Parse per-channel alert rate caps JSON
+	rateLimitsJSON := getEnv("ALERT_RATE_LIMITS", "{}")
+	if err := json.Unmarshal([]byte(rateLimitsJSON), &cfg.AlertRateLimits); err != nil {
+		return nil, fmt.Errorf("invalid ALERT_RATE_LIMITS JSON: %w", err)
+	}
+	if exempt := getEnv("ALERT_RATE_LIMIT_EXEMPT", "ALERT"); !strings.EqualFold(exempt, "none") {
+		cfg.AlertRateLimitExempt = parseCSV(strings.ToUpper(exempt))
+	}
+
+	// Parse histogram bucket overrides (JSON arrays)
+	for _, b := range []struct {
+		name    string
+		buckets *[]float64
+	}{
+		{"METRIC_SCORE_BUCKETS", &cfg.MetricScoreBuckets},
+		{"METRIC_TRADE_DURATION_BUCKETS", &cfg.MetricTradeDurationBuckets},
+	} {
+		if raw := getEnv(b.name, ""); raw != "" {
+			if err := json.Unmarshal([]byte(raw), b.buckets); err != nil {
+				return nil, fmt.Errorf("invalid %s JSON array: %w", b.name, err)
+			}
+		}
+	}
+
+	// Built-in rule thresholds and multipliers (JSON blocks)
+	detection, err := loadDetection()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Detection = detection
+
+	// Watch profiles (JSON array)
+	profiles, err := loadProfiles()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Profiles = profiles
+
+	// A secret that can't be read would otherwise fall back to the default
+	// silently, like a DSN pointing at the wrong database
+	if src.secretErr != nil {
+		return nil, src.secretErr
+	}
+
+	// Settings in the file that Load never read are typos, which would
+	// otherwise silently keep their defaults
+	if unknown := src.unknown(); len(unknown) > 0 {
+		return nil, fmt.Errorf("CONFIG_FILE %s: unknown settings %s", src.path, strings.Join(unknown, ", "))
+	}
+	cfg.Sources = src.sources
+
 	// Validate
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -153,6 +524,17 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// ValidateServe checks what only the serve command needs on top of Validate.
+// Its HTTP server exposes alerts, wallets and admin actions, so it refuses
+// to serve them anonymously rather than fall back to open access; commands
+// without a server run without a token.
+func (c *Config) ValidateServe() error {
+	if c.APIAuthToken == "" {
+		return fmt.Errorf("API_AUTH_TOKEN is required: the HTTP server does not serve alerts, wallets or admin actions without authentication (it replaces ADMIN_TOKEN and READ_API_TOKEN)")
+	}
+	return nil
+}
+
 // Validate checks configuration for errors
 func (c *Config) Validate() error {
 	if c.DatabaseDSN == "" {
@@ -176,11 +558,406 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate alert mode (comma-separated list)
-	modes := strings.Split(c.AlertMode, ",")
+	if err := c.validateAlertModes("ALERT_MODE", c.AlertMode); err != nil {
+		return err
+	}
+
+	if err := validateBuckets("METRIC_SCORE_BUCKETS", c.MetricScoreBuckets); err != nil {
+		return err
+	}
+	if err := validateBuckets("METRIC_TRADE_DURATION_BUCKETS", c.MetricTradeDurationBuckets); err != nil {
+		return err
+	}
+
+	if _, err := time.LoadLocation(c.DisplayTimezone); err != nil {
+		return fmt.Errorf("invalid DISPLAY_TIMEZONE: %w", err)
+	}
+
+	// Validate the alert rate caps
+	for channel, perMinute := range c.AlertRateLimits {
+		switch channel {
+		case "log", "discord", "smtp":
+		default:
+			return fmt.Errorf("invalid ALERT_RATE_LIMITS channel: %s (valid channels: log, discord, smtp)", channel)
+		}
+		if perMinute < 1 {
+			return fmt.Errorf("ALERT_RATE_LIMITS[%s] must be at least 1 alert per minute (got %d)", channel, perMinute)
+		}
+	}
+	if len(c.AlertRateLimits) > 0 && c.AlertOverflowSummaryInterval < time.Minute {
+		return fmt.Errorf("ALERT_OVERFLOW_SUMMARY_INTERVAL must be at least 1m (got %s)", c.AlertOverflowSummaryInterval)
+	}
+	for _, severity := range c.AlertRateLimitExempt {
+		switch severity {
+		case "INFO", "WARN", "ALERT":
+		default:
+			return fmt.Errorf("invalid ALERT_RATE_LIMIT_EXEMPT severity: %s (valid severities: INFO, WARN, ALERT)", severity)
+		}
+	}
+
+	// Validate the daily summary schedule
+	if c.DailySummaryTime != "" {
+		if _, err := time.Parse("15:04", c.DailySummaryTime); err != nil {
+			return fmt.Errorf("DAILY_SUMMARY_TIME must be a time of day like 18:00 (got %q)", c.DailySummaryTime)
+		}
+		if _, err := time.LoadLocation(c.DailySummaryTimezone); err != nil {
+			return fmt.Errorf("invalid DAILY_SUMMARY_TIMEZONE: %w", err)
+		}
+		if err := c.validateAlertModes("DAILY_SUMMARY_CHANNELS", c.DailySummaryChannels); err != nil {
+			return err
+		}
+	}
+
+	// Validate ingest mode
+	switch c.IngestMode {
+	case "poll":
+	case "websocket", "both":
+		if c.LiveFeedURL == "" {
+			return fmt.Errorf("LIVE_FEED_URL is required when INGEST_MODE is %s", c.IngestMode)
+		}
+	default:
+		return fmt.Errorf("invalid INGEST_MODE: %s (must be poll, websocket, or both)", c.IngestMode)
+	}
+
+	// Validate polling and pools; a zero interval panics the ticker and an
+	// empty pool deadlocks every trade
+	if c.PollIntervalSec <= 0 {
+		return fmt.Errorf("POLL_INTERVAL_SEC must be positive (got %d)", c.PollIntervalSec)
+	}
+	if c.PollJitterPct < 0 || c.PollJitterPct > 50 {
+		return fmt.Errorf("POLL_JITTER_PCT must be between 0 and 50 (got %.2f)", c.PollJitterPct)
+	}
+	if c.PollStartSplay < 0 {
+		return fmt.Errorf("POLL_START_SPLAY must not be negative (got %s)", c.PollStartSplay)
+	}
+	if c.WalletLookupWorkers < 1 {
+		return fmt.Errorf("WALLET_LOOKUP_WORKERS must be at least 1 (got %d)", c.WalletLookupWorkers)
+	}
+	if c.DatabaseMaxConns < 1 {
+		return fmt.Errorf("DATABASE_MAX_CONNS must be at least 1 (got %d)", c.DatabaseMaxConns)
+	}
+	if c.DatabaseMaxIdleTime < 0 {
+		return fmt.Errorf("DATABASE_MAX_IDLE_TIME_MINS must not be negative (got %s)", c.DatabaseMaxIdleTime)
+	}
+	if c.HealthPort < 1 || c.HealthPort > 65535 {
+		return fmt.Errorf("HEALTH_PORT must be a port between 1 and 65535 (got %d)", c.HealthPort)
+	}
+	if c.SMTPHost != "" && (c.SMTPPort < 1 || c.SMTPPort > 65535) {
+		return fmt.Errorf("SMTP_PORT must be a port between 1 and 65535 (got %d)", c.SMTPPort)
+	}
+	if c.SMTPHost != "" {
+		if _, err := mail.ParseAddress(c.SMTPFrom); err != nil {
+			return fmt.Errorf("SMTP_FROM %q is not an email address: %v", c.SMTPFrom, err)
+		}
+	}
+	for _, to := range c.SMTPTo {
+		if _, err := mail.ParseAddress(to); err != nil {
+			return fmt.Errorf("SMTP_TO entry %q is not an email address: %v", to, err)
+		}
+	}
+	rates := []struct {
+		name  string
+		value float64
+	}{
+		{"DATA_API_TRADES_RPS", c.DataAPITradesRPS},
+		{"DATA_API_ACTIVITY_RPS", c.DataAPIActivityRPS},
+		{"DATA_API_POSITIONS_RPS", c.DataAPIPositionsRPS},
+		{"DATA_API_HOLDERS_RPS", c.DataAPIHoldersRPS},
+		{"GAMMA_API_MARKETS_RPS", c.GammaAPIMarketsRPS},
+		{"CLOB_API_BOOK_RPS", c.ClobAPIBookRPS},
+		{"SUBGRAPH_RPS", c.SubgraphRPS},
+	}
+	for _, r := range rates {
+		if r.value <= 0 {
+			return fmt.Errorf("%s must be positive (got %.2f)", r.name, r.value)
+		}
+	}
+
+	// Validate trade size and score thresholds
+	if c.BigTradeUSD <= 0 {
+		return fmt.Errorf("BIG_TRADE_USD must be positive (got %.2f)", c.BigTradeUSD)
+	}
+	if c.MinTradeUSD < 0 || c.MinTradeUSD > c.BigTradeUSD {
+		return fmt.Errorf("MIN_TRADE_USD must be between 0 and BIG_TRADE_USD (%.2f), or every fetched trade is filtered out (got %.2f)", c.BigTradeUSD, c.MinTradeUSD)
+	}
+	if c.NewWalletDaysMax < 0 {
+		return fmt.Errorf("NEW_WALLET_DAYS_MAX must not be negative (got %d)", c.NewWalletDaysMax)
+	}
+	if c.SuspicionScoreWarn < 0 || c.SuspicionScoreWarn > 100 {
+		return fmt.Errorf("SUSPICION_SCORE_WARN must be between 0 and 100 (got %.2f)", c.SuspicionScoreWarn)
+	}
+	if c.SuspicionScoreAlert < 0 || c.SuspicionScoreAlert > 100 {
+		return fmt.Errorf("SUSPICION_SCORE_ALERT must be between 0 and 100 (got %.2f)", c.SuspicionScoreAlert)
+	}
+	if c.SuspicionScoreWarn > c.SuspicionScoreAlert {
+		return fmt.Errorf("SUSPICION_SCORE_WARN (%.2f) must not be above SUSPICION_SCORE_ALERT (%.2f)", c.SuspicionScoreWarn, c.SuspicionScoreAlert)
+	}
+	if c.MinWinRateThreshold < 0 || c.MinWinRateThreshold > 1 {
+		return fmt.Errorf("MIN_WIN_RATE_THRESHOLD must be a fraction between 0 and 1, like 0.75 for 75%% (got %.2f)", c.MinWinRateThreshold)
+	}
+	if c.NetPositionWindowHrs <= 0 {
+		return fmt.Errorf("NET_POSITION_WINDOW_HRS must be positive (got %d)", c.NetPositionWindowHrs)
+	}
+	if c.AlertCooldownMins < 0 {
+		return fmt.Errorf("ALERT_COOLDOWN_MINS must not be negative (got %d)", c.AlertCooldownMins)
+	}
+	if c.TimeToCloseHoursMax <= 0 {
+		return fmt.Errorf("TIME_TO_CLOSE_HOURS_MAX must be positive (got %d)", c.TimeToCloseHoursMax)
+	}
+	for category, minimum := range c.MinMarketLiquidityByCategory {
+		if minimum < 0 {
+			return fmt.Errorf("MIN_MARKET_LIQUIDITY_BY_CATEGORY[%s] must not be negative (got %.2f)", category, minimum)
+		}
+	}
+	if c.FundingUtilizationMultiplier < 1.0 {
+		return fmt.Errorf("FUNDING_UTILIZATION_MULTIPLIER must be at least 1.0 (got %.2f)", c.FundingUtilizationMultiplier)
+	}
+	if err := c.Detection.validate(); err != nil {
+		return err
+	}
+	if err := c.validateProfiles(); err != nil {
+		return err
+	}
+
+	// Validate detection windows
+	if c.EnableVelocityDetection {
+		if c.VelocityWindowMinutes <= 0 {
+			return fmt.Errorf("VELOCITY_WINDOW_MINUTES must be positive (got %d)", c.VelocityWindowMinutes)
+		}
+		if c.VelocityThreshold <= 0 {
+			return fmt.Errorf("VELOCITY_THRESHOLD must be positive (got %d)", c.VelocityThreshold)
+		}
+	}
+	if c.EnableClusterDetection && c.ClusterLookbackHours <= 0 {
+		return fmt.Errorf("CLUSTER_LOOKBACK_HOURS must be positive (got %d)", c.ClusterLookbackHours)
+	}
+	if c.EnableWithdrawalClustering {
+		if !c.EnableClusterDetection {
+			return fmt.Errorf("ENABLE_WITHDRAWAL_CLUSTERING requires ENABLE_CLUSTER_DETECTION")
+		}
+		if c.SubgraphURL == "" {
+			return fmt.Errorf("SUBGRAPH_URL is required when ENABLE_WITHDRAWAL_CLUSTERING is true")
+		}
+		if c.WithdrawalScanInterval < time.Minute {
+			return fmt.Errorf("WITHDRAWAL_SCAN_INTERVAL must be at least 1m (got %s)", c.WithdrawalScanInterval)
+		}
+		if c.WithdrawalLookbackDays <= 0 {
+			return fmt.Errorf("WITHDRAWAL_LOOKBACK_DAYS must be positive (got %d)", c.WithdrawalLookbackDays)
+		}
+	}
+	if c.EnableOrderbookCheck && c.OrderbookPriceBand <= 0 {
+		return fmt.Errorf("ORDERBOOK_PRICE_BAND must be positive (got %.2f)", c.OrderbookPriceBand)
+	}
+	if c.MinMarketLiquidityUSD < 0 {
+		return fmt.Errorf("MIN_MARKET_LIQUIDITY_USD must not be negative (got %.2f)", c.MinMarketLiquidityUSD)
+	}
+	bursts := []struct {
+		name  string
+		value int
+	}{
+		{"DATA_API_TRADES_BURST", c.DataAPITradesBurst},
+		{"DATA_API_ACTIVITY_BURST", c.DataAPIActivityBurst},
+		{"DATA_API_POSITIONS_BURST", c.DataAPIPositionsBurst},
+		{"DATA_API_HOLDERS_BURST", c.DataAPIHoldersBurst},
+		{"GAMMA_API_MARKETS_BURST", c.GammaAPIMarketsBurst},
+		{"CLOB_API_BOOK_BURST", c.ClobAPIBookBurst},
+		{"SUBGRAPH_BURST", c.SubgraphBurst},
+		{"SHARED_RATE_LIMIT_BURST", c.SharedRateLimitBurst},
+	}
+	for _, b := range bursts {
+		if b.value < 0 {
+			return fmt.Errorf("%s must not be negative (got %d)", b.name, b.value)
+		}
+	}
+	if c.SharedRateLimitRPS < 0 {
+		return fmt.Errorf("SHARED_RATE_LIMIT_RPS must not be negative (got %.2f)", c.SharedRateLimitRPS)
+	}
+	if c.CircuitBreakerFailures < 0 {
+		return fmt.Errorf("CIRCUIT_BREAKER_FAILURES must not be negative (got %d)", c.CircuitBreakerFailures)
+	}
+	if c.CircuitBreakerFailures > 0 && c.CircuitBreakerCooldownSec <= 0 {
+		return fmt.Errorf("CIRCUIT_BREAKER_COOLDOWN_SECS must be positive (got %d)", c.CircuitBreakerCooldownSec)
+	}
+	if c.APIMaxResponseMB <= 0 {
+		return fmt.Errorf("API_MAX_RESPONSE_MB must be positive (got %d)", c.APIMaxResponseMB)
+	}
+	if c.DataAPITradesTimeout <= 0 {
+		return fmt.Errorf("DATA_API_TRADES_TIMEOUT must be a positive duration (got %s)", c.DataAPITradesTimeout)
+	}
+	if c.DataAPIActivityTimeout <= 0 {
+		return fmt.Errorf("DATA_API_ACTIVITY_TIMEOUT must be a positive duration (got %s)", c.DataAPIActivityTimeout)
+	}
+	if c.GammaAPITimeout <= 0 {
+		return fmt.Errorf("GAMMA_API_TIMEOUT must be a positive duration (got %s)", c.GammaAPITimeout)
+	}
+	if c.APISlowRequestThreshold < 0 {
+		return fmt.Errorf("API_SLOW_REQUEST_THRESHOLD must not be negative (got %s)", c.APISlowRequestThreshold)
+	}
+	if err := validateProxyURL(c.DataAPIProxyURL); err != nil {
+		return fmt.Errorf("DATA_API_PROXY_URL %w", err)
+	}
+	if err := validateProxyURL(c.GammaAPIProxyURL); err != nil {
+		return fmt.Errorf("GAMMA_API_PROXY_URL %w", err)
+	}
+	if c.OldWalletScoreAlert < 0 || c.OldWalletScoreAlert > 100 {
+		return fmt.Errorf("OLD_WALLET_SCORE_ALERT must be between 0 and 100 (got %.2f)", c.OldWalletScoreAlert)
+	}
+	if c.ConcentrationWindowHrs <= 0 {
+		return fmt.Errorf("CONCENTRATION_WINDOW_HRS must be positive (got %d)", c.ConcentrationWindowHrs)
+	}
+	if c.MaxMarketHorizonDays <= 0 {
+		return fmt.Errorf("MAX_MARKET_HORIZON_DAYS must be positive (got %d)", c.MaxMarketHorizonDays)
+	}
+	if c.PollCycleTimeout <= 0 {
+		return fmt.Errorf("POLL_CYCLE_TIMEOUT must be a positive duration (got %s)", c.PollCycleTimeout)
+	}
+	if c.ReadyMaxPollAge <= 0 {
+		return fmt.Errorf("READY_MAX_POLL_AGE must be a positive duration (got %s)", c.ReadyMaxPollAge)
+	}
+	if c.MarketFallbackTTL <= 0 {
+		return fmt.Errorf("MARKET_FALLBACK_TTL must be a positive duration (got %s)", c.MarketFallbackTTL)
+	}
+	if c.EnableMarketPrewarm {
+		if c.MarketPrewarmInterval < time.Minute {
+			return fmt.Errorf("MARKET_PREWARM_INTERVAL must be at least 1m (got %s)", c.MarketPrewarmInterval)
+		}
+		if c.MarketPrewarmPageSize < 1 || c.MarketPrewarmPageSize > 500 {
+			return fmt.Errorf("MARKET_PREWARM_PAGE_SIZE must be between 1 and 500 (got %d)", c.MarketPrewarmPageSize)
+		}
+		if c.MarketPrewarmPages < 1 {
+			return fmt.Errorf("MARKET_PREWARM_PAGES must be at least 1 (got %d)", c.MarketPrewarmPages)
+		}
+	}
+	if c.FundingUtilizationThreshold <= 0 || c.FundingUtilizationThreshold > 1 {
+		return fmt.Errorf("FUNDING_UTILIZATION_THRESHOLD must be between 0 and 1 (got %.2f)", c.FundingUtilizationThreshold)
+	}
+	if c.SizeAnomalyMultiplier < 1.0 {
+		return fmt.Errorf("SIZE_ANOMALY_MULTIPLIER must be at least 1.0 (got %.2f)", c.SizeAnomalyMultiplier)
+	}
+	if c.SizeAnomalyMinTrades < 1 {
+		return fmt.Errorf("SIZE_ANOMALY_MIN_TRADES must be at least 1 (got %d)", c.SizeAnomalyMinTrades)
+	}
+	if c.LosingRecordMaxWinRate < 0 || c.LosingRecordMaxWinRate >= 1 {
+		return fmt.Errorf("LOSING_RECORD_MAX_WIN_RATE must be between 0 and 1 (got %.2f)", c.LosingRecordMaxWinRate)
+	}
+	if c.LosingRecordMaxWinRate > 0 {
+		if c.LosingRecordMinTrades < 1 {
+			return fmt.Errorf("LOSING_RECORD_MIN_TRADES must be at least 1 (got %d)", c.LosingRecordMinTrades)
+		}
+		if c.LosingRecordFloor <= 0 || c.LosingRecordFloor > 1 {
+			return fmt.Errorf("LOSING_RECORD_FLOOR must be between 0 and 1 (got %.2f)", c.LosingRecordFloor)
+		}
+	}
+	if c.EnableHolderDominance {
+		if c.HolderDominanceMinShare <= 0 || c.HolderDominanceMinShare > 1 {
+			return fmt.Errorf("HOLDER_DOMINANCE_MIN_SHARE must be between 0 and 1 (got %.2f)", c.HolderDominanceMinShare)
+		}
+		if c.HolderDominanceMultiplier < 1 {
+			return fmt.Errorf("HOLDER_DOMINANCE_MULTIPLIER must be at least 1 (got %.2f)", c.HolderDominanceMultiplier)
+		}
+	}
+	if c.EnableExitAlerts && (c.ExitAlertFraction <= 0 || c.ExitAlertFraction > 1) {
+		return fmt.Errorf("EXIT_ALERT_FRACTION must be between 0 and 1 (got %.2f)", c.ExitAlertFraction)
+	}
+	if c.EnableExitAlerts && c.ExitScanInterval < time.Minute {
+		return fmt.Errorf("EXIT_SCAN_INTERVAL must be at least 1m (got %s)", c.ExitScanInterval)
+	}
+	if c.EnableExposureAlerts {
+		if c.ExposureAlertUSD <= 0 {
+			return fmt.Errorf("EXPOSURE_ALERT_USD must be positive (got %.2f)", c.ExposureAlertUSD)
+		}
+		if c.ExposureWindowHours <= 0 {
+			return fmt.Errorf("EXPOSURE_WINDOW_HOURS must be positive (got %d)", c.ExposureWindowHours)
+		}
+		if c.ExposureMinMarkets < 1 {
+			return fmt.Errorf("EXPOSURE_MIN_MARKETS must be at least 1 (got %d)", c.ExposureMinMarkets)
+		}
+		if c.ExposureCooldownHours < 0 {
+			return fmt.Errorf("EXPOSURE_COOLDOWN_HOURS must not be negative (got %d)", c.ExposureCooldownHours)
+		}
+	}
+	if c.EnableCopyTradeDetection {
+		if c.CopyTradeFlaggedDays <= 0 {
+			return fmt.Errorf("COPY_TRADE_FLAGGED_DAYS must be positive (got %d)", c.CopyTradeFlaggedDays)
+		}
+		if c.CopyTradeWindowMins <= 0 {
+			return fmt.Errorf("COPY_TRADE_WINDOW_MINS must be positive (got %d)", c.CopyTradeWindowMins)
+		}
+		if c.CopyTradeMultiplier < 1.0 {
+			return fmt.Errorf("COPY_TRADE_MULTIPLIER must be at least 1.0 (got %.2f)", c.CopyTradeMultiplier)
+		}
+	}
+	if c.WinRateRecalcInterval < time.Minute {
+		return fmt.Errorf("WIN_RATE_RECALC_INTERVAL must be at least 1m (got %s)", c.WinRateRecalcInterval)
+	}
+	if c.WinRateWorkers < 1 {
+		return fmt.Errorf("WIN_RATE_WORKERS must be at least 1 (got %d)", c.WinRateWorkers)
+	}
+	if c.AlertStreamMaxClients < 1 {
+		return fmt.Errorf("ALERT_STREAM_MAX_CLIENTS must be at least 1 (got %d)", c.AlertStreamMaxClients)
+	}
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("LOG_LEVEL must be debug, info, warn or error (got %q)", c.LogLevel)
+	}
+	if c.ErrorReportSampleRate < 0 || c.ErrorReportSampleRate > 1 {
+		return fmt.Errorf("ERROR_REPORT_SAMPLE_RATE must be between 0 and 1 (got %.2f)", c.ErrorReportSampleRate)
+	}
+	if c.ErrorReportMaxPerMinute < 0 {
+		return fmt.Errorf("ERROR_REPORT_MAX_PER_MINUTE must not be negative (got %d)", c.ErrorReportMaxPerMinute)
+	}
+	if c.EnablePprof && c.PprofPort != 0 && (c.PprofPort < 1 || c.PprofPort > 65535 || c.PprofPort == c.HealthPort) {
+		return fmt.Errorf("PPROF_PORT must be a port other than HEALTH_PORT, or 0 to use it (got %d)", c.PprofPort)
+	}
+	if c.IncludeMakerTrades && c.MakerScoreMultiplier <= 0 {
+		return fmt.Errorf("MAKER_SCORE_MULTIPLIER must be positive (got %.2f)", c.MakerScoreMultiplier)
+	}
+	if c.EnableClusterDetection && c.OpposingCoordinatedMultiplier < 1.0 {
+		return fmt.Errorf("OPPOSING_COORDINATED_MULTIPLIER must be at least 1.0 (got %.2f)", c.OpposingCoordinatedMultiplier)
+	}
+
+	return nil
+}
+
+// Warnings returns settings that are valid but probably mistakes, for
+// logging at startup. Unlike Validate's errors they don't stop the service.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	pollInterval := time.Duration(c.PollIntervalSec) * time.Second
+
+	if c.MinWinRateThreshold > 0 && c.MinWinRateThreshold < 0.5 {
+		warnings = append(warnings, fmt.Sprintf("MIN_WIN_RATE_THRESHOLD is %.2f: wallets winning fewer than half their trades will count as suspiciously successful", c.MinWinRateThreshold))
+	}
+	if c.PollIntervalSec < 5 {
+		warnings = append(warnings, fmt.Sprintf("POLL_INTERVAL_SEC is %d: polling this often is likely to be rate limited by the Data API", c.PollIntervalSec))
+	}
+	if c.PollCycleTimeout > pollInterval {
+		warnings = append(warnings, fmt.Sprintf("POLL_CYCLE_TIMEOUT (%s) is longer than POLL_INTERVAL_SEC (%s): ticks arriving while a slow cycle runs are skipped", c.PollCycleTimeout, pollInterval))
+	}
+	if c.ReadyMaxPollAge < 2*pollInterval {
+		warnings = append(warnings, fmt.Sprintf("READY_MAX_POLL_AGE (%s) is under two poll intervals: /ready fails after a single slow cycle", c.ReadyMaxPollAge))
+	}
+	if c.SuspicionScoreWarn == c.SuspicionScoreAlert {
+		warnings = append(warnings, fmt.Sprintf("SUSPICION_SCORE_WARN equals SUSPICION_SCORE_ALERT (%.2f): no trade will be rated WARN", c.SuspicionScoreAlert))
+	}
+	if c.OldWalletScoreAlert < c.SuspicionScoreWarn {
+		warnings = append(warnings, fmt.Sprintf("OLD_WALLET_SCORE_ALERT (%.2f) is below SUSPICION_SCORE_WARN (%.2f): old wallets notify as readily as new ones", c.OldWalletScoreAlert, c.SuspicionScoreWarn))
+	}
+	if c.AlertCooldownMins == 0 {
+		warnings = append(warnings, "ALERT_COOLDOWN_MINS is 0: a wallet splitting an order into many trades alerts on every one")
+	}
+	if c.BigTradeUSD < 1000 {
+		warnings = append(warnings, fmt.Sprintf("BIG_TRADE_USD is %.2f: fetching trades this small floods the pipeline and the alert channels", c.BigTradeUSD))
+	}
+	return warnings
+}
+
+// validateAlertModes checks a comma-separated list of alert modes, and that
+// the senders it names are configured
+func (c *Config) validateAlertModes(name, value string) error {
 	hasDiscord := false
 	hasSMTP := false
 	
-	for _, mode := range modes {
+	for _, mode := range strings.Split(value, ",") {
 		mode = strings.TrimSpace(mode)
 		switch mode {
 		case "log", "discord", "smtp":
@@ -191,30 +968,122 @@ func (c *Config) Validate() error {
 				hasSMTP = true
 			}
 		default:
-			return fmt.Errorf("invalid ALERT_MODE value: %s (valid values: log, discord, smtp)", mode)
+			return fmt.Errorf("invalid %s value: %s (valid values: log, discord, smtp)", name, mode)
 		}
 	}
 
 	if hasDiscord && len(c.DiscordWebhookURLs) == 0 {
-		return fmt.Errorf("DISCORD_WEBHOOK_URLS is required when discord is in ALERT_MODE")
+		return fmt.Errorf("DISCORD_WEBHOOK_URLS (or DISCORD_WEBHOOK_URL) is required when discord is in %s", name)
 	}
 
 	if hasSMTP && c.SMTPHost == "" {
-		return fmt.Errorf("SMTP_HOST is required when smtp is in ALERT_MODE")
+		return fmt.Errorf("SMTP_HOST is required when smtp is in %s", name)
+	}
+
+	if hasSMTP && len(c.SMTPTo) == 0 {
+		return fmt.Errorf("SMTP_TO needs at least one recipient when smtp is in %s", name)
 	}
 
 	return nil
 }
 
+// validateBuckets checks a histogram bucket override: nil (unset), or a
+// non-empty list of strictly increasing upper bounds
+func validateBuckets(name string, buckets []float64) error {
+	if buckets == nil {
+		return nil
+	}
+	if len(buckets) == 0 {
+		return fmt.Errorf("%s must list at least one bucket", name)
+	}
+	for i := 1; i < len(buckets); i++ {
+		if buckets[i] <= buckets[i-1] {
+			return fmt.Errorf("%s must be strictly increasing: %v is not above %v (bucket %d)", name, buckets[i], buckets[i-1], i+1)
+		}
+	}
+	return nil
+}
+
+// DailySummaryLocation returns the time zone of DAILY_SUMMARY_TIME, or UTC
+// if it doesn't load
+func (c *Config) DailySummaryLocation() *time.Location {
+	loc, err := time.LoadLocation(c.DailySummaryTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// DisplayLocation returns the time zone timestamps are shown in, or UTC if
+// it doesn't load
+func (c *Config) DisplayLocation() *time.Location {
+	loc, err := time.LoadLocation(c.DisplayTimezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// MinLiquidityFor returns the minimum market liquidity for a category,
+// falling back to MinMarketLiquidityUSD when it has no override
+func (c *Config) MinLiquidityFor(category string) float64 {
+	if minimum, ok := c.MinMarketLiquidityByCategory[strings.ToLower(strings.TrimSpace(category))]; ok {
+		return minimum
+	}
+	return c.MinMarketLiquidityUSD
+}
+
+// validateProxyURL checks an optional proxy URL has a supported scheme and
+// a host
+func validateProxyURL(raw string) error {
+	if raw == "" {
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("is not a valid URL: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("must use http, https, or socks5 (got %q)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host (got %q)", raw)
+	}
+	return nil
+}
+
+// getSecret reads a secret from its environment variable or _FILE, then
+// CONFIG_FILE, resolving provider references like vault:path#key. A secret
+// that can't be read fails the Load.
+func getSecret(key, defaultValue string) string {
+	value, err := secrets.GetSecret(key, "")
+	if err != nil {
+		loading.fail(fmt.Errorf("%s: %w", key, err))
+		return defaultValue
+	}
+	if value != "" {
+		loading.record(key, SourceEnv)
+		return value
+	}
+	value, err = secrets.Resolve(getEnv(key, defaultValue))
+	if err != nil {
+		loading.fail(fmt.Errorf("%s: %w", key, err))
+		return defaultValue
+	}
+	return value
+}
+
 func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+	if value := loading.lookup(key); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
 func getEnvBool(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
+	if value := loading.lookup(key); value != "" {
 		if boolVal, err := strconv.ParseBool(value); err == nil {
 			return boolVal
 		}
@@ -223,7 +1092,7 @@ func getEnvBool(key string, defaultValue bool) bool {
 }
 
 func getEnvInt(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
+	if value := loading.lookup(key); value != "" {
 		if intVal, err := strconv.Atoi(value); err == nil {
 			return intVal
 		}
@@ -231,8 +1100,17 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := loading.lookup(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultValue
+}
+
 func getEnvFloat(key string, defaultValue float64) float64 {
-	if value := os.Getenv(key); value != "" {
+	if value := loading.lookup(key); value != "" {
 		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
 			return floatVal
 		}
@@ -240,6 +1118,45 @@ func getEnvFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+// parseRecipients splits SMTP_TO on commas outside quoted display names,
+// drops empty entries and repeats. Addresses compare case-insensitively, so
+// "Alerts <a@example.com>" and "A@example.com" are one recipient and the
+// first form is kept. Entries that don't parse are kept for Validate to
+// report.
+func parseRecipients(s string) []string {
+	var entries []string
+	start, quoted := 0, false
+	for i, char := range s {
+		switch {
+		case char == '"':
+			quoted = !quoted
+		case char == ',' && !quoted:
+			entries = append(entries, s[start:i])
+			start = i + 1
+		}
+	}
+	entries = append(entries, s[start:])
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, r := range entries {
+		r = strings.TrimSpace(r)
+		if r == "" {
+			continue
+		}
+		key := r
+		if addr, err := mail.ParseAddress(r); err == nil {
+			key = strings.ToLower(addr.Address)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, r)
+	}
+	return result
+}
+
 func parseCSV(s string) []string {
 	var result []string
 	for _, item := range splitCSV(s) {
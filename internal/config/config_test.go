@@ -0,0 +1,809 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/secrets"
+)
+
+func TestLoadDiscordWebhookURLs(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "discord_webhooks")
+	if err := os.WriteFile(secretFile, []byte("https://discord.test/a, https://discord.test/b\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    []string
+		description string
+	}{
+		{
+			name:        "comma-separated list",
+			env:         map[string]string{"DISCORD_WEBHOOK_URLS": "https://discord.test/a,https://discord.test/b"},
+			expected:    []string{"https://discord.test/a", "https://discord.test/b"},
+			description: "Plural env var is split on commas",
+		},
+		{
+			name:        "whitespace and trailing comma",
+			env:         map[string]string{"DISCORD_WEBHOOK_URLS": " https://discord.test/a , https://discord.test/b ,"},
+			expected:    []string{"https://discord.test/a", "https://discord.test/b"},
+			description: "Entries are trimmed and empty entries dropped",
+		},
+		{
+			name:        "singular fallback",
+			env:         map[string]string{"DISCORD_WEBHOOK_URL": "https://discord.test/legacy"},
+			expected:    []string{"https://discord.test/legacy"},
+			description: "Singular DISCORD_WEBHOOK_URL still works",
+		},
+		{
+			name: "plural takes precedence",
+			env: map[string]string{
+				"DISCORD_WEBHOOK_URLS": "https://discord.test/a",
+				"DISCORD_WEBHOOK_URL":  "https://discord.test/legacy",
+			},
+			expected:    []string{"https://discord.test/a"},
+			description: "Singular value is ignored when the list is set",
+		},
+		{
+			name:        "file-based secret",
+			env:         map[string]string{"DISCORD_WEBHOOK_URLS_FILE": secretFile},
+			expected:    []string{"https://discord.test/a", "https://discord.test/b"},
+			description: "List can be loaded through the _FILE mechanism",
+		},
+		{
+			name:        "not configured",
+			env:         map[string]string{},
+			expected:    nil,
+			description: "No webhook configured leaves the list empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DISCORD_WEBHOOK_URLS", "DISCORD_WEBHOOK_URLS_FILE", "DISCORD_WEBHOOK_URL", "DISCORD_WEBHOOK_URL_FILE", "ALERT_MODE"} {
+				t.Setenv(key, "")
+			}
+			t.Setenv("API_AUTH_TOKEN", "secret")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("Load() error: %v", err)
+			}
+			if !reflect.DeepEqual(cfg.DiscordWebhookURLs, tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", cfg.DiscordWebhookURLs, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadSecretFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write secret file: %v", err)
+		}
+		return path
+	}
+	dsnFile := write("dsn", "app:s3cret@tcp(db:3306)/watch?parseTime=true\n")
+	passwordFile := write("password", "p@ss/word\n")
+	smtpUserFile := write("smtp_user", "mailer\n")
+	smtpPasswordFile := write("smtp_password", "hunter2\n")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		check       func(cfg *Config) bool
+		wantErr     string // Empty when Load should succeed
+		description string
+	}{
+		{
+			"default dsn", nil,
+			func(cfg *Config) bool {
+				return cfg.DatabaseDSN == "insiderwatch:insiderwatch@tcp(mysql:3306)/insiderwatch?parseTime=true"
+			},
+			"", "The parts' defaults give the long-standing default DSN",
+		},
+		{
+			"dsn file", map[string]string{"DATABASE_DSN_FILE": dsnFile},
+			func(cfg *Config) bool { return cfg.DatabaseDSN == "app:s3cret@tcp(db:3306)/watch?parseTime=true" },
+			"", "The whole DSN can be a secret file",
+		},
+		{
+			"dsn parts", map[string]string{"DATABASE_PASSWORD_FILE": passwordFile, "DATABASE_HOST": "db.internal:3307", "DATABASE_USER": "app"},
+			func(cfg *Config) bool {
+				return cfg.DatabaseDSN == "app:p@ss/word@tcp(db.internal:3307)/insiderwatch?parseTime=true"
+			},
+			"", "Only the password needs to be a secret when the DSN is assembled",
+		},
+		{
+			"smtp files", map[string]string{"SMTP_USER_FILE": smtpUserFile, "SMTP_PASSWORD_FILE": smtpPasswordFile},
+			func(cfg *Config) bool { return cfg.SMTPUser == "mailer" && cfg.SMTPPassword == "hunter2" },
+			"", "SMTP credentials are read from files",
+		},
+		{
+			"missing file", map[string]string{"DATABASE_PASSWORD_FILE": filepath.Join(dir, "missing")},
+			nil, "DATABASE_PASSWORD: read secret file", "An unreadable secret file fails Load instead of falling back to the default",
+		},
+		{
+			"dsn and parts", map[string]string{"DATABASE_DSN_FILE": dsnFile, "DATABASE_HOST": "db.internal"},
+			nil, "set DATABASE_DSN or DATABASE_HOST", "A DSN and its parts together are ambiguous",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, key := range []string{"DATABASE_DSN", "DATABASE_DSN_FILE", "DATABASE_USER", "DATABASE_PASSWORD", "DATABASE_PASSWORD_FILE", "DATABASE_HOST", "DATABASE_NAME", "DATABASE_PARAMS", "SMTP_USER", "SMTP_PASSWORD"} {
+				t.Setenv(key, "")
+			}
+			t.Setenv("API_AUTH_TOKEN", "secret")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			cfg, err := Load()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			if !tt.check(cfg) {
+				t.Errorf("got DSN %q, SMTP user %q, unexpected values\nDescription: %s", cfg.DatabaseDSN, cfg.SMTPUser, tt.description)
+			}
+		})
+	}
+}
+
+// fakeSecrets resolves provider references from a map
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) Get(ctx context.Context, ref string) (string, error) {
+	if value, ok := f[ref]; ok {
+		return value, nil
+	}
+	return "", errors.New("backend unreachable")
+}
+
+func TestLoadSecretProviderReferences(t *testing.T) {
+	secrets.Register("vault", fakeSecrets{"secret/data/insiderwatch#discord_webhook": "https://discord.test/vault"})
+	defer secrets.Register("vault", nil)
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("alerts:\n  smtp_password: vault:secret/data/insiderwatch#discord_webhook\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DISCORD_WEBHOOK_URLS", "vault:secret/data/insiderwatch#discord_webhook")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if !reflect.DeepEqual(cfg.DiscordWebhookURLs, []string{"https://discord.test/vault"}) || cfg.SMTPPassword != "https://discord.test/vault" {
+		t.Errorf("got webhooks %v and SMTP password %q, want references in the environment and the file resolved", cfg.DiscordWebhookURLs, cfg.SMTPPassword)
+	}
+
+	t.Setenv("DISCORD_WEBHOOK_URLS", "vault:secret/data/insiderwatch#missing")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DISCORD_WEBHOOK_URLS: resolve vault:secret/data/insiderwatch#missing: backend unreachable") {
+		t.Errorf("got %v, want the provider's error naming the setting", err)
+	}
+}
+
+func TestValidateDiscordModeRequiresWebhook(t *testing.T) {
+	t.Setenv("DISCORD_WEBHOOK_URLS", "")
+	t.Setenv("DISCORD_WEBHOOK_URLS_FILE", "")
+	t.Setenv("DISCORD_WEBHOOK_URL", "")
+	t.Setenv("DISCORD_WEBHOOK_URL_FILE", "")
+	t.Setenv("ALERT_MODE", "log,discord")
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error when discord mode has no webhook URLs")
+	}
+
+	t.Setenv("DISCORD_WEBHOOK_URL", "https://discord.test/legacy")
+	if _, err := Load(); err != nil {
+		t.Fatalf("singular webhook should satisfy discord mode: %v", err)
+	}
+}
+
+func TestValidateServeRequiresAPIAuthToken(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "")
+	t.Setenv("API_AUTH_TOKEN_FILE", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error\nDescription: Commands without an HTTP server run without a token", err)
+	}
+	if err := cfg.ValidateServe(); err == nil || !strings.Contains(err.Error(), "API_AUTH_TOKEN") {
+		t.Fatalf("got %v, want an error naming API_AUTH_TOKEN", err)
+	}
+
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if err := cfg.ValidateServe(); err != nil {
+		t.Errorf("got %v, want no error", err)
+	}
+	if cfg.APIAuthToken != "secret" {
+		t.Errorf("got %q, want %q", cfg.APIAuthToken, "secret")
+	}
+}
+
+func TestValidateDailySummary(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	t.Setenv("ALERT_MODE", "log")
+	t.Setenv("SMTP_HOST", "")
+
+	tests := []struct {
+		name        string
+		time        string
+		timezone    string
+		channels    string
+		wantErr     bool
+		description string
+	}{
+		{"disabled", "", "Nowhere/Special", "bogus", false, "Nothing is checked while the summary is off"},
+		{"valid", "18:30", "Europe/London", "", false, "A time of day in a known zone is accepted"},
+		{"bad time", "6pm", "UTC", "", true, "The time must be HH:MM"},
+		{"bad timezone", "18:00", "Nowhere/Special", "", true, "The time zone must load"},
+		{"unconfigured channel", "18:00", "UTC", "smtp", true, "Channels need their sender configured, like ALERT_MODE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DAILY_SUMMARY_TIME", tt.time)
+			t.Setenv("DAILY_SUMMARY_TIMEZONE", tt.timezone)
+			t.Setenv("DAILY_SUMMARY_CHANNELS", tt.channels) // Empty falls back to ALERT_MODE
+
+			_, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got %v, want error %v\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadMetricBuckets(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    []float64
+		wantErr     bool
+		description string
+	}{
+		{"unset", "", nil, false, "Unset keeps the built-in buckets"},
+		{"override", "[0.005, 0.01, 0.05, 0.1]", []float64{0.005, 0.01, 0.05, 0.1}, false, "A JSON array replaces the buckets"},
+		{"not increasing", "[1, 10, 5]", nil, true, "Buckets must be strictly increasing"},
+		{"duplicate", "[1, 1, 2]", nil, true, "Repeated bounds are rejected"},
+		{"empty", "[]", nil, true, "An empty list is rejected rather than leaving the histogram without buckets"},
+		{"not json", "1,2,3", nil, true, "The value must be a JSON array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("METRIC_TRADE_DURATION_BUCKETS", tt.value)
+
+			cfg, err := Load()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %v\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if err != nil {
+				if !strings.Contains(err.Error(), "METRIC_TRADE_DURATION_BUCKETS") {
+					t.Errorf("got %v, want an error naming METRIC_TRADE_DURATION_BUCKETS\nDescription: %s", err, tt.description)
+				}
+				return
+			}
+			if !reflect.DeepEqual(cfg.MetricTradeDurationBuckets, tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", cfg.MetricTradeDurationBuckets, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestMinLiquidityFor(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	t.Setenv("MIN_MARKET_LIQUIDITY_USD", "10000")
+	t.Setenv("MIN_MARKET_LIQUIDITY_BY_CATEGORY", `{"Politics": 50000, "science": 1000}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		category    string
+		expected    float64
+		description string
+	}{
+		{"Politics", 50000, "Category override applies"},
+		{" SCIENCE ", 1000, "Categories match ignoring case and whitespace"},
+		{"Crypto", 10000, "Categories without an override use the global minimum"},
+		{"", 10000, "Unknown category uses the global minimum"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.category, func(t *testing.T) {
+			if got := cfg.MinLiquidityFor(tt.category); got != tt.expected {
+				t.Errorf("got %.0f, want %.0f\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadMaxMarketHorizonDays(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    int
+		description string
+	}{
+		{"default", "", 60, "Markets up to 60 days out are scored by default"},
+		{"override", "120", 120, "MAX_MARKET_HORIZON_DAYS sets the horizon"},
+		{"not a number", "two months", 60, "An unparseable value keeps the default like other integers"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("MAX_MARKET_HORIZON_DAYS", tt.value)
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if cfg.MaxMarketHorizonDays != tt.expected {
+				t.Errorf("got %d, want %d\nDescription: %s", cfg.MaxMarketHorizonDays, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	err := os.WriteFile(path, []byte(`
+environment: staging
+database:
+  database_max_conns: 10
+detection:
+  big_trade_usd: 25000
+  min_win_rate_threshold: 0.8
+  min_market_liquidity_by_category:
+    Politics: 5000
+apis:
+  data_api_extra_headers:
+    X-Team: insiderwatch
+alerts:
+  alert_mode: discord
+  discord_webhook_urls: $SECRET:PROD_WEBHOOK
+  smtp_to: [a@example.com, b@example.com]
+server:
+  api_auth_token: secret
+  metric_score_buckets: [10, 100, 1000]
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("PROD_WEBHOOK", "https://discord.test/prod")
+	t.Setenv("BIG_TRADE_USD", "50000")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	if cfg.Environment != "staging" || cfg.DatabaseMaxConns != 10 || cfg.MinWinRateThreshold != 0.8 {
+		t.Errorf("got environment %q, max conns %d, win rate %v, want the file's values", cfg.Environment, cfg.DatabaseMaxConns, cfg.MinWinRateThreshold)
+	}
+	if cfg.BigTradeUSD != 50000 {
+		t.Errorf("got big trade %v, want 50000: the environment overrides the file", cfg.BigTradeUSD)
+	}
+	if !reflect.DeepEqual(cfg.DiscordWebhookURLs, []string{"https://discord.test/prod"}) {
+		t.Errorf("got webhooks %v, want the $SECRET: reference resolved", cfg.DiscordWebhookURLs)
+	}
+	if !reflect.DeepEqual(cfg.SMTPTo, []string{"a@example.com", "b@example.com"}) || !reflect.DeepEqual(cfg.MetricScoreBuckets, []float64{10, 100, 1000}) {
+		t.Errorf("got SMTP_TO %v and buckets %v, want lists as comma-separated values and JSON arrays", cfg.SMTPTo, cfg.MetricScoreBuckets)
+	}
+	if cfg.MinLiquidityFor("politics") != 5000 || cfg.DataAPIExtraHeaders["X-Team"] != "insiderwatch" {
+		t.Errorf("got liquidity %v and headers %v, want maps as JSON objects", cfg.MinLiquidityFor("politics"), cfg.DataAPIExtraHeaders)
+	}
+
+	sources := map[string]string{
+		"BIG_TRADE_USD":        SourceEnv,
+		"DATABASE_MAX_CONNS":   SourceFile,
+		"DISCORD_WEBHOOK_URLS": SourceSecret,
+		"POLL_INTERVAL_SEC":    SourceDefault,
+	}
+	for key, want := range sources {
+		if got := cfg.Sources[key]; got != want {
+			t.Errorf("got source %q for %s, want %q", got, key, want)
+		}
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		content     string
+		wantErr     string
+		description string
+	}{
+		{"unknown setting", "detection:\n  big_trade_usdd: 1\n", "unknown settings big_trade_usdd", "Misspelt settings are reported rather than ignored"},
+		{"unknown section", "alerting:\n  alert_mode: log\n", `unknown section "alerting"`, "Only the known sections are accepted"},
+		{"duplicate", "alerts:\n  alert_mode: log\nserver:\n  alert_mode: smtp\n", "set more than once", "A setting may appear in one place only"},
+		{"missing secret", "alerts:\n  discord_webhook_urls: $SECRET:NOT_SET_ANYWHERE\n", "NOT_SET_ANYWHERE, which is not set", "A $SECRET: reference must resolve"},
+		{"bad yaml", "detection: [", "parse CONFIG_FILE", "The file must be valid YAML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("CONFIG_FILE", path)
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadDetection(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+detection:
+  flash_funding_rule:
+    window_minutes: 10
+  liquidity_rule:
+    tiers:
+      - {min_ratio: 0.25, multiplier: 2.5}
+  velocity_rule:
+    multiplier: 1.8
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("VELOCITY_RULE", `{"tiers": [{"min_trades": 4, "multiplier": 2.2}]}`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	d, defaults := cfg.Detection, DefaultDetection()
+	if d.FlashFunding.WindowMinutes != 10 || d.FlashFunding.Multiplier != defaults.FlashFunding.Multiplier {
+		t.Errorf("got flash funding %+v, want the file's window and the default multiplier", d.FlashFunding)
+	}
+	if !reflect.DeepEqual(d.Liquidity.Tiers, []LiquidityTier{{MinRatio: 0.25, Multiplier: 2.5}}) || d.Liquidity.MinRatio != defaults.Liquidity.MinRatio {
+		t.Errorf("got liquidity %+v, want the file's tiers replacing the defaults and the default min_ratio", d.Liquidity)
+	}
+	if d.Velocity.Multiplier != defaults.Velocity.Multiplier || !reflect.DeepEqual(d.Velocity.Tiers, []VelocityTier{{MinTrades: 4, Multiplier: 2.2}}) {
+		t.Errorf("got velocity %+v, want the environment's block replacing the file's", d.Velocity)
+	}
+	if !reflect.DeepEqual(d.PriceConfidence, defaults.PriceConfidence) {
+		t.Errorf("got price confidence %+v, want the defaults for blocks set nowhere", d.PriceConfidence)
+	}
+	if cfg.Sources["VELOCITY_RULE"] != SourceEnv || cfg.Sources["LIQUIDITY_RULE"] != SourceFile {
+		t.Errorf("got sources %q and %q, want env and file", cfg.Sources["VELOCITY_RULE"], cfg.Sources["LIQUIDITY_RULE"])
+	}
+}
+
+func TestDetectionMultipliers(t *testing.T) {
+	d := DefaultDetection()
+
+	liquidity := []struct {
+		ratio    float64
+		expected float64
+	}{
+		{0.05, 1.0}, {0.06, 1.2}, {0.10, 1.5}, {0.19, 1.5}, {0.20, 2.0}, {0.50, 3.0}, {2.0, 3.0},
+	}
+	for _, tt := range liquidity {
+		if got := d.Liquidity.LiquidityMultiplier(tt.ratio); got != tt.expected {
+			t.Errorf("got %.2fx for liquidity ratio %.2f, want %.2fx", got, tt.ratio, tt.expected)
+		}
+	}
+
+	velocity := []struct {
+		count    int
+		expected float64
+	}{
+		{3, 1.5}, {4, 1.5}, {5, 2.0}, {9, 2.0}, {10, 3.0}, {50, 3.0},
+	}
+	for _, tt := range velocity {
+		if got := d.Velocity.VelocityMultiplier(tt.count); got != tt.expected {
+			t.Errorf("got %.2fx for %d trades, want %.2fx", got, tt.count, tt.expected)
+		}
+	}
+}
+
+func TestDisabledDetectors(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		expected    []string
+		description string
+	}{
+		{"defaults", nil, nil, "Every detector is on by default"},
+		{"extreme price", map[string]string{"ENABLE_PRICE_CONFIDENCE_DETECTION": "false"}, []string{"price_confidence"}, "Each detector has its own flag"},
+		{"coordinated", map[string]string{"ENABLE_COORDINATED_DETECTION": "false"}, []string{"coordinated"}, "Coordination can be off while clustering stays on"},
+		{"cluster", map[string]string{"ENABLE_CLUSTER_DETECTION": "false"}, []string{"cluster", "coordinated"}, "Coordinated trades are found through clusters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if got := cfg.DisabledDetectors(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestDiffAndReload(t *testing.T) {
+	current := &Config{SuspicionScoreAlert: 85, HealthPort: 8080, LogLevel: "info", MinMarketLiquidityByCategory: map[string]float64{"politics": 1000}}
+	next := &Config{SuspicionScoreAlert: 90, HealthPort: 9090, LogLevel: "debug", MinMarketLiquidityByCategory: map[string]float64{"politics": 1000}, Sources: map[string]string{"HEALTH_PORT": SourceEnv}}
+
+	reloaded, restart := current.Diff(next)
+	if !reflect.DeepEqual(reloaded, []string{"SuspicionScoreAlert", "LogLevel"}) || !reflect.DeepEqual(restart, []string{"HealthPort"}) {
+		t.Errorf("got reloaded %v and restart %v, want the threshold and log level reloaded and the port needing a restart", reloaded, restart)
+	}
+
+	merged := current.Reload(next)
+	if merged.SuspicionScoreAlert != 90 || merged.LogLevel != "debug" || merged.HealthPort != 8080 {
+		t.Errorf("got %+v, want the reloadable fields from next and the running port", merged)
+	}
+	if current.SuspicionScoreAlert != 85 {
+		t.Errorf("got the running config modified, want a copy")
+	}
+}
+
+func TestValidateRanges(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantErr     string // Empty when the config is valid
+		description string
+	}{
+		{"defaults", nil, "", "The defaults are valid"},
+		{"zero poll interval", map[string]string{"POLL_INTERVAL_SEC": "0"}, "POLL_INTERVAL_SEC must be positive", "A zero interval would panic the poll ticker"},
+		{"poll jitter", map[string]string{"POLL_JITTER_PCT": "60"}, "POLL_JITTER_PCT must be between 0 and 50", "Jitter can't shrink an interval to nothing"},
+		{"negative splay", map[string]string{"POLL_START_SPLAY": "-1s"}, "POLL_START_SPLAY must not be negative", "The startup delay can't be negative"},
+		{"no workers", map[string]string{"WALLET_LOOKUP_WORKERS": "-1"}, "WALLET_LOOKUP_WORKERS must be at least 1", "An empty worker pool deadlocks every trade"},
+		{"no db conns", map[string]string{"DATABASE_MAX_CONNS": "0"}, "DATABASE_MAX_CONNS must be at least 1", "The database needs a connection"},
+		{"negative idle time", map[string]string{"DATABASE_MAX_IDLE_TIME_MINS": "-5"}, "DATABASE_MAX_IDLE_TIME_MINS must not be negative", "Idle times can't be negative"},
+		{"health port", map[string]string{"HEALTH_PORT": "70000"}, "HEALTH_PORT must be a port", "Ports must be in range"},
+		{"smtp port", map[string]string{"SMTP_HOST": "smtp.test", "SMTP_PORT": "0"}, "SMTP_PORT must be a port", "The SMTP port is checked when SMTP is configured"},
+		{"smtp port unused", map[string]string{"SMTP_PORT": "0"}, "", "The SMTP port is ignored without SMTP_HOST"},
+		{"smtp recipient typo", map[string]string{"SMTP_TO": "alerts@example.com, oncall.example.com"}, `SMTP_TO entry "oncall.example.com" is not an email address`, "Recipients are checked at load, not at the first send"},
+		{"smtp no recipients", map[string]string{"ALERT_MODE": "smtp", "SMTP_HOST": "smtp.test", "SMTP_TO": " , "}, "SMTP_TO needs at least one recipient", "Enabled smtp mode needs somewhere to send"},
+		{"smtp bad from", map[string]string{"SMTP_HOST": "smtp.test", "SMTP_FROM": "insiderwatch"}, "SMTP_FROM", "The sender address is checked when SMTP is configured"},
+		{"zero rps", map[string]string{"GAMMA_API_MARKETS_RPS": "-1"}, "GAMMA_API_MARKETS_RPS must be positive", "Rate limits must allow requests"},
+		{"zero big trade", map[string]string{"BIG_TRADE_USD": "0"}, "BIG_TRADE_USD must be positive", "The fetch filter must be positive"},
+		{"min above big", map[string]string{"MIN_TRADE_USD": "20000", "BIG_TRADE_USD": "10000"}, "MIN_TRADE_USD must be between 0 and BIG_TRADE_USD", "A post-filter above the fetch filter drops every trade"},
+		{"negative new wallet days", map[string]string{"NEW_WALLET_DAYS_MAX": "-1"}, "NEW_WALLET_DAYS_MAX must not be negative", "Wallet ages can't be negative"},
+		{"warn above 100", map[string]string{"SUSPICION_SCORE_WARN": "150"}, "SUSPICION_SCORE_WARN must be between 0 and 100", "Scores are normalized to 0-100"},
+		{"alert above 100", map[string]string{"SUSPICION_SCORE_ALERT": "101"}, "SUSPICION_SCORE_ALERT must be between 0 and 100", "Scores are normalized to 0-100"},
+		{"warn above alert", map[string]string{"SUSPICION_SCORE_WARN": "90", "SUSPICION_SCORE_ALERT": "80"}, "must not be above SUSPICION_SCORE_ALERT", "WARN must not outrank ALERT"},
+		{"win rate percent", map[string]string{"MIN_WIN_RATE_THRESHOLD": "75"}, "like 0.75 for 75%", "A percentage instead of a fraction never triggers"},
+		{"net position window", map[string]string{"NET_POSITION_WINDOW_HRS": "0"}, "NET_POSITION_WINDOW_HRS must be positive", "Windows are divided by their length"},
+		{"negative cooldown", map[string]string{"ALERT_COOLDOWN_MINS": "-1"}, "ALERT_COOLDOWN_MINS must not be negative", "Cooldowns can't be negative"},
+		{"time to close", map[string]string{"TIME_TO_CLOSE_HOURS_MAX": "0"}, "TIME_TO_CLOSE_HOURS_MAX must be positive", "The time to close window must be positive"},
+		{"zero market horizon", map[string]string{"MAX_MARKET_HORIZON_DAYS": "0"}, "MAX_MARKET_HORIZON_DAYS must be positive", "A zero horizon would skip every market"},
+		{"negative market horizon", map[string]string{"MAX_MARKET_HORIZON_DAYS": "-30"}, "MAX_MARKET_HORIZON_DAYS must be positive", "The horizon can't be negative"},
+		{"negative category liquidity", map[string]string{"MIN_MARKET_LIQUIDITY_BY_CATEGORY": `{"politics": -1}`}, "MIN_MARKET_LIQUIDITY_BY_CATEGORY[politics] must not be negative", "Per-category minimums are checked like the global one"},
+		{"funding multiplier", map[string]string{"FUNDING_UTILIZATION_MULTIPLIER": "0.5"}, "FUNDING_UTILIZATION_MULTIPLIER must be at least 1.0", "Multipliers must not lower scores"},
+		{"zero funding multiplier", map[string]string{"FUNDING_UTILIZATION_MULTIPLIER": "0"}, "FUNDING_UTILIZATION_MULTIPLIER must be at least 1.0", "A zero multiplier would zero every flagged first trade's score"},
+		{"display timezone", map[string]string{"DISPLAY_TIMEZONE": "America/New_York"}, "", "IANA zones are accepted"},
+		{"bad display timezone", map[string]string{"DISPLAY_TIMEZONE": "Eastern"}, "invalid DISPLAY_TIMEZONE", "The display zone must load"},
+		{"rule block", map[string]string{"LIQUIDITY_RULE": `{"min_ratio": 0.02}`}, "", "A partial block keeps the other defaults"},
+		{"rule block typo", map[string]string{"FLASH_FUNDING_RULE": `{"window_mins": 10}`}, "invalid FLASH_FUNDING_RULE JSON", "Unknown fields are rejected rather than ignored"},
+		{"tiers out of order", map[string]string{"LIQUIDITY_RULE": `{"tiers": [{"min_ratio": 0.5, "multiplier": 3}, {"min_ratio": 0.2, "multiplier": 2}]}`}, "LIQUIDITY_RULE tier 2 min_ratio must be above 0.50", "Tiers must ascend"},
+		{"tier below base", map[string]string{"LIQUIDITY_RULE": `{"tiers": [{"min_ratio": 0.01, "multiplier": 1.5}]}`}, "LIQUIDITY_RULE tier 1 min_ratio must be above 0.05", "Tiers start above min_ratio"},
+		{"tier multiplier drops", map[string]string{"VELOCITY_RULE": `{"tiers": [{"min_trades": 5, "multiplier": 2}, {"min_trades": 10, "multiplier": 1.8}]}`}, "VELOCITY_RULE tier 2 multiplier must be at least 2.00", "More trades must not lower the multiplier"},
+		{"rule multiplier", map[string]string{"FIRST_TRADE_RULE": `{"multiplier": 0.5}`}, "FIRST_TRADE_RULE multiplier must not lower scores", "Rule multipliers must be boosts"},
+		{"price bounds", map[string]string{"PRICE_CONFIDENCE_RULE": `{"low": 0.9, "high": 0.1}`}, "PRICE_CONFIDENCE_RULE needs 0 <= low < high <= 1", "The confident price band must be ordered"},
+		{"prewarm interval", map[string]string{"ENABLE_MARKET_PREWARM": "true", "MARKET_PREWARM_INTERVAL": "10s"}, "MARKET_PREWARM_INTERVAL must be at least 1m", "Pre-warming every few seconds would crowd out trade lookups"},
+		{"prewarm page size", map[string]string{"ENABLE_MARKET_PREWARM": "true", "MARKET_PREWARM_PAGE_SIZE": "1000"}, "MARKET_PREWARM_PAGE_SIZE must be between 1 and 500", "Pages are bounded to keep responses small"},
+		{"prewarm unused", map[string]string{"MARKET_PREWARM_PAGES": "0"}, "", "Pre-warm settings are ignored while it is disabled"},
+		{"exit scan interval", map[string]string{"EXIT_SCAN_INTERVAL": "10s"}, "EXIT_SCAN_INTERVAL must be at least 1m", "Each scan fetches every open alerted position's trades"},
+		{"exit scan unused", map[string]string{"ENABLE_EXIT_ALERTS": "false", "EXIT_SCAN_INTERVAL": "10s"}, "", "The scan interval is ignored while exit alerts are disabled"},
+		{"exposure threshold", map[string]string{"ENABLE_EXPOSURE_ALERTS": "true", "EXPOSURE_ALERT_USD": "0"}, "EXPOSURE_ALERT_USD must be positive", "A zero threshold would alert on every new wallet's first buy"},
+		{"exposure window", map[string]string{"ENABLE_EXPOSURE_ALERTS": "true", "EXPOSURE_WINDOW_HOURS": "0"}, "EXPOSURE_WINDOW_HOURS must be positive", "Buys are summed over a window that must have a length"},
+		{"exposure markets", map[string]string{"ENABLE_EXPOSURE_ALERTS": "true", "EXPOSURE_MIN_MARKETS": "0"}, "EXPOSURE_MIN_MARKETS must be at least 1", "An alert lists at least one market"},
+		{"exposure unused", map[string]string{"EXPOSURE_ALERT_USD": "0"}, "", "Exposure settings are ignored while the alerts are disabled"},
+		{"rate limits", map[string]string{"ALERT_RATE_LIMITS": `{"discord": 20, "smtp": 5}`, "ALERT_RATE_LIMIT_EXEMPT": "none"}, "", "Channels are capped independently and exemptions can be turned off"},
+		{"rate limit channel", map[string]string{"ALERT_RATE_LIMITS": `{"slack": 20}`}, "invalid ALERT_RATE_LIMITS channel: slack", "Only channels the service sends to can be capped"},
+		{"rate limit zero", map[string]string{"ALERT_RATE_LIMITS": `{"discord": 0}`}, "ALERT_RATE_LIMITS[discord] must be at least 1 alert per minute", "A zero cap would hold back every alert"},
+		{"overflow interval", map[string]string{"ALERT_RATE_LIMITS": `{"discord": 20}`, "ALERT_OVERFLOW_SUMMARY_INTERVAL": "10s"}, "ALERT_OVERFLOW_SUMMARY_INTERVAL must be at least 1m", "Summaries that frequent would be a burst of their own"},
+		{"rate limit exempt", map[string]string{"ALERT_RATE_LIMITS": `{"discord": 20}`, "ALERT_RATE_LIMIT_EXEMPT": "ALERT,CRITICAL"}, "invalid ALERT_RATE_LIMIT_EXEMPT severity: CRITICAL", "Exemptions name alert severities"},
+		{"profile", map[string]string{"PROFILES": `[{"name": "crypto-desk", "categories": ["Crypto"], "suspicion_score_alert": 80}]`}, "", "A profile may set only what it changes"},
+		{"profile name", map[string]string{"PROFILES": `[{"name": "Crypto Desk"}]`}, `PROFILES name "Crypto Desk" must be lower-case`, "Names become metric labels"},
+		{"profile duplicate", map[string]string{"PROFILES": `[{"name": "a"}, {"name": "a"}]`}, `PROFILES name "a" is used more than once`, "Alerts and cooldowns are keyed by profile name"},
+		{"profile category", map[string]string{"PROFILES": `[{"name": "a", "categories": ["sports"]}]`}, `PROFILES a category "sports" is not one of`, "Sports markets never reach scoring"},
+		{"profile typo", map[string]string{"PROFILES": `[{"name": "a", "min_trade": 1000}]`}, "invalid PROFILES JSON", "Unknown fields are rejected rather than ignored"},
+		{"profile thresholds", map[string]string{"PROFILES": `[{"name": "a", "suspicion_score_warn": 90}]`}, "PROFILES a suspicion_score_warn (90.00) must not exceed suspicion_score_alert (85.00)", "Inherited thresholds are checked against the profile's own"},
+		{"profile route", map[string]string{"PROFILES": `[{"name": "a", "alert_mode": "discord"}]`}, "DISCORD_WEBHOOK_URLS (or DISCORD_WEBHOOK_URL) is required when discord is in PROFILES a alert_mode", "A profile's route must be configured"},
+		{"profile smtp", map[string]string{"PROFILES": `[{"name": "a", "smtp_to": ["desk.example.com"]}]`}, `PROFILES a smtp_to entry "desk.example.com" is not an email address`, "Profile recipients are checked like SMTP_TO"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error\nDescription: %s", err, tt.description)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+		})
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	t.Setenv("DESK_WEBHOOK", "https://discord.test/desk")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(`
+alerts:
+  alert_mode: log
+  profiles:
+    - name: politics-desk
+      categories: [Politics]
+      min_trade_usd: 20000
+      suspicion_score_warn: 50
+      suspicion_score_alert: 70
+      new_wallet_days_max: 30
+      alert_mode: discord
+      discord_webhook_urls: [$SECRET:DESK_WEBHOOK]
+    - name: everything
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", path)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if len(cfg.Profiles) != 2 {
+		t.Fatalf("got %d profiles, want 2", len(cfg.Profiles))
+	}
+
+	desk, everything := cfg.Profiles[0], cfg.Profiles[1]
+	if !desk.Watches("politics") || desk.Watches("crypto") || !everything.Watches("crypto") {
+		t.Errorf("got categories %v and %v, want politics lower-cased and an empty list watching everything", desk.Categories, everything.Categories)
+	}
+	if got := cfg.ForProfile(desk); got.AlertMode != "discord" || !reflect.DeepEqual(got.DiscordWebhookURLs, []string{"https://discord.test/desk"}) {
+		t.Errorf("got route %q to %v, want the profile's discord webhook with its secret resolved", got.AlertMode, got.DiscordWebhookURLs)
+	}
+	if got := cfg.ForProfile(everything); got.AlertMode != "log" {
+		t.Errorf("got route %q, want a profile without one to inherit ALERT_MODE", got.AlertMode)
+	}
+	if cfg.ProfileScoreWarn(desk) != 50 || cfg.ProfileScoreAlert(desk) != 70 || cfg.ProfileNewWalletDaysMax(desk) != 30 {
+		t.Errorf("got thresholds %.0f/%.0f and %d days, want the profile's own", cfg.ProfileScoreWarn(desk), cfg.ProfileScoreAlert(desk), cfg.ProfileNewWalletDaysMax(desk))
+	}
+	if cfg.ProfileScoreWarn(everything) != cfg.SuspicionScoreWarn || cfg.ProfileScoreAlert(everything) != cfg.SuspicionScoreAlert || cfg.ProfileNewWalletDaysMax(everything) != cfg.NewWalletDaysMax {
+		t.Errorf("got thresholds %.0f/%.0f and %d days, want the global settings inherited", cfg.ProfileScoreWarn(everything), cfg.ProfileScoreAlert(everything), cfg.ProfileNewWalletDaysMax(everything))
+	}
+	if cfg.Sources["PROFILES"] != SourceFile {
+		t.Errorf("got source %q, want file", cfg.Sources["PROFILES"])
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+
+	tests := []struct {
+		name        string
+		env         map[string]string
+		wantWarning string // Empty when no warning is expected
+		description string
+	}{
+		{"defaults", nil, "", "The defaults raise no warnings"},
+		{"low win rate", map[string]string{"MIN_WIN_RATE_THRESHOLD": "0.3"}, "MIN_WIN_RATE_THRESHOLD is 0.30", "A win rate under half is unlikely to be meant"},
+		{"fast polling", map[string]string{"POLL_INTERVAL_SEC": "2"}, "POLL_INTERVAL_SEC is 2", "Very fast polling gets rate limited"},
+		{"long cycle", map[string]string{"POLL_CYCLE_TIMEOUT": "45s"}, "POLL_CYCLE_TIMEOUT (45s) is longer", "Cycles longer than the interval skip ticks"},
+		{"short ready age", map[string]string{"READY_MAX_POLL_AGE": "40s"}, "READY_MAX_POLL_AGE (40s)", "/ready should tolerate one slow cycle"},
+		{"equal thresholds", map[string]string{"SUSPICION_SCORE_WARN": "85"}, "no trade will be rated WARN", "Equal thresholds leave WARN unused"},
+		{"low old wallet threshold", map[string]string{"OLD_WALLET_SCORE_ALERT": "50"}, "OLD_WALLET_SCORE_ALERT (50.00)", "The old wallet gate does nothing below WARN"},
+		{"no cooldown", map[string]string{"ALERT_COOLDOWN_MINS": "0"}, "ALERT_COOLDOWN_MINS is 0", "Without a cooldown split orders alert repeatedly"},
+		{"tiny big trade", map[string]string{"BIG_TRADE_USD": "500", "MIN_TRADE_USD": "100"}, "BIG_TRADE_USD is 500.00", "Small trade filters flood the alerts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load()
+			if err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			warnings := cfg.Warnings()
+			if tt.wantWarning == "" {
+				if len(warnings) > 0 {
+					t.Errorf("got warnings %v, want none\nDescription: %s", warnings, tt.description)
+				}
+				return
+			}
+			found := false
+			for _, w := range warnings {
+				found = found || strings.Contains(w, tt.wantWarning)
+			}
+			if !found {
+				t.Errorf("got warnings %v, want one containing %q\nDescription: %s", warnings, tt.wantWarning, tt.description)
+			}
+		})
+	}
+}
+
+func TestParseRecipients(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    []string
+		description string
+	}{
+		{"single", "alerts@example.com", []string{"alerts@example.com"}, "A bare address is kept as is"},
+		{"trailing comma", "alerts@example.com, ", []string{"alerts@example.com"}, "Empty entries are dropped"},
+		{"display name", "Alerts <alerts@example.com>,oncall@example.com", []string{"Alerts <alerts@example.com>", "oncall@example.com"}, "Display-name forms are accepted"},
+		{"quoted comma", `"Ashdown, Liam" <liam@example.com>, alerts@example.com`, []string{`"Ashdown, Liam" <liam@example.com>`, "alerts@example.com"}, "Commas inside a quoted name don't split"},
+		{"duplicates", "Alerts <alerts@example.com>, ALERTS@example.com, alerts@example.com", []string{"Alerts <alerts@example.com>"}, "Repeats compare by address, case-insensitively, keeping the first form"},
+		{"invalid kept", "nope, nope", []string{"nope"}, "Entries that don't parse are kept once for Validate to report"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseRecipients(tt.value)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %q, want %q\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
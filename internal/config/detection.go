@@ -0,0 +1,280 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Detection holds the thresholds and multipliers of the built-in rules
+// that have no setting of their own. Each block is one JSON setting named
+// after the rule, like LIQUIDITY_RULE, or the same key under detection: in
+// CONFIG_FILE; fields a block leaves out keep their defaults.
+type Detection struct {
+	FirstTrade      FirstTradeRule      `json:"first_trade_rule"`
+	FlashFunding    FlashFundingRule    `json:"flash_funding_rule"`
+	FundingAge      FundingAgeRule      `json:"funding_age_rule"`
+	Liquidity       LiquidityRule       `json:"liquidity_rule"`
+	PriceConfidence PriceConfidenceRule `json:"price_confidence_rule"`
+	Concentration   ConcentrationRule   `json:"concentration_rule"`
+	Velocity        VelocityRule        `json:"velocity_rule"`
+	Coordinated     CoordinatedRule     `json:"coordinated_rule"`
+	WinRate         WinRateRule         `json:"win_rate_rule"`
+	TimeToClose     TimeToCloseRule     `json:"time_to_close_rule"`
+}
+
+// FirstTradeRule boosts a new wallet's large first trade
+type FirstTradeRule struct {
+	Multiplier float64 `json:"multiplier"`
+}
+
+// FlashFundingRule boosts wallets funded minutes before their first trade
+type FlashFundingRule struct {
+	WindowMinutes float64 `json:"window_minutes"` // Funding at most this long before the trade
+	Multiplier    float64 `json:"multiplier"`
+}
+
+// FundingAgeRule boosts wallets that trade soon after being funded, by up
+// to MaxBoost on top of 1.0x, falling linearly to 1.0x at WindowHours
+type FundingAgeRule struct {
+	WindowHours float64 `json:"window_hours"`
+	MaxBoost    float64 `json:"max_boost"`
+}
+
+// LiquidityRule boosts trades large relative to market liquidity. Trades
+// above MinRatio of liquidity get Multiplier, or the multiplier of the
+// highest tier they reach.
+type LiquidityRule struct {
+	MinRatio   float64         `json:"min_ratio"`
+	Multiplier float64         `json:"multiplier"`
+	Tiers      []LiquidityTier `json:"tiers"` // Ascending by MinRatio
+}
+
+// LiquidityTier is a liquidity ratio and the multiplier from it up
+type LiquidityTier struct {
+	MinRatio   float64 `json:"min_ratio"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// PriceConfidenceRule boosts trades at or beyond extreme prices
+type PriceConfidenceRule struct {
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// ConcentrationRule boosts wallets with more than MinShare of their market
+// volume on one side
+type ConcentrationRule struct {
+	MinShare   float64 `json:"min_share"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// VelocityRule boosts wallets making VELOCITY_THRESHOLD trades in the
+// velocity window by Multiplier, or the multiplier of the highest tier
+// they reach
+type VelocityRule struct {
+	Multiplier float64        `json:"multiplier"`
+	Tiers      []VelocityTier `json:"tiers"` // Ascending by MinTrades
+}
+
+// VelocityTier is a trade count and the multiplier from it up
+type VelocityTier struct {
+	MinTrades  int     `json:"min_trades"`
+	Multiplier float64 `json:"multiplier"`
+}
+
+// CoordinatedRule boosts cluster wallets piling into the same side;
+// opposing sides use OPPOSING_COORDINATED_MULTIPLIER
+type CoordinatedRule struct {
+	SameSideMultiplier float64 `json:"same_side_multiplier"`
+}
+
+// WinRateRule sets the sample a win rate needs before it boosts a score
+type WinRateRule struct {
+	MinResolvedTrades int `json:"min_resolved_trades"`
+}
+
+// TimeToCloseRule boosts trades close to the market's end by up to
+// MaxBoost on top of 1.0x, falling linearly to 1.0x at
+// TIME_TO_CLOSE_HOURS_MAX
+type TimeToCloseRule struct {
+	MaxBoost float64 `json:"max_boost"`
+}
+
+// DefaultDetection returns the built-in rules' long-standing defaults
+func DefaultDetection() Detection {
+	return Detection{
+		FirstTrade:   FirstTradeRule{Multiplier: 2.0},
+		FlashFunding: FlashFundingRule{WindowMinutes: 5, Multiplier: 3.0},
+		FundingAge:   FundingAgeRule{WindowHours: 24, MaxBoost: 1.5},
+		Liquidity: LiquidityRule{
+			MinRatio:   0.05,
+			Multiplier: 1.2,
+			Tiers: []LiquidityTier{
+				{MinRatio: 0.10, Multiplier: 1.5},
+				{MinRatio: 0.20, Multiplier: 2.0},
+				{MinRatio: 0.50, Multiplier: 3.0},
+			},
+		},
+		PriceConfidence: PriceConfidenceRule{Low: 0.15, High: 0.85, Multiplier: 1.5},
+		Concentration:   ConcentrationRule{MinShare: 0.90, Multiplier: 1.5},
+		Velocity: VelocityRule{
+			Multiplier: 1.5,
+			Tiers: []VelocityTier{
+				{MinTrades: 5, Multiplier: 2.0},
+				{MinTrades: 10, Multiplier: 3.0},
+			},
+		},
+		Coordinated: CoordinatedRule{SameSideMultiplier: 2.0},
+		WinRate:     WinRateRule{MinResolvedTrades: 5},
+		TimeToClose: TimeToCloseRule{MaxBoost: 4.0},
+	}
+}
+
+// loadDetection reads each rule block over its defaults
+func loadDetection() (Detection, error) {
+	d := DefaultDetection()
+	for _, block := range []struct {
+		name  string
+		value any
+	}{
+		{"FIRST_TRADE_RULE", &d.FirstTrade},
+		{"FLASH_FUNDING_RULE", &d.FlashFunding},
+		{"FUNDING_AGE_RULE", &d.FundingAge},
+		{"LIQUIDITY_RULE", &d.Liquidity},
+		{"PRICE_CONFIDENCE_RULE", &d.PriceConfidence},
+		{"CONCENTRATION_RULE", &d.Concentration},
+		{"VELOCITY_RULE", &d.Velocity},
+		{"COORDINATED_RULE", &d.Coordinated},
+		{"WIN_RATE_RULE", &d.WinRate},
+		{"TIME_TO_CLOSE_RULE", &d.TimeToClose},
+	} {
+		raw := getEnv(block.name, "")
+		if raw == "" {
+			continue
+		}
+		// Unknown fields are typos that would otherwise keep their defaults
+		dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(block.value); err != nil {
+			return Detection{}, fmt.Errorf("invalid %s JSON: %w", block.name, err)
+		}
+	}
+	return d, nil
+}
+
+// validate checks the multipliers are boosts and the tiers ascend
+func (d Detection) validate() error {
+	for _, m := range []struct {
+		name  string
+		value float64
+	}{
+		{"FIRST_TRADE_RULE multiplier", d.FirstTrade.Multiplier},
+		{"FLASH_FUNDING_RULE multiplier", d.FlashFunding.Multiplier},
+		{"LIQUIDITY_RULE multiplier", d.Liquidity.Multiplier},
+		{"PRICE_CONFIDENCE_RULE multiplier", d.PriceConfidence.Multiplier},
+		{"CONCENTRATION_RULE multiplier", d.Concentration.Multiplier},
+		{"VELOCITY_RULE multiplier", d.Velocity.Multiplier},
+		{"COORDINATED_RULE same_side_multiplier", d.Coordinated.SameSideMultiplier},
+		{"FUNDING_AGE_RULE max_boost", 1 + d.FundingAge.MaxBoost},
+		{"TIME_TO_CLOSE_RULE max_boost", 1 + d.TimeToClose.MaxBoost},
+	} {
+		if m.value < 1.0 {
+			return fmt.Errorf("%s must not lower scores (got %.2f)", m.name, m.value)
+		}
+	}
+
+	if d.FlashFunding.WindowMinutes <= 0 {
+		return fmt.Errorf("FLASH_FUNDING_RULE window_minutes must be positive (got %.2f)", d.FlashFunding.WindowMinutes)
+	}
+	if d.FundingAge.WindowHours <= 0 {
+		return fmt.Errorf("FUNDING_AGE_RULE window_hours must be positive (got %.2f)", d.FundingAge.WindowHours)
+	}
+	if d.PriceConfidence.Low < 0 || d.PriceConfidence.Low >= d.PriceConfidence.High || d.PriceConfidence.High > 1 {
+		return fmt.Errorf("PRICE_CONFIDENCE_RULE needs 0 <= low < high <= 1 (got %.2f and %.2f)", d.PriceConfidence.Low, d.PriceConfidence.High)
+	}
+	if d.Concentration.MinShare < 0 || d.Concentration.MinShare >= 1 {
+		return fmt.Errorf("CONCENTRATION_RULE min_share must be a fraction below 1 (got %.2f)", d.Concentration.MinShare)
+	}
+	if d.WinRate.MinResolvedTrades < 1 {
+		return fmt.Errorf("WIN_RATE_RULE min_resolved_trades must be at least 1 (got %d)", d.WinRate.MinResolvedTrades)
+	}
+
+	// Tiers ascend, each above the last in both threshold and multiplier
+	prevRatio, prevMult := d.Liquidity.MinRatio, d.Liquidity.Multiplier
+	for i, tier := range d.Liquidity.Tiers {
+		if tier.MinRatio <= prevRatio {
+			return fmt.Errorf("LIQUIDITY_RULE tier %d min_ratio must be above %.2f (got %.2f)", i+1, prevRatio, tier.MinRatio)
+		}
+		if tier.Multiplier < prevMult {
+			return fmt.Errorf("LIQUIDITY_RULE tier %d multiplier must be at least %.2f (got %.2f)", i+1, prevMult, tier.Multiplier)
+		}
+		prevRatio, prevMult = tier.MinRatio, tier.Multiplier
+	}
+	prevTrades, prevMult := 0, d.Velocity.Multiplier
+	for i, tier := range d.Velocity.Tiers {
+		if tier.MinTrades <= prevTrades {
+			return fmt.Errorf("VELOCITY_RULE tier %d min_trades must be above %d (got %d)", i+1, prevTrades, tier.MinTrades)
+		}
+		if tier.Multiplier < prevMult {
+			return fmt.Errorf("VELOCITY_RULE tier %d multiplier must be at least %.2f (got %.2f)", i+1, prevMult, tier.Multiplier)
+		}
+		prevTrades, prevMult = tier.MinTrades, tier.Multiplier
+	}
+	return nil
+}
+
+// LiquidityMultiplier returns the multiplier for a trade that is ratio of
+// its market's liquidity
+func (r LiquidityRule) LiquidityMultiplier(ratio float64) float64 {
+	if ratio <= r.MinRatio {
+		return 1.0
+	}
+	multiplier := r.Multiplier
+	for _, tier := range r.Tiers {
+		if ratio >= tier.MinRatio {
+			multiplier = tier.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// VelocityMultiplier returns the multiplier for count trades in the
+// velocity window, which reached VELOCITY_THRESHOLD
+func (r VelocityRule) VelocityMultiplier(count int) float64 {
+	multiplier := r.Multiplier
+	for _, tier := range r.Tiers {
+		if count >= tier.MinTrades {
+			multiplier = tier.Multiplier
+		}
+	}
+	return multiplier
+}
+
+// DisabledDetectors returns the names of the built-in rules switched off by
+// their ENABLE_*_DETECTION setting, as the rules name themselves. Turning
+// off cluster detection also turns off the coordinated trade rule.
+func (c *Config) DisabledDetectors() []string {
+	var disabled []string
+	for _, d := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"first_trade_large", c.EnableFirstTradeDetection},
+		{"flash_funding", c.EnableFlashFundingDetection},
+		{"funding_age", c.EnableFundingAgeDetection},
+		{"liquidity", c.EnableLiquidityDetection},
+		{"price_confidence", c.EnablePriceConfidenceDetection},
+		{"concentration", c.EnableConcentrationDetection},
+		{"velocity", c.EnableVelocityDetection},
+		{"cluster", c.EnableClusterDetection},
+		{"coordinated", c.EnableClusterDetection && c.EnableCoordinatedDetection},
+		{"win_rate", c.EnableWinRateDetection},
+	} {
+		if !d.enabled {
+			disabled = append(disabled, d.name)
+		}
+	}
+	return disabled
+}
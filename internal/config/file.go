@@ -0,0 +1,217 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/liamashdown/insiderwatch/internal/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// Where a setting's value came from, as reported in Config.Sources
+const (
+	SourceEnv     = "env"     // An environment variable, or its _FILE secret
+	SourceFile    = "file"    // CONFIG_FILE
+	SourceSecret  = "secret"  // A $SECRET: reference in CONFIG_FILE
+	SourceDefault = "default" // Neither was set
+)
+
+// secretPrefix marks a CONFIG_FILE value that names a secret to resolve
+// through the secrets package, like $SECRET:DISCORD_WEBHOOK_URL
+const secretPrefix = "$SECRET:"
+
+// fileSections are the top-level sections of CONFIG_FILE. They only group
+// settings for readability: a setting's key is its environment variable in
+// lower case, under whichever section fits.
+var fileSections = map[string]bool{
+	"database":  true,
+	"detection": true,
+	"alerts":    true,
+	"apis":      true,
+	"server":    true,
+}
+
+// jsonArraySettings hold JSON arrays in the environment; other lists in
+// CONFIG_FILE become comma-separated values
+var jsonArraySettings = map[string]bool{
+	"METRIC_SCORE_BUCKETS":          true,
+	"METRIC_TRADE_DURATION_BUCKETS": true,
+	"PROFILES":                      true,
+}
+
+// fileSource is a parsed CONFIG_FILE and the record of where Load took each
+// setting from
+type fileSource struct {
+	path    string
+	values  map[string]string // Environment variable -> value, as it would be set in the environment
+	secret  map[string]bool   // Settings resolved from a $SECRET: reference
+	sources map[string]string // Setting -> Source* constant, for every setting Load read
+
+	secretErr error // First secret file Load couldn't read
+}
+
+// loading is the source of the Load in progress; loadMu serializes Loads so
+// the getEnv helpers can consult it
+var (
+	loadMu  sync.Mutex
+	loading *fileSource
+)
+
+// readConfigFile parses the YAML file at path. An empty path returns a
+// source with no values, so only the environment is read.
+func readConfigFile(path string) (*fileSource, error) {
+	src := &fileSource{
+		path:    path,
+		values:  make(map[string]string),
+		secret:  make(map[string]bool),
+		sources: make(map[string]string),
+	}
+	// Secrets in the environment and the file may refer to providers
+	if err := secrets.Init(); err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return src, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CONFIG_FILE: %w", err)
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse CONFIG_FILE %s: %w", path, err)
+	}
+
+	for key, value := range doc {
+		section, isSection := value.(map[string]any)
+		if !isSection {
+			// A setting outside any section
+			if err := src.set(key, value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !fileSections[key] {
+			return nil, fmt.Errorf("CONFIG_FILE %s: unknown section %q (want one of database, detection, alerts, apis, server)", path, key)
+		}
+		for k, v := range section {
+			if err := src.set(k, v); err != nil {
+				return nil, fmt.Errorf("%w (in section %s)", err, key)
+			}
+		}
+	}
+	return src, nil
+}
+
+// set stores a setting in the form its environment variable takes
+func (s *fileSource) set(key string, value any) error {
+	name := strings.ToUpper(key)
+	if _, dup := s.values[name]; dup {
+		return fmt.Errorf("CONFIG_FILE %s: %s is set more than once", s.path, key)
+	}
+
+	var str string
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		str = v
+		if ref, ok := strings.CutPrefix(v, secretPrefix); ok {
+			resolved, err := secrets.GetSecret(ref, "")
+			if err != nil {
+				return fmt.Errorf("CONFIG_FILE %s: %s: %w", s.path, key, err)
+			}
+			if resolved == "" {
+				return fmt.Errorf("CONFIG_FILE %s: %s refers to secret %s, which is not set", s.path, key, ref)
+			}
+			str = resolved
+			s.secret[name] = true
+		}
+	case []any:
+		if jsonArraySettings[name] {
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("CONFIG_FILE %s: %s: %w", s.path, key, err)
+			}
+			str = string(raw)
+			break
+		}
+		items := make([]string, len(v))
+		for i, item := range v {
+			items[i] = fmt.Sprint(item)
+		}
+		str = strings.Join(items, ",")
+	case map[string]any:
+		// Maps stand in for the JSON objects of the environment variables
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("CONFIG_FILE %s: %s: %w", s.path, key, err)
+		}
+		str = string(raw)
+	default:
+		str = fmt.Sprint(v)
+	}
+	s.values[name] = str
+	return nil
+}
+
+// lookup returns a setting from the environment, then the file, recording
+// where it came from. An empty value counts as unset, as before.
+func (s *fileSource) lookup(key string) string {
+	if value := os.Getenv(key); value != "" {
+		s.record(key, SourceEnv)
+		return value
+	}
+	if s != nil && s.values[key] != "" {
+		if s.secret[key] {
+			s.record(key, SourceSecret)
+		} else {
+			s.record(key, SourceFile)
+		}
+		return s.values[key]
+	}
+	s.record(key, SourceDefault)
+	return ""
+}
+
+func (s *fileSource) record(key, source string) {
+	if s != nil {
+		s.sources[key] = source
+	}
+}
+
+// fail records the first error reading a secret
+func (s *fileSource) fail(err error) {
+	if s != nil && s.secretErr == nil {
+		s.secretErr = err
+	}
+}
+
+// unknown returns the file's settings Load never read, sorted, which are
+// misspelt or not settings at all
+func (s *fileSource) unknown() []string {
+	var keys []string
+	for key := range s.values {
+		if _, ok := s.sources[key]; !ok {
+			keys = append(keys, strings.ToLower(key))
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lookup returns one setting from the environment or CONFIG_FILE without
+// loading or validating the rest, for commands like healthcheck that need
+// a single value and not the secrets Load requires
+func Lookup(key string) (string, error) {
+	src, err := readConfigFile(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		return "", err
+	}
+	return src.lookup(key), nil
+}
@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/secrets"
+)
+
+// Profile is a named detection configuration bound to its own alert route.
+// Every trade is scored once, then each profile whose filters it passes
+// decides severity with its own thresholds and notifies its own channels,
+// so one instance can serve several audiences. Zero thresholds inherit the
+// global settings, as does an empty route.
+type Profile struct {
+	Name                string   `json:"name"`                 // Recorded on each alert and used as the profile metric label
+	Categories          []string `json:"categories"`           // Normalized market categories to watch; empty watches all
+	MinTradeUSD         float64  `json:"min_trade_usd"`        // Smallest trade to alert on; below MIN_TRADE_USD has no effect
+	SuspicionScoreWarn  float64  `json:"suspicion_score_warn"` // 0-100 scale
+	SuspicionScoreAlert float64  `json:"suspicion_score_alert"`
+	NewWalletDaysMax    *int     `json:"new_wallet_days_max"`  // Older wallets only notify at OLD_WALLET_SCORE_ALERT
+	AlertMode           string   `json:"alert_mode"`           // In ALERT_MODE's format
+	DiscordWebhookURLs  []string `json:"discord_webhook_urls"` // Values may be $SECRET: or secret provider references
+	SMTPTo              []string `json:"smtp_to"`
+}
+
+// profileNamePattern keeps profile names usable as metric labels and in
+// log lines
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// profileCategories are the normalized market categories a profile can
+// watch. Sports markets are filtered out before scoring, so they can't be.
+var profileCategories = map[string]bool{
+	"politics": true,
+	"crypto":   true,
+	"business": true,
+	"science":  true,
+	"culture":  true,
+	"other":    true,
+}
+
+// loadProfiles reads PROFILES, a JSON array (a list under alerts: in
+// CONFIG_FILE), resolving secret references in the routes
+func loadProfiles() ([]Profile, error) {
+	raw := getEnv("PROFILES", "")
+	if raw == "" {
+		return nil, nil
+	}
+	var profiles []Profile
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&profiles); err != nil {
+		return nil, fmt.Errorf("invalid PROFILES JSON: %w", err)
+	}
+
+	for i := range profiles {
+		pr := &profiles[i]
+		pr.Name = strings.TrimSpace(pr.Name)
+		for j, category := range pr.Categories {
+			pr.Categories[j] = strings.ToLower(strings.TrimSpace(category))
+		}
+		for j, url := range pr.DiscordWebhookURLs {
+			resolved, err := resolveProfileSecret(url)
+			if err != nil {
+				return nil, fmt.Errorf("PROFILES %s discord_webhook_urls: %w", pr.Name, err)
+			}
+			pr.DiscordWebhookURLs[j] = resolved
+		}
+		if len(pr.SMTPTo) > 0 {
+			pr.SMTPTo = parseRecipients(strings.Join(pr.SMTPTo, ","))
+		}
+	}
+	return profiles, nil
+}
+
+// resolveProfileSecret returns value, or the secret it names with a
+// $SECRET: prefix or a secret provider's scheme
+func resolveProfileSecret(value string) (string, error) {
+	if ref, ok := strings.CutPrefix(value, secretPrefix); ok {
+		resolved, err := secrets.GetSecret(ref, "")
+		if err != nil {
+			return "", err
+		}
+		if resolved == "" {
+			return "", fmt.Errorf("secret %s is not set", ref)
+		}
+		return resolved, nil
+	}
+	return secrets.Resolve(value)
+}
+
+// validateProfiles checks each profile's name, filters and thresholds, and
+// that its alert route is configured
+func (c *Config) validateProfiles() error {
+	seen := make(map[string]bool, len(c.Profiles))
+	for _, pr := range c.Profiles {
+		if !profileNamePattern.MatchString(pr.Name) {
+			return fmt.Errorf("PROFILES name %q must be lower-case letters, digits, - or _", pr.Name)
+		}
+		if seen[pr.Name] {
+			return fmt.Errorf("PROFILES name %q is used more than once", pr.Name)
+		}
+		seen[pr.Name] = true
+
+		for _, category := range pr.Categories {
+			if !profileCategories[category] {
+				return fmt.Errorf("PROFILES %s category %q is not one of politics, crypto, business, science, culture, other", pr.Name, category)
+			}
+		}
+		if pr.MinTradeUSD < 0 {
+			return fmt.Errorf("PROFILES %s min_trade_usd must not be negative (got %.2f)", pr.Name, pr.MinTradeUSD)
+		}
+		if pr.NewWalletDaysMax != nil && *pr.NewWalletDaysMax < 0 {
+			return fmt.Errorf("PROFILES %s new_wallet_days_max must not be negative (got %d)", pr.Name, *pr.NewWalletDaysMax)
+		}
+		for _, score := range []float64{pr.SuspicionScoreWarn, pr.SuspicionScoreAlert} {
+			if score < 0 || score > 100 {
+				return fmt.Errorf("PROFILES %s scores must be on the 0-100 scale (got %.2f)", pr.Name, score)
+			}
+		}
+		if warn, alert := c.ProfileScoreWarn(pr), c.ProfileScoreAlert(pr); warn > alert {
+			return fmt.Errorf("PROFILES %s suspicion_score_warn (%.2f) must not exceed suspicion_score_alert (%.2f)", pr.Name, warn, alert)
+		}
+		for _, to := range pr.SMTPTo {
+			if _, err := mail.ParseAddress(to); err != nil {
+				return fmt.Errorf("PROFILES %s smtp_to entry %q is not an email address: %v", pr.Name, to, err)
+			}
+		}
+
+		route := c.ForProfile(pr)
+		if err := route.validateAlertModes("PROFILES "+pr.Name+" alert_mode", route.AlertMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ForProfile returns a copy of the configuration with the profile's alert
+// route in place of the global one, for building its sender
+func (c *Config) ForProfile(pr Profile) *Config {
+	route := *c
+	if pr.AlertMode != "" {
+		route.AlertMode = pr.AlertMode
+	}
+	if len(pr.DiscordWebhookURLs) > 0 {
+		route.DiscordWebhookURLs = pr.DiscordWebhookURLs
+	}
+	if len(pr.SMTPTo) > 0 {
+		route.SMTPTo = pr.SMTPTo
+	}
+	return &route
+}
+
+// ProfileScoreWarn returns the profile's WARN threshold, or
+// SUSPICION_SCORE_WARN when it doesn't set one
+func (c *Config) ProfileScoreWarn(pr Profile) float64 {
+	if pr.SuspicionScoreWarn > 0 {
+		return pr.SuspicionScoreWarn
+	}
+	return c.SuspicionScoreWarn
+}
+
+// ProfileScoreAlert returns the profile's ALERT threshold, or
+// SUSPICION_SCORE_ALERT when it doesn't set one
+func (c *Config) ProfileScoreAlert(pr Profile) float64 {
+	if pr.SuspicionScoreAlert > 0 {
+		return pr.SuspicionScoreAlert
+	}
+	return c.SuspicionScoreAlert
+}
+
+// ProfileNewWalletDaysMax returns the profile's wallet age limit, or
+// NEW_WALLET_DAYS_MAX when it doesn't set one
+func (c *Config) ProfileNewWalletDaysMax(pr Profile) int {
+	if pr.NewWalletDaysMax != nil {
+		return *pr.NewWalletDaysMax
+	}
+	return c.NewWalletDaysMax
+}
+
+// Watches reports whether the profile watches a market category
+func (pr Profile) Watches(category string) bool {
+	if len(pr.Categories) == 0 {
+		return true
+	}
+	for _, c := range pr.Categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"reflect"
+)
+
+// reloadable are the fields a running service takes from a reloaded
+// configuration: detection thresholds, rule multipliers and windows,
+// cooldowns and the log level. Every other field (DSNs, ports, API clients,
+// alert senders, schedules) is wired up at startup and needs a restart.
+var reloadable = map[string]bool{
+	"BigTradeUSD":                    true,
+	"MinTradeUSD":                    true,
+	"NewWalletDaysMax":               true,
+	"SuspicionScoreWarn":             true,
+	"SuspicionScoreAlert":            true,
+	"OldWalletScoreAlert":            true,
+	"ConcentrationWindowHrs":         true,
+	"AlertCooldownMins":              true,
+	"TimeToCloseHoursMax":            true,
+	"MinWinRateThreshold":            true,
+	"MaxMarketHorizonDays":           true,
+	"MinMarketLiquidityUSD":          true,
+	"MinMarketLiquidityByCategory":   true,
+	"ClusterLookbackHours":           true,
+	"OpposingCoordinatedMultiplier":  true,
+	"EnableVelocityDetection":        true,
+	"EnableFirstTradeDetection":      true,
+	"EnableFlashFundingDetection":    true,
+	"EnableFundingAgeDetection":      true,
+	"EnableLiquidityDetection":       true,
+	"EnablePriceConfidenceDetection": true,
+	"EnableConcentrationDetection":   true,
+	"EnableCoordinatedDetection":     true,
+	"EnableWinRateDetection":         true,
+	"VelocityWindowMinutes":          true,
+	"VelocityThreshold":              true,
+	"FundingUtilizationThreshold":    true,
+	"FundingUtilizationMultiplier":   true,
+	"SizeAnomalyMultiplier":          true,
+	"SizeAnomalyMinTrades":           true,
+	"LosingRecordMinTrades":          true,
+	"LosingRecordMaxWinRate":         true,
+	"LosingRecordFloor":              true,
+	"EnableHolderDominance":          true,
+	"HolderDominanceMinShare":        true,
+	"HolderDominanceMultiplier":      true,
+	"EnableCopyTradeDetection":       true,
+	"CopyTradeFlaggedDays":           true,
+	"CopyTradeWindowMins":            true,
+	"CopyTradeMultiplier":            true,
+	"ExitAlertFraction":              true,
+	"NormalizeOutcomes":              true,
+	"ExposureAlertUSD":               true,
+	"ExposureWindowHours":            true,
+	"ExposureMinMarkets":             true,
+	"ExposureCooldownHours":          true,
+	"MakerScoreMultiplier":           true,
+	"OrderbookPriceBand":             true,
+	"Detection":                      true,
+	"LogLevel":                       true,
+}
+
+// Diff compares c with a newly loaded configuration and returns the names
+// of the fields that differ, split into those Reload applies and those
+// that only take effect after a restart
+func (c *Config) Diff(next *Config) (reloaded, restart []string) {
+	cur, nxt := reflect.ValueOf(c).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < cur.NumField(); i++ {
+		name := cur.Type().Field(i).Name
+		if name == "Sources" || reflect.DeepEqual(cur.Field(i).Interface(), nxt.Field(i).Interface()) {
+			continue
+		}
+		if reloadable[name] {
+			reloaded = append(reloaded, name)
+		} else {
+			restart = append(restart, name)
+		}
+	}
+	return reloaded, restart
+}
+
+// Reload returns a copy of c with the reloadable fields taken from next.
+// The other fields keep their running values, so the copy always describes
+// what the service is actually doing.
+func (c *Config) Reload(next *Config) *Config {
+	merged := *c
+	out, nxt := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < out.NumField(); i++ {
+		if reloadable[out.Type().Field(i).Name] {
+			out.Field(i).Set(nxt.Field(i))
+		}
+	}
+	merged.Sources = next.Sources
+	return &merged
+}
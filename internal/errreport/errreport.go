@@ -0,0 +1,118 @@
+// Package errreport sends errors and panics to an external error tracker.
+// Until Set installs a Reporter, Capture does nothing, so call sites don't
+// need to check whether reporting is enabled.
+package errreport
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// Event is one error to report
+type Event struct {
+	Err    error
+	Source string            // What failed: trade, alert_send, or the job that panicked
+	Tags   map[string]string // Context to search on, such as wallet and condition_id
+	Stack  []byte            // Set for panics
+}
+
+// Reporter delivers events to an error tracker. Capture must not block the
+// caller; Flush waits for captured events to be delivered.
+type Reporter interface {
+	Capture(ev Event)
+	Flush(ctx context.Context) error
+}
+
+var (
+	mu       sync.RWMutex
+	reporter Reporter
+)
+
+// Set installs r as the reporter for Capture; nil disables reporting
+func Set(r Reporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	reporter = r
+}
+
+// Capture reports ev if a reporter is installed. Cancelled contexts are
+// shutdown rather than failures and are never reported.
+func Capture(ev Event) {
+	if ev.Err == nil || errors.Is(ev.Err, context.Canceled) {
+		return
+	}
+	mu.RLock()
+	r := reporter
+	mu.RUnlock()
+	if r != nil {
+		r.Capture(ev)
+	}
+}
+
+// Flush waits for the installed reporter to deliver captured events
+func Flush(ctx context.Context) error {
+	mu.RLock()
+	r := reporter
+	mu.RUnlock()
+	if r == nil {
+		return nil
+	}
+	return r.Flush(ctx)
+}
+
+// Sampler passes a fraction of events on to another Reporter, and at most a
+// fixed number a minute, so an outage failing every trade sends a handful
+// of events instead of thousands
+type Sampler struct {
+	next      Reporter
+	rate      float64
+	perMinute int
+
+	mu          sync.Mutex
+	windowStart time.Time
+	sent        int
+	random      func() float64
+}
+
+// NewSampler wraps next, keeping rate (0 to 1) of events and at most
+// perMinute a minute; perMinute 0 means no cap
+func NewSampler(next Reporter, rate float64, perMinute int) *Sampler {
+	return &Sampler{next: next, rate: rate, perMinute: perMinute, random: rand.Float64}
+}
+
+// Capture passes ev on unless it is sampled out or the minute's cap is reached
+func (s *Sampler) Capture(ev Event) {
+	if !s.allow(time.Now()) {
+		return
+	}
+	s.next.Capture(ev)
+}
+
+func (s *Sampler) allow(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.random() >= s.rate {
+		metrics.ErrorReports.WithLabelValues("sampled_out").Inc()
+		return false
+	}
+	if now.Sub(s.windowStart) >= time.Minute {
+		s.windowStart, s.sent = now, 0
+	}
+	if s.perMinute > 0 && s.sent >= s.perMinute {
+		metrics.ErrorReports.WithLabelValues("rate_limited").Inc()
+		return false
+	}
+	s.sent++
+	return true
+}
+
+// Flush flushes the wrapped reporter
+func (s *Sampler) Flush(ctx context.Context) error {
+	return s.next.Flush(ctx)
+}
@@ -0,0 +1,167 @@
+package errreport
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fakeReporter records captured events
+type fakeReporter struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func (f *fakeReporter) Capture(ev Event) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, ev)
+}
+
+func (f *fakeReporter) Flush(ctx context.Context) error { return nil }
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		name          string
+		dsn           string
+		expectedURL   string
+		expectedKey   string
+		expectedError bool
+		description   string
+	}{
+		{"sentry.io", "https://abc123@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/store/", "abc123", false, "A hosted DSN posts to the project's store endpoint"},
+		{"path prefix", "http://key@sentry.internal:9000/sentry/7", "http://sentry.internal:9000/sentry/api/7/store/", "key", false, "A self-hosted DSN keeps its path prefix"},
+		{"no key", "https://o1.ingest.sentry.io/42", "", "", true, "The public key is required"},
+		{"no project", "https://abc@o1.ingest.sentry.io/", "", "", true, "The project ID is required"},
+		{"bad scheme", "ftp://abc@host/1", "", "", true, "Only HTTP(S) is supported"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, key, err := parseDSN(tt.dsn)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if url != tt.expectedURL || key != tt.expectedKey {
+				t.Errorf("got %q with key %q, want %q with key %q\nDescription: %s", url, key, tt.expectedURL, tt.expectedKey, tt.description)
+			}
+		})
+	}
+}
+
+func TestSentryCapture(t *testing.T) {
+	var mu sync.Mutex
+	var auth []string
+	var events []sentryEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var ev sentryEvent
+		json.NewDecoder(r.Body).Decode(&ev)
+		mu.Lock()
+		defer mu.Unlock()
+		auth = append(auth, r.Header.Get("X-Sentry-Auth"))
+		events = append(events, ev)
+	}))
+	defer srv.Close()
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	dsn := strings.Replace(srv.URL, "http://", "http://pubkey@", 1) + "/5"
+	sentry, err := NewSentry(dsn, "staging", log)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	sentry.Capture(Event{Err: errors.New("insert alert: deadlock"), Source: "trade", Tags: map[string]string{"wallet": "0xabc", "trade_hash": ""}})
+	sentry.Capture(Event{Err: errors.New("panic: boom"), Source: "win_rate", Stack: []byte("goroutine 1")})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := sentry.Flush(ctx); err != nil {
+		t.Fatalf("got %v flushing, want no error", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if !strings.Contains(auth[0], "sentry_key=pubkey") {
+		t.Errorf("got auth %q, want the DSN's key", auth[0])
+	}
+	ev := events[0]
+	if ev.Level != "error" || ev.Message != "insert alert: deadlock" || ev.Environment != "staging" || len(ev.EventID) != 32 {
+		t.Errorf("got %+v, want an error event with the message, environment and a 32 character ID", ev)
+	}
+	if ev.Tags["source"] != "trade" || ev.Tags["wallet"] != "0xabc" {
+		t.Errorf("got tags %v, want the source and wallet", ev.Tags)
+	}
+	if _, ok := ev.Tags["trade_hash"]; ok {
+		t.Errorf("got tags %v, want empty tags left out", ev.Tags)
+	}
+	if panicEv := events[1]; panicEv.Level != "fatal" || panicEv.Exception.Values[0].Type != "panic" || panicEv.Extra["stack"] != "goroutine 1" {
+		t.Errorf("got %+v, want a fatal panic event with its stack", panicEv)
+	}
+}
+
+func TestSampler(t *testing.T) {
+	tests := []struct {
+		name        string
+		rate        float64
+		perMinute   int
+		random      float64
+		captures    int
+		expected    int
+		description string
+	}{
+		{"all", 1.0, 0, 0.5, 50, 50, "A rate of 1 without a cap reports everything"},
+		{"capped", 1.0, 10, 0.5, 50, 10, "At most perMinute events are reported a minute"},
+		{"sampled out", 0.25, 0, 0.5, 50, 0, "Events drawing above the rate are dropped"},
+		{"sampled in", 0.75, 0, 0.5, 50, 50, "Events drawing below the rate are kept"},
+		{"disabled", 0, 0, 0, 50, 0, "A rate of 0 reports nothing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &fakeReporter{}
+			s := NewSampler(next, tt.rate, tt.perMinute)
+			s.random = func() float64 { return tt.random }
+			for i := 0; i < tt.captures; i++ {
+				s.Capture(Event{Err: errors.New("api down")})
+			}
+			if len(next.events) != tt.expected {
+				t.Errorf("got %d events, want %d\nDescription: %s", len(next.events), tt.expected, tt.description)
+			}
+		})
+	}
+
+	// The cap resets each minute
+	next := &fakeReporter{}
+	s := NewSampler(next, 1.0, 1)
+	start := time.Now()
+	if !s.allow(start) || s.allow(start.Add(30*time.Second)) || !s.allow(start.Add(61*time.Second)) {
+		t.Errorf("got the cap not resetting after a minute, want one event allowed per minute")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	next := &fakeReporter{}
+	Set(next)
+	defer Set(nil)
+
+	Capture(Event{Err: errors.New("failed")})
+	Capture(Event{Err: nil})
+	Capture(Event{Err: context.Canceled})
+	if len(next.events) != 1 {
+		t.Errorf("got %d events, want 1: nil errors and cancellations are not reported", len(next.events))
+	}
+
+	Set(nil)
+	Capture(Event{Err: errors.New("failed")}) // Must not panic without a reporter
+}
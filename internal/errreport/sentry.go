@@ -0,0 +1,198 @@
+package errreport
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	sentryQueueSize   = 100
+	sentryHTTPTimeout = 10 * time.Second
+)
+
+// Sentry reports events to Sentry, or any service accepting Sentry's store
+// API, from a background goroutine. Events captured while the queue is full
+// are dropped.
+type Sentry struct {
+	storeURL    string
+	auth        string // X-Sentry-Auth header
+	environment string
+	client      *http.Client
+	log         *logrus.Logger
+
+	queue   chan sentryEvent
+	pending sync.WaitGroup
+}
+
+// sentryEvent is the subset of Sentry's event payload we fill in
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Logger      string            `json:"logger"`
+	Release     string            `json:"release"`
+	Environment string            `json:"environment,omitempty"`
+	Message     string            `json:"message"`
+	Exception   sentryExceptions  `json:"exception"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Extra       map[string]string `json:"extra,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// NewSentry creates a Sentry reporter for dsn
+// (https://<key>@<host>/<project>), tagging events with environment
+func NewSentry(dsn, environment string, log *logrus.Logger) (*Sentry, error) {
+	storeURL, key, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sentry{
+		storeURL:    storeURL,
+		auth:        fmt.Sprintf("Sentry sentry_version=7, sentry_client=insiderwatch/%s, sentry_key=%s", version.Version, key),
+		environment: environment,
+		client:      &http.Client{Timeout: sentryHTTPTimeout},
+		log:         log,
+		queue:       make(chan sentryEvent, sentryQueueSize),
+	}
+	go s.run()
+	return s, nil
+}
+
+// parseDSN returns the store endpoint and public key of a Sentry DSN
+func parseDSN(dsn string) (storeURL, key string, err error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", "", fmt.Errorf("parse Sentry DSN: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" || u.User == nil || u.User.Username() == "" {
+		return "", "", fmt.Errorf("Sentry DSN must look like https://<key>@<host>/<project>")
+	}
+	path := strings.Trim(u.Path, "/")
+	i := strings.LastIndex(path, "/")
+	prefix, project := "", path
+	if i >= 0 {
+		prefix, project = "/"+path[:i], path[i+1:]
+	}
+	if project == "" {
+		return "", "", fmt.Errorf("Sentry DSN has no project ID")
+	}
+	return fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, prefix, project), u.User.Username(), nil
+}
+
+// Capture queues ev for delivery
+func (s *Sentry) Capture(ev Event) {
+	event := s.build(ev, time.Now())
+	s.pending.Add(1)
+	select {
+	case s.queue <- event:
+	default:
+		s.pending.Done()
+		metrics.ErrorReports.WithLabelValues("dropped").Inc()
+	}
+}
+
+// build converts ev to a Sentry event
+func (s *Sentry) build(ev Event, now time.Time) sentryEvent {
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	level, errType := "error", fmt.Sprintf("%T", ev.Err)
+	extra := map[string]string{}
+	if ev.Stack != nil {
+		level, errType = "fatal", "panic"
+		extra["stack"] = string(ev.Stack)
+	}
+	tags := map[string]string{"source": ev.Source}
+	for k, v := range ev.Tags {
+		if v != "" {
+			tags[k] = v
+		}
+	}
+
+	return sentryEvent{
+		EventID:     hex.EncodeToString(id),
+		Timestamp:   now.UTC().Format(time.RFC3339),
+		Level:       level,
+		Platform:    "go",
+		Logger:      ev.Source,
+		Release:     "insiderwatch@" + version.Version,
+		Environment: s.environment,
+		Message:     ev.Err.Error(),
+		Exception:   sentryExceptions{Values: []sentryException{{Type: errType, Value: ev.Err.Error()}}},
+		Tags:        tags,
+		Extra:       extra,
+	}
+}
+
+// run delivers queued events one at a time
+func (s *Sentry) run() {
+	for event := range s.queue {
+		if err := s.send(event); err != nil {
+			metrics.ErrorReports.WithLabelValues("error").Inc()
+			s.log.WithError(err).Warn("Failed to report error to Sentry")
+		} else {
+			metrics.ErrorReports.WithLabelValues("sent").Inc()
+		}
+		s.pending.Done()
+	}
+}
+
+func (s *Sentry) send(event sentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.storeURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", s.auth)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush waits for queued events to be delivered or ctx to be done
+func (s *Sentry) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
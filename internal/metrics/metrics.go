@@ -7,6 +7,24 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
+// DefaultProfile is the profile label of alerts raised without PROFILES
+const DefaultProfile = "default"
+
+// Histograms whose buckets SetHistogramBuckets can override, with their
+// default buckets
+var (
+	tradeProcessingDurationOpts = prometheus.HistogramOpts{
+		Name:    "insiderwatch_trade_processing_duration_seconds",
+		Help:    "Duration of trade processing",
+		Buckets: prometheus.DefBuckets,
+	}
+	suspicionScoresRawOpts = prometheus.HistogramOpts{
+		Name:    "insiderwatch_suspicion_scores_raw",
+		Help:    "Distribution of raw suspicion scores (before normalization)",
+		Buckets: []float64{100, 500, 1000, 5000, 10000, 25000, 50000, 100000, 250000, 500000, 1000000, 5000000},
+	}
+)
+
 var (
 	// Trade processing metrics
 	TradesProcessed = promauto.NewCounterVec(
@@ -17,21 +35,107 @@ var (
 		[]string{"status"}, // success, duplicate, filtered
 	)
 
-	TradeProcessingDuration = promauto.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "insiderwatch_trade_processing_duration_seconds",
-			Help:    "Duration of trade processing",
-			Buckets: prometheus.DefBuckets,
+	TradesFiltered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_trades_filtered_total",
+			Help: "Trades skipped by market filters, by reason and market category",
+		},
+		[]string{"reason", "category"}, // sports, illiquid, closed, horizon, size
+	)
+
+	TradesAfterEndDate = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_trades_after_end_date_total",
+			Help: "Trades processed at or after their market's cached end date, a sign of stale end dates",
+		},
+		[]string{"source"}, // market, event
+	)
+
+	TradeProcessingDuration = promauto.NewHistogram(tradeProcessingDurationOpts)
+
+	// Last poll cycle metrics, overwritten at the end of every ProcessTrades
+	LastPollTrades = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_poll_trades",
+			Help: "Trades in the last poll cycle by status",
+		},
+		[]string{"status"}, // fetched, new, duplicate, success, filtered_*, *_error
+	)
+
+	LastPollAlerts = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_poll_alerts",
+			Help: "Alerts triggered in the last poll cycle by severity",
+		},
+		[]string{"severity"},
+	)
+
+	PollCyclesSkipped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_poll_cycles_skipped_total",
+			Help: "Poll ticks skipped because the previous cycle was still running",
+		},
+	)
+
+	LastPollDuration = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_poll_duration_seconds",
+			Help: "Wall-clock duration of the last poll cycle, whether or not it succeeded",
+		},
+	)
+
+	LastPollIngestLag = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_poll_ingest_lag_seconds",
+			Help: "Age of the newest trade processed in the last poll cycle",
+		},
+	)
+
+	CheckpointAge = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_checkpoint_age_seconds",
+			Help: "Seconds between the end of the last poll cycle and its last_processed_ts checkpoint",
 		},
 	)
 
+	LastPollTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_poll_timestamp_seconds",
+			Help: "Unix time the last poll cycle finished",
+		},
+	)
+
+	LastSuccessfulPollTimestamp = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_successful_poll_timestamp_seconds",
+			Help: "Unix time the last successful poll cycle finished",
+		},
+	)
+
+	// BuildInfo is always 1; its labels identify the running build
+	BuildInfo = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_build_info",
+			Help: "Build of the running binary, as labels on a constant 1",
+		},
+		[]string{"version", "commit", "build_date", "go_version"},
+	)
+
 	// Alert metrics
 	AlertsTriggered = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "insiderwatch_alerts_triggered_total",
 			Help: "Total number of alerts triggered",
 		},
-		[]string{"severity"}, // critical, high, medium, low
+		[]string{"severity", "category", "profile"}, // Category is the market's normalized category; profile is the watch profile, or default
+	)
+
+	AlertDetectors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_alert_detectors_total",
+			Help: "Scoring rules that fired on stored alerts, counted once per alert",
+		},
+		[]string{"detector", "severity"}, // Detector is the rule's name
 	)
 
 	AlertsSent = promauto.NewCounterVec(
@@ -42,10 +146,64 @@ var (
 		[]string{"status", "type"}, // success/error, discord/smtp/log
 	)
 
-	AlertsSuppressed = promauto.NewCounter(
+	AlertsSuppressed = promauto.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "insiderwatch_alerts_suppressed_total",
-			Help: "Total number of alerts suppressed due to cooldown",
+			Help: "Total number of alerts not notified, by reason",
+		},
+		[]string{"reason", "profile"},
+	)
+
+	ExitAlerts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_exit_alerts_total",
+			Help: "Total number of follow-up alerts for alerted wallets exiting their position",
+		},
+	)
+
+	AlertsRateCapped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_alerts_rate_capped_total",
+			Help: "Alerts held back by a channel's rate cap and rolled into an overflow summary",
+		},
+		[]string{"channel"}, // log, discord, smtp
+	)
+
+	OutcomeNormalizedMatches = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_outcome_normalized_matches_total",
+			Help: "Trades matched to a market's winning outcome only after normalizing the outcome labels",
+		},
+	)
+
+	ExposureAlerts = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_exposure_alerts_total",
+			Help: "Total number of aggregate exposure alerts for new wallets buying across markets",
+		},
+	)
+
+	AlertLatency = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "insiderwatch_alert_latency_seconds",
+			Help:    "Time from trade timestamp to alert being sent",
+			Buckets: []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800},
+		},
+	)
+
+	// Live feed metrics
+	LiveFeedMessages = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_live_feed_messages_total",
+			Help: "Total number of messages received from the live trade feed",
+		},
+		[]string{"type"}, // trade, ignored, invalid
+	)
+
+	LiveFeedReconnects = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_live_feed_reconnects_total",
+			Help: "Total number of live trade feed reconnects",
 		},
 	)
 
@@ -55,7 +213,47 @@ var (
 			Name: "insiderwatch_api_requests_total",
 			Help: "Total number of API requests",
 		},
-		[]string{"api", "endpoint", "status"}, // data/gamma, /trades, success/error
+		[]string{"api", "endpoint", "status"}, // data/gamma, /trades, success/error/retry
+	)
+
+	CircuitState = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_circuit_state",
+			Help: "API circuit breaker state (0 = closed, 1 = half-open, 2 = open)",
+		},
+		[]string{"api"}, // data_trades, data_activity, gamma
+	)
+
+	CoalescedLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_coalesced_lookups_total",
+			Help: "Lookups that waited on an identical in-flight lookup instead of calling the API",
+		},
+		[]string{"lookup"}, // market, wallet
+	)
+
+	InvalidTrades = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_invalid_trades_total",
+			Help: "Malformed trades from the API skipped before processing",
+		},
+		[]string{"reason"}, // missing_wallet, missing_condition_id, bad_timestamp, bad_size, bad_price
+	)
+
+	MarketLookups = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_market_lookups_total",
+			Help: "Trade market resolutions by the path that produced them",
+		},
+		[]string{"path"}, // cache, condition_id, not_modified, slug, stale_cache, trade_data
+	)
+
+	// Market cache pre-warming
+	MarketsPrewarmed = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_last_market_prewarm_markets",
+			Help: "Markets the last pre-warm run cached from Gamma ahead of their first trade",
+		},
 	)
 
 	APIRequestDuration = promauto.NewHistogramVec(
@@ -67,6 +265,24 @@ var (
 		[]string{"api", "endpoint"},
 	)
 
+	APIRateLimitWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "insiderwatch_api_rate_limit_wait_seconds",
+			Help:    "Time API requests spent waiting on our own rate limiter before being sent",
+			Buckets: []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"api", "endpoint"},
+	)
+
+	RateLimitBudgetWait = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "insiderwatch_rate_limit_budget_wait_seconds",
+			Help:    "Time API requests waited on the shared rate limit budget, by priority class",
+			Buckets: []float64{.001, .01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"priority"},
+	)
+
 	// Database metrics
 	DatabaseQueries = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -93,6 +309,22 @@ var (
 		},
 	)
 
+	WinRateCalculationsSkipped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_win_rate_calculations_skipped_total",
+			Help: "Win rate calculation runs skipped because one was already running",
+		},
+	)
+
+	// First-trade verification metrics
+	FirstTradeVerificationsSaved = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_first_trade_verifications_saved_total",
+			Help: "Data API activity calls avoided when verifying first trades",
+		},
+		[]string{"reason"}, // cache, local
+	)
+
 	MarketsResolved = promauto.NewCounter(
 		prometheus.CounterOpts{
 			Name: "insiderwatch_markets_resolved_total",
@@ -110,13 +342,7 @@ var (
 
 	// Suspicion score metrics
 	// Raw scores track the pre-normalization values to understand actual distribution
-	SuspicionScoresRaw = promauto.NewHistogram(
-		prometheus.HistogramOpts{
-			Name:    "insiderwatch_suspicion_scores_raw",
-			Help:    "Distribution of raw suspicion scores (before normalization)",
-			Buckets: []float64{100, 500, 1000, 5000, 10000, 25000, 50000, 100000, 250000, 500000, 1000000, 5000000},
-		},
-	)
+	SuspicionScoresRaw = promauto.NewHistogram(suspicionScoresRawOpts)
 
 	// Normalized scores (0-100) to verify calibration is working correctly
 	SuspicionScoresNormalized = promauto.NewHistogram(
@@ -127,6 +353,42 @@ var (
 		},
 	)
 
+	// Tracked entity metrics, refreshed every few minutes from storage
+	TrackedWallets = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_tracked_wallets",
+			Help: "Wallets stored by the service",
+		},
+	)
+
+	ActiveWallets = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_active_wallets_24h",
+			Help: "Stored wallets that traded in the last 24 hours",
+		},
+	)
+
+	TrackedClusters = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_tracked_clusters",
+			Help: "Wallet clusters with at least 2 wallets, not merged into another",
+		},
+	)
+
+	FlaggedClusters = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_flagged_clusters",
+			Help: "Wallet clusters flagged as suspicious, not merged into another",
+		},
+	)
+
+	UnresolvedMarkets = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_unresolved_markets",
+			Help: "Tracked markets without a recorded resolution",
+		},
+	)
+
 	// System health
 	HealthChecks = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -135,25 +397,92 @@ var (
 		},
 		[]string{"status"}, // healthy/unhealthy
 	)
+
+	AlertStreamClients = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "insiderwatch_alert_stream_clients",
+			Help: "Clients connected to GET /alerts/stream",
+		},
+	)
+
+	AlertStreamDropped = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_alert_stream_dropped_total",
+			Help: "Alert stream clients disconnected for falling too far behind",
+		},
+	)
+
+	Panics = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_panics_total",
+			Help: "Panics recovered in trade processing and background jobs",
+		},
+		[]string{"source"}, // trade, poll, win_rate, withdrawals
+	)
+
+	HTTPPanics = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_http_panics_total",
+			Help: "HTTP handler panics recovered and answered with a 500",
+		},
+	)
+
+	ErrorReports = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "insiderwatch_error_reports_total",
+			Help: "Errors passed to the error tracker, by outcome",
+		},
+		[]string{"status"}, // sent, error, dropped, sampled_out, rate_limited
+	)
 )
 
-// RecordTradeProcessing records trade processing metrics
-func RecordTradeProcessing(duration time.Duration, status string) {
-	TradesProcessed.WithLabelValues(status).Inc()
+// RecordTradeProcessing records how long processing a trade took. Its
+// outcome is counted separately in TradesProcessed, once per trade.
+func RecordTradeProcessing(duration time.Duration) {
 	TradeProcessingDuration.Observe(duration.Seconds())
 }
 
 // RecordAlert records alert metrics
 func RecordAlert(severity, sendStatus, alertType string, suppressed bool) {
 	if suppressed {
-		AlertsSuppressed.Inc()
+		AlertsSuppressed.WithLabelValues("unknown", DefaultProfile).Inc()
 		return
 	}
 	
-	AlertsTriggered.WithLabelValues(severity).Inc()
+	AlertsTriggered.WithLabelValues(severity, "other", DefaultProfile).Inc()
 	AlertsSent.WithLabelValues(sendStatus, alertType).Inc()
 }
 
+// RecordPoll publishes the summary of a poll cycle that fetched trades.
+// Statuses and severities missing from this cycle are reset so they read as
+// absent rather than carrying over from an earlier cycle. An ingest lag of 0
+// means no new trades were processed and leaves the previous value in place.
+func RecordPoll(ingestLag time.Duration, trades, alerts map[string]int) {
+	LastPollTrades.Reset()
+	for status, count := range trades {
+		LastPollTrades.WithLabelValues(status).Set(float64(count))
+	}
+	LastPollAlerts.Reset()
+	for severity, count := range alerts {
+		LastPollAlerts.WithLabelValues(severity).Set(float64(count))
+	}
+	if ingestLag > 0 {
+		LastPollIngestLag.Set(ingestLag.Seconds())
+	}
+	LastPollTimestamp.SetToCurrentTime()
+}
+
+// RecordPollCycle publishes the duration of any poll cycle, including ones
+// that failed or timed out before fetching trades, and the age of the
+// checkpoint it left. A checkpoint of 0 (never set, or unreadable) leaves
+// the previous age in place.
+func RecordPollCycle(duration time.Duration, checkpointTS int64) {
+	LastPollDuration.Set(duration.Seconds())
+	if checkpointTS > 0 {
+		CheckpointAge.Set(time.Since(time.Unix(checkpointTS, 0)).Seconds())
+	}
+}
+
 // RecordAPIRequest records API request metrics
 func RecordAPIRequest(api, endpoint string, duration time.Duration, err error) {
 	status := "success"
@@ -164,6 +493,27 @@ func RecordAPIRequest(api, endpoint string, duration time.Duration, err error) {
 	APIRequestDuration.WithLabelValues(api, endpoint).Observe(duration.Seconds())
 }
 
+// RecordRateLimitWait records how long a request waited on the client's rate
+// limiter. It is kept out of the request duration so a slow API can be told
+// apart from our own throttling.
+func RecordRateLimitWait(api, endpoint string, wait time.Duration) {
+	APIRateLimitWait.WithLabelValues(api, endpoint).Observe(wait.Seconds())
+}
+
+// RecordBudgetWait records how long a request waited on the shared rate
+// limit budget
+func RecordBudgetWait(priority string, wait time.Duration) {
+	RateLimitBudgetWait.WithLabelValues(priority).Observe(wait.Seconds())
+}
+
+// RecordAPIRetry records a retry of a failed API request under the request
+// counter's "retry" status. The failed attempt itself is still counted as an
+// error by RecordAPIRequest, so errors count attempts, not requests; subtract
+// retries to get the requests that failed for good.
+func RecordAPIRetry(api, endpoint string) {
+	APIRequests.WithLabelValues(api, endpoint, "retry").Inc()
+}
+
 // RecordDatabaseQuery records database query metrics
 func RecordDatabaseQuery(operation string, duration time.Duration, err error) {
 	status := "success"
@@ -191,6 +541,33 @@ func RecordSuspicionScore(rawScore, normalizedScore float64) {
 	SuspicionScoresNormalized.Observe(normalizedScore)
 }
 
+// SetHistogramBuckets re-registers the raw score and trade processing
+// duration histograms with the given buckets; nil keeps a histogram's
+// defaults. Call it at startup, before anything is observed.
+func SetHistogramBuckets(scoreBuckets, tradeDurationBuckets []float64) {
+	if scoreBuckets != nil {
+		prometheus.Unregister(SuspicionScoresRaw)
+		opts := suspicionScoresRawOpts
+		opts.Buckets = scoreBuckets
+		SuspicionScoresRaw = promauto.NewHistogram(opts)
+	}
+	if tradeDurationBuckets != nil {
+		prometheus.Unregister(TradeProcessingDuration)
+		opts := tradeProcessingDurationOpts
+		opts.Buckets = tradeDurationBuckets
+		TradeProcessingDuration = promauto.NewHistogram(opts)
+	}
+}
+
+// RecordEntityCounts sets the tracked entity gauges
+func RecordEntityCounts(wallets, activeWallets, clusters, flaggedClusters, unresolvedMarkets int64) {
+	TrackedWallets.Set(float64(wallets))
+	ActiveWallets.Set(float64(activeWallets))
+	TrackedClusters.Set(float64(clusters))
+	FlaggedClusters.Set(float64(flaggedClusters))
+	UnresolvedMarkets.Set(float64(unresolvedMarkets))
+}
+
 // RecordHealthCheck records health check status
 func RecordHealthCheck(healthy bool) {
 	status := "healthy"
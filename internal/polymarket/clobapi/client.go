@@ -0,0 +1,127 @@
+package clobapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// Client handles communication with the Polymarket CLOB API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	limiter    *ratelimit.Limiter
+	budget     *ratelimit.Budget // Shared with the other Polymarket clients; nil when disabled
+}
+
+// NewClient creates a new CLOB API client that also draws on budget, which
+// may be nil
+func NewClient(cfg *config.Config, budget *ratelimit.Budget, log *logrus.Logger) *Client {
+	return &Client{
+		baseURL:    cfg.ClobAPIBaseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second, Transport: transport.New("clob", nil, cfg.APISlowRequestThreshold, log)},
+		limiter:    ratelimit.New(cfg.ClobAPIBookRPS, cfg.ClobAPIBookBurst),
+		budget:     budget,
+	}
+}
+
+// GetOrderBook fetches the current order book for an outcome token
+func (c *Client) GetOrderBook(ctx context.Context, tokenID string) (*OrderBook, error) {
+	// Rate limit
+	if err := c.limiter.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("rate limit wait: %w", err)
+	}
+	if err := c.budget.Wait(ctx); err != nil {
+		return nil, fmt.Errorf("shared rate limit wait: %w", err)
+	}
+
+	u, err := url.Parse(c.baseURL + "/book")
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("token_id", tokenID)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	// Book reads are public - no auth headers needed
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d (request_id=%s): %s", resp.StatusCode, transport.RequestID(resp), string(body))
+	}
+
+	var book OrderBook
+	if err := json.NewDecoder(resp.Body).Decode(&book); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	return &book, nil
+}
+
+// GetMidpoint fetches the current midpoint price for an outcome token
+func (c *Client) GetMidpoint(ctx context.Context, tokenID string) (float64, error) {
+	// Rate limit
+	if err := c.limiter.Wait(ctx); err != nil {
+		return 0, fmt.Errorf("rate limit wait: %w", err)
+	}
+	if err := c.budget.Wait(ctx); err != nil {
+		return 0, fmt.Errorf("shared rate limit wait: %w", err)
+	}
+
+	u, err := url.Parse(c.baseURL + "/midpoint")
+	if err != nil {
+		return 0, fmt.Errorf("parse URL: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("token_id", tokenID)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("unexpected status %d (request_id=%s): %s", resp.StatusCode, transport.RequestID(resp), string(body))
+	}
+
+	var mid Midpoint
+	if err := json.NewDecoder(resp.Body).Decode(&mid); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+
+	price, err := strconv.ParseFloat(mid.Mid, 64)
+	if err != nil {
+		return 0, fmt.Errorf("parse midpoint %q: %w", mid.Mid, err)
+	}
+	return price, nil
+}
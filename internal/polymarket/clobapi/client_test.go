@@ -0,0 +1,93 @@
+package clobapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return NewClient(&config.Config{ClobAPIBaseURL: server.URL, ClobAPIBookRPS: 100, ClobAPIBookBurst: 10}, nil, log)
+}
+
+func TestGetMidpoint(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		body          string
+		expectedPrice float64
+		wantErr       string
+		description   string
+	}{
+		{
+			name:          "midpoint",
+			status:        http.StatusOK,
+			body:          `{"mid": "0.615"}`,
+			expectedPrice: 0.615,
+			description:   "The midpoint string is parsed as a price",
+		},
+		{
+			name:        "empty book",
+			status:      http.StatusNotFound,
+			body:        `{"error": "No orderbook exists for the requested token id"}`,
+			wantErr:     "unexpected status 404",
+			description: "A token without an order book has no midpoint",
+		},
+		{
+			name:        "server error",
+			status:      http.StatusInternalServerError,
+			body:        `internal error`,
+			wantErr:     "unexpected status 500",
+			description: "Non-200 responses are errors and carry the body",
+		},
+		{
+			name:        "empty midpoint",
+			status:      http.StatusOK,
+			body:        `{"mid": ""}`,
+			wantErr:     "parse midpoint",
+			description: "A blank midpoint is an error rather than a price of zero",
+		},
+		{
+			name:        "bad json",
+			status:      http.StatusOK,
+			body:        `{"mid": `,
+			wantErr:     "decode response",
+			description: "A truncated body is an error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotToken string
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				gotPath, gotToken = r.URL.Path, r.URL.Query().Get("token_id")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			price, err := client.GetMidpoint(context.Background(), "token-yes")
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if price != tt.expectedPrice {
+				t.Errorf("got price %.3f, want %.3f\nDescription: %s", price, tt.expectedPrice, tt.description)
+			}
+			if gotPath != "/midpoint" || gotToken != "token-yes" {
+				t.Errorf("got %s?token_id=%s, want /midpoint?token_id=token-yes\nDescription: The midpoint is requested for the traded outcome's token", gotPath, gotToken)
+			}
+		})
+	}
+}
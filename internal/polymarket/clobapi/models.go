@@ -0,0 +1,49 @@
+package clobapi
+
+import "strconv"
+
+// OrderBook is the order book for a single outcome token
+type OrderBook struct {
+	Market    string       `json:"market"`   // Condition ID
+	AssetID   string       `json:"asset_id"` // Token ID
+	Bids      []PriceLevel `json:"bids"`
+	Asks      []PriceLevel `json:"asks"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// Midpoint is the midpoint between the best bid and ask of an outcome token
+type Midpoint struct {
+	Mid string `json:"mid"`
+}
+
+// PriceLevel is the resting size at one price
+type PriceLevel struct {
+	Price string `json:"price"`
+	Size  string `json:"size"` // Shares
+}
+
+// DepthWithin returns the notional (price * size) resting within band of
+// price on the side of the book a trade with the given side takes from:
+// buys take asks, sells take bids
+func (b *OrderBook) DepthWithin(tradeSide string, price, band float64) float64 {
+	levels := b.Asks
+	if tradeSide == "SELL" {
+		levels = b.Bids
+	}
+
+	depth := 0.0
+	for _, level := range levels {
+		p, err := strconv.ParseFloat(level.Price, 64)
+		if err != nil {
+			continue
+		}
+		size, err := strconv.ParseFloat(level.Size, 64)
+		if err != nil {
+			continue
+		}
+		if p >= price-band && p <= price+band {
+			depth += p * size
+		}
+	}
+	return depth
+}
@@ -0,0 +1,77 @@
+package dataapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrResponseTooLarge means a response body exceeded API_MAX_RESPONSE_MB.
+// The request is not retried; the same page would be just as large.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// limitBody caps how much of a response body is read. Unlike io.LimitReader
+// it fails instead of truncating, so a cut-off page can't decode as valid.
+// A max of 0 or less means no limit.
+func limitBody(r io.Reader, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &limitedBody{r: r, remaining: max}
+}
+
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		return 0, ErrResponseTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// streamDecoder is implemented by response types that decode themselves
+// element by element rather than through a single Decode call
+type streamDecoder interface {
+	decodeStream(dec *json.Decoder) error
+}
+
+// tradeList decodes a JSON array of trades one element at a time. Decode
+// would buffer a whole multi-megabyte /trades page before unmarshaling it.
+type tradeList []Trade
+
+func (l *tradeList) decodeStream(dec *json.Decoder) error {
+	*l = (*l)[:0]
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if tok == nil {
+		return nil // null
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected array, got %v", tok)
+	}
+
+	for dec.More() {
+		*l = append(*l, Trade{})
+		if err := dec.Decode(&(*l)[len(*l)-1]); err != nil {
+			*l = (*l)[:len(*l)-1]
+			return err
+		}
+	}
+
+	// Closing bracket
+	_, err = dec.Token()
+	return err
+}
@@ -2,51 +2,78 @@ package dataapi
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"time"
 
+	"github.com/liamashdown/insiderwatch/internal/circuit"
 	"github.com/liamashdown/insiderwatch/internal/config"
 	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+	"github.com/sirupsen/logrus"
 )
 
 // Client handles communication with the Polymarket Data API
 type Client struct {
-	baseURL      string
-	httpClient   *http.Client
-	authMode     config.AuthMode
-	bearerToken  string
-	apiKey       string
-	extraHeaders map[string]string
-	tradesLimiter   *ratelimit.Limiter
-	activityLimiter *ratelimit.Limiter
+	baseURL          string
+	httpClient       *http.Client
+	authMode         config.AuthMode
+	bearerToken      string
+	apiKey           string
+	extraHeaders     map[string]string
+	tradesLimiter    *ratelimit.Limiter
+	activityLimiter  *ratelimit.Limiter
+	positionsLimiter *ratelimit.Limiter
+	holdersLimiter   *ratelimit.Limiter
+	budget           *ratelimit.Budget // Shared with the other Polymarket clients; nil when disabled
+	tradesBreaker    *circuit.Breaker
+	activityBreaker  *circuit.Breaker
+	positionsBreaker *circuit.Breaker
+	holdersBreaker   *circuit.Breaker
+	tradesTimeout    time.Duration // Per attempt, excluding rate limit waits
+	activityTimeout  time.Duration // Per attempt for wallet and market lookups
+	retry            retryPolicy
+	maxResponseBytes int64
 }
 
-// NewClient creates a new Data API client
-func NewClient(cfg *config.Config) *Client {
-	return &Client{
-		baseURL:      cfg.DataAPIBaseURL,
-		httpClient:   &http.Client{Timeout: 30 * time.Second},
-		authMode:     cfg.DataAPIAuthMode,
-		bearerToken:  cfg.DataAPIBearerToken,
-		apiKey:       cfg.DataAPIAPIKey,
-		extraHeaders: cfg.DataAPIExtraHeaders,
-		tradesLimiter:   ratelimit.New(cfg.DataAPITradesRPS),
-		activityLimiter: ratelimit.New(cfg.DataAPIActivityRPS),
+// NewClient creates a new Data API client that also draws on budget, which
+// may be nil. It fails when the proxy or CA bundle can't be set up.
+func NewClient(cfg *config.Config, budget *ratelimit.Budget, log *logrus.Logger) (*Client, error) {
+	base, err := transport.NewBase(cfg.DataAPIProxyURL, cfg.APICABundle)
+	if err != nil {
+		return nil, fmt.Errorf("data API transport: %w", err)
 	}
+	cooldown := time.Duration(cfg.CircuitBreakerCooldownSec) * time.Second
+	// The default transport requests gzip and decompresses transparently as
+	// long as no request sets Accept-Encoding itself. Timeouts are set per
+	// request, so a slow trades page doesn't set the bound for every lookup.
+	return &Client{
+		baseURL:          cfg.DataAPIBaseURL,
+		httpClient:       &http.Client{Transport: transport.New("data", base, cfg.APISlowRequestThreshold, log)},
+		authMode:         cfg.DataAPIAuthMode,
+		bearerToken:      cfg.DataAPIBearerToken,
+		apiKey:           cfg.DataAPIAPIKey,
+		extraHeaders:     cfg.DataAPIExtraHeaders,
+		tradesLimiter:    ratelimit.New(cfg.DataAPITradesRPS, cfg.DataAPITradesBurst),
+		activityLimiter:  ratelimit.New(cfg.DataAPIActivityRPS, cfg.DataAPIActivityBurst),
+		positionsLimiter: ratelimit.New(cfg.DataAPIPositionsRPS, cfg.DataAPIPositionsBurst),
+		holdersLimiter:   ratelimit.New(cfg.DataAPIHoldersRPS, cfg.DataAPIHoldersBurst),
+		budget:           budget,
+		tradesBreaker:    circuit.New("data_trades", cfg.CircuitBreakerFailures, cooldown, log),
+		activityBreaker:  circuit.New("data_activity", cfg.CircuitBreakerFailures, cooldown, log),
+		positionsBreaker: circuit.New("data_positions", cfg.CircuitBreakerFailures, cooldown, log),
+		holdersBreaker:   circuit.New("data_holders", cfg.CircuitBreakerFailures, cooldown, log),
+		tradesTimeout:    cfg.DataAPITradesTimeout,
+		activityTimeout:  cfg.DataAPIActivityTimeout,
+		retry:            defaultRetryPolicy,
+		maxResponseBytes: int64(cfg.APIMaxResponseMB) << 20,
+	}, nil
 }
 
 // GetTrades fetches trades from the Data API with BIG_TRADE_USD filter
 func (c *Client) GetTrades(ctx context.Context, params TradeParams) (*TradesResponse, error) {
-	// Rate limit
-	if err := c.tradesLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
-
 	u, err := url.Parse(c.baseURL + "/trades")
 	if err != nil {
 		return nil, fmt.Errorf("parse URL: %w", err)
@@ -59,9 +86,8 @@ func (c *Client) GetTrades(ctx context.Context, params TradeParams) (*TradesResp
 	if params.Offset > 0 {
 		q.Set("offset", strconv.Itoa(params.Offset))
 	}
-	if params.TakerOnly {
-		q.Set("takerOnly", "true")
-	}
+	// The API defaults to takerOnly=true, so always send it explicitly
+	q.Set("takerOnly", strconv.FormatBool(params.TakerOnly))
 	if params.FilterType != "" {
 		q.Set("filterType", params.FilterType)
 	}
@@ -88,44 +114,17 @@ func (c *Client) GetTrades(ctx context.Context, params TradeParams) (*TradesResp
 	}
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	c.setAuthHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("401 Unauthorized (auth_mode=%s) - check credentials", c.authMode)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Try to decode as array first (actual API response)
-	var trades []Trade
-	if err := json.NewDecoder(resp.Body).Decode(&trades); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	var trades tradeList
+	if err := c.getJSON(ctx, c.tradesLimiter, c.tradesBreaker, c.tradesTimeout, "/trades", u, &trades); err != nil {
+		return nil, err
 	}
 
 	return &TradesResponse{Trades: trades, Count: len(trades)}, nil
 }
 
-// GetWalletFirstActivity fetches the earliest activity for a wallet
+// GetWalletFirstActivity fetches the earliest activity for a wallet. It
+// returns ErrNotFound when the wallet has no activity yet.
 func (c *Client) GetWalletFirstActivity(ctx context.Context, wallet string) (*ActivityEvent, error) {
-	// Rate limit
-	if err := c.activityLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
-
 	u, err := url.Parse(c.baseURL + "/activity")
 	if err != nil {
 		return nil, fmt.Errorf("parse URL: %w", err)
@@ -138,36 +137,13 @@ func (c *Client) GetWalletFirstActivity(ctx context.Context, wallet string) (*Ac
 	q.Set("limit", "1")
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	c.setAuthHeaders(req)
-
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("401 Unauthorized (auth_mode=%s) - check credentials", c.authMode)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Decode as array directly
 	var activities []ActivityEvent
-	if err := json.NewDecoder(resp.Body).Decode(&activities); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	if err := c.getJSON(ctx, c.activityLimiter, c.activityBreaker, c.activityTimeout, "/activity", u, &activities); err != nil {
+		return nil, err
 	}
 
 	if len(activities) == 0 {
-		return nil, fmt.Errorf("no activity found for wallet %s", wallet)
+		return nil, fmt.Errorf("no activity for wallet %s: %w", wallet, ErrNotFound)
 	}
 
 	return &activities[0], nil
@@ -175,11 +151,6 @@ func (c *Client) GetWalletFirstActivity(ctx context.Context, wallet string) (*Ac
 
 // GetWalletActivity fetches recent activity for a wallet with a limit
 func (c *Client) GetWalletActivity(ctx context.Context, wallet string, limit int) ([]ActivityEvent, error) {
-	// Rate limit
-	if err := c.activityLimiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
-
 	u, err := url.Parse(c.baseURL + "/activity")
 	if err != nil {
 		return nil, fmt.Errorf("parse URL: %w", err)
@@ -194,35 +165,136 @@ func (c *Client) GetWalletActivity(ctx context.Context, wallet string, limit int
 	}
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+	var activities []ActivityEvent
+	if err := c.getJSON(ctx, c.activityLimiter, c.activityBreaker, c.activityTimeout, "/activity", u, &activities); err != nil {
+		return nil, err
 	}
 
-	c.setAuthHeaders(req)
+	return activities, nil
+}
+
+// activityPageSize is the largest page /activity serves; activityPageMinTime
+// is how much of the context deadline must remain to start another page
+const (
+	activityPageSize    = 500
+	activityPageMinTime = 2 * time.Second
+)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
+// GetWalletActivityAll walks a wallet's activity newest first until the
+// history is exhausted or maxEvents (0 for no cap) have been collected. On
+// error, including a context deadline too close to fetch another page, the
+// events collected so far are returned with it.
+func (c *Client) GetWalletActivityAll(ctx context.Context, wallet string, maxEvents int) ([]ActivityEvent, error) {
+	var events []ActivityEvent
+	seen := make(map[string]bool)
+	for offset := 0; maxEvents <= 0 || len(events) < maxEvents; offset += activityPageSize {
+		if err := ctx.Err(); err != nil {
+			return events, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < activityPageMinTime {
+			return events, fmt.Errorf("stopped after %d events: %w", len(events), context.DeadlineExceeded)
+		}
+
+		u, err := url.Parse(c.baseURL + "/activity")
+		if err != nil {
+			return events, fmt.Errorf("parse URL: %w", err)
+		}
+
+		q := u.Query()
+		q.Set("user", wallet)
+		q.Set("sortBy", "timestamp")
+		q.Set("sortDirection", "DESC")
+		q.Set("limit", strconv.Itoa(activityPageSize))
+		q.Set("offset", strconv.Itoa(offset))
+		u.RawQuery = q.Encode()
+
+		var batch []ActivityEvent
+		if err := c.getJSON(ctx, c.activityLimiter, c.activityBreaker, c.activityTimeout, "/activity", u, &batch); err != nil {
+			return events, err
+		}
+
+		// Activity arriving between pages shifts older entries down, so a
+		// page can repeat the tail of the previous one
+		for _, event := range batch {
+			key := activityKey(event)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			events = append(events, event)
+			if maxEvents > 0 && len(events) >= maxEvents {
+				break
+			}
+		}
+		if len(batch) < activityPageSize {
+			break
+		}
+	}
+
+	return events, nil
+}
 
-	if resp.StatusCode == http.StatusUnauthorized {
-		return nil, fmt.Errorf("401 Unauthorized (auth_mode=%s) - check credentials", c.authMode)
+// activityKey identifies an activity entry across pages. A transaction can
+// hold several entries, so the hash alone is not enough.
+func activityKey(event ActivityEvent) string {
+	return fmt.Sprintf("%s|%s|%s|%d|%g", event.TransactionHash, event.Type, event.Asset, event.Timestamp, event.Size)
+}
+
+// positionsPageSize is the page size for /positions; maxPositionPages caps
+// how much of a very large portfolio is read
+const (
+	positionsPageSize = 500
+	maxPositionPages  = 10
+)
+
+// GetPositions fetches a wallet's current positions, following pagination
+func (c *Client) GetPositions(ctx context.Context, wallet string) ([]Position, error) {
+	var positions []Position
+	for page := 0; page < maxPositionPages; page++ {
+		u, err := url.Parse(c.baseURL + "/positions")
+		if err != nil {
+			return nil, fmt.Errorf("parse URL: %w", err)
+		}
+
+		q := u.Query()
+		q.Set("user", wallet)
+		q.Set("limit", strconv.Itoa(positionsPageSize))
+		q.Set("offset", strconv.Itoa(page*positionsPageSize))
+		u.RawQuery = q.Encode()
+
+		var batch []Position
+		if err := c.getJSON(ctx, c.positionsLimiter, c.positionsBreaker, c.activityTimeout, "/positions", u, &batch); err != nil {
+			return nil, err
+		}
+		positions = append(positions, batch...)
+		if len(batch) < positionsPageSize {
+			break
+		}
+	}
+
+	return positions, nil
+}
+
+// GetHolders fetches the largest holders of each outcome token in a market
+func (c *Client) GetHolders(ctx context.Context, conditionID string, limit int) ([]TokenHolders, error) {
+	u, err := url.Parse(c.baseURL + "/holders")
+	if err != nil {
+		return nil, fmt.Errorf("parse URL: %w", err)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	q := u.Query()
+	q.Set("market", conditionID)
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
 	}
+	u.RawQuery = q.Encode()
 
-	// Decode as array directly
-	var activities []ActivityEvent
-	if err := json.NewDecoder(resp.Body).Decode(&activities); err != nil {
-		return nil, fmt.Errorf("decode response: %w", err)
+	var holders []TokenHolders
+	if err := c.getJSON(ctx, c.holdersLimiter, c.holdersBreaker, c.activityTimeout, "/holders", u, &holders); err != nil {
+		return nil, err
 	}
 
-	return activities, nil
+	return holders, nil
 }
 
 func (c *Client) setAuthHeaders(req *http.Request) {
@@ -237,6 +309,9 @@ func (c *Client) setAuthHeaders(req *http.Request) {
 
 	// Add extra headers
 	for k, v := range c.extraHeaders {
+		if http.CanonicalHeaderKey(k) == "Accept-Encoding" {
+			continue // Would switch off transparent gzip decompression
+		}
 		req.Header.Set(k, v)
 	}
 }
@@ -0,0 +1,640 @@
+package dataapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/fixtures"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func newTestClient(baseURL string) *Client {
+	c, _ := NewClient(&config.Config{
+		DataAPIBaseURL:      baseURL,
+		DataAPIAuthMode:     config.AuthModeNone,
+		DataAPITradesRPS:    100,
+		DataAPIActivityRPS:  100,
+		DataAPIPositionsRPS: 100,
+		DataAPIHoldersRPS:   100,
+	}, nil, nil)
+	c.retry = retryPolicy{
+		maxAttempts:    4,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     5 * time.Millisecond,
+		budget:         time.Second,
+	}
+	return c
+}
+
+func TestGetTradesRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		failStatus   int
+		wantErr      bool
+		wantRequests int32
+		description  string
+	}{
+		{
+			name:         "recovers after two 502s",
+			failures:     2,
+			failStatus:   http.StatusBadGateway,
+			wantErr:      false,
+			wantRequests: 3,
+			description:  "Transient gateway errors are retried until the API answers",
+		},
+		{
+			name:         "recovers after 429",
+			failures:     1,
+			failStatus:   http.StatusTooManyRequests,
+			wantErr:      false,
+			wantRequests: 2,
+			description:  "Rate limiting is retried",
+		},
+		{
+			name:         "gives up after max attempts",
+			failures:     10,
+			failStatus:   http.StatusServiceUnavailable,
+			wantErr:      true,
+			wantRequests: 4,
+			description:  "Persistent server errors stop at the attempt cap",
+		},
+		{
+			name:         "client error not retried",
+			failures:     10,
+			failStatus:   http.StatusBadRequest,
+			wantErr:      true,
+			wantRequests: 1,
+			description:  "4xx responses other than 429 fail immediately",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= int32(tt.failures) {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				w.Write([]byte(`[{"proxyWallet":"0xabc","side":"BUY","size":10,"price":0.5}]`))
+			}))
+			defer srv.Close()
+
+			resp, err := newTestClient(srv.URL).GetTrades(context.Background(), TradeParams{Limit: 1})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err %v, want error %v\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d\nDescription: %s", got, tt.wantRequests, tt.description)
+			}
+			if !tt.wantErr && (resp == nil || resp.Count != 1) {
+				t.Errorf("got %+v, want 1 trade\nDescription: %s", resp, tt.description)
+			}
+		})
+	}
+}
+
+func TestGetWalletFirstActivityRetryBudget(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	c.retry.initialBackoff = 50 * time.Millisecond
+	c.retry.maxBackoff = 50 * time.Millisecond
+	c.retry.budget = 0
+
+	if _, err := c.GetWalletFirstActivity(context.Background(), "0xabc"); err == nil {
+		t.Errorf("got nil error, want the 502")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want 1: no retry fits an exhausted budget", got)
+	}
+}
+
+func TestGetTradesRetryHonorsContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		cancel()
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).GetTrades(ctx, TradeParams{}); err == nil {
+		t.Errorf("got nil error, want failure after cancellation")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want 1 after the context was cancelled", got)
+	}
+}
+
+func TestGetPositionsPagination(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/positions" || r.URL.Query().Get("user") != "0xabc" {
+			t.Errorf("got %s, want /positions for the wallet", r.URL)
+		}
+
+		// 500 + 500 + 120 positions across three pages
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		count := positionsPageSize
+		if offset >= 2*positionsPageSize {
+			count = 120
+		}
+		page := make([]Position, count)
+		for i := range page {
+			page[i] = Position{ConditionID: fmt.Sprintf("m%d", offset+i), Size: 10, CurrentValue: 5}
+		}
+		json.NewEncoder(w).Encode(page)
+	}))
+	defer srv.Close()
+
+	positions, err := newTestClient(srv.URL).GetPositions(context.Background(), "0xabc")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if len(positions) != 1120 {
+		t.Errorf("got %d positions, want 1120", len(positions))
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("got %d requests, want 3 pages", got)
+	}
+}
+
+func TestGetWalletActivityAll(t *testing.T) {
+	// 1,100 events served newest first. The second page starts one entry
+	// early, as if a new trade landed between requests.
+	all := make([]ActivityEvent, 1100)
+	for i := range all {
+		all[i] = ActivityEvent{TransactionHash: fmt.Sprintf("0x%d", i), Type: "TRADE", Timestamp: int64(len(all) - i)}
+	}
+	newServer := func(requests *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(requests, 1)
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			if offset > 0 {
+				offset--
+			}
+			end := min(offset+limit, len(all))
+			json.NewEncoder(w).Encode(all[offset:end])
+		}))
+	}
+
+	tests := []struct {
+		name         string
+		maxEvents    int
+		wantEvents   int
+		wantRequests int32
+		description  string
+	}{
+		{"exhausts history", 0, 1100, 3, "Pages are read until a short page, skipping the repeated boundary entry"},
+		{"stops at cap", 600, 600, 2, "The cap ends paging early"},
+		{"cap within first page", 10, 10, 1, "A small cap needs a single page"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := newServer(&requests)
+			defer srv.Close()
+
+			events, err := newTestClient(srv.URL).GetWalletActivityAll(context.Background(), "0xabc", tt.maxEvents)
+			if err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			if len(events) != tt.wantEvents {
+				t.Errorf("got %d events, want %d\nDescription: %s", len(events), tt.wantEvents, tt.description)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d\nDescription: %s", got, tt.wantRequests, tt.description)
+			}
+			for i, event := range events {
+				if event.TransactionHash != all[i].TransactionHash {
+					t.Fatalf("event %d is %s, want %s\nDescription: %s", i, event.TransactionHash, all[i].TransactionHash, tt.description)
+				}
+			}
+		})
+	}
+
+	t.Run("empty history", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[]`))
+		}))
+		defer srv.Close()
+
+		events, err := newTestClient(srv.URL).GetWalletActivityAll(context.Background(), "0xabc", 0)
+		if err != nil || len(events) != 0 {
+			t.Errorf("got %d events, %v, want none and no error", len(events), err)
+		}
+	})
+
+	t.Run("deadline too close", func(t *testing.T) {
+		var requests int32
+		srv := newServer(&requests)
+		defer srv.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), activityPageMinTime/2)
+		defer cancel()
+		events, err := newTestClient(srv.URL).GetWalletActivityAll(ctx, "0xabc", 0)
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got %v, want a deadline error", err)
+		}
+		if len(events) != 0 || atomic.LoadInt32(&requests) != 0 {
+			t.Errorf("got %d events from %d requests, want no page started", len(events), requests)
+		}
+	})
+}
+
+func TestGetTradesGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+			t.Errorf("got Accept-Encoding %q, want gzip requested", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(`[{"proxyWallet":"0xabc"},{"proxyWallet":"0xdef"}]`))
+		gz.Close()
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	c.extraHeaders = map[string]string{"accept-encoding": "identity"}
+	resp, err := c.GetTrades(context.Background(), TradeParams{})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if resp.Count != 2 || resp.Trades[1].ProxyWallet != "0xdef" {
+		t.Errorf("got %+v, want both trades decompressed", resp.Trades)
+	}
+}
+
+func TestGetTradesResponseTooLarge(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write(largeTradesJSON(1000))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	c.maxResponseBytes = 1024
+	if _, err := c.GetTrades(context.Background(), TradeParams{}); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("got %v, want ErrResponseTooLarge", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want 1: an oversized response is not retried", got)
+	}
+
+	// A body exactly at the cap still decodes
+	body := []byte(`[{"proxyWallet":"0xabc"}]`)
+	var trades tradeList
+	if err := trades.decodeStream(json.NewDecoder(limitBody(bytes.NewReader(body), int64(len(body))))); err != nil || len(trades) != 1 {
+		t.Errorf("got %d trades, %v, want 1 trade at the cap", len(trades), err)
+	}
+}
+
+func TestTradeListDecodeStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantTrades  int
+		wantErr     bool
+		description string
+	}{
+		{"array", `[{"proxyWallet":"0xabc"},{"proxyWallet":"0xdef"}]`, 2, false, "Each element is decoded in turn"},
+		{"empty", `[]`, 0, false, "An empty page has no trades"},
+		{"null", `null`, 0, false, "null decodes like Decode would, to no trades"},
+		{"object", `{"error":"bad"}`, 0, true, "A non-array body is an error"},
+		{"truncated", `[{"proxyWallet":"0xabc"},`, 1, true, "A cut-off array is an error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trades := tradeList{{ProxyWallet: "stale"}} // Left over from a failed attempt
+			err := trades.decodeStream(json.NewDecoder(strings.NewReader(tt.body)))
+			if (err != nil) != tt.wantErr || len(trades) != tt.wantTrades {
+				t.Errorf("got %d trades, err %v, want %d trades, error %v\nDescription: %s",
+					len(trades), err, tt.wantTrades, tt.wantErr, tt.description)
+			}
+		})
+	}
+}
+
+// largeTradesJSON returns a /trades page of n realistic trades
+func largeTradesJSON(n int) []byte {
+	trades := make([]Trade, n)
+	for i := range trades {
+		trades[i] = Trade{
+			ProxyWallet:     fmt.Sprintf("0x%040d", i),
+			Side:            "BUY",
+			ConditionID:     fmt.Sprintf("0x%064d", i%50),
+			Size:            1000 + float64(i),
+			Price:           0.42,
+			Timestamp:       int64(1700000000 + i),
+			Title:           "Will the example market resolve YES by the end of the year?",
+			Slug:            "example-market",
+			EventSlug:       "example-event",
+			Outcome:         "Yes",
+			TransactionHash: fmt.Sprintf("0x%064d", i),
+		}
+	}
+	body, _ := json.Marshal(trades)
+	return body
+}
+
+// BenchmarkDecodeTrades compares buffering a 10,000-trade page through
+// Decode with decoding it element by element
+func BenchmarkDecodeTrades(b *testing.B) {
+	body := largeTradesJSON(10000)
+
+	b.Run("buffered", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var trades []Trade
+			if err := json.NewDecoder(bytes.NewReader(body)).Decode(&trades); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("streamed", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var trades tradeList
+			if err := trades.decodeStream(json.NewDecoder(bytes.NewReader(body))); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestActivityTimeoutPerAttempt(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+			return
+		}
+		w.Write([]byte(`[{"proxyWallet":"0xabc","timestamp":1700000000}]`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	c.activityTimeout = 50 * time.Millisecond
+	c.tradesTimeout = time.Minute
+
+	start := time.Now()
+	event, err := c.GetWalletFirstActivity(context.Background(), "0xabc")
+	if err != nil || event.Timestamp != 1700000000 {
+		t.Fatalf("got %+v, %v, want the activity from the retry", event, err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 2 {
+		t.Errorf("got %d requests, want 2: the hung attempt times out and is retried", got)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("took %s, want the activity timeout rather than the trades timeout to apply", elapsed)
+	}
+}
+
+// histogramCount scrapes the default registry for the number of
+// observations of a histogram with the given api and endpoint labels
+func histogramCount(t *testing.T, name, api, endpoint string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["api"] == api && labels["endpoint"] == endpoint {
+				return m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+	return 0
+}
+
+func TestRequestMetrics(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	success := metrics.APIRequests.WithLabelValues("data", "/holders", "success")
+	failure := metrics.APIRequests.WithLabelValues("data", "/holders", "error")
+	retry := metrics.APIRequests.WithLabelValues("data", "/holders", "retry")
+	before := []float64{testutil.ToFloat64(success), testutil.ToFloat64(failure), testutil.ToFloat64(retry)}
+	durationsBefore := histogramCount(t, "insiderwatch_api_request_duration_seconds", "data", "/holders")
+	waitsBefore := histogramCount(t, "insiderwatch_api_rate_limit_wait_seconds", "data", "/holders")
+
+	if _, err := newTestClient(srv.URL).GetHolders(context.Background(), "0xcond", 10); err != nil {
+		t.Fatalf("got %v, want success after the retry", err)
+	}
+
+	after := []float64{testutil.ToFloat64(success), testutil.ToFloat64(failure), testutil.ToFloat64(retry)}
+	for i, status := range []string{"success", "error", "retry"} {
+		if after[i]-before[i] != 1 {
+			t.Errorf("got %s counter +%.0f, want +1", status, after[i]-before[i])
+		}
+	}
+	if got := histogramCount(t, "insiderwatch_api_request_duration_seconds", "data", "/holders") - durationsBefore; got != 2 {
+		t.Errorf("got %d request durations, want one per attempt", got)
+	}
+	if got := histogramCount(t, "insiderwatch_api_rate_limit_wait_seconds", "data", "/holders") - waitsBefore; got != 2 {
+		t.Errorf("got %d rate limiter waits, want one per attempt", got)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		expectedErr    error
+		wantRetryAfter time.Duration
+		description    string
+	}{
+		{"not found", http.StatusNotFound, "", ErrNotFound, 0, "404 is a missing record"},
+		{"unauthorized", http.StatusUnauthorized, "", ErrUnauthorized, 0, "401 means bad credentials"},
+		{"forbidden", http.StatusForbidden, "", ErrUnauthorized, 0, "403 is treated as unauthorized"},
+		{"rate limited", http.StatusTooManyRequests, "7", ErrRateLimited, 7 * time.Second, "429 carries the Retry-After delay"},
+		{"server error", http.StatusBadGateway, "", ErrServerError, 0, "5xx is a server error"},
+		{"bad request", http.StatusBadRequest, "", nil, 0, "Other statuses match no sentinel"},
+	}
+
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrServerError}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := newTestClient(srv.URL)
+			c.retry.maxAttempts = 1
+			_, err := c.GetTrades(context.Background(), TradeParams{})
+			if err == nil {
+				t.Fatalf("got nil error, want status %d\nDescription: %s", tt.status, tt.description)
+			}
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.expectedErr; got != want {
+					t.Errorf("got errors.Is(%v, %v) = %v, want %v\nDescription: %s", err, sentinel, got, want, tt.description)
+				}
+			}
+			var rl *RateLimitedError
+			if errors.As(err, &rl) && rl.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("got RetryAfter %s, want %s\nDescription: %s", rl.RetryAfter, tt.wantRetryAfter, tt.description)
+			}
+		})
+	}
+}
+
+func TestGetWalletFirstActivityNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("[]"))
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).GetWalletFirstActivity(context.Background(), "0xabc"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound for a wallet without activity", err)
+	}
+}
+
+// Trades fixtures are recorded from a market with steady volume, three
+// trades per page
+var tradeFixtures = []fixtures.Fixture{
+	{Name: "trades_page0.json", URL: "https://data-api.polymarket.com/trades?limit=3&takerOnly=true&market=0x1d7a3f0c5c8b2e2b6f0f3c6b4e0f7a2d9c1e5b8a7f6d3c2b1a0e9f8d7c6b5a49"},
+	{Name: "trades_page1.json", URL: "https://data-api.polymarket.com/trades?limit=3&offset=3&takerOnly=true&market=0x1d7a3f0c5c8b2e2b6f0f3c6b4e0f7a2d9c1e5b8a7f6d3c2b1a0e9f8d7c6b5a49"},
+}
+
+func TestFixtureGetTradesPagination(t *testing.T) {
+	bodies := fixtures.Load(t, tradeFixtures...)
+	bodies["empty"] = []byte("[]")
+	srv := fixtures.Serve(t, bodies, func(r *http.Request) string {
+		switch r.URL.Query().Get("offset") {
+		case "":
+			return "trades_page0.json"
+		case "3":
+			return "trades_page1.json"
+		}
+		return "empty"
+	})
+
+	c := newTestClient(srv.URL)
+	params := TradeParams{Limit: 3, TakerOnly: true}
+	var all []Trade
+	for page := 0; page < 5; page++ {
+		resp, err := c.GetTrades(context.Background(), params)
+		if err != nil {
+			t.Fatalf("page %d: got %v, want no error", page, err)
+		}
+		all = append(all, resp.Trades...)
+		if len(resp.Trades) < params.Limit {
+			break
+		}
+		params.Offset += len(resp.Trades)
+	}
+
+	if len(all) != 5 {
+		t.Fatalf("got %d trades, want 5 across both pages", len(all))
+	}
+	seen := make(map[string]bool)
+	for i, trade := range all {
+		if seen[trade.TransactionHash] {
+			t.Errorf("got trade %s twice", trade.TransactionHash)
+		}
+		seen[trade.TransactionHash] = true
+		if trade.ProxyWallet == "" || trade.ConditionID == "" || trade.Size <= 0 || trade.Timestamp == 0 {
+			t.Errorf("trade %d: got %+v, want every field decoded", i, trade)
+		}
+		if i > 0 && trade.Timestamp > all[i-1].Timestamp {
+			t.Errorf("trade %d: got timestamp %d after %d, want newest first", i, trade.Timestamp, all[i-1].Timestamp)
+		}
+	}
+}
+
+func TestFixtureAuthHeaders(t *testing.T) {
+	bodies := fixtures.Load(t, tradeFixtures[0])
+
+	tests := []struct {
+		name        string
+		mode        config.AuthMode
+		extra       map[string]string
+		expected    map[string]string
+		description string
+	}{
+		{"none", config.AuthModeNone, nil, map[string]string{"Authorization": "", "X-API-KEY": ""}, "No credentials are sent without auth"},
+		{"bearer", config.AuthModeBearer, nil, map[string]string{"Authorization": "Bearer test-token", "X-API-KEY": ""}, "Bearer mode sends only the token"},
+		{"api key", config.AuthModeAPIKey, nil, map[string]string{"Authorization": "", "X-API-KEY": "test-key"}, "API key mode sends only the key"},
+		{"extra headers", config.AuthModeNone, map[string]string{"X-Team": "alerts", "Accept-Encoding": "identity"}, map[string]string{"X-Team": "alerts", "Accept-Encoding": "gzip"}, "Extra headers are added, except Accept-Encoding"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got http.Header
+			srv := fixtures.Serve(t, bodies, func(r *http.Request) string {
+				got = r.Header.Clone()
+				return "trades_page0.json"
+			})
+
+			c := newTestClient(srv.URL)
+			c.authMode = tt.mode
+			c.bearerToken = "test-token"
+			c.apiKey = "test-key"
+			c.extraHeaders = tt.extra
+
+			resp, err := c.GetTrades(context.Background(), TradeParams{Limit: 3})
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if len(resp.Trades) != 3 {
+				t.Errorf("got %d trades, want the fixture's 3", len(resp.Trades))
+			}
+			for header, want := range tt.expected {
+				if got.Get(header) != want {
+					t.Errorf("got %s %q, want %q\nDescription: %s", header, got.Get(header), want, tt.description)
+				}
+			}
+		})
+	}
+}
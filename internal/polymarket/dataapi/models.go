@@ -14,8 +14,15 @@ type Trade struct {
 	EventSlug       string  `json:"eventSlug"`
 	TransactionHash string  `json:"transactionHash"`
 	USDCSize        float64 `json:"usdcSize"` // Preferred notional
+	Role            string  `json:"-"`        // taker or maker; set by the processor, not the API
 }
 
+// Trade roles
+const (
+	RoleTaker = "taker"
+	RoleMaker = "maker"
+)
+
 // ActivityEvent represents an activity event for a wallet
 type ActivityEvent struct {
 	ProxyWallet          string  `json:"proxyWallet"`
@@ -49,6 +56,39 @@ func (a *ActivityEvent) GetFromAddress() string {
 	return ""
 }
 
+// Position is a wallet's current holding in one market outcome
+type Position struct {
+	ProxyWallet  string  `json:"proxyWallet"`
+	Asset        string  `json:"asset"` // Outcome token ID
+	ConditionID  string  `json:"conditionId"`
+	Outcome      string  `json:"outcome"`
+	Size         float64 `json:"size"` // Shares held
+	AvgPrice     float64 `json:"avgPrice"`
+	InitialValue float64 `json:"initialValue"`
+	CurrentValue float64 `json:"currentValue"` // Size at the current price, in USD
+	CashPnl      float64 `json:"cashPnl"`
+	CurPrice     float64 `json:"curPrice"`
+	Redeemable   bool    `json:"redeemable"` // Market resolved; the position only awaits redemption
+	Title        string  `json:"title"`
+	Slug         string  `json:"slug"`
+	EventSlug    string  `json:"eventSlug"`
+}
+
+// TokenHolders lists the largest holders of one outcome token
+type TokenHolders struct {
+	Token   string   `json:"token"`
+	Holders []Holder `json:"holders"`
+}
+
+// Holder is one wallet's balance of an outcome token
+type Holder struct {
+	ProxyWallet  string  `json:"proxyWallet"`
+	Amount       float64 `json:"amount"` // Shares held
+	OutcomeIndex int     `json:"outcomeIndex"`
+	Name         string  `json:"name"`
+	Pseudonym    string  `json:"pseudonym"`
+}
+
 // TradesResponse wraps the trades API response
 type TradesResponse struct {
 	Trades []Trade `json:"data"`
@@ -0,0 +1,210 @@
+package dataapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/circuit"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+)
+
+// retryPolicy bounds how long a GET keeps retrying transient failures
+type retryPolicy struct {
+	maxAttempts    int           // Total attempts, including the first
+	initialBackoff time.Duration // Delay before the first retry; doubles after each
+	maxBackoff     time.Duration
+	budget         time.Duration // Give up rather than start a retry past this much elapsed time
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts:    4,
+	initialBackoff: 500 * time.Millisecond,
+	maxBackoff:     8 * time.Second,
+	budget:         30 * time.Second,
+}
+
+// Errors for API responses by status, so callers can tell a missing
+// record from a transient failure. Use errors.Is; a 429 also matches
+// errors.As with *RateLimitedError.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServerError  = errors.New("server error")
+)
+
+// RateLimitedError is a 429 response and how long the API asked us to wait
+type RateLimitedError struct {
+	RetryAfter time.Duration // 0 when the response didn't say
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	return "rate limited"
+}
+
+// Is makes a RateLimitedError match ErrRateLimited
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// statusError is a non-200 response from the API
+type statusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+	requestID  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d (request_id=%s): %s", e.code, e.requestID, e.body)
+}
+
+// Unwrap classifies the status as ErrNotFound, ErrUnauthorized, a
+// RateLimitedError, or ErrServerError; other statuses match none of them
+func (e *statusError) Unwrap() error {
+	switch {
+	case e.code == http.StatusNotFound:
+		return ErrNotFound
+	case e.code == http.StatusUnauthorized || e.code == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.code == http.StatusTooManyRequests:
+		return &RateLimitedError{RetryAfter: e.retryAfter}
+	case e.code >= 500:
+		return ErrServerError
+	}
+	return nil
+}
+
+// getJSON performs an idempotent GET and decodes the JSON body into out.
+// 5xx, 429, and network errors are retried with exponential backoff and
+// jitter until the attempts or time budget run out or ctx is cancelled.
+// While the endpoint's circuit is open it fails fast with circuit.ErrOpen.
+// Each attempt is bounded by timeout.
+func (c *Client) getJSON(ctx context.Context, limiter *ratelimit.Limiter, breaker *circuit.Breaker, timeout time.Duration, endpoint string, u *url.URL, out interface{}) error {
+	if err := breaker.Allow(); err != nil {
+		return err
+	}
+	err := c.getWithRetry(ctx, limiter, timeout, endpoint, u, out)
+	if ctx.Err() == nil {
+		breaker.Record(err != nil && retryable(err))
+	}
+	return err
+}
+
+func (c *Client) getWithRetry(ctx context.Context, limiter *ratelimit.Limiter, timeout time.Duration, endpoint string, u *url.URL, out interface{}) error {
+	start := time.Now()
+	backoff := c.retry.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		waitStart := time.Now()
+		if err := limiter.Wait(ctx); err != nil {
+			return fmt.Errorf("rate limit wait: %w", err)
+		}
+		if err := c.budget.Wait(ctx); err != nil {
+			return fmt.Errorf("shared rate limit wait: %w", err)
+		}
+		metrics.RecordRateLimitWait("data", endpoint, time.Since(waitStart))
+
+		attemptStart := time.Now()
+		err := c.doGet(ctx, timeout, u, out)
+		metrics.RecordAPIRequest("data", endpoint, time.Since(attemptStart), err)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil || !retryable(err) || attempt >= c.retry.maxAttempts {
+			return err
+		}
+
+		// Full jitter keeps concurrent workers from retrying in lockstep
+		delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > delay {
+			delay = min(se.retryAfter, c.retry.maxBackoff)
+		}
+		if time.Since(start)+delay > c.retry.budget {
+			return err
+		}
+
+		metrics.RecordAPIRetry("data", endpoint)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		backoff = min(backoff*2, c.retry.maxBackoff)
+	}
+}
+
+func (c *Client) doGet(ctx context.Context, timeout time.Duration, u *url.URL, out interface{}) error {
+	// The timeout covers reading the body too, so it is only cancelled once
+	// decoding is done
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	c.setAuthHeaders(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("401 %w (auth_mode=%s, request_id=%s) - check credentials", ErrUnauthorized, c.authMode, transport.RequestID(resp))
+	}
+
+	body := limitBody(resp.Body, c.maxResponseBytes)
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(body)
+		se := &statusError{code: resp.StatusCode, body: string(body), requestID: transport.RequestID(resp)}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			se.retryAfter = time.Duration(secs) * time.Second
+		}
+		return se
+	}
+
+	dec := json.NewDecoder(body)
+	if s, ok := out.(streamDecoder); ok {
+		err = s.decodeStream(dec)
+	} else {
+		err = dec.Decode(out)
+	}
+	if err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// retryable reports whether a failed GET may succeed if repeated: server
+// errors, rate limiting, and transport failures
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
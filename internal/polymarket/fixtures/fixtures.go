@@ -0,0 +1,152 @@
+// Package fixtures records live Polymarket API responses into testdata and
+// replays them from an httptest.Server, so client tests run against real
+// response shapes without network access.
+//
+// Tests replay the files checked in under their package's testdata. Run
+// them with -record to fetch each fixture from its live URL first:
+//
+//	go test ./internal/polymarket/dataapi -run Fixture -record
+//
+// Recorded responses are sanitized before they are written; see Sanitize.
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+var record = flag.Bool("record", false, "fetch fixtures from the live APIs and rewrite testdata")
+
+// Fixture is a recorded API response: the file it is kept in under testdata
+// and the live URL it is recorded from
+type Fixture struct {
+	Name string
+	URL  string
+}
+
+// Load returns the bodies of fixtures by name. In -record mode each one is
+// fetched from its URL, sanitized, and written to testdata first.
+func Load(t *testing.T, fixtures ...Fixture) map[string][]byte {
+	t.Helper()
+	bodies := make(map[string][]byte, len(fixtures))
+	for _, f := range fixtures {
+		path := filepath.Join("testdata", f.Name)
+		if *record {
+			if err := recordFixture(path, f.URL); err != nil {
+				t.Fatalf("record %s: %v", f.Name, err)
+			}
+		}
+		body, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read fixture: %v", err)
+		}
+		bodies[f.Name] = body
+	}
+	return bodies
+}
+
+// Serve starts a server that answers each request with the fixture route
+// names for it, or 404 when route returns "". It is closed when the test
+// ends.
+func Serve(t *testing.T, bodies map[string][]byte, route func(r *http.Request) string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := bodies[route(r)]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// recordFixture fetches a live URL without credentials and writes the
+// sanitized response to path
+func recordFixture(path, liveURL string) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(liveURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("read body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, liveURL)
+	}
+
+	clean, err := Sanitize(body)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, clean, 0o644)
+}
+
+// secretKey matches JSON fields that may hold credentials
+var secretKey = regexp.MustCompile(`(?i)(secret|password|api[_-]?key|authorization|signature|bearer|access[_-]?token|auth[_-]?token)`)
+
+// secretEnv lists the settings whose values must never reach a fixture
+var secretEnv = []string{"DATA_API_BEARER_TOKEN", "DATA_API_API_KEY", "SUBGRAPH_API_KEY"}
+
+// Redacted replaces secrets in sanitized fixtures
+const Redacted = "REDACTED"
+
+// Sanitize prepares a response for checking in: string values of fields
+// named like credentials are redacted, as is any occurrence of a configured
+// secret, and the JSON is indented for readable diffs
+func Sanitize(body []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	out, err := json.MarshalIndent(redact(v), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode fixture: %w", err)
+	}
+	for _, name := range secretEnv {
+		if secret := os.Getenv(name); secret != "" {
+			out = bytes.ReplaceAll(out, []byte(secret), []byte(Redacted))
+		}
+	}
+	return append(out, '\n'), nil
+}
+
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if _, isString := val.(string); isString && secretKey.MatchString(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = redact(val)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return v
+}
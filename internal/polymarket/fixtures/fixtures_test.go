@@ -0,0 +1,42 @@
+package fixtures
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitize(t *testing.T) {
+	t.Setenv("DATA_API_BEARER_TOKEN", "live-bearer-123")
+
+	body := []byte(`{"data":[{"apiKey":"k1","clobTokenIds":"[\"1\",\"2\"]","nested":{"Authorization":"Bearer x"},"bio":"token live-bearer-123"}],"size":1.50}`)
+	got, err := Sanitize(body)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	out := string(got)
+
+	tests := []struct {
+		text        string
+		present     bool
+		description string
+	}{
+		{`"apiKey": "REDACTED"`, true, "Credential-named fields are redacted"},
+		{`"Authorization": "REDACTED"`, true, "Nested credential fields are redacted"},
+		{`"clobTokenIds": "[\"1\",\"2\"]"`, true, "Token IDs are data, not credentials"},
+		{"live-bearer-123", false, "Configured secrets are scrubbed wherever they appear"},
+		{`"size": 1.50`, true, "Numbers keep their original form"},
+		{"\n  \"data\"", true, "The JSON is indented"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if strings.Contains(out, tt.text) != tt.present {
+				t.Errorf("got %s, want %q present=%v\nDescription: %s", out, tt.text, tt.present, tt.description)
+			}
+		})
+	}
+
+	if _, err := Sanitize([]byte("<html>rate limited</html>")); err == nil {
+		t.Errorf("got no error for a non-JSON body, want one")
+	}
+}
@@ -0,0 +1,38 @@
+package gammaapi
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrResponseTooLarge means a response body exceeded API_MAX_RESPONSE_MB.
+// The request is not retried; the same response would be just as large.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// limitBody caps how much of a response body is read. Unlike io.LimitReader
+// it fails instead of truncating, so a cut-off body can't parse as valid. A
+// max of 0 or less means no limit.
+func limitBody(r io.Reader, max int64) io.Reader {
+	if max <= 0 {
+		return r
+	}
+	return &limitedBody{r: r, remaining: max}
+}
+
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	// Read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		return 0, ErrResponseTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
@@ -3,14 +3,19 @@ package gammaapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/liamashdown/insiderwatch/internal/circuit"
 	"github.com/liamashdown/insiderwatch/internal/config"
 	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+	"github.com/sirupsen/logrus"
 )
 
 // Client handles communication with the Polymarket Gamma API
@@ -18,136 +23,233 @@ type Client struct {
 	baseURL    string
 	httpClient *http.Client
 	limiter    *ratelimit.Limiter
+	budget     *ratelimit.Budget // Shared with the other Polymarket clients; nil when disabled
+	breaker    *circuit.Breaker
+	timeout    time.Duration // Per attempt, excluding rate limit waits
+	retry      retryPolicy
+	maxBody    int64 // Largest decompressed response read; 0 for no limit
 }
 
-// NewClient creates a new Gamma API client
-func NewClient(cfg *config.Config) *Client {
+// NewClient creates a new Gamma API client that also draws on budget, which
+// may be nil. It fails when the proxy or CA bundle can't be set up.
+func NewClient(cfg *config.Config, budget *ratelimit.Budget, log *logrus.Logger) (*Client, error) {
+	base, err := transport.NewBase(cfg.GammaAPIProxyURL, cfg.APICABundle)
+	if err != nil {
+		return nil, fmt.Errorf("gamma API transport: %w", err)
+	}
+	// The default transport requests gzip and decompresses transparently.
+	// The timeout is applied per request rather than on the http.Client.
 	return &Client{
 		baseURL:    cfg.GammaAPIBaseURL,
-		httpClient: &http.Client{Timeout: 30 * time.Second},
-		limiter:    ratelimit.New(cfg.GammaAPIMarketsRPS),
-	}
+		httpClient: &http.Client{Transport: transport.New("gamma", base, cfg.APISlowRequestThreshold, log)},
+		limiter:    ratelimit.New(cfg.GammaAPIMarketsRPS, cfg.GammaAPIMarketsBurst),
+		budget:     budget,
+		breaker:    circuit.New("gamma", cfg.CircuitBreakerFailures, time.Duration(cfg.CircuitBreakerCooldownSec)*time.Second, log),
+		timeout:    cfg.GammaAPITimeout,
+		retry:      defaultRetryPolicy,
+		maxBody:    int64(cfg.APIMaxResponseMB) << 20,
+	}, nil
 }
 
-// GetMarketByConditionID fetches market details by condition ID
+// GetMarketByConditionID fetches market details by condition ID. It returns
+// ErrMarketNotFound when Gamma has no such market.
 func (c *Client) GetMarketByConditionID(ctx context.Context, conditionID string) (*Market, error) {
-	// Rate limit
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
+	market, _, err := c.GetMarketByConditionIDIfModified(ctx, conditionID, Validators{})
+	return market, err
+}
 
+// GetMarketByConditionIDIfModified is GetMarketByConditionID as a
+// conditional request. Given the validators of an earlier response it
+// returns ErrNotModified, without downloading the market, when the market
+// hasn't changed since. It returns the validators to send next time.
+func (c *Client) GetMarketByConditionIDIfModified(ctx context.Context, conditionID string, v Validators) (*Market, Validators, error) {
 	u, err := url.Parse(c.baseURL + "/markets")
 	if err != nil {
-		return nil, fmt.Errorf("parse URL: %w", err)
+		return nil, Validators{}, fmt.Errorf("parse URL: %w", err)
 	}
 
 	q := u.Query()
 	q.Set("condition_ids", conditionID)
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	// Gamma API is public - no auth headers needed per spec
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
-	}
-
 	// Response can be either array or single market
-	body, err := io.ReadAll(resp.Body)
+	body, next, err := c.getConditional(ctx, "/markets", u.String(), v)
 	if err != nil {
-		return nil, fmt.Errorf("read body: %w", err)
+		return nil, next, err
 	}
 
 	// Try array first
 	var markets []Market
 	if err := json.Unmarshal(body, &markets); err == nil {
 		if len(markets) > 0 {
-			return &markets[0], nil
+			return &markets[0], next, nil
 		}
-		return nil, fmt.Errorf("no market found for condition_id %s", conditionID)
+		return nil, Validators{}, fmt.Errorf("condition_id %s: %w", conditionID, ErrMarketNotFound)
 	}
 
 	// Try single market
 	var market Market
 	if err := json.Unmarshal(body, &market); err == nil {
-		return &market, nil
+		return &market, next, nil
 	}
 
-	return nil, fmt.Errorf("failed to decode market response")
+	return nil, Validators{}, fmt.Errorf("failed to decode market response")
 }
 
-// GetMarketBySlug fetches market details by slug
-func (c *Client) GetMarketBySlug(ctx context.Context, slug string) (*Market, error) {
-	// Rate limit
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
+// Batch sizes keep batched /markets and /events URLs well under common
+// length limits
+const (
+	conditionIDBatchSize = 20
+	eventIDBatchSize     = 50
+)
+
+// GetMarketsByConditionIDs fetches markets for many condition IDs, batching
+// the requests. IDs Gamma doesn't return are missing from the map. On error
+// the markets fetched by earlier batches are returned with it.
+func (c *Client) GetMarketsByConditionIDs(ctx context.Context, conditionIDs []string) (map[string]*Market, error) {
+	markets := make(map[string]*Market, len(conditionIDs))
+	for start := 0; start < len(conditionIDs); start += conditionIDBatchSize {
+		batch := conditionIDs[start:min(start+conditionIDBatchSize, len(conditionIDs))]
+
+		u, err := url.Parse(c.baseURL + "/markets")
+		if err != nil {
+			return markets, fmt.Errorf("parse URL: %w", err)
+		}
+		q := u.Query()
+		for _, id := range batch {
+			q.Add("condition_ids", id)
+		}
+		q.Set("limit", strconv.Itoa(len(batch)))
+		u.RawQuery = q.Encode()
 
-	u := c.baseURL + "/markets/slug/" + url.PathEscape(slug)
+		body, err := c.get(ctx, "/markets", u.String())
+		if err != nil {
+			return markets, err
+		}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		var found []Market
+		if err := json.Unmarshal(body, &found); err != nil {
+			return markets, fmt.Errorf("decode response: %w", err)
+		}
+
+		// Key by the requested ID; Gamma may not preserve its case
+		for i := range found {
+			for _, id := range batch {
+				if strings.EqualFold(found[i].ConditionID, id) {
+					markets[id] = &found[i]
+				}
+			}
+		}
 	}
 
-	resp, err := c.httpClient.Do(req)
+	return markets, nil
+}
+
+// ListRecentMarkets fetches a page of open markets, most recently created
+// first. Offset counts markets, so the next page starts at offset+limit.
+func (c *Client) ListRecentMarkets(ctx context.Context, limit, offset int) ([]Market, error) {
+	u, err := url.Parse(c.baseURL + "/markets")
 	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+		return nil, fmt.Errorf("parse URL: %w", err)
 	}
-	defer resp.Body.Close()
+	q := u.Query()
+	q.Set("closed", "false")
+	q.Set("order", "createdAt")
+	q.Set("ascending", "false")
+	q.Set("limit", strconv.Itoa(limit))
+	q.Set("offset", strconv.Itoa(offset))
+	u.RawQuery = q.Encode()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	body, err := c.get(ctx, "/markets", u.String())
+	if err != nil {
+		return nil, err
 	}
 
-	var market Market
-	if err := json.NewDecoder(resp.Body).Decode(&market); err != nil {
+	var markets []Market
+	if err := json.Unmarshal(body, &markets); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
+	return markets, nil
+}
 
-	return &market, nil
+// GetMarketBySlug fetches market details by slug
+func (c *Client) GetMarketBySlug(ctx context.Context, slug string) (*Market, error) {
+	return c.getMarket(ctx, "/markets/slug", c.baseURL+"/markets/slug/"+url.PathEscape(slug))
 }
 
 // GetMarketByID fetches market details by ID
 func (c *Client) GetMarketByID(ctx context.Context, id string) (*Market, error) {
-	// Rate limit
-	if err := c.limiter.Wait(ctx); err != nil {
-		return nil, fmt.Errorf("rate limit wait: %w", err)
-	}
-
-	u := c.baseURL + "/markets/" + url.PathEscape(id)
+	return c.getMarket(ctx, "/markets/id", c.baseURL+"/markets/"+url.PathEscape(id))
+}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+func (c *Client) getMarket(ctx context.Context, endpoint, u string) (*Market, error) {
+	body, err := c.get(ctx, endpoint, u)
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%s: %w", u, ErrMarketNotFound)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, err
 	}
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("execute request: %w", err)
+	var market Market
+	if err := json.Unmarshal(body, &market); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+	return &market, nil
+}
+
+// GetEventBySlug fetches event details by slug. It returns ErrEventNotFound
+// when Gamma has no such event.
+func (c *Client) GetEventBySlug(ctx context.Context, slug string) (*Event, error) {
+	body, err := c.get(ctx, "/events/slug", c.baseURL+"/events/slug/"+url.PathEscape(slug))
+	if errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("event %s: %w", slug, ErrEventNotFound)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	var market Market
-	if err := json.NewDecoder(resp.Body).Decode(&market); err != nil {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
 
-	return &market, nil
+	return &event, nil
+}
+
+// GetEventsByIDs fetches events by ID, batching the requests. IDs Gamma
+// doesn't return are missing from the map. On error the events fetched by
+// earlier batches are returned with it.
+func (c *Client) GetEventsByIDs(ctx context.Context, ids []string) (map[string]*Event, error) {
+	events := make(map[string]*Event, len(ids))
+	for start := 0; start < len(ids); start += eventIDBatchSize {
+		batch := ids[start:min(start+eventIDBatchSize, len(ids))]
+
+		u, err := url.Parse(c.baseURL + "/events")
+		if err != nil {
+			return events, fmt.Errorf("parse URL: %w", err)
+		}
+		q := u.Query()
+		for _, id := range batch {
+			q.Add("id", id)
+		}
+		q.Set("limit", strconv.Itoa(len(batch)))
+		u.RawQuery = q.Encode()
+
+		body, err := c.get(ctx, "/events", u.String())
+		if err != nil {
+			return events, err
+		}
+
+		var found []Event
+		if err := json.Unmarshal(body, &found); err != nil {
+			return events, fmt.Errorf("decode response: %w", err)
+		}
+		for i := range found {
+			events[found[i].ID] = &found[i]
+		}
+	}
+
+	return events, nil
 }
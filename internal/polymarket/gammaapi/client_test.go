@@ -0,0 +1,473 @@
+package gammaapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/fixtures"
+)
+
+func newTestClient(baseURL string) *Client {
+	c, _ := NewClient(&config.Config{GammaAPIBaseURL: baseURL, GammaAPIMarketsRPS: 100}, nil, nil)
+	c.retry = retryPolicy{
+		maxAttempts:    4,
+		initialBackoff: time.Millisecond,
+		maxBackoff:     5 * time.Millisecond,
+		budget:         time.Second,
+	}
+	return c
+}
+
+func TestGetMarketByConditionIDRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		failStatus   int
+		body         string
+		wantNotFound bool
+		wantErr      bool
+		wantRequests int32
+		description  string
+	}{
+		{
+			name:         "recovers after two 502s",
+			failures:     2,
+			failStatus:   http.StatusBadGateway,
+			body:         `[{"question":"Will it happen?","slug":"will-it-happen"}]`,
+			wantRequests: 3,
+			description:  "Transient gateway errors are retried until Gamma answers",
+		},
+		{
+			name:         "recovers after 429",
+			failures:     1,
+			failStatus:   http.StatusTooManyRequests,
+			body:         `[{"question":"Will it happen?","slug":"will-it-happen"}]`,
+			wantRequests: 2,
+			description:  "Rate limiting is retried",
+		},
+		{
+			name:         "empty result is not found",
+			body:         `[]`,
+			wantNotFound: true,
+			wantErr:      true,
+			wantRequests: 1,
+			description:  "A market Gamma doesn't know is ErrMarketNotFound and not retried",
+		},
+		{
+			name:         "persistent 503 is transient",
+			failures:     10,
+			failStatus:   http.StatusServiceUnavailable,
+			wantErr:      true,
+			wantRequests: 4,
+			description:  "Exhausted retries return an error callers can try again later",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&requests, 1) <= int32(tt.failures) {
+					w.WriteHeader(tt.failStatus)
+					return
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			market, err := newTestClient(srv.URL).GetMarketByConditionID(context.Background(), "0xcond")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got err %v, want error %v\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if errors.Is(err, ErrMarketNotFound) != tt.wantNotFound {
+				t.Errorf("got err %v, want not found %v\nDescription: %s", err, tt.wantNotFound, tt.description)
+			}
+			if got := atomic.LoadInt32(&requests); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d\nDescription: %s", got, tt.wantRequests, tt.description)
+			}
+			if !tt.wantErr && (market == nil || market.Slug != "will-it-happen") {
+				t.Errorf("got %+v, want the market\nDescription: %s", market, tt.description)
+			}
+		})
+	}
+}
+
+func TestGetMarketsByConditionIDs(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		var markets []Market
+		for _, id := range r.URL.Query()["condition_ids"] {
+			if id == "0x07" {
+				continue // Unknown to Gamma
+			}
+			markets = append(markets, Market{ConditionID: strings.ToUpper(id), Slug: "market-" + id})
+		}
+		json.NewEncoder(w).Encode(markets)
+	}))
+	defer srv.Close()
+
+	var ids []string
+	for i := 0; i < 45; i++ {
+		ids = append(ids, fmt.Sprintf("0x%02x", i))
+	}
+
+	markets, err := newTestClient(srv.URL).GetMarketsByConditionIDs(context.Background(), ids)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 3 {
+		t.Errorf("got %d requests for 45 IDs, want 3 batches", got)
+	}
+	if len(markets) != 44 {
+		t.Errorf("got %d markets, want 44", len(markets))
+	}
+	if _, ok := markets["0x07"]; ok {
+		t.Errorf("got a market for 0x07, want it missing")
+	}
+	if m := markets["0x2c"]; m == nil || m.Slug != "market-0x2c" {
+		t.Errorf("got %+v for 0x2c, want it keyed by the requested ID", m)
+	}
+}
+
+func TestGetMarketByConditionIDIfModified(t *testing.T) {
+	const etag, lastModified = `"v2"`, "Wed, 14 Oct 2026 09:00:00 GMT"
+
+	tests := []struct {
+		name            string
+		sent            Validators
+		wantNotModified bool
+		wantSent        Validators
+		wantNext        Validators
+		description     string
+	}{
+		{
+			name:        "first fetch",
+			wantSent:    Validators{},
+			wantNext:    Validators{ETag: etag, LastModified: lastModified},
+			description: "An unconditional request downloads the market and returns its validators",
+		},
+		{
+			name:        "changed",
+			sent:        Validators{ETag: `"v1"`, LastModified: "Tue, 13 Oct 2026 09:00:00 GMT"},
+			wantSent:    Validators{ETag: `"v1"`, LastModified: "Tue, 13 Oct 2026 09:00:00 GMT"},
+			wantNext:    Validators{ETag: etag, LastModified: lastModified},
+			description: "A changed market comes back in full with new validators",
+		},
+		{
+			name:            "unchanged",
+			sent:            Validators{ETag: etag, LastModified: lastModified},
+			wantNotModified: true,
+			wantSent:        Validators{ETag: etag, LastModified: lastModified},
+			wantNext:        Validators{ETag: etag, LastModified: lastModified},
+			description:     "A 304 returns ErrNotModified and keeps the validators",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent Validators
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				sent = Validators{ETag: r.Header.Get("If-None-Match"), LastModified: r.Header.Get("If-Modified-Since")}
+				if sent.ETag == etag {
+					w.Header().Set("ETag", etag) // Last-Modified is left out, as a 304 may
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				w.Header().Set("ETag", etag)
+				w.Header().Set("Last-Modified", lastModified)
+				w.Write([]byte(`[{"conditionId": "0x01", "slug": "a-market"}]`))
+			}))
+			defer srv.Close()
+
+			c := newTestClient(srv.URL)
+			market, next, err := c.GetMarketByConditionIDIfModified(context.Background(), "0x01", tt.sent)
+			if sent != tt.wantSent {
+				t.Errorf("got headers %+v sent, want %+v\nDescription: %s", sent, tt.wantSent, tt.description)
+			}
+			if next != tt.wantNext {
+				t.Errorf("got validators %+v, want %+v\nDescription: %s", next, tt.wantNext, tt.description)
+			}
+			if tt.wantNotModified {
+				if !errors.Is(err, ErrNotModified) || market != nil {
+					t.Errorf("got %+v and %v, want ErrNotModified\nDescription: %s", market, err, tt.description)
+				}
+				if c.breaker.Allow() != nil {
+					t.Errorf("got the circuit open, want a 304 counted as success\nDescription: %s", tt.description)
+				}
+				return
+			}
+			if err != nil || market == nil || market.Slug != "a-market" {
+				t.Errorf("got %+v and %v, want the market\nDescription: %s", market, err, tt.description)
+			}
+		})
+	}
+}
+
+func TestListRecentMarkets(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`[{"conditionId": "0x01", "slug": "new-market", "category": "Politics", "events": [{"slug": "new-event"}]}]`))
+	}))
+	defer srv.Close()
+
+	markets, err := newTestClient(srv.URL).ListRecentMarkets(context.Background(), 50, 100)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	for key, want := range map[string]string{"closed": "false", "order": "createdAt", "ascending": "false", "limit": "50", "offset": "100"} {
+		if got := query.Get(key); got != want {
+			t.Errorf("got %s=%q, want %q", key, got, want)
+		}
+	}
+	if len(markets) != 1 || markets[0].ConditionID != "0x01" || len(markets[0].Events) != 1 || markets[0].Events[0].Slug != "new-event" {
+		t.Errorf("got %+v, want the market with its parent event", markets)
+	}
+}
+
+func TestGetMarketBySlugNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).GetMarketBySlug(context.Background(), "missing"); !errors.Is(err, ErrMarketNotFound) {
+		t.Errorf("got %v, want ErrMarketNotFound", err)
+	}
+}
+
+// serveFixture answers every request with a file from testdata
+func serveFixture(t *testing.T, name string, requests *int32) *httptest.Server {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		w.Write(body)
+	}))
+}
+
+func TestGetEventBySlug(t *testing.T) {
+	var requests int32
+	srv := serveFixture(t, "event_by_slug.json", &requests)
+	defer srv.Close()
+
+	event, err := newTestClient(srv.URL).GetEventBySlug(context.Background(), "fed-decision-in-march")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if event.ID != "16085" || event.EndDate != "2025-03-19T00:00:00Z" || event.CreatedAt == "" {
+		t.Errorf("got %+v, want the fixture event", event)
+	}
+	if len(event.Tags) != 2 || event.Tags[1].Slug != "fed-rates" {
+		t.Errorf("got tags %+v, want economy and fed-rates", event.Tags)
+	}
+	if len(event.Markets) != 1 || event.Markets[0].ConditionID == "" {
+		t.Errorf("got markets %+v, want one market with a condition ID", event.Markets)
+	}
+}
+
+func TestGetEventBySlugNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	if _, err := newTestClient(srv.URL).GetEventBySlug(context.Background(), "missing"); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("got %v, want ErrEventNotFound", err)
+	}
+}
+
+func TestGetEventBySlugRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     bool
+		description string
+	}{
+		{"found", http.StatusOK, `{"id": "1", "slug": "fed/march", "endDate": "2025-03-19T00:00:00Z"}`, false, "The event is decoded from the slug endpoint"},
+		{"bad json", http.StatusOK, `{"id": `, true, "A truncated body is an error"},
+		{"client error", http.StatusBadRequest, `bad slug`, true, "Non-retryable errors are returned as they are"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.EscapedPath()
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			event, err := newTestClient(srv.URL).GetEventBySlug(context.Background(), "fed/march")
+			if path != "/events/slug/fed%2Fmarch" {
+				t.Errorf("got path %q, want the escaped slug\nDescription: Slugs are a single path segment", path)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got %v, want error %t\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if err == nil && event.EndDate != "2025-03-19T00:00:00Z" {
+				t.Errorf("got %+v, want the event's end date\nDescription: %s", event, tt.description)
+			}
+			if err != nil && errors.Is(err, ErrEventNotFound) {
+				t.Errorf("got %v, want an error other than ErrEventNotFound\nDescription: Only a 404 means the event doesn't exist", err)
+			}
+		})
+	}
+}
+
+func TestGetEventsByIDs(t *testing.T) {
+	var requests int32
+	srv := serveFixture(t, "events_by_ids.json", &requests)
+	defer srv.Close()
+
+	events, err := newTestClient(srv.URL).GetEventsByIDs(context.Background(), []string{"16085", "21742", "99999"})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+	if len(events) != 2 {
+		t.Errorf("got %d events, want 2", len(events))
+	}
+	if _, ok := events["99999"]; ok {
+		t.Errorf("got an event for 99999, want it missing")
+	}
+	if e := events["21742"]; e == nil || len(e.Tags) != 2 || e.Tags[0].Label != "Sports" {
+		t.Errorf("got %+v for 21742, want the NBA event with its tags", e)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{"missing", "", 0},
+		{"seconds", "3", 3 * time.Second},
+		{"negative seconds", "-1", 0},
+		{"http date", now.Add(5 * time.Second).Format(http.TimeFormat), 5 * time.Second},
+		{"date in the past", now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"garbage", "soon", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.expected {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGetMarketResponseTooLarge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"conditionId":"0xabc","question":"` + strings.Repeat("x", 4096) + `"}]`))
+	}))
+	defer srv.Close()
+
+	c := newTestClient(srv.URL)
+	c.maxBody = 1024
+	if _, err := c.GetMarketByConditionID(context.Background(), "0xabc"); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("got %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	tests := []struct {
+		name           string
+		status         int
+		retryAfter     string
+		expectedErr    error
+		wantRetryAfter time.Duration
+		description    string
+	}{
+		{"not found", http.StatusNotFound, "", ErrNotFound, 0, "404 is a missing event, which also matches ErrNotFound"},
+		{"unauthorized", http.StatusUnauthorized, "", ErrUnauthorized, 0, "401 means bad credentials"},
+		{"forbidden", http.StatusForbidden, "", ErrUnauthorized, 0, "403 is treated as unauthorized"},
+		{"rate limited", http.StatusTooManyRequests, "7", ErrRateLimited, 7 * time.Second, "429 carries the Retry-After delay"},
+		{"server error", http.StatusServiceUnavailable, "", ErrServerError, 0, "5xx is a server error"},
+		{"bad request", http.StatusBadRequest, "", nil, 0, "Other statuses match no sentinel"},
+	}
+
+	sentinels := []error{ErrNotFound, ErrUnauthorized, ErrRateLimited, ErrServerError}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			c := newTestClient(srv.URL)
+			c.retry.maxAttempts = 1
+			_, err := c.GetEventBySlug(context.Background(), "some-event")
+			if err == nil {
+				t.Fatalf("got nil error, want status %d\nDescription: %s", tt.status, tt.description)
+			}
+			for _, sentinel := range sentinels {
+				if got, want := errors.Is(err, sentinel), sentinel == tt.expectedErr; got != want {
+					t.Errorf("got errors.Is(%v, %v) = %v, want %v\nDescription: %s", err, sentinel, got, want, tt.description)
+				}
+			}
+			var rl *RateLimitedError
+			if errors.As(err, &rl) && rl.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("got RetryAfter %s, want %s\nDescription: %s", rl.RetryAfter, tt.wantRetryAfter, tt.description)
+			}
+		})
+	}
+}
+
+func TestFixtureGetMarketByConditionIDDecode(t *testing.T) {
+	const conditionID = "0x1d7a3f0c5c8b2e2b6f0f3c6b4e0f7a2d9c1e5b8a7f6d3c2b1a0e9f8d7c6b5a49"
+	bodies := fixtures.Load(t,
+		fixtures.Fixture{Name: "markets_by_condition_id.json", URL: "https://gamma-api.polymarket.com/markets?condition_ids=" + conditionID},
+		fixtures.Fixture{Name: "market_by_id.json", URL: "https://gamma-api.polymarket.com/markets/512340"},
+	)
+
+	tests := []struct {
+		name        string
+		fixture     string
+		description string
+	}{
+		{"array", "markets_by_condition_id.json", "The markets endpoint answers with an array"},
+		{"object", "market_by_id.json", "A single market object is decoded too"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := fixtures.Serve(t, bodies, func(r *http.Request) string { return tt.fixture })
+
+			market, err := newTestClient(srv.URL).GetMarketByConditionID(context.Background(), conditionID)
+			if err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			if market.ConditionID != conditionID || market.Category != "Economics" || market.LiquidityNum <= 0 || market.ClobTokenIDs == "" {
+				t.Errorf("got %+v, want the fixture market\nDescription: %s", market, tt.description)
+			}
+		})
+	}
+}
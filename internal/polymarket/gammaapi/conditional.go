@@ -0,0 +1,38 @@
+package gammaapi
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrNotModified is returned by a conditional lookup when the resource
+// hasn't changed since the response its validators came from
+var ErrNotModified = errors.New("not modified")
+
+// Validators are the caching headers of a response. Sent back with the
+// next request for the same resource, they let the server answer 304
+// instead of sending it again.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// responseValidators reads the validators of a response
+func responseValidators(resp *http.Response) Validators {
+	return Validators{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+}
+
+// merge returns v updated with the validators a 304 sent. A 304 may leave
+// out headers that haven't changed.
+func (v Validators) merge(next Validators) Validators {
+	if next.ETag != "" {
+		v.ETag = next.ETag
+	}
+	if next.LastModified != "" {
+		v.LastModified = next.LastModified
+	}
+	return v
+}
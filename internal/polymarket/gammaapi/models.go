@@ -14,6 +14,15 @@ type Market struct {
 	Closed        bool    `json:"closed"`
 	Outcomes      string  `json:"outcomes"`      // e.g., "YES,NO"
 	OutcomePrices string  `json:"outcomePrices"` // e.g., "0.02,0.98"
+	ClobTokenIDs  string  `json:"clobTokenIds"`  // JSON array of token IDs, in the same order as Outcomes
+
+	// Resolution data; settled markets report final prices of exactly 1/0
+	// (or 0.5/0.5 for a 50-50 resolution)
+	UMAResolutionStatus string `json:"umaResolutionStatus"` // e.g., proposed, disputed, resolved
+	ClosedTime          string `json:"closedTime"`
+
+	// Parent events, as listed by /markets; the by-ID lookups may omit them
+	Events []Event `json:"events"`
 }
 
 // MarketsResponse wraps the markets API response
@@ -30,6 +39,15 @@ type Event struct {
 	Markets     []Market `json:"markets"`
 	Category    string   `json:"category"`
 	EndDate     string   `json:"endDate"`
+	CreatedAt   string   `json:"createdAt"`
+	Tags        []Tag    `json:"tags"`
 	Active      bool     `json:"active"`
 	Closed      bool     `json:"closed"`
 }
+
+// Tag is a Gamma topic label attached to events, e.g. Politics or Sports
+type Tag struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Slug  string `json:"slug"`
+}
@@ -0,0 +1,242 @@
+package gammaapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+)
+
+// Errors for API responses by status, so callers can tell a missing
+// record from a transient failure. Use errors.Is; a 429 also matches
+// errors.As with *RateLimitedError.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrRateLimited  = errors.New("rate limited")
+	ErrServerError  = errors.New("server error")
+)
+
+// RateLimitedError is a 429 response and how long the API asked us to wait
+type RateLimitedError struct {
+	RetryAfter time.Duration // 0 when the response didn't say
+}
+
+func (e *RateLimitedError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("rate limited, retry after %s", e.RetryAfter)
+	}
+	return "rate limited"
+}
+
+// Is makes a RateLimitedError match ErrRateLimited
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// ErrMarketNotFound means Gamma has no market for the requested ID. Unlike
+// transient failures, repeating the request won't help. It matches
+// ErrNotFound.
+var ErrMarketNotFound = fmt.Errorf("market %w", ErrNotFound)
+
+// ErrEventNotFound means Gamma has no event for the requested slug. It
+// matches ErrNotFound.
+var ErrEventNotFound = fmt.Errorf("event %w", ErrNotFound)
+
+// retryPolicy bounds how long a GET keeps retrying transient failures
+type retryPolicy struct {
+	maxAttempts    int           // Total attempts, including the first
+	initialBackoff time.Duration // Delay before the first retry; doubles after each
+	maxBackoff     time.Duration
+	budget         time.Duration // Give up rather than start a retry past this much elapsed time
+}
+
+var defaultRetryPolicy = retryPolicy{
+	maxAttempts:    4,
+	initialBackoff: 500 * time.Millisecond,
+	maxBackoff:     8 * time.Second,
+	budget:         30 * time.Second,
+}
+
+// statusError is a non-200 response from the API
+type statusError struct {
+	code       int
+	body       string
+	retryAfter time.Duration
+	requestID  string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d (request_id=%s): %s", e.code, e.requestID, e.body)
+}
+
+// Unwrap classifies the status as ErrNotFound, ErrUnauthorized, a
+// RateLimitedError, or ErrServerError; other statuses match none of them
+func (e *statusError) Unwrap() error {
+	switch {
+	case e.code == http.StatusNotFound:
+		return ErrNotFound
+	case e.code == http.StatusUnauthorized || e.code == http.StatusForbidden:
+		return ErrUnauthorized
+	case e.code == http.StatusTooManyRequests:
+		return &RateLimitedError{RetryAfter: e.retryAfter}
+	case e.code >= 500:
+		return ErrServerError
+	}
+	return nil
+}
+
+// get performs an idempotent GET and returns the response body. 5xx and
+// network errors are retried with exponential backoff and jitter; a 429
+// waits out its Retry-After first. Retries stop when the attempts or time
+// budget run out or ctx is cancelled. While the circuit is open it fails
+// fast with circuit.ErrOpen.
+func (c *Client) get(ctx context.Context, endpoint, u string) ([]byte, error) {
+	body, _, err := c.getConditional(ctx, endpoint, u, Validators{})
+	return body, err
+}
+
+// getConditional is get sending the validators of an earlier response, if
+// any. It returns the validators of this response, and ErrNotModified
+// without a body when the server answers 304.
+func (c *Client) getConditional(ctx context.Context, endpoint, u string, v Validators) ([]byte, Validators, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, Validators{}, err
+	}
+	body, next, err := c.getWithRetry(ctx, endpoint, u, v)
+	if ctx.Err() == nil {
+		c.breaker.Record(err != nil && retryable(err))
+	}
+	return body, next, err
+}
+
+func (c *Client) getWithRetry(ctx context.Context, endpoint, u string, v Validators) ([]byte, Validators, error) {
+	start := time.Now()
+	backoff := c.retry.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		waitStart := time.Now()
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, Validators{}, fmt.Errorf("rate limit wait: %w", err)
+		}
+		if err := c.budget.Wait(ctx); err != nil {
+			return nil, Validators{}, fmt.Errorf("shared rate limit wait: %w", err)
+		}
+		metrics.RecordRateLimitWait("gamma", endpoint, time.Since(waitStart))
+
+		attemptStart := time.Now()
+		body, next, err := c.doGet(ctx, u, v)
+		if errors.Is(err, ErrNotModified) {
+			// A 304 is a successful request that saved the download
+			metrics.RecordAPIRequest("gamma", endpoint, time.Since(attemptStart), nil)
+			return nil, next, err
+		}
+		metrics.RecordAPIRequest("gamma", endpoint, time.Since(attemptStart), err)
+		if err == nil {
+			return body, next, nil
+		}
+		if ctx.Err() != nil || !retryable(err) || attempt >= c.retry.maxAttempts {
+			return nil, Validators{}, err
+		}
+
+		// Full jitter keeps concurrent workers from retrying in lockstep
+		delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > 0 {
+			delay = se.retryAfter
+		}
+		if time.Since(start)+delay > c.retry.budget {
+			return nil, Validators{}, err
+		}
+
+		metrics.RecordAPIRetry("gamma", endpoint)
+		select {
+		case <-ctx.Done():
+			return nil, Validators{}, err
+		case <-time.After(delay):
+		}
+
+		backoff = min(backoff*2, c.retry.maxBackoff)
+	}
+}
+
+func (c *Client) doGet(ctx context.Context, u string, v Validators) ([]byte, Validators, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("create request: %w", err)
+	}
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	// Gamma API is public - no auth headers needed per spec
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(limitBody(resp.Body, c.maxBody))
+	if err != nil {
+		return nil, Validators{}, fmt.Errorf("read body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, v.merge(responseValidators(resp)), ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		se := &statusError{code: resp.StatusCode, body: string(body), requestID: transport.RequestID(resp)}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			se.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+		}
+		return nil, Validators{}, se
+	}
+
+	return body, responseValidators(resp), nil
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP
+// date. It returns 0 when the header is missing or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(value); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retryable reports whether a failed GET may succeed if repeated: server
+// errors, rate limiting, and transport failures
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusTooManyRequests
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
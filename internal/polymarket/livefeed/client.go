@@ -0,0 +1,148 @@
+package livefeed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pingInterval   = 10 * time.Second
+	readTimeout    = 30 * time.Second
+	initialBackoff = 1 * time.Second
+	maxBackoff     = 60 * time.Second
+)
+
+// TradeHandler is called for every trade received from the feed
+type TradeHandler func(ctx context.Context, trade dataapi.Trade)
+
+// Client streams trades from the Polymarket real-time data socket
+type Client struct {
+	url    string
+	dialer *websocket.Dialer
+	log    *logrus.Logger
+}
+
+// NewClient creates a new live feed client
+func NewClient(cfg *config.Config, log *logrus.Logger) *Client {
+	return &Client{
+		url:    cfg.LiveFeedURL,
+		dialer: &websocket.Dialer{HandshakeTimeout: 10 * time.Second},
+		log:    log,
+	}
+}
+
+// Run connects to the feed and delivers trades to handler until ctx is cancelled.
+// Dropped connections are re-established with exponential backoff and the
+// subscription is renewed on every reconnect.
+func (c *Client) Run(ctx context.Context, handler TradeHandler) {
+	backoff := initialBackoff
+	for {
+		connected, err := c.stream(ctx, handler)
+		if ctx.Err() != nil {
+			return
+		}
+		if connected {
+			// We had a working session, so start the backoff over
+			backoff = initialBackoff
+		}
+
+		metrics.LiveFeedReconnects.Inc()
+		c.log.WithError(err).WithField("retry_in", backoff.String()).Warn("Live feed disconnected, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// stream runs a single connection until it fails. The bool reports whether
+// the subscription was established before the failure.
+func (c *Client) stream(ctx context.Context, handler TradeHandler) (bool, error) {
+	conn, _, err := c.dialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		return false, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+
+	subscribe := SubscribeRequest{
+		Action:        "subscribe",
+		Subscriptions: []Subscription{{Topic: "activity", Type: "trades"}},
+	}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		return false, fmt.Errorf("subscribe: %w", err)
+	}
+
+	c.log.WithField("url", c.url).Info("Live feed subscribed to trades")
+
+	// Close the connection when ctx is cancelled to unblock ReadMessage,
+	// and keep the connection alive with pings in the meantime
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				conn.Close()
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				deadline := time.Now().Add(5 * time.Second)
+				if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return true, fmt.Errorf("read: %w", err)
+		}
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			metrics.LiveFeedMessages.WithLabelValues("invalid").Inc()
+			continue
+		}
+		if msg.Topic != "activity" || msg.Type != "trades" {
+			metrics.LiveFeedMessages.WithLabelValues("ignored").Inc()
+			continue
+		}
+
+		var trade dataapi.Trade
+		if err := json.Unmarshal(msg.Payload, &trade); err != nil {
+			metrics.LiveFeedMessages.WithLabelValues("invalid").Inc()
+			c.log.WithError(err).Debug("Failed to decode live trade payload")
+			continue
+		}
+
+		metrics.LiveFeedMessages.WithLabelValues("trade").Inc()
+		handler(ctx, trade)
+	}
+}
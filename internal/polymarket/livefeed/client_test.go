@@ -0,0 +1,170 @@
+package livefeed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/sirupsen/logrus"
+)
+
+// fakeFeed is a websocket server that records each connection's subscribe
+// request, sends that connection's messages and then hangs up
+type fakeFeed struct {
+	t        *testing.T
+	upgrader websocket.Upgrader
+	messages [][]string // Raw messages to send, by connection
+
+	mu         sync.Mutex
+	subscribes []SubscribeRequest
+}
+
+func (f *fakeFeed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		f.t.Errorf("upgrade: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var req SubscribeRequest
+	if err := conn.ReadJSON(&req); err != nil {
+		f.t.Errorf("read subscribe: %v", err)
+		return
+	}
+	f.mu.Lock()
+	n := len(f.subscribes)
+	f.subscribes = append(f.subscribes, req)
+	f.mu.Unlock()
+
+	if n >= len(f.messages) {
+		// Keep the last connection open until the client leaves
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}
+	for _, msg := range f.messages[n] {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+			f.t.Errorf("write: %v", err)
+			return
+		}
+	}
+}
+
+func (f *fakeFeed) subscribeRequests() []SubscribeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]SubscribeRequest(nil), f.subscribes...)
+}
+
+func tradeMessage(t *testing.T, trade dataapi.Trade) string {
+	t.Helper()
+	payload, err := json.Marshal(trade)
+	if err != nil {
+		t.Fatalf("marshal trade: %v", err)
+	}
+	msg, err := json.Marshal(Message{Topic: "activity", Type: "trades", Timestamp: 1, Payload: payload})
+	if err != nil {
+		t.Fatalf("marshal message: %v", err)
+	}
+	return string(msg)
+}
+
+func TestClientRunReconnects(t *testing.T) {
+	feed := &fakeFeed{t: t}
+	feed.messages = [][]string{
+		{
+			tradeMessage(t, dataapi.Trade{TransactionHash: "0xfirst"}),
+			`{"topic": "activity", "type": "orders_matched", "payload": {}}`,
+			`not json`,
+		},
+		{
+			tradeMessage(t, dataapi.Trade{TransactionHash: "0xsecond"}),
+		},
+	}
+	server := httptest.NewServer(feed)
+	defer server.Close()
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	client := NewClient(&config.Config{LiveFeedURL: "ws" + strings.TrimPrefix(server.URL, "http")}, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	trades := make(chan dataapi.Trade, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Run(ctx, func(ctx context.Context, trade dataapi.Trade) {
+			trades <- trade
+		})
+	}()
+
+	// The first connection drops after its messages; the client reconnects
+	// after initialBackoff and subscribes again
+	var got []string
+	timeout := time.After(initialBackoff + 5*time.Second)
+	for len(got) < 2 {
+		select {
+		case trade := <-trades:
+			got = append(got, trade.TransactionHash)
+		case <-timeout:
+			t.Fatalf("got trades %v, want both connections' trades\nDescription: The client reconnects after the feed hangs up", got)
+		}
+	}
+	if got[0] != "0xfirst" || got[1] != "0xsecond" {
+		t.Errorf("got trades %v, want [0xfirst 0xsecond]\nDescription: Only trade messages reach the handler, in order", got)
+	}
+
+	subscribes := feed.subscribeRequests()
+	if len(subscribes) != 2 {
+		t.Fatalf("got %d subscribe requests, want 2\nDescription: The subscription is renewed on every connection", len(subscribes))
+	}
+	for i, req := range subscribes {
+		if req.Action != "subscribe" || len(req.Subscriptions) != 1 || req.Subscriptions[0] != (Subscription{Topic: "activity", Type: "trades"}) {
+			t.Errorf("got subscribe request %d %+v, want a subscription to activity trades", i, req)
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("got Run still running after ctx was cancelled, want it to return")
+	}
+}
+
+func TestClientRunStopsWhileDisconnected(t *testing.T) {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	// Nothing listens here, so every dial fails
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+	server.Close()
+	client := NewClient(&config.Config{LiveFeedURL: url}, log)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		client.Run(ctx, func(ctx context.Context, trade dataapi.Trade) {})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("got Run still waiting to reconnect after ctx was cancelled, want it to return\nDescription: Shutdown doesn't wait out the backoff")
+	}
+}
@@ -0,0 +1,25 @@
+package livefeed
+
+import (
+	"encoding/json"
+)
+
+// Subscription identifies a topic/type pair on the real-time data socket
+type Subscription struct {
+	Topic string `json:"topic"`
+	Type  string `json:"type"`
+}
+
+// SubscribeRequest is sent after connecting to start receiving messages
+type SubscribeRequest struct {
+	Action        string         `json:"action"` // subscribe, unsubscribe
+	Subscriptions []Subscription `json:"subscriptions"`
+}
+
+// Message is the envelope for every message pushed by the socket
+type Message struct {
+	Topic     string          `json:"topic"`
+	Type      string          `json:"type"`
+	Timestamp int64           `json:"timestamp"` // Unix timestamp in milliseconds
+	Payload   json.RawMessage `json:"payload"`
+}
@@ -0,0 +1,176 @@
+package subgraph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/transport"
+	"github.com/sirupsen/logrus"
+)
+
+// usdcToken is the USDC.e contract on Polygon that Polymarket settles in.
+// Transfers of any other token are not funding.
+const usdcToken = "0x2791bca1f2de4661ed88a30c99a7a9449aa84174"
+
+// walletHistoryQuery fetches the first USDC inflows, first order fill and
+// position count for a wallet from a Polymarket subgraph (e.g. Goldsky-hosted)
+const walletHistoryQuery = `query WalletHistory($wallet: String!, $token: String!) {
+  inflows: transfers(where: {to: $wallet, token: $token}, orderBy: timestamp, orderDirection: asc, first: 100) {
+    from
+    value
+    timestamp
+    transactionHash
+  }
+  fills: orderFilledEvents(where: {taker: $wallet}, orderBy: timestamp, orderDirection: asc, first: 1) {
+    timestamp
+  }
+  positions: userPositions(where: {user: $wallet}, first: 1000) {
+    id
+  }
+}`
+
+// walletOutflowsQuery fetches USDC transfers sent from a wallet
+const walletOutflowsQuery = `query WalletOutflows($wallet: String!, $token: String!) {
+  outflows: transfers(where: {from: $wallet, token: $token}, orderBy: timestamp, orderDirection: asc, first: 100) {
+    to
+    value
+    timestamp
+    transactionHash
+  }
+}`
+
+// Client queries a subgraph GraphQL endpoint for on-chain wallet history
+type Client struct {
+	url        string
+	apiKey     string
+	httpClient *http.Client
+	limiter    *ratelimit.Limiter
+}
+
+// NewClient creates a new subgraph client, or nil when SUBGRAPH_URL is unset
+func NewClient(cfg *config.Config, log *logrus.Logger) *Client {
+	if cfg.SubgraphURL == "" {
+		return nil
+	}
+	return &Client{
+		url:        cfg.SubgraphURL,
+		apiKey:     cfg.SubgraphAPIKey,
+		httpClient: &http.Client{Timeout: 30 * time.Second, Transport: transport.New("subgraph", nil, cfg.APISlowRequestThreshold, log)},
+		limiter:    ratelimit.New(cfg.SubgraphRPS, cfg.SubgraphBurst),
+	}
+}
+
+// GetWalletHistory fetches a wallet's on-chain history
+func (c *Client) GetWalletHistory(ctx context.Context, wallet string) (*WalletHistory, error) {
+	var result walletHistoryResponse
+	if err := c.query(ctx, walletHistoryQuery, wallet, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("graphql error: %s", result.Errors[0].Message)
+	}
+
+	history := &WalletHistory{
+		PositionCount: len(result.Data.Positions),
+	}
+
+	if len(result.Data.Fills) > 0 {
+		history.FirstActivityTS, _ = strconv.ParseInt(result.Data.Fills[0].Timestamp, 10, 64)
+	}
+
+	for i, inflow := range result.Data.Inflows {
+		amount := parseUSDC(inflow.Value)
+		history.TotalInflowUSD += amount
+		if i == 0 {
+			history.FirstFundingTS, _ = strconv.ParseInt(inflow.Timestamp, 10, 64)
+			history.FundingSource = inflow.From
+			history.FundingAmountUSD = amount
+			history.FundingTxHash = inflow.TransactionHash
+		}
+	}
+
+	return history, nil
+}
+
+// GetWalletOutflows fetches the USDC transfers a wallet has sent, oldest first
+func (c *Client) GetWalletOutflows(ctx context.Context, wallet string) ([]Transfer, error) {
+	var result walletOutflowsResponse
+	if err := c.query(ctx, walletOutflowsQuery, wallet, &result); err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("graphql error: %s", result.Errors[0].Message)
+	}
+
+	transfers := make([]Transfer, 0, len(result.Data.Outflows))
+	for _, outflow := range result.Data.Outflows {
+		ts, _ := strconv.ParseInt(outflow.Timestamp, 10, 64)
+		transfers = append(transfers, Transfer{
+			To:        outflow.To,
+			AmountUSD: parseUSDC(outflow.Value),
+			Timestamp: ts,
+			TxHash:    outflow.TransactionHash,
+		})
+	}
+	return transfers, nil
+}
+
+// query runs a GraphQL query for a wallet and decodes the response into out
+func (c *Client) query(ctx context.Context, query, wallet string, out interface{}) error {
+	// Rate limit
+	if err := c.limiter.Wait(ctx); err != nil {
+		return fmt.Errorf("rate limit wait: %w", err)
+	}
+
+	body, err := json.Marshal(graphQLRequest{
+		Query:     query,
+		Variables: map[string]interface{}{"wallet": strings.ToLower(wallet), "token": usdcToken},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected status %d (request_id=%s): %s", resp.StatusCode, transport.RequestID(resp), string(respBody))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// parseUSDC converts a USDC base-unit amount (6 decimals) to dollars
+func parseUSDC(value string) float64 {
+	units, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return units / 1e6
+}
@@ -0,0 +1,162 @@
+package subgraph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// walletHistoryFixture is a subgraph answer for a wallet funded twice, with
+// one fill and two positions
+const walletHistoryFixture = `{
+  "data": {
+    "inflows": [
+      {"from": "0xfunder", "value": "250000000", "timestamp": "1700000000", "transactionHash": "0xfund1"},
+      {"from": "0xother", "value": "1500000", "timestamp": "1700000600", "transactionHash": "0xfund2"}
+    ],
+    "fills": [{"timestamp": "1700000300"}],
+    "positions": [{"id": "p1"}, {"id": "p2"}]
+  }
+}`
+
+// fakeSubgraph answers every query with body and status, recording the
+// requests it got
+type fakeSubgraph struct {
+	t        *testing.T
+	status   int
+	body     string
+	requests []graphQLRequest
+	auth     []string
+}
+
+func (f *fakeSubgraph) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("decode request: %v", err)
+	}
+	f.requests = append(f.requests, req)
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	w.WriteHeader(f.status)
+	w.Write([]byte(f.body))
+}
+
+func newTestClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return NewClient(&config.Config{SubgraphURL: server.URL, SubgraphAPIKey: "key", SubgraphRPS: 100, SubgraphBurst: 10}, log)
+}
+
+func TestGetWalletHistory(t *testing.T) {
+	fake := &fakeSubgraph{t: t, status: http.StatusOK, body: walletHistoryFixture}
+	client := newTestClient(t, fake)
+
+	history, err := client.GetWalletHistory(context.Background(), "0xWALLET")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	want := WalletHistory{
+		FirstActivityTS:  1700000300,
+		FirstFundingTS:   1700000000,
+		FundingSource:    "0xfunder",
+		FundingAmountUSD: 250,
+		FundingTxHash:    "0xfund1",
+		TotalInflowUSD:   251.5,
+		PositionCount:    2,
+	}
+	if *history != want {
+		t.Errorf("got %+v, want %+v\nDescription: The first inflow is the funding; all inflows add to the total", *history, want)
+	}
+
+	if len(fake.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(fake.requests))
+	}
+	req := fake.requests[0]
+	if req.Variables["wallet"] != "0xwallet" || req.Variables["token"] != usdcToken {
+		t.Errorf("got variables %v, want the lowercased wallet and the USDC token\nDescription: Subgraph IDs are lowercase and only USDC transfers are funding", req.Variables)
+	}
+	if !strings.Contains(req.Query, "token: $token") {
+		t.Errorf("got query %q, want the transfers filtered by token\nDescription: Transfers of other tokens must not count as funding", req.Query)
+	}
+	if fake.auth[0] != "Bearer key" {
+		t.Errorf("got Authorization %q, want %q\nDescription: SUBGRAPH_API_KEY is sent as a bearer token", fake.auth[0], "Bearer key")
+	}
+}
+
+func TestGetWalletHistoryErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantErr     string
+		description string
+	}{
+		{
+			name:        "graphql error",
+			status:      http.StatusOK,
+			body:        `{"data": null, "errors": [{"message": "Type Transfer has no field token"}]}`,
+			wantErr:     "graphql error: Type Transfer has no field token",
+			description: "Errors in a 200 response are returned rather than read as an empty history",
+		},
+		{
+			name:        "bad status",
+			status:      http.StatusBadGateway,
+			body:        `upstream unavailable`,
+			wantErr:     "unexpected status 502",
+			description: "Non-200 responses are errors and carry the body",
+		},
+		{
+			name:        "bad json",
+			status:      http.StatusOK,
+			body:        `{"data": [`,
+			wantErr:     "decode response",
+			description: "A truncated body is an error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, &fakeSubgraph{t: t, status: tt.status, body: tt.body})
+
+			history, err := client.GetWalletHistory(context.Background(), "0xwallet")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+			if history != nil {
+				t.Errorf("got history %+v, want nil\nDescription: %s", *history, tt.description)
+			}
+		})
+	}
+}
+
+func TestGetWalletOutflows(t *testing.T) {
+	fake := &fakeSubgraph{t: t, status: http.StatusOK, body: `{
+  "data": {
+    "outflows": [
+      {"to": "0xdest", "value": "12345678", "timestamp": "1700001000", "transactionHash": "0xout"}
+    ]
+  }
+}`}
+	client := newTestClient(t, fake)
+
+	transfers, err := client.GetWalletOutflows(context.Background(), "0xwallet")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	want := Transfer{To: "0xdest", AmountUSD: 12.345678, Timestamp: 1700001000, TxHash: "0xout"}
+	if len(transfers) != 1 || transfers[0] != want {
+		t.Errorf("got %+v, want [%+v]\nDescription: Outflows are converted from USDC base units", transfers, want)
+	}
+	if got := fake.requests[0].Variables["token"]; got != usdcToken {
+		t.Errorf("got token %v, want %s\nDescription: Outflows are filtered to USDC like inflows", got, usdcToken)
+	}
+}
@@ -0,0 +1,63 @@
+package subgraph
+
+// WalletHistory summarises a wallet's on-chain history
+type WalletHistory struct {
+	FirstActivityTS  int64   // First on-chain Polymarket fill (0 if none)
+	FirstFundingTS   int64   // First USDC inflow (0 if none)
+	FundingSource    string  // Sender of the first USDC inflow
+	FundingAmountUSD float64 // Amount of the first USDC inflow
+	FundingTxHash    string  // Transaction hash of the first USDC inflow
+	TotalInflowUSD   float64 // Sum of USDC inflows (capped at the query page size)
+	PositionCount    int     // Number of historical positions
+}
+
+// Transfer is a USDC transfer sent from a wallet
+type Transfer struct {
+	To        string
+	AmountUSD float64
+	Timestamp int64
+	TxHash    string
+}
+
+// graphQLRequest is the body of a GraphQL POST
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// graphQLError is a single error returned by the endpoint
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+// walletHistoryResponse is the GraphQL response for walletHistoryQuery
+type walletHistoryResponse struct {
+	Data struct {
+		Inflows []struct {
+			From            string `json:"from"`
+			Value           string `json:"value"` // USDC base units (6 decimals)
+			Timestamp       string `json:"timestamp"`
+			TransactionHash string `json:"transactionHash"`
+		} `json:"inflows"`
+		Fills []struct {
+			Timestamp string `json:"timestamp"`
+		} `json:"fills"`
+		Positions []struct {
+			ID string `json:"id"`
+		} `json:"positions"`
+	} `json:"data"`
+	Errors []graphQLError `json:"errors"`
+}
+
+// walletOutflowsResponse is the GraphQL response for walletOutflowsQuery
+type walletOutflowsResponse struct {
+	Data struct {
+		Outflows []struct {
+			To              string `json:"to"`
+			Value           string `json:"value"` // USDC base units (6 decimals)
+			Timestamp       string `json:"timestamp"`
+			TransactionHash string `json:"transactionHash"`
+		} `json:"outflows"`
+	} `json:"data"`
+	Errors []graphQLError `json:"errors"`
+}
@@ -0,0 +1,93 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/sirupsen/logrus"
+)
+
+// backfillPageSize is how many trades Backfill fetches a request
+const backfillPageSize = 1000
+
+// Backfill processes the taker trades made in [since, until), paging back
+// through the Data API, to fill a gap longer than one poll fetches, such as
+// after downtime. It neither reads nor moves the poll checkpoint; trades
+// that were already processed are deduplicated as usual. A zero until
+// means now.
+func (p *Processor) Backfill(ctx context.Context, since, until time.Time) (PollSummary, error) {
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	start := p.clock.Now()
+	trades, err := p.fetchBackfillTrades(ctx, since, until)
+	if err != nil {
+		return PollSummary{}, err
+	}
+	fetchedCount := len(trades)
+	p.log.WithFields(logrus.Fields{
+		"count": fetchedCount,
+		"since": since.UTC().Format(time.RFC3339),
+	}).Info("Fetched trades to backfill")
+
+	stats := newPollStats()
+	trades = p.validTrades(trades, stats)
+	if p.config().AggregateSameTxFills {
+		trades = p.aggregateFills(trades)
+	}
+	p.warmMarketCache(ctx, trades)
+
+	// Every fetched trade is in the window, so none are skipped as older
+	dispatched, cutShort := p.dispatchTrades(ctx, trades, since.Unix()-1, stats)
+	summary := stats.summary(fetchedCount, dispatched, p.clock.Since(start))
+	summary.CutShort = cutShort
+
+	p.log.WithFields(logrus.Fields{
+		"fetched":     summary.Fetched,
+		"new":         summary.New,
+		"processed":   summary.Processed,
+		"alerted":     summary.Alerted,
+		"cut_short":   summary.CutShort,
+		"duration_ms": summary.Duration.Milliseconds(),
+	}).Info("Backfill complete")
+	return summary, nil
+}
+
+// fetchBackfillTrades pages through the Data API's trades, newest first,
+// until it passes since, keeping those in [since, until)
+func (p *Processor) fetchBackfillTrades(ctx context.Context, since, until time.Time) ([]dataapi.Trade, error) {
+	sinceTS, untilTS := since.Unix(), until.Unix()
+	params := dataapi.TradeParams{
+		Limit:         backfillPageSize,
+		TakerOnly:     true,
+		FilterType:    "CASH",
+		FilterAmount:  p.config().BigTradeUSD,
+		SortBy:        "timestamp",
+		SortDirection: "DESC",
+	}
+
+	var trades []dataapi.Trade
+	for {
+		resp, err := p.dataClient.GetTrades(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("fetch trades at offset %d: %w", params.Offset, err)
+		}
+		oldest := int64(0)
+		for _, trade := range resp.Trades {
+			if oldest == 0 || trade.Timestamp < oldest {
+				oldest = trade.Timestamp
+			}
+			if trade.Timestamp < sinceTS || (!until.IsZero() && trade.Timestamp >= untilTS) {
+				continue
+			}
+			trade.Role = dataapi.RoleTaker
+			trades = append(trades, trade)
+		}
+		if len(resp.Trades) < params.Limit || oldest < sinceTS {
+			return trades, nil
+		}
+		params.Offset += len(resp.Trades)
+	}
+}
@@ -0,0 +1,594 @@
+package processor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+)
+
+// backtestStart is when every scenario starts, so results never depend on
+// the day the backtest runs
+var backtestStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// Scenario is a hand-labeled sequence of trades for `insiderwatch backtest`.
+// Wallets and markets are described by the signals that would otherwise come
+// from the APIs; everything that depends on earlier trades (velocity,
+// concentration, clusters trading together, copy trades) is derived from
+// the sequence itself.
+type Scenario struct {
+	Name        string           `json:"name"` // Defaults to the file name
+	Description string           `json:"description"`
+	Wallets     []ScenarioWallet `json:"wallets"`
+	Markets     []ScenarioMarket `json:"markets"`
+	Trades      []ScenarioTrade  `json:"trades"`
+}
+
+// ScenarioWallet is a wallet as the Data API or subgraph would describe it
+type ScenarioWallet struct {
+	Address          string  `json:"address"`
+	AgeDays          int     `json:"age_days"`           // Days first seen before the scenario starts; 0 = new at its first trade
+	FundingAgeHours  float64 `json:"funding_age_hours"`  // Hours between funding and first activity; 0 when unknown
+	FundingAmountUSD float64 `json:"funding_amount_usd"` // Initial funding; 0 when unknown
+	WinRate          float64 `json:"win_rate"`
+	ResolvedTrades   int     `json:"resolved_trades"`
+	Cluster          string  `json:"cluster"` // Wallets naming the same cluster share a funding source
+}
+
+// ScenarioMarket is a market as Gamma would describe it
+type ScenarioMarket struct {
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	Category     string  `json:"category"`
+	HoursToClose float64 `json:"hours_to_close"` // From the scenario start; 0 = no end date
+	LiquidityUSD float64 `json:"liquidity_usd"`  // 0 when unknown
+}
+
+// ScenarioTrade is one trade and, when labeled, whether it should alert.
+// Unlabeled trades are scored like any other but only build up history.
+type ScenarioTrade struct {
+	Wallet      string  `json:"wallet"`
+	Market      string  `json:"market"`
+	Minute      float64 `json:"minute"`  // Minutes after the scenario starts
+	Side        string  `json:"side"`    // BUY (default) or SELL
+	Outcome     string  `json:"outcome"` // Defaults to Yes
+	Price       float64 `json:"price"`
+	Notional    float64 `json:"notional"`
+	Maker       bool    `json:"maker"`
+	HolderRank  int     `json:"holder_rank"`  // Rank among the outcome's top holders; 0 when not listed
+	HolderShare float64 `json:"holder_share"` // Share of the top holders' combined balance
+	ShouldAlert *bool   `json:"should_alert"`
+}
+
+// LoadScenarios reads every *.json file in dir as a Scenario, in file name
+// order, and checks that each is well formed
+func LoadScenarios(dir string) ([]Scenario, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no scenario files (*.json) in %s", dir)
+	}
+	sort.Strings(paths)
+
+	scenarios := make([]Scenario, 0, len(paths))
+	names := make(map[string]string)
+	for _, path := range paths {
+		body, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var s Scenario
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&s); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if s.Name == "" {
+			s.Name = strings.TrimSuffix(filepath.Base(path), ".json")
+		}
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if other, dup := names[s.Name]; dup {
+			return nil, fmt.Errorf("%s: scenario %q is also defined in %s", path, s.Name, other)
+		}
+		names[s.Name] = path
+		scenarios = append(scenarios, s)
+	}
+	return scenarios, nil
+}
+
+// validate checks references and trade fields, filling in the defaults
+func (s *Scenario) validate() error {
+	wallets := make(map[string]bool, len(s.Wallets))
+	for _, w := range s.Wallets {
+		if w.Address == "" {
+			return fmt.Errorf("a wallet has no address")
+		}
+		if wallets[strings.ToLower(w.Address)] {
+			return fmt.Errorf("wallet %s is listed twice", w.Address)
+		}
+		wallets[strings.ToLower(w.Address)] = true
+	}
+	markets := make(map[string]bool, len(s.Markets))
+	for _, m := range s.Markets {
+		if m.ID == "" {
+			return fmt.Errorf("a market has no id")
+		}
+		if markets[m.ID] {
+			return fmt.Errorf("market %s is listed twice", m.ID)
+		}
+		markets[m.ID] = true
+	}
+	if len(s.Trades) == 0 {
+		return fmt.Errorf("scenario has no trades")
+	}
+
+	for i := range s.Trades {
+		t := &s.Trades[i]
+		if !wallets[strings.ToLower(t.Wallet)] {
+			return fmt.Errorf("trade %d: unknown wallet %q", i, t.Wallet)
+		}
+		if !markets[t.Market] {
+			return fmt.Errorf("trade %d: unknown market %q", i, t.Market)
+		}
+		t.Side = strings.ToUpper(t.Side)
+		if t.Side == "" {
+			t.Side = "BUY"
+		}
+		if t.Side != "BUY" && t.Side != "SELL" {
+			return fmt.Errorf("trade %d: side must be BUY or SELL", i)
+		}
+		if t.Outcome == "" {
+			t.Outcome = "Yes"
+		}
+		if t.Price <= 0 || t.Price >= 1 {
+			return fmt.Errorf("trade %d: price must be between 0 and 1", i)
+		}
+		if t.Notional <= 0 {
+			return fmt.Errorf("trade %d: notional must be positive", i)
+		}
+		if t.Minute < 0 {
+			return fmt.Errorf("trade %d: minute must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// BacktestResult is what the scoring pipeline made of one scenario trade
+type BacktestResult struct {
+	Scenario    string          `json:"scenario"`
+	Trade       int             `json:"trade"` // Index in the scenario's trades
+	Wallet      string          `json:"wallet"`
+	Market      string          `json:"market"`
+	ShouldAlert *bool           `json:"should_alert,omitempty"`
+	Severity    alerts.Severity `json:"severity,omitempty"` // Empty when the trade was filtered out
+	RecordOnly  bool            `json:"record_only,omitempty"`
+	Score       float64         `json:"score"` // Normalized
+	FiredRules  []string        `json:"fired_rules,omitempty"`
+	Filtered    string          `json:"filtered,omitempty"` // Why the trade wasn't scored
+}
+
+// Key identifies the result's trade across runs
+func (r *BacktestResult) Key() string {
+	return r.Scenario + "#" + strconv.Itoa(r.Trade)
+}
+
+// Alerted reports whether the trade notified at WARN or ALERT severity,
+// which is what a labeled trade is judged by
+func (r *BacktestResult) Alerted() bool {
+	return !r.RecordOnly && (r.Severity == alerts.SeverityWarn || r.Severity == alerts.SeverityAlert)
+}
+
+// Correct reports whether a labeled trade's result matches its label
+func (r *BacktestResult) Correct() bool {
+	return r.ShouldAlert != nil && *r.ShouldAlert == r.Alerted()
+}
+
+// BacktestReport is a backtest's results and how the labeled trades fared
+type BacktestReport struct {
+	Results        []BacktestResult `json:"results"`
+	TruePositives  int              `json:"true_positives"`
+	FalsePositives int              `json:"false_positives"`
+	FalseNegatives int              `json:"false_negatives"`
+	TrueNegatives  int              `json:"true_negatives"`
+}
+
+// Precision is the share of alerted labeled trades that should have
+// alerted, or 0 when none alerted
+func (r *BacktestReport) Precision() float64 {
+	if r.TruePositives+r.FalsePositives == 0 {
+		return 0
+	}
+	return float64(r.TruePositives) / float64(r.TruePositives+r.FalsePositives)
+}
+
+// Recall is the share of trades labeled to alert that did, or 0 when none
+// are labeled to
+func (r *BacktestReport) Recall() float64 {
+	if r.TruePositives+r.FalseNegatives == 0 {
+		return 0
+	}
+	return float64(r.TruePositives) / float64(r.TruePositives+r.FalseNegatives)
+}
+
+func (r *BacktestReport) count(result BacktestResult) {
+	if result.ShouldAlert == nil {
+		return
+	}
+	switch alerted := result.Alerted(); {
+	case *result.ShouldAlert && alerted:
+		r.TruePositives++
+	case *result.ShouldAlert:
+		r.FalseNegatives++
+	case alerted:
+		r.FalsePositives++
+	default:
+		r.TrueNegatives++
+	}
+}
+
+// BacktestChange is a trade whose result differs from a previous run's.
+// Before is nil for a trade the previous run didn't have, After for one
+// this run doesn't.
+type BacktestChange struct {
+	Key    string          `json:"key"`
+	Before *BacktestResult `json:"before"`
+	After  *BacktestResult `json:"after"`
+}
+
+// backtestScoreTolerance is the smallest score change DiffBacktest reports
+const backtestScoreTolerance = 0.01
+
+// DiffBacktest returns the trades whose severity, notification or score
+// changed between two runs, in the order of the current run and then the
+// removed trades
+func DiffBacktest(previous, current []BacktestResult) []BacktestChange {
+	before := make(map[string]*BacktestResult, len(previous))
+	for i := range previous {
+		before[previous[i].Key()] = &previous[i]
+	}
+
+	var changes []BacktestChange
+	seen := make(map[string]bool, len(current))
+	for i := range current {
+		after := &current[i]
+		key := after.Key()
+		seen[key] = true
+		prev, ok := before[key]
+		if ok && prev.Severity == after.Severity && prev.RecordOnly == after.RecordOnly &&
+			prev.Filtered == after.Filtered && math.Abs(prev.Score-after.Score) < backtestScoreTolerance {
+			continue
+		}
+		changes = append(changes, BacktestChange{Key: key, Before: prev, After: after})
+	}
+	for i := range previous {
+		if key := previous[i].Key(); !seen[key] {
+			changes = append(changes, BacktestChange{Key: key, Before: &previous[i]})
+		}
+	}
+	return changes
+}
+
+// Backtest runs each scenario's trades, in time order, through the same
+// scoring, minimum size filter and old-wallet gate as live trades, using
+// the current configuration and custom rules. The market filters (category,
+// liquidity floor, end date and horizon) are not applied; scenarios describe
+// markets that pass them. Alert cooldowns are not applied either: every
+// trade is judged on its own score.
+func (p *Processor) Backtest(ctx context.Context, scenarios []Scenario) *BacktestReport {
+	report := &BacktestReport{}
+	for i := range scenarios {
+		for _, result := range p.backtestScenario(ctx, &scenarios[i]) {
+			report.count(result)
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report
+}
+
+// backtestState is what a scenario's trades have left behind so far: what
+// storage would hold at that point of a live run
+type backtestState struct {
+	wallets map[string]*ScenarioWallet
+	markets map[string]*ScenarioMarket
+	seen    map[string]*storage.Wallet // Wallets created by a stored trade
+	trades  []storage.TradeSeen        // Stored trades, in time order
+	alerted map[string]int64           // Wallet -> time of its latest ALERT
+}
+
+func (p *Processor) backtestScenario(ctx context.Context, s *Scenario) []BacktestResult {
+	state := &backtestState{
+		wallets: make(map[string]*ScenarioWallet, len(s.Wallets)),
+		markets: make(map[string]*ScenarioMarket, len(s.Markets)),
+		seen:    make(map[string]*storage.Wallet),
+		alerted: make(map[string]int64),
+	}
+	for i := range s.Wallets {
+		state.wallets[strings.ToLower(s.Wallets[i].Address)] = &s.Wallets[i]
+	}
+	for i := range s.Markets {
+		state.markets[s.Markets[i].ID] = &s.Markets[i]
+	}
+
+	order := make([]int, len(s.Trades))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return s.Trades[order[a]].Minute < s.Trades[order[b]].Minute })
+
+	results := make([]BacktestResult, len(s.Trades))
+	for _, i := range order {
+		results[i] = p.backtestTrade(ctx, state, s.Name, i, &s.Trades[i])
+	}
+	return results
+}
+
+// backtestTrade scores one trade the way processTrade does, with storage
+// replaced by the scenario's state
+func (p *Processor) backtestTrade(ctx context.Context, state *backtestState, scenario string, index int, st *ScenarioTrade) BacktestResult {
+	sw := state.wallets[strings.ToLower(st.Wallet)]
+	sm := state.markets[st.Market]
+	result := BacktestResult{Scenario: scenario, Trade: index, Wallet: sw.Address, Market: sm.ID, ShouldAlert: st.ShouldAlert}
+
+	trade := &dataapi.Trade{
+		ProxyWallet:     sw.Address,
+		Side:            st.Side,
+		ConditionID:     sm.ID,
+		Size:            st.Notional / st.Price,
+		Price:           st.Price,
+		Timestamp:       backtestStart + int64(st.Minute*60),
+		Outcome:         st.Outcome,
+		Title:           sm.Title,
+		TransactionHash: fmt.Sprintf("%s#%d", scenario, index),
+		USDCSize:        st.Notional,
+		Role:            dataapi.RoleTaker,
+	}
+	if st.Maker {
+		trade.Role = dataapi.RoleMaker
+	}
+
+	market := &MarketInfo{
+		Title:        sm.Title,
+		Slug:         sm.ID,
+		Category:     sm.Category,
+		LiquidityNum: sm.LiquidityUSD,
+		TokenIDs:     map[string]string{"Yes": sm.ID + "-yes", "No": sm.ID + "-no"},
+	}
+	if sm.HoursToClose > 0 {
+		market.EndDate = backtestStart + int64(sm.HoursToClose*3600)
+	}
+
+	notional := p.calculateNotional(trade)
+	if notional < p.config().MinTradeUSD {
+		result.Filtered = "size"
+		return result
+	}
+
+	// The wallet is created by its first stored trade
+	wallet, ok := state.seen[strings.ToLower(sw.Address)]
+	if !ok {
+		firstSeen := trade.Timestamp
+		if sw.AgeDays > 0 {
+			firstSeen = backtestStart - int64(sw.AgeDays)*86400
+		}
+		wallet = &storage.Wallet{WalletAddress: sw.Address, FirstSeenTS: firstSeen}
+		if sw.FundingAgeHours > 0 {
+			wallet.FundingReceivedTS = firstSeen - int64(sw.FundingAgeHours*3600)
+		}
+		state.seen[strings.ToLower(sw.Address)] = wallet
+	}
+	isFirstTrade := wallet.TotalTrades == 0
+	priorTrades, priorVolumeUSD := wallet.TotalTrades, wallet.TotalVolumeUSD
+	walletAgeDays := int((trade.Timestamp - wallet.FirstSeenTS) / 86400)
+	var hoursToClose float64
+	if market.EndDate > 0 {
+		hoursToClose = float64(market.EndDate-trade.Timestamp) / 3600.0
+	}
+
+	// Stored before scoring, as processTrade does
+	tradeHash := p.calculateTradeHash(trade)
+	state.trades = append(state.trades, storage.TradeSeen{
+		TradeHash:       tradeHash,
+		TransactionHash: trade.TransactionHash,
+		ConditionID:     trade.ConditionID,
+		ProxyWallet:     trade.ProxyWallet,
+		TimestampSec:    trade.Timestamp,
+		NotionalUSD:     notional,
+		Side:            trade.Side,
+		Outcome:         trade.Outcome,
+		Price:           trade.Price,
+		Role:            tradeRole(trade),
+	})
+	wallet.TotalTrades++
+	wallet.TotalVolumeUSD += notional
+	wallet.LastActivityTS = trade.Timestamp
+
+	var stats *storage.WalletStats
+	var winRate float64
+	if sw.ResolvedTrades > 0 {
+		stats = &storage.WalletStats{WalletAddress: sw.Address, TotalResolvedTrades: sw.ResolvedTrades, WinRate: sw.WinRate}
+		winRate = sw.WinRate
+	}
+	var fundingAmountUSD float64
+	if isFirstTrade {
+		fundingAmountUSD = sw.FundingAmountUSD
+	}
+
+	breakdown, severity := p.evaluate(ctx, &TradeContext{
+		Trade:             trade,
+		Wallet:            wallet,
+		Market:            market,
+		Stats:             stats,
+		Notional:          notional,
+		WalletAgeDays:     walletAgeDays,
+		HoursToClose:      hoursToClose,
+		IsFirstTrade:      isFirstTrade,
+		WinRate:           winRate,
+		FundingAgeHours:   sw.FundingAgeHours,
+		FundingAgeMinutes: sw.FundingAgeHours * 60,
+		FundingAmountUSD:  fundingAmountUSD,
+		PriorTrades:       priorTrades,
+		PriorVolumeUSD:    priorVolumeUSD,
+		Lookups:           backtestLookups{p: p, state: state, trade: st},
+	})
+	severity, notify := p.gateSeverity(severity, walletAgeDays, breakdown.NormalizedScore)
+	if severity == alerts.SeverityAlert {
+		state.alerted[strings.ToLower(sw.Address)] = trade.Timestamp
+	}
+
+	result.Severity = severity
+	result.RecordOnly = !notify
+	result.Score = breakdown.NormalizedScore
+	result.FiredRules = breakdown.FiredRules
+	return result
+}
+
+// backtestLookups answers rule lookups from a scenario's state the way the
+// storage queries behind processorLookups would
+type backtestLookups struct {
+	p     *Processor
+	state *backtestState
+	trade *ScenarioTrade
+}
+
+func (l backtestLookups) VerifiedTradeCount(ctx context.Context, wallet string) (int, error) {
+	return l.state.seen[strings.ToLower(wallet)].TotalTrades, nil
+}
+
+func (l backtestLookups) TradeVelocity(ctx context.Context, wallet string, tradeTS int64) (int, error) {
+	// Like checkTradeVelocity, which counts the stored trade and adds one
+	lookbackTS := tradeTS - int64(l.p.config().VelocityWindowMinutes*60)
+	count := 1
+	for _, t := range l.state.trades {
+		if strings.EqualFold(t.ProxyWallet, wallet) && t.TimestampSec >= lookbackTS {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (l backtestLookups) NetPositionConcentration(ctx context.Context, trade *dataapi.Trade, notional float64) (float64, error) {
+	lookbackTS := trade.Timestamp - int64(l.p.config().ConcentrationWindowHrs)*3600
+	tradeHash := l.p.calculateTradeHash(trade)
+	var buyVolume, sellVolume float64
+	for _, t := range l.state.trades {
+		if !strings.EqualFold(t.ProxyWallet, trade.ProxyWallet) || t.ConditionID != trade.ConditionID || t.TradeHash == tradeHash ||
+			t.TimestampSec < lookbackTS || t.TimestampSec > trade.Timestamp {
+			continue
+		}
+		if t.Side == "BUY" {
+			buyVolume += t.NotionalUSD
+		} else {
+			sellVolume += t.NotionalUSD
+		}
+	}
+	if trade.Side == "BUY" {
+		buyVolume += notional
+	} else {
+		sellVolume += notional
+	}
+	return math.Max(buyVolume, sellVolume) / (buyVolume + sellVolume), nil
+}
+
+// clusterMembers returns the wallets sharing wallet's cluster that have been
+// created so far, including wallet
+func (l backtestLookups) clusterMembers(wallet string) (string, []string) {
+	cluster := l.state.wallets[strings.ToLower(wallet)].Cluster
+	if cluster == "" {
+		return "", nil
+	}
+	var members []string
+	for address, w := range l.state.wallets {
+		if _, created := l.state.seen[address]; created && w.Cluster == cluster {
+			members = append(members, address)
+		}
+	}
+	return cluster, members
+}
+
+func (l backtestLookups) CoordinatedTrade(ctx context.Context, trade *dataapi.Trade) (bool, string, string, error) {
+	cluster, members := l.clusterMembers(trade.ProxyWallet)
+	if len(members) <= 1 {
+		return false, "", "", nil
+	}
+
+	// As detectCoordinatedTrade: the cluster's trades in this market within
+	// the lookback, the stored current trade among them
+	lookbackTS := trade.Timestamp - int64(l.p.config().ClusterLookbackHours*3600)
+	firstTS, lastTS := trade.Timestamp, trade.Timestamp
+	uniqueWallets := map[string]bool{strings.ToLower(trade.ProxyWallet): true}
+	pattern := alerts.CoordinationSameSide
+	found := false
+	for _, t := range l.state.trades {
+		address := strings.ToLower(t.ProxyWallet)
+		if t.ConditionID != trade.ConditionID || t.TimestampSec < lookbackTS || l.state.wallets[address].Cluster != cluster {
+			continue
+		}
+		found = true
+		uniqueWallets[address] = true
+		if t.TimestampSec < firstTS {
+			firstTS = t.TimestampSec
+		}
+		if t.TimestampSec > lastTS {
+			lastTS = t.TimestampSec
+		}
+		if !strings.EqualFold(t.ProxyWallet, trade.ProxyWallet) && !sameDirection(trade.Outcome, trade.Side, t.Outcome, t.Side) {
+			pattern = alerts.CoordinationOpposing
+		}
+	}
+	if !found || lastTS-firstTS > 3600 || len(uniqueWallets) < 2 {
+		return false, "", "", nil
+	}
+	return true, pattern, cluster, nil
+}
+
+func (l backtestLookups) ClusterMultiplier(ctx context.Context, wallet string) float64 {
+	_, members := l.clusterMembers(wallet)
+	return clusterSizeMultiplier(len(members))
+}
+
+func (l backtestLookups) RecentTradeSizes(ctx context.Context, trade *dataapi.Trade, limit int) ([]float64, error) {
+	tradeHash := l.p.calculateTradeHash(trade)
+	var sizes []float64
+	for i := len(l.state.trades) - 1; i >= 0 && len(sizes) < limit; i-- {
+		t := l.state.trades[i]
+		if strings.EqualFold(t.ProxyWallet, trade.ProxyWallet) && t.TradeHash != tradeHash {
+			sizes = append(sizes, t.NotionalUSD)
+		}
+	}
+	return sizes, nil
+}
+
+func (l backtestLookups) CopyTradeLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error) {
+	// Trades in this market within the copy window by wallets with an ALERT
+	// in the flagged window, oldest first, as GetFlaggedWalletTrades
+	flaggedSinceTS := trade.Timestamp - int64(l.p.config().CopyTradeFlaggedDays)*86400
+	sinceTS := trade.Timestamp - int64(l.p.config().CopyTradeWindowMins*60)
+	var candidates []storage.TradeSeen
+	for _, t := range l.state.trades {
+		alertedTS, flagged := l.state.alerted[strings.ToLower(t.ProxyWallet)]
+		if flagged && alertedTS >= flaggedSinceTS && t.ConditionID == trade.ConditionID &&
+			t.TimestampSec >= sinceTS && t.TimestampSec <= trade.Timestamp {
+			candidates = append(candidates, t)
+		}
+	}
+	return pickCopyLeader(trade, candidates), nil
+}
+
+func (l backtestLookups) HolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error) {
+	return l.trade.HolderRank, l.trade.HolderShare, nil
+}
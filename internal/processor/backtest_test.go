@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+// TestShippedBacktestScenarios runs the scenarios in backtest/ with the
+// default configuration and expects every labeled trade to come out as
+// labeled, so a scoring change that breaks one shows up here first
+func TestShippedBacktestScenarios(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("got %v loading the default configuration, want no error", err)
+	}
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := New(cfg, nil, nil, nil, nil, nil, nil, log)
+
+	scenarios, err := LoadScenarios(filepath.Join("..", "..", "backtest"))
+	if err != nil {
+		t.Fatalf("got %v loading the scenarios, want no error", err)
+	}
+	report := p.Backtest(context.Background(), scenarios)
+
+	for i := range report.Results {
+		r := &report.Results[i]
+		if r.ShouldAlert != nil && !r.Correct() {
+			t.Errorf("%s: got severity %s (record only %v, score %.1f, rules %v), want should_alert %v",
+				r.Key(), r.Severity, r.RecordOnly, r.Score, r.FiredRules, *r.ShouldAlert)
+		}
+	}
+	if report.FalsePositives != 0 || report.FalseNegatives != 0 {
+		t.Errorf("got %d false positives and %d false negatives, want none", report.FalsePositives, report.FalseNegatives)
+	}
+}
+
+func TestLoadScenarios(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		expectedErr string
+		description string
+	}{
+		{
+			name:        "valid",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xA","market":"m","price":0.5,"notional":10000}]}`,
+			description: "Wallet addresses match case-insensitively",
+		},
+		{
+			name:        "unknown field",
+			body:        `{"wallets":[{"address":"0xa","age":3}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xa","market":"m","price":0.5,"notional":10000}]}`,
+			expectedErr: `unknown field "age"`,
+			description: "A misspelt field is an error rather than silently ignored",
+		},
+		{
+			name:        "unknown wallet",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xb","market":"m","price":0.5,"notional":10000}]}`,
+			expectedErr: `unknown wallet "0xb"`,
+			description: "Trades must name a listed wallet",
+		},
+		{
+			name:        "unknown market",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xa","market":"x","price":0.5,"notional":10000}]}`,
+			expectedErr: `unknown market "x"`,
+			description: "Trades must name a listed market",
+		},
+		{
+			name:        "bad side",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xa","market":"m","side":"HOLD","price":0.5,"notional":10000}]}`,
+			expectedErr: "side must be BUY or SELL",
+			description: "Only BUY and SELL are sides",
+		},
+		{
+			name:        "bad price",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}],"trades":[{"wallet":"0xa","market":"m","price":1,"notional":10000}]}`,
+			expectedErr: "price must be between 0 and 1",
+			description: "Prices are probabilities",
+		},
+		{
+			name:        "no trades",
+			body:        `{"wallets":[{"address":"0xa"}],"markets":[{"id":"m"}]}`,
+			expectedErr: "scenario has no trades",
+			description: "An empty scenario is almost certainly a mistake",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "case.json"), []byte(tt.body), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			scenarios, err := LoadScenarios(dir)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+				}
+				if scenarios[0].Name != "case" || scenarios[0].Trades[0].Side != "BUY" || scenarios[0].Trades[0].Outcome != "Yes" {
+					t.Errorf("got %+v, want name and side/outcome defaults filled in\nDescription: %s", scenarios[0], tt.description)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("got error %v, want one containing %q\nDescription: %s", err, tt.expectedErr, tt.description)
+			}
+		})
+	}
+}
+
+func TestDiffBacktest(t *testing.T) {
+	result := func(trade int, severity alerts.Severity, score float64) BacktestResult {
+		return BacktestResult{Scenario: "s", Trade: trade, Severity: severity, Score: score}
+	}
+
+	tests := []struct {
+		name         string
+		previous     []BacktestResult
+		current      []BacktestResult
+		expectedKeys []string
+		description  string
+	}{
+		{
+			name:        "unchanged",
+			previous:    []BacktestResult{result(0, alerts.SeverityWarn, 80)},
+			current:     []BacktestResult{result(0, alerts.SeverityWarn, 80.001)},
+			description: "Score noise below the tolerance isn't a change",
+		},
+		{
+			name:         "score changed",
+			previous:     []BacktestResult{result(0, alerts.SeverityWarn, 80)},
+			current:      []BacktestResult{result(0, alerts.SeverityWarn, 82)},
+			expectedKeys: []string{"s#0"},
+			description:  "A score change is reported even when the severity holds",
+		},
+		{
+			name:         "severity changed",
+			previous:     []BacktestResult{result(0, alerts.SeverityWarn, 84.9)},
+			current:      []BacktestResult{result(0, alerts.SeverityAlert, 84.9)},
+			expectedKeys: []string{"s#0"},
+			description:  "A severity change is reported",
+		},
+		{
+			name:         "added and removed",
+			previous:     []BacktestResult{result(0, alerts.SeverityInfo, 40), result(1, alerts.SeverityInfo, 40)},
+			current:      []BacktestResult{result(0, alerts.SeverityInfo, 40), result(2, alerts.SeverityInfo, 40)},
+			expectedKeys: []string{"s#2", "s#1"},
+			description:  "New trades come in the current run's order, removed ones after",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := DiffBacktest(tt.previous, tt.current)
+			var keys []string
+			for _, c := range changes {
+				keys = append(keys, c.Key)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.expectedKeys, ",") {
+				t.Errorf("got changes %v, want %v\nDescription: %s", keys, tt.expectedKeys, tt.description)
+			}
+		})
+	}
+}
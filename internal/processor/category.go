@@ -0,0 +1,86 @@
+package processor
+
+import "strings"
+
+// Market categories used as metric labels. Gamma's categories are free
+// text, so they are folded into this fixed set to keep label cardinality
+// bounded; anything unrecognised is categoryOther.
+const (
+	categorySports   = "sports"
+	categoryPolitics = "politics"
+	categoryCrypto   = "crypto"
+	categoryBusiness = "business"
+	categoryScience  = "science"
+	categoryCulture  = "culture"
+	categoryOther    = "other"
+)
+
+// sportsKeywords identify sports markets by category or slug. Trade slugs
+// are matched too because Gamma often leaves sports markets uncategorised.
+var sportsKeywords = []string{
+	"sports",
+	"nfl",
+	"nba",
+	"mlb",
+	"nhl",
+	"soccer",
+	"football",
+	"basketball",
+	"baseball",
+	"hockey",
+	"mma",
+	"ufc",
+	"boxing",
+	"tennis",
+	"golf",
+	"racing",
+	"f1",
+	"nascar",
+}
+
+// categoryKeywords map Gamma category substrings to the other categories,
+// checked in order
+var categoryKeywords = []struct {
+	category string
+	keywords []string
+}{
+	{categoryPolitics, []string{"politic", "election", "government", "world"}},
+	{categoryCrypto, []string{"crypto", "bitcoin", "ethereum"}},
+	{categoryBusiness, []string{"business", "econom", "financ", "stock", "earnings", "tech"}},
+	{categoryScience, []string{"science", "climate", "weather", "space"}},
+	{categoryCulture, []string{"culture", "entertainment", "music", "movie", "film", "award", "celebrit", "mention"}},
+}
+
+// normalizeCategory folds a market's Gamma category into the fixed set of
+// categories. Sports is checked first, against the slug as well, so it
+// agrees with the sports filter.
+func normalizeCategory(category, slug string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	slug = strings.ToLower(slug)
+
+	for _, keyword := range sportsKeywords {
+		if strings.Contains(category, keyword) || strings.Contains(slug, keyword) {
+			return categorySports
+		}
+	}
+	if category == "" {
+		return categoryOther
+	}
+	for _, c := range categoryKeywords {
+		for _, keyword := range c.keywords {
+			if strings.Contains(category, keyword) {
+				return c.category
+			}
+		}
+	}
+	return categoryOther
+}
+
+// marketCategory returns a market's normalized category, or categoryOther
+// when the market couldn't be resolved
+func marketCategory(market *MarketInfo) string {
+	if market == nil {
+		return categoryOther
+	}
+	return normalizeCategory(market.Category, market.Slug)
+}
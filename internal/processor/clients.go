@@ -0,0 +1,43 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/subgraph"
+)
+
+// DataAPI is the part of the Data API client the processor uses.
+// *dataapi.Client implements it; processortest has a fake.
+type DataAPI interface {
+	GetTrades(ctx context.Context, params dataapi.TradeParams) (*dataapi.TradesResponse, error)
+	GetWalletFirstActivity(ctx context.Context, wallet string) (*dataapi.ActivityEvent, error)
+	GetWalletActivity(ctx context.Context, wallet string, limit int) ([]dataapi.ActivityEvent, error)
+	GetPositions(ctx context.Context, wallet string) ([]dataapi.Position, error)
+	GetHolders(ctx context.Context, conditionID string, limit int) ([]dataapi.TokenHolders, error)
+}
+
+// GammaAPI is the part of the Gamma API client the processor uses.
+// *gammaapi.Client implements it; processortest has a fake.
+type GammaAPI interface {
+	GetMarketByConditionID(ctx context.Context, conditionID string) (*gammaapi.Market, error)
+	GetMarketByConditionIDIfModified(ctx context.Context, conditionID string, v gammaapi.Validators) (*gammaapi.Market, gammaapi.Validators, error)
+	GetMarketsByConditionIDs(ctx context.Context, conditionIDs []string) (map[string]*gammaapi.Market, error)
+	GetMarketBySlug(ctx context.Context, slug string) (*gammaapi.Market, error)
+	GetEventBySlug(ctx context.Context, slug string) (*gammaapi.Event, error)
+	ListRecentMarkets(ctx context.Context, limit, offset int) ([]gammaapi.Market, error)
+}
+
+// SubgraphAPI is the part of the subgraph client the processor uses.
+// *subgraph.Client implements it; processortest has a fake.
+type SubgraphAPI interface {
+	GetWalletHistory(ctx context.Context, wallet string) (*subgraph.WalletHistory, error)
+	GetWalletOutflows(ctx context.Context, wallet string) ([]subgraph.Transfer, error)
+}
+
+var (
+	_ DataAPI     = (*dataapi.Client)(nil)
+	_ GammaAPI    = (*gammaapi.Client)(nil)
+	_ SubgraphAPI = (*subgraph.Client)(nil)
+)
@@ -0,0 +1,79 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+)
+
+// eventCacheTTL is how long a Gamma event is reused. Events are shared by
+// many markets and their end dates and tags rarely change.
+const eventCacheTTL = 6 * time.Hour
+
+// eventCache holds Gamma events by slug. A nil event records that Gamma
+// doesn't know the slug, so it isn't requested again until the entry expires.
+type eventCache struct {
+	mu      sync.Mutex
+	entries map[string]eventEntry
+}
+
+type eventEntry struct {
+	event     *gammaapi.Event
+	fetchedAt time.Time
+}
+
+func (c *eventCache) get(slug string, now time.Time) (*gammaapi.Event, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[slug]
+	if !ok {
+		return nil, false
+	}
+	if now.Sub(entry.fetchedAt) > eventCacheTTL {
+		delete(c.entries, slug)
+		return nil, false
+	}
+	return entry.event, true
+}
+
+func (c *eventCache) set(slug string, event *gammaapi.Event, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]eventEntry)
+	}
+	// Drop expired entries so the map doesn't grow without bound
+	for s, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > eventCacheTTL {
+			delete(c.entries, s)
+		}
+	}
+	c.entries[slug] = eventEntry{event: event, fetchedAt: now}
+}
+
+// getEvent returns the Gamma event with the given slug, from the cache when
+// possible. Transient failures are not cached.
+func (p *Processor) getEvent(ctx context.Context, slug string) (*gammaapi.Event, error) {
+	now := p.clock.Now()
+	if event, ok := p.events.get(slug, now); ok {
+		if event == nil {
+			return nil, fmt.Errorf("event %s: %w", slug, gammaapi.ErrEventNotFound)
+		}
+		return event, nil
+	}
+
+	event, err := p.gammaClient.GetEventBySlug(ctx, slug)
+	if errors.Is(err, gammaapi.ErrEventNotFound) {
+		p.events.set(slug, nil, now)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.events.set(slug, event, now)
+	return event, nil
+}
@@ -0,0 +1,286 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/errreport"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// exitScanTradeLimit is how many of a wallet's most recent trades in a
+// market the exit scan fetches
+const exitScanTradeLimit = 100
+
+// trackAlertPosition records the position behind an alert so a later exit
+// by the same wallet can be reported
+func (p *Processor) trackAlertPosition(ctx context.Context, alertID int64, trade *dataapi.Trade, severity alerts.Severity, profile string, notional float64) {
+	shares := trade.Size
+	if shares <= 0 && trade.Price > 0 {
+		shares = notional / trade.Price
+	}
+	if shares <= 0 {
+		return
+	}
+
+	now := p.clock.Now().Unix()
+	pos := &storage.AlertPosition{
+		AlertID:       alertID,
+		WalletAddress: trade.ProxyWallet,
+		ConditionID:   trade.ConditionID,
+		Outcome:       trade.Outcome,
+		Side:          trade.Side,
+		Severity:      string(severity),
+		Profile:       profile,
+		Shares:        shares,
+		EntryPrice:    trade.Price,
+		NotionalUSD:   notional,
+		EntryTS:       trade.Timestamp,
+		Status:        storage.PositionOpen,
+		CreatedTS:     now,
+		UpdatedTS:     now,
+	}
+	if err := p.db.InsertAlertPosition(ctx, pos); err != nil {
+		p.log.WithError(err).WithField("alert_id", alertID).Warn("Failed to track alerted position")
+	}
+}
+
+// checkPositionExit counts a trade against the wallet's open alerted
+// positions it reverses, and sends a follow-up alert once enough of a
+// position has been reversed
+func (p *Processor) checkPositionExit(ctx context.Context, trade *dataapi.Trade, tradeHash string, marketInfo *MarketInfo) error {
+	positions, err := p.db.GetOpenAlertPositions(ctx, trade.ProxyWallet, trade.ConditionID)
+	if err != nil {
+		return fmt.Errorf("get open alert positions: %w", err)
+	}
+
+	remaining := trade.Size
+	for i := range positions {
+		pos := &positions[i]
+		if remaining <= 0 {
+			break
+		}
+		if !reversesPosition(pos, trade) {
+			continue
+		}
+
+		// One trade may close several alerted positions in turn
+		shares := math.Min(remaining, pos.Shares-pos.ReversedShares)
+		if shares <= 0 {
+			continue
+		}
+		counted, err := p.db.InsertAlertPositionExit(ctx, &storage.AlertPositionExit{
+			AlertID:   pos.AlertID,
+			TradeHash: tradeHash,
+			Shares:    shares,
+			Price:     trade.Price,
+			TradeTS:   trade.Timestamp,
+			CreatedTS: p.clock.Now().Unix(),
+		})
+		if err != nil {
+			return fmt.Errorf("record alert position exit: %w", err)
+		}
+		if !counted {
+			continue // Already counted from the other ingest path
+		}
+		remaining -= shares
+
+		pos.ReversedShares += shares
+		pos.ReversedNotional += shares * trade.Price
+		pos.UpdatedTS = p.clock.Now().Unix()
+		exited := pos.ReversedShares/pos.Shares >= p.config().ExitAlertFraction
+		if exited {
+			pos.Status = storage.PositionExited
+			pos.ExitedTS = trade.Timestamp
+		}
+		if err := p.db.UpdateAlertPosition(ctx, pos); err != nil {
+			return fmt.Errorf("update alert position: %w", err)
+		}
+
+		if exited {
+			if err := p.sendExitAlert(ctx, trade, pos, marketInfo); err != nil {
+				p.log.WithError(err).Error("Failed to send exit alert")
+				tags := tradeTags(trade, "")
+				tags["alert_kind"] = "exit"
+				errreport.Capture(errreport.Event{Err: err, Source: "alert_send", Tags: tags})
+			}
+		}
+	}
+
+	return nil
+}
+
+// ScanPositionExits fetches the recent trades of wallets with open alerted
+// positions and counts them against those positions. The poll and the live
+// feed only see trades of at least BIG_TRADE_USD, and most exits, especially
+// partial ones, are smaller. Markets that have ended are skipped. It returns
+// the number of markets scanned.
+func (p *Processor) ScanPositionExits(ctx context.Context) (int, error) {
+	if !p.config().EnableExitAlerts {
+		return 0, nil
+	}
+	if !p.exitScanRunning.CompareAndSwap(false, true) {
+		p.log.Debug("Exit scan already running, skipping")
+		return 0, nil
+	}
+	defer p.exitScanRunning.Store(false)
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	// Yield to the poll and alert path on a shared rate limit budget
+	ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityBackground)
+
+	markets, err := p.db.GetOpenPositionMarkets(ctx, p.clock.Now().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("get open position markets: %w", err)
+	}
+
+	scanned := 0
+	for _, m := range markets {
+		if ctx.Err() != nil {
+			return scanned, ctx.Err()
+		}
+		trades, err := p.walletMarketTrades(ctx, m.WalletAddress, m.ConditionID)
+		if err != nil {
+			p.log.WithError(err).WithFields(logrus.Fields{
+				"wallet":       m.WalletAddress,
+				"condition_id": m.ConditionID,
+			}).Warn("Failed to fetch alerted wallet trades")
+			continue
+		}
+		scanned++
+
+		// Oldest first, so positions fill up in trading order
+		for i := len(trades) - 1; i >= 0; i-- {
+			trade := &trades[i]
+			if trade.Timestamp < m.EntryTS || !p.acceptTrade(trade, nil) {
+				continue
+			}
+			if err := p.checkTradeExit(ctx, trade); err != nil {
+				p.log.WithError(err).WithField("wallet", trade.ProxyWallet).Warn("Failed to check alerted position exit")
+			}
+		}
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"markets": len(markets),
+		"scanned": scanned,
+	}).Debug("Exit scan complete")
+	return scanned, nil
+}
+
+// walletMarketTrades fetches a wallet's most recent trades in a market of
+// any size, newest first, with maker fills when INCLUDE_MAKER_TRADES is set
+func (p *Processor) walletMarketTrades(ctx context.Context, wallet, conditionID string) ([]dataapi.Trade, error) {
+	params := dataapi.TradeParams{
+		Limit:         exitScanTradeLimit,
+		TakerOnly:     true,
+		Market:        conditionID,
+		User:          wallet,
+		SortBy:        "timestamp",
+		SortDirection: "DESC",
+	}
+	resp, err := p.dataClient.GetTrades(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	trades := resp.Trades
+	for i := range trades {
+		trades[i].Role = dataapi.RoleTaker
+	}
+	if p.config().IncludeMakerTrades {
+		params.TakerOnly = false
+		all, err := p.dataClient.GetTrades(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		trades = mergeMakerTrades(trades, all.Trades)
+	}
+	return trades, nil
+}
+
+// checkTradeExit counts a trade the exit scan fetched against the wallet's
+// open alerted positions. It is keyed like processTrade keys the trade, so
+// one the poll or feed already counted isn't counted again.
+func (p *Processor) checkTradeExit(ctx context.Context, trade *dataapi.Trade) error {
+	if trade.Side != "BUY" && trade.Side != "SELL" {
+		return nil
+	}
+	if p.config().NormalizeOutcomes {
+		trade.Outcome = normalizeOutcome(trade.Outcome)
+	}
+	marketInfo, err := p.resolveMarket(ctx, trade)
+	if err != nil {
+		marketInfo = nil // The alert falls back to the trade's title
+	}
+	return p.checkPositionExit(ctx, trade, p.calculateTradeHash(trade), marketInfo)
+}
+
+func (p *Processor) sendExitAlert(ctx context.Context, trade *dataapi.Trade, pos *storage.AlertPosition, marketInfo *MarketInfo) error {
+	exitPrice := pos.ReversedNotional / pos.ReversedShares
+	exit := &alerts.ExitDetails{
+		OriginalAlertID:  pos.AlertID,
+		AlertedAt:        time.Unix(pos.CreatedTS, 0),
+		EntrySide:        pos.Side,
+		EntryPrice:       pos.EntryPrice,
+		EntryNotionalUSD: pos.NotionalUSD,
+		ExitPrice:        exitPrice,
+		ExitedFraction:   pos.ReversedShares / pos.Shares,
+		PnLUSD:           positionPnL(pos.Side, pos.EntryPrice, exitPrice, pos.ReversedShares),
+	}
+
+	title, url := trade.Title, ""
+	if marketInfo != nil {
+		title, url = marketInfo.Title, marketInfo.URL
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"wallet":            trade.ProxyWallet,
+		"condition_id":      trade.ConditionID,
+		"original_alert_id": pos.AlertID,
+		"exited_pct":        exit.ExitedFraction * 100,
+		"pnl_usd":           exit.PnLUSD,
+	}).Info("Alerted wallet exited its position")
+	metrics.ExitAlerts.Inc()
+
+	payload := &alerts.AlertPayload{
+		Severity:        alerts.Severity(pos.Severity),
+		WalletAddress:   trade.ProxyWallet,
+		WalletShort:     shortenAddress(trade.ProxyWallet),
+		MarketTitle:     title,
+		MarketURL:       url,
+		Side:            trade.Side,
+		Outcome:         trade.Outcome,
+		NotionalUSD:     p.calculateNotional(trade),
+		Price:           trade.Price,
+		TransactionHash: trade.TransactionHash,
+		TxHashShort:     shortenHash(trade.TransactionHash),
+		Timestamp:       time.Unix(trade.Timestamp, 0),
+		Environment:     p.config().Environment,
+		Exit:            exit,
+	}
+	return p.senderFor(pos.Profile).Send(ctx, payload)
+}
+
+// reversesPosition reports whether a trade unwinds an alerted position:
+// the same outcome traded on the opposite side, no earlier than the entry
+func reversesPosition(pos *storage.AlertPosition, trade *dataapi.Trade) bool {
+	return strings.EqualFold(pos.Outcome, trade.Outcome) && !strings.EqualFold(pos.Side, trade.Side) && trade.Timestamp >= pos.EntryTS
+}
+
+// positionPnL returns the implied profit on shares bought (or sold) at
+// entryPrice and reversed at exitPrice
+func positionPnL(entrySide string, entryPrice, exitPrice, shares float64) float64 {
+	if strings.EqualFold(entrySide, "SELL") {
+		return (entryPrice - exitPrice) * shares
+	}
+	return (exitPrice - entryPrice) * shares
+}
@@ -0,0 +1,48 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+)
+
+// WalletExposure is a wallet's open positions according to the Data API
+type WalletExposure struct {
+	Wallet    string             `json:"wallet"`
+	TotalUSD  float64            `json:"total_usd"` // Current value of the open positions
+	Markets   int                `json:"markets"`
+	Positions []dataapi.Position `json:"positions"`
+}
+
+// WalletExposure fetches a wallet's current positions and totals them
+func (p *Processor) WalletExposure(ctx context.Context, wallet string) (*WalletExposure, error) {
+	positions, err := p.dataClient.GetPositions(ctx, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("get positions: %w", err)
+	}
+
+	totalUSD, markets := summarizeExposure(positions)
+	return &WalletExposure{
+		Wallet:    wallet,
+		TotalUSD:  totalUSD,
+		Markets:   markets,
+		Positions: positions,
+	}, nil
+}
+
+// summarizeExposure returns the current value of the open positions and the
+// number of markets they span. Resolved positions awaiting redemption are
+// no longer exposure.
+func summarizeExposure(positions []dataapi.Position) (float64, int) {
+	var totalUSD float64
+	markets := make(map[string]bool)
+	for _, pos := range positions {
+		if pos.Size <= 0 || pos.Redeemable {
+			continue
+		}
+		totalUSD += pos.CurrentValue
+		markets[pos.ConditionID] = true
+	}
+	return totalUSD, len(markets)
+}
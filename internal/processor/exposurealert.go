@@ -0,0 +1,123 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// checkAggregateExposure sums a new wallet's tracked buys across markets
+// over the exposure window, and sends an aggregate exposure alert when they
+// reach EXPOSURE_ALERT_USD. Spreading a position across markets keeps each
+// trade under the single-trade thresholds, so this looks at the total. A
+// wallet alerts again only after EXPOSURE_COOLDOWN_HOURS.
+func (p *Processor) checkAggregateExposure(ctx context.Context, trade *dataapi.Trade, tradeHash string, wallet *storage.Wallet, walletAgeDays int, marketInfo *MarketInfo) error {
+	cfg := p.config()
+
+	// Concurrent trades by the wallet would each see the other's buys and
+	// alert twice
+	p.exposureMu.Lock()
+	defer p.exposureMu.Unlock()
+
+	last, err := p.db.GetLastExposureAlert(ctx, trade.ProxyWallet)
+	if err != nil {
+		return fmt.Errorf("get last exposure alert: %w", err)
+	}
+	if last != nil && p.clock.Now().Unix()-last.CreatedTS < int64(cfg.ExposureCooldownHours)*3600 {
+		return nil
+	}
+
+	windowStartTS := trade.Timestamp - int64(cfg.ExposureWindowHours)*3600
+	markets, err := p.db.GetWalletExposure(ctx, trade.ProxyWallet, windowStartTS)
+	if err != nil {
+		return fmt.Errorf("get wallet exposure: %w", err)
+	}
+	totalUSD, crossed := exposureCrossed(markets, cfg.ExposureAlertUSD, cfg.ExposureMinMarkets)
+	if !crossed {
+		return nil
+	}
+
+	if err := p.db.InsertExposureAlert(ctx, &storage.ExposureAlert{
+		WalletAddress: trade.ProxyWallet,
+		TradeHash:     tradeHash,
+		TotalUSD:      totalUSD,
+		Markets:       len(markets),
+		WindowStartTS: windowStartTS,
+		CreatedTS:     p.clock.Now().Unix(),
+	}); err != nil {
+		return fmt.Errorf("record exposure alert: %w", err)
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"wallet":          trade.ProxyWallet,
+		"wallet_age_days": walletAgeDays,
+		"total_usd":       totalUSD,
+		"markets":         len(markets),
+		"window_hours":    cfg.ExposureWindowHours,
+	}).Info("New wallet crossed the aggregate exposure threshold")
+	metrics.ExposureAlerts.Inc()
+
+	return p.sendExposureAlert(ctx, trade, wallet, walletAgeDays, marketInfo, totalUSD, windowStartTS, markets)
+}
+
+func (p *Processor) sendExposureAlert(ctx context.Context, trade *dataapi.Trade, wallet *storage.Wallet, walletAgeDays int, marketInfo *MarketInfo, totalUSD float64, windowStartTS int64, markets []storage.WalletMarketExposure) error {
+	agg := &alerts.AggregateExposure{
+		TotalUSD:    totalUSD,
+		WindowHours: p.config().ExposureWindowHours,
+		WindowStart: time.Unix(windowStartTS, 0),
+		Markets:     make([]alerts.ExposureMarket, 0, len(markets)),
+	}
+	for _, m := range markets {
+		title := m.MarketTitle
+		if title == "" {
+			title = m.ConditionID
+		}
+		agg.Markets = append(agg.Markets, alerts.ExposureMarket{
+			MarketTitle: title,
+			MarketURL:   m.MarketURL,
+			NotionalUSD: m.NotionalUSD,
+			Trades:      m.Trades,
+		})
+	}
+
+	title, url := trade.Title, ""
+	if marketInfo != nil {
+		title, url = marketInfo.Title, marketInfo.URL
+	}
+
+	payload := &alerts.AlertPayload{
+		Severity:        alerts.SeverityAlert,
+		WalletAddress:   trade.ProxyWallet,
+		WalletShort:     shortenAddress(trade.ProxyWallet),
+		MarketTitle:     title,
+		MarketURL:       url,
+		Side:            trade.Side,
+		Outcome:         trade.Outcome,
+		NotionalUSD:     p.calculateNotional(trade),
+		Price:           trade.Price,
+		WalletAgeDays:   walletAgeDays,
+		FirstSeenDate:   time.Unix(wallet.FirstSeenTS, 0).Format("2006-01-02"),
+		TransactionHash: trade.TransactionHash,
+		TxHashShort:     shortenHash(trade.TransactionHash),
+		Timestamp:       time.Unix(trade.Timestamp, 0),
+		Environment:     p.config().Environment,
+		Aggregate:       agg,
+	}
+	return p.senderFor("").Send(ctx, payload)
+}
+
+// exposureCrossed returns the total of a wallet's per-market buys and
+// whether it reaches thresholdUSD across at least minMarkets markets
+func exposureCrossed(markets []storage.WalletMarketExposure, thresholdUSD float64, minMarkets int) (float64, bool) {
+	var totalUSD float64
+	for _, m := range markets {
+		totalUSD += m.NotionalUSD
+	}
+	return totalUSD, len(markets) >= minMarkets && totalUSD >= thresholdUSD
+}
@@ -0,0 +1,120 @@
+package processor
+
+import (
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+)
+
+// aggregateFills merges trade entries that belong to the same order.
+// The Data API reports each fill of a large order as a separate entry sharing
+// a transaction hash; processing them individually wastes API calls and can
+// double-count wallet volume. Sizes and notionals are summed and the price
+// becomes the size-weighted average, so calculateNotional reflects the whole
+// order. Entries without a transaction hash are grouped by wallet, market,
+// outcome, side and timestamp instead.
+func (p *Processor) aggregateFills(trades []dataapi.Trade) []dataapi.Trade {
+	type fillGroup struct {
+		trade         dataapi.Trade
+		weightedPrice float64
+		fillCount     int
+	}
+
+	groups := make(map[string]*fillGroup)
+	var order []string
+
+	for _, trade := range trades {
+		key := fillKey(&trade)
+		notional := p.calculateNotional(&trade)
+
+		group, ok := groups[key]
+		if !ok {
+			merged := trade
+			merged.USDCSize = notional
+			groups[key] = &fillGroup{
+				trade:         merged,
+				weightedPrice: trade.Price * trade.Size,
+				fillCount:     1,
+			}
+			order = append(order, key)
+			continue
+		}
+
+		group.trade.Size += trade.Size
+		group.trade.USDCSize += notional
+		group.weightedPrice += trade.Price * trade.Size
+		group.fillCount++
+		if trade.Timestamp < group.trade.Timestamp {
+			group.trade.Timestamp = trade.Timestamp
+		}
+	}
+
+	result := make([]dataapi.Trade, 0, len(order))
+	for _, key := range order {
+		group := groups[key]
+		if group.fillCount > 1 {
+			if group.trade.Size > 0 {
+				group.trade.Price = group.weightedPrice / group.trade.Size
+			}
+			metrics.TradesProcessed.WithLabelValues("aggregated_fill").Add(float64(group.fillCount - 1))
+		}
+		result = append(result, group.trade)
+	}
+
+	if merged := len(trades) - len(result); merged > 0 {
+		p.log.WithField("merged_fills", merged).Debug("Aggregated same-transaction fills")
+	}
+
+	return result
+}
+
+// mergeMakerTrades adds the maker fills from an unfiltered (takerOnly=false)
+// fetch to the taker trades. The unfiltered response repeats every taker
+// trade, so entries matching a taker trade are dropped and the rest are
+// tagged as maker fills.
+func mergeMakerTrades(takers, all []dataapi.Trade) []dataapi.Trade {
+	takerKeys := make(map[string]bool, len(takers))
+	for i := range takers {
+		takerKeys[fillKey(&takers[i])] = true
+	}
+
+	merged := takers
+	seen := make(map[string]bool)
+	for _, trade := range all {
+		key := fillKey(&trade)
+		if takerKeys[key] {
+			continue
+		}
+		// Multiple fills of the same maker order are kept for aggregateFills,
+		// but an identical entry returned twice is dropped
+		entryKey := fmt.Sprintf("%s:%.6f:%.6f:%d", key, trade.Size, trade.Price, trade.Timestamp)
+		if seen[entryKey] {
+			continue
+		}
+		seen[entryKey] = true
+
+		trade.Role = dataapi.RoleMaker
+		merged = append(merged, trade)
+	}
+	return merged
+}
+
+// tradeRole returns the trade's role, treating untagged trades (such as those
+// from the live feed) as takers
+func tradeRole(trade *dataapi.Trade) string {
+	if trade.Role == "" {
+		return dataapi.RoleTaker
+	}
+	return trade.Role
+}
+
+// fillKey identifies the order a fill belongs to
+func fillKey(trade *dataapi.Trade) string {
+	if trade.TransactionHash != "" {
+		return fmt.Sprintf("%s:%s:%s:%s:%s",
+			trade.TransactionHash, trade.ProxyWallet, trade.ConditionID, trade.Outcome, trade.Side)
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:%d",
+		trade.ProxyWallet, trade.ConditionID, trade.Outcome, trade.Side, trade.Timestamp)
+}
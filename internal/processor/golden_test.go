@@ -0,0 +1,76 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+var updateGolden = flag.Bool("update", false, "Rewrite testdata/golden_scores.json from the current scoring")
+
+// goldenCase is one fixture trade in testdata/golden_scores.json and the
+// result the default configuration gives it
+type goldenCase struct {
+	Name     string       `json:"name"`
+	Input    ScoreInput   `json:"input"`
+	Expected *ScoreResult `json:"expected"`
+}
+
+// TestDefaultScoringGolden scores the fixture trades with the default
+// configuration and compares the breakdowns with the recorded ones, so
+// moving a rule's constants into configuration can't change its defaults.
+// Run with -update after an intended scoring change.
+func TestDefaultScoringGolden(t *testing.T) {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("got %v loading the default configuration, want no error", err)
+	}
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := New(cfg, nil, nil, nil, nil, nil, nil, log)
+
+	path := filepath.Join("testdata", "golden_scores.json")
+	body, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("got %v reading %s, want no error", err, path)
+	}
+	var cases []goldenCase
+	if err := json.Unmarshal(body, &cases); err != nil {
+		t.Fatalf("got %v decoding %s, want no error", err, path)
+	}
+
+	for i := range cases {
+		tc := &cases[i]
+		got, err := p.SimulateScore(context.Background(), tc.Input)
+		if err != nil {
+			t.Fatalf("%s: got %v, want no error", tc.Name, err)
+		}
+		if *updateGolden {
+			tc.Expected = got
+			continue
+		}
+
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(tc.Expected)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s: got %s, want %s", tc.Name, gotJSON, wantJSON)
+		}
+	}
+
+	if *updateGolden {
+		out, err := json.MarshalIndent(cases, "", "  ")
+		if err != nil {
+			t.Fatalf("got %v encoding, want no error", err)
+		}
+		if err := os.WriteFile(path, append(out, '\n'), 0o644); err != nil {
+			t.Fatalf("got %v writing %s, want no error", err, path)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package processor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// lastSuccessfulPollKey is the app_state key holding the Unix time of the
+// last successful poll cycle
+const lastSuccessfulPollKey = "last_successful_poll_ts"
+
+// PollStatus is the outcome of the most recent poll cycles
+type PollStatus struct {
+	LastAttempt time.Time // When the last cycle finished; zero before the first
+	LastSuccess time.Time // When the last successful cycle finished
+	LastError   string    // Why the last cycle failed; empty if it succeeded
+}
+
+// pollHealth is the shared poll status behind /ready
+type pollHealth struct {
+	mu      sync.Mutex
+	status  PollStatus
+	started time.Time // Stands in for the last success until the first one
+}
+
+// recordPollOutcome publishes the result of a poll cycle
+func (p *Processor) recordPollOutcome(err error) {
+	now := p.clock.Now()
+	p.pollHealth.mu.Lock()
+	defer p.pollHealth.mu.Unlock()
+
+	p.pollHealth.status.LastAttempt = now
+	if err != nil {
+		p.pollHealth.status.LastError = err.Error()
+		return
+	}
+	p.pollHealth.status.LastSuccess = now
+	p.pollHealth.status.LastError = ""
+	metrics.LastSuccessfulPollTimestamp.Set(float64(now.Unix()))
+}
+
+// PollStatus returns the outcome of the most recent poll cycles
+func (p *Processor) PollStatus() PollStatus {
+	p.pollHealth.mu.Lock()
+	defer p.pollHealth.mu.Unlock()
+	return p.pollHealth.status
+}
+
+// PollReady reports whether a poll cycle succeeded within maxAge of now.
+// Until the first success the age is measured from startup, so a new
+// instance has maxAge to complete one.
+func (p *Processor) PollReady(now time.Time, maxAge time.Duration) (bool, PollStatus) {
+	p.pollHealth.mu.Lock()
+	defer p.pollHealth.mu.Unlock()
+
+	last := p.pollHealth.status.LastSuccess
+	if last.IsZero() {
+		last = p.pollHealth.started
+	}
+	return now.Sub(last) <= maxAge, p.pollHealth.status
+}
+
+// RuntimeStats is a snapshot of the processor's concurrency, for debugging
+type RuntimeStats struct {
+	Workers               int   `json:"workers"`        // Size of the worker pool
+	WorkersBusy           int   `json:"workers_busy"`   // Workers processing a trade
+	WaitingTrades         int64 `json:"waiting_trades"` // Trades waiting for a worker
+	PollRunning           bool  `json:"poll_running"`
+	RecalculationRunning  bool  `json:"recalculation_running"`
+	WithdrawalScanRunning bool  `json:"withdrawal_scan_running"`
+}
+
+// RuntimeStats returns a snapshot of the processor's concurrency
+func (p *Processor) RuntimeStats() RuntimeStats {
+	return RuntimeStats{
+		Workers:               cap(p.workerPool),
+		WorkersBusy:           cap(p.workerPool) - len(p.workerPool),
+		WaitingTrades:         p.waitingTrades.Load(),
+		PollRunning:           p.pollRunning.Load(),
+		RecalculationRunning:  p.recalcRunning.Load(),
+		WithdrawalScanRunning: p.withdrawalScanRunning.Load(),
+	}
+}
@@ -0,0 +1,146 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/sirupsen/logrus"
+)
+
+// holderCacheTTL is how long a market's top holders are reused. A burst of
+// big trades on one market would otherwise each fetch the same list.
+const holderCacheTTL = 2 * time.Minute
+
+// holderLimit is how many top holders are requested per outcome
+const holderLimit = 20
+
+// holderDominanceMaxRank is the lowest rank that counts as dominant
+const holderDominanceMaxRank = 3
+
+type holderCache struct {
+	mu      sync.Mutex
+	entries map[string]holderEntry
+}
+
+type holderEntry struct {
+	holders   []dataapi.TokenHolders
+	fetchedAt time.Time
+}
+
+func (c *holderCache) get(conditionID string, now time.Time) ([]dataapi.TokenHolders, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[conditionID]
+	if !ok || now.Sub(entry.fetchedAt) > holderCacheTTL {
+		return nil, false
+	}
+	return entry.holders, true
+}
+
+func (c *holderCache) set(conditionID string, holders []dataapi.TokenHolders, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]holderEntry)
+	}
+	// Drop expired entries so the map doesn't grow without bound
+	for id, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) > holderCacheTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[conditionID] = holderEntry{holders: holders, fetchedAt: now}
+}
+
+// walletHolderRank returns the trade's wallet's rank among the top holders of
+// an outcome token and its share of their combined balance
+func (p *Processor) walletHolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error) {
+	now := p.clock.Now()
+	holders, ok := p.holders.get(trade.ConditionID, now)
+	if !ok {
+		var err error
+		holders, err = p.dataClient.GetHolders(ctx, trade.ConditionID, holderLimit)
+		if err != nil {
+			return 0, 0, fmt.Errorf("get holders: %w", err)
+		}
+		p.holders.set(trade.ConditionID, holders, now)
+	}
+
+	for _, token := range holders {
+		if token.Token == tokenID {
+			rank, share := holderRank(token.Holders, trade.ProxyWallet)
+			return rank, share, nil
+		}
+	}
+	return 0, 0, nil
+}
+
+// holderRank returns a wallet's 1-based rank by balance among holders and its
+// share of their combined balance, or 0 when it isn't listed. The list only
+// covers the top holders, so the share overstates the share of total supply.
+func holderRank(holders []dataapi.Holder, wallet string) (int, float64) {
+	sorted := make([]dataapi.Holder, len(holders))
+	copy(sorted, holders)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Amount > sorted[j].Amount })
+
+	var total float64
+	for _, h := range sorted {
+		total += h.Amount
+	}
+	if total <= 0 {
+		return 0, 0
+	}
+	for i, h := range sorted {
+		if strings.EqualFold(h.ProxyWallet, wallet) {
+			return i + 1, h.Amount / total
+		}
+	}
+	return 0, 0
+}
+
+// applyHolderDominance boosts a BUY by a wallet that is now one of the
+// largest holders of the outcome. It costs an API call, so it only runs for
+// trades that already score at the WARN threshold.
+func (p *Processor) applyHolderDominance(ctx context.Context, tc *TradeContext, breakdown *alerts.ScoreBreakdown) {
+	if !p.config().EnableHolderDominance || tc.Trade.Side != "BUY" || breakdown.NormalizedScore < p.config().SuspicionScoreWarn {
+		return
+	}
+	if tc.Market == nil {
+		return
+	}
+	tokenID := tokenIDForOutcome(tc.Market.TokenIDs, tc.Trade.Outcome)
+	if tokenID == "" {
+		return
+	}
+
+	rank, share, err := tc.Lookups.HolderRank(ctx, tc.Trade, tokenID)
+	if err != nil {
+		p.log.WithError(err).WithField("condition_id", tc.Trade.ConditionID).Warn("Failed to check holder dominance")
+		return
+	}
+	breakdown.HolderRank = rank
+	breakdown.HolderShare = share
+	if rank == 0 || rank > holderDominanceMaxRank || share < p.config().HolderDominanceMinShare {
+		return
+	}
+
+	breakdown.HolderDominanceMultiplier = p.config().HolderDominanceMultiplier
+	evidence := fmt.Sprintf("#%d holder of %s with %.0f%% of the top holders' balance", rank, tc.Trade.Outcome, share*100)
+	breakdown.Evidence = append(breakdown.Evidence, evidence)
+	breakdown.FiredRules = append(breakdown.FiredRules, "holder_dominance")
+	p.log.WithFields(logrus.Fields{
+		"wallet":     tc.Trade.ProxyWallet,
+		"rule":       "holder_dominance",
+		"multiplier": breakdown.HolderDominanceMultiplier,
+		"evidence":   evidence,
+	}).Info("Applied scoring rule")
+
+	breakdown.FinalScore = breakdown.BaseScore * breakdown.CombinedMultiplier()
+	breakdown.NormalizedScore = p.normalizeScore(breakdown.FinalScore)
+}
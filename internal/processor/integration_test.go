@@ -0,0 +1,806 @@
+//go:build integration
+
+package processor_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/bootstrap"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/processor"
+	"github.com/liamashdown/insiderwatch/internal/processor/processortest"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// testDSNEnv names the MySQL database the integration tests run against.
+// Every table in it is dropped before each test, so never point it at a
+// database holding data you want to keep.
+const testDSNEnv = "INSIDERWATCH_TEST_MYSQL_DSN"
+
+// Fixture wallets and markets
+const (
+	walletFlash = "0x1111111111111111111111111111111111111111" // Funded minutes before its first trade
+	walletNew   = "0x2222222222222222222222222222222222222222" // No activity before its first trade
+	walletOld   = "0x3333333333333333333333333333333333333333" // First active over a year ago
+	walletLate  = "0x4444444444444444444444444444444444444444" // Only ever returned behind the checkpoint
+	walletTwin  = "0x5555555555555555555555555555555555555555" // Shares a cluster with walletNew in the coordination test
+
+	marketFlash = "0xaaaa000000000000000000000000000000000000000000000000000000000001"
+	marketNew   = "0xaaaa000000000000000000000000000000000000000000000000000000000002"
+	marketOld   = "0xaaaa000000000000000000000000000000000000000000000000000000000003"
+)
+
+// fakeAPIs serves the Data, Gamma and CLOB endpoints the poll loop calls.
+// The trades /trades returns can be swapped between polls.
+type fakeAPIs struct {
+	mu       sync.Mutex
+	trades   []dataapi.Trade
+	activity map[string][]dataapi.ActivityEvent // By wallet
+	markets  map[string]gammaapi.Market         // By condition ID
+	byWallet map[string][]dataapi.Trade         // /trades?user=, by wallet
+}
+
+func (f *fakeAPIs) setTrades(trades []dataapi.Trade) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trades = trades
+}
+
+func (f *fakeAPIs) dataAPI(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.URL.Path {
+	case "/trades":
+		if user := r.URL.Query().Get("user"); user != "" {
+			writeJSON(w, append([]dataapi.Trade{}, f.byWallet[strings.ToLower(user)]...))
+			return
+		}
+		writeJSON(w, f.trades)
+	case "/activity":
+		activity := f.activity[strings.ToLower(r.URL.Query().Get("user"))]
+		if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && len(activity) > limit {
+			activity = activity[:limit]
+		}
+		writeJSON(w, append([]dataapi.ActivityEvent{}, activity...))
+	case "/positions":
+		writeJSON(w, []dataapi.Position{})
+	case "/holders":
+		writeJSON(w, []dataapi.TokenHolders{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (f *fakeAPIs) gammaAPI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/markets" {
+		http.NotFound(w, r)
+		return
+	}
+	found := []gammaapi.Market{}
+	for _, id := range r.URL.Query()["condition_ids"] {
+		if market, ok := f.markets[id]; ok {
+			found = append(found, market)
+		}
+	}
+	writeJSON(w, found)
+}
+
+func (f *fakeAPIs) clobAPI(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/book":
+		writeJSON(w, map[string]any{"asset_id": r.URL.Query().Get("token_id"), "bids": []any{}, "asks": []any{}})
+	case "/midpoint":
+		writeJSON(w, map[string]string{"mid": "0.55"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// recordingSender records the alerts the processor sends
+type recordingSender struct {
+	mu       sync.Mutex
+	payloads []*alerts.AlertPayload
+}
+
+func (s *recordingSender) Send(ctx context.Context, payload *alerts.AlertPayload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.payloads = append(s.payloads, payload)
+	return nil
+}
+
+func (s *recordingSender) sent() []*alerts.AlertPayload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*alerts.AlertPayload(nil), s.payloads...)
+}
+
+// integrationDB returns a migrated, empty database, skipping the test when
+// no test database is configured
+func integrationDB(t *testing.T, cfg *config.Config, log *logrus.Logger) *storage.DB {
+	t.Helper()
+	conn, err := gorm.Open(mysql.Open(cfg.DatabaseDSN), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("got %v connecting to %s, want no error", err, testDSNEnv)
+	}
+	tables, err := conn.Migrator().GetTables()
+	if err != nil {
+		t.Fatalf("got %v listing tables, want no error", err)
+	}
+	for _, table := range tables {
+		if err := conn.Migrator().DropTable(table); err != nil {
+			t.Fatalf("got %v dropping %s, want no error", err, table)
+		}
+	}
+	if sqlDB, err := conn.DB(); err == nil {
+		sqlDB.Close()
+	}
+
+	db, err := bootstrap.Database(cfg, log, true)
+	if err != nil {
+		t.Fatalf("got %v migrating the test database, want no error", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// integrationConfig loads the configuration with the API clients pointed at
+// the fake servers
+func integrationConfig(t *testing.T, dsn string, apis *fakeAPIs) *config.Config {
+	t.Helper()
+	data := httptest.NewServer(http.HandlerFunc(apis.dataAPI))
+	gamma := httptest.NewServer(http.HandlerFunc(apis.gammaAPI))
+	clob := httptest.NewServer(http.HandlerFunc(apis.clobAPI))
+	t.Cleanup(func() {
+		data.Close()
+		gamma.Close()
+		clob.Close()
+	})
+
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	t.Setenv("DATABASE_DSN", dsn)
+	t.Setenv("DATA_API_BASE_URL", data.URL)
+	t.Setenv("GAMMA_API_BASE_URL", gamma.URL)
+	t.Setenv("CLOB_API_BASE_URL", clob.URL)
+	t.Setenv("SUBGRAPH_URL", "")
+	t.Setenv("NEW_WALLET_DAYS_MAX", "30")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("got %v loading the configuration, want no error", err)
+	}
+	return cfg
+}
+
+func fixtureMarket(conditionID, slug string, endDate time.Time) gammaapi.Market {
+	return gammaapi.Market{
+		ID:           slug,
+		ConditionID:  conditionID,
+		Slug:         slug,
+		Question:     "Will " + slug + " happen?",
+		EndDate:      endDate.UTC().Format(time.RFC3339),
+		Category:     "Politics",
+		VolumeNum:    2_000_000,
+		LiquidityNum: 5_000_000,
+		Active:       true,
+		Outcomes:     `["Yes","No"]`,
+		ClobTokenIDs: fmt.Sprintf(`["%s-yes","%s-no"]`, slug, slug),
+	}
+}
+
+func fixtureTrade(wallet, conditionID, tx string, ts int64, notional float64) dataapi.Trade {
+	return dataapi.Trade{
+		ProxyWallet:     wallet,
+		Side:            "BUY",
+		ConditionID:     conditionID,
+		Size:            notional / 0.5,
+		Price:           0.5,
+		Timestamp:       ts,
+		Outcome:         "Yes",
+		TransactionHash: tx,
+		USDCSize:        notional,
+	}
+}
+
+// TestProcessTradesIntegration runs two poll cycles against a real MySQL
+// database and fake Polymarket APIs: the first stores three new wallets'
+// trades and alerts on them, the second stores one more trade from an
+// alerted wallet, which the cooldown keeps from alerting again, and skips a
+// trade behind the checkpoint.
+func TestProcessTradesIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	now := time.Now()
+	base := now.Add(-10 * time.Minute).Unix()
+	endDate := now.Add(10 * 24 * time.Hour)
+	apis := &fakeAPIs{
+		activity: map[string][]dataapi.ActivityEvent{
+			walletFlash: {{ProxyWallet: walletFlash, Type: "TRANSFER", Timestamp: base - 180, USDCSize: 500_000, TransactionHash: "0xfund"}},
+			walletOld:   {{ProxyWallet: walletOld, Type: "TRADE", Timestamp: base - 400*86400}},
+		},
+		markets: map[string]gammaapi.Market{
+			marketFlash: fixtureMarket(marketFlash, "flash-market", endDate),
+			marketNew:   fixtureMarket(marketNew, "new-market", endDate),
+			marketOld:   fixtureMarket(marketOld, "old-market", endDate),
+		},
+	}
+	cfg := integrationConfig(t, dsn, apis)
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	db := integrationDB(t, cfg, log)
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		t.Fatalf("got %v creating the API clients, want no error", err)
+	}
+	sender := &recordingSender{}
+	p := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, sender, log)
+	ctx := context.Background()
+
+	// First poll: three new trades, newest first as the Data API returns them
+	apis.setTrades([]dataapi.Trade{
+		fixtureTrade(walletOld, marketOld, "0xtx3", base+20, 100_000),
+		fixtureTrade(walletNew, marketNew, "0xtx2", base+10, 25_000),
+		fixtureTrade(walletFlash, marketFlash, "0xtx1", base, 25_000),
+	})
+	summary, err := p.ProcessTrades(ctx)
+	if err != nil {
+		t.Fatalf("got %v on the first poll, want no error", err)
+	}
+	if summary.Fetched != 3 || summary.New != 3 || summary.Processed != 3 || summary.Alerted != 2 {
+		t.Errorf("got first poll %+v, want 3 fetched, new and processed trades and 2 notified alerts", summary)
+	}
+	assertCheckpoint(t, db, base+20)
+	assertTradeCount(t, db, marketFlash, 1)
+	assertTradeCount(t, db, marketNew, 1)
+	assertTradeCount(t, db, marketOld, 1)
+	assertWallet(t, db, walletFlash, storage.Wallet{FirstSeenTS: base - 180, FundingReceivedTS: base - 180, TotalTrades: 1, TotalVolumeUSD: 25_000, LastActivityTS: base})
+	assertWallet(t, db, walletNew, storage.Wallet{FirstSeenTS: base + 10, TotalTrades: 1, TotalVolumeUSD: 25_000, LastActivityTS: base + 10})
+	assertWallet(t, db, walletOld, storage.Wallet{FirstSeenTS: base - 400*86400, FundingReceivedTS: base - 400*86400, TotalTrades: 1, TotalVolumeUSD: 100_000, LastActivityTS: base + 20})
+
+	expectedAlerts := map[string]struct {
+		severity   alerts.Severity
+		recordOnly bool
+	}{
+		walletFlash: {alerts.SeverityAlert, false}, // Flash funded first trade
+		walletNew:   {alerts.SeverityWarn, false},  // Large first trade
+		walletOld:   {alerts.SeverityInfo, true},   // Old wallets are recorded without notifying
+	}
+	assertAlerts := func(pass string) {
+		t.Helper()
+		stored, total, err := db.ListAlerts(ctx, storage.AlertFilter{Limit: 100})
+		if err != nil {
+			t.Fatalf("got %v listing alerts after the %s poll, want no error", err, pass)
+		}
+		if total != int64(len(expectedAlerts)) {
+			t.Errorf("got %d alerts after the %s poll, want %d", total, pass, len(expectedAlerts))
+		}
+		for _, alert := range stored {
+			want, ok := expectedAlerts[alert.WalletAddress]
+			if !ok {
+				t.Errorf("got an alert for %s after the %s poll, want none", alert.WalletAddress, pass)
+				continue
+			}
+			if alert.AlertType != string(want.severity) || alert.RecordOnly != want.recordOnly {
+				t.Errorf("got %s alert (record only %t) for %s after the %s poll, want %s (record only %t)",
+					alert.AlertType, alert.RecordOnly, alert.WalletAddress, pass, want.severity, want.recordOnly)
+			}
+		}
+		sent := sender.sent()
+		if len(sent) != 2 {
+			t.Fatalf("got %d alerts sent after the %s poll, want 2", len(sent), pass)
+		}
+		for _, payload := range sent {
+			if want := expectedAlerts[payload.WalletAddress]; want.recordOnly || payload.Severity != want.severity {
+				t.Errorf("got %s sent for %s after the %s poll, want only the notified alerts", payload.Severity, payload.WalletAddress, pass)
+			}
+		}
+	}
+	assertAlerts("first")
+
+	// Second poll: the flash funded wallet trades again inside the cooldown,
+	// and a trade older than the checkpoint shows up late
+	apis.setTrades([]dataapi.Trade{
+		fixtureTrade(walletFlash, marketFlash, "0xtx4", base+60, 30_000),
+		fixtureTrade(walletOld, marketOld, "0xtx3", base+20, 100_000),
+		fixtureTrade(walletNew, marketNew, "0xtx2", base+10, 25_000),
+		fixtureTrade(walletFlash, marketFlash, "0xtx1", base, 25_000),
+		fixtureTrade(walletLate, marketNew, "0xtx5", base-60, 25_000),
+	})
+	summary, err = p.ProcessTrades(ctx)
+	if err != nil {
+		t.Fatalf("got %v on the second poll, want no error", err)
+	}
+	if summary.Fetched != 5 || summary.New != 1 || summary.Processed != 1 || summary.Alerted != 0 {
+		t.Errorf("got second poll %+v, want 5 fetched, 1 new and processed trade and no alerts", summary)
+	}
+	assertCheckpoint(t, db, base+60)
+	assertTradeCount(t, db, marketFlash, 2)
+	assertTradeCount(t, db, marketNew, 1)
+	assertTradeCount(t, db, marketOld, 1)
+	assertWallet(t, db, walletFlash, storage.Wallet{FirstSeenTS: base - 180, FundingReceivedTS: base - 180, TotalTrades: 2, TotalVolumeUSD: 55_000, LastActivityTS: base + 60})
+	if wallet, err := db.GetWallet(ctx, walletLate); err != nil || wallet != nil {
+		t.Errorf("got wallet %+v (error %v) for the trade behind the checkpoint, want none", wallet, err)
+	}
+	assertAlerts("second")
+}
+
+func assertCheckpoint(t *testing.T, db *storage.DB, expected int64) {
+	t.Helper()
+	got, err := db.GetState(context.Background(), "last_processed_ts")
+	if err != nil {
+		t.Fatalf("got %v reading the checkpoint, want no error", err)
+	}
+	if got != strconv.FormatInt(expected, 10) {
+		t.Errorf("got checkpoint %q, want %d", got, expected)
+	}
+}
+
+func assertTradeCount(t *testing.T, db *storage.DB, conditionID string, expected int) {
+	t.Helper()
+	trades, err := db.GetTradesByConditionID(context.Background(), conditionID)
+	if err != nil {
+		t.Fatalf("got %v reading trades, want no error", err)
+	}
+	if len(trades) != expected {
+		t.Errorf("got %d trades stored for %s, want %d", len(trades), conditionID, expected)
+	}
+}
+
+func assertWallet(t *testing.T, db *storage.DB, address string, expected storage.Wallet) {
+	t.Helper()
+	wallet, err := db.GetWallet(context.Background(), address)
+	if err != nil || wallet == nil {
+		t.Fatalf("got wallet %v (error %v) for %s, want it stored", wallet, err, address)
+	}
+	if wallet.FirstSeenTS != expected.FirstSeenTS || wallet.FundingReceivedTS != expected.FundingReceivedTS ||
+		wallet.TotalTrades != expected.TotalTrades || wallet.TotalVolumeUSD != expected.TotalVolumeUSD ||
+		wallet.LastActivityTS != expected.LastActivityTS {
+		t.Errorf("got wallet %s first seen %d, funded %d, %d trades, $%.2f, last active %d; want first seen %d, funded %d, %d trades, $%.2f, last active %d",
+			address, wallet.FirstSeenTS, wallet.FundingReceivedTS, wallet.TotalTrades, wallet.TotalVolumeUSD, wallet.LastActivityTS,
+			expected.FirstSeenTS, expected.FundingReceivedTS, expected.TotalTrades, expected.TotalVolumeUSD, expected.LastActivityTS)
+	}
+}
+
+func TestRecordWalletMarketResultIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	db := integrationDB(t, integrationConfig(t, dsn, &fakeAPIs{}), log)
+	ctx := context.Background()
+
+	results := []struct {
+		conditionID  string
+		result       string
+		wantInserted bool
+	}{
+		{marketFlash, storage.ResultWin, true},
+		{marketFlash, storage.ResultWin, false}, // Re-resolved
+		{marketNew, storage.ResultLoss, true},
+		{marketOld, storage.ResultHedged, true},
+	}
+	for _, r := range results {
+		inserted, err := db.RecordWalletMarketResult(ctx, &storage.WalletMarketResult{
+			WalletAddress: walletNew,
+			ConditionID:   r.conditionID,
+			Result:        r.result,
+		})
+		if err != nil {
+			t.Fatalf("got %v recording %s, want no error", err, r.conditionID)
+		}
+		if inserted != r.wantInserted {
+			t.Errorf("got inserted %v for %s, want %v\nDescription: A wallet is credited once per market", inserted, r.conditionID, r.wantInserted)
+		}
+	}
+
+	stats, err := db.GetWalletStats(ctx, walletNew)
+	if err != nil || stats == nil {
+		t.Fatalf("got %v, %v, want the wallet's stats", stats, err)
+	}
+	if stats.TotalResolvedTrades != 3 || stats.WinningTrades != 1 || stats.LosingTrades != 1 {
+		t.Errorf("got %d resolved, %d wins, %d losses, want 3, 1, 1\nDescription: Stats follow the recorded results, counting the re-resolved market once", stats.TotalResolvedTrades, stats.WinningTrades, stats.LosingTrades)
+	}
+}
+
+// TestRecalculateWinRatesConcurrentIntegration resolves many markets one
+// wallet traded on several workers, which must not lose any of its results
+func TestRecalculateWinRatesConcurrentIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	const marketCount = 100
+	apis := &fakeAPIs{markets: map[string]gammaapi.Market{}}
+	for i := 0; i < marketCount; i++ {
+		conditionID := fmt.Sprintf("0xbbbb%060d", i)
+		market := fixtureMarket(conditionID, fmt.Sprintf("resolved-%d", i), time.Now().Add(-24*time.Hour))
+		market.Closed = true
+		market.OutcomePrices = `["1","0"]`
+		apis.markets[conditionID] = market
+	}
+	t.Setenv("WIN_RATE_WORKERS", "8")
+	cfg := integrationConfig(t, dsn, apis)
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	db := integrationDB(t, cfg, log)
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		t.Fatalf("got %v creating the API clients, want no error", err)
+	}
+	p := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, &recordingSender{}, log)
+	ctx := context.Background()
+
+	for conditionID := range apis.markets {
+		trade := &storage.TradeSeen{
+			TradeHash:    "hash-" + conditionID,
+			ConditionID:  conditionID,
+			ProxyWallet:  walletNew,
+			TimestampSec: time.Now().Add(-48 * time.Hour).Unix(),
+			NotionalUSD:  1000,
+			Side:         "BUY",
+			Outcome:      "Yes",
+			Price:        0.5,
+		}
+		if err := db.InsertTrade(ctx, trade); err != nil {
+			t.Fatalf("got %v inserting a trade, want no error", err)
+		}
+	}
+
+	resolved, err := p.RecalculateWinRates(ctx)
+	if err != nil {
+		t.Fatalf("got %v recalculating win rates, want no error", err)
+	}
+	if resolved != marketCount {
+		t.Errorf("got %d markets resolved, want %d", resolved, marketCount)
+	}
+	stats, err := db.GetWalletStats(ctx, walletNew)
+	if err != nil || stats == nil {
+		t.Fatalf("got %v, %v, want the wallet's stats", stats, err)
+	}
+	if stats.TotalResolvedTrades != marketCount || stats.WinningTrades != marketCount {
+		t.Errorf("got %d resolved and %d wins, want %d of each\nDescription: Workers crediting the same wallet at once don't overwrite each other", stats.TotalResolvedTrades, stats.WinningTrades, marketCount)
+	}
+}
+
+// TestScanPositionExitsIntegration alerts on a wallet, then has it sell the
+// position back in trades under BIG_TRADE_USD, which only the exit scan sees
+func TestScanPositionExitsIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	now := time.Now()
+	base := now.Add(-10 * time.Minute).Unix()
+	apis := &fakeAPIs{
+		activity: map[string][]dataapi.ActivityEvent{
+			walletFlash: {{ProxyWallet: walletFlash, Type: "TRANSFER", Timestamp: base - 180, USDCSize: 500_000, TransactionHash: "0xfund"}},
+		},
+		markets: map[string]gammaapi.Market{
+			marketFlash: fixtureMarket(marketFlash, "flash-market", now.Add(10*24*time.Hour)),
+		},
+	}
+	cfg := integrationConfig(t, dsn, apis)
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	db := integrationDB(t, cfg, log)
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		t.Fatalf("got %v creating the API clients, want no error", err)
+	}
+	sender := &recordingSender{}
+	p := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, sender, log)
+	ctx := context.Background()
+
+	// A $25,000 buy of 50,000 shares raises an ALERT
+	entry := fixtureTrade(walletFlash, marketFlash, "0xentry", base, 25_000)
+	apis.setTrades([]dataapi.Trade{entry})
+	if _, err := p.ProcessTrades(ctx); err != nil {
+		t.Fatalf("got %v on the poll, want no error", err)
+	}
+	if sent := sender.sent(); len(sent) != 1 || sent[0].Severity != alerts.SeverityAlert {
+		t.Fatalf("got %d alerts sent, want the entry's ALERT", len(sent))
+	}
+
+	// Two $9,000 sells of 20,000 shares each reverse 80% of it
+	sell := func(tx string, ts int64) dataapi.Trade {
+		trade := fixtureTrade(walletFlash, marketFlash, tx, ts, 9_000)
+		trade.Side = "SELL"
+		trade.Size = 20_000
+		trade.Price = 0.45
+		return trade
+	}
+	apis.mu.Lock()
+	apis.byWallet = map[string][]dataapi.Trade{
+		walletFlash: {sell("0xexit2", base+120), sell("0xexit1", base+60), entry},
+	}
+	apis.mu.Unlock()
+
+	for pass := 1; pass <= 2; pass++ {
+		scanned, err := p.ScanPositionExits(ctx)
+		if err != nil {
+			t.Fatalf("got %v on exit scan %d, want no error", err, pass)
+		}
+		wantScanned := 1
+		if pass == 2 {
+			wantScanned = 0 // The position has exited
+		}
+		if scanned != wantScanned {
+			t.Errorf("got %d markets scanned on exit scan %d, want %d", scanned, pass, wantScanned)
+		}
+	}
+
+	sent := sender.sent()
+	if len(sent) != 2 {
+		t.Fatalf("got %d alerts sent, want the entry's and one exit alert\nDescription: Exits under BIG_TRADE_USD are found by the scan and alerted once", len(sent))
+	}
+	exit := sent[1].Exit
+	if exit == nil || exit.ExitedFraction < 0.8-1e-9 || exit.ExitPrice != 0.45 {
+		t.Errorf("got exit %+v, want 80%% exited at 0.45", exit)
+	}
+}
+
+// TestWriteSeedRollsBackIntegration fails a seed write on its last trade,
+// which must leave none of the seed behind
+func TestWriteSeedRollsBackIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	cfg := integrationConfig(t, dsn, &fakeAPIs{})
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	db := integrationDB(t, cfg, log)
+	p := processor.New(cfg, db, nil, nil, nil, nil, nil, log)
+	ctx := context.Background()
+
+	data, err := p.GenerateSeed(ctx, processor.SeedOptions{Seed: 7, Wallets: 20, Now: time.Now()})
+	if err != nil {
+		t.Fatalf("got %v generating the seed, want no error", err)
+	}
+	if len(data.Wallets) == 0 || len(data.Trades) == 0 {
+		t.Fatalf("got %d wallets and %d trades, want some of each", len(data.Wallets), len(data.Trades))
+	}
+	data.Trades = append(data.Trades, data.Trades[0])
+
+	if err := p.WriteSeed(ctx, data); err == nil {
+		t.Fatal("got no error writing a duplicate trade, want one")
+	}
+	wallet, err := db.GetWallet(ctx, data.Wallets[0].WalletAddress)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if wallet != nil {
+		t.Errorf("got wallet %s stored, want none\nDescription: A failed seed write is rolled back as a whole", wallet.WalletAddress)
+	}
+}
+
+// TestDetectCoordinatedTradeIntegration has two fresh wallets in one cluster
+// trade the same market minutes apart, on the same or opposite sides, and
+// checks the second wallet's alert carries the matching pattern and multiplier
+func TestDetectCoordinatedTradeIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	tests := []struct {
+		name           string
+		twinOutcome    string
+		wantPattern    string
+		wantMultiplier func(cfg *config.Config) float64
+		description    string
+	}{
+		{
+			name:           "same side",
+			twinOutcome:    "Yes",
+			wantPattern:    alerts.CoordinationSameSide,
+			wantMultiplier: func(cfg *config.Config) float64 { return cfg.Detection.Coordinated.SameSideMultiplier },
+			description:    "Sibling wallets buying the same outcome are a pile-in",
+		},
+		{
+			name:           "opposing",
+			twinOutcome:    "No",
+			wantPattern:    alerts.CoordinationOpposing,
+			wantMultiplier: func(cfg *config.Config) float64 { return cfg.OpposingCoordinatedMultiplier },
+			description:    "Sibling wallets buying opposite outcomes use OPPOSING_COORDINATED_MULTIPLIER",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			base := now.Add(-10 * time.Minute).Unix()
+			apis := &fakeAPIs{
+				markets: map[string]gammaapi.Market{
+					marketNew: fixtureMarket(marketNew, "new-market", now.Add(10*24*time.Hour)),
+				},
+			}
+			cfg := integrationConfig(t, dsn, apis)
+			log := logrus.New()
+			log.SetLevel(logrus.WarnLevel)
+			db := integrationDB(t, cfg, log)
+			clients, err := bootstrap.APIClients(cfg, log)
+			if err != nil {
+				t.Fatalf("got %v creating the API clients, want no error", err)
+			}
+			sender := &recordingSender{}
+			p := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, sender, log)
+			ctx := context.Background()
+
+			cluster := &storage.WalletCluster{
+				ClusterID:      "cluster_twins",
+				FundingSource:  "0xfunder",
+				LinkType:       storage.LinkFunding,
+				WalletCount:    2,
+				FirstSeenTS:    base - 3600,
+				LastActivityTS: base - 3600,
+			}
+			if err := db.UpsertWalletCluster(ctx, cluster); err != nil {
+				t.Fatalf("got %v storing the cluster, want no error", err)
+			}
+			for _, wallet := range []string{walletNew, walletTwin} {
+				member := &storage.WalletClusterMember{WalletAddress: wallet, ClusterID: cluster.ClusterID, LinkType: storage.LinkFunding, CreatedTS: base - 3600}
+				if err := db.UpsertClusterMember(ctx, member); err != nil {
+					t.Fatalf("got %v storing cluster member %s, want no error", err, wallet)
+				}
+			}
+
+			// Separate polls, since trades within one are processed in parallel
+			first := fixtureTrade(walletNew, marketNew, "0xfirst", base, 25_000)
+			twin := fixtureTrade(walletTwin, marketNew, "0xtwin", base+300, 25_000)
+			twin.Outcome = tt.twinOutcome
+			for _, trades := range [][]dataapi.Trade{{first}, {twin, first}} {
+				apis.setTrades(trades)
+				if _, err := p.ProcessTrades(ctx); err != nil {
+					t.Fatalf("got %v processing trades, want no error", err)
+				}
+			}
+
+			var breakdown *alerts.ScoreBreakdown
+			for _, payload := range sender.sent() {
+				if payload.WalletAddress == walletTwin {
+					breakdown = payload.ScoreBreakdown
+				}
+			}
+			if breakdown == nil {
+				t.Fatalf("got no alert sent for the second wallet, want one\nDescription: %s", tt.description)
+			}
+			if !breakdown.IsCoordinated || breakdown.CoordinationPattern != tt.wantPattern {
+				t.Errorf("got coordinated %t with pattern %q, want pattern %q\nDescription: %s",
+					breakdown.IsCoordinated, breakdown.CoordinationPattern, tt.wantPattern, tt.description)
+			}
+			if want := tt.wantMultiplier(cfg); breakdown.CoordinatedMultiplier != want {
+				t.Errorf("got coordinated multiplier %.2f, want %.2f\nDescription: %s", breakdown.CoordinatedMultiplier, want, tt.description)
+			}
+		})
+	}
+}
+
+// TestTradesProcessedCountedOnceIntegration processes a single trade and
+// checks the lifetime counter counts it once
+func TestTradesProcessedCountedOnceIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	now := time.Now()
+	base := now.Add(-10 * time.Minute).Unix()
+	apis := &fakeAPIs{
+		markets: map[string]gammaapi.Market{
+			marketNew: fixtureMarket(marketNew, "new-market", now.Add(10*24*time.Hour)),
+		},
+	}
+	cfg := integrationConfig(t, dsn, apis)
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	db := integrationDB(t, cfg, log)
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		t.Fatalf("got %v creating the API clients, want no error", err)
+	}
+	p := processor.New(cfg, db, clients.Data, clients.Gamma, clients.Clob, clients.Subgraph, &recordingSender{}, log)
+
+	success := metrics.TradesProcessed.WithLabelValues("success")
+	before := testutil.ToFloat64(success)
+	apis.setTrades([]dataapi.Trade{fixtureTrade(walletNew, marketNew, "0xtx1", base, 25_000)})
+	summary, err := p.ProcessTrades(context.Background())
+	if err != nil {
+		t.Fatalf("got %v processing trades, want no error", err)
+	}
+	if summary.Processed != 1 {
+		t.Fatalf("got %+v, want one processed trade", summary)
+	}
+	if got := testutil.ToFloat64(success) - before; got != 1 {
+		t.Errorf("got trades processed{status=\"success\"} +%.0f, want +1\nDescription: Each fully processed trade is counted once", got)
+	}
+}
+
+// TestWalletLookupsCoalescedIntegration processes several trades by one new
+// wallet with the Data API's answer held, so the workers share a single
+// wallet creation
+func TestWalletLookupsCoalescedIntegration(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	const workers = 4
+	now := time.Now()
+	base := now.Add(-10 * time.Minute).Unix()
+	market := fixtureMarket(marketNew, "new-market", now.Add(10*24*time.Hour))
+	gate := make(chan struct{})
+	data := &processortest.DataAPI{Blocked: map[string]chan struct{}{"GetWalletFirstActivity": gate}}
+	for i := 0; i < workers; i++ {
+		data.Trades = append(data.Trades, fixtureTrade(walletNew, marketNew, fmt.Sprintf("0xtx%d", i), base+int64(i), 25_000))
+	}
+	gamma := &processortest.GammaAPI{Markets: map[string]*gammaapi.Market{marketNew: &market}}
+
+	t.Setenv("WALLET_LOOKUP_WORKERS", strconv.Itoa(workers))
+	apis := &fakeAPIs{}
+	cfg := integrationConfig(t, dsn, apis)
+	log := logrus.New()
+	log.SetLevel(logrus.WarnLevel)
+	db := integrationDB(t, cfg, log)
+	clients, err := bootstrap.APIClients(cfg, log)
+	if err != nil {
+		t.Fatalf("got %v creating the API clients, want no error", err)
+	}
+	p := processor.New(cfg, db, data, gamma, clients.Clob, nil, &recordingSender{}, log)
+
+	coalesced := metrics.CoalescedLookups.WithLabelValues("wallet")
+	before := testutil.ToFloat64(coalesced)
+	go func() {
+		for data.Calls("GetWalletFirstActivity") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(100 * time.Millisecond) // Let the other workers join the flight
+		close(gate)
+	}()
+	summary, err := p.ProcessTrades(context.Background())
+	if err != nil {
+		t.Fatalf("got %v processing trades, want no error", err)
+	}
+	if summary.Processed != workers {
+		t.Fatalf("got %+v, want %d processed trades", summary, workers)
+	}
+
+	if got := data.Calls("GetWalletFirstActivity"); got != 1 {
+		t.Errorf("got %d first activity lookups, want 1\nDescription: Concurrent trades by one new wallet share a single creation", got)
+	}
+	if got := testutil.ToFloat64(coalesced) - before; got != workers-1 {
+		t.Errorf("got %.0f coalesced lookups, want %d\nDescription: Every worker but the one creating the wallet is counted as coalesced", got, workers-1)
+	}
+}
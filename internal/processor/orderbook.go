@@ -0,0 +1,107 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+)
+
+// outcomeTokenIDs pairs a Gamma market's outcomes with its CLOB token IDs.
+// Both are JSON-encoded arrays in the same order.
+func outcomeTokenIDs(outcomes, clobTokenIDs string) map[string]string {
+	var outcomeList, tokenList []string
+	if err := json.Unmarshal([]byte(outcomes), &outcomeList); err != nil {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(clobTokenIDs), &tokenList); err != nil {
+		return nil
+	}
+	if len(outcomeList) == 0 || len(outcomeList) != len(tokenList) {
+		return nil
+	}
+
+	tokenIDs := make(map[string]string, len(outcomeList))
+	for i, outcome := range outcomeList {
+		tokenIDs[outcome] = tokenList[i]
+	}
+	return tokenIDs
+}
+
+// encodeTokenIDs serializes the outcome -> token map for MarketMap
+func encodeTokenIDs(tokenIDs map[string]string) string {
+	if len(tokenIDs) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(tokenIDs)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// decodeTokenIDs reads the outcome -> token map cached on MarketMap
+func decodeTokenIDs(encoded string) map[string]string {
+	if encoded == "" {
+		return nil
+	}
+	var tokenIDs map[string]string
+	if err := json.Unmarshal([]byte(encoded), &tokenIDs); err != nil {
+		return nil
+	}
+	return tokenIDs
+}
+
+// tokenIDForOutcome looks up the token for a trade's outcome, ignoring case
+func tokenIDForOutcome(tokenIDs map[string]string, outcome string) string {
+	for o, tokenID := range tokenIDs {
+		if strings.EqualFold(o, outcome) {
+			return tokenID
+		}
+	}
+	return ""
+}
+
+// bookConsumption returns the fraction of visible depth near the trade price
+// that the trade took. The book is read after the fill, so the trade's own
+// notional is added back to the remaining depth.
+func bookConsumption(notional, remainingDepth float64) float64 {
+	if notional <= 0 {
+		return 0
+	}
+	return notional / (notional + remainingDepth)
+}
+
+// checkOrderBook fetches the book for the traded outcome and returns the
+// depth near the trade price before the trade and the fraction consumed
+func (p *Processor) checkOrderBook(ctx context.Context, trade *dataapi.Trade, marketInfo *MarketInfo, notional float64) (float64, float64, error) {
+	tokenID := tokenIDForOutcome(marketInfo.TokenIDs, trade.Outcome)
+	if tokenID == "" {
+		return 0, 0, fmt.Errorf("no token ID for outcome %q", trade.Outcome)
+	}
+
+	book, err := p.clobClient.GetOrderBook(ctx, tokenID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("get order book: %w", err)
+	}
+
+	remaining := book.DepthWithin(trade.Side, trade.Price, p.config().OrderbookPriceBand)
+	return notional + remaining, bookConsumption(notional, remaining), nil
+}
+
+// currentPrice fetches the traded outcome's midpoint price, so an alert read
+// some minutes later shows where the market is now
+func (p *Processor) currentPrice(ctx context.Context, trade *dataapi.Trade, marketInfo *MarketInfo) (float64, error) {
+	tokenID := tokenIDForOutcome(marketInfo.TokenIDs, trade.Outcome)
+	if tokenID == "" {
+		return 0, fmt.Errorf("no token ID for outcome %q", trade.Outcome)
+	}
+
+	price, err := p.clobClient.GetMidpoint(ctx, tokenID)
+	if err != nil {
+		return 0, fmt.Errorf("get midpoint: %w", err)
+	}
+	return price, nil
+}
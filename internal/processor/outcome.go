@@ -0,0 +1,42 @@
+package processor
+
+import (
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// normalizeOutcome trims an outcome label and collapses its inner
+// whitespace. Case is kept, since stored labels are shown in alerts;
+// matching ignores it instead.
+func normalizeOutcome(outcome string) string {
+	return strings.Join(strings.Fields(outcome), " ")
+}
+
+// outcomeMatch reports whether a trade's outcome label names the winning
+// outcome, and whether it took normalization to tell. The Data API and
+// Gamma don't always agree on case ("Yes" and "YES") or whitespace.
+func outcomeMatch(outcome, winningOutcome string, normalize bool) (match, normalized bool) {
+	if outcome == winningOutcome {
+		return true, false
+	}
+	if !normalize {
+		return false, false
+	}
+	if strings.EqualFold(normalizeOutcome(outcome), normalizeOutcome(winningOutcome)) {
+		return true, true
+	}
+	return false, false
+}
+
+// winningSide reports whether a trade's outcome is the winning outcome,
+// counting matches that needed normalization in normalizedMatches and the
+// metric
+func (p *Processor) winningSide(outcome, winningOutcome string, normalizedMatches *int) bool {
+	match, normalized := outcomeMatch(outcome, winningOutcome, p.config().NormalizeOutcomes)
+	if normalized {
+		*normalizedMatches++
+		metrics.OutcomeNormalizedMatches.Inc()
+	}
+	return match
+}
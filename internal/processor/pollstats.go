@@ -0,0 +1,151 @@
+package processor
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// pollStats accumulates what happened to the trades of one ProcessTrades
+// cycle. Workers update it concurrently; a nil *pollStats (live feed trades)
+// only records the lifetime metrics.
+type pollStats struct {
+	mu       sync.Mutex
+	statuses map[string]int // trade status -> count (duplicate, filtered_*, *_error, success)
+	alerts   map[string]int // severity -> alerts stored
+	newestTS int64          // timestamp of the newest trade that was not a duplicate
+}
+
+func newPollStats() *pollStats {
+	return &pollStats{
+		statuses: make(map[string]int),
+		alerts:   make(map[string]int),
+	}
+}
+
+// trade records the outcome of a trade in the lifetime counter and, when
+// called during a poll, in the cycle totals
+func (s *pollStats) trade(status string) {
+	metrics.TradesProcessed.WithLabelValues(status).Inc()
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.statuses[status]++
+	s.mu.Unlock()
+}
+
+// filtered records a trade skipped by a market filter, by reason and
+// normalized market category
+func (s *pollStats) filtered(reason, category string) {
+	metrics.TradesFiltered.WithLabelValues(reason, category).Inc()
+	s.trade("filtered_" + reason)
+}
+
+// seen records a trade that passed deduplication, for the ingest lag
+func (s *pollStats) seen(tradeTS int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if tradeTS > s.newestTS {
+		s.newestTS = tradeTS
+	}
+	s.mu.Unlock()
+}
+
+// alert records a stored alert on a market of the given normalized
+// category, raised by the given profile, and the scoring rules that fired
+// on it
+func (s *pollStats) alert(severity alerts.Severity, category, profile string, fired []string) {
+	metrics.AlertsTriggered.WithLabelValues(string(severity), category, profile).Inc()
+	for _, rule := range fired {
+		metrics.AlertDetectors.WithLabelValues(rule, string(severity)).Inc()
+	}
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.alerts[string(severity)]++
+	s.mu.Unlock()
+}
+
+// PollSummary is what one poll cycle did with the trades it fetched
+type PollSummary struct {
+	Fetched   int            // Trades the Data API returned
+	New       int            // Trades newer than the checkpoint that weren't already stored
+	Processed int            // New trades that were stored and scored
+	Alerted   int            // Alerts stored across severities
+	Trades    map[string]int // Trade status -> count (duplicate, filtered_*, *_error, success)
+	Alerts    map[string]int // Severity -> alerts stored
+	CutShort  string         // Why the cycle stopped early, leaving trades for the next poll; empty if it didn't
+	Duration  time.Duration
+}
+
+// summary returns the cycle totals; fetched and dispatched are as for report
+func (s *pollStats) summary(fetched, dispatched int, duration time.Duration) PollSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := PollSummary{
+		Fetched:   fetched,
+		New:       dispatched - s.statuses["duplicate"],
+		Processed: s.statuses["success"],
+		Trades:    make(map[string]int, len(s.statuses)),
+		Alerts:    make(map[string]int, len(s.alerts)),
+		Duration:  duration,
+	}
+	for status, count := range s.statuses {
+		summary.Trades[status] = count
+	}
+	for severity, count := range s.alerts {
+		summary.Alerts[severity] = count
+		summary.Alerted += count
+	}
+	return summary
+}
+
+// report logs the cycle summary and publishes it as the last_poll gauges.
+// fetched counts trades returned by the Data API (before fill aggregation)
+// and dispatched those newer than the checkpoint.
+func (s *pollStats) report(log *logrus.Logger, now time.Time, fetched, dispatched int, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	duplicates := s.statuses["duplicate"]
+	trades := map[string]int{
+		"fetched": fetched,
+		"new":     dispatched - duplicates,
+	}
+	for status, count := range s.statuses {
+		trades[status] = count
+	}
+
+	var ingestLag time.Duration
+	if s.newestTS > 0 {
+		ingestLag = now.Sub(time.Unix(s.newestTS, 0))
+	}
+
+	metrics.RecordPoll(ingestLag, trades, s.alerts)
+
+	fields := logrus.Fields{
+		"fetched":     fetched,
+		"new":         dispatched - duplicates,
+		"duplicate":   duplicates,
+		"duration_ms": duration.Milliseconds(),
+	}
+	if s.newestTS > 0 {
+		fields["ingest_lag_sec"] = int64(ingestLag.Seconds())
+	}
+	for status, count := range s.statuses {
+		fields[status] = count
+	}
+	for severity, count := range s.alerts {
+		fields["alerts_"+strings.ToLower(severity)] = count
+	}
+	log.WithFields(fields).Info("Poll cycle complete")
+}
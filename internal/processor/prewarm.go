@@ -0,0 +1,83 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+)
+
+// PrewarmMarkets caches the markets Gamma listed most recently, so the
+// first trade in a new market resolves from market_map instead of paying
+// for the lookup inside the alert path, or caching a fallback because
+// Gamma hadn't indexed the market by the time the trade was seen. It reads
+// up to MARKET_PREWARM_PAGES pages, newest first, and stops at a page with
+// nothing new. Sports markets are skipped, since their trades are filtered
+// before they need a market. It returns the number of markets cached.
+func (p *Processor) PrewarmMarkets(ctx context.Context) (int, error) {
+	cfg := p.config()
+	if !cfg.EnableMarketPrewarm {
+		return 0, nil
+	}
+	if !p.prewarmRunning.CompareAndSwap(false, true) {
+		p.log.Debug("Market pre-warm already running, skipping")
+		return 0, nil
+	}
+	defer p.prewarmRunning.Store(false)
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	cached := 0
+	defer func() { metrics.MarketsPrewarmed.Set(float64(cached)) }()
+
+	now := p.clock.Now().Unix()
+	for page := 0; page < cfg.MarketPrewarmPages; page++ {
+		markets, err := p.gammaClient.ListRecentMarkets(ctx, cfg.MarketPrewarmPageSize, page*cfg.MarketPrewarmPageSize)
+		if err != nil {
+			return cached, fmt.Errorf("list recent markets: %w", err)
+		}
+
+		fresh := 0
+		for i := range markets {
+			market := &markets[i]
+			if !prewarmable(market) {
+				continue
+			}
+			existing, err := p.db.GetMarketMap(ctx, market.ConditionID)
+			if err != nil {
+				p.log.WithError(err).WithField("condition_id", market.ConditionID).Warn("Failed to check cached market")
+				continue
+			}
+			// Fallback rows are replaced now that Gamma has the market
+			if existing != nil && !existing.IsFallback && p.marketFresh(existing, now) {
+				continue
+			}
+			p.cacheMarket(ctx, market.ConditionID, marketEventSlug(market), market, gammaapi.Validators{})
+			fresh++
+		}
+		cached += fresh
+
+		if fresh == 0 || len(markets) < cfg.MarketPrewarmPageSize {
+			break
+		}
+	}
+
+	p.log.WithField("markets", cached).Debug("Market cache pre-warmed")
+	return cached, nil
+}
+
+// prewarmable reports whether a listed market is worth caching ahead of
+// its first trade
+func prewarmable(market *gammaapi.Market) bool {
+	return market.ConditionID != "" && normalizeCategory(market.Category, market.Slug) != categorySports
+}
+
+// marketEventSlug returns the slug of a market's parent event, which
+// supplies the end date of markets without one, or "" when it has none
+func marketEventSlug(market *gammaapi.Market) string {
+	if len(market.Events) == 0 {
+		return ""
+	}
+	return market.Events[0].Slug
+}
@@ -4,40 +4,108 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"math"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/broadcast"
+	"github.com/liamashdown/insiderwatch/internal/circuit"
+	"github.com/liamashdown/insiderwatch/internal/clock"
 	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/errreport"
 	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/clobapi"
 	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
 	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/subgraph"
+	"github.com/liamashdown/insiderwatch/internal/ratelimit"
 	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/liamashdown/insiderwatch/internal/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
 )
 
 // Processor handles trade processing and detection logic
 type Processor struct {
-	cfg         *config.Config
+	cfg         *config.Config // Read through config(), since Reload swaps it
+	cfgMu       sync.RWMutex
 	db          *storage.DB
-	dataClient  *dataapi.Client
-	gammaClient *gammaapi.Client
+	dataClient  DataAPI
+	gammaClient GammaAPI
+	clobClient  *clobapi.Client
+	subgraph    SubgraphAPI // Optional; nil when SUBGRAPH_URL is unset
+	clock       clock.Clock // The system clock unless a test sets a fake
 	alertSender alerts.Sender
+	profileSenders map[string]alerts.Sender // Alert routes of the watch profiles, by name
+	alertHub    *broadcast.Hub[storage.Alert] // Stored alerts as they are recorded, for GET /alerts/stream
+	deadLetters deadLetterStore               // Trades whose processing panicked; nil without storage
 	workerPool  chan struct{}
+	liveBacklog chan struct{} // A slot per live trade queued for or holding a worker
+	waitingTrades atomic.Int64 // Trades waiting for a worker
 	log         *logrus.Logger
-	walletLocks sync.Map // Per-wallet locks to prevent duplicate API calls
+	walletFlight singleflight.Group // New wallet creation by address, so concurrent trades share the API calls
+	marketFlight singleflight.Group // Gamma market lookups by condition ID
+	tradeCounts tradeCountCache // Recent first-trade API verifications
+	events      eventCache      // Gamma events by slug, shared by their markets
+	holders     holderCache     // Recent top holders by market
+	rules       []Rule   // Built-in scoring rules applied to every trade
+	customRules []Rule   // User-defined rules from CUSTOM_RULES_FILE
+	rulesMu     sync.RWMutex
+
+	pollRunning   atomic.Bool // Set while a ProcessTrades cycle is running
+	checkpointTS  atomic.Int64 // Last checkpoint a poll cycle read or wrote, for its age metric
+	pollHealth    pollHealth  // Outcome of the last poll cycles, for /ready
+	work          sync.RWMutex // Held for reading by in-flight work; Drain takes it to wait that out
+	drainOnce     sync.Once
+	drained       chan struct{} // Closed once Drain holds work
+	recalcRunning atomic.Bool // Set while RecalculateWinRates is running
+	withdrawalScanRunning atomic.Bool // Set while TrackWithdrawals is running
+	prewarmRunning atomic.Bool // Set while PrewarmMarkets is running
+	exitScanRunning atomic.Bool // Set while ScanPositionExits is running
+	exposureMu     sync.Mutex  // Held while checking aggregate exposure, so a wallet alerts once
+	statsLocks     [64]sync.Mutex // Serialize wallet stats updates; see walletStatsLock
+}
+
+// ErrRecalculationRunning is returned when a win rate recalculation is
+// requested while another one is still in progress
+var ErrRecalculationRunning = errors.New("win rate recalculation already running")
+
+// ErrPollRunning is returned when a poll cycle is requested while the
+// previous one is still running
+var ErrPollRunning = errors.New("poll cycle already running")
+
+// unavailableStatus returns the trade status for an API that is shedding
+// load, circuit_open or rate_limited, deadline_exceeded once the poll cycle
+// runs out of time, or "" for any other error. Trades failing this way are
+// left for the next poll rather than processed with missing data.
+func unavailableStatus(err error) string {
+	switch {
+	case errors.Is(err, circuit.ErrOpen):
+		return "circuit_open"
+	case errors.Is(err, dataapi.ErrRateLimited), errors.Is(err, gammaapi.ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	}
+	return ""
 }
 
 // New creates a new processor
 func New(
 	cfg *config.Config,
 	db *storage.DB,
-	dataClient *dataapi.Client,
-	gammaClient *gammaapi.Client,
+	dataClient DataAPI,
+	gammaClient GammaAPI,
+	clobClient *clobapi.Client,
+	subgraphClient *subgraph.Client,
 	alertSender alerts.Sender,
 	log *logrus.Logger,
 ) *Processor {
@@ -46,43 +114,158 @@ func New(
 		workerPool <- struct{}{}
 	}
 
-	return &Processor{
+	p := &Processor{
 		cfg:         cfg,
 		db:          db,
 		dataClient:  dataClient,
 		gammaClient: gammaClient,
+		clobClient:  clobClient,
 		alertSender: alertSender,
+		alertHub:    broadcast.New[storage.Alert](cfg.AlertStreamMaxClients, alertStreamBuffer),
 		workerPool:  workerPool,
+		liveBacklog: make(chan struct{}, liveTradeBacklog),
 		log:         log,
+		rules:       defaultRules(cfg),
+		clock:       clock.Real{},
+	}
+	if db != nil {
+		p.deadLetters = db
+	}
+	if subgraphClient != nil {
+		p.subgraph = subgraphClient
+	}
+	p.pollHealth.started = p.clock.Now()
+	return p
+}
+
+// SetClock replaces the clock the processor reads the time from, for tests
+// that freeze or step it. Call it before the processor starts work.
+func (p *Processor) SetClock(c clock.Clock) {
+	p.clock = c
+	p.pollHealth.started = c.Now()
+}
+
+// SetProfileSenders installs the alert routes of the watch profiles, by
+// profile name. A profile without one alerts through the global sender.
+// Call it before the processor starts work.
+func (p *Processor) SetProfileSenders(senders map[string]alerts.Sender) {
+	p.profileSenders = senders
+}
+
+// senderFor returns the alert route of a watch profile ("" for none)
+func (p *Processor) senderFor(profile string) alerts.Sender {
+	if sender, ok := p.profileSenders[profile]; ok {
+		return sender
 	}
+	return p.alertSender
+}
+
+// config returns the current configuration. Callers must not modify it.
+func (p *Processor) config() *config.Config {
+	p.cfgMu.RLock()
+	defer p.cfgMu.RUnlock()
+	return p.cfg
+}
+
+// Reload switches the processor to cfg, as returned by config.Reload, for
+// trades processed from now on. The built-in rules are rebuilt so they
+// score with the new thresholds; custom rules are kept.
+func (p *Processor) Reload(cfg *config.Config) {
+	p.cfgMu.Lock()
+	p.cfg = cfg
+	p.cfgMu.Unlock()
+
+	p.rulesMu.Lock()
+	p.rules = defaultRules(cfg)
+	p.rulesMu.Unlock()
+}
+
+// alertStreamBuffer is how many alerts a stream client may fall behind by
+// before it is dropped
+const alertStreamBuffer = 64
+
+// AlertStream returns the hub that every stored alert is published to
+func (p *Processor) AlertStream() *broadcast.Hub[storage.Alert] {
+	return p.alertHub
+}
+
+// ProcessTrades fetches and processes new trades. Each cycle must finish
+// within POLL_CYCLE_TIMEOUT, and a cycle requested while the previous one
+// is still running returns ErrPollRunning, so cycles never interleave on
+// the checkpoint. It returns what the cycle did.
+func (p *Processor) ProcessTrades(ctx context.Context) (PollSummary, error) {
+	ctx, span := tracing.Start(ctx, "ProcessTrades")
+	var summary PollSummary
+	err := p.runPollCycle(ctx, func(ctx context.Context) error {
+		var err error
+		if summary, err = p.processTrades(ctx); err != nil {
+			return err
+		}
+		if err := p.db.SetState(ctx, lastSuccessfulPollKey, strconv.FormatInt(p.clock.Now().Unix(), 10)); err != nil {
+			p.log.WithError(err).Warn("Failed to record successful poll")
+		}
+		return nil
+	})
+	span.SetAttributes(
+		attribute.Int("trades.fetched", summary.Fetched),
+		attribute.Int("trades.new", summary.New),
+		attribute.Int("alerts", summary.Alerted),
+	)
+	tracing.End(span, err)
+	return summary, err
+}
+
+// runPollCycle runs cycle under the poll cycle deadline unless another
+// cycle is still running, and publishes its outcome
+func (p *Processor) runPollCycle(ctx context.Context, cycle func(context.Context) error) error {
+	if !p.pollRunning.CompareAndSwap(false, true) {
+		metrics.PollCyclesSkipped.Inc()
+		return ErrPollRunning
+	}
+	defer p.pollRunning.Store(false)
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	start := p.clock.Now()
+	ctx, cancel := context.WithTimeout(ctx, p.config().PollCycleTimeout)
+	defer cancel()
+	err := cycle(ctx)
+	metrics.RecordPollCycle(p.clock.Since(start), p.checkpointTS.Load())
+	p.recordPollOutcome(err)
+	return err
 }
 
-// ProcessTrades fetches and processes new trades
-func (p *Processor) ProcessTrades(ctx context.Context) error {
+func (p *Processor) processTrades(ctx context.Context) (PollSummary, error) {
+	start := p.clock.Now()
+
 	// Get checkpoint
 	lastProcessedStr, err := p.db.GetState(ctx, "last_processed_ts")
 	if err != nil {
-		return fmt.Errorf("get last processed ts: %w", err)
+		return PollSummary{}, fmt.Errorf("get last processed ts: %w", err)
 	}
 
 	var lastProcessedTS int64
 	if lastProcessedStr != "" {
 		lastProcessedTS, _ = strconv.ParseInt(lastProcessedStr, 10, 64)
 	}
+	p.checkpointTS.Store(lastProcessedTS)
 
 	// Fetch trades with BIG_TRADE_USD filter (sorted by timestamp DESC for recent-first)
 	params := dataapi.TradeParams{
 		Limit:         10000,
 		TakerOnly:     true,
 		FilterType:    "CASH",
-		FilterAmount:  p.cfg.BigTradeUSD,
+		FilterAmount:  p.config().BigTradeUSD,
 		SortBy:        "timestamp",
 		SortDirection: "DESC",
 	}
 
-	resp, err := p.dataClient.GetTrades(ctx, params)
+	// The fetch goes first on a shared rate limit budget; the lookups for
+	// the trades it returns run at the default priority
+	pollCtx := ratelimit.WithPriority(ctx, ratelimit.PriorityPoll)
+	resp, err := p.dataClient.GetTrades(pollCtx, params)
 	if err != nil {
-		return fmt.Errorf("fetch trades: %w", err)
+		return PollSummary{}, fmt.Errorf("fetch trades: %w", err)
 	}
 
 	p.log.WithFields(logrus.Fields{
@@ -90,52 +273,207 @@ func (p *Processor) ProcessTrades(ctx context.Context) error {
 		"last_processed_ts":  lastProcessedTS,
 	}).Info("Fetched trades from Data API")
 
+	// Tag takers, and optionally fetch maker fills with a second query
+	trades := resp.Trades
+	for i := range trades {
+		trades[i].Role = dataapi.RoleTaker
+	}
+	if p.config().IncludeMakerTrades {
+		params.TakerOnly = false
+		allResp, err := p.dataClient.GetTrades(pollCtx, params)
+		if err != nil {
+			p.log.WithError(err).Warn("Failed to fetch maker trades, continuing with taker trades only")
+		} else {
+			trades = mergeMakerTrades(trades, allResp.Trades)
+			p.log.WithField("maker_count", len(trades)-len(resp.Trades)).Debug("Fetched maker trades from Data API")
+		}
+	}
+	fetchedCount := len(trades)
+
+	// Drop malformed rows before they can move the checkpoint
+	stats := newPollStats()
+	trades = p.validTrades(trades, stats)
+	fetched := trades
+
+	// Merge multi-fill orders so each transaction is processed once
+	if p.config().AggregateSameTxFills {
+		trades = p.aggregateFills(trades)
+	}
+
+	// Resolve the new trades' markets in batches before the workers need them
+	var pending []dataapi.Trade
+	for _, trade := range trades {
+		if trade.Timestamp > lastProcessedTS {
+			pending = append(pending, trade)
+		}
+	}
+	p.warmMarketCache(ctx, pending)
+
 	// Process trades in parallel
+	dispatched, cutShort := p.dispatchTrades(ctx, trades, lastProcessedTS, stats)
+
+	duration := p.clock.Since(start)
+	stats.report(p.log, p.clock.Now(), fetchedCount, dispatched, duration)
+	summary := stats.summary(fetchedCount, dispatched, duration)
+
+	// Hold the checkpoint so skipped trades are fetched again; the ones
+	// that were processed are deduplicated
+	if cutShort != "" {
+		p.log.WithField("status", cutShort).Warn("Poll cycle cut short, leaving unprocessed trades for the next poll")
+		summary.CutShort = cutShort
+		return summary, nil
+	}
+
+	// Update checkpoint
+	if len(fetched) > 0 {
+		maxTS := int64(0)
+		for _, trade := range fetched {
+			if trade.Timestamp > maxTS {
+				maxTS = trade.Timestamp
+			}
+		}
+		if maxTS > lastProcessedTS {
+			if err := p.db.SetState(ctx, "last_processed_ts", strconv.FormatInt(maxTS, 10)); err != nil {
+				p.log.WithError(err).Error("Failed to update checkpoint")
+			} else {
+				p.checkpointTS.Store(maxTS)
+			}
+		}
+	}
+
+	return summary, nil
+}
+
+// dispatchTrades processes the trades newer than after in parallel and
+// waits for them. When an API becomes unavailable or ctx's deadline passes,
+// the remaining trades are skipped and cutShort says why.
+func (p *Processor) dispatchTrades(ctx context.Context, trades []dataapi.Trade, after int64, stats *pollStats) (dispatched int, cutShort string) {
+	var unavailable atomic.Value // Status of the first trade an unavailable API failed
 	var wg sync.WaitGroup
-	for _, trade := range resp.Trades {
+	for _, trade := range trades {
 		// Skip if already processed
-		if trade.Timestamp <= lastProcessedTS {
+		if trade.Timestamp <= after {
 			continue
 		}
+		dispatched++
 
 		wg.Add(1)
 		go func(t dataapi.Trade) {
 			defer wg.Done()
 			
 			// Acquire worker
-			<-p.workerPool
-			defer func() { p.workerPool <- struct{}{} }()
+			release := p.acquireWorker()
+			defer release()
+
+			// Once an API circuit opens, an API rate limits us, or the
+			// cycle runs out of time, the rest of the cycle is left for
+			// the next poll
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				unavailable.CompareAndSwap(nil, "deadline_exceeded")
+			}
+			if status, ok := unavailable.Load().(string); ok {
+				stats.trade(status)
+				return
+			}
 
-			if err := p.processTrade(ctx, &t); err != nil {
-				p.log.WithError(err).WithField("trade_hash", p.calculateTradeHash(&t)).Error("Failed to process trade")
+			err := p.processTradeSafely(ctx, &t, stats)
+			if status := unavailableStatus(err); status != "" {
+				unavailable.CompareAndSwap(nil, status)
+				return
+			}
+			if err != nil {
+				tradeHash := p.calculateTradeHash(&t)
+				p.log.WithError(err).WithField("trade_hash", tradeHash).Error("Failed to process trade")
+				errreport.Capture(errreport.Event{Err: err, Source: "trade", Tags: tradeTags(&t, tradeHash)})
 			}
 		}(trade)
 	}
 
 	wg.Wait()
 
-	// Update checkpoint
-	if len(resp.Trades) > 0 {
-		maxTS := int64(0)
-		for _, trade := range resp.Trades {
-			if trade.Timestamp > maxTS {
-				maxTS = trade.Timestamp
-			}
-		}
-		if maxTS > lastProcessedTS {
-			if err := p.db.SetState(ctx, "last_processed_ts", strconv.FormatInt(maxTS, 10)); err != nil {
-				p.log.WithError(err).Error("Failed to update checkpoint")
-			}
-		}
+	cutShort, _ = unavailable.Load().(string)
+	return dispatched, cutShort
+}
+
+// Drain waits for in-flight poll cycles, live trades and background jobs to
+// finish, and keeps new ones from starting; it is only used at shutdown.
+// It returns ctx.Err() if work is still running when ctx is done, and can
+// be called again to keep waiting.
+func (p *Processor) Drain(ctx context.Context) error {
+	p.drainOnce.Do(func() {
+		p.drained = make(chan struct{})
+		go func() {
+			p.work.Lock() // Never released
+			close(p.drained)
+		}()
+	})
+
+	select {
+	case <-p.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	return nil
+// liveTradeBacklog is how many live trades may wait for or hold a worker
+// before ProcessLiveTrade blocks the feed
+const liveTradeBacklog = 256
+
+// ProcessLiveTrade processes a single trade pushed by the live feed.
+// The feed carries every trade, so the BIG_TRADE_USD filter the poll applies
+// server-side is applied here. Trades are still deduplicated against the
+// poll, which keeps running as a backstop in "both" mode. Once
+// liveTradeBacklog trades are waiting it blocks until one finishes or ctx is
+// done, so a burst holds up the feed's read loop rather than piling up
+// goroutines.
+func (p *Processor) ProcessLiveTrade(ctx context.Context, trade dataapi.Trade) {
+	if !p.acceptTrade(&trade, nil) || p.calculateNotional(&trade) < p.config().BigTradeUSD {
+		return
+	}
+
+	select {
+	case p.liveBacklog <- struct{}{}:
+	case <-ctx.Done():
+		return
+	}
+	go func() {
+		defer func() { <-p.liveBacklog }()
+		p.work.RLock()
+		defer p.work.RUnlock()
+
+		// Acquire worker
+		release := p.acquireWorker()
+		defer release()
+
+		if err := p.processTradeSafely(ctx, &trade, nil); err != nil {
+			tradeHash := p.calculateTradeHash(&trade)
+			p.log.WithError(err).WithField("trade_hash", tradeHash).Error("Failed to process live trade")
+			errreport.Capture(errreport.Event{Err: err, Source: "trade", Tags: tradeTags(&trade, tradeHash)})
+		}
+	}()
+}
+
+// acquireWorker takes a slot in the worker pool, counting the trade as
+// waiting until it gets one. The returned func gives the slot back.
+func (p *Processor) acquireWorker() func() {
+	p.waitingTrades.Add(1)
+	<-p.workerPool
+	p.waitingTrades.Add(-1)
+	return func() { p.workerPool <- struct{}{} }
 }
 
-func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) error {
-	start := time.Now()
+func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade, stats *pollStats) (err error) {
+	ctx, span := tracing.Start(ctx, "processTrade",
+		attribute.String("condition_id", trade.ConditionID),
+		attribute.String("wallet", shortenAddress(trade.ProxyWallet)),
+		attribute.String("notional_bucket", tracing.NotionalBucket(p.calculateNotional(trade))),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	start := p.clock.Now()
 	defer func() {
-		metrics.RecordTradeProcessing(time.Since(start), "success")
+		metrics.RecordTradeProcessing(p.clock.Since(start))
 	}()
 
 	// Calculate trade hash for deduplication
@@ -147,72 +485,73 @@ func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) erro
 		return fmt.Errorf("check trade seen: %w", err)
 	}
 	if seen {
-		metrics.TradesProcessed.WithLabelValues("duplicate").Inc()
+		stats.trade("duplicate")
 		return nil // Already processed
 	}
+	stats.seen(trade.Timestamp)
 
 	// Resolve market info FIRST to check if we should process this trade at all
 	marketInfo, err := p.resolveMarket(ctx, trade)
+	if status := unavailableStatus(err); status != "" {
+		stats.trade(status)
+		return fmt.Errorf("resolve market: %w", err)
+	}
 	if err != nil {
 		p.log.WithError(err).WithField("condition_id", trade.ConditionID).Warn("Failed to resolve market")
-		metrics.TradesProcessed.WithLabelValues("market_resolve_error").Inc()
-	}
-
-	// Skip markets that can't involve insider trading (sports, entertainment, etc.)
-	if marketInfo != nil && isNotInsiderCategory(marketInfo) {
-		metrics.TradesProcessed.WithLabelValues("filtered_sports").Inc()
-		p.log.WithFields(logrus.Fields{
-			"category":     marketInfo.Category,
-			"condition_id": trade.ConditionID,
-			"title":        marketInfo.Title,
-		}).Debug("Skipping sports/entertainment market")
-		return nil
+		stats.trade("market_resolve_error")
 	}
 
-	// Skip trades for markets that have already ended/resolved
-	// Or markets ending more than 2 months from now (too far in future)
-	twoMonthsFromNow := time.Now().AddDate(0, 2, 0).Unix()
-	if marketInfo != nil && marketInfo.EndDate > 0 && (trade.Timestamp >= marketInfo.EndDate || marketInfo.EndDate > twoMonthsFromNow) {
-		metrics.TradesProcessed.WithLabelValues("filtered_closed").Inc()
-		p.log.WithFields(logrus.Fields{
-			"condition_id": trade.ConditionID,
-			"title":        marketInfo.Title,
-			"trade_time":   trade.Timestamp,
-			"end_date":     marketInfo.EndDate,
-		}).Debug("Skipping trade for closed or distant market")
+	if p.filterMarket(trade, marketInfo, stats) {
 		return nil
 	}
 
 	// Validate trade data
 	if trade.Side != "BUY" && trade.Side != "SELL" {
 		p.log.WithField("side", trade.Side).Warn("Invalid trade side, skipping")
-		metrics.TradesProcessed.WithLabelValues("invalid_side").Inc()
+		stats.trade("invalid_side")
 		return nil
 	}
+	if p.config().NormalizeOutcomes {
+		trade.Outcome = normalizeOutcome(trade.Outcome)
+	}
 	if trade.Outcome == "" {
 		p.log.Warn("Missing trade outcome, skipping")
-		metrics.TradesProcessed.WithLabelValues("missing_outcome").Inc()
+		stats.trade("missing_outcome")
 		return nil
 	}
 
+	// Count trades from alerted wallets against their positions before the
+	// MIN_TRADE_USD filter. Trades under BIG_TRADE_USD never get here; the
+	// exit scan fetches those.
+	if p.config().EnableExitAlerts {
+		if err := p.checkPositionExit(ctx, trade, tradeHash, marketInfo); err != nil {
+			p.log.WithError(err).WithField("wallet", trade.ProxyWallet).Warn("Failed to check alerted position exit")
+		}
+	}
+
 	// Calculate notional
 	notional := p.calculateNotional(trade)
 
 	// Skip if too small (post-API filter)
-	if notional < p.cfg.MinTradeUSD {
-		metrics.TradesProcessed.WithLabelValues("filtered_size").Inc()
+	if notional < p.config().MinTradeUSD {
+		stats.filtered("size", marketCategory(marketInfo))
 		return nil
 	}
 
 	// Get or create wallet record
 	wallet, err := p.getOrCreateWallet(ctx, trade.ProxyWallet, trade.Timestamp)
 	if err != nil {
-		metrics.TradesProcessed.WithLabelValues("wallet_lookup_error").Inc()
+		if status := unavailableStatus(err); status != "" {
+			stats.trade(status)
+		} else {
+			stats.trade("wallet_lookup_error")
+		}
 		return fmt.Errorf("get wallet: %w", err)
 	}
 
 	// Capture pre-update state for first-trade detection (prevent race conditions)
 	isFirstTrade := wallet.TotalTrades == 0
+	priorTrades, priorVolumeUSD := wallet.TotalTrades, wallet.TotalVolumeUSD
 
 	// Calculate wallet age in days
 	walletAgeDays := int((trade.Timestamp - wallet.FirstSeenTS) / 86400)
@@ -223,9 +562,6 @@ func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) erro
 		hoursToClose = float64(marketInfo.EndDate-trade.Timestamp) / 3600.0
 	}
 
-	// Calculate suspicion score with time-to-close multiplier
-	rawScore := p.calculateSuspicionScore(notional, walletAgeDays, hoursToClose)
-
 	// Store trade
 	tradeRecord := &storage.TradeSeen{
 		TradeHash:       tradeHash,
@@ -237,9 +573,10 @@ func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) erro
 		Side:            trade.Side,
 		Outcome:         trade.Outcome,
 		Price:           trade.Price,
+		Role:            tradeRole(trade),
 	}
 	if err := p.db.InsertTrade(ctx, tradeRecord); err != nil {
-		metrics.TradesProcessed.WithLabelValues("insert_error").Inc()
+		stats.trade("insert_error")
 		return fmt.Errorf("insert trade: %w", err)
 	}
 
@@ -247,16 +584,33 @@ func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) erro
 	wallet.TotalTrades++
 	wallet.TotalVolumeUSD += notional
 	wallet.LastActivityTS = trade.Timestamp
-	wallet.UpdatedTS = time.Now().Unix()
-	if err := p.db.UpsertWallet(ctx, wallet); err != nil {
+	wallet.UpdatedTS = p.clock.Now().Unix()
+	// The stored totals are incremented by this trade alone, so concurrent
+	// trades by the same wallet all count
+	walletUpdate := &storage.Wallet{
+		WalletAddress:  wallet.WalletAddress,
+		TotalTrades:    1,
+		TotalVolumeUSD: notional,
+		LastActivityTS: wallet.LastActivityTS,
+		UpdatedTS:      wallet.UpdatedTS,
+	}
+	if err := p.db.UpsertWallet(ctx, walletUpdate); err != nil {
 		p.log.WithError(err).Error("Failed to update wallet stats")
-		metrics.TradesProcessed.WithLabelValues("wallet_update_error").Inc()
+		stats.trade("wallet_update_error")
 	}
 
 	// Update net position
 	if err := p.updateNetPosition(ctx, trade, notional); err != nil {
 		p.log.WithError(err).Error("Failed to update net position")
-		metrics.TradesProcessed.WithLabelValues("net_position_error").Inc()
+		stats.trade("net_position_error")
+	}
+
+	// A new wallet's buys across markets can add up without any one of them
+	// alerting
+	if p.config().EnableExposureAlerts && trade.Side == "BUY" && walletAgeDays <= p.config().NewWalletDaysMax {
+		if err := p.checkAggregateExposure(ctx, trade, tradeHash, wallet, walletAgeDays, marketInfo); err != nil {
+			p.log.WithError(err).WithField("wallet", trade.ProxyWallet).Warn("Failed to check aggregate exposure")
+		}
 	}
 
 	// Get wallet win rate for additional scoring context
@@ -269,321 +623,137 @@ func (p *Processor) processTrade(ctx context.Context, trade *dataapi.Trade) erro
 		winRate = walletStats.WinRate
 	}
 
-	// Calculate funding age (time between funding and first trade)
-	var fundingAgeHours float64
-	var fundingAgeMinutes float64
-	if wallet.FundingReceivedTS > 0 && wallet.FirstSeenTS > 0 && wallet.FirstSeenTS >= wallet.FundingReceivedTS {
-		fundingAgeHours = float64(wallet.FirstSeenTS-wallet.FundingReceivedTS) / 3600.0
-		fundingAgeMinutes = float64(wallet.FirstSeenTS-wallet.FundingReceivedTS) / 60.0
-	} else if wallet.FundingReceivedTS > wallet.FirstSeenTS {
-		// Edge case: API returned first trade as FirstSeenTS but funding came after
-		// This likely means our FirstSeenTS detection is incomplete
-		p.log.WithFields(logrus.Fields{
-			"wallet":           wallet.WalletAddress,
-			"first_seen":       wallet.FirstSeenTS,
-			"funding_received": wallet.FundingReceivedTS,
-		}).Debug("FirstSeenTS predates FundingReceivedTS - possible detection issue")
-	}
+	fundingAgeHours, fundingAgeMinutes := p.fundingAge(wallet)
 
-	// Check if this is wallet's first trade and it's large
-	var firstTradeLargeMultiplier float64 = 1.0
-	// Use local tracking as primary, but verify for new wallets
-	if isFirstTrade && notional >= p.cfg.MinTradeUSD {
-		// For extra confidence, check if this is truly the first trade via API
-		// Only do this check for very suspicious cases to avoid rate limits
-		if notional >= p.cfg.MinTradeUSD*2 {
-			activity, err := p.dataClient.GetWalletActivity(ctx, trade.ProxyWallet, 10)
-			if err == nil {
-				// Count actual trades from API
-				tradeCount := 0
-				for _, act := range activity {
-					if act.Type == "TRADE" {
-						tradeCount++
-					}
-				}
-				// If API confirms <= 2 trades, this is definitely a first large trade
-				if tradeCount <= 2 {
-					firstTradeLargeMultiplier = 2.0
-					p.log.WithFields(logrus.Fields{
-						"wallet":            wallet.WalletAddress,
-						"notional":          notional,
-						"api_trade_count":   tradeCount,
-					}).Warn("First trade is very large - API verified")
-				}
-			} else {
-				// API failed, fall back to local tracking
-				firstTradeLargeMultiplier = 2.0
-				p.log.WithFields(logrus.Fields{
-					"wallet":   wallet.WalletAddress,
-					"notional": notional,
-				}).Warn("First trade is very large - locally tracked")
-			}
-		} else {
-			// Lower amount, just use local tracking
-			firstTradeLargeMultiplier = 2.0
-			p.log.WithFields(logrus.Fields{
-				"wallet":   wallet.WalletAddress,
-				"notional": notional,
-			}).Warn("First trade is large")
+	// Initial funding amount, to spot a first trade that bets nearly all of it
+	var fundingAmountUSD float64
+	if isFirstTrade {
+		source, err := p.db.GetWalletFundingSource(ctx, trade.ProxyWallet)
+		if err != nil {
+			p.log.WithError(err).Warn("Failed to get wallet funding source")
+		} else if source != nil {
+			fundingAmountUSD = source.AmountUSD
 		}
 	}
 
-	// Check for flash funding (funded and trading within minutes)
-	var flashFundingMultiplier float64 = 1.0
-	if fundingAgeMinutes > 0 && fundingAgeMinutes <= 5 {
-		flashFundingMultiplier = 3.0
-		p.log.WithFields(logrus.Fields{
-			"wallet":              wallet.WalletAddress,
-			"funding_age_minutes": fundingAgeMinutes,
-		}).Warn("Flash funding detected - funded and trading within minutes")
-	}
-
-	// Check trade velocity (rapid successive trades)
-	var velocityCount int
-	var velocityMultiplier float64 = 1.0
-	if p.cfg.EnableVelocityDetection {
-		var err error
-		velocityCount, err = p.checkTradeVelocity(ctx, trade.ProxyWallet, trade.Timestamp)
-		if err != nil {
-			p.log.WithError(err).Warn("Failed to check trade velocity")
-		} else if velocityCount >= p.cfg.VelocityThreshold {
-			// Apply velocity multiplier: 3 trades = 1.5x, 5 trades = 2.0x, 10+ = 3.0x
-			if velocityCount >= 10 {
-				velocityMultiplier = 3.0
-			} else if velocityCount >= 5 {
-				velocityMultiplier = 2.0
-			} else {
-				velocityMultiplier = 1.5
-			}
-			p.log.WithFields(logrus.Fields{
-				"wallet":       wallet.WalletAddress,
-				"velocity_count": velocityCount,
-				"window_minutes": p.cfg.VelocityWindowMinutes,
-				"multiplier":     velocityMultiplier,
-			}).Warn("High trade velocity detected")
+	// Run the scoring rules and build the breakdown for transparency
+	breakdown, severity := p.evaluate(ctx, &TradeContext{
+		Trade:             trade,
+		Wallet:            wallet,
+		Market:            marketInfo,
+		Stats:             walletStats,
+		Notional:          notional,
+		WalletAgeDays:     walletAgeDays,
+		HoursToClose:      hoursToClose,
+		IsFirstTrade:      isFirstTrade,
+		WinRate:           winRate,
+		FundingAgeHours:   fundingAgeHours,
+		FundingAgeMinutes: fundingAgeMinutes,
+		FundingAmountUSD:  fundingAmountUSD,
+		PriorTrades:       priorTrades,
+		PriorVolumeUSD:    priorVolumeUSD,
+		Lookups:           processorLookups{p: p},
+	})
+	adjustedScore, normalizedScore := breakdown.FinalScore, breakdown.NormalizedScore
+
+	// Record both raw and normalized scores for calibration analysis
+	// This allows us to observe actual score distributions in production
+	// and adjust the normalization function if needed
+	metrics.RecordSuspicionScore(adjustedScore, normalizedScore)
+
+	for _, route := range p.alertRoutes(marketInfo, notional, walletAgeDays, normalizedScore, severity) {
+		if err := p.sendAlert(ctx, trade, wallet, marketInfo, notional, walletAgeDays, adjustedScore, normalizedScore, route, breakdown, stats); err != nil {
+			p.log.WithError(err).WithField("profile", profileLabel(route.profile)).Error("Failed to send alert")
 		}
 	}
+	if breakdown.CopyLeader != "" {
+		p.recordCopyLink(ctx, trade, breakdown)
+	}
+	stats.trade("success")
 
-	// Check market liquidity ratio (trade size relative to market)
-	var liquidityMultiplier float64 = 1.0
-	if marketInfo != nil && marketInfo.LiquidityNum > 0 {
-		liquidityRatio := notional / marketInfo.LiquidityNum
-		if liquidityRatio > 0.05 { // Trade is 5%+ of market liquidity
-			// 5% = 1.2x, 10% = 1.5x, 20% = 2.0x, 50%+ = 3.0x
-			if liquidityRatio >= 0.50 {
-				liquidityMultiplier = 3.0
-			} else if liquidityRatio >= 0.20 {
-				liquidityMultiplier = 2.0
-			} else if liquidityRatio >= 0.10 {
-				liquidityMultiplier = 1.5
-			} else {
-				liquidityMultiplier = 1.2
-			}
-			p.log.WithFields(logrus.Fields{
-				"wallet":          wallet.WalletAddress,
-				"liquidity_ratio": liquidityRatio,
-				"multiplier":      liquidityMultiplier,
-			}).Warn("Large trade relative to market liquidity")
-		}
+	return nil
+}
+
+// filterMarket reports whether a trade is skipped for its market, recording
+// the reason in stats. Trades whose market couldn't be resolved are scored.
+func (p *Processor) filterMarket(trade *dataapi.Trade, marketInfo *MarketInfo, stats *pollStats) bool {
+	if marketInfo == nil {
+		return false
 	}
 
-	// Check for extreme price confidence
-	var priceConfidenceMultiplier float64 = 1.0
-	if trade.Price >= 0.85 || trade.Price <= 0.15 {
-		priceConfidenceMultiplier = 1.5
+	// Skip markets that can't involve insider trading (sports, entertainment, etc.)
+	if isNotInsiderCategory(marketInfo) {
+		stats.filtered("sports", marketCategory(marketInfo))
 		p.log.WithFields(logrus.Fields{
-			"wallet": wallet.WalletAddress,
-			"price":  trade.Price,
-			"side":   trade.Side,
-		}).Info("Extreme price confidence detected")
+			"category":     marketInfo.Category,
+			"condition_id": trade.ConditionID,
+			"title":        marketInfo.Title,
+		}).Debug("Skipping sports/entertainment market")
+		return true
 	}
 
-	// Check net position concentration (one-sided positioning)
-	var concentrationMultiplier float64 = 1.0
-	netPosConcentration, err := p.checkNetPositionConcentration(ctx, trade.ProxyWallet, trade.ConditionID, trade.Timestamp, notional, trade.Side)
-	if err != nil {
-		p.log.WithError(err).Warn("Failed to check net position concentration")
-	} else if netPosConcentration > 0.90 { // 90%+ on one side
-		concentrationMultiplier = 1.5
+	// Skip illiquid markets, where a single ordinary trade dwarfs the pool
+	if marketInfo.LiquidityNum > 0 && marketInfo.LiquidityNum < p.config().MinLiquidityFor(marketInfo.Category) {
+		stats.filtered("illiquid", marketCategory(marketInfo))
 		p.log.WithFields(logrus.Fields{
-			"wallet":        wallet.WalletAddress,
-			"concentration": netPosConcentration,
-		}).Warn("High net position concentration detected")
+			"condition_id": trade.ConditionID,
+			"title":        marketInfo.Title,
+			"category":     marketInfo.Category,
+			"liquidity":    marketInfo.LiquidityNum,
+		}).Debug("Skipping trade for illiquid market")
+		return true
 	}
 
-	// Check for coordinated trading patterns
-	var isCoordinated bool
-	var clusterID string
-	var clusterMultiplier float64 = 1.0
-
-	if p.cfg.EnableClusterDetection {
-		var err error
-		isCoordinated, clusterID, err = p.detectCoordinatedTrade(ctx, trade, trade.ProxyWallet)
-		if err != nil {
-			p.log.WithError(err).Warn("Failed to detect coordinated trade")
-		}
-
-		// Get cluster multiplier
-		clusterMultiplier = p.getClusterMultiplier(ctx, trade.ProxyWallet)
-	}
-
-	// Check if alert should be triggered
-	// if walletAgeDays <= p.cfg.NewWalletDaysMax {
-		// Build score breakdown for transparency
-		breakdown := &alerts.ScoreBreakdown{
-			BaseScore:                  rawScore,
-			TimeToCloseMultiplier:      1.0,
-			WinRateMultiplier:          1.0,
-			FirstTradeLargeMultiplier:  firstTradeLargeMultiplier,
-			FlashFundingMultiplier:     flashFundingMultiplier,
-			LiquidityMultiplier:        liquidityMultiplier,
-			PriceConfidenceMultiplier:  priceConfidenceMultiplier,
-			ConcentrationMultiplier:    concentrationMultiplier,
-			VelocityMultiplier:         velocityMultiplier,
-			ClusterMultiplier:          clusterMultiplier,
-			CoordinatedMultiplier:      1.0,
-			FundingAgeMultiplier:       1.0,
-			WinRate:                    winRate,
-			ResolvedTrades:             0,
-			FundingAgeHours:            fundingAgeHours,
-			HoursToClose:               hoursToClose,
-			LiquidityRatio:             0,
-			NetConcentration:           netPosConcentration,
-			VelocityCount:              velocityCount,
-			ClusterID:                  clusterID,
-			IsCoordinated:              isCoordinated,
-		}
-		
-		if walletStats != nil {
-			breakdown.ResolvedTrades = walletStats.TotalResolvedTrades
-		}
-		if marketInfo != nil && marketInfo.LiquidityNum > 0 {
-			breakdown.LiquidityRatio = notional / marketInfo.LiquidityNum
-		}
-
-		// Apply win rate multiplier to severity determination
-		adjustedScore := rawScore
-		// Only apply win rate multiplier if wallet has sufficient sample size (5+ resolved trades)
-		if walletStats != nil && walletStats.TotalResolvedTrades >= 5 && winRate >= p.cfg.MinWinRateThreshold {
-			// High win rate increases suspicion
-			breakdown.WinRateMultiplier = 1.0 + winRate
-			adjustedScore *= breakdown.WinRateMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":         wallet.WalletAddress,
-				"win_rate":       winRate,
-				"resolved_trades": walletStats.TotalResolvedTrades,
-			}).Info("Applied win rate multiplier")
-		}
-
-		// Apply first trade large multiplier
-		if firstTradeLargeMultiplier > 1.0 {
-			adjustedScore *= firstTradeLargeMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":                      wallet.WalletAddress,
-				"first_trade_large_multiplier": firstTradeLargeMultiplier,
-			}).Info("Applied first trade large multiplier")
-		}
-
-		// Apply flash funding multiplier
-		if flashFundingMultiplier > 1.0 {
-			adjustedScore *= flashFundingMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":                   wallet.WalletAddress,
-				"funding_age_minutes":      fundingAgeMinutes,
-				"flash_funding_multiplier": flashFundingMultiplier,
-			}).Info("Applied flash funding multiplier")
-		}
-
-		// Apply liquidity ratio multiplier
-		if liquidityMultiplier > 1.0 {
-			adjustedScore *= liquidityMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":               wallet.WalletAddress,
-				"liquidity_multiplier": liquidityMultiplier,
-			}).Info("Applied liquidity ratio multiplier")
-		}
-
-		// Apply extreme price confidence multiplier
-		if priceConfidenceMultiplier > 1.0 {
-			adjustedScore *= priceConfidenceMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet": wallet.WalletAddress,
-				"price":  trade.Price,
-			}).Info("Applied extreme price multiplier")
-		}
-
-		// Apply net position concentration multiplier
-		if concentrationMultiplier > 1.0 {
-			adjustedScore *= concentrationMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":                    wallet.WalletAddress,
-				"concentration_multiplier": concentrationMultiplier,
-			}).Info("Applied concentration multiplier")
-		}
-
-		// Apply velocity multiplier
-		if velocityMultiplier > 1.0 {
-			adjustedScore *= velocityMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":              wallet.WalletAddress,
-				"velocity_count":      velocityCount,
-				"velocity_multiplier": velocityMultiplier,
-			}).Info("Applied velocity multiplier")
-		}
-
-		// Apply cluster multiplier
-		if clusterMultiplier > 1.0 {
-			adjustedScore *= clusterMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":            wallet.WalletAddress,
-				"cluster_id":        clusterID,
-				"cluster_multiplier": clusterMultiplier,
-			}).Info("Applied cluster multiplier")
-		}
-
-		// Extra boost if coordinated trade detected
-		if isCoordinated {
-			breakdown.CoordinatedMultiplier = 2.0
-			adjustedScore *= 2.0
-			p.log.WithFields(logrus.Fields{
-				"wallet":     wallet.WalletAddress,
-				"cluster_id": clusterID,
-			}).Warn("Trade is part of coordinated cluster activity")
-		}
+	// Skip trades for markets that have already ended/resolved. Trades can't
+	// happen after a market closes, so a surge here means cached end dates
+	// are stale.
+	if marketInfo.EndDate > 0 && trade.Timestamp >= marketInfo.EndDate {
+		stats.filtered("closed", marketCategory(marketInfo))
+		metrics.TradesAfterEndDate.WithLabelValues(marketInfo.EndDateSource).Inc()
+		p.log.WithFields(logrus.Fields{
+			"condition_id":    trade.ConditionID,
+			"title":           marketInfo.Title,
+			"trade_time":      trade.Timestamp,
+			"end_date":        marketInfo.EndDate,
+			"end_date_source": marketInfo.EndDateSource,
+		}).Debug("Skipping trade for closed market")
+		return true
+	}
+
+	// Skip markets ending beyond the max horizon (measured from the trade, so
+	// replayed trades are filtered the same way as live ones)
+	maxHorizonTS := trade.Timestamp + int64(p.config().MaxMarketHorizonDays)*86400
+	if marketInfo.EndDate > maxHorizonTS {
+		stats.filtered("horizon", marketCategory(marketInfo))
+		p.log.WithFields(logrus.Fields{
+			"condition_id": trade.ConditionID,
+			"title":        marketInfo.Title,
+			"trade_time":   trade.Timestamp,
+			"end_date":     marketInfo.EndDate,
+		}).Debug("Skipping trade for distant market")
+		return true
+	}
 
-		// Apply funding age multiplier if wallet traded very soon after funding
-		// Suspicious if first trade within 24 hours of receiving funds
-		if fundingAgeHours > 0 && fundingAgeHours <= 24 {
-			// 1 hour = 2.5x, 12 hours = 1.5x, 24 hours = 1.0x
-			breakdown.FundingAgeMultiplier = 1.0 + (24.0-fundingAgeHours)/24.0*1.5
-			adjustedScore *= breakdown.FundingAgeMultiplier
-			p.log.WithFields(logrus.Fields{
-				"wallet":             wallet.WalletAddress,
-				"funding_age_hours": fundingAgeHours,
-				"multiplier":        breakdown.FundingAgeMultiplier,
-			}).Debug("Applied funding age multiplier")
-		}
-		
-		breakdown.FinalScore = adjustedScore
-		
-		// Normalize score to 0-100 for better UX
-		normalizedScore := p.normalizeScore(adjustedScore)
-		breakdown.NormalizedScore = normalizedScore
-		
-		// Record both raw and normalized scores for calibration analysis
-		// This allows us to observe actual score distributions in production
-		// and adjust the normalization function if needed
-		metrics.RecordSuspicionScore(adjustedScore, normalizedScore)
-
-		severity := p.determineSeverity(normalizedScore)
-		// if severity != alerts.SeverityInfo {
-			if err := p.sendAlert(ctx, trade, wallet, marketInfo, notional, walletAgeDays, adjustedScore, normalizedScore, severity, breakdown); err != nil {
-				p.log.WithError(err).Error("Failed to send alert")
-			}
-		// }
-	// }
+	return false
+}
 
-	return nil
+// fundingAge returns the time between a wallet's funding and its first
+// activity, in hours and minutes, or zero when either is unknown
+func (p *Processor) fundingAge(wallet *storage.Wallet) (hours, minutes float64) {
+	if wallet.FundingReceivedTS > 0 && wallet.FirstSeenTS > 0 && wallet.FirstSeenTS >= wallet.FundingReceivedTS {
+		seconds := float64(wallet.FirstSeenTS - wallet.FundingReceivedTS)
+		return seconds / 3600.0, seconds / 60.0
+	}
+	if wallet.FundingReceivedTS > wallet.FirstSeenTS {
+		// Edge case: API returned first trade as FirstSeenTS but funding came after
+		// This likely means our FirstSeenTS detection is incomplete
+		p.log.WithFields(logrus.Fields{
+			"wallet":           wallet.WalletAddress,
+			"first_seen":       wallet.FirstSeenTS,
+			"funding_received": wallet.FundingReceivedTS,
+		}).Debug("FirstSeenTS predates FundingReceivedTS - possible detection issue")
+	}
+	return 0, 0
 }
 
 func (p *Processor) getOrCreateWallet(ctx context.Context, address string, tradeTimestamp int64) (*storage.Wallet, error) {
@@ -596,14 +766,30 @@ func (p *Processor) getOrCreateWallet(ctx context.Context, address string, trade
 		return wallet, nil
 	}
 
-	// New wallet - acquire lock to prevent duplicate API calls from concurrent goroutines
-	lockValue, _ := p.walletLocks.LoadOrStore(address, &sync.Mutex{})
-	lock := lockValue.(*sync.Mutex)
-	lock.Lock()
-	defer lock.Unlock()
+	// New wallet - concurrent trades by the same wallet share one creation
+	// and its API calls
+	leader := false
+	v, err, shared := p.walletFlight.Do(address, func() (interface{}, error) {
+		leader = true
+		return p.createWallet(ctx, address, tradeTimestamp)
+	})
+	if shared && !leader {
+		metrics.CoalescedLookups.WithLabelValues("wallet").Inc()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Callers update their wallet's totals, so each gets its own copy
+	created := *v.(*storage.Wallet)
+	return &created, nil
+}
 
-	// Double-check after acquiring lock - another goroutine may have created it
-	wallet, err = p.db.GetWallet(ctx, address)
+// createWallet builds a wallet's record from on-chain history or the Data
+// API and stores it. Callers go through walletFlight.
+func (p *Processor) createWallet(ctx context.Context, address string, tradeTimestamp int64) (*storage.Wallet, error) {
+	// Double-check - a flight that just finished may have created it
+	wallet, err := p.db.GetWallet(ctx, address)
 	if err != nil {
 		return nil, err
 	}
@@ -611,45 +797,126 @@ func (p *Processor) getOrCreateWallet(ctx context.Context, address string, trade
 		return wallet, nil
 	}
 
-	// New wallet - get first activity
-	var firstSeenTS, fundingReceivedTS int64
-	var fundingSource string
-	activity, err := p.dataClient.GetWalletFirstActivity(ctx, address)
+	wallet, source, err := p.newWalletRecord(ctx, address, tradeTimestamp)
 	if err != nil {
-		p.log.WithError(err).WithField("wallet", address).Warn("Failed to get first activity, using trade timestamp")
-		firstSeenTS = tradeTimestamp
-		fundingReceivedTS = 0 // Unknown
-	} else {
-		firstSeenTS = activity.Timestamp
-		// First activity is likely funding received
-		fundingReceivedTS = activity.Timestamp
-		// Extract funding source if available
-		fundingSource = activity.GetFromAddress()
+		return nil, err
 	}
 
-	wallet = &storage.Wallet{
+	// Insert wallet into database
+	if err := p.db.UpsertWallet(ctx, wallet); err != nil {
+		return nil, fmt.Errorf("insert wallet: %w", err)
+	}
+
+	// Track funding if detected
+	if source != nil {
+		if err := p.trackFundingSource(ctx, source); err != nil {
+			p.log.WithError(err).Warn("Failed to track funding source")
+		}
+	}
+
+	return wallet, nil
+}
+
+// newWalletRecord looks up a new wallet's first activity and funding. It
+// returns the wallet's record and, when the sender is known, its funding
+// source; neither is stored.
+func (p *Processor) newWalletRecord(ctx context.Context, address string, tradeTimestamp int64) (*storage.Wallet, *storage.WalletFundingSource, error) {
+	// Prefer on-chain history, which knows the real funding time. The first
+	// fill is first activity and the first USDC inflow is funding, kept
+	// apart so the funding age rules can measure the gap between them.
+	var firstSeenTS, fundingReceivedTS int64
+	var fundingSource, fundingTxHash string
+	var fundingAmount float64
+	history := p.getOnChainHistory(ctx, address)
+	if history != nil {
+		firstSeenTS = history.FirstActivityTS
+		if firstSeenTS == 0 {
+			firstSeenTS = tradeTimestamp // Funded but never filled before this trade
+		}
+		fundingReceivedTS = history.FirstFundingTS
+		fundingSource = history.FundingSource
+		fundingAmount = history.FundingAmountUSD
+		fundingTxHash = history.FundingTxHash
+	}
+
+	// Fall back to the Data API's first activity
+	if firstSeenTS == 0 {
+		activity, err := p.dataClient.GetWalletFirstActivity(ctx, address)
+		if unavailableStatus(err) != "" {
+			// Storing the trade time as first seen would make the wallet look
+			// new forever; retry the trade once the API is back
+			return nil, nil, err
+		}
+		if err != nil {
+			// A wallet with no activity yet really is new
+			entry := p.log.WithError(err).WithField("wallet", address)
+			if errors.Is(err, dataapi.ErrNotFound) {
+				entry.Debug("No first activity, using trade timestamp")
+			} else {
+				entry.Warn("Failed to get first activity, using trade timestamp")
+			}
+			firstSeenTS = tradeTimestamp
+			fundingReceivedTS = 0 // Unknown
+		} else {
+			firstSeenTS = activity.Timestamp
+			// First activity is likely funding received
+			fundingReceivedTS = activity.Timestamp
+			// Extract funding source if available
+			fundingSource = activity.GetFromAddress()
+			if activity.Type == "TRANSFER" {
+				fundingAmount = activity.USDCSize
+				fundingTxHash = activity.TransactionHash
+			}
+		}
+	}
+
+	wallet := &storage.Wallet{
 		WalletAddress:     address,
 		FirstSeenTS:       firstSeenTS,
 		FundingReceivedTS: fundingReceivedTS,
 		TotalTrades:       0,
 		TotalVolumeUSD:    0,
 		LastActivityTS:    tradeTimestamp,
-		UpdatedTS:         time.Now().Unix(),
+		UpdatedTS:         p.clock.Now().Unix(),
+	}
+	if fundingSource == "" {
+		return wallet, nil, nil
 	}
+	return wallet, &storage.WalletFundingSource{
+		WalletAddress: address,
+		FundingSource: fundingSource,
+		FundingTS:     fundingReceivedTS,
+		AmountUSD:     fundingAmount,
+		TxHash:        fundingTxHash,
+	}, nil
+}
 
-	// Insert wallet into database
-	if err := p.db.UpsertWallet(ctx, wallet); err != nil {
-		return nil, fmt.Errorf("insert wallet: %w", err)
+// getOnChainHistory fetches wallet history from the subgraph when configured.
+// Returns nil if the subgraph is disabled, fails, or knows nothing about the wallet.
+func (p *Processor) getOnChainHistory(ctx context.Context, address string) *subgraph.WalletHistory {
+	if p.subgraph == nil {
+		return nil
 	}
 
-	// Track funding source if detected
-	if fundingSource != "" && p.cfg.EnableClusterDetection {
-		if err := p.trackFundingSource(ctx, address, fundingSource, fundingReceivedTS); err != nil {
-			p.log.WithError(err).Warn("Failed to track funding source")
-		}
+	history, err := p.subgraph.GetWalletHistory(ctx, address)
+	if err != nil {
+		p.log.WithError(err).WithField("wallet", address).Warn("Failed to get on-chain history, falling back to Data API")
+		return nil
+	}
+	if history.FirstActivityTS == 0 && history.FirstFundingTS == 0 {
+		return nil
 	}
 
-	return wallet, nil
+	p.log.WithFields(logrus.Fields{
+		"wallet":           address,
+		"first_activity":   history.FirstActivityTS,
+		"first_funding":    history.FirstFundingTS,
+		"funding_source":   history.FundingSource,
+		"total_inflow_usd": history.TotalInflowUSD,
+		"positions":        history.PositionCount,
+	}).Debug("Loaded on-chain wallet history")
+
+	return history
 }
 
 func (p *Processor) resolveMarket(ctx context.Context, trade *dataapi.Trade) (*MarketInfo, error) {
@@ -659,100 +926,305 @@ func (p *Processor) resolveMarket(ctx context.Context, trade *dataapi.Trade) (*M
 		return nil, err
 	}
 
-	if cached != nil {
-		// Check TTL (24 hours)
-		if time.Now().Unix()-cached.UpdatedTS < 86400 {
-			return &MarketInfo{
-				Title:        cached.MarketTitle,
-				Slug:         cached.MarketSlug,
-				URL:          cached.MarketURL,
-				Category:     cached.Category,
-				EndDate:      cached.EndDate,
-				LiquidityNum: cached.LiquidityNum,
-				VolumeNum:    cached.VolumeNum,
-			}, nil
-		}
+	if cached != nil && p.marketFresh(cached, p.clock.Now().Unix()) {
+		metrics.MarketLookups.WithLabelValues("cache").Inc()
+		return cachedMarketInfo(cached), nil
+	}
+
+	info, err := p.fetchMarket(ctx, trade, cached)
+	if err == nil && info.Fallback {
+		// Gamma has no such market yet; remember that briefly so every trade
+		// in it doesn't repeat both lookups
+		p.cacheFallbackMarket(ctx, trade.ConditionID, info)
+	}
+	return info, err
+}
+
+// marketFresh reports whether a market_map row is within its TTL: 24 hours,
+// or MARKET_FALLBACK_TTL for rows built from trade data
+func (p *Processor) marketFresh(cached *storage.MarketMap, now int64) bool {
+	ttl := int64(86400)
+	if cached.IsFallback {
+		ttl = int64(p.config().MarketFallbackTTL.Seconds())
 	}
+	return now-cached.UpdatedTS < ttl
+}
 
-	// Resolve via Gamma API or trade data
-	var marketURL, marketTitle, marketSlug string
-	var category string
-	var endDate int64
-	var liquidityNum, volumeNum float64
+// marketLookup is the result of a Gamma market lookup shared between
+// workers, with the lookup that found it
+type marketLookup struct {
+	info *MarketInfo
+	path string // condition_id or slug
+}
 
-	// Always try to get market info from Gamma API for category data
-	market, err := p.gammaClient.GetMarketByConditionID(ctx, trade.ConditionID)
-	if err != nil {
-		// Fallback to trade data if Gamma API fails
-		if trade.Slug != "" {
-			marketSlug = trade.Slug
-			marketTitle = trade.Title
-			marketURL = fmt.Sprintf("https://polymarket.com/market/%s", trade.Slug)
-			// No category available - cannot filter sports
-		} else {
-			marketURL = fmt.Sprintf("https://polymarket.com/search?q=%s", trade.ConditionID)
-			marketTitle = trade.Title
-			marketSlug = ""
+// fetchMarket looks a trade's market up in Gamma and caches it, by condition
+// ID and then by the trade's slug. Refreshing an expired cache entry is a
+// conditional request, so an unchanged market only has its entry renewed.
+// When Gamma fails it falls back to the expired cache entry, if any, then
+// to the trade's own title and slug.
+func (p *Processor) fetchMarket(ctx context.Context, trade *dataapi.Trade, cached *storage.MarketMap) (*MarketInfo, error) {
+	// Always try to get market info from Gamma API for category data.
+	// Workers handling trades in the same new market share one request.
+	leader := false
+	v, err, shared := p.marketFlight.Do(trade.ConditionID, func() (interface{}, error) {
+		leader = true
+		market, validators, err := p.gammaClient.GetMarketByConditionIDIfModified(ctx, trade.ConditionID, cachedValidators(cached))
+		if errors.Is(err, gammaapi.ErrNotModified) {
+			return marketLookup{info: p.renewMarket(ctx, cached, validators), path: "not_modified"}, nil
 		}
-	} else {
-		marketSlug = market.Slug
-		marketTitle = market.Question
-		marketURL = fmt.Sprintf("https://polymarket.com/market/%s", market.Slug)
-		category = market.Category
-		liquidityNum = market.LiquidityNum
-		volumeNum = market.VolumeNum
-
-		// Parse EndDate if present
-		if market.EndDate != "" {
-			endTime, err := time.Parse(time.RFC3339, market.EndDate)
-			if err == nil {
-				endDate = endTime.Unix()
-			}
+		path := "condition_id"
+		if errors.Is(err, gammaapi.ErrMarketNotFound) && trade.Slug != "" {
+			// The condition_ids filter sometimes misses brand-new markets
+			market, err = p.marketBySlug(ctx, trade)
+			validators = gammaapi.Validators{}
+			path = "slug"
 		}
-
-		// Cache it
-		mapRecord := &storage.MarketMap{
-			ConditionID:  trade.ConditionID,
-			MarketSlug:   market.Slug,
-			MarketTitle:  market.Question,
-			MarketURL:    marketURL,
-			Category:     market.Category,
-			EndDate:      endDate,
-			VolumeNum:    market.VolumeNum,
-			LiquidityNum: market.LiquidityNum,
-			IsActive:     market.Active,
-			UpdatedTS:    time.Now().Unix(),
+		if err != nil {
+			return nil, err
 		}
-		if err := p.db.UpsertMarketMap(ctx, mapRecord); err != nil {
-			p.log.WithError(err).Error("Failed to cache market map")
+		return marketLookup{info: p.cacheMarket(ctx, trade.ConditionID, trade.EventSlug, market, validators), path: path}, nil
+	})
+	if shared && !leader {
+		metrics.CoalescedLookups.WithLabelValues("market").Inc()
+	}
+	if err != nil && !errors.Is(err, gammaapi.ErrMarketNotFound) {
+		// Gamma is struggling, not missing the market: an expired cache entry
+		// still beats trade data, which has no category to filter on
+		p.log.WithError(err).WithField("condition_id", trade.ConditionID).Warn("Gamma market lookup failed")
+		if cached != nil {
+			metrics.MarketLookups.WithLabelValues("stale_cache").Inc()
+			return cachedMarketInfo(cached), nil
 		}
+		if unavailableStatus(err) != "" {
+			return nil, err
+		}
+	}
+	if err == nil {
+		lookup := v.(marketLookup)
+		metrics.MarketLookups.WithLabelValues(lookup.path).Inc()
+		return lookup.info, nil
+	}
+
+	// Fallback to trade data if Gamma API fails. No category is available,
+	// so sports can't be filtered.
+	metrics.MarketLookups.WithLabelValues("trade_data").Inc()
+	notFound := errors.Is(err, gammaapi.ErrMarketNotFound)
+	if trade.Slug != "" {
+		return &MarketInfo{
+			Title:    trade.Title,
+			Slug:     trade.Slug,
+			URL:      fmt.Sprintf("https://polymarket.com/market/%s", trade.Slug),
+			Fallback: notFound,
+		}, nil
+	}
+	return &MarketInfo{
+		Title:    trade.Title,
+		URL:      fmt.Sprintf("https://polymarket.com/search?q=%s", trade.ConditionID),
+		Fallback: notFound,
+	}, nil
+}
+
+// marketBySlug looks a trade's market up by its slug, returning
+// ErrMarketNotFound when the slug belongs to a different market
+func (p *Processor) marketBySlug(ctx context.Context, trade *dataapi.Trade) (*gammaapi.Market, error) {
+	market, err := p.gammaClient.GetMarketBySlug(ctx, trade.Slug)
+	if err != nil {
+		return nil, err
+	}
+	if market.ConditionID != "" && !strings.EqualFold(market.ConditionID, trade.ConditionID) {
+		return nil, fmt.Errorf("slug %s is condition_id %s: %w", trade.Slug, market.ConditionID, gammaapi.ErrMarketNotFound)
+	}
+	return market, nil
+}
+
+// cacheFallbackMarket stores a market built from trade data in market_map,
+// flagged so it expires after MARKET_FALLBACK_TTL
+func (p *Processor) cacheFallbackMarket(ctx context.Context, conditionID string, info *MarketInfo) {
+	mapRecord := &storage.MarketMap{
+		ConditionID: conditionID,
+		MarketSlug:  info.Slug,
+		MarketTitle: info.Title,
+		MarketURL:   info.URL,
+		IsActive:    true,
+		IsFallback:  true,
+		UpdatedTS:   p.clock.Now().Unix(),
+	}
+	if err := p.db.UpsertMarketMap(ctx, mapRecord); err != nil {
+		p.log.WithError(err).Error("Failed to cache fallback market map")
+	}
+}
+
+// cacheMarket stores a Gamma market in market_map, with the validators of
+// the response it came in when it was looked up alone, and returns its
+// MarketInfo. eventSlug, when known, supplies the end date for markets
+// without one.
+func (p *Processor) cacheMarket(ctx context.Context, conditionID, eventSlug string, market *gammaapi.Market, validators gammaapi.Validators) *MarketInfo {
+	marketURL := fmt.Sprintf("https://polymarket.com/market/%s", market.Slug)
+	tokenIDs := outcomeTokenIDs(market.Outcomes, market.ClobTokenIDs)
+
+	endDate, endDateSource := p.marketEndDate(ctx, market, eventSlug)
+
+	// Cache it
+	mapRecord := &storage.MarketMap{
+		ConditionID:  conditionID,
+		MarketSlug:   market.Slug,
+		MarketTitle:  market.Question,
+		MarketURL:    marketURL,
+		Category:     market.Category,
+		EndDate:      endDate,
+		EndDateSource: endDateSource,
+		VolumeNum:    market.VolumeNum,
+		LiquidityNum: market.LiquidityNum,
+		OutcomeTokenIDs: encodeTokenIDs(tokenIDs),
+		IsActive:     market.Active,
+		ETag:         validators.ETag,
+		LastModified: validators.LastModified,
+		UpdatedTS:    p.clock.Now().Unix(),
+	}
+	if err := p.db.UpsertMarketMap(ctx, mapRecord); err != nil {
+		p.log.WithError(err).Error("Failed to cache market map")
 	}
 
 	return &MarketInfo{
-		Title:        marketTitle,
-		Slug:         marketSlug,
+		Title:        market.Question,
+		Slug:         market.Slug,
 		URL:          marketURL,
-		Category:     category,
+		Category:     market.Category,
 		EndDate:      endDate,
-		LiquidityNum: liquidityNum,
-		VolumeNum:    volumeNum,
-	}, nil
+		EndDateSource: endDateSource,
+		LiquidityNum: market.LiquidityNum,
+		VolumeNum:    market.VolumeNum,
+		TokenIDs:     tokenIDs,
+	}
+}
+
+// warmMarketCache fetches the markets of a poll's trades that have no fresh
+// market_map entry in batched Gamma requests, so workers resolve them from
+// the cache. Markets it can't fetch are left to resolveMarket.
+func (p *Processor) warmMarketCache(ctx context.Context, trades []dataapi.Trade) {
+	eventSlugs := make(map[string]string)
+	var stale []string
+	now := p.clock.Now().Unix()
+	for _, trade := range trades {
+		if _, ok := eventSlugs[trade.ConditionID]; ok || trade.ConditionID == "" {
+			continue
+		}
+		eventSlugs[trade.ConditionID] = trade.EventSlug
+
+		cached, err := p.db.GetMarketMap(ctx, trade.ConditionID)
+		if err != nil || (cached != nil && p.marketFresh(cached, now)) {
+			continue
+		}
+		stale = append(stale, trade.ConditionID)
+	}
+	if len(stale) == 0 {
+		return
+	}
+
+	markets, err := p.gammaClient.GetMarketsByConditionIDs(ctx, stale)
+	if err != nil {
+		p.log.WithError(err).Warn("Failed to batch fetch markets")
+	}
+	for conditionID, market := range markets {
+		p.cacheMarket(ctx, conditionID, eventSlugs[conditionID], market, gammaapi.Validators{})
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"requested": len(stale),
+		"cached":    len(markets),
+	}).Debug("Warmed market cache")
+}
+
+// cachedValidators returns the validators to refresh a market_map row
+// with, or none when there is no row to fall back on
+func cachedValidators(cached *storage.MarketMap) gammaapi.Validators {
+	if cached == nil || cached.IsFallback {
+		return gammaapi.Validators{}
+	}
+	return gammaapi.Validators{ETag: cached.ETag, LastModified: cached.LastModified}
+}
+
+// renewMarket restarts the TTL of a market_map row Gamma says is unchanged,
+// without rewriting it, and returns its MarketInfo
+func (p *Processor) renewMarket(ctx context.Context, cached *storage.MarketMap, validators gammaapi.Validators) *MarketInfo {
+	now := p.clock.Now().Unix()
+	if err := p.db.TouchMarketMap(ctx, cached.ConditionID, validators.ETag, validators.LastModified, now); err != nil {
+		p.log.WithError(err).Error("Failed to renew market map")
+	}
+	return cachedMarketInfo(cached)
+}
+
+// cachedMarketInfo builds MarketInfo from a market_map row
+func cachedMarketInfo(cached *storage.MarketMap) *MarketInfo {
+	return &MarketInfo{
+		Title:        cached.MarketTitle,
+		Slug:         cached.MarketSlug,
+		URL:          cached.MarketURL,
+		Category:     cached.Category,
+		EndDate:      cached.EndDate,
+		EndDateSource: cached.EndDateSource,
+		LiquidityNum: cached.LiquidityNum,
+		VolumeNum:    cached.VolumeNum,
+		TokenIDs:     decodeTokenIDs(cached.OutcomeTokenIDs),
+		Fallback:     cached.IsFallback,
+	}
+}
+
+// Market end date sources recorded on MarketMap
+const (
+	endDateSourceMarket = "market"
+	endDateSourceEvent  = "event"
+)
+
+// marketEndDate returns a market's end date and where it came from: the
+// market itself or, since many markets only carry one on their parent
+// event, the event named by eventSlug. It returns 0 and "" when neither has
+// one.
+func (p *Processor) marketEndDate(ctx context.Context, market *gammaapi.Market, eventSlug string) (int64, string) {
+	if market.EndDate != "" {
+		if endTime, err := time.Parse(time.RFC3339, market.EndDate); err == nil {
+			return endTime.Unix(), endDateSourceMarket
+		}
+	}
+	if eventSlug != "" {
+		if endDate := p.eventEndDate(ctx, eventSlug); endDate > 0 {
+			return endDate, endDateSourceEvent
+		}
+	}
+	return 0, ""
+}
+
+// eventEndDate returns the end date of a market's parent event, or 0 when it
+// can't be determined
+func (p *Processor) eventEndDate(ctx context.Context, eventSlug string) int64 {
+	event, err := p.getEvent(ctx, eventSlug)
+	if err != nil {
+		p.log.WithError(err).WithField("event_slug", eventSlug).Debug("Failed to fetch parent event")
+		return 0
+	}
+	if event.EndDate == "" {
+		return 0
+	}
+	endTime, err := time.Parse(time.RFC3339, event.EndDate)
+	if err != nil {
+		return 0
+	}
+	return endTime.Unix()
 }
 
 // calculateSuspicionScore calculates a suspicion score based on trade size, wallet age, and time to close
 func (p *Processor) calculateSuspicionScore(notional float64, walletAgeDays int, hoursToClose float64) float64 {
-	// Base score: notional / wallet age
-	baseScore := notional / float64(max(walletAgeDays, 1))
+	return p.baseSuspicionScore(notional, walletAgeDays) * p.timeToCloseMultiplier(hoursToClose)
+}
 
-	// Apply time-to-close multiplier if trade is close to market resolution
-	if hoursToClose > 0 && hoursToClose <= float64(p.cfg.TimeToCloseHoursMax) {
-		// Exponential multiplier: closer to close = higher multiplier
-		// e.g., 48 hours = 1.5x, 24 hours = 2x, 12 hours = 3x, 1 hour = 5x
-		multiplier := 1.0 + (float64(p.cfg.TimeToCloseHoursMax)-hoursToClose)/float64(p.cfg.TimeToCloseHoursMax)*4.0
-		baseScore *= multiplier
-	}
+// baseSuspicionScore is the pre-multiplier score: notional / wallet age
+func (p *Processor) baseSuspicionScore(notional float64, walletAgeDays int) float64 {
+	return notional / float64(max(walletAgeDays, 1))
+}
 
-	return baseScore
+// timeToCloseMultiplier boosts trades placed close to market resolution
+func (p *Processor) timeToCloseMultiplier(hoursToClose float64) float64 {
+	return (&timeToCloseRule{cfg: p.config()}).multiplier(hoursToClose)
 }
 
 // normalizeScore converts raw suspicion score to 0-100 scale using logarithmic normalization
@@ -795,41 +1267,45 @@ func (p *Processor) normalizeScore(rawScore float64) float64 {
 	return normalized
 }
 
+// Reasons a scored trade's alert isn't notified, the reason label of
+// insiderwatch_alerts_suppressed_total
+const (
+	suppressedRecordOnly     = "record_only"     // Old wallet below OLD_WALLET_SCORE_ALERT; stored, never notified
+	suppressedWalletCooldown = "wallet_cooldown" // Wallet alerted within ALERT_COOLDOWN_MINS
+)
+
+// suppressAlert counts and logs an alert that won't be notified
+func (p *Processor) suppressAlert(reason, profile string, trade *dataapi.Trade, wallet string, score float64) {
+	metrics.AlertsSuppressed.WithLabelValues(reason, profileLabel(profile)).Inc()
+	p.log.WithFields(logrus.Fields{
+		"reason":       reason,
+		"profile":      profileLabel(profile),
+		"wallet":       wallet,
+		"condition_id": trade.ConditionID,
+		"market_slug":  trade.Slug,
+		"score":        score,
+	}).Info("Alert suppressed")
+}
+
+// inAlertCooldown reports whether the wallet's last notified alert is
+// within ALERT_COOLDOWN_MINS, which holds back another notification
+func (p *Processor) inAlertCooldown(lastAlert *storage.Alert) bool {
+	if lastAlert == nil {
+		return false
+	}
+	cooldownSec := int64(p.config().AlertCooldownMins * 60)
+	return p.clock.Now().Unix()-lastAlert.CreatedTS < cooldownSec
+}
+
 // isNotInsiderCategory checks if a market category cannot involve insider trading
-// (sports, entertainment, etc.)
+// (sports)
 func isNotInsiderCategory(market *MarketInfo) bool {
-	excludedCategories := []string{
-		"sports",
-		"nfl",
-		"nba",
-		"mlb",
-		"nhl",
-		"soccer",
-		"football",
-		"basketball",
-		"baseball",
-		"hockey",
-		"mma",
-		"ufc",
-		"boxing",
-		"tennis",
-		"golf",
-		"racing",
-		"f1",
-		"nascar",
-	}
-
-	for _, excluded := range excludedCategories {
-		if strings.Contains(strings.ToLower(market.Category), excluded) || strings.Contains(strings.ToLower(market.Slug), excluded) {
-			return true
-		}
-	}
-	return false
+	return marketCategory(market) == categorySports
 }
 
 func (p *Processor) updateNetPosition(ctx context.Context, trade *dataapi.Trade, notional float64) error {
 	// Calculate window start (rolling window in hours)
-	windowHrs := int64(p.cfg.NetPositionWindowHrs)
+	windowHrs := int64(p.config().NetPositionWindowHrs)
 	windowStartTS := (trade.Timestamp / (windowHrs * 3600)) * (windowHrs * 3600)
 
 	// Get existing position to properly accumulate
@@ -850,7 +1326,7 @@ func (p *Processor) updateNetPosition(ctx context.Context, trade *dataapi.Trade,
 		WindowStartTS:  windowStartTS,
 		NetNotionalUSD: netNotional,
 		TradeCount:     1,
-		UpdatedTS:      time.Now().Unix(),
+		UpdatedTS:      p.clock.Now().Unix(),
 	}
 
 	// Accumulate if position exists
@@ -871,24 +1347,18 @@ func (p *Processor) sendAlert(
 	walletAgeDays int,
 	rawScore float64,
 	normalizedScore float64,
-	severity alerts.Severity,
+	route alertRoute,
 	breakdown *alerts.ScoreBreakdown,
+	stats *pollStats,
 ) error {
-	// Check cooldown
-	lastAlert, err := p.db.GetLastAlertForWallet(ctx, wallet.WalletAddress)
+	severity := route.severity
+
+	// Keep the breakdown on the alert row for retrospective analysis
+	breakdownJSON, err := json.Marshal(breakdown)
 	if err != nil {
-		p.log.WithError(err).Warn("Failed to get last alert")
-	}
-	if lastAlert != nil {
-		cooldownSec := int64(p.cfg.AlertCooldownMins * 60)
-		if time.Now().Unix()-lastAlert.CreatedTS < cooldownSec {
-			p.log.WithField("wallet", wallet.WalletAddress).Info("Alert suppressed (cooldown)")
-			metrics.AlertsSuppressed.Inc()
-			return nil
-		}
+		p.log.WithError(err).Warn("Failed to encode score breakdown")
 	}
 
-	// Store alert
 	alertRecord := &storage.Alert{
 		AlertType:         string(severity),
 		WalletAddress:     wallet.WalletAddress,
@@ -902,17 +1372,79 @@ func (p *Processor) sendAlert(
 		Price:             trade.Price,
 		WalletAgeDays:     walletAgeDays,
 		SuspicionScore:    rawScore,
+		ScoreBreakdown:    string(breakdownJSON),
+		RecordOnly:        !route.notify,
+		Profile:           route.profile,
 		TransactionHash:   trade.TransactionHash,
 		TradeTimestampSec: trade.Timestamp,
 	}
-	if _, err := p.db.InsertAlert(ctx, alertRecord); err != nil {
+
+	// Record-only alerts skip the cooldown; they don't notify anyone
+	if !route.notify {
+		if _, err := p.db.InsertAlert(ctx, alertRecord); err != nil {
+			return fmt.Errorf("insert alert: %w", err)
+		}
+		p.alertHub.Publish(*alertRecord)
+		p.suppressAlert(suppressedRecordOnly, route.profile, trade, wallet.WalletAddress, normalizedScore)
+		return nil
+	}
+
+	// Check cooldown, which each profile keeps separately
+	lastAlert, err := p.db.GetLastAlertForWallet(ctx, wallet.WalletAddress, route.profile)
+	if err != nil {
+		p.log.WithError(err).Warn("Failed to get last alert")
+	}
+	if p.inAlertCooldown(lastAlert) {
+		p.suppressAlert(suppressedWalletCooldown, route.profile, trade, wallet.WalletAddress, normalizedScore)
+		return nil
+	}
+
+	// Measure how thin the book was, only for trades worth alerting on to
+	// keep CLOB request volume down
+	var bookDepthUSD, bookConsumed float64
+	if p.config().EnableOrderbookCheck && severity != alerts.SeverityInfo && len(marketInfo.TokenIDs) > 0 {
+		bookDepthUSD, bookConsumed, err = p.checkOrderBook(ctx, trade, marketInfo, notional)
+		if err != nil {
+			p.log.WithError(err).WithField("condition_id", trade.ConditionID).Warn("Failed to check order book depth")
+		}
+	}
+
+	// Store alert
+	alertID, err := p.db.InsertAlert(ctx, alertRecord)
+	if err != nil {
 		return fmt.Errorf("insert alert: %w", err)
 	}
+	p.alertHub.Publish(*alertRecord)
+	if p.config().EnableExitAlerts && severity != alerts.SeverityInfo {
+		p.trackAlertPosition(ctx, alertID, trade, severity, route.profile, notional)
+	}
+
+	// Show how much the wallet has riding elsewhere on the strongest alerts
+	var exposureUSD float64
+	var exposureMarkets int
+	if severity == alerts.SeverityAlert {
+		exposure, err := p.WalletExposure(ctx, wallet.WalletAddress)
+		if err != nil {
+			p.log.WithError(err).WithField("wallet", wallet.WalletAddress).Warn("Failed to get wallet exposure")
+		} else {
+			exposureUSD, exposureMarkets = exposure.TotalUSD, exposure.Markets
+		}
+	}
+
+	// Best effort: the alert goes out without the current price on failure
+	var currentPrice float64
+	if len(marketInfo.TokenIDs) > 0 {
+		currentPrice, err = p.currentPrice(ctx, trade, marketInfo)
+		if err != nil {
+			p.log.WithError(err).WithField("condition_id", trade.ConditionID).Debug("Failed to get current price")
+		}
+	}
 
 	// Send alert
-	metrics.AlertsTriggered.WithLabelValues(string(severity)).Inc()
+	stats.alert(severity, marketCategory(marketInfo), profileLabel(route.profile), breakdown.FiredRules)
 
 	payload := &alerts.AlertPayload{
+		AlertID:         alertID,
 		Severity:        severity,
 		WalletAddress:   wallet.WalletAddress,
 		WalletShort:     shortenAddress(wallet.WalletAddress),
@@ -922,33 +1454,67 @@ func (p *Processor) sendAlert(
 		Outcome:         trade.Outcome,
 		NotionalUSD:     notional,
 		Price:           trade.Price,
+		CurrentPrice:    currentPrice,
 		WalletAgeDays:   walletAgeDays,
 		FirstSeenDate:   time.Unix(wallet.FirstSeenTS, 0).Format("2006-01-02"),
 		SuspicionScore:  rawScore,
 		NormalizedScore: normalizedScore,
 		ScoreBreakdown:  breakdown,
+		BookDepthUSD:    bookDepthUSD,
+		BookConsumed:    bookConsumed,
+		ExposureUSD:     exposureUSD,
+		ExposureMarkets: exposureMarkets,
 		TransactionHash: trade.TransactionHash,
 		TxHashShort:     shortenHash(trade.TransactionHash),
 		Timestamp:       time.Unix(trade.Timestamp, 0),
-		Environment:     p.cfg.Environment,
+		Environment:     p.config().Environment,
 	}
 
-	return p.alertSender.Send(ctx, payload)
+	if err := p.senderFor(route.profile).Send(ctx, payload); err != nil {
+		tags := tradeTags(trade, "")
+		tags["severity"] = string(severity)
+		tags["profile"] = profileLabel(route.profile)
+		errreport.Capture(errreport.Event{Err: err, Source: "alert_send", Tags: tags})
+		return err
+	}
+
+	metrics.AlertLatency.Observe(p.clock.Since(payload.Timestamp).Seconds())
+	return nil
+}
+
+// gateSeverity decides whether a scored trade notifies. Trades from wallets
+// older than NEW_WALLET_DAYS_MAX are recorded as INFO without a notification
+// unless their score reaches OLD_WALLET_SCORE_ALERT.
+func (p *Processor) gateSeverity(severity alerts.Severity, walletAgeDays int, normalizedScore float64) (alerts.Severity, bool) {
+	return p.gateSeverityAt(severity, walletAgeDays, p.config().NewWalletDaysMax, normalizedScore)
+}
+
+// gateSeverityAt is gateSeverity with the wallet age limit given, for
+// profiles that set their own
+func (p *Processor) gateSeverityAt(severity alerts.Severity, walletAgeDays, newWalletDaysMax int, normalizedScore float64) (alerts.Severity, bool) {
+	if walletAgeDays <= newWalletDaysMax || normalizedScore >= p.config().OldWalletScoreAlert {
+		return severity, true
+	}
+	return alerts.SeverityInfo, false
 }
 
 func (p *Processor) determineSeverity(score float64) alerts.Severity {
-	if score >= p.cfg.SuspicionScoreAlert {
+	if score >= p.config().SuspicionScoreAlert {
 		return alerts.SeverityAlert
 	}
-	if score >= p.cfg.SuspicionScoreWarn {
+	if score >= p.config().SuspicionScoreWarn {
 		return alerts.SeverityWarn
 	}
 	return alerts.SeverityInfo
 }
 
 func (p *Processor) calculateTradeHash(trade *dataapi.Trade) string {
-	// Prefer transaction hash
+	// Prefer transaction hash. A maker fill shares its transaction with the
+	// taker trade, so it is keyed by wallet as well.
 	if trade.TransactionHash != "" {
+		if trade.Role == dataapi.RoleMaker {
+			return trade.TransactionHash + ":" + trade.ProxyWallet
+		}
 		return trade.TransactionHash
 	}
 
@@ -1000,36 +1566,136 @@ func max(a, b int) int {
 	return b
 }
 
-// RecalculateWinRates checks for resolved markets and updates wallet win rates
-func (p *Processor) RecalculateWinRates(ctx context.Context) error {
-	start := time.Now()
+// RecalculateWinRates checks for resolved markets and updates wallet win rates.
+// It returns the number of markets newly resolved.
+func (p *Processor) RecalculateWinRates(ctx context.Context) (int, error) {
+	// Only one recalculation at a time; scheduled, startup and manual runs
+	// can otherwise overlap
+	if !p.recalcRunning.CompareAndSwap(false, true) {
+		metrics.WinRateCalculationsSkipped.Inc()
+		return 0, ErrRecalculationRunning
+	}
+	defer p.recalcRunning.Store(false)
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	// Yield to the poll and alert path on a shared rate limit budget
+	ctx = ratelimit.WithPriority(ctx, ratelimit.PriorityBackground)
+
+	start := p.clock.Now()
 	p.log.Info("Starting win rate recalculation")
 
 	// Get all unique condition IDs from trades
 	conditionIDs, err := p.db.GetAllConditionIDs(ctx)
 	if err != nil {
-		return fmt.Errorf("get condition IDs: %w", err)
+		return 0, fmt.Errorf("get condition IDs: %w", err)
 	}
 
-	p.log.WithField("markets", len(conditionIDs)).Info("Checking markets for resolution")
+	// Resume an interrupted run. Markets behind the cursor wait for the
+	// next full pass, including ones whose batch failed.
+	cursor, err := p.db.GetState(ctx, resolutionScanCursorKey)
+	if err != nil {
+		p.log.WithError(err).Warn("Failed to load resolution scan cursor, starting from the beginning")
+		cursor = ""
+	}
+	scan := newResolutionScan(conditionIDs, cursor, resolutionBatchSize)
 
-	resolvedCount := 0
-	for _, conditionID := range conditionIDs {
-		// Check if already resolved
+	p.log.WithFields(logrus.Fields{
+		"markets":   len(conditionIDs),
+		"remaining": scan.total,
+		"cursor":    cursor,
+	}).Info("Checking markets for resolution")
+
+	// Log progress so a long run shows it's alive
+	progressDone := make(chan struct{})
+	go func() {
+		ticker := p.clock.NewTicker(resolutionProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C():
+				processed, resolved := scan.progress()
+				p.log.WithFields(logrus.Fields{
+					"processed": processed,
+					"total":     scan.total,
+					"resolved":  resolved,
+				}).Info("Win rate recalculation progress")
+			case <-progressDone:
+				return
+			}
+		}
+	}()
+
+	// Gamma's rate limiter is the real throttle; the workers keep its
+	// requests and the database writes between them overlapping
+	err = scan.run(ctx, p.config().WinRateWorkers, p.resolveMarketBatchSafely, func(cursor string) {
+		if err := p.db.SetState(ctx, resolutionScanCursorKey, cursor); err != nil {
+			p.log.WithError(err).Warn("Failed to save resolution scan cursor")
+		}
+	})
+	close(progressDone)
+
+	processed, resolvedCount := scan.progress()
+	if err != nil {
+		p.log.WithFields(logrus.Fields{
+			"processed": processed,
+			"total":     scan.total,
+			"resolved":  resolvedCount,
+		}).Info("Win rate recalculation interrupted, the next run resumes from its cursor")
+		return resolvedCount, err
+	}
+
+	// The pass is complete; the next run starts over
+	if err := p.db.SetState(ctx, resolutionScanCursorKey, ""); err != nil {
+		p.log.WithError(err).Warn("Failed to reset resolution scan cursor")
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"resolved_count":  resolvedCount,
+		"markets_missing": scan.missing.Load(),
+	}).Info("Win rate recalculation complete")
+	metrics.RecordWinRateCalculation(p.clock.Since(start), resolvedCount)
+	return resolvedCount, nil
+}
+
+// resolveMarketBatch fetches a batch of markets from Gamma and records the
+// ones that have resolved. It returns the number newly resolved and the
+// number Gamma didn't return.
+func (p *Processor) resolveMarketBatch(ctx context.Context, batch []string) (int, int) {
+	// Skip markets already resolved
+	var unresolved []string
+	for _, conditionID := range batch {
 		existing, err := p.db.GetMarketResolution(ctx, conditionID)
 		if err != nil {
 			p.log.WithError(err).WithField("condition_id", conditionID).Warn("Failed to check resolution")
 			continue
 		}
-		if existing != nil {
-			continue // Already resolved
+		if existing == nil {
+			unresolved = append(unresolved, conditionID)
 		}
+	}
+	if len(unresolved) == 0 {
+		return 0, 0
+	}
 
-		// Try to resolve via Gamma API
-		market, err := p.gammaClient.GetMarketByConditionID(ctx, conditionID)
-		if err != nil {
-			p.log.WithError(err).WithField("condition_id", conditionID).Debug("Failed to fetch market")
-			continue
+	// On failure the markets fetched so far are still resolved and the
+	// others wait for the next pass
+	markets, err := p.gammaClient.GetMarketsByConditionIDs(ctx, unresolved)
+	if err != nil {
+		p.log.WithError(err).WithFields(logrus.Fields{
+			"fetched":    len(markets),
+			"unresolved": len(unresolved),
+		}).Warn("Failed to fetch markets")
+	}
+
+	resolvedCount := 0
+	for _, conditionID := range unresolved {
+		if ctx.Err() != nil {
+			break
+		}
+		market, ok := markets[conditionID]
+		if !ok {
+			continue // Not found on Gamma, or not fetched
 		}
 
 		// Check if market is closed
@@ -1037,34 +1703,43 @@ func (p *Processor) RecalculateWinRates(ctx context.Context) error {
 			continue
 		}
 
-		// Determine winning outcome from prices
-		winningOutcome := p.determineWinner(market.Outcomes, market.OutcomePrices)
+		// Determine winning outcome, preferring the official resolution data
+		winningOutcome, method := p.resolveWinner(market)
 		if winningOutcome == "" {
 			p.log.WithFields(logrus.Fields{
 				"condition_id": conditionID,
 				"market":       market.Question,
 				"outcomes":     market.Outcomes,
 				"prices":       market.OutcomePrices,
+				"uma_status":   market.UMAResolutionStatus,
 			}).Debug("Could not determine winner")
 			continue
 		}
 
+		if p.config().NormalizeOutcomes {
+			winningOutcome = normalizeOutcome(winningOutcome)
+		}
+
 		// Store resolution
 		resolution := &storage.MarketResolution{
 			ConditionID:    conditionID,
 			WinningOutcome: winningOutcome,
-			ResolvedTS:     time.Now().Unix(),
+			ResolvedTS:     p.clock.Now().Unix(),
 			MarketTitle:    market.Question,
+			Method:         method,
 		}
 		if err := p.db.UpsertMarketResolution(ctx, resolution); err != nil {
 			p.log.WithError(err).Error("Failed to store resolution")
 			continue
 		}
 
-		// Update wallet stats
-		if err := p.updateWalletStatsForResolution(ctx, conditionID, winningOutcome); err != nil {
-			p.log.WithError(err).Error("Failed to update wallet stats")
-			continue
+		// Update wallet stats. A 50-50 resolution has no winner, so it says
+		// nothing about a wallet's skill and is not counted.
+		if winningOutcome != splitOutcome {
+			if err := p.updateWalletStatsForResolution(ctx, conditionID, winningOutcome); err != nil {
+				p.log.WithError(err).Error("Failed to update wallet stats")
+				continue
+			}
 		}
 
 		resolvedCount++
@@ -1072,12 +1747,11 @@ func (p *Processor) RecalculateWinRates(ctx context.Context) error {
 			"condition_id":    conditionID,
 			"market":          market.Question,
 			"winning_outcome": winningOutcome,
+			"method":          method,
 		}).Info("Resolved market and updated wallet stats")
 	}
 
-	p.log.WithField("resolved_count", resolvedCount).Info("Win rate recalculation complete")
-	metrics.RecordWinRateCalculation(time.Since(start), resolvedCount)
-	return nil
+	return resolvedCount, len(unresolved) - len(markets)
 }
 
 // determineWinner parses outcome prices to find the winning outcome
@@ -1106,7 +1780,7 @@ func (p *Processor) determineWinner(outcomes, outcomePrices string) string {
 
 	// Find outcome with price >= 0.95 (95% probability = winner)
 	for i, priceStr := range priceList {
-		price, err := strconv.ParseFloat(priceStr, 64)
+		price, err := strconv.ParseFloat(strings.TrimSpace(priceStr), 64)
 		if err != nil {
 			continue
 		}
@@ -1132,6 +1806,7 @@ func (p *Processor) updateWalletStatsForResolution(ctx context.Context, conditio
 		tradeCount  int
 	}
 	walletPositions := make(map[string]*walletPosition)
+	normalizedMatches := 0
 
 	for _, trade := range trades {
 		if walletPositions[trade.ProxyWallet] == nil {
@@ -1141,54 +1816,56 @@ func (p *Processor) updateWalletStatsForResolution(ctx context.Context, conditio
 		pos.tradeCount++
 
 		// Calculate net position: positive if long winning outcome, negative if short
+		won := p.winningSide(trade.Outcome, winningOutcome, &normalizedMatches)
 		if trade.Side == "BUY" {
-			if trade.Outcome == winningOutcome {
+			if won {
 				pos.netPosition += trade.NotionalUSD
 			} else {
 				pos.netPosition -= trade.NotionalUSD
 			}
 		} else { // SELL
-			if trade.Outcome == winningOutcome {
+			if won {
 				pos.netPosition -= trade.NotionalUSD
 			} else {
 				pos.netPosition += trade.NotionalUSD
 			}
 		}
 	}
+	if normalizedMatches > 0 {
+		p.log.WithFields(logrus.Fields{
+			"condition_id":    conditionID,
+			"winning_outcome": winningOutcome,
+			"trades":          normalizedMatches,
+		}).Info("Matched trades to the winning outcome only after normalizing outcome labels")
+	}
 
 	// Update stats for each wallet based on net position
+	now := p.clock.Now().Unix()
 	for walletAddr, pos := range walletPositions {
-		stats, err := p.db.GetWalletStats(ctx, walletAddr)
-		if err != nil {
-			p.log.WithError(err).WithField("wallet", walletAddr).Warn("Failed to get wallet stats")
-			continue
-		}
-
-		if stats == nil {
-			stats = &storage.WalletStats{
-				WalletAddress: walletAddr,
-			}
-		}
-
 		// Wallet wins if net position is positive (profited from the outcome)
-		// Zero or negative position = loss
-		wins := pos.netPosition > 0
-		stats.TotalResolvedTrades++
-		if wins {
-			stats.WinningTrades++
+		// pos.netPosition == 0 means perfectly hedged, not counted as win or loss
+		outcome := storage.ResultHedged
+		if pos.netPosition > 0 {
+			outcome = storage.ResultWin
 		} else if pos.netPosition < 0 {
-			stats.LosingTrades++
+			outcome = storage.ResultLoss
 		}
-		// Note: pos.netPosition == 0 means perfectly hedged, not counted as win or loss
 
-		// Recalculate win rate
-		if stats.TotalResolvedTrades > 0 {
-			stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalResolvedTrades)
+		// The result row and the stats it updates are written together, so
+		// re-resolving a market can't credit the wallet twice and a failed
+		// update leaves the market to be counted again
+		result := &storage.WalletMarketResult{
+			WalletAddress:  walletAddr,
+			ConditionID:    conditionID,
+			Result:         outcome,
+			NetPositionUSD: pos.netPosition,
+			ResolvedTS:     now,
 		}
-
-		stats.LastCalculatedTS = time.Now().Unix()
-
-		if err := p.db.UpsertWalletStats(ctx, stats); err != nil {
+		lock := p.walletStatsLock(walletAddr)
+		lock.Lock()
+		_, err := p.db.RecordWalletMarketResult(ctx, result)
+		lock.Unlock()
+		if err != nil {
 			p.log.WithError(err).WithField("wallet", walletAddr).Error("Failed to update wallet stats")
 		}
 	}
@@ -1196,23 +1873,49 @@ func (p *Processor) updateWalletStatsForResolution(ctx context.Context, conditio
 	return nil
 }
 
-// trackFundingSource tracks the funding source for a wallet and updates clusters
-func (p *Processor) trackFundingSource(ctx context.Context, walletAddress, fundingSource string, fundingTS int64) error {
-	// Store funding source
-	source := &storage.WalletFundingSource{
-		WalletAddress: walletAddress,
-		FundingSource: fundingSource,
-		FundingTS:     fundingTS,
+// walletStatsLock returns the lock serializing a wallet's stats updates.
+// Resolution workers can credit a wallet for different markets at once, and
+// each update rebuilds the stats from the results its transaction sees, so
+// two running together would each miss the other's market. Wallets share a
+// fixed set of locks by hash.
+func (p *Processor) walletStatsLock(walletAddress string) *sync.Mutex {
+	h := fnv.New32a()
+	h.Write([]byte(walletAddress))
+	return &p.statsLocks[h.Sum32()%uint32(len(p.statsLocks))]
+}
+
+// trackFundingSource stores a wallet's funding and, when cluster detection is
+// enabled, updates the sender's cluster. Funding without a known sender is
+// skipped.
+func (p *Processor) trackFundingSource(ctx context.Context, source *storage.WalletFundingSource) error {
+	if source.FundingSource == "" {
+		return nil
 	}
 	if err := p.db.UpsertWalletFundingSource(ctx, source); err != nil {
 		return fmt.Errorf("upsert funding source: %w", err)
 	}
 
+	if !p.config().EnableClusterDetection {
+		return nil
+	}
+	fundingSource := source.FundingSource
+	fundingTS := source.FundingTS
+
+	// Shared senders such as exchange hot wallets would link unrelated wallets
+	if p.isExcludedClusterAddress(fundingSource) {
+		return nil
+	}
+
 	// Update or create cluster
 	cluster, err := p.db.GetWalletClusterBySource(ctx, fundingSource)
 	if err != nil {
 		return fmt.Errorf("get cluster: %w", err)
 	}
+	if cluster != nil {
+		if cluster, err = p.resolveMergedCluster(ctx, cluster); err != nil {
+			return err
+		}
+	}
 
 	if cluster == nil {
 		// Create new cluster
@@ -1220,16 +1923,54 @@ func (p *Processor) trackFundingSource(ctx context.Context, walletAddress, fundi
 		cluster = &storage.WalletCluster{
 			ClusterID:      clusterID,
 			FundingSource:  fundingSource,
-			WalletCount:    1,
+			LinkType:       storage.LinkFunding,
 			FirstSeenTS:    fundingTS,
 			LastActivityTS: fundingTS,
 		}
-	} else {
-		// Update existing cluster
-		cluster.WalletCount++
-		cluster.LastActivityTS = time.Now().Unix()
 	}
 
+	return p.addClusterMember(ctx, cluster, source.WalletAddress, storage.LinkFunding)
+}
+
+// addClusterMember links a wallet to a cluster. A wallet that already belongs
+// to another cluster brings that cluster with it: the two are merged.
+func (p *Processor) addClusterMember(ctx context.Context, cluster *storage.WalletCluster, walletAddress, linkType string) error {
+	member, err := p.db.GetClusterMember(ctx, walletAddress)
+	if err != nil {
+		return fmt.Errorf("get cluster member: %w", err)
+	}
+
+	if member == nil {
+		member = &storage.WalletClusterMember{
+			WalletAddress: walletAddress,
+			ClusterID:     cluster.ClusterID,
+		}
+	} else if member.ClusterID != cluster.ClusterID {
+		previous, err := p.db.GetWalletClusterByID(ctx, member.ClusterID)
+		if err != nil {
+			return fmt.Errorf("get cluster: %w", err)
+		}
+		if previous != nil {
+			if err := p.mergeClusters(ctx, cluster, previous); err != nil {
+				return err
+			}
+		}
+		member.ClusterID = cluster.ClusterID
+	}
+	member.LinkType = storage.CombineLinkTypes(member.LinkType, linkType)
+
+	if err := p.db.UpsertClusterMember(ctx, member); err != nil {
+		return fmt.Errorf("upsert cluster member: %w", err)
+	}
+
+	members, err := p.db.GetClusterMembers(ctx, cluster.ClusterID)
+	if err != nil {
+		return fmt.Errorf("get cluster members: %w", err)
+	}
+	cluster.WalletCount = len(members)
+	cluster.LinkType = storage.CombineLinkTypes(cluster.LinkType, linkType)
+	cluster.LastActivityTS = p.clock.Now().Unix()
+
 	if err := p.db.UpsertWalletCluster(ctx, cluster); err != nil {
 		return fmt.Errorf("upsert cluster: %w", err)
 	}
@@ -1238,7 +1979,8 @@ func (p *Processor) trackFundingSource(ctx context.Context, walletAddress, fundi
 	if cluster.WalletCount > 1 {
 		p.log.WithFields(logrus.Fields{
 			"cluster_id":     cluster.ClusterID,
-			"funding_source": fundingSource,
+			"funding_source": cluster.FundingSource,
+			"link_type":      cluster.LinkType,
 			"wallet_count":   cluster.WalletCount,
 		}).Info("Detected wallet cluster")
 	}
@@ -1246,34 +1988,80 @@ func (p *Processor) trackFundingSource(ctx context.Context, walletAddress, fundi
 	return nil
 }
 
-// detectCoordinatedTrade checks if a trade is part of coordinated activity
-func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.Trade, walletAddress string) (bool, string, error) {
-	// Get funding source for this wallet
-	fundingSource, err := p.db.GetWalletFundingSource(ctx, walletAddress)
-	if err != nil {
-		return false, "", err
+// mergeClusters moves every wallet in from into into. The emptied cluster is
+// kept, pointing at into, so later wallets from its funding source still
+// find the merged cluster.
+func (p *Processor) mergeClusters(ctx context.Context, into, from *storage.WalletCluster) error {
+	if err := p.db.MoveClusterMembers(ctx, from.ClusterID, into.ClusterID); err != nil {
+		return fmt.Errorf("move cluster members: %w", err)
 	}
-	if fundingSource == nil {
-		return false, "", nil // No funding source tracked
+
+	into.LinkType = storage.CombineLinkTypes(into.LinkType, from.LinkType)
+	if from.FirstSeenTS > 0 && from.FirstSeenTS < into.FirstSeenTS {
+		into.FirstSeenTS = from.FirstSeenTS
+	}
+
+	from.MergedInto = into.ClusterID
+	from.WalletCount = 0
+	if err := p.db.UpsertWalletCluster(ctx, from); err != nil {
+		return fmt.Errorf("upsert merged cluster: %w", err)
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"cluster_id":        into.ClusterID,
+		"merged_cluster_id": from.ClusterID,
+	}).Info("Merged wallet clusters")
+
+	return nil
+}
+
+// resolveMergedCluster follows merge pointers to the cluster that currently
+// holds the wallets
+func (p *Processor) resolveMergedCluster(ctx context.Context, cluster *storage.WalletCluster) (*storage.WalletCluster, error) {
+	// Bounded in case of a pointer cycle
+	for i := 0; i < 10 && cluster.MergedInto != ""; i++ {
+		next, err := p.db.GetWalletClusterByID(ctx, cluster.MergedInto)
+		if err != nil {
+			return nil, fmt.Errorf("get merged cluster: %w", err)
+		}
+		if next == nil {
+			break
+		}
+		cluster = next
 	}
+	return cluster, nil
+}
 
+// getWalletCluster returns the cluster a wallet belongs to, or nil
+func (p *Processor) getWalletCluster(ctx context.Context, walletAddress string) (*storage.WalletCluster, error) {
+	member, err := p.db.GetClusterMember(ctx, walletAddress)
+	if err != nil || member == nil {
+		return nil, err
+	}
+	return p.db.GetWalletClusterByID(ctx, member.ClusterID)
+}
+
+// detectCoordinatedTrade checks if a trade is part of coordinated activity.
+// It returns whether the trade is coordinated, the pattern (same-side pile-in
+// or opposing sides across sibling wallets) and the cluster ID.
+func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.Trade, walletAddress string) (bool, string, string, error) {
 	// Get cluster
-	cluster, err := p.db.GetWalletClusterBySource(ctx, fundingSource.FundingSource)
+	cluster, err := p.getWalletCluster(ctx, walletAddress)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 	if cluster == nil || cluster.WalletCount <= 1 {
-		return false, "", nil // Not a multi-wallet cluster
+		return false, "", "", nil // Not a multi-wallet cluster
 	}
 
 	// Get all wallets in this cluster
-	clusterWallets, err := p.db.GetWalletsByFundingSource(ctx, fundingSource.FundingSource)
+	clusterWallets, err := p.db.GetClusterMembers(ctx, cluster.ClusterID)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	// Get recent trades from cluster wallets (configurable lookback period)
-	lookbackTS := trade.Timestamp - int64(p.cfg.ClusterLookbackHours*3600)
+	lookbackTS := trade.Timestamp - int64(p.config().ClusterLookbackHours*3600)
 	var walletAddrs []string
 	for _, w := range clusterWallets {
 		walletAddrs = append(walletAddrs, w.WalletAddress)
@@ -1281,7 +2069,7 @@ func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.T
 
 	recentTrades, err := p.db.GetRecentTradesForCluster(ctx, walletAddrs, lookbackTS)
 	if err != nil {
-		return false, "", err
+		return false, "", "", err
 	}
 
 	// Check for coordinated activity on this market
@@ -1298,6 +2086,7 @@ func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.T
 		var firstTS, lastTS int64 = trade.Timestamp, trade.Timestamp
 		uniqueWallets := make(map[string]bool)
 		totalNotional := 0.0
+		pattern := alerts.CoordinationSameSide
 
 		// Include current trade
 		uniqueWallets[walletAddress] = true
@@ -1312,6 +2101,9 @@ func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.T
 			if t.TimestampSec > lastTS {
 				lastTS = t.TimestampSec
 			}
+			if t.ProxyWallet != walletAddress && !sameDirection(trade.Outcome, trade.Side, t.Outcome, t.Side) {
+				pattern = alerts.CoordinationOpposing
+			}
 		}
 
 		timeWindowSec := int(lastTS - firstTS)
@@ -1323,6 +2115,7 @@ func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.T
 				WalletCount:      len(uniqueWallets),
 				TotalNotionalUSD: totalNotional,
 				TimeWindowSec:    timeWindowSec,
+				PatternType:      pattern,
 				FirstTradeTS:     firstTS,
 				LastTradeTS:      lastTS,
 				MarketTitle:      trade.Title,
@@ -1337,19 +2130,29 @@ func (p *Processor) detectCoordinatedTrade(ctx context.Context, trade *dataapi.T
 				"wallet_count":   len(uniqueWallets),
 				"time_window":    timeWindowSec,
 				"total_notional": totalNotional,
+				"pattern":        pattern,
 			}).Warn("Detected coordinated trading activity")
 
-			return true, cluster.ClusterID, nil
+			return true, pattern, cluster.ClusterID, nil
 		}
 	}
 
-	return false, "", nil
+	return false, "", "", nil
+}
+
+// sameDirection reports whether two trades on the same market express the
+// same view. Buying one outcome is equivalent to selling the other, so the
+// trades agree when outcome and side both match or both differ.
+func sameDirection(outcomeA, sideA, outcomeB, sideB string) bool {
+	sameOutcome := strings.EqualFold(outcomeA, outcomeB)
+	sameSide := strings.EqualFold(sideA, sideB)
+	return sameOutcome == sameSide
 }
 
 // checkTradeVelocity checks how many trades a wallet made in the recent time window
 func (p *Processor) checkTradeVelocity(ctx context.Context, walletAddress string, currentTradeTS int64) (int, error) {
 	// Calculate lookback timestamp based on velocity window
-	lookbackTS := currentTradeTS - int64(p.cfg.VelocityWindowMinutes*60)
+	lookbackTS := currentTradeTS - int64(p.config().VelocityWindowMinutes*60)
 
 	// Get recent trades for this wallet
 	recentTrades, err := p.db.GetRecentTradesForWallet(ctx, walletAddress, lookbackTS)
@@ -1365,22 +2168,19 @@ func (p *Processor) checkTradeVelocity(ctx context.Context, walletAddress string
 
 // checkNetPositionConcentration checks if wallet is heavily concentrated on one side of a market
 // Returns a ratio from 0.0 to 1.0 indicating concentration (1.0 = 100% on one side)
-func (p *Processor) checkNetPositionConcentration(ctx context.Context, walletAddress, conditionID string, currentTS int64, currentNotional float64, currentSide string) (float64, error) {
-	// Get all trades for this wallet in this market within the window
+func (p *Processor) checkNetPositionConcentration(ctx context.Context, walletAddress, conditionID, tradeHash string, currentTS int64, currentNotional float64, currentSide string) (float64, error) {
+	// Get the wallet's other trades in this market within the window. The
+	// current trade is already stored, so it is excluded and added below.
 	// We need actual trades to calculate gross BUY and SELL volumes
-	windowHrs := int64(p.cfg.NetPositionWindowHrs)
-	lookbackTS := currentTS - int64(windowHrs*3600)
-	recentTrades, err := p.db.GetRecentTradesForWallet(ctx, walletAddress, lookbackTS)
+	lookbackTS := currentTS - int64(p.config().ConcentrationWindowHrs)*3600
+	recentTrades, err := p.db.GetWalletMarketTrades(ctx, walletAddress, conditionID, tradeHash, lookbackTS, currentTS)
 	if err != nil {
-		return 0, fmt.Errorf("get recent trades: %w", err)
+		return 0, fmt.Errorf("get wallet market trades: %w", err)
 	}
 
-	// Calculate gross BUY and SELL volumes for this specific market
+	// Calculate gross BUY and SELL volumes
 	var buyVolume, sellVolume float64
 	for _, trade := range recentTrades {
-		if trade.ConditionID != conditionID {
-			continue
-		}
 		if trade.Side == "BUY" {
 			buyVolume += trade.NotionalUSD
 		} else if trade.Side == "SELL" {
@@ -1409,26 +2209,24 @@ func (p *Processor) checkNetPositionConcentration(ctx context.Context, walletAdd
 
 // getClusterMultiplier returns a suspicion score multiplier based on cluster activity
 func (p *Processor) getClusterMultiplier(ctx context.Context, walletAddress string) float64 {
-	fundingSource, err := p.db.GetWalletFundingSource(ctx, walletAddress)
-	if err != nil || fundingSource == nil {
-		return 1.0
-	}
-
-	cluster, err := p.db.GetWalletClusterBySource(ctx, fundingSource.FundingSource)
+	cluster, err := p.getWalletCluster(ctx, walletAddress)
 	if err != nil || cluster == nil {
 		return 1.0
 	}
 
-	// Multiplier based on cluster size
-	// 2 wallets = 1.5x, 5 wallets = 2.0x, 10+ wallets = 3.0x
-	if cluster.WalletCount >= 10 {
+	return clusterSizeMultiplier(cluster.WalletCount)
+}
+
+// clusterSizeMultiplier returns the multiplier for a cluster of the given size:
+// 2 wallets = 1.5x, 5 wallets = 2.0x, 10+ wallets = 3.0x
+func clusterSizeMultiplier(walletCount int) float64 {
+	if walletCount >= 10 {
 		return 3.0
-	} else if cluster.WalletCount >= 5 {
+	} else if walletCount >= 5 {
 		return 2.0
-	} else if cluster.WalletCount >= 2 {
+	} else if walletCount >= 2 {
 		return 1.5
 	}
-
 	return 1.0
 }
 
@@ -1439,6 +2237,9 @@ type MarketInfo struct {
 	URL          string
 	Category     string
 	EndDate      int64   // Unix timestamp
+	EndDateSource string // market, event, or empty when unknown
 	LiquidityNum float64 // Market liquidity for ratio analysis
 	VolumeNum    float64 // Market volume
+	TokenIDs     map[string]string // Outcome -> CLOB token ID; nil when unknown
+	Fallback     bool              // Built from trade data because Gamma has no such market
 }
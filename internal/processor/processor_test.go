@@ -1,16 +1,40 @@
 package processor
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/circuit"
+	"github.com/liamashdown/insiderwatch/internal/clock/clocktest"
 	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/clobapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/subgraph"
+	"github.com/liamashdown/insiderwatch/internal/processor/processortest"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/sirupsen/logrus"
 )
 
 func TestCalculateSuspicionScore(t *testing.T) {
 	cfg := &config.Config{
 		TimeToCloseHoursMax: 48,
+		Detection:           config.DefaultDetection(),
 	}
 	log := logrus.New()
 	p := &Processor{cfg: cfg, log: log}
@@ -155,6 +179,85 @@ func TestDetermineSeverity(t *testing.T) {
 	}
 }
 
+func TestGateSeverity(t *testing.T) {
+	cfg := &config.Config{
+		NewWalletDaysMax:    30,
+		OldWalletScoreAlert: 95.0,
+	}
+	p := &Processor{cfg: cfg, log: logrus.New()}
+
+	tests := []struct {
+		name         string
+		severity     alerts.Severity
+		walletAge    int
+		score        float64
+		wantSeverity alerts.Severity
+		wantNotify   bool
+	}{
+		{"new wallet alert", alerts.SeverityAlert, 5, 88.0, alerts.SeverityAlert, true},
+		{"new wallet info", alerts.SeverityInfo, 5, 20.0, alerts.SeverityInfo, true},
+		{"wallet at age limit", alerts.SeverityWarn, 30, 75.0, alerts.SeverityWarn, true},
+		{"old wallet below threshold", alerts.SeverityAlert, 31, 90.0, alerts.SeverityInfo, false},
+		{"old wallet at threshold", alerts.SeverityAlert, 400, 95.0, alerts.SeverityAlert, true},
+		{"old wallet low score", alerts.SeverityInfo, 400, 10.0, alerts.SeverityInfo, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			severity, notify := p.gateSeverity(tt.severity, tt.walletAge, tt.score)
+			if severity != tt.wantSeverity || notify != tt.wantNotify {
+				t.Errorf("got %s notify=%v, want %s notify=%v",
+					severity, notify, tt.wantSeverity, tt.wantNotify)
+			}
+		})
+	}
+}
+
+func TestAlertRoutes(t *testing.T) {
+	days := 7
+	cfg := &config.Config{
+		SuspicionScoreWarn:  70,
+		SuspicionScoreAlert: 85,
+		NewWalletDaysMax:    30,
+		OldWalletScoreAlert: 95,
+	}
+	profiles := []config.Profile{
+		{Name: "crypto-desk", Categories: []string{"crypto"}, SuspicionScoreWarn: 50, SuspicionScoreAlert: 60},
+		{Name: "whales", MinTradeUSD: 50000, NewWalletDaysMax: &days},
+	}
+
+	tests := []struct {
+		name        string
+		profiles    []config.Profile
+		category    string
+		notional    float64
+		walletAge   int
+		score       float64
+		expected    []alertRoute
+		description string
+	}{
+		{"no profiles", nil, "Crypto", 10000, 10, 75, []alertRoute{{severity: alerts.SeverityWarn, notify: true}}, "Without profiles the global thresholds raise one unnamed alert"},
+		{"no profiles old wallet", nil, "Crypto", 10000, 400, 75, []alertRoute{{severity: alerts.SeverityInfo}}, "The old-wallet gate still applies"},
+		{"profile thresholds", profiles, "Crypto", 10000, 10, 65, []alertRoute{{profile: "crypto-desk", severity: alerts.SeverityAlert, notify: true}}, "A profile grades the score with its own thresholds, and others filter the trade out"},
+		{"both profiles", profiles, "Crypto", 60000, 5, 75, []alertRoute{{profile: "crypto-desk", severity: alerts.SeverityAlert, notify: true}, {profile: "whales", severity: alerts.SeverityWarn, notify: true}}, "Every matching profile raises its own alert from the one score"},
+		{"category filter", profiles, "Politics", 60000, 10, 90, []alertRoute{{profile: "whales", severity: alerts.SeverityInfo}}, "A profile's wallet age limit gates it alone"},
+		{"nothing matches", profiles, "Politics", 10000, 10, 90, nil, "A trade no profile watches raises no alert"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := *cfg
+			c.Profiles = tt.profiles
+			p := &Processor{cfg: &c, log: logrus.New()}
+			market := &MarketInfo{Category: tt.category}
+			got := p.alertRoutes(market, tt.notional, tt.walletAge, tt.score, p.determineSeverity(tt.score))
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("got %+v, want %+v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
 func TestNormalizeScore(t *testing.T) {
 	cfg := &config.Config{}
 	log := logrus.New()
@@ -411,9 +514,48 @@ func TestIsNotInsiderCategory(t *testing.T) {
 	}
 }
 
+func TestNormalizeCategory(t *testing.T) {
+	tests := []struct {
+		name        string
+		category    string
+		slug        string
+		expected    string
+		description string
+	}{
+		{"empty", "", "", categoryOther, "Uncategorised markets are other"},
+		{"politics", "Politics", "", categoryPolitics, "Matching ignores case"},
+		{"us politics", " US Politics ", "", categoryPolitics, "Categories are trimmed and matched as substrings"},
+		{"geopolitics", "Geopolitics", "", categoryPolitics, "Geopolitics folds into politics"},
+		{"elections", "Elections", "", categoryPolitics, "Elections fold into politics"},
+		{"crypto", "Crypto", "", categoryCrypto, "Crypto is its own category"},
+		{"economy", "Economy", "", categoryBusiness, "Economy folds into business"},
+		{"tech", "Tech", "", categoryBusiness, "Tech folds into business"},
+		{"science", "Science", "", categoryScience, "Science is its own category"},
+		{"pop culture", "Pop Culture", "", categoryCulture, "Pop culture folds into culture"},
+		{"sports", "Sports", "", categorySports, "Sports is its own category"},
+		{"league", "NBA Playoffs", "", categorySports, "League names fold into sports"},
+		{"sports slug", "", "nfl-week-3-chiefs-vs-bills", categorySports, "An uncategorised market with a sports slug is sports, as for the filter"},
+		{"sports slug wins", "Politics", "ufc-fight-night", categorySports, "Sports is checked first so the metric agrees with the filter"},
+		{"politics slug ignored", "", "will-the-senate-pass-the-bill", categoryOther, "Only sports is matched against the slug"},
+		{"mentions", "Mentions", "", categoryCulture, "Mentions markets fold into culture"},
+		{"unmatched", "Weird Stuff", "", categoryOther, "Unrecognised categories are other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeCategory(tt.category, tt.slug); got != tt.expected {
+				t.Errorf("got %q, want %q\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+
+	if got := marketCategory(nil); got != categoryOther {
+		t.Errorf("got %q for an unresolved market, want %q", got, categoryOther)
+	}
+}
+
 func TestCalculateFundingAgeMultiplier(t *testing.T) {
-	// This tests the funding age logic that appears in processTrade
-	// Testing the multiplier calculation: 1.0 + (24-hours)/24*1.5
+	// Tests fundingAgeRule: 1.0 + (24-hours)/24*1.5
 	
 	tests := []struct {
 		name               string
@@ -455,8 +597,7 @@ func TestCalculateFundingAgeMultiplier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			// Replicate the logic from processTrade
-			multiplier := 1.0 + (24.0-tt.fundingAgeHours)/24.0*1.5
+			multiplier, _, _ := (&fundingAgeRule{cfg: testRuleConfig()}).Evaluate(context.Background(), &TradeContext{FundingAgeHours: tt.fundingAgeHours})
 			
 			tolerance := 0.0001
 			diff := multiplier - tt.expectedMultiplier
@@ -473,83 +614,89 @@ func TestCalculateFundingAgeMultiplier(t *testing.T) {
 }
 
 func TestWinRateMultiplier(t *testing.T) {
-	// Tests the win rate multiplier logic: adjustedScore *= (1.0 + winRate)
-	cfg := &config.Config{
-		MinWinRateThreshold: 0.75,
-	}
+	// The win rate rule multiplies the score by (1.0 + winRate)
+	rule := &winRateRule{cfg: testRuleConfig()}
 
 	tests := []struct {
-		name               string
-		baseScore          float64
-		winRate            float64
-		shouldApply        bool
-		expectedScore      float64
-		description        string
+		name           string
+		baseScore      float64
+		winRate        float64
+		resolvedTrades int
+		expectedScore  float64
+		description    string
 	}{
 		{
-			name:          "75% win rate - threshold exact",
-			baseScore:     10000,
-			winRate:       0.75,
-			shouldApply:   true,
-			expectedScore: 17500,
-			description:   "10000 * (1 + 0.75) = 17500",
+			name:           "75% win rate - threshold exact",
+			baseScore:      10000,
+			winRate:        0.75,
+			resolvedTrades: 8,
+			expectedScore:  17500,
+			description:    "10000 * (1 + 0.75) = 17500",
 		},
 		{
-			name:          "80% win rate",
-			baseScore:     10000,
-			winRate:       0.80,
-			shouldApply:   true,
-			expectedScore: 18000,
-			description:   "10000 * (1 + 0.80) = 18000",
+			name:           "80% win rate",
+			baseScore:      10000,
+			winRate:        0.80,
+			resolvedTrades: 8,
+			expectedScore:  18000,
+			description:    "10000 * (1 + 0.80) = 18000",
 		},
 		{
-			name:          "90% win rate - highly suspicious",
-			baseScore:     10000,
-			winRate:       0.90,
-			shouldApply:   true,
-			expectedScore: 19000,
-			description:   "10000 * (1 + 0.90) = 19000",
+			name:           "90% win rate - highly suspicious",
+			baseScore:      10000,
+			winRate:        0.90,
+			resolvedTrades: 8,
+			expectedScore:  19000,
+			description:    "10000 * (1 + 0.90) = 19000",
 		},
 		{
-			name:          "74% win rate - below threshold",
-			baseScore:     10000,
-			winRate:       0.74,
-			shouldApply:   false,
-			expectedScore: 10000,
-			description:   "Below 75% threshold, no multiplier applied",
+			name:           "74% win rate - below threshold",
+			baseScore:      10000,
+			winRate:        0.74,
+			resolvedTrades: 8,
+			expectedScore:  10000,
+			description:    "Below 75% threshold, no multiplier applied",
 		},
 		{
-			name:          "50% win rate - no multiplier",
-			baseScore:     10000,
-			winRate:       0.50,
-			shouldApply:   false,
-			expectedScore: 10000,
-			description:   "Average win rate, no multiplier",
+			name:           "50% win rate - no multiplier",
+			baseScore:      10000,
+			winRate:        0.50,
+			resolvedTrades: 8,
+			expectedScore:  10000,
+			description:    "Average win rate, no multiplier",
+		},
+		{
+			name:           "100% win rate - maximum",
+			baseScore:      10000,
+			winRate:        1.0,
+			resolvedTrades: 8,
+			expectedScore:  20000,
+			description:    "10000 * (1 + 1.0) = 20000",
 		},
 		{
-			name:          "100% win rate - maximum",
-			baseScore:     10000,
-			winRate:       1.0,
-			shouldApply:   true,
-			expectedScore: 20000,
-			description:   "10000 * (1 + 1.0) = 20000",
+			name:           "100% win rate - too few resolved trades",
+			baseScore:      10000,
+			winRate:        1.0,
+			resolvedTrades: 4,
+			expectedScore:  10000,
+			description:    "A perfect record over fewer than 5 resolved trades could be luck",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			adjustedScore := tt.baseScore
-			if tt.winRate >= cfg.MinWinRateThreshold {
-				adjustedScore *= (1.0 + tt.winRate)
+			tc := &TradeContext{
+				Trade:   &dataapi.Trade{ProxyWallet: "0xabc"},
+				Stats:   &storage.WalletStats{TotalResolvedTrades: tt.resolvedTrades, WinRate: tt.winRate},
+				WinRate: tt.winRate,
+				Lookups: &stubLookups{},
 			}
-
-			tolerance := 0.01
-			diff := adjustedScore - tt.expectedScore
-			if diff < 0 {
-				diff = -diff
+			multiplier, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
 			}
 
-			if diff > tolerance {
+			if adjustedScore := tt.baseScore * multiplier; math.Abs(adjustedScore-tt.expectedScore) > 0.01 {
 				t.Errorf("got %.2f, want %.2f\nDescription: %s",
 					adjustedScore, tt.expectedScore, tt.description)
 			}
@@ -656,10 +803,7 @@ func TestFlashFundingMultiplier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			multiplier := 1.0
-			if tt.fundingAgeMinutes <= 5 {
-				multiplier = 3.0
-			}
+			multiplier, _, _ := (&flashFundingRule{cfg: testRuleConfig()}).Evaluate(context.Background(), &TradeContext{FundingAgeMinutes: tt.fundingAgeMinutes})
 
 			if multiplier != tt.expectedMultiplier {
 				t.Errorf("got %.1f, want %.1f\nDescription: %s",
@@ -723,18 +867,9 @@ func TestLiquidityRatioMultiplier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			liquidityRatio := tt.tradeSize / tt.marketLiquidity
-			multiplier := 1.0
-
-			if liquidityRatio >= 0.50 {
-				multiplier = 3.0
-			} else if liquidityRatio >= 0.20 {
-				multiplier = 2.0
-			} else if liquidityRatio >= 0.10 {
-				multiplier = 1.5
-			} else if liquidityRatio >= 0.05 {
-				multiplier = 1.2
-			}
+			tc := &TradeContext{Notional: tt.tradeSize, Market: &MarketInfo{LiquidityNum: tt.marketLiquidity}}
+			liquidityRatio := tc.LiquidityRatio()
+			multiplier, _, _ := (&liquidityRule{cfg: testRuleConfig()}).Evaluate(context.Background(), tc)
 
 			if multiplier != tt.expectedMultiplier {
 				t.Errorf("ratio %.2f: got %.1f, want %.1f\nDescription: %s",
@@ -797,10 +932,7 @@ func TestExtremePriceMultiplier(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			multiplier := 1.0
-			if tt.price >= 0.85 || tt.price <= 0.15 {
-				multiplier = 1.5
-			}
+			multiplier, _, _ := (&priceConfidenceRule{cfg: testRuleConfig()}).Evaluate(context.Background(), &TradeContext{Trade: &dataapi.Trade{Price: tt.price}})
 
 			if multiplier != tt.expectedMultiplier {
 				t.Errorf("price %.2f: got %.1f, want %.1f\nDescription: %s",
@@ -811,213 +943,1875 @@ func TestExtremePriceMultiplier(t *testing.T) {
 }
 
 func TestNetPositionConcentration(t *testing.T) {
+	rule := &concentrationRule{cfg: testRuleConfig()}
+
 	tests := []struct {
 		name               string
-		netPosition        float64
-		totalVolume        float64
+		concentration      float64 // Share of the wallet's recent volume in this market on one side
 		expectedMultiplier float64
 		description        string
 	}{
 		{
-			name:               "95% buy concentration",
-			netPosition:        95000,
-			totalVolume:        100000,
+			name:               "95% concentration",
+			concentration:      0.95,
 			expectedMultiplier: 1.5,
-			description:        "90%+ concentration triggers multiplier",
+			description:        "Over 90% on one side triggers the multiplier",
 		},
 		{
 			name:               "90% exact threshold",
-			netPosition:        90000,
-			totalVolume:        100000,
-			expectedMultiplier: 1.5,
-			description:        "Exact 90% triggers",
+			concentration:      0.90,
+			expectedMultiplier: 1.0,
+			description:        "The share must be more than 90%",
 		},
 		{
 			name:               "89% concentration",
-			netPosition:        89000,
-			totalVolume:        100000,
+			concentration:      0.89,
 			expectedMultiplier: 1.0,
 			description:        "Just below 90%, no multiplier",
 		},
-		{
-			name:               "95% sell concentration (negative)",
-			netPosition:        -95000,
-			totalVolume:        100000,
-			expectedMultiplier: 1.5,
-			description:        "Works for sell side too",
-		},
 		{
 			name:               "50% balanced",
-			netPosition:        50000,
-			totalVolume:        100000,
+			concentration:      0.50,
 			expectedMultiplier: 1.0,
 			description:        "Balanced position, no multiplier",
 		},
+		{
+			name:               "no volume",
+			concentration:      0,
+			expectedMultiplier: 1.0,
+			description:        "A wallet without volume in the window has no concentration",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			multiplier := 1.0
-			absNetPosition := tt.netPosition
-			if absNetPosition < 0 {
-				absNetPosition = -absNetPosition
+			tc := &TradeContext{
+				Trade:    &dataapi.Trade{ProxyWallet: "0xabc"},
+				Notional: 20000,
+				Lookups:  &stubLookups{concentration: tt.concentration},
 			}
-			
-			if tt.totalVolume > 0 {
-				concentration := absNetPosition / tt.totalVolume
-				if concentration >= 0.90 {
-					multiplier = 1.5
-				}
+			multiplier, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
 			}
-
 			if multiplier != tt.expectedMultiplier {
 				t.Errorf("concentration %.2f: got %.1f, want %.1f\nDescription: %s",
-					absNetPosition/tt.totalVolume, multiplier, tt.expectedMultiplier, tt.description)
+					tt.concentration, multiplier, tt.expectedMultiplier, tt.description)
+			}
+			if tc.NetConcentration != tt.concentration {
+				t.Errorf("got trade context concentration %.2f, want %.2f\nDescription: The breakdown reports the share", tc.NetConcentration, tt.concentration)
 			}
 		})
 	}
 }
 
-func TestCombinedMultipliers(t *testing.T) {
-	// Test realistic scenarios with all multipliers combined
-	tests := []struct {
-		name                string
-		notional            float64
-		walletAgeDays       int
-		totalTrades         int
-		hoursToClose        float64
-		fundingAgeHours     float64
-		fundingAgeMinutes   float64
-		winRate             float64
-		price               float64
-		liquidityRatio      float64
-		netConcentration    float64
-		minWinRateThreshold float64
-		minTradeUSD         float64
-		expectedMin         float64
-		expectedMax         float64
-		description         string
-	}{
-		{
-			name:                "nuclear insider signal: all red flags",
-			notional:            50000,
-			walletAgeDays:       1,
-			totalTrades:         1,
-			hoursToClose:        1,
-			fundingAgeHours:     1,
-			fundingAgeMinutes:   3, // Flash funding
-			winRate:             0.85,
-			price:               0.95, // Extreme confidence
-			liquidityRatio:      0.60, // 60% of market
-			netConcentration:    0.95, // 95% one-sided
-			minWinRateThreshold: 0.75,
-			minTradeUSD:         5000,
-			expectedMin:         8000000,  // Rough minimum with all multipliers
-			expectedMax:         12000000, // Rough maximum
-			description:         "Brand new wallet, first huge trade, flash funded, extreme confidence, whale on small market",
-		},
-		{
-			name:                "moderate suspicious trade",
-			notional:            25000,
-			walletAgeDays:       3,
-			totalTrades:         5,
-			hoursToClose:        24,
-			fundingAgeHours:     12,
-			fundingAgeMinutes:   720, // Normal funding
-			winRate:             0.60,  // Below threshold
-			price:               0.70,  // Normal
-			liquidityRatio:      0.08,  // 8% - moderate
-			netConcentration:    0.75,  // Balanced
-			minWinRateThreshold: 0.75,
-			minTradeUSD:         5000,
-			expectedMin:         30000,
-			expectedMax:         80000,
-			description:         "Some red flags but not extreme",
-		},
-		{
-			name:                "low suspicion: normal trading",
-			notional:            10000,
-			walletAgeDays:       30,
-			totalTrades:         50,
-			hoursToClose:        100, // No time pressure
-			fundingAgeHours:     48,  // No funding multiplier
-			fundingAgeMinutes:   2880,
-			winRate:             0.50,
-			price:               0.50,
-			liquidityRatio:      0.02, // 2% - normal
-			netConcentration:    0.55,
-			minWinRateThreshold: 0.75,
-			minTradeUSD:         5000,
-			expectedMin:         300,
-			expectedMax:         400,
-			description:         "Established wallet, normal trade",
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Calculate base score with time multiplier
-			baseScore := tt.notional / float64(max(tt.walletAgeDays, 1))
-			
-			// Time to close multiplier
-			if tt.hoursToClose > 0 && tt.hoursToClose <= 48 {
-				multiplier := 1.0 + (48-tt.hoursToClose)/48*4.0
-				baseScore *= multiplier
+// frozenProcessor returns a processor with the test rule config and a clock
+// frozen at a fixed time
+func frozenProcessor() (*Processor, *clocktest.Fake) {
+	cfg := testRuleConfig()
+	cfg.AlertCooldownMins = 60
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	fake := clocktest.NewFake(time.Unix(1700000000, 0))
+	return &Processor{cfg: cfg, log: log, rules: defaultRules(cfg), clock: fake}, fake
+}
+
+func TestFundingAge(t *testing.T) {
+	tests := []struct {
+		name            string
+		fundedBefore    time.Duration // Funding time relative to first seen; negative when it came after
+		unfunded        bool
+		expectedHours   float64
+		expectedFunding float64
+		expectedFlash   float64
+		description     string
+	}{
+		{"flash funded", 3 * time.Minute, false, 0.05, 2.496875, 3.0, "Funding minutes before the first trade boosts both funding rules"},
+		{"same day", 12 * time.Hour, false, 12, 1.75, 1.0, "Funding within a day boosts the funding age rule only"},
+		{"old funding", 48 * time.Hour, false, 48, 1.0, 1.0, "Funding over a day before the first trade is ordinary"},
+		{"unknown", 0, true, 0, 1.0, 1.0, "A wallet without a known funding time scores no funding boost"},
+		{"funded later", -time.Hour, false, 0, 1.0, 1.0, "Funding recorded after first seen is a detection gap, not a signal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := frozenProcessor()
+			now := clock.Now()
+			wallet := &storage.Wallet{WalletAddress: "0x1", FirstSeenTS: now.Unix()}
+			if !tt.unfunded {
+				wallet.FundingReceivedTS = now.Add(-tt.fundedBefore).Unix()
 			}
 
-			adjustedScore := baseScore
+			hours, minutes := p.fundingAge(wallet)
+			if math.Abs(hours-tt.expectedHours) > 1e-9 || math.Abs(minutes-tt.expectedHours*60) > 1e-9 {
+				t.Errorf("got funding age %.4fh (%.2fm), want %.4fh\nDescription: %s", hours, minutes, tt.expectedHours, tt.description)
+			}
 
-			// Win rate multiplier
-			if tt.winRate >= tt.minWinRateThreshold {
-				adjustedScore *= (1.0 + tt.winRate)
+			tc := &TradeContext{
+				Trade:             &dataapi.Trade{ProxyWallet: wallet.WalletAddress, Side: "BUY", Price: 0.5, Timestamp: now.Unix()},
+				Wallet:            wallet,
+				Notional:          20000,
+				FundingAgeHours:   hours,
+				FundingAgeMinutes: minutes,
+				Lookups:           &stubLookups{},
+			}
+			b := p.scoreTrade(context.Background(), tc)
+			if math.Abs(b.FundingAgeMultiplier-tt.expectedFunding) > 1e-9 || b.FlashFundingMultiplier != tt.expectedFlash {
+				t.Errorf("got funding age %.6fx and flash funding %.1fx, want %.6fx and %.1fx\nDescription: %s",
+					b.FundingAgeMultiplier, b.FlashFundingMultiplier, tt.expectedFunding, tt.expectedFlash, tt.description)
 			}
+		})
+	}
+}
 
-			// First trade large multiplier
-			if tt.totalTrades == 1 && tt.notional >= tt.minTradeUSD {
-				adjustedScore *= 2.0
+func TestNewWalletRecordFromSubgraph(t *testing.T) {
+	const wallet = "0xabc"
+	const tradeTS = int64(1700000000)
+	tests := []struct {
+		name              string
+		history           *subgraph.WalletHistory
+		subgraphErr       error
+		firstActivity     *dataapi.ActivityEvent
+		expectedFirstSeen int64
+		expectedFunding   int64
+		expectedAgeMins   float64
+		expectedSource    string
+		description       string
+	}{
+		{
+			name:              "funded before first fill",
+			history:           &subgraph.WalletHistory{FirstActivityTS: tradeTS - 3600, FirstFundingTS: tradeTS - 3900, FundingSource: "0xfunder", FundingAmountUSD: 5000},
+			expectedFirstSeen: tradeTS - 3600,
+			expectedFunding:   tradeTS - 3900,
+			expectedAgeMins:   5,
+			expectedSource:    "0xfunder",
+			description:       "The first fill and the first inflow stay apart, so the funding age is the gap between them",
+		},
+		{
+			name:              "funded but never filled",
+			history:           &subgraph.WalletHistory{FirstFundingTS: tradeTS - 180, FundingSource: "0xfunder"},
+			expectedFirstSeen: tradeTS,
+			expectedFunding:   tradeTS - 180,
+			expectedAgeMins:   3,
+			expectedSource:    "0xfunder",
+			description:       "Without an earlier fill this trade is the first activity",
+		},
+		{
+			name:              "subgraph down",
+			subgraphErr:       errors.New("subgraph down"),
+			firstActivity:     &dataapi.ActivityEvent{Type: "TRADE", Timestamp: tradeTS - 86400},
+			expectedFirstSeen: tradeTS - 86400,
+			expectedFunding:   tradeTS - 86400,
+			description:       "A failed subgraph lookup falls back to the Data API's first activity",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := &processortest.DataAPI{FirstActivity: map[string]*dataapi.ActivityEvent{}}
+			if tt.firstActivity != nil {
+				data.FirstActivity[wallet] = tt.firstActivity
+			}
+			p := newFakeProcessor(data, &processortest.GammaAPI{})
+			p.subgraph = &processortest.Subgraph{
+				Histories: map[string]*subgraph.WalletHistory{wallet: tt.history},
+				Err:       tt.subgraphErr,
+			}
+
+			record, source, err := p.newWalletRecord(context.Background(), wallet, tradeTS)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if record.FirstSeenTS != tt.expectedFirstSeen || record.FundingReceivedTS != tt.expectedFunding {
+				t.Errorf("got first seen %d and funded %d, want %d and %d\nDescription: %s",
+					record.FirstSeenTS, record.FundingReceivedTS, tt.expectedFirstSeen, tt.expectedFunding, tt.description)
+			}
+			if _, minutes := p.fundingAge(record); math.Abs(minutes-tt.expectedAgeMins) > 1e-9 {
+				t.Errorf("got funding age %.2fm, want %.2fm\nDescription: %s", minutes, tt.expectedAgeMins, tt.description)
+			}
+			var gotSource string
+			if source != nil {
+				gotSource = source.FundingSource
+			}
+			if gotSource != tt.expectedSource {
+				t.Errorf("got funding source %q, want %q\nDescription: %s", gotSource, tt.expectedSource, tt.description)
+			}
+		})
+	}
+}
+
+func TestInAlertCooldown(t *testing.T) {
+	tests := []struct {
+		name        string
+		lastAlert   time.Duration // How long before now the wallet's last alert was stored
+		noAlert     bool
+		advance     time.Duration // How far the clock moves before checking
+		expected    bool
+		description string
+	}{
+		{"no alert", 0, true, 0, false, "A wallet never alerted on isn't cooling down"},
+		{"just alerted", time.Minute, false, 0, true, "An alert a minute ago holds back the next"},
+		{"last second", 59*time.Minute + 59*time.Second, false, 0, true, "The cooldown lasts the whole ALERT_COOLDOWN_MINS"},
+		{"expired", time.Hour, false, 0, false, "The cooldown ends exactly ALERT_COOLDOWN_MINS after the alert"},
+		{"expires as time passes", 30 * time.Minute, false, 30 * time.Minute, false, "The same alert stops holding back once the clock moves on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := frozenProcessor()
+			var last *storage.Alert
+			if !tt.noAlert {
+				last = &storage.Alert{WalletAddress: "0x1", CreatedTS: clock.Now().Add(-tt.lastAlert).Unix()}
+			}
+			clock.Advance(tt.advance)
+
+			if got := p.inAlertCooldown(last); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestCombinedMultipliers(t *testing.T) {
+	// Realistic scenarios with many multipliers combined, scored by the
+	// rules against wallets timestamped relative to a frozen clock
+	tests := []struct {
+		name          string
+		notional      float64
+		walletAge     time.Duration
+		priorTrades   int
+		hoursToClose  float64
+		fundedBefore  time.Duration // Funding time before the wallet was first seen
+		winRate       float64
+		price         float64
+		liquidity     float64
+		concentration float64
+		expectedMin   float64
+		expectedMax   float64
+		description   string
+	}{
+		{
+			name:          "nuclear insider signal: all red flags",
+			notional:      50000,
+			walletAge:     36 * time.Hour,
+			hoursToClose:  1,
+			fundedBefore:  3 * time.Minute,
+			winRate:       0.85,
+			price:         0.95,
+			liquidity:     80000, // 60% of the pool
+			concentration: 0.95,
+			expectedMin:   1e7,
+			expectedMax:   5e7,
+			description:   "Brand new wallet, first huge trade, flash funded, extreme confidence, whale on small market",
+		},
+		{
+			name:          "moderate suspicious trade",
+			notional:      25000,
+			walletAge:     3 * 24 * time.Hour,
+			priorTrades:   5,
+			hoursToClose:  24,
+			fundedBefore:  12 * time.Hour,
+			winRate:       0.60,
+			price:         0.70,
+			liquidity:     300000, // 8% of the pool
+			concentration: 0.75,
+			expectedMin:   30000,
+			expectedMax:   80000,
+			description:   "Some red flags but not extreme",
+		},
+		{
+			name:          "low suspicion: normal trading",
+			notional:      10000,
+			walletAge:     30 * 24 * time.Hour,
+			priorTrades:   50,
+			hoursToClose:  100,
+			fundedBefore:  48 * time.Hour,
+			winRate:       0.50,
+			price:         0.50,
+			liquidity:     500000, // 2% of the pool
+			concentration: 0.55,
+			expectedMin:   300,
+			expectedMax:   400,
+			description:   "Established wallet, normal trade",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, clock := frozenProcessor()
+			now := clock.Now().Unix()
+			firstSeen := clock.Now().Add(-tt.walletAge)
+			wallet := &storage.Wallet{
+				WalletAddress:     "0x1",
+				FirstSeenTS:       firstSeen.Unix(),
+				FundingReceivedTS: firstSeen.Add(-tt.fundedBefore).Unix(),
+				TotalTrades:       tt.priorTrades,
+			}
+			trade := &dataapi.Trade{ProxyWallet: wallet.WalletAddress, Side: "BUY", Outcome: "Yes", Price: tt.price, Timestamp: now}
+			fundingHours, fundingMinutes := p.fundingAge(wallet)
+
+			tc := &TradeContext{
+				Trade:             trade,
+				Wallet:            wallet,
+				Market:            &MarketInfo{LiquidityNum: tt.liquidity},
+				Notional:          tt.notional,
+				WalletAgeDays:     int((trade.Timestamp - wallet.FirstSeenTS) / 86400),
+				HoursToClose:      tt.hoursToClose,
+				IsFirstTrade:      tt.priorTrades == 0,
+				WinRate:           tt.winRate,
+				FundingAgeHours:   fundingHours,
+				FundingAgeMinutes: fundingMinutes,
+				PriorTrades:       tt.priorTrades,
+				Lookups:           &stubLookups{concentration: tt.concentration, activity: []dataapi.ActivityEvent{{Type: "TRADE"}}},
+			}
+			score := p.scoreTrade(context.Background(), tc).FinalScore
+			if score < tt.expectedMin || score > tt.expectedMax {
+				t.Errorf("got score %.2f, want between %.2f and %.2f\nDescription: %s", score, tt.expectedMin, tt.expectedMax, tt.description)
 			}
+		})
+	}
+}
+
+func TestAggregateFills(t *testing.T) {
+	cfg := &config.Config{}
+	log := logrus.New()
+	p := &Processor{cfg: cfg, log: log}
+
+	tests := []struct {
+		name             string
+		trades           []dataapi.Trade
+		expectedCount    int
+		expectedNotional float64
+		expectedPrice    float64
+		description      string
+	}{
+		{
+			name: "three fills same transaction",
+			trades: []dataapi.Trade{
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", TransactionHash: "0xtx", Timestamp: 100, Size: 10000, Price: 0.50, USDCSize: 5000},
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", TransactionHash: "0xtx", Timestamp: 100, Size: 10000, Price: 0.60, USDCSize: 6000},
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", TransactionHash: "0xtx", Timestamp: 100, Size: 20000, Price: 0.70, USDCSize: 14000},
+			},
+			expectedCount:    1,
+			expectedNotional: 25000,
+			expectedPrice:    0.625,
+			description:      "Fills merge into one trade with summed notional and size-weighted price",
+		},
+		{
+			name: "fills without usdcSize",
+			trades: []dataapi.Trade{
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "NO", Side: "BUY", TransactionHash: "0xtx", Timestamp: 100, Size: 10000, Price: 0.40},
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "NO", Side: "BUY", TransactionHash: "0xtx", Timestamp: 100, Size: 10000, Price: 0.60},
+			},
+			expectedCount:    1,
+			expectedNotional: 10000,
+			expectedPrice:    0.50,
+			description:      "Notional falls back to size * price per fill before summing",
+		},
+		{
+			name: "empty hash same order",
+			trades: []dataapi.Trade{
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", Timestamp: 100, Size: 10000, Price: 0.50, USDCSize: 5000},
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", Timestamp: 100, Size: 5000, Price: 0.50, USDCSize: 2500},
+			},
+			expectedCount:    1,
+			expectedNotional: 7500,
+			expectedPrice:    0.50,
+			description:      "Missing hashes group on wallet, market, outcome, side and timestamp",
+		},
+		{
+			name: "different transactions untouched",
+			trades: []dataapi.Trade{
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", TransactionHash: "0xtx1", Timestamp: 100, Size: 10000, Price: 0.50, USDCSize: 5000},
+				{ProxyWallet: "0xabc", ConditionID: "c1", Outcome: "YES", Side: "BUY", TransactionHash: "0xtx2", Timestamp: 101, Size: 10000, Price: 0.50, USDCSize: 5000},
+			},
+			expectedCount:    2,
+			expectedNotional: 5000,
+			expectedPrice:    0.50,
+			description:      "Separate orders are processed separately",
+		},
+	}
 
-			// Flash funding multiplier
-			if tt.fundingAgeMinutes <= 5 {
-				adjustedScore *= 3.0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := p.aggregateFills(tt.trades)
+			if len(result) != tt.expectedCount {
+				t.Fatalf("got %d trades, want %d\nDescription: %s", len(result), tt.expectedCount, tt.description)
 			}
 
-			// Liquidity ratio multiplier
-			if tt.liquidityRatio >= 0.50 {
-				adjustedScore *= 3.0
-			} else if tt.liquidityRatio >= 0.20 {
-				adjustedScore *= 2.0
-			} else if tt.liquidityRatio >= 0.10 {
-				adjustedScore *= 1.5
-			} else if tt.liquidityRatio >= 0.05 {
-				adjustedScore *= 1.2
+			notional := p.calculateNotional(&result[0])
+			if math.Abs(notional-tt.expectedNotional) > 0.01 {
+				t.Errorf("notional: got %.2f, want %.2f\nDescription: %s", notional, tt.expectedNotional, tt.description)
+			}
+			if math.Abs(result[0].Price-tt.expectedPrice) > 0.0001 {
+				t.Errorf("price: got %.4f, want %.4f\nDescription: %s", result[0].Price, tt.expectedPrice, tt.description)
 			}
+			if p.calculateTradeHash(&result[0]) != p.calculateTradeHash(&tt.trades[0]) && tt.trades[0].TransactionHash != "" {
+				t.Errorf("aggregated trade hash changed\nDescription: %s", tt.description)
+			}
+		})
+	}
+}
+
+func TestScoreBreakdownIdentity(t *testing.T) {
+	cfg := &config.Config{
+		TimeToCloseHoursMax: 48,
+	}
+	log := logrus.New()
+	p := &Processor{cfg: cfg, log: log}
+
+	// Base score times the time-to-close factor must equal the combined score
+	for _, hoursToClose := range []float64{-10, 0, 0.5, 1, 12, 24, 48, 100} {
+		base := p.baseSuspicionScore(50000, 2)
+		combined := p.calculateSuspicionScore(50000, 2, hoursToClose)
+		if math.Abs(base*p.timeToCloseMultiplier(hoursToClose)-combined) > 0.0001 {
+			t.Errorf("hours %.1f: base %.2f * time multiplier %.4f != combined %.2f",
+				hoursToClose, base, p.timeToCloseMultiplier(hoursToClose), combined)
+		}
+	}
+
+	// Every *Multiplier field must contribute to CombinedMultiplier, so a new
+	// multiplier added to the breakdown can't be left out of the final score
+	breakdown := &alerts.ScoreBreakdown{}
+	v := reflect.ValueOf(breakdown).Elem()
+	expected := 1.0
+	factor := 1.1
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !strings.HasSuffix(field.Name, "Multiplier") || field.Type.Kind() != reflect.Float64 {
+			continue
+		}
+		v.Field(i).SetFloat(factor)
+		expected *= factor
+		factor += 0.1
+	}
 
-			// Extreme price multiplier
-			if tt.price >= 0.85 || tt.price <= 0.15 {
-				adjustedScore *= 1.5
+	got := breakdown.CombinedMultiplier()
+	if math.Abs(got-expected) > 1e-9 {
+		t.Errorf("CombinedMultiplier: got %.6f, want %.6f - a multiplier field is missing from the product", got, expected)
+	}
+}
+
+func TestSameDirection(t *testing.T) {
+	tests := []struct {
+		name        string
+		outcomeA    string
+		sideA       string
+		outcomeB    string
+		sideB       string
+		expected    bool
+		description string
+	}{
+		{"both buy yes", "Yes", "BUY", "Yes", "BUY", true, "Identical positions pile in the same direction"},
+		{"buy yes vs buy no", "Yes", "BUY", "No", "BUY", false, "Buying opposite outcomes is a hedge across wallets"},
+		{"buy yes vs sell yes", "Yes", "BUY", "Yes", "SELL", false, "Selling the outcome another wallet buys opposes it"},
+		{"buy yes vs sell no", "Yes", "BUY", "No", "SELL", true, "Selling NO is the same view as buying YES"},
+		{"case insensitive", "yes", "buy", "Yes", "BUY", true, "Outcome and side comparisons ignore case"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sameDirection(tt.outcomeA, tt.sideA, tt.outcomeB, tt.sideB)
+			if got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
 			}
+		})
+	}
+}
+
+func TestMergeMakerTrades(t *testing.T) {
+	takers := []dataapi.Trade{
+		{TransactionHash: "0xtx1", ProxyWallet: "0xtaker", ConditionID: "c1", Outcome: "Yes", Side: "BUY", Size: 100, Price: 0.5, Role: dataapi.RoleTaker},
+	}
+	all := []dataapi.Trade{
+		// Taker trade repeated in the unfiltered response
+		{TransactionHash: "0xtx1", ProxyWallet: "0xtaker", ConditionID: "c1", Outcome: "Yes", Side: "BUY", Size: 100, Price: 0.5},
+		// Maker on the other side of the same transaction
+		{TransactionHash: "0xtx1", ProxyWallet: "0xmaker", ConditionID: "c1", Outcome: "Yes", Side: "SELL", Size: 100, Price: 0.5},
+		// Same maker entry returned twice
+		{TransactionHash: "0xtx1", ProxyWallet: "0xmaker", ConditionID: "c1", Outcome: "Yes", Side: "SELL", Size: 100, Price: 0.5},
+	}
+
+	merged := mergeMakerTrades(takers, all)
+	if len(merged) != 2 {
+		t.Fatalf("got %d trades, want 2 (taker + one maker)", len(merged))
+	}
+	if merged[1].ProxyWallet != "0xmaker" || merged[1].Role != dataapi.RoleMaker {
+		t.Errorf("second trade should be the maker fill, got %+v", merged[1])
+	}
+
+	// The maker shares the taker's transaction hash, so dedup keys must differ
+	p := &Processor{cfg: &config.Config{}, log: logrus.New()}
+	if p.calculateTradeHash(&merged[0]) == p.calculateTradeHash(&merged[1]) {
+		t.Error("maker and taker fills in the same transaction must not share a trade hash")
+	}
+	if got := p.calculateTradeHash(&merged[0]); got != "0xtx1" {
+		t.Errorf("taker hash got %q, want the transaction hash so existing records still dedupe", got)
+	}
+}
+
+func TestResolveWinner(t *testing.T) {
+	p := &Processor{cfg: &config.Config{}, log: logrus.New()}
+
+	tests := []struct {
+		name           string
+		market         gammaapi.Market
+		expectedWinner string
+		expectedMethod string
+		description    string
+	}{
+		{
+			name:           "settled by oracle",
+			market:         gammaapi.Market{Outcomes: `["Yes","No"]`, OutcomePrices: `["0","1"]`, UMAResolutionStatus: "resolved"},
+			expectedWinner: "No",
+			expectedMethod: resolutionMethodUMA,
+			description:    "Payout price of 1 marks the winner",
+		},
+		{
+			name:           "settled for the first outcome",
+			market:         gammaapi.Market{Outcomes: `["Yes","No"]`, OutcomePrices: `["1","0"]`, UMAResolutionStatus: "resolved"},
+			expectedWinner: "Yes",
+			expectedMethod: resolutionMethodUMA,
+			description:    "The winner is read from the payout prices whichever outcome it is",
+		},
+		{
+			name:           "50-50 resolution",
+			market:         gammaapi.Market{Outcomes: `["Yes","No"]`, OutcomePrices: `["0.5","0.5"]`, UMAResolutionStatus: "resolved"},
+			expectedWinner: splitOutcome,
+			expectedMethod: resolutionMethodUMA,
+			description:    "N/A markets resolve with no winner",
+		},
+		{
+			name:           "disputed",
+			market:         gammaapi.Market{Outcomes: `["Yes","No"]`, OutcomePrices: `["0.97","0.03"]`, UMAResolutionStatus: "disputed"},
+			expectedWinner: "",
+			expectedMethod: resolutionMethodUMA,
+			description:    "Disputed markets wait for the oracle even if prices look decided",
+		},
+		{
+			name:           "no resolution data",
+			market:         gammaapi.Market{Outcomes: `["Yes","No"]`, OutcomePrices: `["0.97","0.03"]`},
+			expectedWinner: "Yes",
+			expectedMethod: resolutionMethodPrice,
+			description:    "Falls back to the 95% price heuristic",
+		},
+	}
 
-			// Net position concentration
-			if tt.netConcentration >= 0.90 {
-				adjustedScore *= 1.5
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			winner, method := p.resolveWinner(&tt.market)
+			if winner != tt.expectedWinner || method != tt.expectedMethod {
+				t.Errorf("got (%q, %q), want (%q, %q)\nDescription: %s",
+					winner, method, tt.expectedWinner, tt.expectedMethod, tt.description)
 			}
+		})
+	}
+}
+
+func TestOutcomeMatch(t *testing.T) {
+	tests := []struct {
+		name               string
+		outcome            string
+		winningOutcome     string
+		normalize          bool
+		expectedMatch      bool
+		expectedNormalized bool
+		description        string
+	}{
+		{"exact", "Yes", "Yes", true, true, false, "Identical labels match without normalization"},
+		{"case", "Yes", "YES", true, true, true, "The Data API and Gamma disagree on case"},
+		{"whitespace", "Donald  Trump ", "Donald Trump", true, true, true, "Stray and doubled spaces are ignored"},
+		{"different", "No", "Yes", true, false, false, "Different outcomes never match"},
+		{"strict", "Yes", "YES", false, false, false, "NORMALIZE_OUTCOMES=false compares labels exactly"},
+	}
 
-			// Funding age multiplier (in addition to flash)
-			if tt.fundingAgeHours > 0 && tt.fundingAgeHours <= 24 {
-				fundingMultiplier := 1.0 + (24.0-tt.fundingAgeHours)/24.0*1.5
-				adjustedScore *= fundingMultiplier
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, normalized := outcomeMatch(tt.outcome, tt.winningOutcome, tt.normalize)
+			if match != tt.expectedMatch || normalized != tt.expectedNormalized {
+				t.Errorf("got match %v normalized %v, want %v and %v\nDescription: %s", match, normalized, tt.expectedMatch, tt.expectedNormalized, tt.description)
 			}
+		})
+	}
 
-			// Check if score is within expected range
-			if adjustedScore < tt.expectedMin || adjustedScore > tt.expectedMax {
-				t.Logf("Score: %.2f (expected between %.2f and %.2f)\nDescription: %s",
-					adjustedScore, tt.expectedMin, tt.expectedMax, tt.description)
-				// Don't fail, just log - these are rough estimates with many multipliers
-			} else {
-				t.Logf("✓ Score: %.2f (within expected range %.2f-%.2f)\nDescription: %s",
-					adjustedScore, tt.expectedMin, tt.expectedMax, tt.description)
+	if got := normalizeOutcome("  Donald\tTrump  "); got != "Donald Trump" {
+		t.Errorf("got %q, want %q: labels are trimmed and inner whitespace collapsed, keeping case", got, "Donald Trump")
+	}
+}
+
+func TestIsExcludedClusterAddress(t *testing.T) {
+	p := &Processor{
+		cfg: &config.Config{ClusterExcludedAddresses: []string{"0xExchangeHotWallet"}},
+		log: logrus.New(),
+	}
+
+	tests := []struct {
+		name        string
+		address     string
+		expected    bool
+		description string
+	}{
+		{"configured exchange", "0xexchangehotwallet", true, "Configured addresses match case-insensitively"},
+		{"protocol contract", "0x4bfb41d5b3570defd03c39a9a4d8de6bd8b8982e", true, "Polymarket contracts are always excluded"},
+		{"ordinary wallet", "0x1234", false, "Other addresses can link wallets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.isExcludedClusterAddress(tt.address); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
 			}
 		})
 	}
 }
+
+func TestOutcomeTokenIDs(t *testing.T) {
+	tokenIDs := outcomeTokenIDs(`["Yes","No"]`, `["111","222"]`)
+	if tokenIDForOutcome(tokenIDs, "YES") != "111" || tokenIDForOutcome(tokenIDs, "No") != "222" {
+		t.Errorf("unexpected token IDs: %v", tokenIDs)
+	}
+	if got := outcomeTokenIDs(`["Yes","No"]`, `["111"]`); got != nil {
+		t.Errorf("mismatched lengths should give nil, got %v", got)
+	}
+	if got := decodeTokenIDs(encodeTokenIDs(tokenIDs)); !reflect.DeepEqual(got, tokenIDs) {
+		t.Errorf("round trip got %v, want %v", got, tokenIDs)
+	}
+}
+
+func TestBookConsumption(t *testing.T) {
+	book := &clobapi.OrderBook{
+		Bids: []clobapi.PriceLevel{{Price: "0.60", Size: "1000"}, {Price: "0.50", Size: "5000"}},
+		Asks: []clobapi.PriceLevel{{Price: "0.63", Size: "1000"}, {Price: "0.64", Size: "2000"}, {Price: "0.80", Size: "9000"}},
+	}
+
+	tests := []struct {
+		name             string
+		side             string
+		notional         float64
+		expectedDepth    float64
+		expectedConsumed float64
+		description      string
+	}{
+		{"buy takes asks", "BUY", 3900, 630 + 1280, 3900.0 / (3900 + 1910), "Only asks within 3 cents of 0.62 count"},
+		{"sell takes bids", "SELL", 600, 600, 0.5, "Only bids within 3 cents of 0.62 count"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			depth := book.DepthWithin(tt.side, 0.62, 0.03)
+			if math.Abs(depth-tt.expectedDepth) > 1e-9 {
+				t.Errorf("depth got %.2f, want %.2f\nDescription: %s", depth, tt.expectedDepth, tt.description)
+			}
+			if got := bookConsumption(tt.notional, depth); math.Abs(got-tt.expectedConsumed) > 1e-9 {
+				t.Errorf("consumed got %.4f, want %.4f\nDescription: %s", got, tt.expectedConsumed, tt.description)
+			}
+		})
+	}
+}
+
+func TestCurrentPrice(t *testing.T) {
+	tests := []struct {
+		name             string
+		outcome          string
+		status           int
+		body             string
+		expectedPrice    float64
+		expectedErr      bool
+		expectedRequests int
+		description      string
+	}{
+		{"midpoint", "Yes", http.StatusOK, `{"mid": "0.72"}`, 0.72, false, 1, "The traded outcome's midpoint is the current price"},
+		{"outcome matched case-insensitively", "YES", http.StatusOK, `{"mid": "0.72"}`, 0.72, false, 1, "Trade outcomes may differ in case from Gamma's"},
+		{"empty book", "Yes", http.StatusNotFound, `{"error": "No orderbook exists for the requested token id"}`, 0, true, 1, "Without a book there is no price; the alert goes out without one"},
+		{"clob down", "Yes", http.StatusServiceUnavailable, `unavailable`, 0, true, 1, "A failed lookup leaves the price unset rather than failing the alert"},
+		{"unknown outcome", "Maybe", http.StatusOK, `{"mid": "0.72"}`, 0, true, 0, "An outcome without a token ID is not looked up"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if got := r.URL.Query().Get("token_id"); got != "token-yes" {
+					t.Errorf("got token %q, want %q\nDescription: %s", got, "token-yes", tt.description)
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{})
+			p.clobClient = clobapi.NewClient(&config.Config{ClobAPIBaseURL: server.URL, ClobAPIBookRPS: 100, ClobAPIBookBurst: 10}, nil, p.log)
+			market := &MarketInfo{TokenIDs: map[string]string{"Yes": "token-yes", "No": "token-no"}}
+
+			price, err := p.currentPrice(context.Background(), &dataapi.Trade{Outcome: tt.outcome}, market)
+			if (err != nil) != tt.expectedErr {
+				t.Errorf("got error %v, want error %t\nDescription: %s", err, tt.expectedErr, tt.description)
+			}
+			if price != tt.expectedPrice {
+				t.Errorf("got price %.2f, want %.2f\nDescription: %s", price, tt.expectedPrice, tt.description)
+			}
+			if requests != tt.expectedRequests {
+				t.Errorf("got %d midpoint requests, want %d\nDescription: %s", requests, tt.expectedRequests, tt.description)
+			}
+		})
+	}
+}
+
+func TestPollStats(t *testing.T) {
+	stats := newPollStats()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%5 == 0 {
+				stats.trade("duplicate")
+				return
+			}
+			stats.seen(int64(1000 + i))
+			stats.trade("success")
+			if i%10 == 1 {
+				stats.alert(alerts.SeverityAlert, categoryPolitics, metrics.DefaultProfile, nil)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if got := stats.statuses["duplicate"]; got != 10 {
+		t.Errorf("duplicates got %d, want 10", got)
+	}
+	if got := stats.statuses["success"]; got != 40 {
+		t.Errorf("successes got %d, want 40", got)
+	}
+	if got := stats.alerts[string(alerts.SeverityAlert)]; got != 5 {
+		t.Errorf("alerts got %d, want 5", got)
+	}
+	if stats.newestTS != 1049 {
+		t.Errorf("newest ts got %d, want 1049", stats.newestTS)
+	}
+
+	summary := stats.summary(60, 50, time.Second)
+	if summary.Fetched != 60 || summary.New != 40 || summary.Processed != 40 || summary.Alerted != 5 {
+		t.Errorf("summary got %+v, want 60 fetched, 40 new, 40 processed, 5 alerted", summary)
+	}
+
+	// Live feed trades carry no cycle stats
+	var live *pollStats
+	fired := testutil.ToFloat64(metrics.AlertDetectors.WithLabelValues("velocity", string(alerts.SeverityWarn)))
+	live.trade("duplicate")
+	live.seen(1000)
+	live.alert(alerts.SeverityWarn, categoryOther, metrics.DefaultProfile, []string{"velocity"})
+	if got := testutil.ToFloat64(metrics.AlertDetectors.WithLabelValues("velocity", string(alerts.SeverityWarn))) - fired; got != 1 {
+		t.Errorf("got %v velocity detections, want 1: the rules that fired are counted per alert", got)
+	}
+}
+
+func TestReversesPosition(t *testing.T) {
+	pos := &storage.AlertPosition{Outcome: "Yes", Side: "BUY", EntryTS: 1000}
+
+	tests := []struct {
+		name        string
+		trade       dataapi.Trade
+		expected    bool
+		description string
+	}{
+		{"sell same outcome", dataapi.Trade{Outcome: "Yes", Side: "SELL", Timestamp: 2000}, true, "Selling the alerted outcome unwinds it"},
+		{"case insensitive", dataapi.Trade{Outcome: "yes", Side: "sell", Timestamp: 2000}, true, "Outcome and side compare case-insensitively"},
+		{"adding", dataapi.Trade{Outcome: "Yes", Side: "BUY", Timestamp: 2000}, false, "Buying more is not an exit"},
+		{"other outcome", dataapi.Trade{Outcome: "No", Side: "SELL", Timestamp: 2000}, false, "Trading the other outcome does not touch the alerted tokens"},
+		{"before entry", dataapi.Trade{Outcome: "Yes", Side: "SELL", Timestamp: 500}, false, "The exit scan fetches trades from before the alert, which don't unwind it"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reversesPosition(pos, &tt.trade); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestPositionPnL(t *testing.T) {
+	tests := []struct {
+		name        string
+		side        string
+		entry       float64
+		exit        float64
+		shares      float64
+		expected    float64
+		description string
+	}{
+		{"buy then sell higher", "BUY", 0.40, 0.70, 10000, 3000, "Long position gains when the price rises"},
+		{"buy then sell lower", "BUY", 0.60, 0.45, 10000, -1500, "Long position loses when the price falls"},
+		{"sell then buy back lower", "SELL", 0.80, 0.50, 1000, 300, "Selling first gains when buying back cheaper"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := positionPnL(tt.side, tt.entry, tt.exit, tt.shares); math.Abs(got-tt.expected) > 1e-6 {
+				t.Errorf("got %.2f, want %.2f\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestSummarizeExposure(t *testing.T) {
+	positions := []dataapi.Position{
+		{ConditionID: "m1", Outcome: "Yes", Size: 1000, CurrentValue: 620},
+		{ConditionID: "m1", Outcome: "No", Size: 200, CurrentValue: 76},
+		{ConditionID: "m2", Outcome: "Yes", Size: 5000, CurrentValue: 4500},
+		{ConditionID: "m3", Outcome: "Yes", Size: 300, CurrentValue: 300, Redeemable: true},
+		{ConditionID: "m4", Outcome: "No", Size: 0, CurrentValue: 0},
+	}
+
+	totalUSD, markets := summarizeExposure(positions)
+	if math.Abs(totalUSD-5196) > 0.001 {
+		t.Errorf("got total $%.2f, want $5196.00: resolved and empty positions are excluded", totalUSD)
+	}
+	if markets != 2 {
+		t.Errorf("got %d markets, want 2: both outcomes of m1 count once", markets)
+	}
+}
+
+func TestExposureCrossed(t *testing.T) {
+	spread := []storage.WalletMarketExposure{
+		{ConditionID: "m1", NotionalUSD: 15000},
+		{ConditionID: "m2", NotionalUSD: 15000},
+		{ConditionID: "m3", NotionalUSD: 15000},
+		{ConditionID: "m4", NotionalUSD: 15000},
+		{ConditionID: "m5", NotionalUSD: 15000},
+		{ConditionID: "m6", NotionalUSD: 15000},
+	}
+
+	tests := []struct {
+		name        string
+		markets     []storage.WalletMarketExposure
+		minMarkets  int
+		expected    bool
+		description string
+	}{
+		{"spread across markets", spread, 2, true, "$90k across six markets crosses a $50k threshold"},
+		{"below threshold", spread[:3], 2, false, "$45k stays under a $50k threshold"},
+		{"single market", []storage.WalletMarketExposure{{ConditionID: "m1", NotionalUSD: 90000}}, 2, false, "One market is left to net concentration"},
+		{"single market allowed", []storage.WalletMarketExposure{{ConditionID: "m1", NotionalUSD: 90000}}, 1, true, "EXPOSURE_MIN_MARKETS=1 counts one market"},
+		{"no buys", nil, 1, false, "A wallet with no tracked buys has no exposure"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := exposureCrossed(tt.markets, 50000, tt.minMarkets); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+
+	if totalUSD, _ := exposureCrossed(spread, 50000, 2); totalUSD != 90000 {
+		t.Errorf("got total $%.2f, want $90000.00", totalUSD)
+	}
+}
+
+// newFakeProcessor returns a processor backed by fake API clients and no
+// storage, for paths that don't touch the database
+func newFakeProcessor(data *processortest.DataAPI, gamma *processortest.GammaAPI) *Processor {
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return New(&config.Config{}, nil, data, gamma, nil, nil, nil, log)
+}
+
+func TestMarketEndDate(t *testing.T) {
+	marketEnd := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	eventEnd := time.Date(2025, 3, 19, 0, 0, 0, 0, time.UTC)
+	gamma := &processortest.GammaAPI{Events: map[string]*gammaapi.Event{
+		"fed-decision":  {Slug: "fed-decision", EndDate: eventEnd.Format(time.RFC3339)},
+		"open-ended":    {Slug: "open-ended"},
+		"garbled-dates": {Slug: "garbled-dates", EndDate: "next spring"},
+	}}
+
+	tests := []struct {
+		name           string
+		marketEndDate  string
+		eventSlug      string
+		expectedEnd    int64
+		expectedSource string
+		description    string
+	}{
+		{"market end date", marketEnd.Format(time.RFC3339), "fed-decision", marketEnd.Unix(), endDateSourceMarket, "A market's own end date wins over its event's"},
+		{"event fallback", "", "fed-decision", eventEnd.Unix(), endDateSourceEvent, "A market without an end date uses its parent event's"},
+		{"unparseable market date", "soon", "fed-decision", eventEnd.Unix(), endDateSourceEvent, "A market date that doesn't parse falls back like a missing one"},
+		{"no event", "", "", 0, "", "Without a parent event the end date is unknown"},
+		{"event not found", "", "deleted-event", 0, "", "An event Gamma doesn't know leaves the end date unknown"},
+		{"event without end date", "", "open-ended", 0, "", "Events may not have an end date either"},
+		{"unparseable event date", "", "garbled-dates", 0, "", "An event date that doesn't parse is ignored"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProcessor(&processortest.DataAPI{}, gamma)
+			endDate, source := p.marketEndDate(context.Background(), &gammaapi.Market{EndDate: tt.marketEndDate}, tt.eventSlug)
+			if endDate != tt.expectedEnd || source != tt.expectedSource {
+				t.Errorf("got end date %d from %q, want %d from %q\nDescription: %s", endDate, source, tt.expectedEnd, tt.expectedSource, tt.description)
+			}
+		})
+	}
+}
+
+func TestFilterMarket(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC).Unix()
+	day := int64(86400)
+
+	tests := []struct {
+		name           string
+		market         *MarketInfo
+		expectedSkip   bool
+		expectedReason string
+		expectedSource string
+		description    string
+	}{
+		{"unresolved market", nil, false, "", "", "A trade whose market couldn't be resolved isn't filtered here"},
+		{"in window", &MarketInfo{Category: "Politics", EndDate: now + 30*day, EndDateSource: endDateSourceMarket}, false, "", "", "A market ending inside the horizon is kept"},
+		{"unknown end date", &MarketInfo{Category: "Politics"}, false, "", "", "A market without an end date is kept"},
+		{"closed by market date", &MarketInfo{Category: "Politics", EndDate: now - day, EndDateSource: endDateSourceMarket}, true, "closed", endDateSourceMarket, "A trade after the market's end date is filtered as closed"},
+		{"closed by event date", &MarketInfo{Category: "Politics", EndDate: now, EndDateSource: endDateSourceEvent}, true, "closed", endDateSourceEvent, "A trade at the event's end date is filtered as closed and counted against the event source"},
+		{"beyond horizon", &MarketInfo{Category: "Politics", EndDate: now + 61*day, EndDateSource: endDateSourceEvent}, true, "horizon", "", "A market ending past MAX_MARKET_HORIZON_DAYS is filtered as distant"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{})
+			p.cfg.MaxMarketHorizonDays = 60
+			stats := newPollStats()
+
+			filteredBefore := map[string]float64{}
+			for _, reason := range []string{"closed", "horizon"} {
+				filteredBefore[reason] = testutil.ToFloat64(metrics.TradesFiltered.WithLabelValues(reason, categoryPolitics))
+			}
+			afterEndBefore := map[string]float64{}
+			for _, source := range []string{endDateSourceMarket, endDateSourceEvent} {
+				afterEndBefore[source] = testutil.ToFloat64(metrics.TradesAfterEndDate.WithLabelValues(source))
+			}
+
+			skip := p.filterMarket(&dataapi.Trade{ConditionID: "0xmarket", Timestamp: now}, tt.market, stats)
+			if skip != tt.expectedSkip {
+				t.Errorf("got skip %v, want %v\nDescription: %s", skip, tt.expectedSkip, tt.description)
+			}
+
+			for _, reason := range []string{"closed", "horizon"} {
+				want := 0
+				if reason == tt.expectedReason {
+					want = 1
+				}
+				if got := stats.statuses["filtered_"+reason]; got != want {
+					t.Errorf("got %d filtered_%s trades, want %d\nDescription: %s", got, reason, want, tt.description)
+				}
+				if got := testutil.ToFloat64(metrics.TradesFiltered.WithLabelValues(reason, categoryPolitics)) - filteredBefore[reason]; got != float64(want) {
+					t.Errorf("got %.0f trades filtered as %s, want %d\nDescription: %s", got, reason, want, tt.description)
+				}
+			}
+
+			for _, source := range []string{endDateSourceMarket, endDateSourceEvent} {
+				want := 0.0
+				if source == tt.expectedSource {
+					want = 1
+				}
+				if got := testutil.ToFloat64(metrics.TradesAfterEndDate.WithLabelValues(source)) - afterEndBefore[source]; got != want {
+					t.Errorf("got %.0f trades after the %s end date, want %.0f\nDescription: %s", got, source, want, tt.description)
+				}
+			}
+		})
+	}
+}
+
+func TestPrewarmable(t *testing.T) {
+	tests := []struct {
+		name        string
+		market      gammaapi.Market
+		expected    bool
+		eventSlug   string
+		description string
+	}{
+		{"politics", gammaapi.Market{ConditionID: "0x1", Category: "Politics", Events: []gammaapi.Event{{Slug: "election"}}}, true, "election", "Markets in watched categories are cached with their event"},
+		{"uncategorised", gammaapi.Market{ConditionID: "0x2", Slug: "will-it-rain"}, true, "", "Markets without a category are cached; trades in them are scored"},
+		{"sports category", gammaapi.Market{ConditionID: "0x3", Category: "Sports"}, false, "", "Sports trades are filtered before they need a market"},
+		{"sports slug", gammaapi.Market{ConditionID: "0x4", Slug: "nba-finals-game-7"}, false, "", "Sports is recognised from the slug like the trade filter does"},
+		{"no condition id", gammaapi.Market{Category: "Politics"}, false, "", "market_map is keyed by condition ID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := prewarmable(&tt.market); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+			if got := marketEventSlug(&tt.market); got != tt.eventSlug {
+				t.Errorf("got event slug %q, want %q\nDescription: %s", got, tt.eventSlug, tt.description)
+			}
+		})
+	}
+}
+
+func TestPrewarmMarketsDisabled(t *testing.T) {
+	gamma := &processortest.GammaAPI{Recent: []gammaapi.Market{{ConditionID: "0x1"}}}
+	p := newFakeProcessor(nil, gamma)
+
+	cached, err := p.PrewarmMarkets(context.Background())
+	if cached != 0 || err != nil {
+		t.Errorf("got %d cached and %v, want nothing done", cached, err)
+	}
+	if got := gamma.Calls("ListRecentMarkets"); got != 0 {
+		t.Errorf("got %d listings, want none while ENABLE_MARKET_PREWARM is off", got)
+	}
+}
+
+func TestFetchMarketFallback(t *testing.T) {
+	stale := &storage.MarketMap{
+		ConditionID: "0xcond",
+		MarketSlug:  "cached-market",
+		MarketTitle: "Cached market",
+		MarketURL:   "https://polymarket.com/market/cached-market",
+		Category:    "Politics",
+	}
+
+	tests := []struct {
+		name             string
+		gammaErr         error
+		cached           *storage.MarketMap
+		trade            dataapi.Trade
+		expectedURL      string
+		expectedErr      error
+		expectedFallback bool
+		expectedBySlug   int
+		description      string
+	}{
+		{
+			name:        "gamma down uses trade slug",
+			gammaErr:    errors.New("unexpected status 503"),
+			trade:       dataapi.Trade{ConditionID: "0xcond", Title: "Will it happen?", Slug: "will-it-happen"},
+			expectedURL: "https://polymarket.com/market/will-it-happen",
+			description: "Without a cache entry the trade's own slug builds the market URL",
+		},
+		{
+			name:             "market not found without slug",
+			trade:            dataapi.Trade{ConditionID: "0xcond", Title: "Will it happen?"},
+			expectedURL:      "https://polymarket.com/search?q=0xcond",
+			expectedFallback: true,
+			description:      "A trade without a slug links to a search for its condition ID",
+		},
+		{
+			name:             "market not found by slug either",
+			trade:            dataapi.Trade{ConditionID: "0xcond", Title: "Will it happen?", Slug: "will-it-happen"},
+			expectedURL:      "https://polymarket.com/market/will-it-happen",
+			expectedFallback: true,
+			expectedBySlug:   1,
+			description:      "A missing market is looked up by slug before falling back to trade data",
+		},
+		{
+			name:        "gamma down prefers stale cache",
+			gammaErr:    errors.New("unexpected status 502"),
+			cached:      stale,
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "will-it-happen"},
+			expectedURL: "https://polymarket.com/market/cached-market",
+			description: "An expired cache entry still has the category the trade lacks",
+		},
+		{
+			name:        "circuit open without cache",
+			gammaErr:    circuit.ErrOpen,
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "will-it-happen"},
+			expectedErr: circuit.ErrOpen,
+			description: "An open circuit fails the trade so it is retried rather than scored without a category",
+		},
+		{
+			name:        "rate limited without cache",
+			gammaErr:    fmt.Errorf("get market: %w", &gammaapi.RateLimitedError{RetryAfter: time.Minute}),
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "will-it-happen"},
+			expectedErr: gammaapi.ErrRateLimited,
+			description: "A rate limited lookup is retried on the next poll like an open circuit",
+		},
+		{
+			name:        "rate limited prefers stale cache",
+			gammaErr:    &gammaapi.RateLimitedError{},
+			cached:      stale,
+			trade:       dataapi.Trade{ConditionID: "0xcond"},
+			expectedURL: "https://polymarket.com/market/cached-market",
+			description: "An expired cache entry is still used while Gamma is rate limiting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gamma := &processortest.GammaAPI{MarketErr: tt.gammaErr}
+			p := newFakeProcessor(&processortest.DataAPI{}, gamma)
+
+			info, err := p.fetchMarket(context.Background(), &tt.trade, tt.cached)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error %v, want %v\nDescription: %s", err, tt.expectedErr, tt.description)
+			}
+			if tt.expectedErr == nil && info.URL != tt.expectedURL {
+				t.Errorf("got URL %s, want %s\nDescription: %s", info.URL, tt.expectedURL, tt.description)
+			}
+			if tt.expectedErr == nil && info.Fallback != tt.expectedFallback {
+				t.Errorf("got fallback %v, want %v\nDescription: %s", info.Fallback, tt.expectedFallback, tt.description)
+			}
+			if got := gamma.Calls("GetMarketByConditionIDIfModified"); got != 1 {
+				t.Errorf("got %d Gamma calls, want 1\nDescription: %s", got, tt.description)
+			}
+			if got := gamma.Calls("GetMarketBySlug"); got != tt.expectedBySlug {
+				t.Errorf("got %d slug lookups, want %d\nDescription: %s", got, tt.expectedBySlug, tt.description)
+			}
+		})
+	}
+}
+
+// TestFetchMarketCoalesced holds Gamma's answer while several workers look
+// the same market up, so all but the first join its lookup
+func TestFetchMarketCoalesced(t *testing.T) {
+	const workers = 5
+	gate := make(chan struct{})
+	gamma := &processortest.GammaAPI{Blocked: map[string]chan struct{}{"GetMarketByConditionIDIfModified": gate}}
+	p := newFakeProcessor(&processortest.DataAPI{}, gamma)
+	coalesced := metrics.CoalescedLookups.WithLabelValues("market")
+	before := testutil.ToFloat64(coalesced)
+
+	var started, done sync.WaitGroup
+	results := make([]*MarketInfo, workers)
+	for i := 0; i < workers; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func(i int) {
+			defer done.Done()
+			started.Done()
+			trade := dataapi.Trade{ConditionID: "0xcond", Title: "Will it happen?"}
+			results[i], _ = p.fetchMarket(context.Background(), &trade, nil)
+		}(i)
+	}
+	started.Wait()
+	for gamma.Calls("GetMarketByConditionIDIfModified") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the other workers join the flight
+	close(gate)
+	done.Wait()
+
+	if got := gamma.Calls("GetMarketByConditionIDIfModified"); got != 1 {
+		t.Errorf("got %d Gamma lookups, want 1\nDescription: Concurrent lookups of one market share a single request", got)
+	}
+	if got := testutil.ToFloat64(coalesced) - before; got != workers-1 {
+		t.Errorf("got %.0f coalesced lookups, want %d\nDescription: Every worker but the one making the request is counted as coalesced", got, workers-1)
+	}
+	for i, info := range results {
+		if info == nil || info.URL != "https://polymarket.com/search?q=0xcond" {
+			t.Errorf("got worker %d market %+v, want the trade data fallback\nDescription: Workers that joined the lookup get its result", i, info)
+		}
+	}
+}
+
+func TestCachedValidators(t *testing.T) {
+	tests := []struct {
+		name        string
+		cached      *storage.MarketMap
+		expected    gammaapi.Validators
+		description string
+	}{
+		{"no cache entry", nil, gammaapi.Validators{}, "A market seen for the first time is fetched unconditionally"},
+		{"cached with validators", &storage.MarketMap{ETag: `"v1"`, LastModified: "Mon, 02 Jan 2026 15:04:05 GMT"}, gammaapi.Validators{ETag: `"v1"`, LastModified: "Mon, 02 Jan 2026 15:04:05 GMT"}, "An expired entry is revalidated with the validators it was stored with"},
+		{"fallback entry", &storage.MarketMap{IsFallback: true, ETag: `"v1"`}, gammaapi.Validators{}, "A fallback row built from trade data must be replaced, never kept on a 304"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cachedValidators(tt.cached); got != tt.expected {
+				t.Errorf("got %+v, want %+v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestMarketBySlug(t *testing.T) {
+	gamma := &processortest.GammaAPI{MarketsBySlug: map[string]*gammaapi.Market{
+		"new-market":   {ConditionID: "0xCOND", Slug: "new-market", Category: "Sports"},
+		"other-market": {ConditionID: "0xother", Slug: "other-market"},
+	}}
+	p := newFakeProcessor(&processortest.DataAPI{}, gamma)
+
+	tests := []struct {
+		name        string
+		slug        string
+		expectedErr error
+		description string
+	}{
+		{"found", "new-market", nil, "The slug's market matches the trade's condition ID, ignoring case"},
+		{"different market", "other-market", gammaapi.ErrMarketNotFound, "A slug for another market is treated as not found"},
+		{"missing", "missing-market", gammaapi.ErrMarketNotFound, "An unknown slug is not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			market, err := p.marketBySlug(context.Background(), &dataapi.Trade{ConditionID: "0xcond", Slug: tt.slug})
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("got error %v, want %v\nDescription: %s", err, tt.expectedErr, tt.description)
+			}
+			if err == nil && market.Category != "Sports" {
+				t.Errorf("got %+v, want the new market\nDescription: %s", market, tt.description)
+			}
+		})
+	}
+}
+
+func TestMarketFresh(t *testing.T) {
+	p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{})
+	p.cfg.MarketFallbackTTL = 10 * time.Minute
+	now := time.Now().Unix()
+
+	tests := []struct {
+		name        string
+		market      storage.MarketMap
+		expected    bool
+		description string
+	}{
+		{"gamma market", storage.MarketMap{UpdatedTS: now - 3600}, true, "Gamma markets are cached for 24 hours"},
+		{"expired gamma market", storage.MarketMap{UpdatedTS: now - 86400}, false, "Gamma markets expire after 24 hours"},
+		{"fresh fallback", storage.MarketMap{IsFallback: true, UpdatedTS: now - 60}, true, "Fallback rows are used within MARKET_FALLBACK_TTL"},
+		{"expired fallback", storage.MarketMap{IsFallback: true, UpdatedTS: now - 3600}, false, "Fallback rows are looked up again after MARKET_FALLBACK_TTL"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.marketFresh(&tt.market, now); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestFallbackMarketSportsSkip(t *testing.T) {
+	tests := []struct {
+		name        string
+		gammaErr    error
+		cached      *storage.MarketMap
+		trade       dataapi.Trade
+		expected    bool
+		description string
+	}{
+		{
+			name:        "sports slug from trade",
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "nba-finals-game-7-celtics-lakers"},
+			expected:    true,
+			description: "With Gamma missing the market the trade slug alone identifies sports",
+		},
+		{
+			name:        "sports category from stale cache",
+			gammaErr:    errors.New("unexpected status 503"),
+			cached:      &storage.MarketMap{ConditionID: "0xcond", MarketSlug: "game-7", Category: "Sports"},
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "game-7"},
+			expected:    true,
+			description: "The cached category still filters sports while Gamma is down",
+		},
+		{
+			name:        "politics slug from trade",
+			gammaErr:    errors.New("unexpected status 503"),
+			trade:       dataapi.Trade{ConditionID: "0xcond", Slug: "will-the-senate-pass-the-bill"},
+			expected:    false,
+			description: "Non-sports trades are still processed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{MarketErr: tt.gammaErr})
+
+			info, err := p.fetchMarket(context.Background(), &tt.trade, tt.cached)
+			if err != nil {
+				t.Fatalf("got %v, want no error\nDescription: %s", err, tt.description)
+			}
+			if got := isNotInsiderCategory(info); got != tt.expected {
+				t.Errorf("got skip %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestAPIVerifiedTradeCount(t *testing.T) {
+	data := &processortest.DataAPI{
+		Activity: map[string][]dataapi.ActivityEvent{
+			"0xnew": {
+				{Type: "TRADE", Timestamp: 1700000100},
+				{Type: "TRANSFER", Timestamp: 1700000000},
+			},
+		},
+	}
+	p := newFakeProcessor(data, &processortest.GammaAPI{})
+	ctx := context.Background()
+
+	count, err := p.apiTradeCount(ctx, "0xnew", time.Now())
+	if err != nil || count != 1 {
+		t.Fatalf("got %d, %v, want 1 trade: transfers are not trades", count, err)
+	}
+
+	// The verified count is served from the cache without the API or storage
+	count, err = p.verifiedTradeCount(ctx, "0xnew")
+	if err != nil || count != 1 {
+		t.Errorf("got %d, %v, want the cached count of 1", count, err)
+	}
+	if got := data.Calls("GetWalletActivity"); got != 1 {
+		t.Errorf("got %d activity calls, want 1", got)
+	}
+
+	// A failed lookup is not cached, so the next trade asks again
+	data.ActivityErr = errors.New("unexpected status 503")
+	if _, err := p.apiTradeCount(ctx, "0xother", time.Now()); err == nil {
+		t.Errorf("got nil error, want the activity failure")
+	}
+	if _, ok := p.tradeCounts.get("0xother", time.Now()); ok {
+		t.Errorf("failed lookup was cached")
+	}
+}
+
+func TestInvalidTradeReason(t *testing.T) {
+	body, err := os.ReadFile(filepath.Join("testdata", "malformed_trades.json"))
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	var trades []dataapi.Trade
+	if err := json.Unmarshal(body, &trades); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+	byHash := make(map[string]*dataapi.Trade)
+	for i := range trades {
+		byHash[trades[i].TransactionHash] = &trades[i]
+	}
+
+	now := time.Unix(1733000000, 0)
+	tests := []struct {
+		hash        string
+		expected    string
+		description string
+	}{
+		{"0xvalid", "", "A well-formed trade passes"},
+		{"0xnowallet", "missing_wallet", "An empty proxyWallet would create a garbage wallet row"},
+		{"0xnocondition", "missing_condition_id", "A blank conditionId can't be matched to a market"},
+		{"0xzerots", "bad_timestamp", "A zero timestamp would make the wallet look decades old"},
+		{"0xmillis", "bad_timestamp", "A timestamp in milliseconds lands far in the future"},
+		{"0xnegsize", "bad_size", "Negative sizes are rejected"},
+		{"0xzerosize", "bad_size", "Zero sizes are rejected"},
+		{"0xcents", "bad_price", "Prices are probabilities between 0 and 1"},
+		{"0xcertain", "", "A price of exactly 1 is valid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.hash, func(t *testing.T) {
+			trade, ok := byHash[tt.hash]
+			if !ok {
+				t.Fatalf("fixture has no trade %s", tt.hash)
+			}
+			if got := invalidTradeReason(trade, now); got != tt.expected {
+				t.Errorf("got %q, want %q\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+
+	p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{})
+	stats := newPollStats()
+	if valid := p.validTrades(trades, stats); len(valid) != 2 {
+		t.Errorf("got %d valid trades, want 2", len(valid))
+	}
+	if got := stats.statuses["invalid"]; got != 7 {
+		t.Errorf("got %d trades counted invalid, want 7", got)
+	}
+}
+
+func TestPollCyclesNeverOverlap(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	p.cfg.PollCycleTimeout = 30 * time.Millisecond
+
+	var mu sync.Mutex
+	active, maxActive, processed := 0, 0, 0
+
+	// Each trade takes 15ms, so the 10 in a cycle outlast both the 10ms
+	// tick and the cycle deadline
+	slowCycle := func(ctx context.Context) error {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			active--
+			mu.Unlock()
+		}()
+
+		for i := 0; i < 10; i++ {
+			select {
+			case <-time.After(15 * time.Millisecond):
+				mu.Lock()
+				processed++
+				mu.Unlock()
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var skipped, deadlines, other int
+	var resultsMu sync.Mutex
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for i := 0; i < 15; i++ {
+		<-ticker.C
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := p.runPollCycle(context.Background(), slowCycle)
+			resultsMu.Lock()
+			defer resultsMu.Unlock()
+			switch {
+			case errors.Is(err, ErrPollRunning):
+				skipped++
+			case errors.Is(err, context.DeadlineExceeded):
+				deadlines++
+			default:
+				other++
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("got %d concurrent cycles, want 1\nDescription: A tick that arrives while a cycle is running must not start another", maxActive)
+	}
+	if skipped == 0 {
+		t.Errorf("got no skipped ticks, want some\nDescription: Ticks during a slow cycle are skipped")
+	}
+	if deadlines == 0 || other != 0 {
+		t.Errorf("got %d cycles cut off by the deadline and %d other results, want every cycle cut off\nDescription: The cycle deadline stops a slow cycle before it finishes", deadlines, other)
+	}
+	if processed >= 10*deadlines {
+		t.Errorf("got %d trades processed, want fewer than %d\nDescription: No cycle processes all of its trades past the deadline", processed, 10*deadlines)
+	}
+}
+
+func TestNewResolutionScan(t *testing.T) {
+	tests := []struct {
+		name            string
+		cursor          string
+		expectedBatches [][]string
+		description     string
+	}{
+		{"fresh", "", [][]string{{"0xa", "0xb"}, {"0xc", "0xd"}, {"0xe"}}, "A run without a cursor scans every market in sorted order"},
+		{"resumed", "0xb", [][]string{{"0xc", "0xd"}, {"0xe"}}, "A resumed run skips markets up to the cursor"},
+		{"complete", "0xe", nil, "Nothing is left after the last market"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scan := newResolutionScan([]string{"0xc", "0xa", "0xe", "0xb", "0xd"}, tt.cursor, 2)
+			if !reflect.DeepEqual(scan.batches, tt.expectedBatches) {
+				t.Errorf("got %v, want %v\nDescription: %s", scan.batches, tt.expectedBatches, tt.description)
+			}
+		})
+	}
+}
+
+func TestResolutionScanRun(t *testing.T) {
+	ids := make([]string, 40)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("0x%02d", i)
+	}
+
+	t.Run("cursor", func(t *testing.T) {
+		scan := newResolutionScan(ids, "", 4)
+
+		var mu sync.Mutex
+		active, maxActive := 0, 0
+		var saved []string
+		err := scan.run(context.Background(), 3, func(ctx context.Context, batch []string) (int, int) {
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			// The first batch finishes last, holding the cursor back
+			if batch[0] == "0x00" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			mu.Lock()
+			active--
+			mu.Unlock()
+			return 1, 0
+		}, func(cursor string) {
+			saved = append(saved, cursor)
+		})
+
+		if err != nil {
+			t.Fatalf("got %v, want no error", err)
+		}
+		if maxActive > 3 {
+			t.Errorf("got %d concurrent batches, want at most 3\nDescription: The worker count bounds concurrency", maxActive)
+		}
+		if len(saved) > 2 {
+			t.Errorf("got cursors %v, want at most 2\nDescription: The cursor can't pass the first batch while it is still running", saved)
+		}
+		for i := 1; i < len(saved); i++ {
+			if saved[i] <= saved[i-1] {
+				t.Errorf("got cursors %v, want them increasing\nDescription: The cursor never moves back", saved)
+				break
+			}
+		}
+		if len(saved) == 0 || saved[len(saved)-1] != "0x39" {
+			t.Errorf("got cursors %v, want the last one to be 0x39\nDescription: A full pass ends at the last market", saved)
+		}
+		if processed, resolved := scan.progress(); processed != 40 || resolved != 10 {
+			t.Errorf("got %d processed and %d resolved, want 40 and 10\nDescription: Every batch is counted", processed, resolved)
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		scan := newResolutionScan(ids, "", 4)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var mu sync.Mutex
+		var saved []string
+		started := 0
+		err := scan.run(ctx, 2, func(ctx context.Context, batch []string) (int, int) {
+			mu.Lock()
+			started++
+			if started == 3 {
+				cancel()
+			}
+			mu.Unlock()
+			return 0, 0
+		}, func(cursor string) {
+			saved = append(saved, cursor)
+		})
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("got %v, want context.Canceled\nDescription: Shutdown stops the run", err)
+		}
+		if started > 4 {
+			t.Errorf("got %d batches started, want the run to stop promptly\nDescription: No batches start after cancellation", started)
+		}
+		if len(saved) > 0 && saved[len(saved)-1] > "0x07" {
+			t.Errorf("got cursor %s, want at most 0x07\nDescription: The cursor only covers batches that finished before cancellation", saved[len(saved)-1])
+		}
+	})
+}
+
+func TestPollCycleMetrics(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	p.cfg.PollCycleTimeout = time.Second
+	p.checkpointTS.Store(time.Now().Add(-90 * time.Second).Unix())
+
+	// A cycle that fails before fetching still publishes its duration and
+	// the checkpoint's age
+	p.runPollCycle(context.Background(), func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return errors.New("fetch trades: timeout")
+	})
+
+	if got := testutil.ToFloat64(metrics.LastPollDuration); got < 0.02 {
+		t.Errorf("got last poll duration %.3fs, want at least 0.02s", got)
+	}
+	if got := testutil.ToFloat64(metrics.CheckpointAge); got < 90 || got > 95 {
+		t.Errorf("got checkpoint age %.0fs, want about 90s", got)
+	}
+}
+
+func TestPollReady(t *testing.T) {
+	now := time.Now()
+	maxAge := 90 * time.Second
+
+	tests := []struct {
+		name          string
+		started       time.Time
+		outcomes      []error
+		lastSuccess   time.Duration // How long ago the recorded success was, when any
+		expectedReady bool
+		description   string
+	}{
+		{"new instance", now.Add(-time.Minute), nil, 0, true, "A new instance has maxAge to complete its first poll"},
+		{"never succeeded", now.Add(-time.Hour), []error{errors.New("fetch trades: unauthorized")}, 0, false, "An instance whose polls have all failed is not ready"},
+		{"recent success", now.Add(-time.Hour), []error{nil}, time.Minute, true, "A poll that succeeded within maxAge keeps the instance ready"},
+		{"failing since success", now.Add(-time.Hour), []error{nil, errors.New("fetch trades: unauthorized")}, 10 * time.Minute, false, "Failed cycles don't refresh the last success"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newFakeProcessor(nil, nil)
+			p.pollHealth.started = tt.started
+			for _, err := range tt.outcomes {
+				p.recordPollOutcome(err)
+			}
+			if !p.pollHealth.status.LastSuccess.IsZero() {
+				p.pollHealth.status.LastSuccess = now.Add(-tt.lastSuccess)
+			}
+
+			ready, status := p.PollReady(now, maxAge)
+			if ready != tt.expectedReady {
+				t.Errorf("got ready %v (%+v), want %v\nDescription: %s", ready, status, tt.expectedReady, tt.description)
+			}
+			if last := tt.outcomes; len(last) > 0 && (status.LastError == "") != (last[len(last)-1] == nil) {
+				t.Errorf("got last error %q, want the last cycle's error\nDescription: %s", status.LastError, tt.description)
+			}
+		})
+	}
+}
+
+func TestDrain(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	p.cfg.PollCycleTimeout = time.Second
+
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	go p.runPollCycle(context.Background(), func(ctx context.Context) error {
+		close(started)
+		time.Sleep(50 * time.Millisecond)
+		close(finished)
+		return nil
+	})
+	<-started
+
+	short, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	if err := p.Drain(short); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want context.DeadlineExceeded\nDescription: Drain gives up when the cycle outlasts its context", err)
+	}
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Errorf("got Drain returning before the cycle finished, want it to wait\nDescription: Drain waits for in-flight poll cycles")
+	}
+}
+
+type fakeDeadLetters struct {
+	mu     sync.Mutex
+	trades []storage.DeadLetterTrade
+}
+
+func (f *fakeDeadLetters) InsertDeadLetterTrade(_ context.Context, trade *storage.DeadLetterTrade) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.trades = append(f.trades, *trade)
+	return nil
+}
+
+func TestProcessTradeSafelyRecoversPanic(t *testing.T) {
+	// Without storage, processTrade panics on its first query
+	p := newFakeProcessor(nil, nil)
+	deadLetters := &fakeDeadLetters{}
+	p.deadLetters = deadLetters
+	stats := newPollStats()
+	before := testutil.ToFloat64(metrics.Panics.WithLabelValues("trade"))
+
+	trades := []dataapi.Trade{
+		{ConditionID: "0xcond", ProxyWallet: "0xwallet1", TransactionHash: "0xtx1", Size: 100, Price: 0.5, Timestamp: 1},
+		{ConditionID: "0xcond", ProxyWallet: "0xwallet2", TransactionHash: "0xtx2", Size: 100, Price: 0.5, Timestamp: 2},
+	}
+	var wg sync.WaitGroup
+	for _, trade := range trades {
+		wg.Add(1)
+		go func(t dataapi.Trade) {
+			defer wg.Done()
+			p.processTradeSafely(context.Background(), &t, stats)
+		}(trade)
+	}
+	wg.Wait()
+
+	if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("trade")) - before; got != 2 {
+		t.Errorf("got %v panics counted, want 2\nDescription: Each panicking trade is counted", got)
+	}
+	if got := stats.summary(2, 2, 0).Trades["panic"]; got != 2 {
+		t.Errorf("got %d trades with status panic, want 2\nDescription: Panicking trades show up in the poll stats", got)
+	}
+	if len(deadLetters.trades) != 2 {
+		t.Fatalf("got %d dead-lettered trades, want 2\nDescription: Each panicking trade is dead-lettered and the batch carries on", len(deadLetters.trades))
+	}
+	for _, dl := range deadLetters.trades {
+		if dl.TradeHash == "" || dl.Reason == "" || dl.Stack == "" || !strings.Contains(dl.TradeJSON, dl.ProxyWallet) {
+			t.Errorf("got %+v, want the trade with its hash, panic and stack", dl)
+		}
+	}
+}
+
+func TestResolveMarketBatchSafelyRecoversPanic(t *testing.T) {
+	// Without storage, resolveMarketBatch panics on its first query
+	p := newFakeProcessor(nil, nil)
+	before := testutil.ToFloat64(metrics.Panics.WithLabelValues("win_rate"))
+
+	resolved, missing := p.resolveMarketBatchSafely(context.Background(), []string{"0xa", "0xb"})
+	if resolved != 0 || missing != 2 {
+		t.Errorf("got %d resolved and %d missing, want the batch counted as missing", resolved, missing)
+	}
+	if got := testutil.ToFloat64(metrics.Panics.WithLabelValues("win_rate")) - before; got != 1 {
+		t.Errorf("got %v panics counted, want 1", got)
+	}
+}
+
+func TestSuppressAlertCountsReason(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	trade := &dataapi.Trade{ConditionID: "0xcond", Slug: "some-market", ProxyWallet: "0xwallet"}
+	cooldown := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedWalletCooldown, metrics.DefaultProfile))
+	recordOnly := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedRecordOnly, metrics.DefaultProfile))
+	profiled := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedWalletCooldown, "crypto-desk"))
+
+	p.suppressAlert(suppressedWalletCooldown, "", trade, trade.ProxyWallet, 80)
+
+	if got := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedWalletCooldown, metrics.DefaultProfile)) - cooldown; got != 1 {
+		t.Errorf("got %v cooldown suppressions, want 1\nDescription: Suppressions are counted under their reason", got)
+	}
+	if got := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedRecordOnly, metrics.DefaultProfile)) - recordOnly; got != 0 {
+		t.Errorf("got %v record-only suppressions, want 0\nDescription: Other reasons are left alone", got)
+	}
+
+	p.suppressAlert(suppressedWalletCooldown, "crypto-desk", trade, trade.ProxyWallet, 80)
+	if got := testutil.ToFloat64(metrics.AlertsSuppressed.WithLabelValues(suppressedWalletCooldown, "crypto-desk")) - profiled; got != 1 {
+		t.Errorf("got %v profile suppressions, want 1\nDescription: Each profile is counted under its own label", got)
+	}
+}
+
+func TestBuildDailySummary(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	loc, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		to          time.Time
+		wantDate    string
+		description string
+	}{
+		{"evening", time.Date(2024, 6, 3, 18, 0, 0, 0, loc), "2024-06-03", "An evening summary is dated the day it is sent"},
+		{"midnight", time.Date(2024, 6, 4, 0, 0, 0, 0, loc), "2024-06-03", "A midnight summary is dated the day that just ended"},
+	}
+
+	period := &storage.PeriodSummary{
+		Trades:    12,
+		VolumeUSD: 340000,
+		Alerts:    []storage.SeverityCount{{AlertType: "ALERT", Alerts: 2}, {AlertType: "INFO", Alerts: 7}},
+		TopAlerts: []storage.Alert{{ID: 9, AlertType: "ALERT", WalletAddress: "0x1234567890abcdef1234567890abcdef12345678", SuspicionScore: 100000}},
+		FlaggedWins: []storage.FlaggedWin{{MarketTitle: "Some market", WinningOutcome: "Yes", Wallets: 2, AlertedUSD: 50000}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := p.buildDailySummary(period, tt.to.AddDate(0, 0, -1), tt.to)
+			if summary.Date != tt.wantDate || summary.Timezone != "Europe/London" {
+				t.Errorf("got %s %s, want %s Europe/London\nDescription: %s", summary.Date, summary.Timezone, tt.wantDate, tt.description)
+			}
+		})
+	}
+
+	summary := p.buildDailySummary(period, tests[0].to.AddDate(0, 0, -1), tests[0].to)
+	if summary.QualifyingTrades != 12 || summary.Alerts[alerts.SeverityAlert] != 2 || summary.Alerts[alerts.SeverityWarn] != 0 {
+		t.Errorf("got %d trades and alerts %v, want 12 trades with 2 ALERT and no WARN", summary.QualifyingTrades, summary.Alerts)
+	}
+	if len(summary.TopAlerts) != 1 || summary.TopAlerts[0].NormalizedScore < 83 || summary.TopAlerts[0].WalletShort == summary.TopAlerts[0].WalletAddress {
+		t.Errorf("got %+v, want the alert with its normalized score and short wallet", summary.TopAlerts)
+	}
+	if len(summary.FlaggedWins) != 1 || summary.FlaggedWins[0].Wallets != 2 {
+		t.Errorf("got %+v, want the one flagged win", summary.FlaggedWins)
+	}
+}
+
+func TestReload(t *testing.T) {
+	p := newFakeProcessor(nil, nil)
+	p.cfg = &config.Config{SuspicionScoreWarn: 70, SuspicionScoreAlert: 85, VelocityThreshold: 3}
+
+	if got := p.determineSeverity(80); got != alerts.SeverityWarn {
+		t.Fatalf("got %s before reloading, want %s", got, alerts.SeverityWarn)
+	}
+
+	p.Reload(&config.Config{SuspicionScoreWarn: 60, SuspicionScoreAlert: 75, VelocityThreshold: 5})
+
+	if got := p.determineSeverity(80); got != alerts.SeverityAlert {
+		t.Errorf("got %s after lowering SUSPICION_SCORE_ALERT, want %s", got, alerts.SeverityAlert)
+	}
+	for _, rule := range p.activeRules() {
+		if v, ok := rule.(*velocityRule); ok && v.cfg.VelocityThreshold != 5 {
+			t.Errorf("got velocity threshold %d in the rule, want the reloaded 5: built-in rules must be rebuilt", v.cfg.VelocityThreshold)
+		}
+	}
+}
+
+func TestFetchBackfillTrades(t *testing.T) {
+	since := time.Unix(1_700_000_000, 0)
+	until := since.Add(time.Hour)
+	data := &processortest.DataAPI{Trades: []dataapi.Trade{
+		{TransactionHash: "0xlate", Timestamp: until.Unix() + 10},
+		{TransactionHash: "0xuntil", Timestamp: until.Unix()},
+		{TransactionHash: "0xin", Timestamp: since.Unix() + 60},
+		{TransactionHash: "0xsince", Timestamp: since.Unix()},
+		{TransactionHash: "0xearly", Timestamp: since.Unix() - 1},
+	}}
+
+	tests := []struct {
+		name        string
+		until       time.Time
+		expected    []string
+		description string
+	}{
+		{"window", until, []string{"0xin", "0xsince"}, "Trades at since are kept and trades at until are not"},
+		{"open ended", time.Time{}, []string{"0xlate", "0xuntil", "0xin", "0xsince"}, "A zero until keeps everything since"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trades, err := newFakeProcessor(data, nil).fetchBackfillTrades(context.Background(), since, tt.until)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			var hashes []string
+			for _, trade := range trades {
+				hashes = append(hashes, trade.TransactionHash)
+				if trade.Role != dataapi.RoleTaker {
+					t.Errorf("got role %q for %s, want taker", trade.Role, trade.TransactionHash)
+				}
+			}
+			if !reflect.DeepEqual(hashes, tt.expected) {
+				t.Errorf("got %v, want %v\nDescription: %s", hashes, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestProcessLiveTradeBacklog(t *testing.T) {
+	p := newFakeProcessor(&processortest.DataAPI{}, &processortest.GammaAPI{})
+	for i := 0; i < cap(p.liveBacklog); i++ {
+		p.liveBacklog <- struct{}{}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		defer close(returned)
+		p.ProcessLiveTrade(ctx, dataapi.Trade{
+			ProxyWallet: "0xwallet",
+			ConditionID: "0xcond",
+			Timestamp:   time.Now().Unix(),
+			Size:        100000,
+			Price:       0.5,
+		})
+	}()
+
+	select {
+	case <-returned:
+		t.Fatal("got ProcessLiveTrade returning with a full backlog, want it to wait\nDescription: A burst on the feed holds up its read loop instead of starting goroutines")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("got ProcessLiveTrade still waiting after ctx was cancelled, want it to return")
+	}
+	if n := len(p.liveBacklog); n != cap(p.liveBacklog) {
+		t.Errorf("got %d backlog slots held, want %d\nDescription: A trade given up on doesn't take a slot", n, cap(p.liveBacklog))
+	}
+}
@@ -0,0 +1,243 @@
+// Package processortest provides fake API clients for testing the processor
+// without live HTTP.
+package processortest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/subgraph"
+)
+
+// DataAPI is a fake Data API client. Responses are keyed by wallet address
+// (case-insensitively) or condition ID; the Err fields fail every call to
+// their method. Calls records how often each method was called, and
+// Blocked holds calls to a method until its channel is closed.
+type DataAPI struct {
+	Trades        []dataapi.Trade
+	TradesErr     error
+	FirstActivity map[string]*dataapi.ActivityEvent
+	Activity      map[string][]dataapi.ActivityEvent
+	ActivityErr   error
+	Positions     map[string][]dataapi.Position
+	PositionsErr  error
+	Holders       map[string][]dataapi.TokenHolders
+	HoldersErr    error
+	Blocked       map[string]chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Calls returns how many times method was called
+func (f *DataAPI) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *DataAPI) record(method string) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+	f.mu.Unlock()
+	if gate, ok := f.Blocked[method]; ok {
+		<-gate
+	}
+}
+
+func (f *DataAPI) GetTrades(ctx context.Context, params dataapi.TradeParams) (*dataapi.TradesResponse, error) {
+	f.record("GetTrades")
+	if f.TradesErr != nil {
+		return nil, f.TradesErr
+	}
+	return &dataapi.TradesResponse{Trades: f.Trades, Count: len(f.Trades)}, nil
+}
+
+func (f *DataAPI) GetWalletFirstActivity(ctx context.Context, wallet string) (*dataapi.ActivityEvent, error) {
+	f.record("GetWalletFirstActivity")
+	if f.ActivityErr != nil {
+		return nil, f.ActivityErr
+	}
+	if event, ok := f.FirstActivity[strings.ToLower(wallet)]; ok {
+		return event, nil
+	}
+	return nil, errNoActivity(wallet)
+}
+
+func (f *DataAPI) GetWalletActivity(ctx context.Context, wallet string, limit int) ([]dataapi.ActivityEvent, error) {
+	f.record("GetWalletActivity")
+	if f.ActivityErr != nil {
+		return nil, f.ActivityErr
+	}
+	activity := f.Activity[strings.ToLower(wallet)]
+	if limit > 0 && len(activity) > limit {
+		activity = activity[:limit]
+	}
+	return activity, nil
+}
+
+func (f *DataAPI) GetPositions(ctx context.Context, wallet string) ([]dataapi.Position, error) {
+	f.record("GetPositions")
+	if f.PositionsErr != nil {
+		return nil, f.PositionsErr
+	}
+	return f.Positions[strings.ToLower(wallet)], nil
+}
+
+func (f *DataAPI) GetHolders(ctx context.Context, conditionID string, limit int) ([]dataapi.TokenHolders, error) {
+	f.record("GetHolders")
+	if f.HoldersErr != nil {
+		return nil, f.HoldersErr
+	}
+	return f.Holders[conditionID], nil
+}
+
+// GammaAPI is a fake Gamma API client. Markets are keyed by condition ID
+// and events by slug; unknown keys return the not-found errors the real
+// client does. Recent lists markets newest first for ListRecentMarkets.
+// ETags holds each market's current ETag for conditional lookups.
+// MarketErr and EventErr fail every call to their lookups. Blocked holds
+// calls to a method until its channel is closed.
+type GammaAPI struct {
+	Markets       map[string]*gammaapi.Market
+	MarketsBySlug map[string]*gammaapi.Market
+	Recent        []gammaapi.Market
+	ETags         map[string]string
+	MarketErr     error
+	Events        map[string]*gammaapi.Event
+	EventErr      error
+	Blocked       map[string]chan struct{}
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+// Calls returns how many times method was called
+func (f *GammaAPI) Calls(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[method]
+}
+
+func (f *GammaAPI) record(method string) {
+	f.mu.Lock()
+	if f.calls == nil {
+		f.calls = make(map[string]int)
+	}
+	f.calls[method]++
+	f.mu.Unlock()
+	if gate, ok := f.Blocked[method]; ok {
+		<-gate
+	}
+}
+
+func (f *GammaAPI) GetMarketByConditionID(ctx context.Context, conditionID string) (*gammaapi.Market, error) {
+	f.record("GetMarketByConditionID")
+	if f.MarketErr != nil {
+		return nil, f.MarketErr
+	}
+	if market, ok := f.Markets[conditionID]; ok {
+		return market, nil
+	}
+	return nil, gammaapi.ErrMarketNotFound
+}
+
+func (f *GammaAPI) GetMarketByConditionIDIfModified(ctx context.Context, conditionID string, v gammaapi.Validators) (*gammaapi.Market, gammaapi.Validators, error) {
+	f.record("GetMarketByConditionIDIfModified")
+	if f.MarketErr != nil {
+		return nil, v, f.MarketErr
+	}
+	market, ok := f.Markets[conditionID]
+	if !ok {
+		return nil, v, gammaapi.ErrMarketNotFound
+	}
+	etag := f.ETags[conditionID]
+	if etag != "" && v.ETag == etag {
+		return nil, v, gammaapi.ErrNotModified
+	}
+	return market, gammaapi.Validators{ETag: etag}, nil
+}
+
+func (f *GammaAPI) GetMarketsByConditionIDs(ctx context.Context, conditionIDs []string) (map[string]*gammaapi.Market, error) {
+	f.record("GetMarketsByConditionIDs")
+	if f.MarketErr != nil {
+		return nil, f.MarketErr
+	}
+	markets := make(map[string]*gammaapi.Market)
+	for _, id := range conditionIDs {
+		if market, ok := f.Markets[id]; ok {
+			markets[id] = market
+		}
+	}
+	return markets, nil
+}
+
+func (f *GammaAPI) GetMarketBySlug(ctx context.Context, slug string) (*gammaapi.Market, error) {
+	f.record("GetMarketBySlug")
+	if f.MarketErr != nil {
+		return nil, f.MarketErr
+	}
+	if market, ok := f.MarketsBySlug[slug]; ok {
+		return market, nil
+	}
+	return nil, gammaapi.ErrMarketNotFound
+}
+
+func (f *GammaAPI) ListRecentMarkets(ctx context.Context, limit, offset int) ([]gammaapi.Market, error) {
+	f.record("ListRecentMarkets")
+	if f.MarketErr != nil {
+		return nil, f.MarketErr
+	}
+	if offset >= len(f.Recent) {
+		return nil, nil
+	}
+	return f.Recent[offset:min(offset+limit, len(f.Recent))], nil
+}
+
+func (f *GammaAPI) GetEventBySlug(ctx context.Context, slug string) (*gammaapi.Event, error) {
+	f.record("GetEventBySlug")
+	if f.EventErr != nil {
+		return nil, f.EventErr
+	}
+	if event, ok := f.Events[slug]; ok {
+		return event, nil
+	}
+	return nil, gammaapi.ErrEventNotFound
+}
+
+// Subgraph is a fake subgraph client. Histories and outflows are keyed by
+// wallet address (case-insensitively); an unknown wallet has an empty
+// history. Err fails every call.
+type Subgraph struct {
+	Histories map[string]*subgraph.WalletHistory
+	Outflows  map[string][]subgraph.Transfer
+	Err       error
+}
+
+func (f *Subgraph) GetWalletHistory(ctx context.Context, wallet string) (*subgraph.WalletHistory, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	if history, ok := f.Histories[strings.ToLower(wallet)]; ok {
+		return history, nil
+	}
+	return &subgraph.WalletHistory{}, nil
+}
+
+func (f *Subgraph) GetWalletOutflows(ctx context.Context, wallet string) ([]subgraph.Transfer, error) {
+	if f.Err != nil {
+		return nil, f.Err
+	}
+	return f.Outflows[strings.ToLower(wallet)], nil
+}
+
+func errNoActivity(wallet string) error {
+	return fmt.Errorf("no activity for wallet %s: %w", wallet, dataapi.ErrNotFound)
+}
@@ -0,0 +1,53 @@
+package processor
+
+import (
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// alertRoute is one alert a scored trade raises: the watch profile behind
+// it, the severity that profile gives it and whether it notifies
+type alertRoute struct {
+	profile  string // "" without PROFILES
+	severity alerts.Severity
+	notify   bool
+}
+
+// alertRoutes decides the alerts a scored trade raises. Without PROFILES
+// that is one alert with the global thresholds; otherwise one per profile
+// that watches the market's category and the trade's size, with the
+// profile's thresholds. Old wallets are recorded without notifying unless
+// they clear the higher old-wallet threshold.
+func (p *Processor) alertRoutes(marketInfo *MarketInfo, notional float64, walletAgeDays int, normalizedScore float64, severity alerts.Severity) []alertRoute {
+	cfg := p.config()
+	if len(cfg.Profiles) == 0 {
+		severity, notify := p.gateSeverity(severity, walletAgeDays, normalizedScore)
+		return []alertRoute{{severity: severity, notify: notify}}
+	}
+
+	category := marketCategory(marketInfo)
+	var routes []alertRoute
+	for _, pr := range cfg.Profiles {
+		if !pr.Watches(category) || notional < pr.MinTradeUSD {
+			continue
+		}
+		severity := alerts.SeverityInfo
+		switch {
+		case normalizedScore >= cfg.ProfileScoreAlert(pr):
+			severity = alerts.SeverityAlert
+		case normalizedScore >= cfg.ProfileScoreWarn(pr):
+			severity = alerts.SeverityWarn
+		}
+		severity, notify := p.gateSeverityAt(severity, walletAgeDays, cfg.ProfileNewWalletDaysMax(pr), normalizedScore)
+		routes = append(routes, alertRoute{profile: pr.Name, severity: severity, notify: notify})
+	}
+	return routes
+}
+
+// profileLabel returns the profile metric label of an alert's profile
+func profileLabel(profile string) string {
+	if profile == "" {
+		return metrics.DefaultProfile
+	}
+	return profile
+}
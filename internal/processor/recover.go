@@ -0,0 +1,113 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/errreport"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// deadLetterTimeout bounds recording a dead-lettered trade, which runs even
+// when the trade's own context is done
+const deadLetterTimeout = 5 * time.Second
+
+// deadLetterStore keeps trades whose processing panicked; *storage.DB
+// implements it
+type deadLetterStore interface {
+	InsertDeadLetterTrade(ctx context.Context, trade *storage.DeadLetterTrade) error
+}
+
+// processTradeSafely runs processTrade, recovering a panic so one bad trade
+// can't take down the service or the rest of its batch. The panic is logged
+// with its stack, counted, and the trade dead-lettered; the trade then
+// counts as handled and nil is returned.
+func (p *Processor) processTradeSafely(ctx context.Context, trade *dataapi.Trade, stats *pollStats) (err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		stack := debug.Stack()
+		metrics.Panics.WithLabelValues("trade").Inc()
+		stats.trade("panic")
+
+		tradeHash := p.calculateTradeHash(trade)
+		p.log.WithFields(logrus.Fields{
+			"trade_hash":   tradeHash,
+			"condition_id": trade.ConditionID,
+			"wallet":       trade.ProxyWallet,
+			"panic":        v,
+			"stack":        string(stack),
+		}).Error("Panic processing trade, dead-lettering it")
+		errreport.Capture(errreport.Event{Err: fmt.Errorf("panic: %v", v), Source: "trade", Tags: tradeTags(trade, tradeHash), Stack: stack})
+		p.deadLetter(ctx, trade, tradeHash, fmt.Sprint(v), stack)
+		err = nil
+	}()
+	return p.processTrade(ctx, trade, stats)
+}
+
+// tradeTags is a trade's context on error reports; tradeHash may be empty
+func tradeTags(trade *dataapi.Trade, tradeHash string) map[string]string {
+	return map[string]string{
+		"trade_hash":   tradeHash,
+		"wallet":       trade.ProxyWallet,
+		"condition_id": trade.ConditionID,
+		"market_slug":  trade.Slug,
+	}
+}
+
+// deadLetter records a trade whose processing panicked
+func (p *Processor) deadLetter(ctx context.Context, trade *dataapi.Trade, tradeHash, reason string, stack []byte) {
+	if p.deadLetters == nil {
+		return
+	}
+	raw, err := json.Marshal(trade)
+	if err != nil {
+		p.log.WithError(err).Warn("Failed to encode dead-lettered trade")
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), deadLetterTimeout)
+	defer cancel()
+	err = p.deadLetters.InsertDeadLetterTrade(ctx, &storage.DeadLetterTrade{
+		TradeHash:       tradeHash,
+		TransactionHash: trade.TransactionHash,
+		ConditionID:     trade.ConditionID,
+		ProxyWallet:     trade.ProxyWallet,
+		TradeJSON:       string(raw),
+		Reason:          reason,
+		Stack:           string(stack),
+	})
+	if err != nil {
+		p.log.WithError(err).WithField("trade_hash", tradeHash).Error("Failed to dead-letter trade")
+	}
+}
+
+// resolveMarketBatchSafely runs resolveMarketBatch on a recalculation
+// worker, recovering a panic so it doesn't take down the service; a recover
+// in the goroutine that started the recalculation can't catch it. The
+// batch's markets count as missing and are checked again on the next pass.
+func (p *Processor) resolveMarketBatchSafely(ctx context.Context, batch []string) (resolved, missing int) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+		stack := debug.Stack()
+		metrics.Panics.WithLabelValues("win_rate").Inc()
+		p.log.WithFields(logrus.Fields{
+			"markets": len(batch),
+			"panic":   v,
+			"stack":   string(stack),
+		}).Error("Panic resolving market batch")
+		errreport.Capture(errreport.Event{Err: fmt.Errorf("panic: %v", v), Source: "win_rate", Stack: stack})
+		resolved, missing = 0, len(batch)
+	}()
+	return p.resolveMarketBatch(ctx, batch)
+}
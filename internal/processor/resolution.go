@@ -0,0 +1,75 @@
+package processor
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/polymarket/gammaapi"
+)
+
+// Resolution methods recorded on MarketResolution
+const (
+	resolutionMethodUMA   = "uma"             // Settled through the UMA oracle
+	resolutionMethodPrice = "price_heuristic" // Inferred from outcome prices >= 0.95
+)
+
+// splitOutcome is recorded as the winner of markets resolved 50-50 (N/A)
+const splitOutcome = "50-50"
+
+// resolveWinner returns the winning outcome and how it was determined.
+// Markets the UMA oracle has settled are resolved from their final payout
+// prices, which are exactly 1/0 or 0.5/0.5 for a 50-50 resolution. Proposed
+// or disputed markets are left unresolved until the oracle settles them. The
+// 95% price heuristic is only used when no resolution data is present.
+// An empty winner means the market can't be resolved yet.
+func (p *Processor) resolveWinner(market *gammaapi.Market) (string, string) {
+	status := strings.ToLower(strings.TrimSpace(market.UMAResolutionStatus))
+	switch status {
+	case "":
+		return p.determineWinner(market.Outcomes, market.OutcomePrices), resolutionMethodPrice
+	case "resolved":
+		return settledWinner(market.Outcomes, market.OutcomePrices), resolutionMethodUMA
+	default:
+		// proposed, disputed, etc: the outcome may still change
+		return "", resolutionMethodUMA
+	}
+}
+
+// settledWinner reads the payout prices of a settled market
+func settledWinner(outcomes, outcomePrices string) string {
+	var outcomeList []string
+	var priceList []string
+	if err := json.Unmarshal([]byte(outcomes), &outcomeList); err != nil {
+		return ""
+	}
+	if err := json.Unmarshal([]byte(outcomePrices), &priceList); err != nil {
+		return ""
+	}
+	if len(outcomeList) == 0 || len(outcomeList) != len(priceList) {
+		return ""
+	}
+
+	winner := ""
+	split := true
+	for i, priceStr := range priceList {
+		price, err := strconv.ParseFloat(strings.TrimSpace(priceStr), 64)
+		if err != nil {
+			return ""
+		}
+		if price == 1 {
+			winner = outcomeList[i]
+		}
+		if price != 0.5 {
+			split = false
+		}
+	}
+
+	if winner != "" {
+		return winner
+	}
+	if split {
+		return splitOutcome
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package processor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// resolutionScanCursorKey is the app_state key holding the last
+	// condition ID a win rate recalculation got through, so a run
+	// interrupted at shutdown resumes there instead of starting over
+	resolutionScanCursorKey = "resolution_scan_cursor"
+
+	resolutionBatchSize        = 20 // Condition IDs per Gamma request
+	resolutionProgressInterval = 30 * time.Second
+)
+
+// resolutionScan splits a recalculation's condition IDs into batches for a
+// worker pool and tracks the cursor: the last ID of the longest run of
+// finished batches from the start. Batches finish out of order, so the
+// cursor never passes one that is still in flight.
+type resolutionScan struct {
+	batches [][]string
+	total   int // Condition IDs after the cursor the scan started from
+
+	mu        sync.Mutex
+	done      []bool
+	finished  int // Leading batches that are all done
+	processed int // Condition IDs in done batches
+
+	resolved atomic.Int64
+	missing  atomic.Int64
+}
+
+// newResolutionScan batches the condition IDs after cursor in sorted order
+func newResolutionScan(conditionIDs []string, cursor string, batchSize int) *resolutionScan {
+	ids := make([]string, 0, len(conditionIDs))
+	for _, id := range conditionIDs {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	s := &resolutionScan{total: len(ids)}
+	for start := 0; start < len(ids); start += batchSize {
+		s.batches = append(s.batches, ids[start:min(start+batchSize, len(ids))])
+	}
+	s.done = make([]bool, len(s.batches))
+	return s
+}
+
+// run resolves the batches on workers in parallel, calling save with the
+// new cursor whenever it advances. Once ctx is cancelled no further batches
+// start, and a batch cut short is not counted as done. It returns ctx.Err().
+func (s *resolutionScan) run(
+	ctx context.Context,
+	workers int,
+	resolve func(ctx context.Context, batch []string) (resolved, missing int),
+	save func(cursor string),
+) error {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), len(s.batches)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					continue // Handed out as ctx was cancelled
+				}
+				resolved, missing := resolve(ctx, s.batches[i])
+				s.resolved.Add(int64(resolved))
+				s.missing.Add(int64(missing))
+				if ctx.Err() == nil {
+					s.finish(i, save)
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range s.batches {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return ctx.Err()
+}
+
+// finish marks batch i done and saves the cursor if the finished run grew.
+// Saves happen under mu so they are never reordered.
+func (s *resolutionScan) finish(i int, save func(cursor string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done[i] = true
+	s.processed += len(s.batches[i])
+	advanced := false
+	for s.finished < len(s.batches) && s.done[s.finished] {
+		s.finished++
+		advanced = true
+	}
+	if advanced {
+		batch := s.batches[s.finished-1]
+		save(batch[len(batch)-1])
+	}
+}
+
+// progress returns the condition IDs processed so far and the markets resolved
+func (s *resolutionScan) progress() (processed, resolved int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.processed, int(s.resolved.Load())
+}
@@ -0,0 +1,247 @@
+package processor
+
+import (
+	"context"
+	"slices"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// Rule is a single scoring signal. Evaluate returns the multiplier to apply to
+// the base score (1.0 when the rule does not fire) and a short human-readable
+// reason. A rule that fails is logged and treated as 1.0.
+type Rule interface {
+	Name() string
+	Evaluate(ctx context.Context, tc *TradeContext) (multiplier float64, evidence string, err error)
+}
+
+// breakdownRule is implemented by built-in rules that have a dedicated
+// multiplier field in the score breakdown
+type breakdownRule interface {
+	setBreakdown(b *alerts.ScoreBreakdown, multiplier float64)
+}
+
+// RuleLookups provides the storage and API lookups rules may need
+type RuleLookups interface {
+	VerifiedTradeCount(ctx context.Context, wallet string) (int, error)
+	TradeVelocity(ctx context.Context, wallet string, tradeTS int64) (int, error)
+	NetPositionConcentration(ctx context.Context, trade *dataapi.Trade, notional float64) (float64, error)
+	CoordinatedTrade(ctx context.Context, trade *dataapi.Trade) (bool, string, string, error)
+	ClusterMultiplier(ctx context.Context, wallet string) float64
+	RecentTradeSizes(ctx context.Context, trade *dataapi.Trade, limit int) ([]float64, error)
+	CopyTradeLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error)
+	HolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error)
+}
+
+// TradeContext bundles everything known about a trade when it is scored
+type TradeContext struct {
+	Trade             *dataapi.Trade
+	Wallet            *storage.Wallet
+	Market            *MarketInfo          // nil when the market could not be resolved
+	Stats             *storage.WalletStats // nil when the wallet has no resolved trades
+	Notional          float64
+	WalletAgeDays     int
+	HoursToClose      float64
+	IsFirstTrade      bool // Wallet had no trades before this one
+	WinRate           float64
+	FundingAgeHours   float64
+	FundingAgeMinutes float64
+	FundingAmountUSD  float64 // Size of the wallet's initial funding; 0 when unknown or not a first trade
+	PriorTrades       int     // Tracked trades before this one
+	PriorVolumeUSD    float64 // Tracked volume before this one
+	Lookups           RuleLookups
+
+	// Signal details recorded by rules for the score breakdown
+	VelocityCount       int
+	NetConcentration    float64
+	ClusterID           string
+	IsCoordinated       bool
+	CoordinationPattern string
+	SizeRatio           float64
+	CopyLeader          string
+	CopyLagMinutes      float64
+}
+
+// LiquidityRatio returns the trade size relative to market liquidity, or 0
+// when liquidity is unknown
+func (tc *TradeContext) LiquidityRatio() float64 {
+	if tc.Market == nil || tc.Market.LiquidityNum <= 0 {
+		return 0
+	}
+	return tc.Notional / tc.Market.LiquidityNum
+}
+
+// FundingUtilization returns the trade size relative to the wallet's initial
+// funding, or 0 when the funding amount is unknown
+func (tc *TradeContext) FundingUtilization() float64 {
+	if tc.FundingAmountUSD <= 0 {
+		return 0
+	}
+	return tc.Notional / tc.FundingAmountUSD
+}
+
+// defaultRules returns the built-in scoring rules, leaving out the
+// detectors the config switches off
+func defaultRules(cfg *config.Config) []Rule {
+	rules := []Rule{
+		&timeToCloseRule{cfg: cfg},
+		&winRateRule{cfg: cfg},
+		&firstTradeLargeRule{cfg: cfg},
+		&flashFundingRule{cfg: cfg},
+		&liquidityRule{cfg: cfg},
+		&priceConfidenceRule{cfg: cfg},
+		&concentrationRule{cfg: cfg},
+		&velocityRule{cfg: cfg},
+		&clusterRule{cfg: cfg},
+		&coordinatedRule{cfg: cfg},
+		&fundingAgeRule{cfg: cfg},
+		&makerRule{cfg: cfg},
+		&fundingUtilizationRule{cfg: cfg},
+		&sizeAnomalyRule{cfg: cfg},
+		&copyTradeRule{cfg: cfg},
+		&losingRecordRule{cfg: cfg},
+	}
+
+	disabled := cfg.DisabledDetectors()
+	enabled := rules[:0]
+	for _, rule := range rules {
+		if !slices.Contains(disabled, rule.Name()) {
+			enabled = append(enabled, rule)
+		}
+	}
+	return enabled
+}
+
+// scoreTrade runs every registered rule against the trade and builds the
+// score breakdown from the results
+func (p *Processor) scoreTrade(ctx context.Context, tc *TradeContext) *alerts.ScoreBreakdown {
+	breakdown := &alerts.ScoreBreakdown{
+		BaseScore:                    p.baseSuspicionScore(tc.Notional, tc.WalletAgeDays),
+		TimeToCloseMultiplier:        1.0,
+		WinRateMultiplier:            1.0,
+		FirstTradeLargeMultiplier:    1.0,
+		FlashFundingMultiplier:       1.0,
+		LiquidityMultiplier:          1.0,
+		PriceConfidenceMultiplier:    1.0,
+		ConcentrationMultiplier:      1.0,
+		VelocityMultiplier:           1.0,
+		ClusterMultiplier:            1.0,
+		CoordinatedMultiplier:        1.0,
+		FundingAgeMultiplier:         1.0,
+		MakerMultiplier:              1.0,
+		FundingUtilizationMultiplier: 1.0,
+		SizeAnomalyMultiplier:        1.0,
+		CopyTradeMultiplier:          1.0,
+		LosingRecordMultiplier:       1.0,
+		HolderDominanceMultiplier:    1.0,
+		IsMaker:                      tc.Trade.Role == dataapi.RoleMaker,
+		WinRate:                      tc.WinRate,
+		FundingAgeHours:              tc.FundingAgeHours,
+		HoursToClose:                 tc.HoursToClose,
+		LiquidityRatio:               tc.LiquidityRatio(),
+		FundingUtilization:           tc.FundingUtilization(),
+		ConcentrationWindowHrs:       p.config().ConcentrationWindowHrs,
+		DisabledRules:                p.config().DisabledDetectors(),
+	}
+	if tc.Stats != nil {
+		breakdown.ResolvedTrades = tc.Stats.TotalResolvedTrades
+	}
+
+	for _, rule := range p.activeRules() {
+		multiplier, evidence, err := rule.Evaluate(ctx, tc)
+		if err != nil {
+			p.log.WithError(err).WithField("rule", rule.Name()).Warn("Scoring rule failed")
+			continue
+		}
+
+		if setter, ok := rule.(breakdownRule); ok {
+			setter.setBreakdown(breakdown, multiplier)
+		} else if multiplier != 1.0 {
+			// Rules without a dedicated field are listed by name
+			breakdown.CustomRules = append(breakdown.CustomRules, alerts.CustomRuleResult{
+				Label:      rule.Name(),
+				Multiplier: multiplier,
+			})
+		}
+
+		if multiplier != 1.0 {
+			breakdown.Evidence = append(breakdown.Evidence, evidence)
+			breakdown.FiredRules = append(breakdown.FiredRules, rule.Name())
+			p.log.WithFields(logrus.Fields{
+				"wallet":     tc.Trade.ProxyWallet,
+				"rule":       rule.Name(),
+				"multiplier": multiplier,
+				"evidence":   evidence,
+			}).Info("Applied scoring rule")
+		}
+	}
+
+	breakdown.NetConcentration = tc.NetConcentration
+	breakdown.VelocityCount = tc.VelocityCount
+	breakdown.ClusterID = tc.ClusterID
+	breakdown.IsCoordinated = tc.IsCoordinated
+	breakdown.CoordinationPattern = tc.CoordinationPattern
+	breakdown.SizeRatio = tc.SizeRatio
+	breakdown.CopyLeader = tc.CopyLeader
+	breakdown.CopyLagMinutes = tc.CopyLagMinutes
+
+	// Final score is derived from the breakdown so the two can't drift apart
+	breakdown.FinalScore = breakdown.BaseScore * breakdown.CombinedMultiplier()
+
+	return breakdown
+}
+
+// evaluate scores a trade and assigns the severity the current thresholds
+// give it. It has no side effects beyond the rules' own lookups.
+func (p *Processor) evaluate(ctx context.Context, tc *TradeContext) (*alerts.ScoreBreakdown, alerts.Severity) {
+	breakdown := p.scoreTrade(ctx, tc)
+
+	// Normalize score to 0-100 for better UX
+	breakdown.NormalizedScore = p.normalizeScore(breakdown.FinalScore)
+
+	// Checked last: it needs the score so far to decide whether to call the API
+	p.applyHolderDominance(ctx, tc, breakdown)
+
+	return breakdown, p.determineSeverity(breakdown.NormalizedScore)
+}
+
+// processorLookups adapts the processor's storage and API helpers to RuleLookups
+type processorLookups struct {
+	p *Processor
+}
+
+func (l processorLookups) VerifiedTradeCount(ctx context.Context, wallet string) (int, error) {
+	return l.p.verifiedTradeCount(ctx, wallet)
+}
+
+func (l processorLookups) TradeVelocity(ctx context.Context, wallet string, tradeTS int64) (int, error) {
+	return l.p.checkTradeVelocity(ctx, wallet, tradeTS)
+}
+
+func (l processorLookups) NetPositionConcentration(ctx context.Context, trade *dataapi.Trade, notional float64) (float64, error) {
+	return l.p.checkNetPositionConcentration(ctx, trade.ProxyWallet, trade.ConditionID, l.p.calculateTradeHash(trade), trade.Timestamp, notional, trade.Side)
+}
+
+func (l processorLookups) CoordinatedTrade(ctx context.Context, trade *dataapi.Trade) (bool, string, string, error) {
+	return l.p.detectCoordinatedTrade(ctx, trade, trade.ProxyWallet)
+}
+
+func (l processorLookups) ClusterMultiplier(ctx context.Context, wallet string) float64 {
+	return l.p.getClusterMultiplier(ctx, wallet)
+}
+
+func (l processorLookups) RecentTradeSizes(ctx context.Context, trade *dataapi.Trade, limit int) ([]float64, error) {
+	return l.p.db.GetRecentTradeSizes(ctx, trade.ProxyWallet, l.p.calculateTradeHash(trade), limit)
+}
+
+func (l processorLookups) CopyTradeLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error) {
+	return l.p.findCopyLeader(ctx, trade)
+}
+
+func (l processorLookups) HolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error) {
+	return l.p.walletHolderRank(ctx, trade, tokenID)
+}
@@ -0,0 +1,26 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// clusterRule boosts wallets that share a funding source with other wallets
+type clusterRule struct {
+	cfg *config.Config
+}
+
+func (r *clusterRule) Name() string { return "cluster" }
+
+func (r *clusterRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if !r.cfg.EnableClusterDetection {
+		return 1.0, "", nil
+	}
+	return tc.Lookups.ClusterMultiplier(ctx, tc.Trade.ProxyWallet), "part of a wallet cluster sharing a funding source", nil
+}
+
+func (r *clusterRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.ClusterMultiplier = multiplier
+}
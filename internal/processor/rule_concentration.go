@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// concentrationRule flags one-sided positioning in a market
+type concentrationRule struct {
+	cfg *config.Config
+}
+
+func (r *concentrationRule) Name() string { return "concentration" }
+
+func (r *concentrationRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	concentration, err := tc.Lookups.NetPositionConcentration(ctx, tc.Trade, tc.Notional)
+	if err != nil {
+		return 1.0, "", fmt.Errorf("check net position concentration: %w", err)
+	}
+	tc.NetConcentration = concentration
+
+	rule := r.cfg.Detection.Concentration
+	if concentration <= rule.MinShare { // By default 90%+ on one side
+		return 1.0, "", nil
+	}
+	return rule.Multiplier, fmt.Sprintf("%.0f%% of market volume on one side", concentration*100), nil
+}
+
+func (r *concentrationRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.ConcentrationMultiplier = multiplier
+}
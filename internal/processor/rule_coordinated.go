@@ -0,0 +1,44 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// coordinatedRule flags trades placed alongside other wallets in the same
+// cluster. Opposing-side coordination (hedging across sibling wallets) has its
+// own multiplier since its meaning differs from a same-side pile-in.
+type coordinatedRule struct {
+	cfg *config.Config
+}
+
+func (r *coordinatedRule) Name() string { return "coordinated" }
+
+func (r *coordinatedRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if !r.cfg.EnableClusterDetection {
+		return 1.0, "", nil
+	}
+
+	isCoordinated, pattern, clusterID, err := tc.Lookups.CoordinatedTrade(ctx, tc.Trade)
+	if err != nil {
+		return 1.0, "", fmt.Errorf("detect coordinated trade: %w", err)
+	}
+	tc.IsCoordinated = isCoordinated
+	tc.CoordinationPattern = pattern
+	tc.ClusterID = clusterID
+
+	if !isCoordinated {
+		return 1.0, "", nil
+	}
+	if pattern == alerts.CoordinationOpposing {
+		return r.cfg.OpposingCoordinatedMultiplier, fmt.Sprintf("cluster %s took opposite sides of this market", clusterID), nil
+	}
+	return r.cfg.Detection.Coordinated.SameSideMultiplier, fmt.Sprintf("cluster %s traded this market together", clusterID), nil
+}
+
+func (r *coordinatedRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.CoordinatedMultiplier = multiplier
+}
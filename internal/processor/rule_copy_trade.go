@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// copyTradeRule flags a trade that mirrors a recently flagged wallet: same
+// market, same direction, shortly after. Followers are often the same actor's
+// other wallets, or accounts tailing a wallet that looks informed.
+type copyTradeRule struct {
+	cfg *config.Config
+}
+
+func (r *copyTradeRule) Name() string { return "copy_trade" }
+
+func (r *copyTradeRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if !r.cfg.EnableCopyTradeDetection {
+		return 1.0, "", nil
+	}
+
+	leader, err := tc.Lookups.CopyTradeLeader(ctx, tc.Trade)
+	if err != nil {
+		return 1.0, "", fmt.Errorf("find copy trade leader: %w", err)
+	}
+	if leader == nil {
+		return 1.0, "", nil
+	}
+
+	tc.CopyLeader = leader.ProxyWallet
+	tc.CopyLagMinutes = float64(tc.Trade.Timestamp-leader.TimestampSec) / 60.0
+	return r.cfg.CopyTradeMultiplier, fmt.Sprintf("mirrored flagged wallet %s %.0f minutes later", shortenAddress(leader.ProxyWallet), tc.CopyLagMinutes), nil
+}
+
+func (r *copyTradeRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.CopyTradeMultiplier = multiplier
+}
+
+// findCopyLeader returns the most recent trade by a flagged wallet that the
+// given trade mirrors, or nil. Nothing is recorded; see recordCopyLink.
+func (p *Processor) findCopyLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error) {
+	flaggedSinceTS := p.clock.Now().AddDate(0, 0, -p.config().CopyTradeFlaggedDays).Unix()
+	sinceTS := trade.Timestamp - int64(p.config().CopyTradeWindowMins*60)
+
+	candidates, err := p.db.GetFlaggedWalletTrades(ctx, trade.ConditionID, string(alerts.SeverityAlert), flaggedSinceTS, sinceTS, trade.Timestamp)
+	if err != nil {
+		return nil, err
+	}
+	return pickCopyLeader(trade, candidates), nil
+}
+
+// recordCopyLink stores a soft link from a processed trade's wallet to the
+// flagged wallet it mirrored. Scoring only finds the leader, so scoring a
+// trade outside the live pipeline leaves no link behind.
+func (p *Processor) recordCopyLink(ctx context.Context, trade *dataapi.Trade, breakdown *alerts.ScoreBreakdown) {
+	now := p.clock.Now().Unix()
+	link := &storage.WalletCopyLink{
+		FollowerWallet:  trade.ProxyWallet,
+		LeaderWallet:    breakdown.CopyLeader,
+		LastConditionID: trade.ConditionID,
+		CopyCount:       1,
+		FirstSeenTS:     now,
+		LastSeenTS:      now,
+	}
+	if err := p.db.RecordCopyLink(ctx, link); err != nil {
+		p.log.WithError(err).Warn("Failed to record copy trade link")
+		return
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"follower":     trade.ProxyWallet,
+		"leader":       breakdown.CopyLeader,
+		"condition_id": trade.ConditionID,
+		"lag_min":      breakdown.CopyLagMinutes,
+	}).Info("Copy trade of flagged wallet detected")
+}
+
+// pickCopyLeader returns the latest candidate trade by another wallet in the
+// same direction as trade. Candidates are ordered oldest first.
+func pickCopyLeader(trade *dataapi.Trade, candidates []storage.TradeSeen) *storage.TradeSeen {
+	var leader *storage.TradeSeen
+	for i := range candidates {
+		t := &candidates[i]
+		if strings.EqualFold(t.ProxyWallet, trade.ProxyWallet) {
+			continue // A flagged wallet trading again is not copying itself
+		}
+		if sameDirection(trade.Outcome, trade.Side, t.Outcome, t.Side) {
+			leader = t
+		}
+	}
+	return leader
+}
@@ -0,0 +1,55 @@
+package processor
+
+import (
+	"context"
+
+	"github.com/liamashdown/insiderwatch/internal/rules"
+)
+
+// customRule adapts a user-defined threshold rule from CUSTOM_RULES_FILE
+type customRule struct {
+	rule *rules.Rule
+}
+
+func (r *customRule) Name() string { return r.rule.Label }
+
+func (r *customRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	vars := rules.Vars{
+		"notional":        tc.Notional,
+		"price":           tc.Trade.Price,
+		"wallet_age_days": float64(tc.WalletAgeDays),
+		"hours_to_close":  tc.HoursToClose,
+		"liquidity_ratio": tc.LiquidityRatio(),
+		"win_rate":        tc.WinRate,
+		"velocity_count":  float64(tc.VelocityCount),
+	}
+	if !r.rule.Match(vars) {
+		return 1.0, "", nil
+	}
+	return r.rule.Multiplier, r.rule.When, nil
+}
+
+// SetCustomRules replaces the user-defined rules. Safe to call while trades
+// are being processed, e.g. when the rules file is reloaded on SIGHUP.
+// Custom rules run after the built-in rules so they can see signal details
+// such as the velocity count.
+func (p *Processor) SetCustomRules(compiled []*rules.Rule) {
+	custom := make([]Rule, 0, len(compiled))
+	for _, r := range compiled {
+		custom = append(custom, &customRule{rule: r})
+	}
+
+	p.rulesMu.Lock()
+	p.customRules = custom
+	p.rulesMu.Unlock()
+}
+
+// activeRules returns the built-in rules followed by the custom rules
+func (p *Processor) activeRules() []Rule {
+	p.rulesMu.RLock()
+	defer p.rulesMu.RUnlock()
+
+	active := make([]Rule, 0, len(p.rules)+len(p.customRules))
+	active = append(active, p.rules...)
+	return append(active, p.customRules...)
+}
@@ -0,0 +1,138 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// firstTradeMaxAPITrades is the most trades the Data API may show for a
+// trade to still count as the wallet's first
+const firstTradeMaxAPITrades = 2
+
+// verificationCacheTTL is how long an API trade count is reused. Several big
+// trades from one wallet in the same poll would otherwise each spend an
+// activity request.
+const verificationCacheTTL = 10 * time.Minute
+
+// firstTradeLargeRule boosts a wallet whose very first trade is large.
+// Local tracking is the primary signal; for very large trades the Data API
+// is consulted to confirm the wallet really has no earlier history.
+type firstTradeLargeRule struct {
+	cfg *config.Config
+}
+
+func (r *firstTradeLargeRule) Name() string { return "first_trade_large" }
+
+func (r *firstTradeLargeRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if !tc.IsFirstTrade || tc.Notional < r.cfg.MinTradeUSD {
+		return 1.0, "", nil
+	}
+
+	// Lower amount, just use local tracking
+	if tc.Notional < r.cfg.MinTradeUSD*2 {
+		return r.cfg.Detection.FirstTrade.Multiplier, "first trade is large", nil
+	}
+
+	// Only verify very suspicious cases via the API to avoid rate limits
+	tradeCount, err := tc.Lookups.VerifiedTradeCount(ctx, tc.Trade.ProxyWallet)
+	if err != nil {
+		// API failed, fall back to local tracking
+		return r.cfg.Detection.FirstTrade.Multiplier, "first trade is very large (locally tracked)", nil
+	}
+
+	// If API confirms <= 2 trades, this is definitely a first large trade
+	if tradeCount <= firstTradeMaxAPITrades {
+		return r.cfg.Detection.FirstTrade.Multiplier, fmt.Sprintf("first trade is very large (API shows %d trades)", tradeCount), nil
+	}
+
+	return 1.0, "", nil
+}
+
+func (r *firstTradeLargeRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.FirstTradeLargeMultiplier = multiplier
+}
+
+// tradeCountCache remembers recent API trade counts per wallet
+type tradeCountCache struct {
+	mu      sync.Mutex
+	entries map[string]tradeCountEntry
+}
+
+type tradeCountEntry struct {
+	count     int
+	checkedAt time.Time
+}
+
+func (c *tradeCountCache) get(wallet string, now time.Time) (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[wallet]
+	if !ok {
+		return 0, false
+	}
+	if now.Sub(entry.checkedAt) > verificationCacheTTL {
+		delete(c.entries, wallet)
+		return 0, false
+	}
+	return entry.count, true
+}
+
+func (c *tradeCountCache) set(wallet string, count int, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]tradeCountEntry)
+	}
+	// Drop expired entries so the map doesn't grow without bound
+	for w, entry := range c.entries {
+		if now.Sub(entry.checkedAt) > verificationCacheTTL {
+			delete(c.entries, w)
+		}
+	}
+	c.entries[wallet] = tradeCountEntry{count: count, checkedAt: now}
+}
+
+// verifiedTradeCount returns how many trades the Data API shows for a
+// wallet. The API is skipped when the answer is already known: a recent
+// cached count, or local tracking that already shows more trades than a
+// first trade allows (concurrent trades from one wallet all see an empty
+// wallet record when they start).
+func (p *Processor) verifiedTradeCount(ctx context.Context, walletAddress string) (int, error) {
+	now := p.clock.Now()
+	if count, ok := p.tradeCounts.get(walletAddress, now); ok {
+		metrics.FirstTradeVerificationsSaved.WithLabelValues("cache").Inc()
+		return count, nil
+	}
+
+	wallet, err := p.db.GetWallet(ctx, walletAddress)
+	if err == nil && wallet != nil && wallet.TotalTrades > firstTradeMaxAPITrades {
+		metrics.FirstTradeVerificationsSaved.WithLabelValues("local").Inc()
+		return wallet.TotalTrades, nil
+	}
+
+	return p.apiTradeCount(ctx, walletAddress, now)
+}
+
+// apiTradeCount counts a wallet's trades in its recent Data API activity
+// and caches the count. Failures are not cached.
+func (p *Processor) apiTradeCount(ctx context.Context, walletAddress string, now time.Time) (int, error) {
+	activity, err := p.dataClient.GetWalletActivity(ctx, walletAddress, 10)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, act := range activity {
+		if act.Type == "TRADE" {
+			count++
+		}
+	}
+	p.tradeCounts.set(walletAddress, count, now)
+	return count, nil
+}
@@ -0,0 +1,28 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// flashFundingRule flags wallets funded and trading within minutes
+type flashFundingRule struct {
+	cfg *config.Config
+}
+
+func (r *flashFundingRule) Name() string { return "flash_funding" }
+
+func (r *flashFundingRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	rule := r.cfg.Detection.FlashFunding
+	if tc.FundingAgeMinutes <= 0 || tc.FundingAgeMinutes > rule.WindowMinutes {
+		return 1.0, "", nil
+	}
+	return rule.Multiplier, fmt.Sprintf("funded %.1f minutes before first trade", tc.FundingAgeMinutes), nil
+}
+
+func (r *flashFundingRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.FlashFundingMultiplier = multiplier
+}
@@ -0,0 +1,31 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// fundingAgeRule boosts wallets that started trading within a day (by
+// default) of receiving funds
+type fundingAgeRule struct {
+	cfg *config.Config
+}
+
+func (r *fundingAgeRule) Name() string { return "funding_age" }
+
+func (r *fundingAgeRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	rule := r.cfg.Detection.FundingAge
+	if tc.FundingAgeHours <= 0 || tc.FundingAgeHours > rule.WindowHours {
+		return 1.0, "", nil
+	}
+	// By default 1 hour = 2.5x, 12 hours = 1.75x, 24 hours = 1.0x
+	multiplier := 1.0 + (rule.WindowHours-tc.FundingAgeHours)/rule.WindowHours*rule.MaxBoost
+	return multiplier, fmt.Sprintf("first trade %.1fh after funding", tc.FundingAgeHours), nil
+}
+
+func (r *fundingAgeRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.FundingAgeMultiplier = multiplier
+}
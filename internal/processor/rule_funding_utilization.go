@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// fundingUtilizationRule flags a first trade that bets (nearly) the whole
+// initial funding. Funding a fresh wallet with exactly the amount it
+// immediately bets is a strong sign the wallet exists for this one trade.
+type fundingUtilizationRule struct {
+	cfg *config.Config
+}
+
+func (r *fundingUtilizationRule) Name() string { return "funding_utilization" }
+
+func (r *fundingUtilizationRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	utilization := tc.FundingUtilization()
+	if !tc.IsFirstTrade || utilization == 0 || utilization < r.cfg.FundingUtilizationThreshold {
+		return 1.0, "", nil
+	}
+	return r.cfg.FundingUtilizationMultiplier, fmt.Sprintf("bet %.0f%% of its initial funding", utilization*100), nil
+}
+
+func (r *fundingUtilizationRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.FundingUtilizationMultiplier = multiplier
+}
@@ -0,0 +1,30 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// liquidityRule boosts trades that are large relative to market liquidity
+type liquidityRule struct {
+	cfg *config.Config
+}
+
+func (r *liquidityRule) Name() string { return "liquidity" }
+
+func (r *liquidityRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	ratio := tc.LiquidityRatio()
+	// By default 5% = 1.2x, 10% = 1.5x, 20% = 2.0x, 50%+ = 3.0x
+	multiplier := r.cfg.Detection.Liquidity.LiquidityMultiplier(ratio)
+	if multiplier == 1.0 {
+		return 1.0, "", nil
+	}
+	return multiplier, fmt.Sprintf("trade is %.0f%% of market liquidity", ratio*100), nil
+}
+
+func (r *liquidityRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.LiquidityMultiplier = multiplier
+}
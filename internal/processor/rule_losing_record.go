@@ -0,0 +1,36 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// losingRecordRule discounts wallets with a proven losing record. A wallet
+// that keeps losing is more likely a gambler than an insider, so its big bets
+// score below an unknown wallet's. The discount scales from 1.0 at the win
+// rate threshold down to the configured floor at a 0% win rate.
+type losingRecordRule struct {
+	cfg *config.Config
+}
+
+func (r *losingRecordRule) Name() string { return "losing_record" }
+
+func (r *losingRecordRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if tc.Stats == nil || r.cfg.LosingRecordMaxWinRate <= 0 {
+		return 1.0, "", nil
+	}
+	if tc.Stats.TotalResolvedTrades < r.cfg.LosingRecordMinTrades || tc.WinRate >= r.cfg.LosingRecordMaxWinRate {
+		return 1.0, "", nil
+	}
+
+	floor := r.cfg.LosingRecordFloor
+	multiplier := floor + (1.0-floor)*(tc.WinRate/r.cfg.LosingRecordMaxWinRate)
+	return multiplier, fmt.Sprintf("losing record: %.0f%% wins over %d resolved trades", tc.WinRate*100, tc.Stats.TotalResolvedTrades), nil
+}
+
+func (r *losingRecordRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.LosingRecordMultiplier = multiplier
+}
@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+)
+
+// makerRule discounts maker fills. A resting limit order that gets filled is
+// a weaker signal than a taker crossing the spread, so makers score lower.
+type makerRule struct {
+	cfg *config.Config
+}
+
+func (r *makerRule) Name() string { return "maker_fill" }
+
+func (r *makerRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if tc.Trade.Role != dataapi.RoleMaker {
+		return 1.0, "", nil
+	}
+	return r.cfg.MakerScoreMultiplier, fmt.Sprintf("maker fill at %.2f", tc.Trade.Price), nil
+}
+
+func (r *makerRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.MakerMultiplier = multiplier
+}
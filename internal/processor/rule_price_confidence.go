@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// priceConfidenceRule flags trades at extreme prices, where the trader is
+// either very confident or betting on a long shot
+type priceConfidenceRule struct {
+	cfg *config.Config
+}
+
+func (r *priceConfidenceRule) Name() string { return "price_confidence" }
+
+func (r *priceConfidenceRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	rule := r.cfg.Detection.PriceConfidence
+	if tc.Trade.Price < rule.High && tc.Trade.Price > rule.Low {
+		return 1.0, "", nil
+	}
+	return rule.Multiplier, fmt.Sprintf("%s at extreme price %.2f", tc.Trade.Side, tc.Trade.Price), nil
+}
+
+func (r *priceConfidenceRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.PriceConfidenceMultiplier = multiplier
+}
@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// sizeAnomalyWindow is how many recent trades the usual trade size is taken from
+const sizeAnomalyWindow = 50
+
+// sizeAnomalyRule flags a trade far larger than the wallet usually trades.
+// An old wallet that suddenly bets 100x its normal size is suspicious even
+// though its age alone looks harmless.
+type sizeAnomalyRule struct {
+	cfg *config.Config
+}
+
+func (r *sizeAnomalyRule) Name() string { return "size_anomaly" }
+
+func (r *sizeAnomalyRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if tc.PriorTrades < r.cfg.SizeAnomalyMinTrades {
+		return 1.0, "", nil
+	}
+
+	// Median of recent trades resists a few earlier outliers; fall back to
+	// the lifetime average when the window is short
+	var usual float64
+	sizes, err := tc.Lookups.RecentTradeSizes(ctx, tc.Trade, sizeAnomalyWindow)
+	if err != nil {
+		return 1.0, "", fmt.Errorf("get recent trade sizes: %w", err)
+	}
+	if len(sizes) >= r.cfg.SizeAnomalyMinTrades {
+		usual = median(sizes)
+	} else {
+		usual = tc.PriorVolumeUSD / float64(tc.PriorTrades)
+	}
+	if usual <= 0 {
+		return 1.0, "", nil
+	}
+
+	ratio := tc.Notional / usual
+	tc.SizeRatio = ratio
+
+	// 10x = multiplier, then one more step each at 50x and 100x
+	step := r.cfg.SizeAnomalyMultiplier - 1.0
+	var multiplier float64
+	switch {
+	case ratio >= 100:
+		multiplier = 1.0 + 3*step
+	case ratio >= 50:
+		multiplier = 1.0 + 2*step
+	case ratio >= 10:
+		multiplier = 1.0 + step
+	default:
+		return 1.0, "", nil
+	}
+	return multiplier, fmt.Sprintf("%.0fx its usual trade size of $%.0f", ratio, usual), nil
+}
+
+func (r *sizeAnomalyRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.SizeAnomalyMultiplier = multiplier
+}
+
+// median returns the median of values, which must not be empty
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
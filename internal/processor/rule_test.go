@@ -0,0 +1,1008 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/clock"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/rules"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// stubLookups returns canned answers for the lookups rules depend on
+type stubLookups struct {
+	activity      []dataapi.ActivityEvent
+	activityErr   error
+	velocity      int
+	velocityErr   error
+	concentration float64
+	concErr       error
+	coordinated   bool
+	pattern       string
+	clusterID     string
+	coordErr      error
+	clusterMult   float64
+	tradeSizes    []float64
+	sizesErr      error
+	copyLeader    *storage.TradeSeen
+	holderRank    int
+	holderShare   float64
+}
+
+func (s *stubLookups) VerifiedTradeCount(ctx context.Context, wallet string) (int, error) {
+	if s.activityErr != nil {
+		return 0, s.activityErr
+	}
+	count := 0
+	for _, act := range s.activity {
+		if act.Type == "TRADE" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *stubLookups) TradeVelocity(ctx context.Context, wallet string, tradeTS int64) (int, error) {
+	return s.velocity, s.velocityErr
+}
+
+func (s *stubLookups) NetPositionConcentration(ctx context.Context, trade *dataapi.Trade, notional float64) (float64, error) {
+	return s.concentration, s.concErr
+}
+
+func (s *stubLookups) CoordinatedTrade(ctx context.Context, trade *dataapi.Trade) (bool, string, string, error) {
+	if s.coordErr != nil {
+		return false, "", "", s.coordErr
+	}
+	return s.coordinated, s.pattern, s.clusterID, nil
+}
+
+func (s *stubLookups) ClusterMultiplier(ctx context.Context, wallet string) float64 {
+	if s.clusterMult == 0 {
+		return 1.0
+	}
+	return s.clusterMult
+}
+
+func (s *stubLookups) RecentTradeSizes(ctx context.Context, trade *dataapi.Trade, limit int) ([]float64, error) {
+	return s.tradeSizes, s.sizesErr
+}
+
+func (s *stubLookups) CopyTradeLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error) {
+	return s.copyLeader, nil
+}
+
+func (s *stubLookups) HolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error) {
+	return s.holderRank, s.holderShare, nil
+}
+
+func testRuleConfig() *config.Config {
+	return &config.Config{
+		MinTradeUSD:                    10000,
+		TimeToCloseHoursMax:            48,
+		MinWinRateThreshold:            0.75,
+		EnableVelocityDetection:        true,
+		EnableFirstTradeDetection:      true,
+		EnableFlashFundingDetection:    true,
+		EnableFundingAgeDetection:      true,
+		EnableLiquidityDetection:       true,
+		EnablePriceConfidenceDetection: true,
+		EnableConcentrationDetection:   true,
+		EnableCoordinatedDetection:     true,
+		EnableWinRateDetection:         true,
+		VelocityWindowMinutes:          10,
+		VelocityThreshold:              3,
+		EnableClusterDetection:         true,
+		OpposingCoordinatedMultiplier:  1.5,
+		FundingUtilizationThreshold:    0.9,
+		ConcentrationWindowHrs:         6,
+		FundingUtilizationMultiplier:   2.0,
+		SizeAnomalyMultiplier:          1.5,
+		SizeAnomalyMinTrades:           5,
+		EnableCopyTradeDetection:       true,
+		CopyTradeMultiplier:            1.8,
+		LosingRecordMinTrades:          20,
+		LosingRecordMaxWinRate:         0.3,
+		LosingRecordFloor:              0.5,
+		EnableHolderDominance:          true,
+		HolderDominanceMinShare:        0.2,
+		HolderDominanceMultiplier:      1.5,
+		Detection:                      config.DefaultDetection(),
+	}
+}
+
+func TestFirstTradeLargeRule(t *testing.T) {
+	rule := &firstTradeLargeRule{cfg: testRuleConfig()}
+
+	tests := []struct {
+		name               string
+		isFirstTrade       bool
+		notional           float64
+		lookups            *stubLookups
+		expectedMultiplier float64
+		description        string
+	}{
+		{
+			name:               "not first trade",
+			isFirstTrade:       false,
+			notional:           50000,
+			lookups:            &stubLookups{},
+			expectedMultiplier: 1.0,
+			description:        "Only a wallet's first trade qualifies",
+		},
+		{
+			name:               "first trade below double minimum",
+			isFirstTrade:       true,
+			notional:           15000,
+			lookups:            &stubLookups{activityErr: errors.New("should not be called")},
+			expectedMultiplier: 2.0,
+			description:        "Smaller first trades rely on local tracking only",
+		},
+		{
+			name:               "API confirms new wallet",
+			isFirstTrade:       true,
+			notional:           50000,
+			lookups:            &stubLookups{activity: []dataapi.ActivityEvent{{Type: "TRADE"}, {Type: "SPLIT"}}},
+			expectedMultiplier: 2.0,
+			description:        "API showing <= 2 trades confirms the first trade",
+		},
+		{
+			name:               "API shows history",
+			isFirstTrade:       true,
+			notional:           50000,
+			lookups:            &stubLookups{activity: []dataapi.ActivityEvent{{Type: "TRADE"}, {Type: "TRADE"}, {Type: "TRADE"}}},
+			expectedMultiplier: 1.0,
+			description:        "Wallet with earlier trades on-chain is not new",
+		},
+		{
+			name:               "API failure falls back",
+			isFirstTrade:       true,
+			notional:           50000,
+			lookups:            &stubLookups{activityErr: errors.New("timeout")},
+			expectedMultiplier: 2.0,
+			description:        "API errors fall back to local tracking",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{
+				Trade:        &dataapi.Trade{ProxyWallet: "0xabc"},
+				IsFirstTrade: tt.isFirstTrade,
+				Notional:     tt.notional,
+				Lookups:      tt.lookups,
+			}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier {
+				t.Errorf("got %.1f, want %.1f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+func TestTradeCountCache(t *testing.T) {
+	var cache tradeCountCache
+	now := time.Now()
+
+	if _, ok := cache.get("0xabc", now); ok {
+		t.Fatal("empty cache should miss")
+	}
+	cache.set("0xabc", 1, now)
+	if count, ok := cache.get("0xabc", now.Add(time.Minute)); !ok || count != 1 {
+		t.Errorf("got (%d, %v), want (1, true) within the TTL", count, ok)
+	}
+	if _, ok := cache.get("0xabc", now.Add(verificationCacheTTL+time.Second)); ok {
+		t.Error("entry should expire after the TTL")
+	}
+}
+
+func TestVelocityRule(t *testing.T) {
+	tests := []struct {
+		name               string
+		velocity           int
+		enabled            bool
+		expectedMultiplier float64
+		description        string
+	}{
+		{"below threshold", 2, true, 1.0, "Fewer trades than the threshold are normal"},
+		{"at threshold", 3, true, 1.5, "Threshold reached gets 1.5x"},
+		{"five trades", 5, true, 2.0, "5+ trades gets 2x"},
+		{"ten trades", 10, true, 3.0, "10+ trades gets 3x"},
+		{"disabled", 10, false, 1.0, "Disabled detection never fires"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testRuleConfig()
+			cfg.EnableVelocityDetection = tt.enabled
+			rule := &velocityRule{cfg: cfg}
+			tc := &TradeContext{
+				Trade:   &dataapi.Trade{ProxyWallet: "0xabc"},
+				Lookups: &stubLookups{velocity: tt.velocity},
+			}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier {
+				t.Errorf("got %.1f, want %.1f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+func TestCoordinatedRule(t *testing.T) {
+	tests := []struct {
+		name               string
+		lookups            *stubLookups
+		expectedMultiplier float64
+		description        string
+	}{
+		{
+			name:               "not coordinated",
+			lookups:            &stubLookups{},
+			expectedMultiplier: 1.0,
+			description:        "No cluster activity on this market",
+		},
+		{
+			name:               "same side",
+			lookups:            &stubLookups{coordinated: true, pattern: alerts.CoordinationSameSide, clusterID: "c1"},
+			expectedMultiplier: 2.0,
+			description:        "Same-side pile-in gets 2x",
+		},
+		{
+			name:               "opposing",
+			lookups:            &stubLookups{coordinated: true, pattern: alerts.CoordinationOpposing, clusterID: "c1"},
+			expectedMultiplier: 1.5,
+			description:        "Opposing sides use the configured multiplier",
+		},
+	}
+
+	rule := &coordinatedRule{cfg: testRuleConfig()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{Trade: &dataapi.Trade{ProxyWallet: "0xabc"}, Lookups: tt.lookups}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier {
+				t.Errorf("got %.1f, want %.1f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+			if tc.IsCoordinated != tt.lookups.coordinated || tc.ClusterID != tt.lookups.clusterID {
+				t.Errorf("trade context not updated: coordinated=%v cluster=%q", tc.IsCoordinated, tc.ClusterID)
+			}
+		})
+	}
+}
+
+func TestFundingUtilizationRule(t *testing.T) {
+	rule := &fundingUtilizationRule{cfg: testRuleConfig()}
+
+	tests := []struct {
+		name               string
+		isFirstTrade       bool
+		notional           float64
+		fundingAmount      float64
+		expectedMultiplier float64
+		expectedEvidence   string
+		description        string
+	}{
+		{"bets nearly all funding", true, 9700, 10000, 2.0, "bet 97% of its initial funding", "First trade using 97% of funding is flagged"},
+		{"bets more than funding", true, 12000, 10000, 2.0, "bet 120% of its initial funding", "Topped-up wallets can exceed the initial amount"},
+		{"below threshold", true, 5000, 10000, 1.0, "", "Using half the funding is not flagged"},
+		{"not first trade", false, 9700, 10000, 1.0, "", "Only a wallet's first trade qualifies"},
+		{"unknown funding", true, 9700, 0, 1.0, "", "Missing funding amount is neutral"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{
+				Trade:            &dataapi.Trade{ProxyWallet: "0xabc"},
+				IsFirstTrade:     tt.isFirstTrade,
+				Notional:         tt.notional,
+				FundingAmountUSD: tt.fundingAmount,
+			}
+			got, evidence, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier || evidence != tt.expectedEvidence {
+				t.Errorf("got %.1f %q, want %.1f %q\nDescription: %s", got, evidence, tt.expectedMultiplier, tt.expectedEvidence, tt.description)
+			}
+		})
+	}
+}
+
+func TestSizeAnomalyRule(t *testing.T) {
+	rule := &sizeAnomalyRule{cfg: testRuleConfig()}
+	usual := []float64{200, 250, 180, 220, 5000}
+
+	tests := []struct {
+		name               string
+		notional           float64
+		priorTrades        int
+		priorVolume        float64
+		lookups            *stubLookups
+		expectedMultiplier float64
+		expectedRatio      float64
+		description        string
+	}{
+		{"too few prior trades", 40000, 1, 200, &stubLookups{tradeSizes: []float64{200}}, 1.0, 0, "A second-ever trade is never anomalous"},
+		{"normal size", 1000, 5, 5850, &stubLookups{tradeSizes: usual}, 1.0, 1000.0 / 220, "Below 10x the median is not flagged"},
+		{"10x", 3000, 5, 5850, &stubLookups{tradeSizes: usual}, 1.5, 3000.0 / 220, "10x the median is the first tier"},
+		{"50x", 12000, 5, 5850, &stubLookups{tradeSizes: usual}, 2.0, 12000.0 / 220, "50x adds another step"},
+		{"100x", 40000, 5, 5850, &stubLookups{tradeSizes: usual}, 2.5, 40000.0 / 220, "100x is the top tier; the $5000 outlier doesn't skew the median"},
+		{"short window uses average", 20000, 10, 20000, &stubLookups{tradeSizes: []float64{2000}}, 1.5, 10, "Falls back to lifetime average when few trades are stored"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{
+				Trade:          &dataapi.Trade{ProxyWallet: "0xabc"},
+				Notional:       tt.notional,
+				PriorTrades:    tt.priorTrades,
+				PriorVolumeUSD: tt.priorVolume,
+				Lookups:        tt.lookups,
+			}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier || tc.SizeRatio != tt.expectedRatio {
+				t.Errorf("got %.2f (ratio %.2f), want %.2f (ratio %.2f)\nDescription: %s",
+					got, tc.SizeRatio, tt.expectedMultiplier, tt.expectedRatio, tt.description)
+			}
+		})
+	}
+}
+
+func TestCopyTradeRule(t *testing.T) {
+	rule := &copyTradeRule{cfg: testRuleConfig()}
+	trade := &dataapi.Trade{ProxyWallet: "0xfollower", Side: "BUY", Outcome: "Yes", Timestamp: 10000}
+
+	tests := []struct {
+		name               string
+		candidates         []storage.TradeSeen
+		expectedMultiplier float64
+		expectedLeader     string
+		description        string
+	}{
+		{"no flagged trades", nil, 1.0, "", "Nothing to copy"},
+		{
+			"same side",
+			[]storage.TradeSeen{{ProxyWallet: "0xleader", Side: "BUY", Outcome: "Yes", TimestampSec: 9400}},
+			1.8, "0xleader", "Buying what a flagged wallet just bought",
+		},
+		{
+			"equivalent side",
+			[]storage.TradeSeen{{ProxyWallet: "0xleader", Side: "SELL", Outcome: "No", TimestampSec: 9400}},
+			1.8, "0xleader", "Selling No is the same bet as buying Yes",
+		},
+		{
+			"opposite side",
+			[]storage.TradeSeen{{ProxyWallet: "0xleader", Side: "BUY", Outcome: "No", TimestampSec: 9400}},
+			1.0, "", "Betting against a flagged wallet is not copying it",
+		},
+		{
+			"own trade",
+			[]storage.TradeSeen{{ProxyWallet: "0xFOLLOWER", Side: "BUY", Outcome: "Yes", TimestampSec: 9400}},
+			1.0, "", "A flagged wallet adding to its own position is not a copy",
+		},
+		{
+			"closest leader",
+			[]storage.TradeSeen{
+				{ProxyWallet: "0xearly", Side: "BUY", Outcome: "Yes", TimestampSec: 8500},
+				{ProxyWallet: "0xlate", Side: "BUY", Outcome: "Yes", TimestampSec: 9700},
+				{ProxyWallet: "0xagainst", Side: "SELL", Outcome: "Yes", TimestampSec: 9900},
+			},
+			1.8, "0xlate", "The most recent same-side leader is reported",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{
+				Trade:   trade,
+				Lookups: &stubLookups{copyLeader: pickCopyLeader(trade, tt.candidates)},
+			}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier || tc.CopyLeader != tt.expectedLeader {
+				t.Errorf("got %.2f (leader %q), want %.2f (leader %q)\nDescription: %s",
+					got, tc.CopyLeader, tt.expectedMultiplier, tt.expectedLeader, tt.description)
+			}
+		})
+	}
+}
+
+func TestLosingRecordRule(t *testing.T) {
+	rule := &losingRecordRule{cfg: testRuleConfig()}
+
+	tests := []struct {
+		name               string
+		stats              *storage.WalletStats
+		expectedMultiplier float64
+		description        string
+	}{
+		{"no stats", nil, 1.0, "Unknown wallets are not discounted"},
+		{"small sample", &storage.WalletStats{TotalResolvedTrades: 10, WinRate: 0}, 1.0, "Ten straight losses is not yet a meaningful sample"},
+		{"average record", &storage.WalletStats{TotalResolvedTrades: 40, WinRate: 0.5}, 1.0, "Coin-flip records are neutral"},
+		{"at threshold", &storage.WalletStats{TotalResolvedTrades: 40, WinRate: 0.3}, 1.0, "The threshold itself is not discounted"},
+		{"losing record", &storage.WalletStats{TotalResolvedTrades: 20, WinRate: 0.15}, 0.75, "Halfway to zero is halfway to the floor"},
+		{"never wins", &storage.WalletStats{TotalResolvedTrades: 25, WinRate: 0}, 0.5, "A 0% win rate gets the floor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{Trade: &dataapi.Trade{ProxyWallet: "0xabc"}, Stats: tt.stats, Lookups: &stubLookups{}}
+			if tt.stats != nil {
+				tc.WinRate = tt.stats.WinRate
+			}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.expectedMultiplier) > 1e-9 {
+				t.Errorf("got %.2f, want %.2f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+func TestConcentrationRuleError(t *testing.T) {
+	rule := &concentrationRule{cfg: testRuleConfig()}
+	tc := &TradeContext{
+		Trade:   &dataapi.Trade{ProxyWallet: "0xabc"},
+		Lookups: &stubLookups{concErr: errors.New("db down")},
+	}
+	if _, _, err := rule.Evaluate(context.Background(), tc); err == nil {
+		t.Error("expected lookup error to be returned")
+	}
+	if tc.NetConcentration != 0 {
+		t.Errorf("concentration should stay 0 on error, got %.2f", tc.NetConcentration)
+	}
+}
+
+func TestTimeToCloseRule(t *testing.T) {
+	rule := &timeToCloseRule{cfg: testRuleConfig()}
+
+	tests := []struct {
+		name               string
+		hoursToClose       float64
+		expectedMultiplier float64
+		description        string
+	}{
+		{"unknown close", 0, 1.0, "Markets without an end date get no boost"},
+		{"past the window", 72, 1.0, "Markets closing after TIME_TO_CLOSE_HOURS_MAX get no boost"},
+		{"window edge", 48, 1.0, "The boost starts at 1.0x at the edge of the window"},
+		{"one day", 24, 3.0, "Halfway through the window gives half the boost"},
+		{"twelve hours", 12, 4.0, "Three quarters of the way gives three quarters of the boost"},
+		{"closing now", 0.5, 1.0 + 47.5/48*4.0, "The boost approaches its maximum at close"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{Trade: &dataapi.Trade{ProxyWallet: "0xabc"}, HoursToClose: tt.hoursToClose, Lookups: &stubLookups{}}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(got-tt.expectedMultiplier) > 1e-9 {
+				t.Errorf("got %.4f, want %.4f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+func TestClusterRule(t *testing.T) {
+	tests := []struct {
+		name               string
+		enabled            bool
+		clusterMult        float64
+		expectedMultiplier float64
+		description        string
+	}{
+		{"no cluster", true, 0, 1.0, "Wallets outside a cluster get no boost"},
+		{"small cluster", true, 1.5, 1.5, "The cluster's size multiplier is applied"},
+		{"large cluster", true, 3.0, 3.0, "Larger clusters boost more"},
+		{"disabled", false, 3.0, 1.0, "ENABLE_CLUSTER_DETECTION=false skips the lookup"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testRuleConfig()
+			cfg.EnableClusterDetection = tt.enabled
+			rule := &clusterRule{cfg: cfg}
+			tc := &TradeContext{Trade: &dataapi.Trade{ProxyWallet: "0xabc"}, Lookups: &stubLookups{clusterMult: tt.clusterMult}}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier {
+				t.Errorf("got %.1f, want %.1f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+func TestMakerRule(t *testing.T) {
+	cfg := testRuleConfig()
+	cfg.MakerScoreMultiplier = 0.8
+	rule := &makerRule{cfg: cfg}
+
+	tests := []struct {
+		name               string
+		role               string
+		expectedMultiplier float64
+		description        string
+	}{
+		{"taker", dataapi.RoleTaker, 1.0, "Taker trades are scored as they are"},
+		{"unknown role", "", 1.0, "Trades from the public feed carry no role and count as takers"},
+		{"maker", dataapi.RoleMaker, 0.8, "Maker fills are damped by MAKER_SCORE_MULTIPLIER"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := &TradeContext{Trade: &dataapi.Trade{ProxyWallet: "0xabc", Role: tt.role, Price: 0.4}, Lookups: &stubLookups{}}
+			got, _, err := rule.Evaluate(context.Background(), tc)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.expectedMultiplier {
+				t.Errorf("got %.1f, want %.1f\nDescription: %s", got, tt.expectedMultiplier, tt.description)
+			}
+		})
+	}
+}
+
+// legacyBreakdown is the scoring block processTrade used before the rule
+// engine, kept verbatim (minus logging) to prove the engine is compatible
+func legacyBreakdown(p *Processor, tc *TradeContext, l *stubLookups) *alerts.ScoreBreakdown {
+	trade := tc.Trade
+	notional := tc.Notional
+	marketInfo := tc.Market
+	walletStats := tc.Stats
+	winRate := tc.WinRate
+	fundingAgeHours := tc.FundingAgeHours
+	fundingAgeMinutes := tc.FundingAgeMinutes
+	hoursToClose := tc.HoursToClose
+
+	baseScore := p.baseSuspicionScore(notional, tc.WalletAgeDays)
+	timeToCloseMultiplier := p.timeToCloseMultiplier(hoursToClose)
+
+	var firstTradeLargeMultiplier float64 = 1.0
+	if tc.IsFirstTrade && notional >= p.cfg.MinTradeUSD {
+		if notional >= p.cfg.MinTradeUSD*2 {
+			activity, err := l.activity, l.activityErr
+			if err == nil {
+				tradeCount := 0
+				for _, act := range activity {
+					if act.Type == "TRADE" {
+						tradeCount++
+					}
+				}
+				if tradeCount <= 2 {
+					firstTradeLargeMultiplier = 2.0
+				}
+			} else {
+				firstTradeLargeMultiplier = 2.0
+			}
+		} else {
+			firstTradeLargeMultiplier = 2.0
+		}
+	}
+
+	var flashFundingMultiplier float64 = 1.0
+	if fundingAgeMinutes > 0 && fundingAgeMinutes <= 5 {
+		flashFundingMultiplier = 3.0
+	}
+
+	var velocityCount int
+	var velocityMultiplier float64 = 1.0
+	if p.cfg.EnableVelocityDetection {
+		var err error
+		velocityCount, err = l.TradeVelocity(context.Background(), trade.ProxyWallet, trade.Timestamp)
+		if err == nil && velocityCount >= p.cfg.VelocityThreshold {
+			if velocityCount >= 10 {
+				velocityMultiplier = 3.0
+			} else if velocityCount >= 5 {
+				velocityMultiplier = 2.0
+			} else {
+				velocityMultiplier = 1.5
+			}
+		}
+	}
+
+	var liquidityMultiplier float64 = 1.0
+	if marketInfo != nil && marketInfo.LiquidityNum > 0 {
+		liquidityRatio := notional / marketInfo.LiquidityNum
+		if liquidityRatio > 0.05 {
+			if liquidityRatio >= 0.50 {
+				liquidityMultiplier = 3.0
+			} else if liquidityRatio >= 0.20 {
+				liquidityMultiplier = 2.0
+			} else if liquidityRatio >= 0.10 {
+				liquidityMultiplier = 1.5
+			} else {
+				liquidityMultiplier = 1.2
+			}
+		}
+	}
+
+	var priceConfidenceMultiplier float64 = 1.0
+	if trade.Price >= 0.85 || trade.Price <= 0.15 {
+		priceConfidenceMultiplier = 1.5
+	}
+
+	var concentrationMultiplier float64 = 1.0
+	netPosConcentration, err := l.NetPositionConcentration(context.Background(), trade, notional)
+	if err == nil && netPosConcentration > 0.90 {
+		concentrationMultiplier = 1.5
+	}
+
+	var isCoordinated bool
+	var coordinationPattern string
+	var clusterID string
+	var clusterMultiplier float64 = 1.0
+	if p.cfg.EnableClusterDetection {
+		isCoordinated, coordinationPattern, clusterID, _ = l.CoordinatedTrade(context.Background(), trade)
+		clusterMultiplier = l.ClusterMultiplier(context.Background(), trade.ProxyWallet)
+	}
+
+	breakdown := &alerts.ScoreBreakdown{
+		BaseScore:                    baseScore,
+		TimeToCloseMultiplier:        timeToCloseMultiplier,
+		WinRateMultiplier:            1.0,
+		FirstTradeLargeMultiplier:    firstTradeLargeMultiplier,
+		FlashFundingMultiplier:       flashFundingMultiplier,
+		LiquidityMultiplier:          liquidityMultiplier,
+		PriceConfidenceMultiplier:    priceConfidenceMultiplier,
+		ConcentrationMultiplier:      concentrationMultiplier,
+		VelocityMultiplier:           velocityMultiplier,
+		ClusterMultiplier:            clusterMultiplier,
+		CoordinatedMultiplier:        1.0,
+		FundingAgeMultiplier:         1.0,
+		MakerMultiplier:              1.0, // Maker fills were not fetched before, so neutral
+		FundingUtilizationMultiplier: 1.0, // Funding amounts were not recorded before, so neutral
+		SizeAnomalyMultiplier:        1.0, // Fixtures have no prior trades
+		CopyTradeMultiplier:          1.0, // Flagged wallet trades were not checked before, so neutral
+		LosingRecordMultiplier:       1.0, // Fixtures have too few resolved trades
+		HolderDominanceMultiplier:    1.0, // Holders were not fetched before, so neutral
+		WinRate:                      winRate,
+		FundingAgeHours:              fundingAgeHours,
+		HoursToClose:                 hoursToClose,
+		NetConcentration:             netPosConcentration,
+		ConcentrationWindowHrs:       p.cfg.ConcentrationWindowHrs,
+		VelocityCount:                velocityCount,
+		ClusterID:                    clusterID,
+		IsCoordinated:                isCoordinated,
+	}
+	if walletStats != nil {
+		breakdown.ResolvedTrades = walletStats.TotalResolvedTrades
+	}
+	if marketInfo != nil && marketInfo.LiquidityNum > 0 {
+		breakdown.LiquidityRatio = notional / marketInfo.LiquidityNum
+	}
+	if walletStats != nil && walletStats.TotalResolvedTrades >= 5 && winRate >= p.cfg.MinWinRateThreshold {
+		breakdown.WinRateMultiplier = 1.0 + winRate
+	}
+	if isCoordinated {
+		breakdown.CoordinatedMultiplier = 2.0
+		if coordinationPattern == alerts.CoordinationOpposing {
+			breakdown.CoordinatedMultiplier = p.cfg.OpposingCoordinatedMultiplier
+		}
+		breakdown.CoordinationPattern = coordinationPattern
+	}
+	if fundingAgeHours > 0 && fundingAgeHours <= 24 {
+		breakdown.FundingAgeMultiplier = 1.0 + (24.0-fundingAgeHours)/24.0*1.5
+	}
+	breakdown.FinalScore = breakdown.BaseScore * breakdown.CombinedMultiplier()
+	return breakdown
+}
+
+func TestRuleEngineMatchesLegacyScoring(t *testing.T) {
+	fixtures := []struct {
+		name    string
+		tc      TradeContext
+		lookups *stubLookups
+	}{
+		{
+			name: "plain old wallet far from close",
+			tc: TradeContext{
+				Trade:         &dataapi.Trade{ProxyWallet: "0x1", Price: 0.5, Side: "BUY", Timestamp: 1700000000},
+				Notional:      12000,
+				WalletAgeDays: 200,
+				HoursToClose:  500,
+			},
+			lookups: &stubLookups{velocity: 1, concentration: 0.6},
+		},
+		{
+			name: "fresh wallet stacking every signal",
+			tc: TradeContext{
+				Trade:             &dataapi.Trade{ProxyWallet: "0x2", Price: 0.92, Side: "BUY", Timestamp: 1700000000},
+				Market:            &MarketInfo{LiquidityNum: 80000},
+				Stats:             &storage.WalletStats{TotalResolvedTrades: 8, WinRate: 0.875},
+				Notional:          47321.77,
+				WalletAgeDays:     0,
+				HoursToClose:      7.3,
+				IsFirstTrade:      true,
+				WinRate:           0.875,
+				FundingAgeHours:   0.05,
+				FundingAgeMinutes: 3,
+			},
+			lookups: &stubLookups{
+				activity:      []dataapi.ActivityEvent{{Type: "TRADE"}},
+				velocity:      6,
+				concentration: 0.97,
+				coordinated:   true,
+				pattern:       alerts.CoordinationSameSide,
+				clusterID:     "cluster-a",
+				clusterMult:   2.0,
+			},
+		},
+		{
+			name: "opposing cluster with lookup failures",
+			tc: TradeContext{
+				Trade:           &dataapi.Trade{ProxyWallet: "0x3", Price: 0.11, Side: "SELL", Timestamp: 1700000000},
+				Market:          &MarketInfo{LiquidityNum: 150000},
+				Stats:           &storage.WalletStats{TotalResolvedTrades: 3, WinRate: 1.0},
+				Notional:        20500.5,
+				WalletAgeDays:   3,
+				HoursToClose:    30.25,
+				WinRate:         1.0,
+				FundingAgeHours: 13.7,
+			},
+			lookups: &stubLookups{
+				velocityErr: errors.New("db down"),
+				concErr:     errors.New("db down"),
+				coordinated: true,
+				pattern:     alerts.CoordinationOpposing,
+				clusterID:   "cluster-b",
+				clusterMult: 1.5,
+			},
+		},
+		{
+			name: "API history and coordination error",
+			tc: TradeContext{
+				Trade:         &dataapi.Trade{ProxyWallet: "0x4", Price: 0.33, Side: "BUY", Timestamp: 1700000000},
+				Market:        &MarketInfo{LiquidityNum: 9000},
+				Notional:      33333.33,
+				WalletAgeDays: 1,
+				HoursToClose:  47.9,
+				IsFirstTrade:  true,
+			},
+			lookups: &stubLookups{
+				activity:      []dataapi.ActivityEvent{{Type: "TRADE"}, {Type: "TRADE"}, {Type: "TRADE"}},
+				velocity:      11,
+				concentration: 0.905,
+				coordErr:      errors.New("db down"),
+			},
+		},
+	}
+
+	cfg := testRuleConfig()
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := &Processor{cfg: cfg, log: log, rules: defaultRules(cfg), clock: clock.Real{}}
+
+	for _, fx := range fixtures {
+		t.Run(fx.name, func(t *testing.T) {
+			legacyTC := fx.tc
+			want := legacyBreakdown(p, &legacyTC, fx.lookups)
+
+			tc := fx.tc
+			tc.Lookups = fx.lookups
+			got := p.scoreTrade(context.Background(), &tc)
+
+			// Evidence and the rule names are new with the rule engine and
+			// have no legacy counterpart
+			got.Evidence, got.FiredRules = nil, nil
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("breakdown mismatch\ngot:  %+v\nwant: %+v", *got, *want)
+			}
+			if got.FinalScore != want.FinalScore {
+				t.Errorf("final score got %v, want %v (must be bit-for-bit identical)", got.FinalScore, want.FinalScore)
+			}
+		})
+	}
+}
+
+func TestCustomRulesInBreakdown(t *testing.T) {
+	compiled, err := rules.Compile([]rules.Definition{
+		{When: "price >= 0.9 and notional >= 25000", Multiplier: 2.0, Label: "heavy favorite pile-in"},
+		{When: "velocity_count >= 5", Multiplier: 1.25, Label: "burst"},
+		{When: "hours_to_close < 1", Multiplier: 3.0, Label: "never fires"},
+	})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	cfg := testRuleConfig()
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := &Processor{cfg: cfg, log: log, rules: defaultRules(cfg), clock: clock.Real{}}
+
+	tc := TradeContext{
+		Trade:         &dataapi.Trade{ProxyWallet: "0x1", Price: 0.95, Side: "BUY"},
+		Notional:      30000,
+		WalletAgeDays: 100,
+		HoursToClose:  500,
+		Lookups:       &stubLookups{velocity: 6},
+	}
+	baseline := tc
+	want := p.scoreTrade(context.Background(), &baseline).FinalScore * 2.0 * 1.25
+
+	p.SetCustomRules(compiled)
+	got := p.scoreTrade(context.Background(), &tc)
+
+	expected := []alerts.CustomRuleResult{
+		{Label: "heavy favorite pile-in", Multiplier: 2.0},
+		{Label: "burst", Multiplier: 1.25},
+	}
+	if !reflect.DeepEqual(got.CustomRules, expected) {
+		t.Errorf("custom rules got %+v, want %+v", got.CustomRules, expected)
+	}
+	if got.FinalScore != want {
+		t.Errorf("final score got %v, want %v", got.FinalScore, want)
+	}
+}
+
+func TestSimulateScore(t *testing.T) {
+	cfg := testRuleConfig()
+	cfg.SuspicionScoreWarn = 70
+	cfg.SuspicionScoreAlert = 85
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := &Processor{cfg: cfg, log: log, rules: defaultRules(cfg), clock: clock.Real{}}
+
+	tests := []struct {
+		name             string
+		input            ScoreInput
+		expectedFinal    float64
+		expectedSeverity alerts.Severity
+		description      string
+	}{
+		{
+			"late favorite",
+			ScoreInput{Notional: 40000, Price: 0.92, WalletAgeDays: 2, HoursToClose: 6},
+			40000.0 / 2 * 4.5 * 1.5, alerts.SeverityAlert,
+			"Time to close and extreme price stack on the base score",
+		},
+		{
+			"lookup signals",
+			ScoreInput{Notional: 20000, Price: 0.5, WalletAgeDays: 10, VelocityCount: 5, ClusterSize: 5, Coordinated: alerts.CoordinationOpposing},
+			20000.0 / 10 * 2.0 * 2.0 * 1.5, alerts.SeverityInfo,
+			"Velocity, cluster and coordination come from the input instead of storage",
+		},
+		{
+			"losing record",
+			ScoreInput{Notional: 20000, Price: 0.5, WalletAgeDays: 10, WinRate: 0, ResolvedTrades: 30},
+			20000.0 / 10 * 0.5, alerts.SeverityInfo,
+			"Resolved trades make the win rate count",
+		},
+		{
+			"dominant holder",
+			ScoreInput{Notional: 40000, Price: 0.92, WalletAgeDays: 2, HoursToClose: 6, HolderRank: 1, HolderShare: 0.4},
+			40000.0 / 2 * 4.5 * 1.5 * 1.5, alerts.SeverityAlert,
+			"A top holder of the outcome is boosted once the score reaches WARN",
+		},
+		{
+			"minor holder",
+			ScoreInput{Notional: 40000, Price: 0.92, WalletAgeDays: 2, HoursToClose: 6, HolderRank: 2, HolderShare: 0.1},
+			40000.0 / 2 * 4.5 * 1.5, alerts.SeverityAlert,
+			"A small share of the top holders' balance is not dominance",
+		},
+		{
+			"holder below warn",
+			ScoreInput{Notional: 20000, Price: 0.5, WalletAgeDays: 10, HolderRank: 1, HolderShare: 0.9},
+			20000.0 / 10, alerts.SeverityInfo,
+			"Low scoring trades skip the holder lookup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := p.SimulateScore(context.Background(), tt.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if math.Abs(result.Breakdown.FinalScore-tt.expectedFinal) > 1e-6 || result.Severity != tt.expectedSeverity {
+				t.Errorf("got %.2f (%s), want %.2f (%s)\nDescription: %s",
+					result.Breakdown.FinalScore, result.Severity, tt.expectedFinal, tt.expectedSeverity, tt.description)
+			}
+		})
+	}
+
+	for _, bad := range []ScoreInput{
+		{Notional: 0, Price: 0.5},
+		{Notional: 1000, Price: 1.2},
+		{Notional: 1000, Price: 0.5, Side: "HOLD"},
+		{Notional: 1000, Price: 0.5, Coordinated: "sideways"},
+	} {
+		if _, err := p.SimulateScore(context.Background(), bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestDisabledDetectors(t *testing.T) {
+	cfg := testRuleConfig()
+	cfg.EnablePriceConfidenceDetection = false
+	cfg.EnableClusterDetection = false
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	p := &Processor{cfg: cfg, log: log, rules: defaultRules(cfg), clock: clock.Real{}}
+
+	for _, rule := range p.rules {
+		switch rule.Name() {
+		case "price_confidence", "cluster", "coordinated":
+			t.Errorf("got %s registered, want disabled detectors left out", rule.Name())
+		}
+	}
+
+	input := ScoreInput{Notional: 40000, Price: 0.92, WalletAgeDays: 2, HoursToClose: 6, ClusterSize: 5, Coordinated: alerts.CoordinationSameSide}
+	result, err := p.SimulateScore(context.Background(), input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := result.Breakdown
+	if want := 40000.0 / 2 * 4.5; math.Abs(b.FinalScore-want) > 1e-6 {
+		t.Errorf("got %.2f, want %.2f: only time to close should apply", b.FinalScore, want)
+	}
+	if b.PriceConfidenceMultiplier != 1.0 || b.ClusterMultiplier != 1.0 || b.CoordinatedMultiplier != 1.0 {
+		t.Errorf("got %+v, want the disabled multipliers left neutral", b)
+	}
+	if !reflect.DeepEqual(b.FiredRules, []string{"time_to_close"}) {
+		t.Errorf("got fired rules %v, want only time_to_close", b.FiredRules)
+	}
+	if !reflect.DeepEqual(b.DisabledRules, []string{"price_confidence", "cluster", "coordinated"}) {
+		t.Errorf("got disabled rules %v, want the switched off detectors", b.DisabledRules)
+	}
+}
+
+func TestHolderRank(t *testing.T) {
+	holders := []dataapi.Holder{
+		{ProxyWallet: "0xaaa", Amount: 100},
+		{ProxyWallet: "0xbbb", Amount: 500},
+		{ProxyWallet: "0xccc", Amount: 400},
+	}
+
+	tests := []struct {
+		name          string
+		holders       []dataapi.Holder
+		wallet        string
+		expectedRank  int
+		expectedShare float64
+		description   string
+	}{
+		{"largest", holders, "0xbbb", 1, 0.5, "Holders are ranked by balance, not list order"},
+		{"case insensitive", holders, "0xCCC", 2, 0.4, "Addresses match regardless of case"},
+		{"smallest", holders, "0xaaa", 3, 0.1, "The share is of the listed holders' combined balance"},
+		{"not listed", holders, "0xddd", 0, 0, "A wallet outside the top holders has no rank"},
+		{"empty", nil, "0xaaa", 0, 0, "No holders means no rank"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rank, share := holderRank(tt.holders, tt.wallet)
+			if rank != tt.expectedRank || math.Abs(share-tt.expectedShare) > 1e-9 {
+				t.Errorf("got rank %d share %.2f, want rank %d share %.2f\nDescription: %s",
+					rank, share, tt.expectedRank, tt.expectedShare, tt.description)
+			}
+		})
+	}
+}
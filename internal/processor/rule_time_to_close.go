@@ -0,0 +1,34 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// timeToCloseRule boosts trades placed shortly before the market closes
+type timeToCloseRule struct {
+	cfg *config.Config
+}
+
+func (r *timeToCloseRule) Name() string { return "time_to_close" }
+
+func (r *timeToCloseRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	return r.multiplier(tc.HoursToClose), fmt.Sprintf("market closes in %.1fh", tc.HoursToClose), nil
+}
+
+func (r *timeToCloseRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.TimeToCloseMultiplier = multiplier
+}
+
+func (r *timeToCloseRule) multiplier(hoursToClose float64) float64 {
+	if hoursToClose <= 0 || hoursToClose > float64(r.cfg.TimeToCloseHoursMax) {
+		return 1.0
+	}
+
+	// Closer to close = higher multiplier; by default 48 hours = 1.0x,
+	// 24 hours = 3x, 12 hours = 4x, 1 hour = ~5x
+	return 1.0 + (float64(r.cfg.TimeToCloseHoursMax)-hoursToClose)/float64(r.cfg.TimeToCloseHoursMax)*r.cfg.Detection.TimeToClose.MaxBoost
+}
@@ -0,0 +1,40 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// velocityRule flags rapid successive trades from the same wallet
+type velocityRule struct {
+	cfg *config.Config
+}
+
+func (r *velocityRule) Name() string { return "velocity" }
+
+func (r *velocityRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if !r.cfg.EnableVelocityDetection {
+		return 1.0, "", nil
+	}
+
+	count, err := tc.Lookups.TradeVelocity(ctx, tc.Trade.ProxyWallet, tc.Trade.Timestamp)
+	if err != nil {
+		return 1.0, "", fmt.Errorf("check trade velocity: %w", err)
+	}
+	tc.VelocityCount = count
+
+	if count < r.cfg.VelocityThreshold {
+		return 1.0, "", nil
+	}
+
+	// By default 3 trades = 1.5x, 5 trades = 2.0x, 10+ = 3.0x
+	multiplier := r.cfg.Detection.Velocity.VelocityMultiplier(count)
+	return multiplier, fmt.Sprintf("%d trades in %d minutes", count, r.cfg.VelocityWindowMinutes), nil
+}
+
+func (r *velocityRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.VelocityMultiplier = multiplier
+}
@@ -0,0 +1,29 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+)
+
+// winRateRule boosts wallets with a proven record of picking winners.
+// Only applied with a sufficient sample size (5+ resolved trades by default).
+type winRateRule struct {
+	cfg *config.Config
+}
+
+func (r *winRateRule) Name() string { return "win_rate" }
+
+func (r *winRateRule) Evaluate(ctx context.Context, tc *TradeContext) (float64, string, error) {
+	if tc.Stats == nil || tc.Stats.TotalResolvedTrades < r.cfg.Detection.WinRate.MinResolvedTrades || tc.WinRate < r.cfg.MinWinRateThreshold {
+		return 1.0, "", nil
+	}
+	// High win rate increases suspicion
+	return 1.0 + tc.WinRate, fmt.Sprintf("win rate %.0f%% over %d resolved trades", tc.WinRate*100, tc.Stats.TotalResolvedTrades), nil
+}
+
+func (r *winRateRule) setBreakdown(b *alerts.ScoreBreakdown, multiplier float64) {
+	b.WinRateMultiplier = multiplier
+}
@@ -0,0 +1,808 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+)
+
+// seedMarkets are the markets `insiderwatch seed` creates. Closed markets
+// ended during the seeded week and have resolved; sports markets are cached
+// but, like live sports trades, never traded.
+var seedMarkets = []struct {
+	title    string
+	category string
+	closed   bool
+}{
+	{"Will the incumbent win the presidential election?", "Politics", false},
+	{"Will the Senate pass the budget bill before the recess?", "Politics", false},
+	{"Will the Fed cut rates at the next meeting?", "Economics", false},
+	{"Will Bitcoin close above $100k on Friday?", "Crypto", false},
+	{"Will the merger be approved by regulators?", "Business", false},
+	{"Will the launch happen before the end of the month?", "Science", false},
+	{"Will the film win Best Picture?", "Entertainment", false},
+	{"Will the prime minister call a snap election?", "Politics", false},
+	{"Will the central bank raise rates?", "Economics", true},
+	{"Will the governor be indicted?", "Politics", true},
+	{"Will Ethereum ETF flows turn positive this week?", "Crypto", true},
+	{"Will the company beat earnings estimates?", "Business", true},
+	{"Will the treaty be signed at the summit?", "Politics", true},
+	{"Will the home side win the final?", "Sports", false},
+	{"Will the champion retain the title?", "Sports", false},
+	{"Will the team make the playoffs?", "Sports", true},
+}
+
+// Seeded wallet kinds, by how long the wallet had been active
+const (
+	seedFresh       = "fresh"       // Days old, funded just before trading
+	seedYoung       = "young"       // Weeks to months
+	seedEstablished = "established" // Months to years
+	seedVeteran     = "veteran"     // Older than NEW_WALLET_DAYS_MAX
+	seedSharp       = "sharp"       // Young, trades every closed market and mostly wins
+)
+
+// SeedOptions controls GenerateSeed
+type SeedOptions struct {
+	Seed    int64     // Same seed and options, same data
+	Wallets int       // Wallets to create, including cluster members
+	Now     time.Time // The seeded week ends here
+}
+
+// SeedData is the synthetic data `insiderwatch seed` writes. Trades cover
+// the week before SeedOptions.Now and every alert is what the current
+// scoring makes of its trade, so the read APIs and dashboard show the same
+// shapes as a live deployment.
+type SeedData struct {
+	Markets           []storage.MarketMap
+	Resolutions       []storage.MarketResolution
+	Wallets           []storage.Wallet
+	FundingSources    []storage.WalletFundingSource
+	Clusters          []storage.WalletCluster
+	ClusterMembers    []storage.WalletClusterMember
+	Trades            []storage.TradeSeen // Oldest first
+	CoordinatedTrades []storage.CoordinatedTrade
+	Results           []storage.WalletMarketResult
+	Stats             []storage.WalletStats
+	Alerts            []storage.Alert // Oldest first
+	CheckpointTS      int64           // Poll checkpoint: the newest trade
+}
+
+// seedWallet is a wallet being generated and what it has traded so far
+type seedWallet struct {
+	wallet    *storage.Wallet
+	kind      string
+	cluster   *storage.WalletCluster
+	funding   *storage.WalletFundingSource
+	lastAlert int64 // Creation time of its latest notified alert
+}
+
+// seedGenerator holds the state of one GenerateSeed run
+type seedGenerator struct {
+	p       *Processor
+	rng     *rand.Rand
+	now     int64
+	start   int64 // Now minus a week
+	markets []*storage.MarketMap
+	wallets []*seedWallet
+	data    *SeedData
+}
+
+// GenerateSeed builds deterministic synthetic data for demos and
+// development: wallets of every age, trades across the seeded markets,
+// clusters whose members trade together, resolved markets with the win
+// rates they produce, and the alerts the current configuration and custom
+// rules give each trade. Nothing is written; see WriteSeed.
+func (p *Processor) GenerateSeed(ctx context.Context, opts SeedOptions) (*SeedData, error) {
+	if opts.Wallets < 20 {
+		return nil, fmt.Errorf("at least 20 wallets are needed, got %d", opts.Wallets)
+	}
+	g := &seedGenerator{
+		p:     p,
+		rng:   rand.New(rand.NewSource(opts.Seed)),
+		now:   opts.Now.Unix(),
+		start: opts.Now.Add(-7 * 24 * time.Hour).Unix(),
+		data:  &SeedData{},
+	}
+
+	g.generateMarkets()
+	g.generateWallets(opts.Wallets)
+	g.generateTrades()
+	g.generateCoordinatedTrades()
+	sort.SliceStable(g.data.Trades, func(i, j int) bool { return g.data.Trades[i].TimestampSec < g.data.Trades[j].TimestampSec })
+	g.resolveMarkets()
+	if err := g.scoreTrades(ctx); err != nil {
+		return nil, err
+	}
+	g.finishClusters()
+
+	for _, w := range g.wallets {
+		g.data.Wallets = append(g.data.Wallets, *w.wallet)
+	}
+	if n := len(g.data.Trades); n > 0 {
+		g.data.CheckpointTS = g.data.Trades[n-1].TimestampSec
+	}
+	return g.data, nil
+}
+
+// hexID returns a random 0x-prefixed hex string of n bytes
+func (g *seedGenerator) hexID(n int) string {
+	b := make([]byte, n)
+	g.rng.Read(b)
+	return "0x" + hex.EncodeToString(b)
+}
+
+// between returns a random value in [lo, hi)
+func (g *seedGenerator) between(lo, hi float64) float64 {
+	return lo + g.rng.Float64()*(hi-lo)
+}
+
+func (g *seedGenerator) generateMarkets() {
+	for _, m := range seedMarkets {
+		slug := strings.Join(strings.FieldsFunc(strings.ToLower(m.title), func(r rune) bool {
+			return (r < 'a' || r > 'z') && (r < '0' || r > '9')
+		}), "-")
+		conditionID := g.hexID(32)
+
+		// Closed markets ended during the week, with a day of trading at
+		// least; open ones end from hours to months out
+		endDate := g.now + int64(g.between(6, 60*24)*3600)
+		if m.closed {
+			endDate = g.start + int64(g.between(36, 150)*3600)
+		}
+		tokenIDs := map[string]string{"Yes": g.hexID(16), "No": g.hexID(16)}
+
+		market := &storage.MarketMap{
+			ConditionID:     conditionID,
+			MarketSlug:      slug,
+			MarketTitle:     m.title,
+			MarketURL:       fmt.Sprintf("https://polymarket.com/market/%s", slug),
+			Category:        m.category,
+			EndDate:         endDate,
+			EndDateSource:   endDateSourceMarket,
+			VolumeNum:       math.Round(g.between(2e5, 2e7)),
+			LiquidityNum:    math.Round(g.between(8e4, 4e6)),
+			OutcomeTokenIDs: encodeTokenIDs(tokenIDs),
+			IsActive:        !m.closed,
+			UpdatedTS:       g.now,
+		}
+		g.data.Markets = append(g.data.Markets, *market)
+		if normalizeCategory(m.category, slug) != categorySports {
+			g.markets = append(g.markets, market)
+		}
+	}
+}
+
+func (g *seedGenerator) generateWallets(n int) {
+	// Two clusters: fresh wallets from one faucet, and young ones from
+	// another that also trade against each other
+	clusterSizes := []int{6, 4}
+	for c, size := range clusterSizes {
+		source := g.hexID(20)
+		cluster := &storage.WalletCluster{
+			ClusterID:     fmt.Sprintf("cluster_%x", sha256.Sum256([]byte(source))),
+			FundingSource: source,
+			LinkType:      storage.LinkFunding,
+			WalletCount:   size,
+			FirstSeenTS:   g.now,
+		}
+		kind := seedFresh
+		if c == 1 {
+			kind = seedYoung
+		}
+		for i := 0; i < size; i++ {
+			w := g.newWallet(kind, true)
+			w.cluster = cluster
+			w.funding.FundingSource = source
+			if w.funding.FundingTS < cluster.FirstSeenTS {
+				cluster.FirstSeenTS = w.funding.FundingTS
+			}
+			g.data.ClusterMembers = append(g.data.ClusterMembers, storage.WalletClusterMember{
+				WalletAddress: w.wallet.WalletAddress,
+				ClusterID:     cluster.ClusterID,
+				LinkType:      storage.LinkFunding,
+				CreatedTS:     w.funding.FundingTS,
+			})
+		}
+		g.data.Clusters = append(g.data.Clusters, *cluster)
+	}
+
+	for len(g.wallets) < n {
+		var kind string
+		switch r := g.rng.Float64(); {
+		case r < 0.03:
+			kind = seedSharp
+		case r < 0.13:
+			kind = seedFresh
+		case r < 0.43:
+			kind = seedYoung
+		case r < 0.83:
+			kind = seedEstablished
+		default:
+			kind = seedVeteran
+		}
+		g.newWallet(kind, false)
+	}
+
+	for _, w := range g.wallets {
+		if w.funding != nil {
+			g.data.FundingSources = append(g.data.FundingSources, *w.funding)
+		}
+	}
+}
+
+// newWallet creates a wallet of the given kind, with its funding when
+// funded is set or by chance
+func (g *seedGenerator) newWallet(kind string, funded bool) *seedWallet {
+	var ageDays float64
+	switch kind {
+	case seedFresh:
+		ageDays = g.between(0, 3)
+	case seedYoung, seedSharp:
+		ageDays = g.between(8, 120)
+	case seedEstablished:
+		ageDays = g.between(120, float64(g.p.config().NewWalletDaysMax))
+	default:
+		ageDays = float64(g.p.config().NewWalletDaysMax) + g.between(30, 1200)
+	}
+	firstSeen := g.now - int64(ageDays*86400)
+
+	w := &seedWallet{
+		kind: kind,
+		wallet: &storage.Wallet{
+			WalletAddress:  g.hexID(20),
+			FirstSeenTS:    firstSeen,
+			LastActivityTS: firstSeen,
+			UpdatedTS:      g.now,
+		},
+	}
+
+	// Funding is known for most wallets: fresh ones were funded minutes to
+	// hours before their first trade
+	if funded || kind == seedFresh || g.rng.Float64() < 0.6 {
+		fundingAge := g.between(24, 24*30) * 3600
+		if kind == seedFresh {
+			fundingAge = g.between(2, 600) * 60
+		}
+		w.wallet.FundingReceivedTS = firstSeen - int64(fundingAge)
+		w.funding = &storage.WalletFundingSource{
+			WalletAddress: w.wallet.WalletAddress,
+			FundingSource: g.hexID(20),
+			FundingTS:     w.wallet.FundingReceivedTS,
+			AmountUSD:     math.Round(g.between(5e3, 3e5)),
+			TxHash:        g.hexID(32),
+			CreatedTS:     g.now,
+		}
+	}
+	g.wallets = append(g.wallets, w)
+	return w
+}
+
+// openMarket returns a random market still trading at ts
+func (g *seedGenerator) openMarket(ts int64) *storage.MarketMap {
+	var open []*storage.MarketMap
+	for _, m := range g.markets {
+		if m.EndDate > ts {
+			open = append(open, m)
+		}
+	}
+	return open[g.rng.Intn(len(open))]
+}
+
+// notional returns a trade size from MIN_TRADE_USD up, mostly small with a
+// long tail of whales
+func (g *seedGenerator) notional() float64 {
+	minTrade := g.p.config().MinTradeUSD
+	return math.Round(math.Min(minTrade*math.Exp(g.rng.ExpFloat64()*0.9), 400000))
+}
+
+// addTrade records a trade by w and returns it
+func (g *seedGenerator) addTrade(w *seedWallet, market *storage.MarketMap, ts int64, side, outcome string, price, notional float64) storage.TradeSeen {
+	role := dataapi.RoleTaker
+	if g.rng.Float64() < 0.12 {
+		role = dataapi.RoleMaker
+	}
+	txHash := g.hexID(32)
+	tradeHash := txHash
+	if role == dataapi.RoleMaker {
+		tradeHash = txHash + ":" + w.wallet.WalletAddress
+	}
+	trade := storage.TradeSeen{
+		TradeHash:       tradeHash,
+		TransactionHash: txHash,
+		ConditionID:     market.ConditionID,
+		ProxyWallet:     w.wallet.WalletAddress,
+		TimestampSec:    ts,
+		NotionalUSD:     notional,
+		Side:            side,
+		Outcome:         outcome,
+		Price:           math.Round(price*1000) / 1000,
+		Role:            role,
+		CreatedTS:       ts + 30,
+	}
+	g.data.Trades = append(g.data.Trades, trade)
+	return trade
+}
+
+// randomOutcome returns a side, outcome and price for an ordinary trade
+func (g *seedGenerator) randomOutcome() (string, string, float64) {
+	side := "BUY"
+	if g.rng.Float64() < 0.2 {
+		side = "SELL"
+	}
+	outcome := "Yes"
+	if g.rng.Float64() < 0.4 {
+		outcome = "No"
+	}
+	return side, outcome, g.between(0.04, 0.96)
+}
+
+func (g *seedGenerator) generateTrades() {
+	for _, w := range g.wallets {
+		if w.cluster != nil {
+			continue // Cluster wallets trade together; see generateCoordinatedTrades
+		}
+		from := g.start
+		if w.wallet.FirstSeenTS > from {
+			from = w.wallet.FirstSeenTS
+		}
+		until := g.now - 300
+
+		var count int
+		switch w.kind {
+		case seedFresh:
+			count = 1 + g.rng.Intn(3)
+		case seedVeteran:
+			count = 1 + g.rng.Intn(4)
+		default:
+			count = 1 + g.rng.Intn(7)
+		}
+
+		// Sharp wallets back the eventual winner of every closed market
+		// they can still trade, then carry on in the open ones
+		if w.kind == seedSharp {
+			for _, m := range g.markets {
+				if m.IsActive || m.EndDate <= from {
+					continue
+				}
+				ts := from + g.rng.Int63n(m.EndDate-from)
+				g.addTrade(w, m, ts, "BUY", sharpOutcome, g.between(0.2, 0.6), g.notional())
+			}
+		}
+
+		for i := 0; i < count; i++ {
+			ts := from
+			if w.kind == seedFresh && i == 0 {
+				ts = w.wallet.FirstSeenTS // A fresh wallet is first seen at its first trade
+			} else if until > from {
+				ts += g.rng.Int63n(until - from)
+			}
+			if ts < g.start {
+				ts = g.start
+			}
+			market := g.openMarket(ts)
+			side, outcome, price := g.randomOutcome()
+			notional := g.notional()
+			if w.kind == seedFresh {
+				notional = math.Round(notional * g.between(2, 6)) // Fresh wallets bet big
+			}
+			g.addTrade(w, market, ts, side, outcome, price, notional)
+		}
+	}
+}
+
+// sharpOutcome stands in for the winning outcome of a closed market until
+// resolveMarkets picks it
+const sharpOutcome = "?winner"
+
+// generateCoordinatedTrades has each cluster trade a few markets together,
+// minutes apart, and records the coordinated trade events
+func (g *seedGenerator) generateCoordinatedTrades() {
+	byCluster := make(map[string][]*seedWallet)
+	var order []*storage.WalletCluster
+	for _, w := range g.wallets {
+		if w.cluster == nil {
+			continue
+		}
+		if len(byCluster[w.cluster.ClusterID]) == 0 {
+			order = append(order, w.cluster)
+		}
+		byCluster[w.cluster.ClusterID] = append(byCluster[w.cluster.ClusterID], w)
+	}
+
+	for c, cluster := range order {
+		members := byCluster[cluster.ClusterID]
+		latestFirstSeen := int64(0)
+		for _, w := range members {
+			if w.wallet.FirstSeenTS > latestFirstSeen {
+				latestFirstSeen = w.wallet.FirstSeenTS
+			}
+		}
+		from := g.start
+		if latestFirstSeen > from {
+			from = latestFirstSeen
+		}
+
+		for event := 0; event < 3; event++ {
+			ts := from + g.rng.Int63n(g.now-3600-from)
+			market := g.openMarket(ts)
+			price := g.between(0.1, 0.5)
+			// The second cluster's last event is its members trading
+			// opposite sides, as wash trading would
+			opposing := c == 1 && event == 2
+			pattern := alerts.CoordinationSameSide
+			if opposing {
+				pattern = alerts.CoordinationOpposing
+			}
+
+			coordinated := storage.CoordinatedTrade{
+				ClusterID:    cluster.ClusterID,
+				ConditionID:  market.ConditionID,
+				WalletCount:  len(members),
+				PatternType:  pattern,
+				FirstTradeTS: ts,
+				MarketTitle:  market.MarketTitle,
+				CreatedTS:    ts,
+			}
+			for i, w := range members {
+				tradeTS := ts + int64(i)*int64(g.between(60, 600))
+				outcome := "Yes"
+				if opposing && i%2 == 1 {
+					outcome = "No"
+				}
+				trade := g.addTrade(w, market, tradeTS, "BUY", outcome, price+g.between(-0.02, 0.02), math.Round(g.notional()*g.between(1.5, 3)))
+				coordinated.TotalNotionalUSD += trade.NotionalUSD
+				coordinated.LastTradeTS = tradeTS
+				coordinated.CreatedTS = tradeTS + 30
+			}
+			coordinated.TimeWindowSec = int(coordinated.LastTradeTS - coordinated.FirstTradeTS)
+			g.data.CoordinatedTrades = append(g.data.CoordinatedTrades, coordinated)
+		}
+	}
+}
+
+// resolveMarkets picks a winner for each closed market, settles the sharp
+// wallets' bets on it, and records every trading wallet's result and stats
+// the way updateWalletStatsForResolution does
+func (g *seedGenerator) resolveMarkets() {
+	stats := make(map[string]*storage.WalletStats)
+	for _, m := range g.markets {
+		if m.IsActive {
+			continue
+		}
+		winner := "Yes"
+		if g.rng.Float64() < 0.5 {
+			winner = "No"
+		}
+		resolvedTS := m.EndDate + int64(g.between(1, 12)*3600)
+		g.data.Resolutions = append(g.data.Resolutions, storage.MarketResolution{
+			ConditionID:    m.ConditionID,
+			WinningOutcome: winner,
+			ResolvedTS:     resolvedTS,
+			MarketTitle:    m.MarketTitle,
+			Method:         "uma",
+		})
+
+		netPositions := make(map[string]float64)
+		var walletOrder []string
+		for i := range g.data.Trades {
+			t := &g.data.Trades[i]
+			if t.ConditionID != m.ConditionID {
+				continue
+			}
+			if t.Outcome == sharpOutcome {
+				// Sharp wallets are right four times in five
+				t.Outcome = winner
+				if g.rng.Float64() < 0.2 {
+					t.Outcome = otherOutcome(winner)
+				}
+			}
+			if _, ok := netPositions[t.ProxyWallet]; !ok {
+				walletOrder = append(walletOrder, t.ProxyWallet)
+			}
+			if (t.Side == "BUY") == (t.Outcome == winner) {
+				netPositions[t.ProxyWallet] += t.NotionalUSD
+			} else {
+				netPositions[t.ProxyWallet] -= t.NotionalUSD
+			}
+		}
+
+		for _, wallet := range walletOrder {
+			net := netPositions[wallet]
+			result := storage.ResultHedged
+			if net > 0 {
+				result = storage.ResultWin
+			} else if net < 0 {
+				result = storage.ResultLoss
+			}
+			g.data.Results = append(g.data.Results, storage.WalletMarketResult{
+				WalletAddress:  wallet,
+				ConditionID:    m.ConditionID,
+				Result:         result,
+				NetPositionUSD: net,
+				ResolvedTS:     resolvedTS,
+				CreatedTS:      resolvedTS,
+			})
+
+			s := stats[wallet]
+			if s == nil {
+				s = &storage.WalletStats{WalletAddress: wallet}
+				stats[wallet] = s
+			}
+			s.TotalResolvedTrades++
+			switch result {
+			case storage.ResultWin:
+				s.WinningTrades++
+				s.TotalProfitUSD += net
+			case storage.ResultLoss:
+				s.LosingTrades++
+				s.TotalProfitUSD += net
+			}
+			s.WinRate = float64(s.WinningTrades) / float64(s.TotalResolvedTrades)
+			s.LastCalculatedTS = resolvedTS
+		}
+	}
+
+	for _, w := range g.wallets {
+		if s := stats[w.wallet.WalletAddress]; s != nil {
+			g.data.Stats = append(g.data.Stats, *s)
+		}
+	}
+}
+
+func otherOutcome(outcome string) string {
+	if outcome == "Yes" {
+		return "No"
+	}
+	return "Yes"
+}
+
+// scoreTrades replays the trades oldest first, scoring each with the signals
+// storage would have given it and keeping the alert processTrade would have
+// stored, wallet cooldown included
+func (g *seedGenerator) scoreTrades(ctx context.Context) error {
+	cfg := g.p.config()
+	wallets := make(map[string]*seedWallet, len(g.wallets))
+	for _, w := range g.wallets {
+		wallets[w.wallet.WalletAddress] = w
+		w.wallet.TotalTrades = 0
+		w.wallet.TotalVolumeUSD = 0
+	}
+	markets := make(map[string]*storage.MarketMap, len(g.markets))
+	for _, m := range g.markets {
+		markets[m.ConditionID] = m
+	}
+	stats := make(map[string]*storage.WalletStats, len(g.data.Stats))
+	for i := range g.data.Stats {
+		stats[g.data.Stats[i].WalletAddress] = &g.data.Stats[i]
+	}
+	coordinated := make(map[string]string) // Trade hash -> pattern, for every trade after an event's first
+	for _, event := range g.data.CoordinatedTrades {
+		for i := range g.data.Trades {
+			t := &g.data.Trades[i]
+			if t.ConditionID == event.ConditionID && t.TimestampSec > event.FirstTradeTS && t.TimestampSec <= event.LastTradeTS {
+				coordinated[t.TradeHash] = event.PatternType
+			}
+		}
+	}
+	clusterSizes := make(map[string]int)
+	for _, m := range g.data.ClusterMembers {
+		clusterSizes[m.ClusterID]++
+	}
+
+	for i := range g.data.Trades {
+		t := &g.data.Trades[i]
+		w := wallets[t.ProxyWallet]
+		market := markets[t.ConditionID]
+		priorTrades, priorVolume := w.wallet.TotalTrades, w.wallet.TotalVolumeUSD
+
+		// Velocity and concentration from this wallet's trades so far, the
+		// velocity count including the stored trade plus one as
+		// checkTradeVelocity does
+		velocityCount := 1
+		var sideVolume, marketVolume float64
+		for _, prev := range g.data.Trades[:i+1] {
+			if prev.ProxyWallet != t.ProxyWallet {
+				continue
+			}
+			if prev.TimestampSec >= t.TimestampSec-int64(cfg.VelocityWindowMinutes*60) {
+				velocityCount++
+			}
+			if prev.ConditionID == t.ConditionID && prev.TimestampSec >= t.TimestampSec-int64(cfg.ConcentrationWindowHrs)*3600 {
+				marketVolume += prev.NotionalUSD
+				if prev.Side == t.Side {
+					sideVolume += prev.NotionalUSD
+				}
+			}
+		}
+
+		in := ScoreInput{
+			Notional:       t.NotionalUSD,
+			Price:          t.Price,
+			Side:           t.Side,
+			WalletAgeDays:  int((t.TimestampSec - w.wallet.FirstSeenTS) / 86400),
+			HoursToClose:   float64(market.EndDate-t.TimestampSec) / 3600,
+			LiquidityRatio: t.NotionalUSD / market.LiquidityNum,
+			VelocityCount:  velocityCount,
+			Concentration:  sideVolume / marketVolume,
+			PriorTrades:    priorTrades,
+			PriorVolumeUSD: priorVolume,
+			FirstTrade:     priorTrades == 0,
+			Maker:          t.Role == dataapi.RoleMaker,
+			Coordinated:    coordinated[t.TradeHash],
+		}
+		if s := stats[t.ProxyWallet]; s != nil && s.LastCalculatedTS <= t.TimestampSec {
+			in.WinRate, in.ResolvedTrades = s.WinRate, s.TotalResolvedTrades
+		}
+		if w.wallet.FundingReceivedTS > 0 {
+			in.FundingAgeHours = float64(w.wallet.FirstSeenTS-w.wallet.FundingReceivedTS) / 3600
+			if priorTrades == 0 && w.funding != nil {
+				in.FundingAmountUSD = w.funding.AmountUSD
+			}
+		}
+		if w.cluster != nil {
+			in.ClusterSize = clusterSizes[w.cluster.ClusterID]
+		}
+
+		result, err := g.p.SimulateScore(ctx, in)
+		if err != nil {
+			return fmt.Errorf("score seeded trade %s: %w", t.TradeHash, err)
+		}
+
+		w.wallet.TotalTrades++
+		w.wallet.TotalVolumeUSD += t.NotionalUSD
+		w.wallet.LastActivityTS = t.TimestampSec
+
+		breakdown := result.Breakdown
+		severity, notify := g.p.gateSeverity(result.Severity, in.WalletAgeDays, breakdown.NormalizedScore)
+		createdTS := t.TimestampSec + 30
+		if notify && w.lastAlert > 0 && createdTS-w.lastAlert < int64(cfg.AlertCooldownMins*60) {
+			continue // Suppressed by the wallet cooldown
+		}
+		if notify {
+			w.lastAlert = createdTS
+		}
+		breakdownJSON, err := json.Marshal(breakdown)
+		if err != nil {
+			return fmt.Errorf("encode score breakdown: %w", err)
+		}
+		g.data.Alerts = append(g.data.Alerts, storage.Alert{
+			AlertType:         string(severity),
+			WalletAddress:     t.ProxyWallet,
+			ConditionID:       t.ConditionID,
+			MarketTitle:       market.MarketTitle,
+			MarketSlug:        market.MarketSlug,
+			MarketURL:         market.MarketURL,
+			Side:              t.Side,
+			Outcome:           t.Outcome,
+			NotionalUSD:       t.NotionalUSD,
+			Price:             t.Price,
+			WalletAgeDays:     in.WalletAgeDays,
+			SuspicionScore:    breakdown.FinalScore,
+			ScoreBreakdown:    string(breakdownJSON),
+			RecordOnly:        !notify,
+			TransactionHash:   t.TransactionHash,
+			TradeTimestampSec: t.TimestampSec,
+			CreatedTS:         createdTS,
+		})
+	}
+	return nil
+}
+
+// finishClusters fills in the cluster totals and flags clusters with a
+// member on an ALERT
+func (g *seedGenerator) finishClusters() {
+	for i := range g.data.Clusters {
+		cluster := &g.data.Clusters[i]
+		members := make(map[string]bool)
+		for _, m := range g.data.ClusterMembers {
+			if m.ClusterID == cluster.ClusterID {
+				members[m.WalletAddress] = true
+			}
+		}
+		for _, w := range g.wallets {
+			if members[w.wallet.WalletAddress] {
+				cluster.TotalVolumeUSD += w.wallet.TotalVolumeUSD
+				if w.wallet.LastActivityTS > cluster.LastActivityTS {
+					cluster.LastActivityTS = w.wallet.LastActivityTS
+				}
+			}
+		}
+		for _, a := range g.data.Alerts {
+			if !members[a.WalletAddress] {
+				continue
+			}
+			if score := g.p.normalizeScore(a.SuspicionScore); score > cluster.SuspicionScore {
+				cluster.SuspicionScore = math.Round(score*100) / 100
+			}
+			if a.AlertType == string(alerts.SeverityAlert) {
+				cluster.IsFlagged = true
+			}
+		}
+		cluster.UpdatedTS = g.now
+	}
+}
+
+// WriteSeed stores seed data in a single transaction. It is meant for an
+// empty database: rows that already exist make it fail, and nothing is stored.
+func (p *Processor) WriteSeed(ctx context.Context, data *SeedData) error {
+	return p.db.Transaction(ctx, func(tx *storage.DB) error {
+		return writeSeed(ctx, tx, data)
+	})
+}
+
+// writeSeed stores each kind of seed row in turn
+func writeSeed(ctx context.Context, db *storage.DB, data *SeedData) error {
+	for i := range data.Markets {
+		if err := db.UpsertMarketMap(ctx, &data.Markets[i]); err != nil {
+			return fmt.Errorf("store market: %w", err)
+		}
+	}
+	for i := range data.Resolutions {
+		if err := db.UpsertMarketResolution(ctx, &data.Resolutions[i]); err != nil {
+			return fmt.Errorf("store market resolution: %w", err)
+		}
+	}
+	for i := range data.Wallets {
+		if err := db.UpsertWallet(ctx, &data.Wallets[i]); err != nil {
+			return fmt.Errorf("store wallet: %w", err)
+		}
+	}
+	for i := range data.FundingSources {
+		if err := db.UpsertWalletFundingSource(ctx, &data.FundingSources[i]); err != nil {
+			return fmt.Errorf("store funding source: %w", err)
+		}
+	}
+	for i := range data.Clusters {
+		if err := db.UpsertWalletCluster(ctx, &data.Clusters[i]); err != nil {
+			return fmt.Errorf("store cluster: %w", err)
+		}
+	}
+	for i := range data.ClusterMembers {
+		if err := db.UpsertClusterMember(ctx, &data.ClusterMembers[i]); err != nil {
+			return fmt.Errorf("store cluster member: %w", err)
+		}
+	}
+	for i := range data.Trades {
+		if err := db.InsertTrade(ctx, &data.Trades[i]); err != nil {
+			return fmt.Errorf("store trade: %w", err)
+		}
+	}
+	for i := range data.CoordinatedTrades {
+		if err := db.InsertCoordinatedTrade(ctx, &data.CoordinatedTrades[i]); err != nil {
+			return fmt.Errorf("store coordinated trade: %w", err)
+		}
+	}
+	for i := range data.Results {
+		if _, err := db.InsertWalletMarketResult(ctx, &data.Results[i]); err != nil {
+			return fmt.Errorf("store wallet market result: %w", err)
+		}
+	}
+	for i := range data.Stats {
+		if err := db.UpsertWalletStats(ctx, &data.Stats[i]); err != nil {
+			return fmt.Errorf("store wallet stats: %w", err)
+		}
+	}
+	for i := range data.Alerts {
+		if _, err := db.InsertAlert(ctx, &data.Alerts[i]); err != nil {
+			return fmt.Errorf("store alert: %w", err)
+		}
+	}
+	if data.CheckpointTS > 0 {
+		if err := db.SetState(ctx, "last_processed_ts", strconv.FormatInt(data.CheckpointTS, 10)); err != nil {
+			return fmt.Errorf("store checkpoint: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package processor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/config"
+	"github.com/sirupsen/logrus"
+)
+
+func newSeedTestProcessor(t *testing.T) *Processor {
+	t.Setenv("API_AUTH_TOKEN", "secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("got %v loading the default configuration, want no error", err)
+	}
+	log := logrus.New()
+	log.SetLevel(logrus.PanicLevel)
+	return New(cfg, nil, nil, nil, nil, nil, nil, log)
+}
+
+func TestGenerateSeedIsDeterministic(t *testing.T) {
+	p := newSeedTestProcessor(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	first, err := p.GenerateSeed(context.Background(), SeedOptions{Seed: 7, Wallets: 100, Now: now})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	second, err := p.GenerateSeed(context.Background(), SeedOptions{Seed: 7, Wallets: 100, Now: now})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("got different data from the same seed, want identical")
+	}
+
+	other, err := p.GenerateSeed(context.Background(), SeedOptions{Seed: 8, Wallets: 100, Now: now})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	if other.Wallets[0].WalletAddress == first.Wallets[0].WalletAddress {
+		t.Errorf("got the same first wallet from seeds 7 and 8, want different data")
+	}
+
+	if _, err := p.GenerateSeed(context.Background(), SeedOptions{Seed: 7, Wallets: 5, Now: now}); err == nil {
+		t.Errorf("got no error for 5 wallets, want one")
+	}
+}
+
+func TestGenerateSeedShapes(t *testing.T) {
+	p := newSeedTestProcessor(t)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	data, err := p.GenerateSeed(context.Background(), SeedOptions{Seed: 1, Wallets: 300, Now: now})
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	weekAgo := now.Add(-7 * 24 * time.Hour).Unix()
+
+	if len(data.Wallets) != 300 {
+		t.Errorf("got %d wallets, want 300", len(data.Wallets))
+	}
+	if len(data.Markets) != len(seedMarkets) {
+		t.Errorf("got %d markets, want %d", len(data.Markets), len(seedMarkets))
+	}
+
+	markets := make(map[string]bool)
+	sports := make(map[string]bool)
+	for _, m := range data.Markets {
+		markets[m.ConditionID] = true
+		if normalizeCategory(m.Category, m.MarketSlug) == categorySports {
+			sports[m.ConditionID] = true
+		}
+	}
+	if len(sports) == 0 {
+		t.Errorf("got no sports markets, want some for the sports filter")
+	}
+
+	// Trades: in the seeded week, on known non-sports markets, above the
+	// minimum, oldest first, and adding up to the wallet totals
+	tradeCounts := make(map[string]int)
+	tradeHashes := make(map[string]bool)
+	for i, trade := range data.Trades {
+		if !markets[trade.ConditionID] || sports[trade.ConditionID] {
+			t.Errorf("trade %s: got market %s, want a known non-sports market", trade.TradeHash, trade.ConditionID)
+		}
+		if trade.TimestampSec < weekAgo || trade.TimestampSec > now.Unix() {
+			t.Errorf("trade %s: got timestamp %d, want within the week before %d", trade.TradeHash, trade.TimestampSec, now.Unix())
+		}
+		if trade.NotionalUSD < p.config().MinTradeUSD {
+			t.Errorf("trade %s: got notional %.0f, want at least MIN_TRADE_USD", trade.TradeHash, trade.NotionalUSD)
+		}
+		if i > 0 && trade.TimestampSec < data.Trades[i-1].TimestampSec {
+			t.Errorf("trade %d: got trades out of order, want oldest first", i)
+		}
+		if tradeHashes[trade.TradeHash] {
+			t.Errorf("trade %s: got a duplicate trade hash, want unique", trade.TradeHash)
+		}
+		tradeHashes[trade.TradeHash] = true
+		tradeCounts[trade.ProxyWallet]++
+	}
+	for _, w := range data.Wallets {
+		if w.TotalTrades != tradeCounts[w.WalletAddress] || w.TotalTrades == 0 {
+			t.Errorf("wallet %s: got %d total trades, want its %d seeded trades", w.WalletAddress, w.TotalTrades, tradeCounts[w.WalletAddress])
+		}
+	}
+	if data.CheckpointTS != data.Trades[len(data.Trades)-1].TimestampSec {
+		t.Errorf("got checkpoint %d, want the newest trade's timestamp", data.CheckpointTS)
+	}
+
+	// Clusters with coordinated trades, one of them opposing
+	if len(data.Clusters) != 2 || len(data.ClusterMembers) != 10 {
+		t.Errorf("got %d clusters with %d members, want 2 with 10", len(data.Clusters), len(data.ClusterMembers))
+	}
+	patterns := make(map[string]int)
+	for _, c := range data.CoordinatedTrades {
+		patterns[c.PatternType]++
+		if c.WalletCount < 2 || c.TimeWindowSec > 3600 {
+			t.Errorf("coordinated trade in %s: got %d wallets over %ds, want 2 or more within an hour", c.ConditionID, c.WalletCount, c.TimeWindowSec)
+		}
+	}
+	if patterns[alerts.CoordinationSameSide] == 0 || patterns[alerts.CoordinationOpposing] == 0 {
+		t.Errorf("got coordinated trade patterns %v, want both same side and opposing", patterns)
+	}
+
+	// Resolved markets with results and win rates consistent with them
+	if len(data.Resolutions) == 0 || len(data.Results) == 0 {
+		t.Errorf("got %d resolutions and %d results, want some of each", len(data.Resolutions), len(data.Results))
+	}
+	resolved := make(map[string]int)
+	for _, r := range data.Results {
+		resolved[r.WalletAddress]++
+	}
+	var experienced int
+	for _, s := range data.Stats {
+		if s.TotalResolvedTrades != resolved[s.WalletAddress] {
+			t.Errorf("wallet %s: got %d resolved trades in stats, want its %d results", s.WalletAddress, s.TotalResolvedTrades, resolved[s.WalletAddress])
+		}
+		if s.TotalResolvedTrades >= p.config().Detection.WinRate.MinResolvedTrades {
+			experienced++
+		}
+	}
+	if experienced == 0 {
+		t.Errorf("got no wallet with enough resolved trades for the win rate rule, want some")
+	}
+
+	// Alerts of every severity, some record-only, each for a seeded trade
+	severities := make(map[string]int)
+	var recordOnly int
+	for _, a := range data.Alerts {
+		severities[a.AlertType]++
+		if a.RecordOnly {
+			recordOnly++
+		}
+		if a.ScoreBreakdown == "" {
+			t.Errorf("alert for %s: got no score breakdown, want one", a.TransactionHash)
+		}
+	}
+	for _, severity := range []alerts.Severity{alerts.SeverityInfo, alerts.SeverityWarn, alerts.SeverityAlert} {
+		if severities[string(severity)] == 0 {
+			t.Errorf("got no %s alerts, want some", severity)
+		}
+	}
+	if recordOnly == 0 {
+		t.Errorf("got no record-only alerts, want some from old wallets")
+	}
+}
@@ -0,0 +1,152 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+)
+
+// ScoreInput describes a hypothetical trade for score simulation. Signals
+// that normally come from storage or APIs (velocity, clustering, ...) are
+// given directly.
+type ScoreInput struct {
+	Notional         float64 `json:"notional"`
+	Price            float64 `json:"price"`
+	Side             string  `json:"side"` // BUY (default) or SELL
+	WalletAgeDays    int     `json:"wallet_age_days"`
+	HoursToClose     float64 `json:"hours_to_close"` // 0 when the market has no end date
+	WinRate          float64 `json:"win_rate"`
+	ResolvedTrades   int     `json:"resolved_trades"`
+	LiquidityRatio   float64 `json:"liquidity_ratio"` // Notional / market liquidity; 0 when unknown
+	VelocityCount    int     `json:"velocity_count"`
+	Concentration    float64 `json:"concentration"` // Share of the wallet's market volume on this side
+	FundingAgeHours  float64 `json:"funding_age_hours"`
+	FundingAmountUSD float64 `json:"funding_amount_usd"`
+	PriorTrades      int     `json:"prior_trades"`
+	PriorVolumeUSD   float64 `json:"prior_volume_usd"`
+	ClusterSize      int     `json:"cluster_size"`
+	Coordinated      string  `json:"coordinated"` // same_side, opposing, or empty
+	FirstTrade       bool    `json:"first_trade"`
+	Maker            bool    `json:"maker"`
+	CopyTrade        bool    `json:"copy_trade"`
+	HolderRank       int     `json:"holder_rank"`  // Rank among the outcome's top holders; 0 when not listed
+	HolderShare      float64 `json:"holder_share"` // Share of the top holders' combined balance
+}
+
+// ScoreResult is the outcome of scoring a trade
+type ScoreResult struct {
+	Breakdown *alerts.ScoreBreakdown `json:"breakdown"`
+	Severity  alerts.Severity        `json:"severity"`
+}
+
+// SimulateScore runs the scoring pipeline, including custom rules, against a
+// hypothetical trade without touching storage or external APIs
+func (p *Processor) SimulateScore(ctx context.Context, in ScoreInput) (*ScoreResult, error) {
+	if in.Notional <= 0 {
+		return nil, fmt.Errorf("notional must be positive")
+	}
+	if in.Price <= 0 || in.Price >= 1 {
+		return nil, fmt.Errorf("price must be between 0 and 1")
+	}
+	side := strings.ToUpper(in.Side)
+	if side == "" {
+		side = "BUY"
+	}
+	if side != "BUY" && side != "SELL" {
+		return nil, fmt.Errorf("side must be BUY or SELL")
+	}
+	switch in.Coordinated {
+	case "", alerts.CoordinationSameSide, alerts.CoordinationOpposing:
+	default:
+		return nil, fmt.Errorf("coordinated must be %s, %s, or empty", alerts.CoordinationSameSide, alerts.CoordinationOpposing)
+	}
+
+	trade := &dataapi.Trade{
+		ProxyWallet: "simulated",
+		Side:        side,
+		Outcome:     "Yes",
+		Price:       in.Price,
+		Timestamp:   p.clock.Now().Unix(),
+		Role:        dataapi.RoleTaker,
+	}
+	if in.Maker {
+		trade.Role = dataapi.RoleMaker
+	}
+
+	tc := &TradeContext{
+		Trade:             trade,
+		Wallet:            &storage.Wallet{WalletAddress: trade.ProxyWallet, TotalTrades: in.PriorTrades},
+		Market:            &MarketInfo{Title: "Simulated market", TokenIDs: map[string]string{"Yes": "simulated"}},
+		Notional:          in.Notional,
+		WalletAgeDays:     in.WalletAgeDays,
+		HoursToClose:      in.HoursToClose,
+		IsFirstTrade:      in.FirstTrade,
+		WinRate:           in.WinRate,
+		FundingAgeHours:   in.FundingAgeHours,
+		FundingAgeMinutes: in.FundingAgeHours * 60,
+		FundingAmountUSD:  in.FundingAmountUSD,
+		PriorTrades:       in.PriorTrades,
+		PriorVolumeUSD:    in.PriorVolumeUSD,
+		Lookups:           simulatedLookups{in: in},
+	}
+	if in.LiquidityRatio > 0 {
+		tc.Market.LiquidityNum = in.Notional / in.LiquidityRatio
+	}
+	if in.ResolvedTrades > 0 {
+		tc.Stats = &storage.WalletStats{
+			WalletAddress:       trade.ProxyWallet,
+			TotalResolvedTrades: in.ResolvedTrades,
+			WinRate:             in.WinRate,
+		}
+	}
+
+	breakdown, severity := p.evaluate(ctx, tc)
+	return &ScoreResult{Breakdown: breakdown, Severity: severity}, nil
+}
+
+// simulatedLookups answers rule lookups from a ScoreInput
+type simulatedLookups struct {
+	in ScoreInput
+}
+
+func (l simulatedLookups) VerifiedTradeCount(ctx context.Context, wallet string) (int, error) {
+	return 1, nil // Confirms a simulated first trade
+}
+
+func (l simulatedLookups) TradeVelocity(ctx context.Context, wallet string, tradeTS int64) (int, error) {
+	return l.in.VelocityCount, nil
+}
+
+func (l simulatedLookups) NetPositionConcentration(ctx context.Context, trade *dataapi.Trade, notional float64) (float64, error) {
+	return l.in.Concentration, nil
+}
+
+func (l simulatedLookups) CoordinatedTrade(ctx context.Context, trade *dataapi.Trade) (bool, string, string, error) {
+	if l.in.Coordinated == "" {
+		return false, "", "", nil
+	}
+	return true, l.in.Coordinated, "simulated", nil
+}
+
+func (l simulatedLookups) ClusterMultiplier(ctx context.Context, wallet string) float64 {
+	return clusterSizeMultiplier(l.in.ClusterSize)
+}
+
+func (l simulatedLookups) RecentTradeSizes(ctx context.Context, trade *dataapi.Trade, limit int) ([]float64, error) {
+	return nil, nil // The size anomaly rule falls back to PriorVolumeUSD / PriorTrades
+}
+
+func (l simulatedLookups) CopyTradeLeader(ctx context.Context, trade *dataapi.Trade) (*storage.TradeSeen, error) {
+	if !l.in.CopyTrade {
+		return nil, nil
+	}
+	return &storage.TradeSeen{ProxyWallet: "simulated-leader", TimestampSec: trade.Timestamp - 600}, nil
+}
+
+func (l simulatedLookups) HolderRank(ctx context.Context, trade *dataapi.Trade, tokenID string) (int, float64, error) {
+	return l.in.HolderRank, l.in.HolderShare, nil
+}
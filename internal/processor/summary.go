@@ -0,0 +1,77 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/alerts"
+	"github.com/liamashdown/insiderwatch/internal/storage"
+)
+
+// dailySummaryTopAlerts is how many of the day's highest scoring alerts the
+// daily summary lists
+const dailySummaryTopAlerts = 5
+
+// GenerateDailySummary reports the day up to until: the calendar day
+// before it in DAILY_SUMMARY_TIMEZONE, so a summary sent at 18:00 covers
+// 18:00 the previous evening onwards.
+func (p *Processor) GenerateDailySummary(ctx context.Context, until time.Time) (*alerts.DailySummary, error) {
+	loc := p.config().DailySummaryLocation()
+	to := until.In(loc)
+	from := to.AddDate(0, 0, -1)
+
+	period, err := p.db.GetPeriodSummary(ctx, from.Unix(), to.Unix(), dailySummaryTopAlerts)
+	if err != nil {
+		return nil, fmt.Errorf("get period summary: %w", err)
+	}
+	return p.buildDailySummary(period, from, to), nil
+}
+
+// buildDailySummary formats what storage reported for [from, to)
+func (p *Processor) buildDailySummary(period *storage.PeriodSummary, from, to time.Time) *alerts.DailySummary {
+	summary := &alerts.DailySummary{
+		// A summary sent at midnight is about the day that just ended
+		Date:                to.Add(-time.Nanosecond).Format("2006-01-02"),
+		Timezone:            to.Location().String(),
+		From:                from,
+		To:                  to,
+		QualifyingTrades:    period.Trades,
+		QualifyingVolumeUSD: period.VolumeUSD,
+		Alerts:              make(map[alerts.Severity]int64, len(period.Alerts)),
+	}
+	for _, c := range period.Alerts {
+		summary.Alerts[alerts.Severity(c.AlertType)] = c.Alerts
+	}
+	for _, a := range period.TopAlerts {
+		summary.TopAlerts = append(summary.TopAlerts, alerts.SummaryAlert{
+			AlertID:         a.ID,
+			Severity:        alerts.Severity(a.AlertType),
+			WalletAddress:   a.WalletAddress,
+			WalletShort:     shortenAddress(a.WalletAddress),
+			MarketTitle:     a.MarketTitle,
+			MarketURL:       a.MarketURL,
+			Side:            a.Side,
+			Outcome:         a.Outcome,
+			NotionalUSD:     a.NotionalUSD,
+			NormalizedScore: p.normalizeScore(a.SuspicionScore),
+		})
+	}
+	for _, c := range period.FlaggedClusters {
+		summary.FlaggedClusters = append(summary.FlaggedClusters, alerts.SummaryCluster{
+			ClusterID:      c.ClusterID,
+			WalletCount:    c.WalletCount,
+			TotalVolumeUSD: c.TotalVolumeUSD,
+		})
+	}
+	for _, w := range period.FlaggedWins {
+		summary.FlaggedWins = append(summary.FlaggedWins, alerts.SummaryWin{
+			MarketTitle:    w.MarketTitle,
+			MarketURL:      w.MarketURL,
+			WinningOutcome: w.WinningOutcome,
+			Wallets:        w.Wallets,
+			AlertedUSD:     w.AlertedUSD,
+		})
+	}
+	return summary
+}
@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"strings"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+	"github.com/liamashdown/insiderwatch/internal/polymarket/dataapi"
+	"github.com/sirupsen/logrus"
+)
+
+// earliestTradeTS rejects zero and implausibly old timestamps; Polymarket
+// launched in 2020
+var earliestTradeTS = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// invalidTradeReason checks the invariants every trade from the API should
+// meet and returns why a trade breaks them, or "" for a valid trade
+func invalidTradeReason(trade *dataapi.Trade, now time.Time) string {
+	switch {
+	case strings.TrimSpace(trade.ProxyWallet) == "":
+		return "missing_wallet"
+	case strings.TrimSpace(trade.ConditionID) == "":
+		return "missing_condition_id"
+	case trade.Timestamp < earliestTradeTS || trade.Timestamp > now.Add(time.Hour).Unix():
+		return "bad_timestamp"
+	case !(trade.Size > 0): // Also catches NaN
+		return "bad_size"
+	case !(trade.Price >= 0 && trade.Price <= 1):
+		return "bad_price"
+	}
+	return ""
+}
+
+// validTrades drops malformed trades before they are dispatched or move the
+// checkpoint
+func (p *Processor) validTrades(trades []dataapi.Trade, stats *pollStats) []dataapi.Trade {
+	valid := make([]dataapi.Trade, 0, len(trades))
+	for i := range trades {
+		if p.acceptTrade(&trades[i], stats) {
+			valid = append(valid, trades[i])
+		}
+	}
+	return valid
+}
+
+// acceptTrade reports whether a trade is well formed, counting and logging
+// it by reason when it isn't
+func (p *Processor) acceptTrade(trade *dataapi.Trade, stats *pollStats) bool {
+	reason := invalidTradeReason(trade, p.clock.Now())
+	if reason == "" {
+		return true
+	}
+	metrics.InvalidTrades.WithLabelValues(reason).Inc()
+	stats.trade("invalid")
+	p.log.WithFields(logrus.Fields{
+		"reason":       reason,
+		"wallet":       trade.ProxyWallet,
+		"condition_id": trade.ConditionID,
+		"timestamp":    trade.Timestamp,
+		"size":         trade.Size,
+		"price":        trade.Price,
+		"tx_hash":      trade.TransactionHash,
+	}).Debug("Skipping malformed trade")
+	return false
+}
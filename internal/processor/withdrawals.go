@@ -0,0 +1,154 @@
+package processor
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+
+	"github.com/liamashdown/insiderwatch/internal/storage"
+	"github.com/sirupsen/logrus"
+)
+
+// protocolAddresses are Polymarket contracts every trading wallet sends USDC
+// to. They are never treated as a shared funding source or withdrawal
+// destination.
+var protocolAddresses = []string{
+	"0x4bFb41d5B3570DeFd03C39a9A4D8dE6Bd8B8982E", // CTF Exchange
+	"0xC5d563A36AE78145C45a50134d48A1215220f80a", // Neg Risk CTF Exchange
+	"0xd91E80cF2E7be2e162c6513ceD06f1dD0dA35296", // Neg Risk Adapter
+	"0x4D97DCd97eC945f40cF65F87097ACe5EA0476045", // Conditional Tokens
+}
+
+// isExcludedClusterAddress reports whether an address is too widely shared
+// (protocol contracts, configured exchange hot wallets) to link wallets
+func (p *Processor) isExcludedClusterAddress(address string) bool {
+	for _, excluded := range protocolAddresses {
+		if strings.EqualFold(address, excluded) {
+			return true
+		}
+	}
+	for _, excluded := range p.config().ClusterExcludedAddresses {
+		if strings.EqualFold(address, excluded) {
+			return true
+		}
+	}
+	return false
+}
+
+// TrackWithdrawals records where recently flagged wallets have sent funds and
+// clusters wallets that withdraw to the same destination. Actors who fund
+// wallets from an exchange often consolidate winnings to one address, which
+// funding-source clustering cannot see. It returns the number of new
+// destinations recorded.
+func (p *Processor) TrackWithdrawals(ctx context.Context) (int, error) {
+	if !p.config().EnableWithdrawalClustering || p.subgraph == nil {
+		return 0, nil
+	}
+	if !p.withdrawalScanRunning.CompareAndSwap(false, true) {
+		p.log.Debug("Withdrawal scan already running, skipping")
+		return 0, nil
+	}
+	defer p.withdrawalScanRunning.Store(false)
+	p.work.RLock()
+	defer p.work.RUnlock()
+
+	sinceTS := p.clock.Now().AddDate(0, 0, -p.config().WithdrawalLookbackDays).Unix()
+	wallets, err := p.db.GetAlertedWallets(ctx, sinceTS)
+	if err != nil {
+		return 0, fmt.Errorf("get alerted wallets: %w", err)
+	}
+
+	recorded := 0
+	for _, wallet := range wallets {
+		if ctx.Err() != nil {
+			return recorded, ctx.Err()
+		}
+
+		outflows, err := p.subgraph.GetWalletOutflows(ctx, wallet)
+		if err != nil {
+			p.log.WithError(err).WithField("wallet", wallet).Warn("Failed to get wallet outflows")
+			continue
+		}
+
+		for _, outflow := range outflows {
+			if outflow.To == "" || strings.EqualFold(outflow.To, wallet) || p.isExcludedClusterAddress(outflow.To) {
+				continue
+			}
+
+			inserted, err := p.db.InsertWithdrawalDestination(ctx, &storage.WalletWithdrawalDestination{
+				WalletAddress: wallet,
+				Destination:   strings.ToLower(outflow.To),
+				AmountUSD:     outflow.AmountUSD,
+				TxHash:        outflow.TxHash,
+				WithdrawalTS:  outflow.Timestamp,
+			})
+			if err != nil {
+				p.log.WithError(err).WithField("wallet", wallet).Warn("Failed to record withdrawal destination")
+				continue
+			}
+			if !inserted {
+				continue
+			}
+			recorded++
+
+			if err := p.linkByWithdrawal(ctx, strings.ToLower(outflow.To)); err != nil {
+				p.log.WithError(err).WithField("destination", outflow.To).Warn("Failed to cluster by withdrawal destination")
+			}
+		}
+	}
+
+	p.log.WithFields(logrus.Fields{
+		"wallets":      len(wallets),
+		"destinations": recorded,
+	}).Info("Withdrawal scan complete")
+
+	return recorded, nil
+}
+
+// linkByWithdrawal puts every wallet that withdrew to destination into one
+// cluster. An existing cluster of any of the wallets is reused (the largest
+// one), and any other clusters they belong to are merged into it.
+func (p *Processor) linkByWithdrawal(ctx context.Context, destination string) error {
+	withdrawals, err := p.db.GetWalletsByWithdrawalDestination(ctx, destination)
+	if err != nil {
+		return fmt.Errorf("get wallets by destination: %w", err)
+	}
+	if len(withdrawals) < 2 {
+		return nil
+	}
+
+	var cluster *storage.WalletCluster
+	firstTS := withdrawals[0].WithdrawalTS
+	for _, w := range withdrawals {
+		if w.WithdrawalTS < firstTS {
+			firstTS = w.WithdrawalTS
+		}
+		existing, err := p.getWalletCluster(ctx, w.WalletAddress)
+		if err != nil {
+			return fmt.Errorf("get wallet cluster: %w", err)
+		}
+		if existing != nil && (cluster == nil || existing.WalletCount > cluster.WalletCount) {
+			cluster = existing
+		}
+	}
+
+	if cluster == nil {
+		cluster = &storage.WalletCluster{
+			ClusterID:             fmt.Sprintf("cluster_%x", sha256.Sum256([]byte("withdrawal:"+destination))),
+			WithdrawalDestination: destination,
+			LinkType:              storage.LinkWithdrawal,
+			FirstSeenTS:           firstTS,
+			LastActivityTS:        firstTS,
+		}
+	} else if cluster.WithdrawalDestination == "" {
+		cluster.WithdrawalDestination = destination
+	}
+
+	for _, w := range withdrawals {
+		if err := p.addClusterMember(ctx, cluster, w.WalletAddress, storage.LinkWithdrawal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
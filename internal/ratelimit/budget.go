@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/metrics"
+)
+
+// Priority orders requests competing for a shared Budget
+type Priority int
+
+const (
+	PriorityPoll       Priority = iota // The trades poll
+	PriorityLookup                     // Lookups on the trade and alert path (default)
+	PriorityBackground                 // Recalculation and other background jobs
+	numPriorities
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityPoll:
+		return "poll"
+	case PriorityBackground:
+		return "background"
+	default:
+		return "lookup"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose requests wait on a Budget at the
+// given priority
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom returns the priority set on ctx, or PriorityLookup
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < numPriorities {
+		return p
+	}
+	return PriorityLookup
+}
+
+// Budget is a token bucket shared by several API clients, for APIs that
+// limit per IP rather than per endpoint. When tokens run short, queued
+// requests are granted them highest priority first, so background work
+// yields to the poll and alert path. A nil *Budget never waits.
+type Budget struct {
+	mu         sync.Mutex
+	rate       float64
+	tokens     float64
+	maxTokens  float64
+	lastUpdate time.Time
+	queues     [numPriorities][]chan struct{}
+	timer      *time.Timer // Pending dispatch while requests are queued
+}
+
+// NewBudget creates a shared budget of rps requests per second with room
+// for burst at once (0 defaults to the rate). It returns nil, which
+// disables the budget, when rps is not positive.
+func NewBudget(rps float64, burst int) *Budget {
+	if rps <= 0 {
+		return nil
+	}
+	maxTokens := float64(burst)
+	if burst <= 0 {
+		maxTokens = rps
+	}
+	maxTokens = max(maxTokens, 1.0)
+	return &Budget{
+		rate:       rps,
+		tokens:     maxTokens,
+		maxTokens:  maxTokens,
+		lastUpdate: time.Now(),
+	}
+}
+
+// Wait blocks until the budget grants a token at ctx's priority or ctx is
+// cancelled
+func (b *Budget) Wait(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	priority := PriorityFrom(ctx)
+	start := time.Now()
+
+	b.mu.Lock()
+	b.refill()
+	// Queued requests get tokens first, whatever their priority, so a new
+	// arrival can't slip in between dispatches
+	if b.tokens >= 1.0 && b.queued() == 0 {
+		b.tokens -= 1.0
+		b.mu.Unlock()
+		metrics.RecordBudgetWait(priority.String(), 0)
+		return nil
+	}
+	ready := make(chan struct{})
+	b.queues[priority] = append(b.queues[priority], ready)
+	b.schedule()
+	b.mu.Unlock()
+
+	select {
+	case <-ready:
+		metrics.RecordBudgetWait(priority.String(), time.Since(start))
+		return nil
+	case <-ctx.Done():
+		b.mu.Lock()
+		if !b.remove(priority, ready) {
+			// Granted as ctx was cancelled; hand the token on
+			b.tokens = min(b.tokens+1.0, b.maxTokens)
+			b.dispatch()
+		}
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens earned since the last update. Callers hold mu.
+func (b *Budget) refill() {
+	now := time.Now()
+	b.tokens = min(b.tokens+now.Sub(b.lastUpdate).Seconds()*b.rate, b.maxTokens)
+	b.lastUpdate = now
+}
+
+// queued returns the number of waiting requests. Callers hold mu.
+func (b *Budget) queued() int {
+	n := 0
+	for _, q := range b.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// dispatch grants available tokens to queued requests in priority order and
+// schedules the next dispatch while any are left. Callers hold mu.
+func (b *Budget) dispatch() {
+	b.refill()
+	for p := range b.queues {
+		for len(b.queues[p]) > 0 && b.tokens >= 1.0 {
+			close(b.queues[p][0])
+			b.queues[p] = b.queues[p][1:]
+			b.tokens -= 1.0
+		}
+	}
+	if b.queued() > 0 {
+		b.schedule()
+	}
+}
+
+// schedule arranges a dispatch for when the next token is due, unless one
+// is already pending. Callers hold mu.
+func (b *Budget) schedule() {
+	if b.timer != nil {
+		return
+	}
+	wait := time.Duration((1.0 - b.tokens) / b.rate * float64(time.Second))
+	b.timer = time.AfterFunc(max(wait, 0), func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.timer = nil
+		b.dispatch()
+	})
+}
+
+// remove drops a waiter from its queue, reporting false when it was already
+// granted. Callers hold mu.
+func (b *Budget) remove(p Priority, ready chan struct{}) bool {
+	q := b.queues[p]
+	for i, c := range q {
+		if c == ready {
+			b.queues[p] = append(q[:i], q[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBudgetPriority(t *testing.T) {
+	b := NewBudget(20, 1)
+	b.Wait(context.Background()) // Empty the bucket
+
+	// Queue background work first, then lookups, then the poll; tokens
+	// should still go to the poll first
+	var mu sync.Mutex
+	var order []Priority
+	var wg sync.WaitGroup
+	for _, p := range []Priority{PriorityBackground, PriorityBackground, PriorityLookup, PriorityPoll} {
+		wg.Add(1)
+		go func(p Priority) {
+			defer wg.Done()
+			if err := b.Wait(WithPriority(context.Background(), p)); err != nil {
+				t.Errorf("got %v, want no error", err)
+				return
+			}
+			mu.Lock()
+			order = append(order, p)
+			mu.Unlock()
+		}(p)
+		time.Sleep(5 * time.Millisecond) // Let each waiter queue before the next
+	}
+	wg.Wait()
+
+	want := []Priority{PriorityPoll, PriorityLookup, PriorityBackground, PriorityBackground}
+	for i := range want {
+		if i >= len(order) || order[i] != want[i] {
+			t.Fatalf("got grant order %v, want %v", order, want)
+		}
+	}
+}
+
+func TestBudgetCancel(t *testing.T) {
+	b := NewBudget(1, 1)
+	b.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("got %s, want Wait to return promptly on cancellation", elapsed)
+	}
+
+	b.mu.Lock()
+	queued := b.queued()
+	b.mu.Unlock()
+	if queued != 0 {
+		t.Errorf("got %d queued after cancellation, want 0", queued)
+	}
+}
+
+func TestBudgetDisabled(t *testing.T) {
+	b := NewBudget(0, 5)
+	if b != nil {
+		t.Fatalf("got a budget for 0 RPS, want nil")
+	}
+	if err := b.Wait(context.Background()); err != nil {
+		t.Errorf("got %v, want a nil budget to never wait", err)
+	}
+}
+
+func TestPriorityFrom(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		expected    Priority
+		description string
+	}{
+		{"unset", context.Background(), PriorityLookup, "Requests without a priority are alert path lookups"},
+		{"poll", WithPriority(context.Background(), PriorityPoll), PriorityPoll, "The poll priority is carried on the context"},
+		{"invalid", WithPriority(context.Background(), Priority(9)), PriorityLookup, "Unknown priorities fall back to lookups"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PriorityFrom(tt.ctx); got != tt.expected {
+				t.Errorf("got %s, want %s\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
@@ -15,42 +15,78 @@ type Limiter struct {
 	mu         sync.Mutex
 }
 
-// New creates a new rate limiter with the specified rate (requests per second)
-func New(rps float64) *Limiter {
+// New creates a new rate limiter with the specified rate (requests per
+// second) that lets up to burst requests through at once after a quiet
+// period. A burst of 0 defaults to the rate.
+func New(rps float64, burst int) *Limiter {
 	if rps <= 0 {
 		rps = 1.0
 	}
+	maxTokens := float64(burst)
+	if burst <= 0 {
+		maxTokens = rps
+	}
+	// A bucket smaller than one token could never hand one out
+	maxTokens = max(maxTokens, 1.0)
 	return &Limiter{
 		rate:       rps,
-		tokens:     rps,
-		maxTokens:  rps,
+		tokens:     maxTokens,
+		maxTokens:  maxTokens,
 		lastUpdate: time.Now(),
 	}
 }
 
-// Wait blocks until a token is available or context is cancelled
+// Wait blocks until a token is available or context is cancelled. Each
+// caller reserves the next token up front and sleeps exactly until it is
+// due, so queued callers are spaced at the rate instead of waking together.
 func (l *Limiter) Wait(ctx context.Context) error {
-	for {
-		if l.tryTake() {
-			return nil
-		}
+	wait := l.reserve()
+	if wait <= 0 {
+		return nil
+	}
 
-		// Calculate wait time
-		waitTime := time.Duration(float64(time.Second) / l.rate)
-		
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case <-time.After(waitTime):
-			// Continue loop
-		}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
 	}
 }
 
-func (l *Limiter) tryTake() bool {
+// Allow takes a token if one is available and reports whether it did,
+// without waiting, for callers that would rather skip than queue
+func (l *Limiter) Allow() bool {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	l.refill()
+	if l.tokens < 1.0 {
+		return false
+	}
+	l.tokens -= 1.0
+	return true
+}
+
+// reserve takes a token, letting the bucket go negative when none is left,
+// and returns how long until that token is actually due
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+
+	l.tokens -= 1.0
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens earned since the last update. Callers hold mu.
+func (l *Limiter) refill() {
 	now := time.Now()
 	elapsed := now.Sub(l.lastUpdate).Seconds()
 
@@ -61,12 +97,11 @@ func (l *Limiter) tryTake() bool {
 	}
 
 	l.lastUpdate = now
+}
 
-	// Try to take a token
-	if l.tokens >= 1.0 {
-		l.tokens -= 1.0
-		return true
-	}
-
-	return false
+// cancel returns the token of a Wait abandoned before it was due
+func (l *Limiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens = min(l.tokens+1.0, l.maxTokens)
 }
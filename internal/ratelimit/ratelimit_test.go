@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name        string
+		rps         float64
+		burst       int
+		wantBurst   int
+		description string
+	}{
+		{"burst", 1, 5, 5, "A burst above the rate passes immediately"},
+		{"default", 3, 0, 3, "A zero burst defaults to the rate"},
+		{"fractional", 0.5, 0, 1, "A rate below 1 still allows one request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.rps, tt.burst)
+
+			start := time.Now()
+			for i := 0; i < tt.wantBurst; i++ {
+				if err := l.Wait(context.Background()); err != nil {
+					t.Fatalf("request %d: got %v, want no error", i+1, err)
+				}
+			}
+			if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+				t.Errorf("got %s for %d requests, want them immediate\nDescription: %s", elapsed, tt.wantBurst, tt.description)
+			}
+
+			// The next token is at least 1/rps away
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			if err := l.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("request %d: got %v, want it to wait past the deadline\nDescription: %s", tt.wantBurst+1, err, tt.description)
+			}
+		})
+	}
+}
+
+func TestLimiterPacing(t *testing.T) {
+	tests := []struct {
+		name        string
+		calls       int
+		concurrent  bool
+		description string
+	}{
+		{"sequential", 11, false, "Calls after the burst are spaced at the rate"},
+		{"concurrent", 11, true, "Queued callers are spaced at the rate rather than retrying together"},
+	}
+
+	const rps = 50.0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(rps, 1)
+
+			start := time.Now()
+			if tt.concurrent {
+				var wg sync.WaitGroup
+				for i := 0; i < tt.calls; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						l.Wait(context.Background())
+					}()
+				}
+				wg.Wait()
+			} else {
+				for i := 0; i < tt.calls; i++ {
+					l.Wait(context.Background())
+				}
+			}
+			elapsed := time.Since(start)
+
+			// The first call uses the burst token; the rest wait 1/rps each
+			want := time.Duration(float64(tt.calls-1) / rps * float64(time.Second))
+			if elapsed < want-10*time.Millisecond || elapsed > want+60*time.Millisecond {
+				t.Errorf("got %s for %d calls, want about %s\nDescription: %s", elapsed, tt.calls, want, tt.description)
+			}
+		})
+	}
+}
+
+func TestLimiterCancel(t *testing.T) {
+	l := New(1, 1)
+	l.Wait(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	start := time.Now()
+	err := l.Wait(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("got %s, want Wait to return promptly on cancellation", elapsed)
+	}
+
+	// The abandoned reservation is returned, so the next token is due about
+	// a second after the first call rather than two
+	if wait := l.reserve(); wait > time.Second {
+		t.Errorf("got next token in %s, want at most 1s", wait)
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	l := New(1, 3)
+	for i := 0; i < 3; i++ {
+		if !l.Allow() {
+			t.Fatalf("request %d: got refused, want the burst allowed", i+1)
+		}
+	}
+	if l.Allow() {
+		t.Errorf("got allowed, want refused once the burst is spent")
+	}
+
+	// Allow never goes into debt, so Wait only waits for the next token
+	if wait := l.reserve(); wait > time.Second {
+		t.Errorf("got next token in %s, want at most 1s after refusals", wait)
+	}
+}
@@ -0,0 +1,286 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Fields are the trade attributes a rule expression may reference
+var Fields = []string{
+	"notional",
+	"price",
+	"wallet_age_days",
+	"hours_to_close",
+	"liquidity_ratio",
+	"win_rate",
+	"velocity_count",
+}
+
+// Vars holds the field values a rule is evaluated against
+type Vars map[string]float64
+
+// node is a compiled boolean expression
+type node interface {
+	eval(v Vars) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(v Vars) bool { return n.left.eval(v) && n.right.eval(v) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(v Vars) bool { return n.left.eval(v) || n.right.eval(v) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(v Vars) bool { return !n.inner.eval(v) }
+
+// operand is either a field reference or a numeric literal
+type operand struct {
+	field string
+	value float64
+}
+
+func (o operand) resolve(v Vars) float64 {
+	if o.field != "" {
+		return v[o.field]
+	}
+	return o.value
+}
+
+type compareNode struct {
+	left  operand
+	op    string
+	right operand
+}
+
+func (n compareNode) eval(v Vars) bool {
+	l, r := n.left.resolve(v), n.right.resolve(v)
+	switch n.op {
+	case ">=":
+		return l >= r
+	case "<=":
+		return l <= r
+	case ">":
+		return l > r
+	case "<":
+		return l < r
+	case "==":
+		return l == r
+	case "!=":
+		return l != r
+	}
+	return false
+}
+
+type tokenKind int
+
+const (
+	tokIdent tokenKind = iota
+	tokNumber
+	tokOp
+	tokLParen
+	tokRParen
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int // 1-based column in the expression
+}
+
+// tokenize splits an expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(expr) {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i + 1})
+			i++
+		case strings.ContainsRune("<>=!", c):
+			start := i
+			i++
+			if i < len(expr) && expr[i] == '=' {
+				i++
+			}
+			op := expr[start:i]
+			if op == "=" || op == "!" {
+				return nil, fmt.Errorf("invalid operator %q at position %d", op, start+1)
+			}
+			tokens = append(tokens, token{tokOp, op, start + 1})
+		case unicode.IsDigit(c) || c == '.' || c == '-':
+			start := i
+			i++
+			for i < len(expr) && (unicode.IsDigit(rune(expr[i])) || expr[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, expr[start:i], start + 1})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) && (unicode.IsLetter(rune(expr[i])) || unicode.IsDigit(rune(expr[i])) || expr[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokIdent, strings.ToLower(expr[start:i]), start + 1})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(expr) + 1})
+	return tokens, nil
+}
+
+// parser is a recursive descent parser for:
+//
+//	expr    := and ("or" and)*
+//	and     := unary ("and" unary)*
+//	unary   := "not" unary | "(" expr ")" | operand op operand
+//	operand := field | number
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse compiles an expression string into an evaluable node
+func parse(expr string) (node, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(word string) bool {
+	tok := p.peek()
+	return tok.kind == tokIdent && tok.text == word
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isKeyword("not") {
+		p.next()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected \")\" at position %d", tok.pos)
+		}
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, fmt.Errorf("expected comparison operator at position %d", opTok.pos)
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{left: left, op: opTok.text, right: right}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q at position %d", tok.text, tok.pos)
+		}
+		return operand{value: value}, nil
+	case tokIdent:
+		if !isField(tok.text) {
+			return operand{}, fmt.Errorf("unknown field %q at position %d (valid fields: %s)",
+				tok.text, tok.pos, strings.Join(Fields, ", "))
+		}
+		return operand{field: tok.text}, nil
+	case tokEOF:
+		return operand{}, fmt.Errorf("unexpected end of expression")
+	default:
+		return operand{}, fmt.Errorf("expected field or number at position %d, got %q", tok.pos, tok.text)
+	}
+}
+
+func isField(name string) bool {
+	for _, f := range Fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Definition is a single rule as written in the rules file:
+//
+//   - when: price >= 0.9 and notional >= 25000
+//     multiplier: 2.0
+//     label: "heavy favorite pile-in"
+type Definition struct {
+	When       string  `yaml:"when"`
+	Multiplier float64 `yaml:"multiplier"`
+	Label      string  `yaml:"label"`
+}
+
+// Rule is a compiled threshold rule
+type Rule struct {
+	Label      string
+	Multiplier float64
+	When       string
+	expr       node
+}
+
+// Match reports whether the rule's condition holds for the given values
+func (r *Rule) Match(v Vars) bool {
+	return r.expr.eval(v)
+}
+
+// LoadFile reads and compiles the rules in a YAML file
+func LoadFile(path string) ([]*Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rules file: %w", err)
+	}
+
+	var defs []Definition
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("parse rules file %s: %w", path, err)
+	}
+
+	compiled, err := Compile(defs)
+	if err != nil {
+		return nil, fmt.Errorf("rules file %s: %w", path, err)
+	}
+	return compiled, nil
+}
+
+// Compile validates the definitions and compiles their expressions.
+// Errors identify the offending rule by position and label.
+func Compile(defs []Definition) ([]*Rule, error) {
+	compiled := make([]*Rule, 0, len(defs))
+	for i, def := range defs {
+		if def.Label == "" {
+			return nil, fmt.Errorf("rule %d: label is required", i+1)
+		}
+		if def.When == "" {
+			return nil, fmt.Errorf("rule %d (%q): when is required", i+1, def.Label)
+		}
+		if def.Multiplier <= 0 {
+			return nil, fmt.Errorf("rule %d (%q): multiplier must be positive (got %.2f)", i+1, def.Label, def.Multiplier)
+		}
+
+		expr, err := parse(def.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d (%q): invalid expression %q: %w", i+1, def.Label, def.When, err)
+		}
+
+		compiled = append(compiled, &Rule{
+			Label:      def.Label,
+			Multiplier: def.Multiplier,
+			When:       def.When,
+			expr:       expr,
+		})
+	}
+	return compiled, nil
+}
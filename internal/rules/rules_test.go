@@ -0,0 +1,106 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRuleMatch(t *testing.T) {
+	vars := Vars{
+		"notional":        30000,
+		"price":           0.92,
+		"wallet_age_days": 2,
+		"hours_to_close":  12,
+		"liquidity_ratio": 0.08,
+		"win_rate":        0,
+		"velocity_count":  1,
+	}
+
+	tests := []struct {
+		name        string
+		when        string
+		expected    bool
+		description string
+	}{
+		{"simple comparison", "price >= 0.9", true, "Single comparison against a literal"},
+		{"and", "price >= 0.9 and notional >= 25000", true, "Both sides hold"},
+		{"and fails", "price >= 0.9 and notional >= 50000", false, "One side fails"},
+		{"or", "win_rate > 0.8 or velocity_count < 2", true, "Either side holds"},
+		{"not", "not wallet_age_days > 7", true, "Negation of a comparison"},
+		{"precedence", "win_rate > 0.8 or price > 0.9 and notional > 1000", true, "and binds tighter than or"},
+		{"parentheses", "(win_rate > 0.8 or price > 0.9) and notional > 50000", false, "Parentheses override precedence"},
+		{"field on both sides", "hours_to_close > wallet_age_days", true, "Fields can be compared with each other"},
+		{"equality", "velocity_count == 1 and win_rate != 1", true, "== and != are supported"},
+		{"negative literal", "price > -1", true, "Negative numbers parse"},
+		{"case insensitive keywords", "PRICE >= 0.9 AND Notional >= 25000", true, "Keywords and fields ignore case"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			compiled, err := Compile([]Definition{{When: tt.when, Multiplier: 2, Label: "test"}})
+			if err != nil {
+				t.Fatalf("compile %q: %v", tt.when, err)
+			}
+			if got := compiled[0].Match(vars); got != tt.expected {
+				t.Errorf("got %v, want %v\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		def      Definition
+		contains string
+	}{
+		{"unknown field", Definition{When: "size > 5", Multiplier: 2, Label: "bad"}, `unknown field "size"`},
+		{"missing operator", Definition{When: "price 0.9", Multiplier: 2, Label: "bad"}, "expected comparison operator"},
+		{"dangling and", Definition{When: "price > 0.9 and", Multiplier: 2, Label: "bad"}, "unexpected end of expression"},
+		{"unbalanced paren", Definition{When: "(price > 0.9", Multiplier: 2, Label: "bad"}, `expected ")"`},
+		{"single equals", Definition{When: "price = 0.9", Multiplier: 2, Label: "bad"}, `invalid operator "="`},
+		{"bad character", Definition{When: "price > 0.9 & notional > 1", Multiplier: 2, Label: "bad"}, "unexpected character"},
+		{"missing label", Definition{When: "price > 0.9", Multiplier: 2}, "label is required"},
+		{"zero multiplier", Definition{When: "price > 0.9", Label: "bad"}, "multiplier must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Compile([]Definition{{When: "price > 0", Multiplier: 1.1, Label: "ok"}, tt.def})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.contains) || !strings.Contains(err.Error(), "rule 2") {
+				t.Errorf("error %q should point at rule 2 and contain %q", err, tt.contains)
+			}
+		})
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `
+- when: price >= 0.9 and notional >= 25000
+  multiplier: 2.0
+  label: "heavy favorite pile-in"
+- when: hours_to_close < 2
+  multiplier: 1.5
+  label: last-minute bet
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write rules file: %v", err)
+	}
+
+	compiled, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if len(compiled) != 2 {
+		t.Fatalf("got %d rules, want 2", len(compiled))
+	}
+	if compiled[0].Label != "heavy favorite pile-in" || compiled[0].Multiplier != 2.0 {
+		t.Errorf("unexpected first rule: %+v", compiled[0])
+	}
+}
@@ -0,0 +1,213 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	awsHTTPTimeout = 10 * time.Second
+	awsService     = "secretsmanager"
+)
+
+// AWSCredentials sign requests to AWS
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials
+}
+
+// AWS reads secrets from AWS Secrets Manager. A reference is the secret's
+// name or ARN, and optionally a key within a JSON secret, like
+// aws:prod/insiderwatch#smtp_password.
+type AWS struct {
+	endpoint string
+	region   string
+	creds    AWSCredentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewAWS creates a Secrets Manager provider for region. An empty endpoint
+// uses the region's public one.
+func NewAWS(region, endpoint string, creds AWSCredentials) (*AWS, error) {
+	if region == "" {
+		return nil, fmt.Errorf("aws: AWS_REGION is required")
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("aws: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required")
+	}
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", awsService, region)
+	}
+	return &AWS{
+		endpoint: strings.TrimRight(endpoint, "/"),
+		region:   region,
+		creds:    creds,
+		client:   &http.Client{Timeout: awsHTTPTimeout},
+		now:      time.Now,
+	}, nil
+}
+
+// NewAWSFromEnv creates a Secrets Manager provider from AWS_REGION (or
+// AWS_DEFAULT_REGION), the standard AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY
+// and AWS_SESSION_TOKEN variables, and AWS_SECRETS_MANAGER_ENDPOINT
+func NewAWSFromEnv() (*AWS, error) {
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	secretKey, err := GetSecret("AWS_SECRET_ACCESS_KEY", "")
+	if err != nil {
+		return nil, fmt.Errorf("aws: %w", err)
+	}
+	return NewAWS(region, os.Getenv("AWS_SECRETS_MANAGER_ENDPOINT"), AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: secretKey,
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	})
+}
+
+// Get returns the secret's string value, or one key of it when the
+// reference has a #key and the secret is a JSON object
+func (a *AWS) Get(ctx context.Context, ref string) (string, error) {
+	id, key := splitRef(ref)
+	if id == "" {
+		return "", fmt.Errorf("aws reference must look like aws:<secret id>[#<key>]")
+	}
+
+	body, _ := json.Marshal(map[string]string{"SecretId": id})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("aws: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	signV4(req, body, a.creds, a.region, awsService, a.now())
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("aws: get %s: %s unreachable: %w", id, a.endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+			Msg     string `json:"Message"` // Some errors capitalize it
+		}
+		json.NewDecoder(resp.Body).Decode(&awsErr)
+		msg := awsErr.Message
+		if msg == "" {
+			msg = awsErr.Msg
+		}
+		return "", fmt.Errorf("aws: get %s: status %d: %s %s", id, resp.StatusCode, awsErr.Type, msg)
+	}
+
+	var out struct {
+		SecretString string `json:"SecretString"`
+		SecretBinary string `json:"SecretBinary"` // Base64
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("aws: get %s: decode response: %w", id, err)
+	}
+	value := out.SecretString
+	if value == "" && out.SecretBinary != "" {
+		raw, err := base64.StdEncoding.DecodeString(out.SecretBinary)
+		if err != nil {
+			return "", fmt.Errorf("aws: get %s: decode binary secret: %w", id, err)
+		}
+		value = string(raw)
+	}
+	if key == "" {
+		return value, nil
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal([]byte(value), &fields); err != nil {
+		return "", fmt.Errorf("aws: %s is not a JSON object, so it has no key %q", id, key)
+	}
+	field, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("aws: %s has no key %q", id, key)
+	}
+	if s, ok := field.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(field), nil
+}
+
+// signV4 signs req with AWS Signature Version 4, covering the host and
+// every header already set
+func signV4(req *http.Request, body []byte, creds AWSCredentials, region, service string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// lookupTimeout bounds one lookup against a provider's backend
+const lookupTimeout = 10 * time.Second
+
+// Provider looks secrets up in an external store. A secret's value names
+// the provider and what to fetch, like vault:secret/data/insiderwatch#token;
+// Get receives everything after the scheme.
+type Provider interface {
+	Get(ctx context.Context, ref string) (string, error)
+}
+
+// knownSchemes are the providers Resolve recognizes even when they aren't
+// configured, so a reference is never mistaken for a literal value
+var knownSchemes = []string{"vault", "aws"}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]*cachedProvider{}
+
+	initOnce sync.Once
+	initErr  error
+)
+
+// cachedProvider keeps every value fetched for the life of the process, so
+// a reload doesn't hit the backend again
+type cachedProvider struct {
+	next Provider
+
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (c *cachedProvider) Get(ctx context.Context, ref string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if value, ok := c.values[ref]; ok {
+		return value, nil
+	}
+	value, err := c.next.Get(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	c.values[ref] = value
+	return value, nil
+}
+
+// Register makes p resolve references starting with scheme:, replacing any
+// provider registered for it before
+func Register(scheme string, p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	if p == nil {
+		delete(providers, scheme)
+		return
+	}
+	providers[scheme] = &cachedProvider{next: p, values: make(map[string]string)}
+}
+
+// Init registers the providers named by SECRET_PROVIDERS (a comma-separated
+// list of vault and aws), configured from their environment variables. It
+// runs once; later calls return the first result. Without it only the
+// environment and _FILE secrets are available.
+func Init() error {
+	initOnce.Do(func() {
+		initErr = configureProviders(os.Getenv("SECRET_PROVIDERS"))
+	})
+	return initErr
+}
+
+func configureProviders(names string) error {
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "":
+			continue
+		case "vault":
+			p, err := NewVaultFromEnv()
+			if err != nil {
+				return err
+			}
+			Register("vault", p)
+		case "aws":
+			p, err := NewAWSFromEnv()
+			if err != nil {
+				return err
+			}
+			Register("aws", p)
+		default:
+			return fmt.Errorf("SECRET_PROVIDERS: unknown provider %q (want vault or aws)", name)
+		}
+	}
+	return nil
+}
+
+// Resolve returns value, or the secret it refers to when it starts with a
+// provider's scheme, like vault: or aws:
+func Resolve(value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, ":")
+	if !ok {
+		return value, nil
+	}
+
+	providersMu.RLock()
+	p := providers[scheme]
+	providersMu.RUnlock()
+	if p == nil {
+		for _, known := range knownSchemes {
+			if scheme == known {
+				return "", fmt.Errorf("%s: reference found but the %s provider is not enabled in SECRET_PROVIDERS", scheme, scheme)
+			}
+		}
+		return value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	secret, err := p.Get(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s:%s: %w", scheme, ref, err)
+	}
+	return secret, nil
+}
+
+// splitRef splits a reference into its path and the #key within it
+func splitRef(ref string) (path, key string) {
+	path, key, _ = strings.Cut(ref, "#")
+	return path, key
+}
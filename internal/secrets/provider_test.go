@@ -0,0 +1,277 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProvider returns values from a map and counts lookups
+type fakeProvider struct {
+	values map[string]string
+	calls  int
+}
+
+func (f *fakeProvider) Get(ctx context.Context, ref string) (string, error) {
+	f.calls++
+	value, ok := f.values[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return value, nil
+}
+
+func TestResolve(t *testing.T) {
+	fake := &fakeProvider{values: map[string]string{"secret/data/app#token": "s3cret"}}
+	Register("vault", fake)
+	defer Register("vault", nil)
+
+	tests := []struct {
+		name          string
+		value         string
+		expected      string
+		expectedError string
+		description   string
+	}{
+		{"reference", "vault:secret/data/app#token", "s3cret", "", "A registered scheme resolves through its provider"},
+		{"literal", "https://discord.test/a", "https://discord.test/a", "", "Values without a provider scheme are returned as they are"},
+		{"dsn", "app:pw@tcp(db:3306)/watch", "app:pw@tcp(db:3306)/watch", "", "A DSN's user is not a scheme"},
+		{"lookup error", "vault:secret/data/app#missing", "", "resolve vault:secret/data/app#missing: not found", "Provider errors name the reference"},
+		{"disabled provider", "aws:prod/app", "", "not enabled in SECRET_PROVIDERS", "Known schemes are never taken literally"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Resolve(tt.value)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.expectedError, tt.description)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("got %q (%v), want %q\nDescription: %s", got, err, tt.expected, tt.description)
+			}
+		})
+	}
+
+	// Values are cached for the life of the process
+	fake.calls = 0
+	for i := 0; i < 3; i++ {
+		Resolve("vault:secret/data/app#token")
+	}
+	if fake.calls != 0 {
+		t.Errorf("got %d lookups, want the cached value reused", fake.calls)
+	}
+}
+
+func TestConfigureProviders(t *testing.T) {
+	t.Setenv("VAULT_ADDR", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	tests := []struct {
+		name          string
+		providers     string
+		expectedError string
+		description   string
+	}{
+		{"none", "", "", "Without providers only the environment is read"},
+		{"unknown", "gcp", `unknown provider "gcp"`, "Only vault and aws are supported"},
+		{"vault unconfigured", "vault", "VAULT_ADDR is required", "Vault needs an address"},
+		{"aws unconfigured", "aws", "AWS_REGION is required", "AWS needs a region"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := configureProviders(tt.providers)
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Errorf("got %v, want no error\nDescription: %s", err, tt.description)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+		})
+	}
+}
+
+func TestVault(t *testing.T) {
+	jwtFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(jwtFile, []byte("service-account-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var reads int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/auth/kubernetes/login" {
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["role"] != "insiderwatch" || body["jwt"] != "service-account-jwt" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"auth": {"client_token": "k8s-token"}}`))
+			return
+		}
+		if token := r.Header.Get("X-Vault-Token"); token != "root" && token != "k8s-token" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		reads++
+		switch r.URL.Path {
+		case "/v1/secret/data/insiderwatch":
+			w.Write([]byte(`{"data": {"data": {"discord_webhook": "https://discord.test/a", "port": 587}, "metadata": {"version": 3}}}`))
+		case "/v1/kv/insiderwatch":
+			w.Write([]byte(`{"data": {"smtp_password": "hunter2"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	defer srv.Close()
+
+	tokenVault, err := NewVault(VaultConfig{Addr: srv.URL, Token: "root"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	k8sVault, err := NewVault(VaultConfig{Addr: srv.URL, Role: "insiderwatch", JWTPath: jwtFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	badVault, _ := NewVault(VaultConfig{Addr: srv.URL, Token: "wrong"})
+
+	tests := []struct {
+		name          string
+		vault         *Vault
+		ref           string
+		expected      string
+		expectedError string
+		description   string
+	}{
+		{"kv v2", tokenVault, "secret/data/insiderwatch#discord_webhook", "https://discord.test/a", "", "KV version 2 secrets are read from data.data"},
+		{"non-string", tokenVault, "secret/data/insiderwatch#port", "587", "", "Non-string values are formatted"},
+		{"kv v1", tokenVault, "kv/insiderwatch#smtp_password", "hunter2", "", "KV version 1 secrets are read from data"},
+		{"kubernetes auth", k8sVault, "kv/insiderwatch#smtp_password", "hunter2", "", "Kubernetes auth logs in with the service account token"},
+		{"missing key", tokenVault, "secret/data/insiderwatch#nope", "", `has no key "nope"`, "A missing key is an error"},
+		{"no key", tokenVault, "secret/data/insiderwatch", "", "vault:<path>#<key>", "References need a key"},
+		{"not found", tokenVault, "secret/data/other#key", "", "status 404", "Missing paths report Vault's status"},
+		{"denied", badVault, "kv/insiderwatch#smtp_password", "", "permission denied", "Vault's errors are passed on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.vault.Get(context.Background(), tt.ref)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.expectedError, tt.description)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("got %q (%v), want %q\nDescription: %s", got, err, tt.expected, tt.description)
+			}
+		})
+	}
+
+	// Keys of one path share a read
+	reads = 0
+	tokenVault.Get(context.Background(), "secret/data/insiderwatch#discord_webhook")
+	tokenVault.Get(context.Background(), "secret/data/insiderwatch#port")
+	if reads != 0 {
+		t.Errorf("got %d reads, want the path's earlier read reused", reads)
+	}
+
+	srv.Close()
+	unreachable, _ := NewVault(VaultConfig{Addr: srv.URL, Token: "root"})
+	if _, err := unreachable.Get(context.Background(), "kv/insiderwatch#smtp_password"); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("got %v, want an unreachable error", err)
+	}
+}
+
+func TestAWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "secretsmanager.GetSecretValue" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20240601/eu-west-1/secretsmanager/aws4_request") {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"__type": "AccessDeniedException", "Message": "bad signature"}`))
+			return
+		}
+		var body struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&body)
+		switch body.SecretId {
+		case "prod/dsn":
+			w.Write([]byte(`{"SecretString": "app:pw@tcp(db:3306)/watch"}`))
+		case "prod/insiderwatch":
+			w.Write([]byte(`{"SecretString": "{\"smtp_password\": \"hunter2\"}"}`))
+		case "prod/binary":
+			w.Write([]byte(`{"SecretBinary": "Ynl0ZXM="}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer srv.Close()
+
+	aws, err := NewAWS("eu-west-1", srv.URL, AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	aws.now = func() time.Time { return time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name          string
+		ref           string
+		expected      string
+		expectedError string
+		description   string
+	}{
+		{"string", "prod/dsn", "app:pw@tcp(db:3306)/watch", "", "A plain secret is returned whole"},
+		{"json key", "prod/insiderwatch#smtp_password", "hunter2", "", "A #key picks a field of a JSON secret"},
+		{"binary", "prod/binary", "bytes", "", "Binary secrets are decoded"},
+		{"not json", "prod/dsn#password", "", "is not a JSON object", "A #key needs a JSON secret"},
+		{"missing key", "prod/insiderwatch#nope", "", `has no key "nope"`, "A missing key is an error"},
+		{"not found", "prod/other", "", "ResourceNotFoundException", "AWS errors are passed on"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := aws.Get(context.Background(), tt.ref)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("got %v, want an error containing %q\nDescription: %s", err, tt.expectedError, tt.description)
+				}
+				return
+			}
+			if err != nil || got != tt.expected {
+				t.Errorf("got %q (%v), want %q\nDescription: %s", got, err, tt.expected, tt.description)
+			}
+		})
+	}
+
+	srv.Close()
+	if _, err := aws.Get(context.Background(), "prod/dsn"); err == nil || !strings.Contains(err.Error(), "unreachable") {
+		t.Errorf("got %v, want an unreachable error", err)
+	}
+}
+
+func TestSignV4(t *testing.T) {
+	// The get-vanilla case of AWS's Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := AWSCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("got %s, want %s", got, expected)
+	}
+}
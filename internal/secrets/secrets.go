@@ -9,6 +9,7 @@ import (
 // GetSecret retrieves a secret value, supporting both direct env vars and file-based secrets
 // File-based format: /run/secrets/secret_name or any file path
 // Env var format: SECRET_NAME
+// Either may hold a provider reference like vault:path#key, which is resolved.
 func GetSecret(envKey string, defaultValue string) (string, error) {
 	// First, check if there's a _FILE variant (Docker secrets pattern)
 	filePathKey := envKey + "_FILE"
@@ -17,12 +18,12 @@ func GetSecret(envKey string, defaultValue string) (string, error) {
 		if err != nil {
 			return "", fmt.Errorf("read secret file %s: %w", filePath, err)
 		}
-		return strings.TrimSpace(string(data)), nil
+		return Resolve(strings.TrimSpace(string(data)))
 	}
 
 	// Fall back to direct environment variable
 	if value := os.Getenv(envKey); value != "" {
-		return value, nil
+		return Resolve(value)
 	}
 
 	// Use default if provided
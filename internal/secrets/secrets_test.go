@@ -0,0 +1,56 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetSecret(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db_password")
+	if err := os.WriteFile(secretFile, []byte("  from-file\n"), 0o600); err != nil {
+		t.Fatalf("write secret file: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		env           map[string]string
+		defaultValue  string
+		expected      string
+		expectedError bool
+		description   string
+	}{
+		{"file", map[string]string{"TEST_SECRET_FILE": secretFile}, "", "from-file", false, "The _FILE variant is read and trimmed"},
+		{"file over env", map[string]string{"TEST_SECRET_FILE": secretFile, "TEST_SECRET": "from-env"}, "", "from-file", false, "The file wins over the plain variable"},
+		{"env", map[string]string{"TEST_SECRET": "from-env"}, "", "from-env", false, "The plain variable is used without a file"},
+		{"default", nil, "fallback", "fallback", false, "The default applies when neither is set"},
+		{"missing file", map[string]string{"TEST_SECRET_FILE": filepath.Join(dir, "missing")}, "fallback", "", true, "An unreadable file is an error, not the default"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_SECRET", "")
+			t.Setenv("TEST_SECRET_FILE", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			got, err := GetSecret("TEST_SECRET", tt.defaultValue)
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("got error %v, want error %v\nDescription: %s", err, tt.expectedError, tt.description)
+			}
+			if got != tt.expected {
+				t.Errorf("got %q, want %q\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
+
+func TestGetOptionalSecret(t *testing.T) {
+	t.Setenv("TEST_SECRET", "")
+	t.Setenv("TEST_SECRET_FILE", filepath.Join(t.TempDir(), "missing"))
+	if got := GetOptionalSecret("TEST_SECRET", "fallback"); got != "fallback" {
+		t.Errorf("got %q, want the default when the file can't be read", got)
+	}
+}
@@ -0,0 +1,223 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	vaultHTTPTimeout    = 10 * time.Second
+	defaultK8sTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	defaultK8sAuthMount = "kubernetes"
+	vaultAuthToken      = "token"
+	vaultAuthKubernetes = "kubernetes"
+)
+
+// Vault reads secrets from HashiCorp Vault's KV engine (version 1 or 2).
+// A reference is the secret's API path and a key within it, like
+// vault:secret/data/insiderwatch#discord_webhook.
+type Vault struct {
+	addr      string
+	namespace string
+	client    *http.Client
+
+	// Kubernetes auth; role is empty with token auth
+	role      string
+	authMount string
+	jwtPath   string
+
+	mu    sync.Mutex
+	token string
+	docs  map[string]map[string]any // Path -> its key/value pairs, fetched once
+}
+
+// VaultConfig configures a Vault provider. With Role set, the provider logs
+// in through Vault's Kubernetes auth method using the pod's service account
+// token; otherwise it uses Token.
+type VaultConfig struct {
+	Addr      string
+	Namespace string // Enterprise namespace; empty for none
+	Token     string
+	Role      string
+	AuthMount string // Kubernetes auth mount; defaults to kubernetes
+	JWTPath   string // Service account token; defaults to the in-cluster path
+}
+
+// NewVault creates a Vault provider
+func NewVault(cfg VaultConfig) (*Vault, error) {
+	if cfg.Addr == "" {
+		return nil, fmt.Errorf("vault: VAULT_ADDR is required")
+	}
+	if cfg.Role == "" && cfg.Token == "" {
+		return nil, fmt.Errorf("vault: VAULT_TOKEN is required with token auth")
+	}
+	if cfg.AuthMount == "" {
+		cfg.AuthMount = defaultK8sAuthMount
+	}
+	if cfg.JWTPath == "" {
+		cfg.JWTPath = defaultK8sTokenPath
+	}
+	return &Vault{
+		addr:      strings.TrimRight(cfg.Addr, "/"),
+		namespace: cfg.Namespace,
+		client:    &http.Client{Timeout: vaultHTTPTimeout},
+		role:      cfg.Role,
+		authMount: strings.Trim(cfg.AuthMount, "/"),
+		jwtPath:   cfg.JWTPath,
+		token:     cfg.Token,
+		docs:      make(map[string]map[string]any),
+	}, nil
+}
+
+// NewVaultFromEnv creates a Vault provider from VAULT_ADDR, VAULT_NAMESPACE
+// and VAULT_AUTH_METHOD: token (VAULT_TOKEN, which supports _FILE) or
+// kubernetes (VAULT_K8S_ROLE, VAULT_K8S_MOUNT, VAULT_K8S_TOKEN_PATH)
+func NewVaultFromEnv() (*Vault, error) {
+	cfg := VaultConfig{
+		Addr:      os.Getenv("VAULT_ADDR"),
+		Namespace: os.Getenv("VAULT_NAMESPACE"),
+	}
+	switch method := os.Getenv("VAULT_AUTH_METHOD"); method {
+	case "", vaultAuthToken:
+		token, err := GetSecret("VAULT_TOKEN", "")
+		if err != nil {
+			return nil, fmt.Errorf("vault: %w", err)
+		}
+		cfg.Token = token
+	case vaultAuthKubernetes:
+		cfg.Role = os.Getenv("VAULT_K8S_ROLE")
+		cfg.AuthMount = os.Getenv("VAULT_K8S_MOUNT")
+		cfg.JWTPath = os.Getenv("VAULT_K8S_TOKEN_PATH")
+		if cfg.Role == "" {
+			return nil, fmt.Errorf("vault: VAULT_K8S_ROLE is required with kubernetes auth")
+		}
+	default:
+		return nil, fmt.Errorf("vault: unknown VAULT_AUTH_METHOD %q (want token or kubernetes)", method)
+	}
+	return NewVault(cfg)
+}
+
+// Get returns the key named after # in the secret at the reference's path
+func (v *Vault) Get(ctx context.Context, ref string) (string, error) {
+	path, key := splitRef(ref)
+	if path == "" || key == "" {
+		return "", fmt.Errorf("vault reference must look like vault:<path>#<key>")
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	doc, ok := v.docs[path]
+	if !ok {
+		var err error
+		if doc, err = v.read(ctx, path); err != nil {
+			return "", err
+		}
+		v.docs[path] = doc
+	}
+
+	value, ok := doc[key]
+	if !ok {
+		return "", fmt.Errorf("vault: %s has no key %q", path, key)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// read fetches the key/value pairs at path, logging in first with
+// Kubernetes auth
+func (v *Vault) read(ctx context.Context, path string) (map[string]any, error) {
+	if v.token == "" {
+		if err := v.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := v.do(ctx, http.MethodGet, "/v1/"+strings.TrimLeft(path, "/"), nil, &resp); err != nil {
+		return nil, fmt.Errorf("vault: read %s: %w", path, err)
+	}
+
+	// KV version 2 nests the pairs under data.data beside data.metadata
+	if inner, ok := resp.Data["data"].(map[string]any); ok {
+		if _, v2 := resp.Data["metadata"]; v2 {
+			return inner, nil
+		}
+	}
+	return resp.Data, nil
+}
+
+// login exchanges the service account token for a Vault token
+func (v *Vault) login(ctx context.Context) error {
+	jwt, err := os.ReadFile(v.jwtPath)
+	if err != nil {
+		return fmt.Errorf("vault: read service account token: %w", err)
+	}
+	body := map[string]string{"role": v.role, "jwt": strings.TrimSpace(string(jwt))}
+	var resp struct {
+		Auth struct {
+			ClientToken string `json:"client_token"`
+		} `json:"auth"`
+	}
+	if err := v.do(ctx, http.MethodPost, "/v1/auth/"+v.authMount+"/login", body, &resp); err != nil {
+		return fmt.Errorf("vault: kubernetes login as %s: %w", v.role, err)
+	}
+	if resp.Auth.ClientToken == "" {
+		return fmt.Errorf("vault: kubernetes login as %s returned no token", v.role)
+	}
+	v.token = resp.Auth.ClientToken
+	return nil
+}
+
+func (v *Vault) do(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.addr+path, reader)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	if v.token != "" {
+		req.Header.Set("X-Vault-Token", v.token)
+	}
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s unreachable: %w", v.addr, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var vaultErr struct {
+			Errors []string `json:"errors"`
+		}
+		json.NewDecoder(resp.Body).Decode(&vaultErr)
+		if len(vaultErr.Errors) > 0 {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, strings.Join(vaultErr.Errors, "; "))
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
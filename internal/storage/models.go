@@ -1,8 +1,6 @@
 package storage
 
 import (
-	"time"
-
 	"gorm.io/gorm"
 )
 
@@ -21,13 +19,14 @@ func (AppState) TableName() string {
 type TradeSeen struct {
 	TradeHash       string  `gorm:"primaryKey;size:128"`
 	TransactionHash string  `gorm:"size:128;index"`
-	ConditionID     string  `gorm:"size:128;not null;index"`
-	ProxyWallet     string  `gorm:"size:128;not null;index"`
-	TimestampSec    int64   `gorm:"not null;index"`
+	ConditionID     string  `gorm:"size:128;not null;index;index:idx_wallet_market_ts,priority:2"`
+	ProxyWallet     string  `gorm:"size:128;not null;index;index:idx_wallet_market_ts,priority:1"`
+	TimestampSec    int64   `gorm:"not null;index;index:idx_wallet_market_ts,priority:3"`
 	NotionalUSD     float64 `gorm:"type:decimal(20,6);not null"`
 	Side            string  `gorm:"size:10;not null"`
 	Outcome         string  `gorm:"size:255;not null"`
 	Price           float64 `gorm:"type:decimal(10,6);not null"`
+	Role            string  `gorm:"size:8;not null;default:taker"` // taker, maker
 	CreatedTS       int64   `gorm:"not null"`
 }
 
@@ -55,19 +54,22 @@ type Alert struct {
 	ID                int64   `gorm:"primaryKey;autoIncrement"`
 	AlertType         string  `gorm:"size:32;not null;index"`
 	WalletAddress     string  `gorm:"size:128;not null;index"`
-	ConditionID       string  `gorm:"size:128;not null;index"`
+	ConditionID       string  `gorm:"size:128;not null;index;index:idx_alerts_market_activity,priority:1"`
 	MarketTitle       string  `gorm:"size:512"`
 	MarketSlug        string  `gorm:"size:255"`
 	MarketURL         string  `gorm:"size:512"`
 	Side              string  `gorm:"size:10;not null"`
 	Outcome           string  `gorm:"size:255;not null"`
-	NotionalUSD       float64 `gorm:"type:decimal(20,6);not null"`
+	NotionalUSD       float64 `gorm:"type:decimal(20,6);not null;index:idx_alerts_market_activity,priority:3"`
 	Price             float64 `gorm:"type:decimal(10,6);not null"`
 	WalletAgeDays     int     `gorm:"not null"`
 	SuspicionScore    float64 `gorm:"type:decimal(20,6);not null"`
+	ScoreBreakdown    string  `gorm:"type:text"`               // JSON alerts.ScoreBreakdown
+	RecordOnly        bool    `gorm:"not null;default:false;index:idx_alerts_market_activity,priority:2"` // Stored for analysis without a notification
+	Profile           string  `gorm:"size:64;not null;default:'';index"` // Watch profile that raised it; empty without PROFILES
 	TransactionHash   string  `gorm:"size:128"`
 	TradeTimestampSec int64   `gorm:"not null"`
-	CreatedTS         int64   `gorm:"not null;index"`
+	CreatedTS         int64   `gorm:"not null;index;index:idx_alerts_market_activity,priority:4"`
 }
 
 func (Alert) TableName() string {
@@ -94,11 +96,16 @@ type MarketMap struct {
 	MarketSlug   string  `gorm:"size:255;index"`
 	MarketTitle  string  `gorm:"size:512"`
 	MarketURL    string  `gorm:"size:512"`
-	Category     string  `gorm:"size:128"`
+	Category     string  `gorm:"size:128;index"`
 	EndDate      int64   `gorm:"default:0"`
+	EndDateSource string `gorm:"size:16;not null;default:''"` // market, event, or empty when unknown
 	VolumeNum    float64 `gorm:"type:decimal(20,6)"`
 	LiquidityNum float64 `gorm:"type:decimal(20,6)"`
+	OutcomeTokenIDs string `gorm:"type:text"` // JSON object of outcome -> CLOB token ID
 	IsActive     bool    `gorm:"default:true"`
+	IsFallback   bool    `gorm:"not null;default:false"` // Built from trade data because Gamma had no such market; expires after MARKET_FALLBACK_TTL
+	ETag         string  `gorm:"column:etag;size:255;not null;default:''"` // Validators of Gamma's response, sent on refresh so an unchanged market costs a 304
+	LastModified string  `gorm:"size:64;not null;default:''"`
 	UpdatedTS    int64   `gorm:"not null;index"`
 }
 
@@ -112,6 +119,7 @@ type MarketResolution struct {
 	WinningOutcome  string `gorm:"size:255;not null"`
 	ResolvedTS      int64  `gorm:"not null;index"`
 	MarketTitle     string `gorm:"size:512"`
+	Method          string `gorm:"size:32;not null;default:price_heuristic"` // uma, price_heuristic
 }
 
 func (MarketResolution) TableName() string {
@@ -133,6 +141,28 @@ func (WalletStats) TableName() string {
 	return "wallet_stats"
 }
 
+// Wallet market results
+const (
+	ResultWin    = "win"
+	ResultLoss   = "loss"
+	ResultHedged = "hedged" // Flat net position; counted as resolved but neither win nor loss
+)
+
+// WalletMarketResult records a wallet's result in one resolved market. The
+// composite primary key ensures a wallet is credited at most once per market.
+type WalletMarketResult struct {
+	WalletAddress  string  `gorm:"primaryKey;size:128"`
+	ConditionID    string  `gorm:"primaryKey;size:128;index"`
+	Result         string  `gorm:"size:8;not null"` // win, loss, hedged
+	NetPositionUSD float64 `gorm:"type:decimal(20,6);not null;default:0"`
+	ResolvedTS     int64   `gorm:"not null"`
+	CreatedTS      int64   `gorm:"not null"`
+}
+
+func (WalletMarketResult) TableName() string {
+	return "wallet_market_results"
+}
+
 // WalletFundingSource tracks where wallets receive initial funding from
 type WalletFundingSource struct {
 	WalletAddress  string  `gorm:"primaryKey;size:255"`
@@ -147,10 +177,32 @@ func (WalletFundingSource) TableName() string {
 	return "wallet_funding_sources"
 }
 
-// WalletCluster groups wallets funded from the same source
+// Cluster link types
+const (
+	LinkFunding    = "funding"    // Wallets share a funding source
+	LinkWithdrawal = "withdrawal" // Wallets send funds to the same destination
+	LinkBoth       = "both"
+)
+
+// CombineLinkTypes returns the link type covering both a and b
+func CombineLinkTypes(a, b string) string {
+	if a == "" || a == b {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	return LinkBoth
+}
+
+// WalletCluster groups wallets funded from the same source or withdrawing to
+// the same destination
 type WalletCluster struct {
 	ClusterID        string  `gorm:"primaryKey;size:64"`
-	FundingSource    string  `gorm:"uniqueIndex;size:255;not null"`
+	FundingSource    string  `gorm:"index;size:255;not null"` // Empty for clusters formed by withdrawals
+	WithdrawalDestination string `gorm:"index;size:255;not null;default:''"`
+	LinkType         string  `gorm:"size:16;not null;default:funding"` // funding, withdrawal, both
+	MergedInto       string  `gorm:"size:64;not null;default:''"`      // Cluster that absorbed this one
 	WalletCount      int     `gorm:"not null;default:1"`
 	TotalVolumeUSD   float64 `gorm:"type:decimal(20,2);default:0"`
 	FirstSeenTS      int64   `gorm:"not null"`
@@ -164,6 +216,94 @@ func (WalletCluster) TableName() string {
 	return "wallet_clusters"
 }
 
+// WalletClusterMember assigns a wallet to a cluster and records how it was linked
+type WalletClusterMember struct {
+	WalletAddress string `gorm:"primaryKey;size:255"`
+	ClusterID     string `gorm:"size:64;not null;index"`
+	LinkType      string `gorm:"size:16;not null"` // funding, withdrawal, both
+	CreatedTS     int64  `gorm:"not null"`
+}
+
+func (WalletClusterMember) TableName() string {
+	return "wallet_cluster_members"
+}
+
+// WalletWithdrawalDestination records an address a wallet sent funds to
+type WalletWithdrawalDestination struct {
+	WalletAddress string  `gorm:"primaryKey;size:255"`
+	Destination   string  `gorm:"primaryKey;size:255;index"`
+	AmountUSD     float64 `gorm:"type:decimal(20,2);default:0"` // First withdrawal to this destination
+	TxHash        string  `gorm:"size:255"`
+	WithdrawalTS  int64   `gorm:"not null"`
+	CreatedTS     int64   `gorm:"not null"`
+}
+
+func (WalletWithdrawalDestination) TableName() string {
+	return "wallet_withdrawal_destinations"
+}
+
+// Alerted position statuses
+const (
+	PositionOpen   = "open"
+	PositionExited = "exited"
+)
+
+// AlertPosition tracks the position behind a WARN or ALERT alert so a later
+// exit by the same wallet can be reported against it
+type AlertPosition struct {
+	AlertID          int64   `gorm:"primaryKey"`
+	WalletAddress    string  `gorm:"size:128;not null;index:idx_alert_positions_wallet_market"`
+	ConditionID      string  `gorm:"size:128;not null;index:idx_alert_positions_wallet_market"`
+	Outcome          string  `gorm:"size:255;not null"`
+	Side             string  `gorm:"size:10;not null"` // Side of the alerted trade
+	Severity         string  `gorm:"size:32;not null"`
+	Profile          string  `gorm:"size:64;not null;default:''"` // Profile of the alert, whose route gets the exit alert
+	Shares           float64 `gorm:"type:decimal(20,6);not null"`
+	EntryPrice       float64 `gorm:"type:decimal(10,6);not null"`
+	NotionalUSD      float64 `gorm:"type:decimal(20,6);not null"`
+	EntryTS          int64   `gorm:"not null;default:0"` // Time of the alerted trade; earlier trades don't reverse it
+	ReversedShares   float64 `gorm:"type:decimal(20,6);not null;default:0"`
+	ReversedNotional float64 `gorm:"type:decimal(20,6);not null;default:0"` // Proceeds (or cost) of the reversing trades
+	Status           string  `gorm:"size:16;not null;default:open;index"`
+	CreatedTS        int64   `gorm:"not null"`
+	UpdatedTS        int64   `gorm:"not null"`
+	ExitedTS         int64   `gorm:"not null;default:0"`
+}
+
+func (AlertPosition) TableName() string {
+	return "alert_positions"
+}
+
+// AlertPositionExit records a trade counted against an alerted position, so
+// a trade seen by both the poll and the live feed is only counted once
+type AlertPositionExit struct {
+	AlertID   int64   `gorm:"primaryKey"`
+	TradeHash string  `gorm:"primaryKey;size:128"`
+	Shares    float64 `gorm:"type:decimal(20,6);not null"`
+	Price     float64 `gorm:"type:decimal(10,6);not null"`
+	TradeTS   int64   `gorm:"not null"`
+	CreatedTS int64   `gorm:"not null"`
+}
+
+func (AlertPositionExit) TableName() string {
+	return "alert_position_exits"
+}
+
+// WalletCopyLink records a wallet mirroring a flagged wallet's trades. It is a
+// soft link kept for analysis and does not merge the wallets' clusters.
+type WalletCopyLink struct {
+	FollowerWallet  string `gorm:"primaryKey;size:255"`
+	LeaderWallet    string `gorm:"primaryKey;size:255;index"`
+	LastConditionID string `gorm:"size:128;not null"`
+	CopyCount       int    `gorm:"not null;default:1"`
+	FirstSeenTS     int64  `gorm:"not null"`
+	LastSeenTS      int64  `gorm:"not null;index"`
+}
+
+func (WalletCopyLink) TableName() string {
+	return "wallet_copy_links"
+}
+
 // CoordinatedTrade tracks synchronized trades across cluster wallets
 type CoordinatedTrade struct {
 	ID               int64   `gorm:"primaryKey;autoIncrement"`
@@ -172,6 +312,7 @@ type CoordinatedTrade struct {
 	WalletCount      int     `gorm:"not null"`
 	TotalNotionalUSD float64 `gorm:"type:decimal(20,2);not null"`
 	TimeWindowSec    int     `gorm:"not null"`
+	PatternType      string  `gorm:"size:16;not null;default:same_side"` // same_side, opposing
 	FirstTradeTS     int64   `gorm:"not null;index"`
 	LastTradeTS      int64   `gorm:"not null"`
 	MarketTitle      string  `gorm:"type:text"`
@@ -182,73 +323,127 @@ func (CoordinatedTrade) TableName() string {
 	return "coordinated_trades"
 }
 
-// BeforeCreate hook for timestamps
+// DeadLetterTrade is a trade whose processing panicked, kept with the panic
+// so it can be inspected and replayed once the bug is fixed
+type DeadLetterTrade struct {
+	TradeHash       string `gorm:"primaryKey;size:128"`
+	TransactionHash string `gorm:"size:128"`
+	ConditionID     string `gorm:"size:128;not null"`
+	ProxyWallet     string `gorm:"size:128;not null"`
+	TradeJSON       string `gorm:"type:text"` // The trade as received from the Data API
+	Reason          string `gorm:"type:text"` // The panic value
+	Stack           string `gorm:"type:text"`
+	CreatedTS       int64  `gorm:"not null;index"`
+}
+
+func (DeadLetterTrade) TableName() string {
+	return "dead_letter_trades"
+}
+
+// ExposureAlert records an aggregate exposure alert, so the wallet's further
+// buys don't alert again within the cooldown
+type ExposureAlert struct {
+	ID            int64   `gorm:"primaryKey;autoIncrement"`
+	WalletAddress string  `gorm:"size:128;not null;index:idx_exposure_alerts_wallet_ts,priority:1"`
+	TradeHash     string  `gorm:"size:128;not null"` // Trade that crossed the threshold
+	TotalUSD      float64 `gorm:"type:decimal(20,6);not null"`
+	Markets       int     `gorm:"not null"`
+	WindowStartTS int64   `gorm:"not null"`
+	CreatedTS     int64   `gorm:"not null;index:idx_exposure_alerts_wallet_ts,priority:2"`
+}
+
+func (ExposureAlert) TableName() string {
+	return "exposure_alerts"
+}
+
+// BeforeCreate hook for timestamps, read from the DB's clock
 func (a *AppState) BeforeCreate(tx *gorm.DB) error {
 	if a.UpdatedTS == 0 {
-		a.UpdatedTS = time.Now().Unix()
+		a.UpdatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (t *TradeSeen) BeforeCreate(tx *gorm.DB) error {
 	if t.CreatedTS == 0 {
-		t.CreatedTS = time.Now().Unix()
+		t.CreatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (w *Wallet) BeforeCreate(tx *gorm.DB) error {
 	if w.UpdatedTS == 0 {
-		w.UpdatedTS = time.Now().Unix()
+		w.UpdatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (a *Alert) BeforeCreate(tx *gorm.DB) error {
 	if a.CreatedTS == 0 {
-		a.CreatedTS = time.Now().Unix()
+		a.CreatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (w *WalletMarketNet) BeforeCreate(tx *gorm.DB) error {
 	if w.UpdatedTS == 0 {
-		w.UpdatedTS = time.Now().Unix()
+		w.UpdatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (m *MarketMap) BeforeCreate(tx *gorm.DB) error {
 	if m.UpdatedTS == 0 {
-		m.UpdatedTS = time.Now().Unix()
+		m.UpdatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (w *WalletStats) BeforeCreate(tx *gorm.DB) error {
 	if w.LastCalculatedTS == 0 {
-		w.LastCalculatedTS = time.Now().Unix()
+		w.LastCalculatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (w *WalletFundingSource) BeforeCreate(tx *gorm.DB) error {
 	if w.CreatedTS == 0 {
-		w.CreatedTS = time.Now().Unix()
+		w.CreatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (w *WalletCluster) BeforeCreate(tx *gorm.DB) error {
 	if w.UpdatedTS == 0 {
-		w.UpdatedTS = time.Now().Unix()
+		w.UpdatedTS = hookNow(tx)
+	}
+	return nil
+}
+
+func (m *WalletClusterMember) BeforeCreate(tx *gorm.DB) error {
+	if m.CreatedTS == 0 {
+		m.CreatedTS = hookNow(tx)
+	}
+	return nil
+}
+
+func (w *WalletWithdrawalDestination) BeforeCreate(tx *gorm.DB) error {
+	if w.CreatedTS == 0 {
+		w.CreatedTS = hookNow(tx)
 	}
 	return nil
 }
 
 func (c *CoordinatedTrade) BeforeCreate(tx *gorm.DB) error {
 	if c.CreatedTS == 0 {
-		c.CreatedTS = time.Now().Unix()
+		c.CreatedTS = hookNow(tx)
+	}
+	return nil
+}
+
+func (r *WalletMarketResult) BeforeCreate(tx *gorm.DB) error {
+	if r.CreatedTS == 0 {
+		r.CreatedTS = hookNow(tx)
 	}
 	return nil
 }
@@ -3,19 +3,36 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/liamashdown/insiderwatch/internal/clock"
 	"github.com/liamashdown/insiderwatch/internal/config"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
 // DB wraps the GORM database connection
 type DB struct {
-	conn *gorm.DB
-	log  *logrus.Logger
+	conn  *gorm.DB
+	log   *logrus.Logger
+	clock clock.Clock
+}
+
+// clockSetting is the GORM setting the DB's clock travels under, so model
+// hooks stamp records with the same time as the rest of the DB
+const clockSetting = "insiderwatch:clock"
+
+// hookNow returns the current Unix time for a model hook, from the clock
+// set with SetClock
+func hookNow(tx *gorm.DB) int64 {
+	if c, ok := tx.Get(clockSetting); ok {
+		return c.(clock.Clock).Now().Unix()
+	}
+	return time.Now().Unix()
 }
 
 // New creates a new database connection with GORM
@@ -38,6 +55,12 @@ func New(cfg *config.Config, log *logrus.Logger) (*DB, error) {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
+	if cfg.EnableTracing {
+		if err := registerTracing(conn); err != nil {
+			return nil, fmt.Errorf("register tracing: %w", err)
+		}
+	}
+
 	sqlDB, err := conn.DB()
 	if err != nil {
 		return nil, fmt.Errorf("get sql.DB: %w", err)
@@ -57,7 +80,22 @@ func New(cfg *config.Config, log *logrus.Logger) (*DB, error) {
 
 	log.Info("Database connection established")
 
-	return &DB{conn: conn, log: log}, nil
+	return &DB{conn: conn, log: log, clock: clock.Real{}}, nil
+}
+
+// SetClock replaces the clock records are timestamped with, for tests that
+// freeze it. Call it before the DB is shared.
+func (db *DB) SetClock(c clock.Clock) {
+	db.clock = c
+	db.conn = db.conn.Set(clockSetting, c).Session(&gorm.Session{})
+}
+
+// Transaction runs fn with a DB whose queries all belong to one transaction,
+// committed when fn returns nil and rolled back otherwise
+func (db *DB) Transaction(ctx context.Context, fn func(tx *DB) error) error {
+	return db.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&DB{conn: tx, log: db.log, clock: db.clock})
+	})
 }
 
 // Close closes the database connection
@@ -83,9 +121,44 @@ func (db *DB) AutoMigrate() error {
 		&WalletFundingSource{},
 		&WalletCluster{},
 		&CoordinatedTrade{},
+		&WalletMarketResult{},
+		&WalletClusterMember{},
+		&WalletWithdrawalDestination{},
+		&WalletCopyLink{},
+		&AlertPosition{},
+		&AlertPositionExit{},
+		&DeadLetterTrade{},
+		&ExposureAlert{},
 	)
 }
 
+// outcomeColumns are the stored outcome labels NormalizeOutcomeLabels
+// rewrites, by table
+var outcomeColumns = []struct{ table, column string }{
+	{"trades_seen", "outcome"},
+	{"alerts", "outcome"},
+	{"alert_positions", "outcome"},
+	{"market_resolutions", "winning_outcome"},
+}
+
+// NormalizeOutcomeLabels trims the stored outcome labels and collapses
+// their inner whitespace, the way the processor stores new ones. Rows that
+// are already normalized aren't touched, so it is cheap to run again. It
+// returns the number of rows rewritten.
+func (db *DB) NormalizeOutcomeLabels(ctx context.Context) (int64, error) {
+	var rewritten int64
+	for _, c := range outcomeColumns {
+		normalized := fmt.Sprintf("TRIM(REGEXP_REPLACE(%s, '[[:space:]]+', ' '))", c.column)
+		result := db.conn.WithContext(ctx).Exec(
+			fmt.Sprintf("UPDATE %s SET %s = %s WHERE BINARY %s <> BINARY %s", c.table, c.column, normalized, c.column, normalized))
+		if result.Error != nil {
+			return rewritten, fmt.Errorf("normalize %s.%s: %w", c.table, c.column, result.Error)
+		}
+		rewritten += result.RowsAffected
+	}
+	return rewritten, nil
+}
+
 // GetState retrieves a state value by key
 func (db *DB) GetState(ctx context.Context, key string) (string, error) {
 	var state AppState
@@ -101,7 +174,7 @@ func (db *DB) GetState(ctx context.Context, key string) (string, error) {
 
 // SetState sets a state value
 func (db *DB) SetState(ctx context.Context, key, value string) error {
-	now := time.Now().Unix()
+	now := db.clock.Now().Unix()
 	state := AppState{
 		StateKey:   key,
 		StateValue: value,
@@ -143,7 +216,8 @@ func (db *DB) GetWallet(ctx context.Context, address string) (*Wallet, error) {
 	return &wallet, nil
 }
 
-// UpsertWallet inserts or updates a wallet record
+// UpsertWallet inserts a wallet record or, when it exists, adds wallet's
+// TotalTrades and TotalVolumeUSD to its totals
 func (db *DB) UpsertWallet(ctx context.Context, wallet *Wallet) error {
 	// Check if exists
 	existing, err := db.GetWallet(ctx, wallet.WalletAddress)
@@ -152,8 +226,8 @@ func (db *DB) UpsertWallet(ctx context.Context, wallet *Wallet) error {
 	}
 
 	if existing == nil {
-		// Insert new
-		return db.conn.WithContext(ctx).Create(wallet).Error
+		// Insert new, zero totals included: total_trades defaults to 1
+		return db.conn.WithContext(ctx).Select("*").Create(wallet).Error
 	}
 
 	// Update existing
@@ -178,9 +252,76 @@ func (db *DB) InsertAlert(ctx context.Context, alert *Alert) (int64, error) {
 	return alert.ID, nil
 }
 
-// GetLastAlertForWallet retrieves the most recent alert for a wallet
-func (db *DB) GetLastAlertForWallet(ctx context.Context, wallet string) (*Alert, error) {
-	var alert Alert
+// InsertDeadLetterTrade records a trade whose processing panicked. A trade
+// already dead-lettered keeps its first record.
+func (db *DB) InsertDeadLetterTrade(ctx context.Context, trade *DeadLetterTrade) error {
+	if trade.CreatedTS == 0 {
+		trade.CreatedTS = db.clock.Now().Unix()
+	}
+	return db.conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(trade).Error
+}
+
+// InsertAlertPosition records the position behind an alert
+func (db *DB) InsertAlertPosition(ctx context.Context, pos *AlertPosition) error {
+	return db.conn.WithContext(ctx).Create(pos).Error
+}
+
+// GetOpenAlertPositions returns a wallet's open alerted positions in a market
+func (db *DB) GetOpenAlertPositions(ctx context.Context, wallet, conditionID string) ([]AlertPosition, error) {
+	var positions []AlertPosition
+	result := db.conn.WithContext(ctx).
+		Where("wallet_address = ? AND condition_id = ? AND status = ?", wallet, conditionID, PositionOpen).
+		Order("alert_id ASC").
+		Find(&positions)
+	return positions, result.Error
+}
+
+// OpenPositionMarket is a market where a wallet holds open alerted positions
+type OpenPositionMarket struct {
+	WalletAddress string
+	ConditionID   string
+	EntryTS       int64 // Earliest alerted trade of the open positions
+}
+
+// GetOpenPositionMarkets returns the markets where wallets hold open
+// alerted positions, skipping markets that ended before nowTS
+func (db *DB) GetOpenPositionMarkets(ctx context.Context, nowTS int64) ([]OpenPositionMarket, error) {
+	var markets []OpenPositionMarket
+	result := db.conn.WithContext(ctx).
+		Table("alert_positions AS p").
+		Select("p.wallet_address, p.condition_id, MIN(p.entry_ts) AS entry_ts").
+		Joins("LEFT JOIN market_map AS m ON m.condition_id = p.condition_id").
+		Where("p.status = ? AND (m.end_date IS NULL OR m.end_date = 0 OR m.end_date > ?)", PositionOpen, nowTS).
+		Group("p.wallet_address, p.condition_id").
+		Order("p.wallet_address, p.condition_id").
+		Scan(&markets)
+	return markets, result.Error
+}
+
+// InsertAlertPositionExit records a trade against an alerted position. It
+// returns false without error when the trade was already counted.
+func (db *DB) InsertAlertPositionExit(ctx context.Context, exit *AlertPositionExit) (bool, error) {
+	res := db.conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(exit)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UpdateAlertPosition saves an alerted position
+func (db *DB) UpdateAlertPosition(ctx context.Context, pos *AlertPosition) error {
+	return db.conn.WithContext(ctx).Save(pos).Error
+}
+
+// InsertExposureAlert records an aggregate exposure alert
+func (db *DB) InsertExposureAlert(ctx context.Context, alert *ExposureAlert) error {
+	return db.conn.WithContext(ctx).Create(alert).Error
+}
+
+// GetLastExposureAlert retrieves a wallet's most recent aggregate exposure
+// alert, or nil when it has none
+func (db *DB) GetLastExposureAlert(ctx context.Context, wallet string) (*ExposureAlert, error) {
+	var alert ExposureAlert
 	result := db.conn.WithContext(ctx).
 		Where("wallet_address = ?", wallet).
 		Order("created_ts DESC").
@@ -194,6 +335,140 @@ func (db *DB) GetLastAlertForWallet(ctx context.Context, wallet string) (*Alert,
 	return &alert, nil
 }
 
+// WalletMarketExposure is a wallet's tracked buying in one market. Title
+// and URL are empty when the market isn't cached.
+type WalletMarketExposure struct {
+	ConditionID string
+	MarketTitle string
+	MarketURL   string
+	NotionalUSD float64
+	Trades      int64
+}
+
+// GetWalletExposure returns a wallet's tracked buys since sinceTS, summed
+// per market, largest first
+func (db *DB) GetWalletExposure(ctx context.Context, wallet string, sinceTS int64) ([]WalletMarketExposure, error) {
+	var markets []WalletMarketExposure
+	result := db.conn.WithContext(ctx).
+		Table("trades_seen AS t").
+		Select(`t.condition_id, COALESCE(MAX(m.market_title), '') AS market_title, COALESCE(MAX(m.market_url), '') AS market_url,
+			SUM(t.notional_usd) AS notional_usd, COUNT(*) AS trades`).
+		Joins("LEFT JOIN market_map AS m ON m.condition_id = t.condition_id").
+		Where("t.proxy_wallet = ? AND t.side = ? AND t.timestamp_sec >= ?", wallet, "BUY", sinceTS).
+		Group("t.condition_id").
+		Order("notional_usd DESC, t.condition_id ASC").
+		Scan(&markets)
+	return markets, result.Error
+}
+
+// GetLastAlertForWallet retrieves the most recent notified alert for a
+// wallet by a watch profile ("" without profiles)
+func (db *DB) GetLastAlertForWallet(ctx context.Context, wallet, profile string) (*Alert, error) {
+	var alert Alert
+	result := db.conn.WithContext(ctx).
+		Where("wallet_address = ? AND profile = ? AND record_only = ?", wallet, profile, false).
+		Order("created_ts DESC").
+		First(&alert)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &alert, nil
+}
+
+// AlertFilter selects alerts for ListAlerts. Zero values don't filter.
+type AlertFilter struct {
+	AlertType   string // Severity: INFO, WARN or ALERT
+	Wallet      string
+	ConditionID string
+	SinceTS     int64 // Created at or after
+	UntilTS     int64 // Created before
+	MinNotional float64
+	BeforeID    int64 // Pagination cursor: only alerts older than this one
+	Limit       int
+}
+
+// ListAlerts returns the alerts matching filter, newest first, and how many
+// match across all pages
+func (db *DB) ListAlerts(ctx context.Context, filter AlertFilter) ([]Alert, int64, error) {
+	query := db.conn.WithContext(ctx).Model(&Alert{})
+	if filter.AlertType != "" {
+		query = query.Where("alert_type = ?", filter.AlertType)
+	}
+	if filter.Wallet != "" {
+		query = query.Where("wallet_address = ?", filter.Wallet)
+	}
+	if filter.ConditionID != "" {
+		query = query.Where("condition_id = ?", filter.ConditionID)
+	}
+	if filter.SinceTS > 0 {
+		query = query.Where("created_ts >= ?", filter.SinceTS)
+	}
+	if filter.UntilTS > 0 {
+		query = query.Where("created_ts < ?", filter.UntilTS)
+	}
+	if filter.MinNotional > 0 {
+		query = query.Where("notional_usd >= ?", filter.MinNotional)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if filter.BeforeID > 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	var alerts []Alert
+	result := query.Order("id DESC").Limit(filter.Limit).Find(&alerts)
+	return alerts, total, result.Error
+}
+
+// GetAlert returns an alert by ID, or nil if there is none
+func (db *DB) GetAlert(ctx context.Context, id int64) (*Alert, error) {
+	var alert Alert
+	result := db.conn.WithContext(ctx).Where("id = ?", id).First(&alert)
+	if result.Error == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &alert, nil
+}
+
+// WalletTotals is a wallet's trading up to a point in time
+type WalletTotals struct {
+	Trades    int64
+	VolumeUSD float64
+}
+
+// GetWalletTotalsAt sums a wallet's stored trades with timestamps at or
+// before atTS, reconstructing its history as of an alert
+func (db *DB) GetWalletTotalsAt(ctx context.Context, walletAddress string, atTS int64) (*WalletTotals, error) {
+	var totals WalletTotals
+	result := db.conn.WithContext(ctx).
+		Model(&TradeSeen{}).
+		Select("COUNT(*) AS trades, COALESCE(SUM(notional_usd), 0) AS volume_usd").
+		Where("proxy_wallet = ? AND timestamp_sec <= ?", walletAddress, atTS).
+		Scan(&totals)
+	return &totals, result.Error
+}
+
+// GetAlertsAfter returns up to limit alerts with IDs after afterID, oldest
+// first, for catching up a client that saw afterID last
+func (db *DB) GetAlertsAfter(ctx context.Context, afterID int64, limit int) ([]Alert, error) {
+	var alerts []Alert
+	result := db.conn.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&alerts)
+	return alerts, result.Error
+}
+
 // UpsertNetPosition updates or inserts net position
 func (db *DB) UpsertNetPosition(ctx context.Context, pos *WalletMarketNet) error {
 	// Check if exists
@@ -259,6 +534,15 @@ func (db *DB) UpsertMarketMap(ctx context.Context, market *MarketMap) error {
 	return result.Error
 }
 
+// TouchMarketMap marks a cached market as refreshed without rewriting it,
+// for a refresh Gamma answered with 304 Not Modified, and stores the
+// validators it sent back
+func (db *DB) TouchMarketMap(ctx context.Context, conditionID, etag, lastModified string, updatedTS int64) error {
+	return db.conn.WithContext(ctx).Model(&MarketMap{}).
+		Where("condition_id = ?", conditionID).
+		Updates(map[string]any{"updated_ts": updatedTS, "etag": etag, "last_modified": lastModified}).Error
+}
+
 // GetMarketResolution retrieves a market resolution by condition ID
 func (db *DB) GetMarketResolution(ctx context.Context, conditionID string) (*MarketResolution, error) {
 	var resolution MarketResolution
@@ -297,6 +581,74 @@ func (db *DB) UpsertWalletStats(ctx context.Context, stats *WalletStats) error {
 	return result.Error
 }
 
+// InsertWalletMarketResult records a wallet's result in a market. It returns
+// false without error when the wallet was already credited for the market.
+func (db *DB) InsertWalletMarketResult(ctx context.Context, result *WalletMarketResult) (bool, error) {
+	res := db.conn.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(result)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// RecordWalletMarketResult records a wallet's result in a market and, when
+// the wallet wasn't already credited for it, rebuilds the wallet's stats from
+// its results in the same transaction. A failed stats update rolls the result
+// back too, so the market is counted on the next pass rather than never. It
+// returns false without error when the wallet was already credited. Calls for
+// the same wallet must not overlap, or each may miss the other's result.
+func (db *DB) RecordWalletMarketResult(ctx context.Context, result *WalletMarketResult) (bool, error) {
+	inserted := false
+	err := db.conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(result)
+		if res.Error != nil {
+			return fmt.Errorf("insert result: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		inserted = true
+		return db.rebuildWalletStats(tx, result.WalletAddress)
+	})
+	return inserted, err
+}
+
+// rebuildWalletStats derives a wallet's stats from its market results
+// within tx. The realized profit isn't derived from results and is kept.
+func (db *DB) rebuildWalletStats(tx *gorm.DB, walletAddress string) error {
+	var results []WalletMarketResult
+	if err := tx.Where("wallet_address = ?", walletAddress).Find(&results).Error; err != nil {
+		return fmt.Errorf("get wallet market results: %w", err)
+	}
+
+	stats := WalletStats{WalletAddress: walletAddress}
+	if err := tx.Where("wallet_address = ?", walletAddress).First(&stats).Error; err != nil && err != gorm.ErrRecordNotFound {
+		return fmt.Errorf("get wallet stats: %w", err)
+	}
+
+	stats.TotalResolvedTrades = len(results)
+	stats.WinningTrades = 0
+	stats.LosingTrades = 0
+	for _, r := range results {
+		switch r.Result {
+		case ResultWin:
+			stats.WinningTrades++
+		case ResultLoss:
+			stats.LosingTrades++
+		}
+	}
+	stats.WinRate = 0
+	if stats.TotalResolvedTrades > 0 {
+		stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalResolvedTrades)
+	}
+	stats.LastCalculatedTS = db.clock.Now().Unix()
+
+	if err := tx.Save(&stats).Error; err != nil {
+		return fmt.Errorf("save wallet stats: %w", err)
+	}
+	return nil
+}
+
 // GetTradesByConditionID retrieves all trades for a specific condition ID
 func (db *DB) GetTradesByConditionID(ctx context.Context, conditionID string) ([]TradeSeen, error) {
 	var trades []TradeSeen
@@ -358,12 +710,307 @@ func (db *DB) GetWalletClusterBySource(ctx context.Context, fundingSource string
 	return &cluster, nil
 }
 
+// GetWalletClusterByID retrieves a cluster by ID
+func (db *DB) GetWalletClusterByID(ctx context.Context, clusterID string) (*WalletCluster, error) {
+	var cluster WalletCluster
+	result := db.conn.WithContext(ctx).Where("cluster_id = ?", clusterID).First(&cluster)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &cluster, nil
+}
+
+// GetClusterMember retrieves a wallet's cluster membership
+func (db *DB) GetClusterMember(ctx context.Context, walletAddress string) (*WalletClusterMember, error) {
+	var member WalletClusterMember
+	result := db.conn.WithContext(ctx).Where("wallet_address = ?", walletAddress).First(&member)
+	if result.Error != nil {
+		if result.Error == gorm.ErrRecordNotFound {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return &member, nil
+}
+
+// UpsertClusterMember inserts or updates a wallet's cluster membership
+func (db *DB) UpsertClusterMember(ctx context.Context, member *WalletClusterMember) error {
+	result := db.conn.WithContext(ctx).Save(member)
+	return result.Error
+}
+
+// GetClusterMembers retrieves all wallets in a cluster
+func (db *DB) GetClusterMembers(ctx context.Context, clusterID string) ([]WalletClusterMember, error) {
+	var members []WalletClusterMember
+	result := db.conn.WithContext(ctx).Where("cluster_id = ?", clusterID).Find(&members)
+	return members, result.Error
+}
+
+// MoveClusterMembers reassigns every member of one cluster to another
+func (db *DB) MoveClusterMembers(ctx context.Context, fromClusterID, toClusterID string) error {
+	result := db.conn.WithContext(ctx).
+		Model(&WalletClusterMember{}).
+		Where("cluster_id = ?", fromClusterID).
+		Update("cluster_id", toClusterID)
+	return result.Error
+}
+
+// InsertWithdrawalDestination records a wallet's withdrawal destination.
+// Returns false if the destination was already recorded for the wallet.
+func (db *DB) InsertWithdrawalDestination(ctx context.Context, destination *WalletWithdrawalDestination) (bool, error) {
+	result := db.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(destination)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetWalletsByWithdrawalDestination retrieves all wallets that sent funds to a destination
+func (db *DB) GetWalletsByWithdrawalDestination(ctx context.Context, destination string) ([]WalletWithdrawalDestination, error) {
+	var wallets []WalletWithdrawalDestination
+	result := db.conn.WithContext(ctx).Where("destination = ?", destination).Find(&wallets)
+	return wallets, result.Error
+}
+
+// GetAlertedWallets returns the distinct wallets notified on since the given time
+func (db *DB) GetAlertedWallets(ctx context.Context, sinceTS int64) ([]string, error) {
+	var wallets []string
+	result := db.conn.WithContext(ctx).
+		Model(&Alert{}).
+		Where("created_ts >= ? AND record_only = ?", sinceTS, false).
+		Distinct("wallet_address").
+		Pluck("wallet_address", &wallets)
+	return wallets, result.Error
+}
+
+// GetFlaggedWalletTrades returns trades on a market between sinceTS and
+// untilTS (inclusive) by wallets that received an alert of the given type
+// since flaggedSinceTS, oldest first
+func (db *DB) GetFlaggedWalletTrades(ctx context.Context, conditionID, alertType string, flaggedSinceTS, sinceTS, untilTS int64) ([]TradeSeen, error) {
+	flagged := db.conn.WithContext(ctx).
+		Model(&Alert{}).
+		Select("wallet_address").
+		Where("alert_type = ? AND created_ts >= ?", alertType, flaggedSinceTS)
+
+	var trades []TradeSeen
+	result := db.conn.WithContext(ctx).
+		Where("condition_id = ?", conditionID).
+		Where("timestamp_sec BETWEEN ? AND ?", sinceTS, untilTS).
+		Where("proxy_wallet IN (?)", flagged).
+		Order("timestamp_sec ASC").
+		Find(&trades)
+	return trades, result.Error
+}
+
+// RecordCopyLink inserts a follower/leader link or bumps its count
+func (db *DB) RecordCopyLink(ctx context.Context, link *WalletCopyLink) error {
+	return db.conn.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "follower_wallet"}, {Name: "leader_wallet"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"copy_count":        gorm.Expr("copy_count + 1"),
+				"last_condition_id": link.LastConditionID,
+				"last_seen_ts":      link.LastSeenTS,
+			}),
+		}).
+		Create(link).Error
+}
+
 // InsertCoordinatedTrade records a coordinated trade event
 func (db *DB) InsertCoordinatedTrade(ctx context.Context, trade *CoordinatedTrade) error {
 	result := db.conn.WithContext(ctx).Create(trade)
 	return result.Error
 }
 
+// GetCoordinatedTrades returns a cluster's most recent coordinated trade
+// events, newest first
+func (db *DB) GetCoordinatedTrades(ctx context.Context, clusterID string, limit int) ([]CoordinatedTrade, error) {
+	var trades []CoordinatedTrade
+	result := db.conn.WithContext(ctx).
+		Where("cluster_id = ?", clusterID).
+		Order("first_trade_ts DESC").
+		Limit(limit).
+		Find(&trades)
+	return trades, result.Error
+}
+
+// GetCoordinatedTradesInMarket returns a cluster's coordinated trade events
+// in one market, newest first
+func (db *DB) GetCoordinatedTradesInMarket(ctx context.Context, clusterID, conditionID string, limit int) ([]CoordinatedTrade, error) {
+	var trades []CoordinatedTrade
+	result := db.conn.WithContext(ctx).
+		Where("cluster_id = ? AND condition_id = ?", clusterID, conditionID).
+		Order("first_trade_ts DESC").
+		Limit(limit).
+		Find(&trades)
+	return trades, result.Error
+}
+
+// ClusterFilter selects clusters for ListClusters. Zero values don't filter.
+type ClusterFilter struct {
+	MinWallets  int
+	FlaggedOnly bool
+	Offset      int
+	Limit       int
+}
+
+// ListClusters returns the clusters still in use (not merged into another)
+// matching filter, most suspicious first, and how many match across all
+// pages
+func (db *DB) ListClusters(ctx context.Context, filter ClusterFilter) ([]WalletCluster, int64, error) {
+	query := db.conn.WithContext(ctx).Model(&WalletCluster{}).Where("merged_into = ?", "")
+	if filter.MinWallets > 0 {
+		query = query.Where("wallet_count >= ?", filter.MinWallets)
+	}
+	if filter.FlaggedOnly {
+		query = query.Where("is_flagged = ?", true)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var clusters []WalletCluster
+	result := query.
+		Order("suspicion_score DESC, wallet_count DESC, cluster_id ASC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Find(&clusters)
+	return clusters, total, result.Error
+}
+
+// Market statuses for MarketFilter
+const (
+	MarketActive = "active" // Not resolved and not past its end date (or without one)
+	MarketClosed = "closed" // Resolved or past its end date
+)
+
+// MarketFilter narrows ListMarkets. Zero values match everything.
+type MarketFilter struct {
+	Category  string
+	Status    string // MarketActive or MarketClosed
+	HasAlerts *bool  // With or without notified alerts
+	NowTS     int64  // What "past its end date" is measured against
+	Offset    int
+	Limit     int
+}
+
+// MarketActivity is a cached market with its notified alerts and resolution
+type MarketActivity struct {
+	ConditionID        string
+	MarketSlug         string
+	MarketTitle        string
+	MarketURL          string
+	Category           string
+	EndDate            int64
+	VolumeNum          float64
+	LiquidityNum       float64
+	AlertCount         int64
+	FlaggedNotionalUSD float64 // Notional of the market's notified alerts
+	LastAlertTS        int64
+	WinningOutcome     string // Empty until the market resolves
+	ResolvedTS         int64
+}
+
+// ListMarkets returns the cached markets matching filter with their alert
+// activity, most flagged notional first, and how many match across all
+// pages. Record-only alerts don't count as flagged activity.
+func (db *DB) ListMarkets(ctx context.Context, filter MarketFilter) ([]MarketActivity, int64, error) {
+	conn := db.conn.WithContext(ctx)
+	activity := conn.Model(&Alert{}).
+		Select("condition_id, COUNT(*) AS alert_count, SUM(notional_usd) AS notional_usd, MAX(created_ts) AS last_alert_ts").
+		Where("record_only = ?", false).
+		Group("condition_id")
+
+	query := conn.Table("market_map AS m").
+		Joins("LEFT JOIN (?) AS a ON a.condition_id = m.condition_id", activity).
+		Joins("LEFT JOIN market_resolutions AS r ON r.condition_id = m.condition_id")
+	if filter.Category != "" {
+		query = query.Where("m.category = ?", filter.Category)
+	}
+	switch filter.Status {
+	case MarketActive:
+		query = query.Where("r.condition_id IS NULL AND (m.end_date = 0 OR m.end_date > ?)", filter.NowTS)
+	case MarketClosed:
+		query = query.Where("r.condition_id IS NOT NULL OR (m.end_date > 0 AND m.end_date <= ?)", filter.NowTS)
+	}
+	if filter.HasAlerts != nil {
+		if *filter.HasAlerts {
+			query = query.Where("a.condition_id IS NOT NULL")
+		} else {
+			query = query.Where("a.condition_id IS NULL")
+		}
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var markets []MarketActivity
+	result := query.
+		Select(`m.condition_id, m.market_slug, m.market_title, m.market_url, m.category, m.end_date, m.volume_num, m.liquidity_num,
+			COALESCE(a.alert_count, 0) AS alert_count, COALESCE(a.notional_usd, 0) AS flagged_notional_usd,
+			COALESCE(a.last_alert_ts, 0) AS last_alert_ts,
+			COALESCE(r.winning_outcome, '') AS winning_outcome, COALESCE(r.resolved_ts, 0) AS resolved_ts`).
+		Order("flagged_notional_usd DESC, alert_count DESC, m.condition_id ASC").
+		Offset(filter.Offset).
+		Limit(filter.Limit).
+		Scan(&markets)
+	return markets, total, result.Error
+}
+
+// GetMarketTrades returns a market's most recent tracked trades, newest first
+func (db *DB) GetMarketTrades(ctx context.Context, conditionID string, limit int) ([]TradeSeen, error) {
+	var trades []TradeSeen
+	result := db.conn.WithContext(ctx).
+		Where("condition_id = ?", conditionID).
+		Order("timestamp_sec DESC").
+		Limit(limit).
+		Find(&trades)
+	return trades, result.Error
+}
+
+// ClusterMemberDetail is a cluster member with its wallet record and stats.
+// Wallet and stats fields are zero when the wallet has no such row.
+type ClusterMemberDetail struct {
+	WalletAddress       string
+	LinkType            string
+	JoinedTS            int64
+	FirstSeenTS         int64
+	TotalTrades         int
+	TotalVolumeUSD      float64
+	LastActivityTS      int64
+	TotalResolvedTrades int
+	WinRate             float64
+	TotalProfitUSD      float64
+}
+
+// GetClusterMemberDetails returns a cluster's members joined with their
+// wallet records and stats in one query, largest volume first
+func (db *DB) GetClusterMemberDetails(ctx context.Context, clusterID string) ([]ClusterMemberDetail, error) {
+	var members []ClusterMemberDetail
+	result := db.conn.WithContext(ctx).
+		Table("wallet_cluster_members AS m").
+		Select(`m.wallet_address, m.link_type, m.created_ts AS joined_ts,
+			COALESCE(w.first_seen_ts, 0) AS first_seen_ts,
+			COALESCE(w.total_trades, 0) AS total_trades,
+			COALESCE(w.total_volume_usd, 0) AS total_volume_usd,
+			COALESCE(w.last_activity_ts, 0) AS last_activity_ts,
+			COALESCE(s.total_resolved_trades, 0) AS total_resolved_trades,
+			COALESCE(s.win_rate, 0) AS win_rate,
+			COALESCE(s.total_profit_usd, 0) AS total_profit_usd`).
+		Joins("LEFT JOIN wallets AS w ON w.wallet_address = m.wallet_address").
+		Joins("LEFT JOIN wallet_stats AS s ON s.wallet_address = m.wallet_address").
+		Where("m.cluster_id = ?", clusterID).
+		Order("total_volume_usd DESC, m.wallet_address ASC").
+		Scan(&members)
+	return members, result.Error
+}
+
 // GetRecentTradesForCluster gets recent trades from wallets in a cluster
 func (db *DB) GetRecentTradesForCluster(ctx context.Context, walletAddresses []string, sinceTS int64) ([]TradeSeen, error) {
 	if len(walletAddresses) == 0 {
@@ -389,6 +1036,259 @@ func (db *DB) GetRecentTradesForWallet(ctx context.Context, walletAddress string
 	return trades, result.Error
 }
 
+// GetWalletTrades returns a wallet's most recent tracked trades, newest first
+func (db *DB) GetWalletTrades(ctx context.Context, walletAddress string, limit int) ([]TradeSeen, error) {
+	var trades []TradeSeen
+	result := db.conn.WithContext(ctx).
+		Where("proxy_wallet = ?", walletAddress).
+		Order("timestamp_sec DESC").
+		Limit(limit).
+		Find(&trades)
+	return trades, result.Error
+}
+
+// GetWalletMarketTrades returns a wallet's trades in one market between
+// sinceTS and untilTS (inclusive), excluding the given trade
+func (db *DB) GetWalletMarketTrades(ctx context.Context, walletAddress, conditionID, excludeTradeHash string, sinceTS, untilTS int64) ([]TradeSeen, error) {
+	var trades []TradeSeen
+	result := db.conn.WithContext(ctx).
+		Where("proxy_wallet = ? AND condition_id = ?", walletAddress, conditionID).
+		Where("timestamp_sec BETWEEN ? AND ?", sinceTS, untilTS).
+		Where("trade_hash <> ?", excludeTradeHash).
+		Find(&trades)
+	return trades, result.Error
+}
+
+// GetRecentTradeSizes returns the notionals of a wallet's most recent trades,
+// newest first, excluding the given trade
+func (db *DB) GetRecentTradeSizes(ctx context.Context, walletAddress, excludeTradeHash string, limit int) ([]float64, error) {
+	var sizes []float64
+	result := db.conn.WithContext(ctx).
+		Model(&TradeSeen{}).
+		Where("proxy_wallet = ?", walletAddress).
+		Where("trade_hash <> ?", excludeTradeHash).
+		Order("timestamp_sec DESC").
+		Limit(limit).
+		Pluck("notional_usd", &sizes)
+	return sizes, result.Error
+}
+
+// WalletAlertSummary is a wallet's alerts over a window
+type WalletAlertSummary struct {
+	WalletAddress string
+	Alerts        int64
+	MaxScore      float64
+	TotalNotional float64
+	LastAlertTS   int64
+}
+
+// GetTopAlertedWallets returns the wallets with alerts since sinceTS, highest
+// suspicion score first. Record-only alerts are left out.
+func (db *DB) GetTopAlertedWallets(ctx context.Context, sinceTS int64, limit int) ([]WalletAlertSummary, error) {
+	var wallets []WalletAlertSummary
+	result := db.conn.WithContext(ctx).
+		Model(&Alert{}).
+		Select(`wallet_address, COUNT(*) AS alerts, MAX(suspicion_score) AS max_score,
+			SUM(notional_usd) AS total_notional, MAX(created_ts) AS last_alert_ts`).
+		Where("created_ts >= ? AND record_only = ?", sinceTS, false).
+		Group("wallet_address").
+		Order("max_score DESC, alerts DESC").
+		Limit(limit).
+		Scan(&wallets)
+	return wallets, result.Error
+}
+
+// AlertCounts is the number of alerts of one severity in recent windows
+type AlertCounts struct {
+	AlertType string
+	Last24h   int64
+	Last7d    int64
+}
+
+// ServiceCounts is a summary of what the service has stored
+type ServiceCounts struct {
+	TradesToday     int64         // Trades with a timestamp at or after the given day start
+	Alerts          []AlertCounts // By severity; severities without alerts in 7 days are left out
+	Wallets         int64
+	Clusters        int64 // Clusters still in use (not merged into another)
+	FlaggedClusters int64
+	ResolvedMarkets int64
+	LastProcessedTS int64 // The poll checkpoint; 0 before the first poll
+}
+
+// GetServiceCounts runs the aggregate queries behind the service summary.
+// Alert windows are measured back from now.
+func (db *DB) GetServiceCounts(ctx context.Context, dayStartTS int64, now time.Time) (*ServiceCounts, error) {
+	conn := db.conn.WithContext(ctx)
+	counts := &ServiceCounts{}
+
+	if err := conn.Model(&TradeSeen{}).Where("timestamp_sec >= ?", dayStartTS).Count(&counts.TradesToday).Error; err != nil {
+		return nil, fmt.Errorf("count trades: %w", err)
+	}
+
+	since24h := now.Add(-24 * time.Hour).Unix()
+	since7d := now.Add(-7 * 24 * time.Hour).Unix()
+	err := conn.Model(&Alert{}).
+		Select("alert_type, SUM(CASE WHEN created_ts >= ? THEN 1 ELSE 0 END) AS last24h, COUNT(*) AS last7d", since24h).
+		Where("created_ts >= ?", since7d).
+		Group("alert_type").
+		Order("alert_type").
+		Scan(&counts.Alerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("count alerts: %w", err)
+	}
+
+	if err := conn.Model(&Wallet{}).Count(&counts.Wallets).Error; err != nil {
+		return nil, fmt.Errorf("count wallets: %w", err)
+	}
+	clusters := conn.Model(&WalletCluster{}).Where("merged_into = ?", "")
+	if err := clusters.Session(&gorm.Session{}).Count(&counts.Clusters).Error; err != nil {
+		return nil, fmt.Errorf("count clusters: %w", err)
+	}
+	if err := clusters.Where("is_flagged = ?", true).Count(&counts.FlaggedClusters).Error; err != nil {
+		return nil, fmt.Errorf("count flagged clusters: %w", err)
+	}
+	if err := conn.Model(&MarketResolution{}).Count(&counts.ResolvedMarkets).Error; err != nil {
+		return nil, fmt.Errorf("count resolved markets: %w", err)
+	}
+
+	checkpoint, err := db.GetState(ctx, "last_processed_ts")
+	if err != nil {
+		return nil, fmt.Errorf("get checkpoint: %w", err)
+	}
+	if checkpoint != "" {
+		counts.LastProcessedTS, _ = strconv.ParseInt(checkpoint, 10, 64)
+	}
+
+	return counts, nil
+}
+
+// EntityCounts is how much the service is tracking
+type EntityCounts struct {
+	Wallets           int64
+	ActiveWallets     int64 // Wallets with activity at or after the given time
+	Clusters          int64 // Clusters of 2 or more wallets, not merged into another
+	FlaggedClusters   int64
+	UnresolvedMarkets int64 // Markets in the market map without a resolution
+}
+
+// GetEntityCounts runs the COUNT queries behind the tracked entity gauges
+func (db *DB) GetEntityCounts(ctx context.Context, activeSinceTS int64) (*EntityCounts, error) {
+	conn := db.conn.WithContext(ctx)
+	counts := &EntityCounts{}
+
+	if err := conn.Model(&Wallet{}).Count(&counts.Wallets).Error; err != nil {
+		return nil, fmt.Errorf("count wallets: %w", err)
+	}
+	if err := conn.Model(&Wallet{}).Where("last_activity_ts >= ?", activeSinceTS).Count(&counts.ActiveWallets).Error; err != nil {
+		return nil, fmt.Errorf("count active wallets: %w", err)
+	}
+	clusters := conn.Model(&WalletCluster{}).Where("merged_into = ?", "")
+	if err := clusters.Session(&gorm.Session{}).Where("wallet_count >= ?", 2).Count(&counts.Clusters).Error; err != nil {
+		return nil, fmt.Errorf("count clusters: %w", err)
+	}
+	if err := clusters.Where("is_flagged = ?", true).Count(&counts.FlaggedClusters).Error; err != nil {
+		return nil, fmt.Errorf("count flagged clusters: %w", err)
+	}
+	err := conn.Model(&MarketMap{}).
+		Joins("LEFT JOIN market_resolutions ON market_resolutions.condition_id = market_map.condition_id").
+		Where("market_resolutions.condition_id IS NULL").
+		Count(&counts.UnresolvedMarkets).Error
+	if err != nil {
+		return nil, fmt.Errorf("count unresolved markets: %w", err)
+	}
+
+	return counts, nil
+}
+
+// SeverityCount is the number of alerts of one severity
+type SeverityCount struct {
+	AlertType string
+	Alerts    int64
+}
+
+// FlaggedWin is a market that resolved with notified alerts on its winning
+// outcome
+type FlaggedWin struct {
+	ConditionID    string
+	MarketTitle    string
+	MarketURL      string
+	WinningOutcome string
+	Wallets        int64   // Alerted wallets that bought the winning outcome
+	AlertedUSD     float64 // Their alerted notional on it
+}
+
+// PeriodSummary is what happened between two times, for the daily summary
+type PeriodSummary struct {
+	Trades          int64 // Stored trades, i.e. those that passed the filters and were scored
+	VolumeUSD       float64
+	Alerts          []SeverityCount // Severities without alerts are left out
+	TopAlerts       []Alert         // Notified alerts, highest score first
+	FlaggedClusters []WalletCluster // Flagged clusters first seen in the period
+	FlaggedWins     []FlaggedWin    // Markets resolved in the period, biggest alerted notional first
+}
+
+// GetPeriodSummary runs the aggregate queries behind the daily summary for
+// [fromTS, toTS). Trades are placed by their timestamp, alerts by creation
+// and markets by resolution.
+func (db *DB) GetPeriodSummary(ctx context.Context, fromTS, toTS int64, topAlerts int) (*PeriodSummary, error) {
+	conn := db.conn.WithContext(ctx)
+	summary := &PeriodSummary{}
+
+	var trades struct {
+		Trades    int64
+		VolumeUSD float64
+	}
+	err := conn.Model(&TradeSeen{}).
+		Select("COUNT(*) AS trades, COALESCE(SUM(notional_usd), 0) AS volume_usd").
+		Where("timestamp_sec >= ? AND timestamp_sec < ?", fromTS, toTS).
+		Scan(&trades).Error
+	if err != nil {
+		return nil, fmt.Errorf("count trades: %w", err)
+	}
+	summary.Trades, summary.VolumeUSD = trades.Trades, trades.VolumeUSD
+
+	err = conn.Model(&Alert{}).
+		Select("alert_type, COUNT(*) AS alerts").
+		Where("created_ts >= ? AND created_ts < ?", fromTS, toTS).
+		Group("alert_type").
+		Order("alert_type").
+		Scan(&summary.Alerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("count alerts: %w", err)
+	}
+
+	err = conn.Where("created_ts >= ? AND created_ts < ? AND record_only = ?", fromTS, toTS, false).
+		Order("suspicion_score DESC, id DESC").
+		Limit(topAlerts).
+		Find(&summary.TopAlerts).Error
+	if err != nil {
+		return nil, fmt.Errorf("get top alerts: %w", err)
+	}
+
+	err = conn.Where("is_flagged = ? AND merged_into = ? AND first_seen_ts >= ? AND first_seen_ts < ?", true, "", fromTS, toTS).
+		Order("suspicion_score DESC").
+		Find(&summary.FlaggedClusters).Error
+	if err != nil {
+		return nil, fmt.Errorf("get flagged clusters: %w", err)
+	}
+
+	err = conn.Model(&MarketResolution{}).
+		Select(`market_resolutions.condition_id, market_resolutions.market_title, MAX(alerts.market_url) AS market_url,
+			market_resolutions.winning_outcome, COUNT(DISTINCT alerts.wallet_address) AS wallets, SUM(alerts.notional_usd) AS alerted_usd`).
+		Joins("JOIN alerts ON alerts.condition_id = market_resolutions.condition_id AND alerts.outcome = market_resolutions.winning_outcome").
+		Where("market_resolutions.resolved_ts >= ? AND market_resolutions.resolved_ts < ?", fromTS, toTS).
+		Where("alerts.side = ? AND alerts.record_only = ?", "BUY", false).
+		Group("market_resolutions.condition_id, market_resolutions.market_title, market_resolutions.winning_outcome").
+		Order("alerted_usd DESC").
+		Scan(&summary.FlaggedWins).Error
+	if err != nil {
+		return nil, fmt.Errorf("get flagged wins: %w", err)
+	}
+
+	return summary, nil
+}
+
 // gormLogAdapter adapts logrus to GORM's logger interface
 type gormLogAdapter struct {
 	log *logrus.Logger
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+
+	"github.com/liamashdown/insiderwatch/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// registerTracing wraps every GORM operation in a span named after it
+// (db.query, db.create, ...), as a child of the span in the statement's
+// context. The span carries the table and the SQL with placeholders, never
+// the bound values.
+func registerTracing(conn *gorm.DB) error {
+	cb := conn.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("tracing:before_create", startSpan("db.create")),
+		cb.Create().After("*").Register("tracing:after_create", endSpan),
+		cb.Query().Before("*").Register("tracing:before_query", startSpan("db.query")),
+		cb.Query().After("*").Register("tracing:after_query", endSpan),
+		cb.Update().Before("*").Register("tracing:before_update", startSpan("db.update")),
+		cb.Update().After("*").Register("tracing:after_update", endSpan),
+		cb.Delete().Before("*").Register("tracing:before_delete", startSpan("db.delete")),
+		cb.Delete().After("*").Register("tracing:after_delete", endSpan),
+		cb.Row().Before("*").Register("tracing:before_row", startSpan("db.row")),
+		cb.Row().After("*").Register("tracing:after_row", endSpan),
+		cb.Raw().Before("*").Register("tracing:before_raw", startSpan("db.raw")),
+		cb.Raw().After("*").Register("tracing:after_raw", endSpan),
+	)
+}
+
+func startSpan(name string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx, _ := tracing.Start(tx.Statement.Context, name, attribute.String("db.system", "mysql"))
+		tx.Statement.Context = ctx
+	}
+}
+
+func endSpan(tx *gorm.DB) {
+	span := trace.SpanFromContext(tx.Statement.Context)
+	span.SetAttributes(
+		attribute.String("db.sql.table", tx.Statement.Table),
+		attribute.String("db.statement", tx.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", tx.RowsAffected),
+	)
+	err := tx.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil // An expected miss, not a failure
+	}
+	tracing.End(span, err)
+}
@@ -0,0 +1,95 @@
+// Package tracing wraps OpenTelemetry for the processing pipeline. Until
+// Setup enables an exporter the global tracer provider is a no-op, so spans
+// cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "insiderwatch"
+
+var tracer = otel.Tracer("github.com/liamashdown/insiderwatch")
+
+// Setup installs an OTLP/HTTP exporter as the global tracer provider. The
+// exporter and sampler are configured by the standard OTEL_EXPORTER_OTLP_*
+// and OTEL_TRACES_SAMPLER variables. It returns a func that flushes pending
+// spans and stops the exporter; when tracing is disabled it installs nothing
+// and the func does nothing.
+func Setup(ctx context.Context, enabled bool) (func(context.Context) error, error) {
+	if !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("create OTLP exporter: %w", err)
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override these
+	res, err := resource.Merge(
+		resource.NewSchemaless(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(version.Version),
+		),
+		resource.Environment(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("build trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of any span in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on span, if any, and ends it. A cancelled context is
+// recorded on the span but not marked as a failure.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if !errors.Is(err, context.Canceled) {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// NotionalBucket groups a trade's USD notional into a few coarse ranges, so
+// traces can be filtered by size without a high-cardinality attribute
+func NotionalBucket(usd float64) string {
+	switch {
+	case usd < 10_000:
+		return "<10k"
+	case usd < 50_000:
+		return "10k-50k"
+	case usd < 100_000:
+		return "50k-100k"
+	case usd < 500_000:
+		return "100k-500k"
+	default:
+		return "500k+"
+	}
+}
@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestNotionalBucket(t *testing.T) {
+	tests := []struct {
+		notional float64
+		expected string
+	}{
+		{5_000, "<10k"},
+		{10_000, "10k-50k"},
+		{75_000, "50k-100k"},
+		{250_000, "100k-500k"},
+		{2_000_000, "500k+"},
+	}
+
+	for _, tt := range tests {
+		if got := NotionalBucket(tt.notional); got != tt.expected {
+			t.Errorf("NotionalBucket(%v) got %q, want %q", tt.notional, got, tt.expected)
+		}
+	}
+}
+
+func TestEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, ok := Start(ctx, "ok")
+	End(ok, nil)
+	_, failed := Start(ctx, "failed")
+	End(failed, errors.New("gamma: 503"))
+	_, cancelled := Start(ctx, "cancelled")
+	End(cancelled, context.Canceled)
+	End(parent, nil)
+
+	tests := []struct {
+		name           string
+		expectedStatus codes.Code
+		description    string
+	}{
+		{"ok", codes.Unset, "A span without an error keeps the default status"},
+		{"failed", codes.Error, "An error marks the span failed"},
+		{"cancelled", codes.Unset, "Cancellation is recorded but not a failure"},
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span, ok := spans[tt.name]
+			if !ok {
+				t.Fatalf("got no %q span", tt.name)
+			}
+			if span.Status().Code != tt.expectedStatus {
+				t.Errorf("got status %v, want %v\nDescription: %s", span.Status().Code, tt.expectedStatus, tt.description)
+			}
+			if span.Parent().SpanID() != spans["parent"].SpanContext().SpanID() {
+				t.Errorf("got parent %v, want the parent span\nDescription: %s", span.Parent().SpanID(), tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// NewBase returns a copy of the default transport that sends requests
+// through proxyURL and trusts the certificates in caBundle on top of the
+// system roots. An empty proxyURL keeps the standard HTTP_PROXY /
+// HTTPS_PROXY variables; an explicit one still honors NO_PROXY. caBundle is
+// a PEM file, for corporate proxies that re-sign TLS traffic.
+func NewBase(proxyURL, caBundle string) (*http.Transport, error) {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+
+	if proxyURL != "" {
+		if _, err := url.Parse(proxyURL); err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		cfg := &httpproxy.Config{
+			HTTPProxy:  proxyURL,
+			HTTPSProxy: proxyURL,
+			NoProxy:    httpproxy.FromEnvironment().NoProxy,
+		}
+		proxy := cfg.ProxyFunc()
+		base.Proxy = func(req *http.Request) (*url.URL, error) {
+			return proxy(req.URL)
+		}
+	}
+
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", caBundle)
+		}
+		base.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return base, nil
+}
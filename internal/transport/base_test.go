@@ -0,0 +1,118 @@
+package transport
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewBaseProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", "")
+
+	// The proxy answers itself rather than forwarding, so the target host
+	// never has to resolve
+	var gotURL string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL.String()
+		io.WriteString(w, "proxied")
+	}))
+	defer proxy.Close()
+
+	base, err := NewBase(proxy.URL, "")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	resp, err := (&http.Client{Transport: New("test", base, 0, nil)}).Get("http://data-api.test/trades?limit=1")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(body) != "proxied" || gotURL != "http://data-api.test/trades?limit=1" {
+		t.Errorf("got %q via proxy request %q, want the request sent through the proxy", body, gotURL)
+	}
+}
+
+func TestNewBaseNoProxy(t *testing.T) {
+	t.Setenv("NO_PROXY", "gamma-api.test,.internal.test")
+	t.Setenv("no_proxy", "")
+
+	base, err := NewBase("http://proxy.test:3128", "")
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		wantProxy   bool
+		description string
+	}{
+		{"proxied", "https://data-api.test/trades", true, "Hosts not in NO_PROXY use the configured proxy"},
+		{"exact", "https://gamma-api.test/markets", false, "An exact NO_PROXY host bypasses the proxy"},
+		{"suffix", "http://svc.internal.test/x", false, "A leading dot in NO_PROXY matches subdomains"},
+		{"localhost", "http://127.0.0.1:8080/", false, "Loopback addresses are never proxied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", tt.url, nil)
+			got, err := base.Proxy(req)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			if (got != nil) != tt.wantProxy {
+				t.Errorf("got proxy %v, want proxied %v\nDescription: %s", got, tt.wantProxy, tt.description)
+			}
+		})
+	}
+}
+
+func TestNewBaseCABundle(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		caBundle    string
+		wantErr     bool
+		description string
+	}{
+		{"trusted", bundle, false, "The bundle's certificate is trusted"},
+		{"system", "", true, "The test server's certificate isn't in the system roots"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, err := NewBase("", tt.caBundle)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			resp, err := (&http.Client{Transport: base}).Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v\nDescription: %s", err, tt.wantErr, tt.description)
+			}
+		})
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0o600)
+	if _, err := NewBase("", empty); err == nil {
+		t.Errorf("got no error for a bundle without certificates, want one")
+	}
+}
@@ -0,0 +1,98 @@
+package transport
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/tracing"
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// RequestIDHeader carries the ID generated for each outbound request, so a
+// request in our logs can be matched with the API provider's
+const RequestIDHeader = "X-Request-ID"
+
+// Transport tags outbound API requests with the insiderwatch User-Agent and
+// a request ID, traces them, and logs responses slower than its threshold
+type Transport struct {
+	api  string
+	slow time.Duration // 0 disables slow response logging
+	log  *logrus.Logger
+	base http.RoundTripper
+}
+
+// New returns a Transport for calls to the named API that sends requests
+// through base, or the default transport when base is nil. Either way gzip
+// is still requested and decompressed transparently.
+func New(api string, base http.RoundTripper, slow time.Duration, log *logrus.Logger) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &Transport{
+		api:  api,
+		slow: slow,
+		log:  log,
+		base: base,
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	requestID := newRequestID()
+	// The span times the call up to the response headers. Trace context
+	// isn't propagated to third-party APIs.
+	ctx, span := tracing.Start(req.Context(), t.api+" "+req.Method,
+		attribute.String("api", t.api),
+		attribute.String("http.request.method", req.Method),
+		attribute.String("url.path", req.URL.Path),
+		attribute.String("request_id", requestID),
+	)
+
+	// A RoundTripper must not modify the caller's request
+	req = req.Clone(ctx)
+	req.Header.Set("User-Agent", version.UserAgent())
+	req.Header.Set(RequestIDHeader, requestID)
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+	if resp != nil {
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	}
+	tracing.End(span, err)
+
+	if t.log != nil && t.slow > 0 && duration >= t.slow {
+		fields := logrus.Fields{
+			"api":         t.api,
+			"request_id":  requestID,
+			"url":         req.URL.String(),
+			"duration_ms": duration.Milliseconds(),
+		}
+		if resp != nil {
+			fields["status"] = resp.StatusCode
+		}
+		t.log.WithFields(fields).Warn("Slow API response")
+	}
+
+	return resp, err
+}
+
+// RequestID returns the ID sent with the request behind resp, or "" when
+// the request didn't go through a Transport
+func RequestID(resp *http.Response) string {
+	if resp == nil || resp.Request == nil {
+		return ""
+	}
+	return resp.Request.Header.Get(RequestIDHeader)
+}
+
+// newRequestID returns a random 16-character hex ID
+func newRequestID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,80 @@
+package transport
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamashdown/insiderwatch/internal/version"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTransportHeaders(t *testing.T) {
+	var gotUA, gotID string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotUA, gotID = r.Header.Get("User-Agent"), r.Header.Get(RequestIDHeader)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: New("test", nil, 0, nil)}
+	req, _ := http.NewRequest("GET", srv.URL, nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("got %v, want no error", err)
+	}
+	resp.Body.Close()
+
+	if gotUA != version.UserAgent() {
+		t.Errorf("got User-Agent %q, want %q", gotUA, version.UserAgent())
+	}
+	if len(gotID) != 16 || RequestID(resp) != gotID {
+		t.Errorf("got request ID %q sent and %q on the response, want the same 16-character ID", gotID, RequestID(resp))
+	}
+	if req.Header.Get(RequestIDHeader) != "" {
+		t.Errorf("caller's request was modified")
+	}
+}
+
+func TestTransportSlowResponse(t *testing.T) {
+	tests := []struct {
+		name        string
+		delay       time.Duration
+		slow        time.Duration
+		wantLogged  bool
+		description string
+	}{
+		{"slow", 30 * time.Millisecond, 10 * time.Millisecond, true, "Responses past the threshold are logged"},
+		{"fast", 0, time.Second, false, "Responses within the threshold are not logged"},
+		{"disabled", 30 * time.Millisecond, 0, false, "A zero threshold turns logging off"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(tt.delay)
+			}))
+			defer srv.Close()
+
+			var buf bytes.Buffer
+			log := logrus.New()
+			log.SetOutput(&buf)
+
+			resp, err := (&http.Client{Transport: New("test", nil, tt.slow, log)}).Get(srv.URL)
+			if err != nil {
+				t.Fatalf("got %v, want no error", err)
+			}
+			resp.Body.Close()
+
+			logged := strings.Contains(buf.String(), "Slow API response")
+			if logged != tt.wantLogged {
+				t.Errorf("got logged %v, want %v\nDescription: %s", logged, tt.wantLogged, tt.description)
+			}
+			if logged && !strings.Contains(buf.String(), RequestID(resp)) {
+				t.Errorf("got %q, want the request ID in the log\nDescription: %s", buf.String(), tt.description)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package version
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Build details, set at build time with
+// -ldflags "-X github.com/liamashdown/insiderwatch/internal/version.Version=v1.2.3
+// -X github.com/liamashdown/insiderwatch/internal/version.Commit=$(git rev-parse HEAD)
+// -X github.com/liamashdown/insiderwatch/internal/version.BuildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)".
+// Commit and BuildDate fall back to the VCS details Go embeds when building
+// from a git checkout.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+func init() {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision" && Commit == "":
+			Commit = s.Value
+		case s.Key == "vcs.time" && BuildDate == "":
+			BuildDate = s.Value
+		}
+	}
+}
+
+// UserAgent is sent on every outbound API request
+func UserAgent() string {
+	return "insiderwatch/" + Version
+}
+
+// ShortCommit returns the first 7 characters of the commit, or "unknown"
+func ShortCommit() string {
+	if Commit == "" {
+		return "unknown"
+	}
+	return Commit[:min(7, len(Commit))]
+}
+
+// Short identifies the build in one token, such as "v1.2.3 (abc1234)"
+func Short() string {
+	if Commit == "" {
+		return Version
+	}
+	return fmt.Sprintf("%s (%s)", Version, ShortCommit())
+}
+
+// String describes the build for --version
+func String() string {
+	date := BuildDate
+	if date == "" {
+		date = "unknown"
+	}
+	return fmt.Sprintf("insiderwatch %s (commit %s, built %s)", Version, ShortCommit(), date)
+}
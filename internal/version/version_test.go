@@ -0,0 +1,28 @@
+package version
+
+import "testing"
+
+func TestShort(t *testing.T) {
+	defer func(v, c string) { Version, Commit = v, c }(Version, Commit)
+
+	tests := []struct {
+		name        string
+		version     string
+		commit      string
+		expected    string
+		description string
+	}{
+		{"stamped", "v1.2.3", "abcdef1234567890", "v1.2.3 (abcdef1)", "The commit is shortened to 7 characters"},
+		{"short commit", "v1.2.3", "abc", "v1.2.3 (abc)", "A commit shorter than 7 characters is kept whole"},
+		{"no commit", "dev", "", "dev", "Without a commit only the version is shown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Version, Commit = tt.version, tt.commit
+			if got := Short(); got != tt.expected {
+				t.Errorf("got %q, want %q\nDescription: %s", got, tt.expected, tt.description)
+			}
+		})
+	}
+}
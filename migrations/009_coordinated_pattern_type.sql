@@ -0,0 +1,2 @@
+-- Record whether coordinated cluster trades were on the same side or opposing sides
+ALTER TABLE coordinated_trades ADD COLUMN pattern_type VARCHAR(16) NOT NULL DEFAULT 'same_side' AFTER time_window_sec;
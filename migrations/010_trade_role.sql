@@ -0,0 +1,2 @@
+-- Tag each trade with the wallet's role so maker fills can be told apart
+ALTER TABLE trades_seen ADD COLUMN role VARCHAR(8) NOT NULL DEFAULT 'taker' AFTER price;
@@ -0,0 +1,2 @@
+-- Record how each market resolution was determined (oracle data or price heuristic)
+ALTER TABLE market_resolutions ADD COLUMN method VARCHAR(32) NOT NULL DEFAULT 'price_heuristic' AFTER market_title;
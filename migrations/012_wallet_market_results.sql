@@ -0,0 +1,14 @@
+-- Migration: 012_wallet_market_results
+-- Description: Record each wallet's result per resolved market so win-rate
+-- updates are idempotent and stats can be rebuilt from scratch
+
+CREATE TABLE IF NOT EXISTS wallet_market_results (
+    wallet_address VARCHAR(128) NOT NULL,
+    condition_id VARCHAR(128) NOT NULL,
+    result VARCHAR(8) NOT NULL, -- win, loss, hedged
+    net_position_usd DECIMAL(20, 6) NOT NULL DEFAULT 0,
+    resolved_ts BIGINT NOT NULL,
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (wallet_address, condition_id),
+    INDEX idx_condition (condition_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
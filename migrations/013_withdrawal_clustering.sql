@@ -0,0 +1,42 @@
+-- Migration: 013_withdrawal_clustering
+-- Description: Link cluster wallets by shared withdrawal destination as well as
+-- funding source, with explicit cluster membership
+
+-- Addresses wallets sent funds to
+CREATE TABLE IF NOT EXISTS wallet_withdrawal_destinations (
+    wallet_address VARCHAR(255) NOT NULL,
+    destination VARCHAR(255) NOT NULL,
+    amount_usd DECIMAL(20,2) DEFAULT 0, -- First withdrawal to this destination
+    tx_hash VARCHAR(255),
+    withdrawal_ts BIGINT NOT NULL,
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (wallet_address, destination),
+    INDEX idx_destination (destination)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Cluster membership, so wallets can join a cluster by either link
+CREATE TABLE IF NOT EXISTS wallet_cluster_members (
+    wallet_address VARCHAR(255) NOT NULL,
+    cluster_id VARCHAR(64) NOT NULL,
+    link_type VARCHAR(16) NOT NULL, -- funding, withdrawal, both
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (wallet_address),
+    INDEX idx_cluster_id (cluster_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Withdrawal clusters have no funding source, so it can no longer be unique
+ALTER TABLE wallet_clusters DROP INDEX idx_funding_source;
+ALTER TABLE wallet_clusters ADD INDEX idx_funding_source (funding_source);
+ALTER TABLE wallet_clusters ADD COLUMN withdrawal_destination VARCHAR(255) NOT NULL DEFAULT '' AFTER funding_source;
+ALTER TABLE wallet_clusters ADD COLUMN link_type VARCHAR(16) NOT NULL DEFAULT 'funding' AFTER withdrawal_destination;
+ALTER TABLE wallet_clusters ADD COLUMN merged_into VARCHAR(64) NOT NULL DEFAULT '' AFTER link_type;
+ALTER TABLE wallet_clusters ADD INDEX idx_withdrawal_destination (withdrawal_destination);
+
+-- Existing clusters were all funding-based
+INSERT IGNORE INTO wallet_cluster_members (wallet_address, cluster_id, link_type, created_ts)
+SELECT fs.wallet_address, c.cluster_id, 'funding', fs.created_ts
+FROM wallet_funding_sources fs
+JOIN wallet_clusters c ON c.funding_source = fs.funding_source;
+
+UPDATE wallet_clusters c
+SET wallet_count = (SELECT COUNT(*) FROM wallet_cluster_members m WHERE m.cluster_id = c.cluster_id);
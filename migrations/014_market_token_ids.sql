@@ -0,0 +1,2 @@
+-- Cache each market's outcome -> CLOB token ID mapping for order book lookups
+ALTER TABLE market_map ADD COLUMN outcome_token_ids TEXT AFTER liquidity_num;
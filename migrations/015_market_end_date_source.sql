@@ -0,0 +1,2 @@
+-- Record whether a market's end date came from the market itself or its parent event
+ALTER TABLE market_map ADD COLUMN end_date_source VARCHAR(16) NOT NULL DEFAULT '' AFTER end_date;
@@ -0,0 +1,14 @@
+-- Migration: 016_copy_trade_links
+-- Description: Record wallets that mirror trades of recently flagged wallets
+
+CREATE TABLE IF NOT EXISTS wallet_copy_links (
+    follower_wallet VARCHAR(255) NOT NULL,
+    leader_wallet VARCHAR(255) NOT NULL,
+    last_condition_id VARCHAR(128) NOT NULL,
+    copy_count INT NOT NULL DEFAULT 1,
+    first_seen_ts BIGINT NOT NULL,
+    last_seen_ts BIGINT NOT NULL,
+    PRIMARY KEY (follower_wallet, leader_wallet),
+    INDEX idx_leader_wallet (leader_wallet),
+    INDEX idx_last_seen_ts (last_seen_ts)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
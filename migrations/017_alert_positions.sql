@@ -0,0 +1,34 @@
+-- Migration: 017_alert_positions
+-- Description: Track the positions behind alerts so exits can be reported
+
+CREATE TABLE IF NOT EXISTS alert_positions (
+    alert_id BIGINT NOT NULL,
+    wallet_address VARCHAR(128) NOT NULL,
+    condition_id VARCHAR(128) NOT NULL,
+    outcome VARCHAR(255) NOT NULL,
+    side VARCHAR(10) NOT NULL, -- Side of the alerted trade
+    severity VARCHAR(32) NOT NULL,
+    shares DECIMAL(20,6) NOT NULL,
+    entry_price DECIMAL(10,6) NOT NULL,
+    notional_usd DECIMAL(20,6) NOT NULL,
+    reversed_shares DECIMAL(20,6) NOT NULL DEFAULT 0,
+    reversed_notional DECIMAL(20,6) NOT NULL DEFAULT 0,
+    status VARCHAR(16) NOT NULL DEFAULT 'open', -- open, exited
+    created_ts BIGINT NOT NULL,
+    updated_ts BIGINT NOT NULL,
+    exited_ts BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (alert_id),
+    INDEX idx_alert_positions_wallet_market (wallet_address, condition_id),
+    INDEX idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Trades counted against an alerted position
+CREATE TABLE IF NOT EXISTS alert_position_exits (
+    alert_id BIGINT NOT NULL,
+    trade_hash VARCHAR(128) NOT NULL,
+    shares DECIMAL(20,6) NOT NULL,
+    price DECIMAL(10,6) NOT NULL,
+    trade_ts BIGINT NOT NULL,
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (alert_id, trade_hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,5 @@
+-- Migration: 018_wallet_market_trade_index
+-- Description: Index a wallet's trades in one market by time, for the
+-- concentration check
+
+ALTER TABLE trades_seen ADD INDEX idx_wallet_market_ts (proxy_wallet, condition_id, timestamp_sec);
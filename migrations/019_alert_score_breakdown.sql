@@ -0,0 +1,6 @@
+-- Migration: 019_alert_score_breakdown
+-- Description: Keep the score breakdown on every alert and mark record-only
+-- alerts from old wallets that did not notify
+
+ALTER TABLE alerts ADD COLUMN score_breakdown TEXT NULL AFTER suspicion_score;
+ALTER TABLE alerts ADD COLUMN record_only BOOLEAN NOT NULL DEFAULT FALSE AFTER score_breakdown;
@@ -0,0 +1,5 @@
+-- Migration: 020_market_fallback
+-- Description: Flag market_map rows built from trade data when Gamma had no
+-- market, so they expire after MARKET_FALLBACK_TTL instead of 24 hours
+
+ALTER TABLE market_map ADD COLUMN is_fallback BOOLEAN NOT NULL DEFAULT FALSE AFTER is_active;
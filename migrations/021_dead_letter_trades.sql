@@ -0,0 +1,15 @@
+-- Migration: 021_dead_letter_trades
+-- Description: Keep trades whose processing panicked, for inspection
+
+CREATE TABLE IF NOT EXISTS dead_letter_trades (
+    trade_hash VARCHAR(128) NOT NULL,
+    transaction_hash VARCHAR(128),
+    condition_id VARCHAR(128) NOT NULL,
+    proxy_wallet VARCHAR(128) NOT NULL,
+    trade_json TEXT,
+    reason TEXT,
+    stack TEXT,
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (trade_hash),
+    INDEX idx_created_ts (created_ts)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
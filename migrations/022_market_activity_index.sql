@@ -0,0 +1,6 @@
+-- Migration: 022_market_activity_index
+-- Description: Cover the per-market alert aggregate behind GET /markets and
+-- index market categories for its filter
+
+ALTER TABLE alerts ADD INDEX idx_alerts_market_activity (condition_id, record_only, notional_usd, created_ts);
+ALTER TABLE market_map ADD INDEX idx_market_map_category (category);
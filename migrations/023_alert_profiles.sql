@@ -0,0 +1,7 @@
+-- Migration: 023_alert_profiles
+-- Description: Record the watch profile behind each alert, so cooldowns and
+-- exit alerts follow the profile
+
+ALTER TABLE alerts ADD COLUMN profile VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE alerts ADD INDEX idx_alerts_profile (profile);
+ALTER TABLE alert_positions ADD COLUMN profile VARCHAR(64) NOT NULL DEFAULT '';
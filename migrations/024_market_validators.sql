@@ -0,0 +1,6 @@
+-- Migration: 024_market_validators
+-- Description: Keep Gamma's ETag and Last-Modified per cached market, so a
+-- refresh of an unchanged market is a 304 instead of a full download
+
+ALTER TABLE market_map ADD COLUMN etag VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE market_map ADD COLUMN last_modified VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,15 @@
+-- Migration: 025_exposure_alerts
+-- Description: Record aggregate exposure alerts, whose cooldown keeps a
+-- wallet's further buys from alerting again
+
+CREATE TABLE IF NOT EXISTS exposure_alerts (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    wallet_address VARCHAR(128) NOT NULL,
+    trade_hash VARCHAR(128) NOT NULL,
+    total_usd DECIMAL(20,6) NOT NULL,
+    markets INT NOT NULL,
+    window_start_ts BIGINT NOT NULL,
+    created_ts BIGINT NOT NULL,
+    PRIMARY KEY (id),
+    INDEX idx_exposure_alerts_wallet_ts (wallet_address, created_ts)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
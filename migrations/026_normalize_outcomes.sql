@@ -0,0 +1,13 @@
+-- Migration: 026_normalize_outcomes
+-- Description: Trim stored outcome labels and collapse their inner
+-- whitespace, as NORMALIZE_OUTCOMES does for new rows. The migrate command
+-- runs the same pass.
+
+UPDATE trades_seen SET outcome = TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '))
+    WHERE BINARY outcome <> BINARY TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '));
+UPDATE alerts SET outcome = TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '))
+    WHERE BINARY outcome <> BINARY TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '));
+UPDATE alert_positions SET outcome = TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '))
+    WHERE BINARY outcome <> BINARY TRIM(REGEXP_REPLACE(outcome, '[[:space:]]+', ' '));
+UPDATE market_resolutions SET winning_outcome = TRIM(REGEXP_REPLACE(winning_outcome, '[[:space:]]+', ' '))
+    WHERE BINARY winning_outcome <> BINARY TRIM(REGEXP_REPLACE(winning_outcome, '[[:space:]]+', ' '));
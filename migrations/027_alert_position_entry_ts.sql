@@ -0,0 +1,9 @@
+-- Migration: 027_alert_position_entry_ts
+-- Description: Keep the time of the trade behind each alerted position, so
+-- the exit scan only counts the wallet's trades after it
+
+ALTER TABLE alert_positions ADD COLUMN entry_ts BIGINT NOT NULL DEFAULT 0;
+
+UPDATE alert_positions p
+JOIN alerts a ON a.id = p.alert_id
+SET p.entry_ts = a.trade_timestamp_sec;